@@ -0,0 +1,57 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// CancelHandle allows operations that are in progress to be aborted. Once Cancel has been called, any network
+// requests made by operations that were given the handle are aborted, and those operations return an error.
+// A CancelHandle can't be reset, so a new one is needed for each cancellable operation.
+type CancelHandle struct {
+	ctx    context.Context //nolint:containedctx // The context is how cancellation is passed to the Go API.
+	cancel context.CancelFunc
+}
+
+// NewCancelHandle returns a new CancelHandle.
+func NewCancelHandle() *CancelHandle {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &CancelHandle{ctx: ctx, cancel: cancel}
+}
+
+// NewCancelHandleWithTimeoutNanoseconds returns a new CancelHandle that cancels automatically once the given timeout
+// (in nanoseconds) has elapsed. The timeout starts when the handle is created and covers all the operations that are
+// given the handle, unlike HTTP timeouts, which apply to each request separately.
+func NewCancelHandleWithTimeoutNanoseconds(timeout int64) *CancelHandle {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout))
+
+	return &CancelHandle{ctx: ctx, cancel: cancel}
+}
+
+// Cancel aborts the operations that were given this handle. It's safe to call Cancel more than once.
+func (c *CancelHandle) Cancel() {
+	c.cancel()
+}
+
+// Cancelled indicates whether this handle has been cancelled, either by calling Cancel or by its timeout elapsing.
+func (c *CancelHandle) Cancelled() bool {
+	return c.ctx.Err() != nil
+}
+
+// Context returns the context that is passed to the Go API. If the given handle is nil, then a context that's never
+// cancelled is returned.
+// This function is not compatible with gomobile and so will not be available in the generated bindings.
+func Context(handle *CancelHandle) context.Context {
+	if handle == nil {
+		return context.Background()
+	}
+
+	return handle.ctx
+}
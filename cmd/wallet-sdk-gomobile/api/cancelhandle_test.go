@@ -0,0 +1,46 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package api_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
+)
+
+func TestCancelHandle(t *testing.T) {
+	t.Run("Cancel", func(t *testing.T) {
+		handle := api.NewCancelHandle()
+		require.False(t, handle.Cancelled())
+
+		ctx := api.Context(handle)
+		require.NoError(t, ctx.Err())
+
+		handle.Cancel()
+		handle.Cancel()
+
+		require.True(t, handle.Cancelled())
+		require.ErrorIs(t, ctx.Err(), context.Canceled)
+	})
+	t.Run("Timeout", func(t *testing.T) {
+		handle := api.NewCancelHandleWithTimeoutNanoseconds(int64(time.Millisecond))
+
+		<-api.Context(handle).Done()
+
+		require.True(t, handle.Cancelled())
+		require.ErrorIs(t, api.Context(handle).Err(), context.DeadlineExceeded)
+	})
+	t.Run("Nil handle", func(t *testing.T) {
+		ctx := api.Context(nil)
+		require.NoError(t, ctx.Err())
+		require.Nil(t, ctx.Done())
+	})
+}
@@ -0,0 +1,19 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package api
+
+// MetadataCacheStore is a storage mechanism for cached issuer metadata. Implementations may persist entries (e.g. in
+// a database or in the app's files) so that they survive app restarts. Implementations must be safe for concurrent
+// use.
+type MetadataCacheStore interface {
+	// Get returns the value stored under the given key. nil (and no error) must be returned if there isn't one.
+	Get(key string) ([]byte, error)
+	// Put stores the given value under the given key, replacing any existing value.
+	Put(key string, value []byte) error
+	// Delete deletes the value stored under the given key. No error is returned if there isn't one.
+	Delete(key string) error
+}
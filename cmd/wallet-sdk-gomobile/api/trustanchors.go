@@ -0,0 +1,69 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package api
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+)
+
+// TrustAnchors represents a set of trusted X.509 certificates that certificate chains are verified against.
+type TrustAnchors struct {
+	Certificates []*x509.Certificate // Will be skipped in the gomobile bindings due to using an incompatible type
+}
+
+// NewTrustAnchors returns a new, empty set of trust anchors.
+func NewTrustAnchors() *TrustAnchors {
+	return &TrustAnchors{}
+}
+
+// Add adds the given certificates to the set. The data can either be a single DER-encoded certificate or one or more
+// PEM-encoded certificates.
+func (t *TrustAnchors) Add(certificates []byte) error {
+	if block, _ := pem.Decode(certificates); block == nil {
+		certificate, err := x509.ParseCertificate(certificates)
+		if err != nil {
+			return fmt.Errorf("failed to parse DER-encoded certificate: %w", err)
+		}
+
+		t.Certificates = append(t.Certificates, certificate)
+
+		return nil
+	}
+
+	var parsedCertificates []*x509.Certificate
+
+	for rest := certificates; ; {
+		var block *pem.Block
+
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			return fmt.Errorf("unexpected PEM block type %s", block.Type)
+		}
+
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("failed to parse PEM-encoded certificate: %w", err)
+		}
+
+		parsedCertificates = append(parsedCertificates, certificate)
+	}
+
+	t.Certificates = append(t.Certificates, parsedCertificates...)
+
+	return nil
+}
+
+// Length returns the number of certificates in the set.
+func (t *TrustAnchors) Length() int {
+	return len(t.Certificates)
+}
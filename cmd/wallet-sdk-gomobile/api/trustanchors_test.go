@@ -0,0 +1,78 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package api_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
+)
+
+func TestTrustAnchors_Add(t *testing.T) {
+	certificate1 := createSelfSignedCertificate(t, "Test CA 1")
+	certificate2 := createSelfSignedCertificate(t, "Test CA 2")
+
+	t.Run("DER", func(t *testing.T) {
+		trustAnchors := api.NewTrustAnchors()
+
+		require.NoError(t, trustAnchors.Add(certificate1.Raw))
+		require.Equal(t, 1, trustAnchors.Length())
+		require.Equal(t, certificate1, trustAnchors.Certificates[0])
+	})
+	t.Run("PEM with multiple certificates", func(t *testing.T) {
+		trustAnchors := api.NewTrustAnchors()
+
+		pemData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate1.Raw})
+		pemData = append(pemData, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate2.Raw})...)
+
+		require.NoError(t, trustAnchors.Add(pemData))
+		require.Equal(t, 2, trustAnchors.Length())
+		require.Equal(t, []*x509.Certificate{certificate1, certificate2}, trustAnchors.Certificates)
+	})
+	t.Run("Unexpected PEM block type", func(t *testing.T) {
+		err := api.NewTrustAnchors().Add(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("key")}))
+		require.EqualError(t, err, "unexpected PEM block type PRIVATE KEY")
+	})
+	t.Run("Invalid DER", func(t *testing.T) {
+		err := api.NewTrustAnchors().Add([]byte("invalid"))
+		require.ErrorContains(t, err, "failed to parse DER-encoded certificate")
+	})
+}
+
+func createSelfSignedCertificate(t *testing.T, commonName string) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+
+	certificateBytes, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	certificate, err := x509.ParseCertificate(certificateBytes)
+	require.NoError(t, err)
+
+	return certificate
+}
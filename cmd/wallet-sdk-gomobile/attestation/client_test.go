@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package attestation //nolint:testpackage
 
 import (
+	"context"
 	_ "embed"
 	"errors"
 	"testing"
@@ -213,6 +214,7 @@ type serverAPIMock struct {
 }
 
 func (a *serverAPIMock) AttestationInit(
+	_ context.Context,
 	_ attestation.AttestWalletInitRequest,
 	_ string,
 ) (*attestation.AttestWalletInitResponse, error) {
@@ -224,6 +226,7 @@ func (a *serverAPIMock) AttestationInit(
 }
 
 func (a *serverAPIMock) AttestationComplete(
+	_ context.Context,
 	_ attestation.AttestWalletCompleteRequest,
 	_ string,
 ) (*attestation.AttestWalletCompleteResponse, error) {
@@ -234,6 +237,14 @@ func (a *serverAPIMock) AttestationComplete(
 	return a.attestationCompleteResp, nil
 }
 
+func (a *serverAPIMock) KeyAttestation(
+	_ context.Context,
+	_ attestation.AttestKeyRequest,
+	_ string,
+) (*attestation.AttestKeyResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
 type metricsLoggerMock struct{}
 
 func (m *metricsLoggerMock) Log(_ *api.MetricsEvent) error {
@@ -0,0 +1,149 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package credential
+
+import (
+	"encoding/json"
+	"slices"
+
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/verifiable"
+	"github.com/trustbloc/wallet-sdk/pkg/dcql"
+)
+
+// DCQLMatchResult contains information about VCs that matched a DCQL query.
+type DCQLMatchResult struct {
+	wrapped *dcql.Result
+}
+
+// Satisfied indicates whether the matched VCs are enough to satisfy the DCQL query.
+func (d *DCQLMatchResult) Satisfied() bool {
+	return d.wrapped.Satisfied
+}
+
+// CredentialQueryMatchLength returns the number of credential queries in the DCQL query.
+func (d *DCQLMatchResult) CredentialQueryMatchLength() int {
+	return len(d.wrapped.CredentialMatches)
+}
+
+// CredentialQueryMatchAtIndex returns the match for the credential query at the given index.
+// If the index passed in is out of bounds, then nil is returned.
+func (d *DCQLMatchResult) CredentialQueryMatchAtIndex(index int) *DCQLCredentialQueryMatch {
+	if index < 0 || index >= d.CredentialQueryMatchLength() {
+		return nil
+	}
+
+	return &DCQLCredentialQueryMatch{wrapped: d.wrapped.CredentialMatches[index]}
+}
+
+// CredentialSetLength returns the number of credential sets in the DCQL query.
+func (d *DCQLMatchResult) CredentialSetLength() int {
+	return len(d.wrapped.CredentialSets)
+}
+
+// CredentialSetAtIndex returns the credential set at the given index.
+// If the index passed in is out of bounds, then nil is returned.
+func (d *DCQLMatchResult) CredentialSetAtIndex(index int) *DCQLCredentialSet {
+	if index < 0 || index >= d.CredentialSetLength() {
+		return nil
+	}
+
+	return &DCQLCredentialSet{wrapped: d.wrapped.CredentialSets[index]}
+}
+
+// DCQLCredentialQueryMatch contains the VCs that matched a single credential query of a DCQL query.
+type DCQLCredentialQueryMatch struct {
+	wrapped *dcql.CredentialMatch
+}
+
+// ID returns the credential query's ID. It's used to identify the VCs selected for this credential query when
+// presenting them.
+func (d *DCQLCredentialQueryMatch) ID() string {
+	return d.wrapped.Query.ID
+}
+
+// Format returns the credential format that the credential query asks for.
+func (d *DCQLCredentialQueryMatch) Format() string {
+	return d.wrapped.Query.Format
+}
+
+// Multiple indicates whether more than one VC may be presented for the credential query.
+func (d *DCQLCredentialQueryMatch) Multiple() bool {
+	return d.wrapped.Query.Multiple
+}
+
+// MatchedVCs returns the VCs that match the credential query.
+func (d *DCQLCredentialQueryMatch) MatchedVCs() *verifiable.CredentialsArray {
+	matchedVCs := verifiable.NewCredentialsArray()
+
+	for _, matched := range d.wrapped.Credentials {
+		matchedVCs.Add(verifiable.NewCredential(matched.Credential))
+	}
+
+	return matchedVCs
+}
+
+// DCQLCredentialSet describes a combination of credential queries that the verifier will accept.
+type DCQLCredentialSet struct {
+	wrapped *dcql.CredentialSetMatch
+}
+
+// Required indicates whether one of the credential set's options must be satisfied.
+func (d *DCQLCredentialSet) Required() bool {
+	return d.wrapped.Query.IsRequired()
+}
+
+// Purpose returns why the verifier is asking for the credential set. If the verifier provided the purpose as an
+// object, then it's returned as JSON. If there's no purpose, then an empty string is returned.
+func (d *DCQLCredentialSet) Purpose() string {
+	switch purpose := d.wrapped.Query.Purpose.(type) {
+	case nil:
+		return ""
+	case string:
+		return purpose
+	default:
+		purposeBytes, err := json.Marshal(purpose)
+		if err != nil {
+			return ""
+		}
+
+		return string(purposeBytes)
+	}
+}
+
+// OptionLength returns the number of options in the credential set.
+func (d *DCQLCredentialSet) OptionLength() int {
+	return len(d.wrapped.Query.Options)
+}
+
+// OptionAtIndex returns the IDs of the credential queries that make up the option at the given index.
+// If the index passed in is out of bounds, then nil is returned.
+func (d *DCQLCredentialSet) OptionAtIndex(index int) *api.StringArray {
+	if index < 0 || index >= d.OptionLength() {
+		return nil
+	}
+
+	return &api.StringArray{Strings: d.wrapped.Query.Options[index]}
+}
+
+// IsOptionSatisfiable indicates whether every credential query in the option at the given index has at least one
+// matching VC.
+func (d *DCQLCredentialSet) IsOptionSatisfiable(index int) bool {
+	if index < 0 || index >= d.OptionLength() {
+		return false
+	}
+
+	option := d.wrapped.Query.Options[index]
+
+	for _, satisfiable := range d.wrapped.SatisfiableOptions {
+		if slices.Equal(satisfiable, option) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -5,7 +5,8 @@ Copyright Gen Digital Inc. All Rights Reserved.
 SPDX-License-Identifier: Apache-2.0
 */
 
-// Package credential contains a type that can be used to query for credentials using a presentation definition.
+// Package credential contains a type that can be used to query for credentials using a presentation definition or a
+// DCQL query.
 // It also contains a credential storage implementation using in-memory storage only.
 package credential
 
@@ -23,6 +24,7 @@ import (
 	goapi "github.com/trustbloc/wallet-sdk/pkg/api"
 	"github.com/trustbloc/wallet-sdk/pkg/common"
 	"github.com/trustbloc/wallet-sdk/pkg/credentialquery"
+	"github.com/trustbloc/wallet-sdk/pkg/dcql"
 	"github.com/trustbloc/wallet-sdk/pkg/memstorage/legacy"
 )
 
@@ -94,6 +96,27 @@ func (c *Inquirer) GetSubmissionRequirements(query []byte, credentials *verifiab
 	return &SubmissionRequirementArray{wrapped: requirements}, nil
 }
 
+// MatchDCQLQuery returns information about the VCs that match each credential query of the given DCQL query.
+func (c *Inquirer) MatchDCQLQuery(query []byte, credentials *verifiable.CredentialsArray,
+) (*DCQLMatchResult, error) {
+	if credentials == nil {
+		credentials = verifiable.NewCredentialsArray()
+	}
+
+	dcqlQuery, err := dcql.Parse(query)
+	if err != nil {
+		return nil, fmt.Errorf("validation of DCQL query failed: %w", err)
+	}
+
+	result, err := c.goAPICredentialQuery.GetDCQLMatches(dcqlQuery,
+		credentialquery.WithCredentialsArray(unwrapVCs(credentials)))
+	if err != nil {
+		return nil, wrapper.ToMobileError(err)
+	}
+
+	return &DCQLMatchResult{wrapped: result}, nil
+}
+
 func unwrapQuery(query []byte) (*presexch.PresentationDefinition, error) {
 	pdQuery := &presexch.PresentationDefinition{}
 
@@ -253,6 +253,89 @@ func TestInstance_GetSubmissionRequirementsCitizenship(t *testing.T) {
 	})
 }
 
+func TestInstance_MatchDCQLQuery(t *testing.T) {
+	contents := [][]byte{
+		universityDegreeVCJWT,
+		permanentResidentCardVC,
+		driverLicenseVC,
+		verifiedEmployeeVC,
+	}
+
+	opts := credential.NewInquirerOpts()
+	opts.SetDocumentLoader(&documentLoaderReverseWrapper{DocumentLoader: testutil.DocumentLoader(t)})
+
+	query := []byte(`{
+		"credentials": [
+			{"id": "degree", "format": "jwt_vc_json", "meta": {"type_values": [["UniversityDegreeCredential"]]}},
+			{"id": "employee", "format": "jwt_vc_json", "multiple": true,
+				"meta": {"type_values": [["VerifiedEmployee"]]}},
+			{"id": "mdl", "format": "mso_mdoc", "meta": {"doctype_value": "org.iso.18013.5.1.mDL"}}
+		],
+		"credential_sets": [
+			{"options": [["degree", "employee"], ["mdl"]], "purpose": {"en": "Proof of employment"}},
+			{"options": [["mdl"]], "required": false, "purpose": "Proof of age"}
+		]
+	}`)
+
+	t.Run("Success", func(t *testing.T) {
+		inquirer, err := credential.NewInquirer(opts)
+		require.NoError(t, err)
+
+		result, err := inquirer.MatchDCQLQuery(query, createCredJSONArray(t, contents))
+		require.NoError(t, err)
+		require.True(t, result.Satisfied())
+
+		require.Equal(t, 3, result.CredentialQueryMatchLength())
+		require.Nil(t, result.CredentialQueryMatchAtIndex(3))
+
+		degreeMatch := result.CredentialQueryMatchAtIndex(0)
+		require.Equal(t, "degree", degreeMatch.ID())
+		require.Equal(t, "jwt_vc_json", degreeMatch.Format())
+		require.False(t, degreeMatch.Multiple())
+		require.Equal(t, 1, degreeMatch.MatchedVCs().Length())
+		require.Equal(t, "UniversityDegreeCredential", degreeMatch.MatchedVCs().AtIndex(0).Types().AtIndex(1))
+
+		require.True(t, result.CredentialQueryMatchAtIndex(1).Multiple())
+		require.Equal(t, 0, result.CredentialQueryMatchAtIndex(2).MatchedVCs().Length())
+
+		require.Equal(t, 2, result.CredentialSetLength())
+		require.Nil(t, result.CredentialSetAtIndex(2))
+
+		requiredSet := result.CredentialSetAtIndex(0)
+		require.True(t, requiredSet.Required())
+		require.JSONEq(t, `{"en": "Proof of employment"}`, requiredSet.Purpose())
+		require.Equal(t, 2, requiredSet.OptionLength())
+		require.Equal(t, []string{"degree", "employee"}, requiredSet.OptionAtIndex(0).Strings)
+		require.Nil(t, requiredSet.OptionAtIndex(2))
+		require.True(t, requiredSet.IsOptionSatisfiable(0))
+		require.False(t, requiredSet.IsOptionSatisfiable(1))
+		require.False(t, requiredSet.IsOptionSatisfiable(2))
+
+		optionalSet := result.CredentialSetAtIndex(1)
+		require.False(t, optionalSet.Required())
+		require.Equal(t, "Proof of age", optionalSet.Purpose())
+	})
+
+	t.Run("No credentials", func(t *testing.T) {
+		inquirer, err := credential.NewInquirer(opts)
+		require.NoError(t, err)
+
+		result, err := inquirer.MatchDCQLQuery(query, nil)
+		require.NoError(t, err)
+		require.False(t, result.Satisfied())
+		require.Equal(t, 0, result.CredentialQueryMatchAtIndex(0).MatchedVCs().Length())
+	})
+
+	t.Run("Invalid query", func(t *testing.T) {
+		inquirer, err := credential.NewInquirer(opts)
+		require.NoError(t, err)
+
+		result, err := inquirer.MatchDCQLQuery([]byte(`{"credentials": []}`), nil)
+		require.ErrorContains(t, err, "validation of DCQL query failed")
+		require.Nil(t, result)
+	})
+}
+
 func createCredJSONArray(t *testing.T, creds [][]byte) *verifiable.CredentialsArray {
 	t.Helper()
 
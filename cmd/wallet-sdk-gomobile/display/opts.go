@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/metadatacache"
 )
 
 // Opts contains all optional arguments that can be passed into the Resolve function.
@@ -23,6 +24,7 @@ type Opts struct {
 	didResolver                      api.DIDResolver
 	skipNonClaimData                 bool
 	credentialConfigIDs              []string
+	metadataCache                    *metadatacache.Cache
 }
 
 // NewOpts returns a new Opts object.
@@ -111,6 +113,15 @@ func (o *Opts) SetDIDResolver(didResolver api.DIDResolver) *Opts {
 	return o
 }
 
+// SetMetadataCache sets the cache used for the issuer's metadata. The same cache should be used for every resolution
+// (and passed to OpenID4CI interactions) so that the metadata isn't fetched again while it's still fresh. If a cache
+// isn't set, then a shared in-memory cache is used.
+func (o *Opts) SetMetadataCache(cache *metadatacache.Cache) *Opts {
+	o.metadataCache = cache
+
+	return o
+}
+
 // SkipNonClaimData skips the non-claims related data like issue and expiry date.
 func (o *Opts) SkipNonClaimData() *Opts {
 	o.skipNonClaimData = true
@@ -138,6 +138,10 @@ func generateGoAPIOpts(vcs *verifiable.CredentialsArray, issuerURI string,
 		goAPIOpts = append(goAPIOpts, goAPIOpt)
 	}
 
+	if opts.metadataCache != nil {
+		goAPIOpts = append(goAPIOpts, goapicredentialschema.WithMetadataCache(opts.metadataCache.GoAPICache))
+	}
+
 	if opts.didResolver != nil {
 		jwtVerifier := defaults.NewDefaultProofChecker(
 			common.NewVDRKeyResolver(&wrapper.VDRResolverWrapper{
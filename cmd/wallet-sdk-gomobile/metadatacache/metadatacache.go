@@ -0,0 +1,64 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package metadatacache contains a cache for issuer metadata that can be shared across OpenID4CI interactions and
+// display data resolutions. It honours the Cache-Control and ETag headers of the responses the metadata came from.
+package metadatacache
+
+import (
+	"time"
+
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
+	goapimetadatacache "github.com/trustbloc/wallet-sdk/pkg/metadatacache"
+)
+
+// Opts contains all optional arguments that can be passed into the NewCache function.
+type Opts struct {
+	store      api.MetadataCacheStore
+	defaultTTL time.Duration
+}
+
+// NewOpts returns a new Opts object.
+func NewOpts() *Opts {
+	return &Opts{}
+}
+
+// SetStore sets the store that the cache's entries are kept in. If a store isn't set, then up to 100 entries are kept
+// in memory, with the least recently used ones being evicted first.
+func (o *Opts) SetStore(store api.MetadataCacheStore) *Opts {
+	o.store = store
+
+	return o
+}
+
+// SetDefaultTTLSeconds sets how long (in seconds) metadata stays fresh if the response it came from doesn't say so
+// itself. If this isn't set, then such metadata is always revalidated with the issuer before being used again.
+func (o *Opts) SetDefaultTTLSeconds(ttl int) *Opts {
+	o.defaultTTL = time.Duration(ttl) * time.Second
+
+	return o
+}
+
+// Cache caches issuer metadata. A single Cache should be shared by all the interactions and display data resolutions
+// that it's passed to.
+type Cache struct {
+	GoAPICache *goapimetadatacache.Cache // Will be skipped in the gomobile bindings due to using an incompatible type
+}
+
+// NewCache returns a new Cache.
+func NewCache(opts *Opts) *Cache {
+	if opts == nil {
+		opts = NewOpts()
+	}
+
+	goAPIOpts := []goapimetadatacache.Opt{goapimetadatacache.WithDefaultTTL(opts.defaultTTL)}
+
+	if opts.store != nil {
+		goAPIOpts = append(goAPIOpts, goapimetadatacache.WithStore(opts.store))
+	}
+
+	return &Cache{GoAPICache: goapimetadatacache.New(goAPIOpts...)}
+}
@@ -0,0 +1,75 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package metadatacache_test
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/metadatacache"
+)
+
+type mockStore struct {
+	mutex   sync.Mutex
+	entries map[string][]byte
+}
+
+func (m *mockStore) Get(key string) ([]byte, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.entries[key], nil
+}
+
+func (m *mockStore) Put(key string, value []byte) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.entries[key] = value
+
+	return nil
+}
+
+func (m *mockStore) Delete(key string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.entries, key)
+
+	return nil
+}
+
+func TestNewCache(t *testing.T) {
+	t.Run("Default options", func(t *testing.T) {
+		cache := metadatacache.NewCache(nil)
+		require.NotNil(t, cache.GoAPICache)
+
+		// With no default TTL, a response without caching headers isn't fresh and has no ETag to revalidate with.
+		require.NoError(t, cache.GoAPICache.Put("https://example.com", []byte("{}"), http.Header{}))
+
+		entry, err := cache.GoAPICache.Get("https://example.com")
+		require.NoError(t, err)
+		require.Nil(t, entry)
+	})
+	t.Run("Custom store and default TTL", func(t *testing.T) {
+		store := &mockStore{entries: map[string][]byte{}}
+
+		cache := metadatacache.NewCache(metadatacache.NewOpts().SetStore(store).SetDefaultTTLSeconds(60))
+
+		require.NoError(t, cache.GoAPICache.Put("https://example.com", []byte("{}"), http.Header{}))
+		require.Len(t, store.entries, 1)
+
+		entry, err := cache.GoAPICache.Get("https://example.com")
+		require.NoError(t, err)
+		require.NotNil(t, entry)
+		require.True(t, cache.GoAPICache.Fresh(entry))
+		require.Equal(t, []byte("{}"), entry.Document)
+	})
+}
@@ -5,12 +5,25 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/wrapper"
 	openid4cigoapi "github.com/trustbloc/wallet-sdk/pkg/openid4ci"
 )
 
+const (
+	// EventCredentialAccepted is used with NotifyIssuer when a credential was successfully stored in the wallet.
+	EventCredentialAccepted = string(openid4cigoapi.EventStatusCredentialAccepted)
+	// EventCredentialFailure is used with NotifyIssuer when a credential couldn't be stored, for any reason other than
+	// a user action.
+	EventCredentialFailure = string(openid4cigoapi.EventStatusCredentialFailure)
+	// EventCredentialDeleted is used with NotifyIssuer when the user rejected or deleted a credential.
+	EventCredentialDeleted = string(openid4cigoapi.EventStatusCredentialDeleted)
+)
+
 // Acknowledgment represents an object that allows to acknowledge the issuer the user's accepted or rejected credential.
 type Acknowledgment struct {
 	acknowledgment *openid4cigoapi.Acknowledgment
+	cancelHandle   *api.CancelHandle
 }
 
 // NewAcknowledgment recreates acknowledgment object from serialized state.
@@ -48,6 +61,25 @@ func (a *Acknowledgment) SetInteractionDetails(serializedInteractionDetails stri
 	return nil
 }
 
+// SetDPoPSigningKey sets the key to use for DPoP proofs. It's only needed if the issuer issued a DPoP-bound access
+// token and this Acknowledgment was recreated from serialized state (using NewAcknowledgment), in which case it
+// must be the same key that was used to request the credentials.
+func (a *Acknowledgment) SetDPoPSigningKey(vm *api.VerificationMethod, crypto api.Crypto) error {
+	signer, err := createSigner(vm, crypto)
+	if err != nil {
+		return wrapper.ToMobileError(err)
+	}
+
+	a.acknowledgment.DPoPSigner = signer
+
+	return nil
+}
+
+// SetCancelHandle sets a handle that can be used to abort acknowledgment requests that are in progress.
+func (a *Acknowledgment) SetCancelHandle(cancelHandle *api.CancelHandle) {
+	a.cancelHandle = cancelHandle
+}
+
 // Success acknowledges the client's acceptance of credentials. Each call to this function
 // acknowledges the client's acceptance of the next credential in the list of issued credentials.
 //
@@ -57,7 +89,8 @@ func (a *Acknowledgment) SetInteractionDetails(serializedInteractionDetails stri
 //
 // Between the calls caller might set different interaction details using SetInteractionDetails.
 func (a *Acknowledgment) Success() error {
-	return a.acknowledgment.AcknowledgeIssuer(openid4cigoapi.EventStatusCredentialAccepted, &http.Client{})
+	return a.acknowledgment.AcknowledgeIssuerContext(api.Context(a.cancelHandle),
+		openid4cigoapi.EventStatusCredentialAccepted, &http.Client{})
 }
 
 // Reject acknowledges the client's rejection of credentials. Each call to this function
@@ -69,11 +102,30 @@ func (a *Acknowledgment) Success() error {
 //
 // Between the calls caller might set different interaction details using SetInteractionDetails.
 func (a *Acknowledgment) Reject() error {
-	return a.acknowledgment.AcknowledgeIssuer(openid4cigoapi.EventStatusCredentialFailure, &http.Client{})
+	return a.acknowledgment.AcknowledgeIssuerContext(api.Context(a.cancelHandle),
+		openid4cigoapi.EventStatusCredentialFailure, &http.Client{})
 }
 
 // RejectWithCode acknowledges the client's rejection of credentials with specific code.
 // See Reject for details.
 func (a *Acknowledgment) RejectWithCode(code string) error {
-	return a.acknowledgment.AcknowledgeIssuer(openid4cigoapi.EventStatus(code), &http.Client{})
+	return a.acknowledgment.AcknowledgeIssuerContext(api.Context(a.cancelHandle), openid4cigoapi.EventStatus(code),
+		&http.Client{})
+}
+
+// NotifyIssuer notifies the issuer about an event concerning the credential with the given notification ID (see the
+// NotificationID method on the interaction objects and on PendingIssuance). Unlike Success and Reject, it can be
+// called any number of times, including much later using an Acknowledgment restored with NewAcknowledgment (e.g. to
+// send EventCredentialDeleted when the user deletes a credential).
+// The event must be one of EventCredentialAccepted, EventCredentialFailure or EventCredentialDeleted. The event
+// description is optional (pass in an empty string to omit it), and must only contain printable ASCII characters.
+// If the stored access token had to be refreshed, then this Acknowledgment should be serialized and stored again.
+func (a *Acknowledgment) NotifyIssuer(notificationID, event, eventDescription string) error {
+	err := a.acknowledgment.NotifyIssuerContext(api.Context(a.cancelHandle), notificationID,
+		openid4cigoapi.EventStatus(event), eventDescription, &http.Client{})
+	if err != nil {
+		return wrapper.ToMobileError(err)
+	}
+
+	return nil
 }
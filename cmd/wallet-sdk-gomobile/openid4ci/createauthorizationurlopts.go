@@ -14,6 +14,7 @@ type CreateAuthorizationURLOpts struct {
 	scopes                             *api.StringArray
 	issuerState                        *string
 	useOAuthDiscoverableClientIDScheme bool
+	usePushedAuthorizationRequest      bool
 }
 
 // NewCreateAuthorizationURLOpts returns a new CreateAuthorizationURLOpts object.
@@ -62,3 +63,14 @@ func (c *CreateAuthorizationURLOpts) UseOAuthDiscoverableClientIDScheme() *Creat
 
 	return c
 }
+
+// UsePushedAuthorizationRequest is an option for the createAuthorizationURL method that requires the authorization
+// request to be pushed to the authorization server (RFC 9126), so that the returned authorization URL only contains
+// the client ID and a request URI. By default, a pushed authorization request is made only if the authorization server
+// advertises a pushed authorization request endpoint. With this option, an error is returned instead if the
+// authorization server doesn't support pushed authorization requests.
+func (c *CreateAuthorizationURLOpts) UsePushedAuthorizationRequest() *CreateAuthorizationURLOpts {
+	c.usePushedAuthorizationRequest = true
+
+	return c
+}
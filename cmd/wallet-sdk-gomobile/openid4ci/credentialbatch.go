@@ -0,0 +1,70 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/verifiable"
+	openid4cigoapi "github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+// CredentialBatch contains copies of the same credential that were requested using batch issuance. Each copy is bound
+// to a different holder key. To prevent the copies from being linked to each other, a different copy should be used
+// for each presentation.
+type CredentialBatch struct {
+	batch *openid4cigoapi.CredentialBatch
+}
+
+// CredentialConfigurationID returns the ID of the credential configuration that the copies were issued for, if known.
+func (c *CredentialBatch) CredentialConfigurationID() string {
+	return c.batch.CredentialConfigurationID
+}
+
+// Credentials returns the copies of the credential.
+func (c *CredentialBatch) Credentials() *verifiable.CredentialsArray {
+	return toGomobileCredentials(c.batch.Credentials)
+}
+
+// HolderKeyIDs returns the IDs of the keys that the copies are bound to, in the same order as Credentials.
+func (c *CredentialBatch) HolderKeyIDs() *api.StringArray {
+	return &api.StringArray{Strings: c.batch.HolderKeyIDs}
+}
+
+// CredentialBatches represents a set of CredentialBatch objects.
+type CredentialBatches struct {
+	batches []*openid4cigoapi.CredentialBatch
+}
+
+// Length returns the number of CredentialBatch objects contained within this CredentialBatches object.
+func (c *CredentialBatches) Length() int {
+	return len(c.batches)
+}
+
+// AtIndex returns the CredentialBatch at the given index.
+// If the index passed in is out of bounds, then nil is returned.
+func (c *CredentialBatches) AtIndex(index int) *CredentialBatch {
+	maxIndex := len(c.batches) - 1
+	if index > maxIndex || index < 0 {
+		return nil
+	}
+
+	return &CredentialBatch{batch: c.batches[index]}
+}
+
+// batchedConfigIDs returns the credential configuration ID of each copy in the given batches, in the same order as
+// the credentials returned by the credential request.
+func batchedConfigIDs(batches []*openid4cigoapi.CredentialBatch) []string {
+	var configIDs []string
+
+	for _, batch := range batches {
+		for range batch.Credentials {
+			configIDs = append(configIDs, batch.CredentialConfigurationID)
+		}
+	}
+
+	return configIDs
+}
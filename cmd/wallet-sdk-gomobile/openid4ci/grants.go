@@ -21,6 +21,13 @@ func (p *PreAuthorizedCodeGrantParams) PINRequired() bool {
 	return p.goAPIPreAuthorizedCodeGrantParams.PINRequired()
 }
 
+// ClientIDRequired indicates whether the issuer's authorization server requires a client ID in the token request
+// (i.e. it doesn't support anonymous access). If so, it can be set using
+// RequestCredentialWithPreAuthOpts.SetClientID.
+func (p *PreAuthorizedCodeGrantParams) ClientIDRequired() bool {
+	return p.goAPIPreAuthorizedCodeGrantParams.ClientIDRequired()
+}
+
 // AuthorizationCodeGrantParams represents an issuer's authorization code grant parameters.
 type AuthorizationCodeGrantParams struct {
 	goAPIAuthorizationCodeGrantParams *openid4cigoapi.AuthorizationCodeGrantParams
@@ -12,6 +12,7 @@ import (
 	"errors"
 
 	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/otel"
 	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/verifiable"
 	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/wrapper"
 	goapi "github.com/trustbloc/wallet-sdk/pkg/api"
@@ -29,12 +30,22 @@ func createGoAPIClientConfig(didResolver api.DIDResolver, opts *InteractionOpts)
 	httpClient := wrapper.NewHTTPClient(opts.httpTimeout, opts.additionalHeaders, opts.disableHTTPClientTLSVerification)
 
 	goAPIClientConfig := &openid4cigoapi.ClientConfig{
-		DIDResolver:                      &wrapper.VDRResolverWrapper{DIDResolver: didResolver},
-		ActivityLogger:                   activityLogger,
-		MetricsLogger:                    &wrapper.MobileMetricsLoggerWrapper{MobileAPIMetricsLogger: opts.metricsLogger},
-		DisableVCProofChecks:             opts.disableVCProofChecks,
-		NetworkDocumentLoaderHTTPTimeout: opts.httpTimeout,
-		HTTPClient:                       httpClient,
+		DIDResolver:                        &wrapper.VDRResolverWrapper{DIDResolver: didResolver},
+		ActivityLogger:                     activityLogger,
+		MetricsLogger:                      &wrapper.MobileMetricsLoggerWrapper{MobileAPIMetricsLogger: opts.metricsLogger},
+		DisableVCProofChecks:               opts.disableVCProofChecks,
+		NetworkDocumentLoaderHTTPTimeout:   opts.httpTimeout,
+		HTTPClient:                         httpClient,
+		EnableCredentialResponseEncryption: opts.enableResponseEncryption,
+		UseCredentialConfigurationIDs:      opts.useCredentialConfigurationIDs,
+	}
+
+	if opts.metadataCache != nil {
+		goAPIClientConfig.MetadataCache = opts.metadataCache.GoAPICache
+	}
+
+	if opts.metadataVerificationPolicy != nil {
+		goAPIClientConfig.MetadataVerificationPolicy = opts.metadataVerificationPolicy.toGoAPIPolicy()
 	}
 
 	if opts.documentLoader != nil {
@@ -57,6 +68,36 @@ func createGoAPIClientConfig(didResolver api.DIDResolver, opts *InteractionOpts)
 	return goAPIClientConfig, nil
 }
 
+// createGoAPIClientConfigWithTrace is used by the standalone functions in this package that don't have an
+// interaction object to take a trace from. A new trace is started unless OpenTelemetry is disabled in the opts.
+func createGoAPIClientConfigWithTrace(didResolver api.DIDResolver,
+	opts *InteractionOpts,
+) (*openid4cigoapi.ClientConfig, *otel.Trace, error) {
+	if opts == nil {
+		opts = NewInteractionOpts()
+	}
+
+	var oTel *otel.Trace
+
+	if !opts.disableOpenTelemetry {
+		var err error
+
+		oTel, err = otel.NewTrace()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		opts.AddHeader(oTel.TraceHeader())
+	}
+
+	goAPIClientConfig, err := createGoAPIClientConfig(didResolver, opts)
+	if err != nil {
+		return nil, oTel, err
+	}
+
+	return goAPIClientConfig, oTel, nil
+}
+
 func createGoAPIActivityLogger(mobileAPIActivityLogger api.ActivityLogger) goapi.ActivityLogger {
 	if mobileAPIActivityLogger == nil {
 		return nil // Will result in activity logging being disabled in the OpenID4CI IssuerInitiatedInteraction object.
@@ -84,6 +125,10 @@ func convertToGoAPICreateAuthURLOpts(opts *CreateAuthorizationURLOpts) []openid4
 		goAPIOpts = append(goAPIOpts, openid4cigoapi.WithOAuthDiscoverableClientIDScheme())
 	}
 
+	if opts.usePushedAuthorizationRequest {
+		goAPIOpts = append(goAPIOpts, openid4cigoapi.WithPushedAuthorizationRequest())
+	}
+
 	return goAPIOpts
 }
 
@@ -11,6 +11,7 @@ import (
 
 	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
 	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/localkms"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/metadatacache"
 )
 
 // InteractionOpts contains all optional arguments that can be passed into the NewIssuerInitiatedInteraction function.
@@ -24,6 +25,11 @@ type InteractionOpts struct {
 	disableOpenTelemetry             bool
 	httpTimeout                      *time.Duration
 	kms                              *localkms.KMS
+	enableResponseEncryption         bool
+	useCredentialConfigurationIDs    bool
+	metadataCache                    *metadatacache.Cache
+	metadataVerificationPolicy       *MetadataVerificationPolicy
+	cancelHandle                     *api.CancelHandle
 }
 
 // NewInteractionOpts returns a new InteractionOpts object.
@@ -47,6 +53,31 @@ func (o *InteractionOpts) SetHTTPTimeoutNanoseconds(timeout int64) *InteractionO
 	return o
 }
 
+// SetMetadataCache sets the cache used for the issuer's metadata. The same cache should be passed to every interaction
+// (and to display data resolutions) so that the metadata isn't fetched again while it's still fresh. If a cache isn't
+// set, then a shared in-memory cache is used.
+func (o *InteractionOpts) SetMetadataCache(cache *metadatacache.Cache) *InteractionOpts {
+	o.metadataCache = cache
+
+	return o
+}
+
+// SetMetadataVerificationPolicy sets the policy that the issuer's metadata must satisfy. If a policy isn't set, then
+// unsigned metadata is accepted, and signed metadata is accepted from any DID but never from an x5c certificate chain.
+func (o *InteractionOpts) SetMetadataVerificationPolicy(policy *MetadataVerificationPolicy) *InteractionOpts {
+	o.metadataVerificationPolicy = policy
+
+	return o
+}
+
+// SetCancelHandle sets a handle that can be used to abort the interaction. Once the handle is cancelled, any request
+// to the issuer that's in progress is aborted, and all further calls that need to contact the issuer fail.
+func (o *InteractionOpts) SetCancelHandle(cancelHandle *api.CancelHandle) *InteractionOpts {
+	o.cancelHandle = cancelHandle
+
+	return o
+}
+
 // AddHeaders adds the given HTTP headers to all REST calls made to the issuer during the OpenID4CI flow.
 func (o *InteractionOpts) AddHeaders(headers *api.Headers) *InteractionOpts {
 	headersAsArray := headers.GetAll()
@@ -108,3 +139,32 @@ func (o *InteractionOpts) EnableDIProofChecks(kms *localkms.KMS) *InteractionOpt
 
 	return o
 }
+
+// EnableCredentialResponseEncryption causes the issuer to be asked to encrypt credential responses whenever it
+// supports doing so. If the issuer requires encrypted credential responses, then they will be used regardless of
+// whether this option is set.
+func (o *InteractionOpts) EnableCredentialResponseEncryption() *InteractionOpts {
+	o.enableResponseEncryption = true
+
+	return o
+}
+
+// UseCredentialConfigurationIDs causes credentials to be identified by their credential configuration ID in
+// authorization requests and credential requests, as required by draft 15 and later of the OpenID4VCI specification.
+// By default, they're only identified that way if the issuer's metadata clearly belongs to a draft 15 or later
+// issuer (i.e. it has a nonce_endpoint, or its credential configurations don't have any of the claim display fields
+// from earlier drafts), and by their format and type otherwise, as expected by earlier drafts.
+func (o *InteractionOpts) UseCredentialConfigurationIDs() *InteractionOpts {
+	o.useCredentialConfigurationIDs = true
+
+	return o
+}
+
+// getCancelHandle returns the cancel handle that was set, if any. It's safe to call on nil opts.
+func (o *InteractionOpts) getCancelHandle() *api.CancelHandle {
+	if o == nil {
+		return nil
+	}
+
+	return o.cancelHandle
+}
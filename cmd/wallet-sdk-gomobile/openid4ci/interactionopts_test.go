@@ -12,6 +12,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/metadatacache"
 )
 
 func TestClientConfig_AddHeader(t *testing.T) {
@@ -26,3 +27,54 @@ func TestClientConfig_AddHeader(t *testing.T) {
 	require.Equal(t, "testName", headers[0].Name)
 	require.Equal(t, "testValue", headers[0].Value)
 }
+
+func TestClientConfig_EnableCredentialResponseEncryption(t *testing.T) {
+	opts := NewInteractionOpts()
+
+	goAPIClientConfig, err := createGoAPIClientConfig(nil, opts)
+	require.NoError(t, err)
+	require.False(t, goAPIClientConfig.EnableCredentialResponseEncryption)
+
+	goAPIClientConfig, err = createGoAPIClientConfig(nil, opts.EnableCredentialResponseEncryption())
+	require.NoError(t, err)
+	require.True(t, goAPIClientConfig.EnableCredentialResponseEncryption)
+}
+
+func TestClientConfig_UseCredentialConfigurationIDs(t *testing.T) {
+	opts := NewInteractionOpts()
+
+	goAPIClientConfig, err := createGoAPIClientConfig(nil, opts)
+	require.NoError(t, err)
+	require.False(t, goAPIClientConfig.UseCredentialConfigurationIDs)
+
+	goAPIClientConfig, err = createGoAPIClientConfig(nil, opts.UseCredentialConfigurationIDs())
+	require.NoError(t, err)
+	require.True(t, goAPIClientConfig.UseCredentialConfigurationIDs)
+}
+
+func TestClientConfig_SetMetadataCache(t *testing.T) {
+	cache := metadatacache.NewCache(nil)
+
+	goAPIClientConfig, err := createGoAPIClientConfig(nil, NewInteractionOpts().SetMetadataCache(cache))
+	require.NoError(t, err)
+	require.Same(t, cache.GoAPICache, goAPIClientConfig.MetadataCache)
+}
+
+func TestClientConfig_SetMetadataVerificationPolicy(t *testing.T) {
+	goAPIClientConfig, err := createGoAPIClientConfig(nil, NewInteractionOpts())
+	require.NoError(t, err)
+	require.Nil(t, goAPIClientConfig.MetadataVerificationPolicy)
+
+	policy := NewMetadataVerificationPolicy().
+		RequireSignedMetadata().
+		AddAllowedDID("did:example:issuer1").
+		AddAllowedDID("did:example:issuer2").
+		SetTrustAnchors(api.NewTrustAnchors())
+
+	goAPIClientConfig, err = createGoAPIClientConfig(nil, NewInteractionOpts().SetMetadataVerificationPolicy(policy))
+	require.NoError(t, err)
+	require.True(t, goAPIClientConfig.MetadataVerificationPolicy.RequireSignedMetadata)
+	require.Equal(t, []string{"did:example:issuer1", "did:example:issuer2"},
+		goAPIClientConfig.MetadataVerificationPolicy.AllowedDIDs)
+	require.Empty(t, goAPIClientConfig.MetadataVerificationPolicy.TrustAnchors)
+}
@@ -0,0 +1,134 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/verifiable"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/wrapper"
+	openid4cigoapi "github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+// IssuanceGrant holds the refresh token that an issuer returned during an OpenID4CI interaction, along with the
+// details of the credentials that were requested. It can be serialized, stored (securely, since it contains a
+// refresh token), and later used with the RenewCredentials function to request the same credentials again
+// without any user interaction.
+type IssuanceGrant struct {
+	issuanceGrant *openid4cigoapi.IssuanceGrant
+}
+
+// NewIssuanceGrant recreates an IssuanceGrant object from its serialized state.
+func NewIssuanceGrant(serialized string) (*IssuanceGrant, error) {
+	issuanceGrant := &openid4cigoapi.IssuanceGrant{}
+
+	err := json.Unmarshal([]byte(serialized), issuanceGrant)
+	if err != nil {
+		return nil, fmt.Errorf("invalid issuance grant json structure: %w", err)
+	}
+
+	return &IssuanceGrant{issuanceGrant: issuanceGrant}, nil
+}
+
+// Serialize serializes the IssuanceGrant object so it can be restored later.
+func (g *IssuanceGrant) Serialize() (string, error) {
+	data, err := json.Marshal(g.issuanceGrant)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// IssuerURI returns the URI of the issuer that the grant is for.
+func (g *IssuanceGrant) IssuerURI() string {
+	return g.issuanceGrant.IssuerURI
+}
+
+// RenewCredentialsOpts contains all optional arguments that can be passed into the RenewCredentials function.
+type RenewCredentialsOpts struct {
+	interactionOpts *InteractionOpts
+}
+
+// NewRenewCredentialsOpts returns a new RenewCredentialsOpts object.
+func NewRenewCredentialsOpts() *RenewCredentialsOpts {
+	return &RenewCredentialsOpts{}
+}
+
+// SetInteractionOpts sets the options (HTTP timeout, headers, document loader, etc.) to use when renewing the
+// credentials and parsing them.
+func (r *RenewCredentialsOpts) SetInteractionOpts(interactionOpts *InteractionOpts) *RenewCredentialsOpts {
+	r.interactionOpts = interactionOpts
+
+	return r
+}
+
+// RenewedCredentials contains the result of a call to RenewCredentials.
+type RenewedCredentials struct {
+	credentials      *verifiable.CredentialsArrayV2
+	pendingIssuances *PendingIssuances
+}
+
+// Credentials returns the credentials that the issuer issued, along with their config IDs (if known).
+func (r *RenewedCredentials) Credentials() *verifiable.CredentialsArrayV2 {
+	return r.credentials
+}
+
+// PendingIssuances returns the credentials that the issuer deferred.
+// Each PendingIssuance can be serialized and later redeemed using the RequestDeferredCredential function.
+func (r *RenewedCredentials) PendingIssuances() *PendingIssuances {
+	return r.pendingIssuances
+}
+
+// RenewCredentials uses the refresh token in the given IssuanceGrant to get a new access token from the issuer,
+// and then requests the same credentials that were requested in the interaction that the grant came from.
+// If the issuer rotates the refresh token, then the given IssuanceGrant is updated, so it should be serialized and
+// stored again after calling this function.
+func RenewCredentials(grant *IssuanceGrant, vm *api.VerificationMethod, crypto api.Crypto,
+	didResolver api.DIDResolver, opts *RenewCredentialsOpts,
+) (*RenewedCredentials, error) {
+	if grant == nil {
+		return nil, wrapper.ToMobileError(walleterror.NewInvalidSDKUsageError(
+			openid4cigoapi.ErrorModule, errors.New("issuance grant object must be provided")))
+	}
+
+	if opts == nil {
+		opts = NewRenewCredentialsOpts()
+	}
+
+	goAPIClientConfig, oTel, err := createGoAPIClientConfigWithTrace(didResolver, opts.interactionOpts)
+	if err != nil {
+		return nil, wrapper.ToMobileErrorWithTrace(err, oTel)
+	}
+
+	signer, err := createSigner(vm, crypto)
+	if err != nil {
+		return nil, wrapper.ToMobileErrorWithTrace(err, oTel)
+	}
+
+	credentials, pendingIssuances, err := openid4cigoapi.RenewCredentialsContext(
+		api.Context(opts.interactionOpts.getCancelHandle()), grant.issuanceGrant, signer, goAPIClientConfig)
+	if err != nil {
+		return nil, wrapper.ToMobileErrorWithTrace(err, oTel)
+	}
+
+	configIDs := issuedConfigIDs(grant.issuanceGrant.CredentialConfigurationIDs, pendingIssuances)
+
+	// Config IDs aren't known for grants that came from wallet-initiated interactions.
+	if len(configIDs) != len(credentials) {
+		configIDs = make([]string, len(credentials))
+	}
+
+	return &RenewedCredentials{
+		credentials:      toGomobileCredentialsV2(credentials, configIDs),
+		pendingIssuances: &PendingIssuances{pendingIssuances: pendingIssuances},
+	}, nil
+}
@@ -0,0 +1,104 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	arieskms "github.com/trustbloc/kms-go/spi/kms"
+
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/localkms"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/openid4ci"
+	"github.com/trustbloc/wallet-sdk/internal/testutil"
+	"github.com/trustbloc/wallet-sdk/pkg/models"
+)
+
+func TestRenewCredentials(t *testing.T) {
+	issuerServerHandler := &mockIssuerServerHandler{
+		t:                  t,
+		credentialResponse: sampleCredentialResponse,
+		tokenResponse: []byte(`{"access_token":"access-token","token_type":"bearer","expires_in":86400,` +
+			`"refresh_token":"refresh-token","c_nonce":"tZignsnFbp"}`),
+	}
+
+	server := httptest.NewServer(issuerServerHandler)
+	defer server.Close()
+
+	issuerServerHandler.issuerMetadata = strings.ReplaceAll(sampleIssuerMetadata, serverURLPlaceholder, server.URL)
+
+	kms, err := localkms.NewKMS(localkms.NewMemKMSStore())
+	require.NoError(t, err)
+
+	interaction := createIssuerInitiatedInteraction(t, kms, nil, nil,
+		createCredentialOfferIssuanceURI(t, server.URL, false), nil, false)
+
+	keyHandle, err := kms.Create(arieskms.ED25519)
+	require.NoError(t, err)
+
+	verificationMethod := &api.VerificationMethod{
+		ID:   mockKeyID,
+		Type: "JsonWebKey2020",
+		Key:  models.VerificationKey{JSONWebKey: keyHandle.JWK},
+	}
+
+	_, err = interaction.RequestCredentialWithPreAuth(verificationMethod,
+		openid4ci.NewRequestCredentialWithPreAuthOpts().SetPIN("1234"))
+	require.NoError(t, err)
+
+	issuanceGrant, err := interaction.IssuanceGrant()
+	require.NoError(t, err)
+
+	serialized, err := issuanceGrant.Serialize()
+	require.NoError(t, err)
+
+	issuanceGrant, err = openid4ci.NewIssuanceGrant(serialized)
+	require.NoError(t, err)
+	require.Equal(t, server.URL, issuanceGrant.IssuerURI())
+
+	t.Run("Success", func(t *testing.T) {
+		opts := openid4ci.NewRenewCredentialsOpts().SetInteractionOpts(
+			openid4ci.NewInteractionOpts().DisableVCProofChecks().
+				SetDocumentLoader(&documentLoaderWrapper{DocumentLoader: testutil.DocumentLoader(t)}))
+
+		renewedCredentials, err := openid4ci.RenewCredentials(issuanceGrant, verificationMethod, kms.GetCrypto(),
+			&mockResolver{keyWriter: kms}, opts)
+		require.NoError(t, err)
+		require.Equal(t, 1, renewedCredentials.Credentials().Length())
+		require.Equal(t, "PermanentResidentCard_jwt_vc_json-ld_v1",
+			renewedCredentials.Credentials().ConfigIDAtIndex(0))
+		require.Zero(t, renewedCredentials.PendingIssuances().Length())
+	})
+	t.Run("Token request fails", func(t *testing.T) {
+		issuerServerHandler.tokenRequestShouldFail = true
+		defer func() { issuerServerHandler.tokenRequestShouldFail = false }()
+
+		renewedCredentials, err := openid4ci.RenewCredentials(issuanceGrant, verificationMethod, kms.GetCrypto(),
+			&mockResolver{keyWriter: kms}, nil)
+		requireErrorContains(t, err, "OTHER_TOKEN_REQUEST_ERROR")
+		require.Nil(t, renewedCredentials)
+	})
+	t.Run("Missing verification method", func(t *testing.T) {
+		renewedCredentials, err := openid4ci.RenewCredentials(issuanceGrant, nil, kms.GetCrypto(),
+			&mockResolver{keyWriter: kms}, nil)
+		requireErrorContains(t, err, "verification method must be provided")
+		require.Nil(t, renewedCredentials)
+	})
+	t.Run("Missing issuance grant", func(t *testing.T) {
+		renewedCredentials, err := openid4ci.RenewCredentials(nil, verificationMethod, kms.GetCrypto(),
+			&mockResolver{keyWriter: kms}, nil)
+		requireErrorContains(t, err, "issuance grant object must be provided")
+		require.Nil(t, renewedCredentials)
+	})
+	t.Run("Invalid serialized issuance grant", func(t *testing.T) {
+		_, err := openid4ci.NewIssuanceGrant("[")
+		require.Error(t, err)
+	})
+}
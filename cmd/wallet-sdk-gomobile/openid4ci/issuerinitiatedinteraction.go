@@ -12,6 +12,7 @@ import (
 	"errors"
 	"fmt"
 
+	kmsspi "github.com/trustbloc/kms-go/spi/kms"
 	verifiableapi "github.com/trustbloc/vc-go/verifiable"
 
 	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
@@ -31,6 +32,7 @@ type IssuerInitiatedInteraction struct {
 	goAPIInteraction *openid4cigoapi.IssuerInitiatedInteraction
 	crypto           api.Crypto
 	oTel             *otel.Trace
+	cancelHandle     *api.CancelHandle
 }
 
 // NewIssuerInitiatedInteraction creates a new OpenID4CI IssuerInitiatedInteraction.
@@ -65,7 +67,8 @@ func NewIssuerInitiatedInteraction( //nolint: dupl // Similar looking but for di
 		return nil, wrapper.ToMobileErrorWithTrace(err, oTel)
 	}
 
-	goAPIInteraction, err := openid4cigoapi.NewIssuerInitiatedInteraction(args.initiateIssuanceURI, goAPIClientConfig)
+	goAPIInteraction, err := openid4cigoapi.NewIssuerInitiatedInteractionContext(api.Context(opts.cancelHandle),
+		args.initiateIssuanceURI, goAPIClientConfig)
 	if err != nil {
 		return nil, wrapper.ToMobileErrorWithTrace(err, oTel)
 	}
@@ -74,6 +77,7 @@ func NewIssuerInitiatedInteraction( //nolint: dupl // Similar looking but for di
 		crypto:           args.crypto,
 		goAPIInteraction: goAPIInteraction,
 		oTel:             oTel,
+		cancelHandle:     opts.cancelHandle,
 	}, nil
 }
 
@@ -88,7 +92,8 @@ func (i *IssuerInitiatedInteraction) CreateAuthorizationURL(clientID, redirectUR
 ) (string, error) {
 	goAPIOpts := convertToGoAPICreateAuthURLOpts(opts)
 
-	authorizationURL, err := i.goAPIInteraction.CreateAuthorizationURL(clientID, redirectURI, goAPIOpts...)
+	authorizationURL, err := i.goAPIInteraction.CreateAuthorizationURLContext(api.Context(i.cancelHandle), clientID,
+		redirectURI, goAPIOpts...)
 	if err != nil {
 		return "", wrapper.ToMobileErrorWithTrace(err, i.oTel)
 	}
@@ -114,7 +119,7 @@ func (i *IssuerInitiatedInteraction) RequestCredentialWithPreAuth(
 
 // RequestCredentialWithPreAuthV2 requests credentials using a pre-authorized code flow.
 // Returns an array of credentials with config IDs, which map to CredentialConfigurationSupported in the
-// issuer's metadata.
+// issuer's metadata. Credentials deferred by the issuer are not included - see PendingIssuances.
 func (i *IssuerInitiatedInteraction) RequestCredentialWithPreAuthV2(
 	vm *api.VerificationMethod, opts *RequestCredentialWithPreAuthOpts,
 ) (*verifiable.CredentialsArrayV2, error) {
@@ -126,6 +131,29 @@ func (i *IssuerInitiatedInteraction) RequestCredentialWithPreAuthV2(
 	return toGomobileCredentialsV2(credentials, configIDs), nil
 }
 
+// issuedConfigIDs returns the offered config IDs, excluding the ones that the issuer deferred.
+func issuedConfigIDs(offeredConfigIDs []string, pendingIssuances []*openid4cigoapi.PendingIssuance) []string {
+	pendingCount := make(map[string]int)
+
+	for _, pendingIssuance := range pendingIssuances {
+		pendingCount[pendingIssuance.CredentialConfigurationID]++
+	}
+
+	configIDs := make([]string, 0, len(offeredConfigIDs))
+
+	for _, configID := range offeredConfigIDs {
+		if pendingCount[configID] > 0 {
+			pendingCount[configID]--
+
+			continue
+		}
+
+		configIDs = append(configIDs, configID)
+	}
+
+	return configIDs
+}
+
 func (i *IssuerInitiatedInteraction) requestCredentialWithPreAuth(
 	vm *api.VerificationMethod, opts *RequestCredentialWithPreAuthOpts,
 ) ([]*verifiableapi.Credential, []string, error) {
@@ -138,7 +166,10 @@ func (i *IssuerInitiatedInteraction) requestCredentialWithPreAuth(
 		return nil, nil, wrapper.ToMobileErrorWithTrace(err, i.oTel)
 	}
 
-	goOpts := []openid4cigoapi.RequestCredentialWithPreAuthOpt{openid4cigoapi.WithPIN(opts.pin)}
+	goOpts := []openid4cigoapi.RequestCredentialWithPreAuthOpt{
+		openid4cigoapi.WithPIN(opts.pin),
+		openid4cigoapi.WithClientID(opts.clientID),
+	}
 
 	if opts.attestationVM != nil {
 		attestationSigner, attErr := createSigner(opts.attestationVM, i.crypto)
@@ -149,12 +180,25 @@ func (i *IssuerInitiatedInteraction) requestCredentialWithPreAuth(
 		goOpts = append(goOpts, openid4cigoapi.WithAttestationVC(attestationSigner, opts.attestationVC))
 	}
 
-	credentials, err := i.goAPIInteraction.RequestCredentialWithPreAuth(signer, goOpts...)
+	if opts.batchIssuance != nil {
+		keyWriter, crypto := opts.batchIssuance.goAPIKMS()
+
+		goOpts = append(goOpts, openid4cigoapi.WithBatchIssuance(opts.batchIssuance.copies, keyWriter, crypto,
+			kmsspi.KeyType(opts.batchIssuance.keyType)))
+	}
+
+	credentials, err := i.goAPIInteraction.RequestCredentialWithPreAuthContext(api.Context(i.cancelHandle), signer,
+		goOpts...)
 	if err != nil {
 		return nil, nil, wrapper.ToMobileErrorWithTrace(err, i.oTel)
 	}
 
-	configIDs := i.goAPIInteraction.CredentialConfigIDs()
+	configIDs := issuedConfigIDs(i.goAPIInteraction.GrantedCredentialConfigIDs(),
+		i.goAPIInteraction.PendingIssuances())
+
+	if opts.batchIssuance != nil {
+		configIDs = batchedConfigIDs(i.goAPIInteraction.CredentialBatches())
+	}
 
 	if len(credentials) != len(configIDs) {
 		return nil, nil, fmt.Errorf("mismatch in the number of credentials and configuration IDs: "+
@@ -173,14 +217,15 @@ func (i *IssuerInitiatedInteraction) requestCredentialWithPreAuth(
 // CreateAuthorizationURL, except that now it has some URL query parameters appended to it.
 func (i *IssuerInitiatedInteraction) RequestCredentialWithAuth(vm *api.VerificationMethod,
 	redirectURIWithAuthCode string,
-	opts *RequestCredentialWithAuthOpts, //nolint: revive // The opts param is reserved for future use.
+	opts *RequestCredentialWithAuthOpts,
 ) (*verifiable.CredentialsArray, error) {
 	signer, err := createSigner(vm, i.crypto)
 	if err != nil {
 		return nil, wrapper.ToMobileErrorWithTrace(err, i.oTel)
 	}
 
-	credentials, err := i.goAPIInteraction.RequestCredentialWithAuth(signer, redirectURIWithAuthCode)
+	credentials, err := i.goAPIInteraction.RequestCredentialWithAuthContext(api.Context(i.cancelHandle), signer,
+		redirectURIWithAuthCode, opts.goAPIOpts()...)
 	if err != nil {
 		return nil, wrapper.ToMobileErrorWithTrace(err, i.oTel)
 	}
@@ -188,6 +233,46 @@ func (i *IssuerInitiatedInteraction) RequestCredentialWithAuth(vm *api.Verificat
 	return toGomobileCredentials(credentials), nil
 }
 
+// GrantedCredentialConfigIDs returns the IDs of the offered credential configurations that the authorization
+// server granted access to. This may be a subset of the offered credential configurations, in which case only the
+// granted credentials are returned when requesting credentials.
+// An empty array is returned if no access token has been obtained yet.
+func (i *IssuerInitiatedInteraction) GrantedCredentialConfigIDs() *api.StringArray {
+	return &api.StringArray{Strings: i.goAPIInteraction.GrantedCredentialConfigIDs()}
+}
+
+// GrantedCredentialIdentifiers returns the credential identifiers that the issuer listed in its token response for
+// the offered credential configuration with the given ID. Each credential identifier refers to a distinct credential
+// dataset that can be issued. An empty array is returned if the issuer didn't provide any credential identifiers for
+// the given credential configuration.
+func (i *IssuerInitiatedInteraction) GrantedCredentialIdentifiers(configID string) *api.StringArray {
+	return &api.StringArray{Strings: i.goAPIInteraction.GrantedCredentialIdentifiers(configID)}
+}
+
+// IssuanceGrant returns an IssuanceGrant that can be serialized, stored, and later used with the RenewCredentials
+// function to request the same credentials again without any user interaction.
+// It can only be called after credentials have been requested, and only if the issuer provided a refresh token.
+func (i *IssuerInitiatedInteraction) IssuanceGrant() (*IssuanceGrant, error) {
+	issuanceGrant, err := i.goAPIInteraction.IssuanceGrant()
+	if err != nil {
+		return nil, wrapper.ToMobileErrorWithTrace(err, i.oTel)
+	}
+
+	return &IssuanceGrant{issuanceGrant: issuanceGrant}, nil
+}
+
+// CredentialBatches returns the copies of each credential that were requested during the last credential request
+// using the SetBatchIssuance option. It's empty if batch issuance wasn't used.
+func (i *IssuerInitiatedInteraction) CredentialBatches() *CredentialBatches {
+	return &CredentialBatches{batches: i.goAPIInteraction.CredentialBatches()}
+}
+
+// PendingIssuances returns the credentials that the issuer deferred during the last credential request.
+// Each PendingIssuance can be serialized and later redeemed using the RequestDeferredCredential function.
+func (i *IssuerInitiatedInteraction) PendingIssuances() *PendingIssuances {
+	return &PendingIssuances{pendingIssuances: i.goAPIInteraction.PendingIssuances()}
+}
+
 // IssuerURI returns the issuer's URI from the initiation request. It's useful to store this somewhere in case
 // there's a later need to refresh credential display data using the latest display information from the issuer.
 func (i *IssuerInitiatedInteraction) IssuerURI() string {
@@ -237,7 +322,7 @@ func (i *IssuerInitiatedInteraction) AuthorizationCodeGrantParams() (*Authorizat
 
 // DynamicClientRegistrationSupported indicates whether the issuer supports dynamic client registration.
 func (i *IssuerInitiatedInteraction) DynamicClientRegistrationSupported() (bool, error) {
-	supported, err := i.goAPIInteraction.DynamicClientRegistrationSupported()
+	supported, err := i.goAPIInteraction.DynamicClientRegistrationSupportedContext(api.Context(i.cancelHandle))
 	if err != nil {
 		return false, wrapper.ToMobileErrorWithTrace(err, i.oTel)
 	}
@@ -250,7 +335,7 @@ func (i *IssuerInitiatedInteraction) DynamicClientRegistrationSupported() (bool,
 // if DynamicClientRegistrationSupported returns true.
 // This method will return an error if the issuer does not support dynamic client registration.
 func (i *IssuerInitiatedInteraction) DynamicClientRegistrationEndpoint() (string, error) {
-	endpoint, err := i.goAPIInteraction.DynamicClientRegistrationEndpoint()
+	endpoint, err := i.goAPIInteraction.DynamicClientRegistrationEndpointContext(api.Context(i.cancelHandle))
 	if err != nil {
 		return "", wrapper.ToMobileErrorWithTrace(err, i.oTel)
 	}
@@ -265,7 +350,7 @@ func (i *IssuerInitiatedInteraction) OfferedCredentialsTypes() *api.StringArrayA
 
 // IssuerMetadata returns the issuer's metadata.
 func (i *IssuerInitiatedInteraction) IssuerMetadata() (*IssuerMetadata, error) {
-	goAPIIssuerMetadata, err := i.goAPIInteraction.IssuerMetadata()
+	goAPIIssuerMetadata, err := i.goAPIInteraction.IssuerMetadataContext(api.Context(i.cancelHandle))
 	if err != nil {
 		return nil, wrapper.ToMobileErrorWithTrace(err, i.oTel)
 	}
@@ -277,7 +362,7 @@ func (i *IssuerInitiatedInteraction) IssuerMetadata() (*IssuerMetadata, error) {
 // An error means that either the issuer failed the verification check, or something went wrong during the
 // process (and so a verification status could not be determined).
 func (i *IssuerInitiatedInteraction) VerifyIssuer() (string, error) {
-	serviceURL, err := i.goAPIInteraction.VerifyIssuer()
+	serviceURL, err := i.goAPIInteraction.VerifyIssuerContext(api.Context(i.cancelHandle))
 	if err != nil {
 		return "", wrapper.ToMobileErrorWithTrace(err, i.oTel)
 	}
@@ -287,7 +372,7 @@ func (i *IssuerInitiatedInteraction) VerifyIssuer() (string, error) {
 
 // IssuerTrustInfo returns issuer trust info like, did, domain, credential type, format.
 func (i *IssuerInitiatedInteraction) IssuerTrustInfo() (*IssuerTrustInfo, error) {
-	trustInfo, err := i.goAPIInteraction.IssuerTrustInfo()
+	trustInfo, err := i.goAPIInteraction.IssuerTrustInfoContext(api.Context(i.cancelHandle))
 	if err != nil {
 		return nil, wrapper.ToMobileErrorWithTrace(err, i.oTel)
 	}
@@ -306,9 +391,11 @@ func (i *IssuerInitiatedInteraction) IssuerTrustInfo() (*IssuerTrustInfo, error)
 	}
 
 	return &IssuerTrustInfo{
-		DID:              trustInfo.DID,
-		Domain:           trustInfo.Domain,
-		CredentialOffers: credentialOffers,
+		DID:                trustInfo.DID,
+		Domain:             trustInfo.Domain,
+		CredentialOffers:   credentialOffers,
+		MetadataSigned:     trustInfo.MetadataSigned,
+		CertificateSubject: trustInfo.CertificateSubject,
 	}, nil
 }
 
@@ -323,6 +410,18 @@ func (i *IssuerInitiatedInteraction) OTelTraceID() string {
 	return traceID
 }
 
+// NotificationID returns the notification ID that the issuer returned along with the given credential, which must be
+// one of the credentials returned by this interaction. It identifies the credential when calling
+// Acknowledgment.NotifyIssuer, so it should be stored along with the credential. An empty string is returned if the
+// issuer didn't return a notification ID for the credential.
+func (i *IssuerInitiatedInteraction) NotificationID(credential *verifiable.Credential) string {
+	if credential == nil {
+		return ""
+	}
+
+	return i.goAPIInteraction.NotificationID(credential.VC)
+}
+
 // RequireAcknowledgment if true indicates that the issuer requires to be acknowledged if
 // the user accepts or rejects credentials.
 func (i *IssuerInitiatedInteraction) RequireAcknowledgment() (bool, error) {
@@ -339,5 +438,6 @@ func (i *IssuerInitiatedInteraction) Acknowledgment() (*Acknowledgment, error) {
 
 	return &Acknowledgment{
 		acknowledgment: acknowledgment,
+		cancelHandle:   i.cancelHandle,
 	}, nil
 }
@@ -140,6 +140,7 @@ type mockIssuerServerHandler struct {
 	ackRequestExpectInteractionDetails                bool
 	ackRequestExpectedAmount                          int
 	credentialResponse                                []byte
+	tokenResponse                                     []byte
 	headersToCheck                                    *api.Headers
 }
 
@@ -166,6 +167,9 @@ func (m *mockIssuerServerHandler) ServeHTTP(writer http.ResponseWriter, //nolint
 			_, err = writer.Write([]byte("test failure"))
 		case m.tokenRequestShouldGiveUnmarshallableResponse:
 			_, err = writer.Write([]byte("invalid"))
+		case m.tokenResponse != nil:
+			writer.Header().Set("Content-Type", "application/json")
+			_, err = writer.Write(m.tokenResponse)
 		default:
 			writer.Header().Set("Content-Type", "application/json")
 			_, err = writer.Write([]byte(sampleTokenResponse))
@@ -253,6 +257,19 @@ func TestIssuerInitiatedInteraction_CreateAuthorizationURL(t *testing.T) {
 		requireErrorContains(t, err, "issuer does not support the authorization code grant type")
 		require.Empty(t, authorizationLink)
 	})
+	t.Run("Pushed authorization request required, but not supported", func(t *testing.T) {
+		kms, err := localkms.NewKMS(localkms.NewMemKMSStore())
+		require.NoError(t, err)
+
+		interaction := createIssuerInitiatedInteraction(t, kms, nil, nil,
+			createCredentialOfferIssuanceURI(t, server.URL, true), nil, false)
+
+		opts := openid4ci.NewCreateAuthorizationURLOpts().UsePushedAuthorizationRequest()
+
+		authorizationLink, err := interaction.CreateAuthorizationURL("clientID", "redirectURI", opts)
+		requireErrorContains(t, err, "PUSHED_AUTHORIZATION_REQUEST_FAILED")
+		require.Empty(t, authorizationLink)
+	})
 }
 
 func TestIssuerInitiatedInteraction_RequestCredential(t *testing.T) {
@@ -344,6 +361,41 @@ func TestIssuerInitiatedInteraction_RequestCredential(t *testing.T) {
 		require.NoError(t, err)
 		require.NotNil(t, credentials)
 	})
+	t.Run("Batch issuance", func(t *testing.T) {
+		requestURI := createCredentialOfferIssuanceURI(t, server.URL, false)
+
+		kms, err := localkms.NewKMS(localkms.NewMemKMSStore())
+		require.NoError(t, err)
+
+		interactionRequiredArgs, interactionOptionalArgs := getTestArgs(t, requestURI, kms, nil, nil, nil, false)
+
+		interaction, err := openid4ci.NewIssuerInitiatedInteraction(interactionRequiredArgs, interactionOptionalArgs)
+		require.NoError(t, err)
+
+		keyHandle, err := kms.Create(arieskms.ED25519)
+		require.NoError(t, err)
+
+		verificationMethod := &api.VerificationMethod{
+			ID:   mockKeyID,
+			Type: "JsonWebKey2020",
+			Key:  models.VerificationKey{JSONWebKey: keyHandle.JWK},
+		}
+
+		credentials, err := interaction.RequestCredentialWithPreAuthV2(verificationMethod,
+			openid4ci.NewRequestCredentialWithPreAuthOpts().SetPIN("1234").
+				SetBatchIssuance(2, kms, arieskms.ED25519))
+		require.NoError(t, err)
+		require.Equal(t, 2, credentials.Length())
+
+		batches := interaction.CredentialBatches()
+		require.Equal(t, 1, batches.Length())
+		require.Nil(t, batches.AtIndex(1))
+
+		batch := batches.AtIndex(0)
+		require.Equal(t, batch.CredentialConfigurationID(), credentials.ConfigIDAtIndex(1))
+		require.Equal(t, 2, batch.Credentials().Length())
+		require.Equal(t, 2, batch.HolderKeyIDs().Length())
+	})
 
 	t.Run("attestation invalid VC", func(t *testing.T) {
 		requestURI := createCredentialOfferIssuanceURI(t, server.URL, false)
@@ -396,6 +448,8 @@ func TestIssuerInitiatedInteraction_RequestCredential(t *testing.T) {
 			"redirectURIWithAuthCode", nil)
 		requireErrorContains(t, err, "authorization URL must be created first")
 		require.Nil(t, credentials)
+		require.Zero(t, interaction.GrantedCredentialConfigIDs().Length())
+		require.Zero(t, interaction.GrantedCredentialIdentifiers("credential_configuration_id_1").Length())
 	})
 }
 
@@ -446,6 +500,51 @@ func TestIssuerInitiatedInteraction_GrantTypes(t *testing.T) {
 	require.Equal(t, "1234", issuerState)
 }
 
+func TestIssuerInitiatedInteraction_ClientIDRequired(t *testing.T) {
+	issuerServerHandler := &mockIssuerServerHandler{
+		t:                  t,
+		credentialResponse: sampleCredentialResponse,
+	}
+
+	server := httptest.NewServer(issuerServerHandler)
+	defer server.Close()
+
+	metadata := strings.ReplaceAll(sampleIssuerMetadata, serverURLPlaceholder, server.URL)
+	issuerServerHandler.issuerMetadata = modifyCredentialMetadata(t, metadata, func(m *issuer.Metadata) {
+		anonymousAccessSupported := false
+		m.PreAuthorizedGrantAnonymousAccessSupported = &anonymousAccessSupported
+	})
+
+	kms, err := localkms.NewKMS(localkms.NewMemKMSStore())
+	require.NoError(t, err)
+
+	interaction := createIssuerInitiatedInteraction(t, kms, nil, nil,
+		createCredentialOfferIssuanceURI(t, server.URL, false), nil, false)
+
+	preAuthorizedCodeGrantParams, err := interaction.PreAuthorizedCodeGrantParams()
+	require.NoError(t, err)
+	require.True(t, preAuthorizedCodeGrantParams.ClientIDRequired())
+
+	keyHandle, err := kms.Create(arieskms.ED25519)
+	require.NoError(t, err)
+
+	verificationMethod := &api.VerificationMethod{
+		ID:   mockKeyID,
+		Type: "JsonWebKey2020",
+		Key:  models.VerificationKey{JSONWebKey: keyHandle.JWK},
+	}
+
+	credentials, err := interaction.RequestCredentialWithPreAuth(verificationMethod,
+		openid4ci.NewRequestCredentialWithPreAuthOpts().SetPIN("1234"))
+	requireErrorContains(t, err, "INVALID_SDK_USAGE")
+	require.Nil(t, credentials)
+
+	credentials, err = interaction.RequestCredentialWithPreAuth(verificationMethod,
+		openid4ci.NewRequestCredentialWithPreAuthOpts().SetPIN("1234").SetClientID("clientID"))
+	require.NoError(t, err)
+	require.NotNil(t, credentials)
+}
+
 func TestIssuerInitiatedInteraction_DynamicClientRegistration(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		issuerServerHandler := &mockIssuerServerHandler{
@@ -789,6 +888,58 @@ func TestNewRequestedAcknowledgment(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestAcknowledgment_SetDPoPSigningKey(t *testing.T) {
+	kms, err := localkms.NewKMS(localkms.NewMemKMSStore())
+	require.NoError(t, err)
+
+	keyHandle, err := kms.Create(arieskms.ED25519)
+	require.NoError(t, err)
+
+	acknowledgment, err := openid4ci.NewAcknowledgment(`{"ack_ids":["ack_id1"]}`)
+	require.NoError(t, err)
+
+	err = acknowledgment.SetDPoPSigningKey(&api.VerificationMethod{
+		ID:   mockKeyID,
+		Type: "JsonWebKey2020",
+		Key:  models.VerificationKey{JSONWebKey: keyHandle.JWK},
+	}, kms.GetCrypto())
+	require.NoError(t, err)
+
+	err = acknowledgment.SetDPoPSigningKey(nil, kms.GetCrypto())
+	requireErrorContains(t, err, "verification method must be provided")
+}
+
+func TestAcknowledgment_NotifyIssuer(t *testing.T) {
+	var notifications []map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		var notification map[string]interface{}
+
+		assert.NoError(t, json.NewDecoder(request.Body).Decode(&notification))
+
+		notifications = append(notifications, notification)
+
+		writer.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	acknowledgment, err := openid4ci.NewAcknowledgment(fmt.Sprintf(
+		`{"ack_ids":["ack_id1"],"credential_ack_endpoint":"%s","auth_token":{"access_token":"token",`+
+			`"token_type":"Bearer"}}`, server.URL))
+	require.NoError(t, err)
+
+	err = acknowledgment.NotifyIssuer("ack_id1", openid4ci.EventCredentialDeleted, "Deleted by the user")
+	require.NoError(t, err)
+
+	require.Len(t, notifications, 1)
+	require.Equal(t, "ack_id1", notifications[0]["notification_id"])
+	require.Equal(t, "credential_deleted", notifications[0]["event"])
+	require.Equal(t, "Deleted by the user", notifications[0]["event_description"])
+
+	err = acknowledgment.NotifyIssuer("ack_id1", "credential_lost", "")
+	requireErrorContains(t, err, "unsupported notification event")
+}
+
 func createIssuerInitiatedInteraction(t *testing.T, kms *localkms.KMS, activityLogger api.ActivityLogger,
 	metricsLogger api.MetricsLogger, requestURI string, additionalHeaders *api.Headers, disableTLSVerification bool,
 ) *openid4ci.IssuerInitiatedInteraction {
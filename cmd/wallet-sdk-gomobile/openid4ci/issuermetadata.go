@@ -55,6 +55,11 @@ type IssuerTrustInfo struct {
 	DID              string
 	Domain           string
 	CredentialOffers []*CredentialOffer
+	// Indicates whether the issuer's metadata was signed.
+	MetadataSigned bool
+	// The subject of the X.509 certificate that the issuer's metadata was signed with. Only set if the metadata was
+	// signed using an x5c certificate chain.
+	CertificateSubject string
 }
 
 // CredentialOffer contains data related to a credential type being offered in an issuance request.
@@ -0,0 +1,63 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
+	openid4cigoapi "github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+// MetadataVerificationPolicy controls which issuer metadata is accepted. Signed metadata is always rejected if its
+// signature is invalid.
+type MetadataVerificationPolicy struct {
+	requireSignedMetadata bool
+	allowedDIDs           []string
+	trustAnchors          *api.TrustAnchors
+}
+
+// NewMetadataVerificationPolicy returns a new MetadataVerificationPolicy. By default, unsigned metadata is accepted,
+// and signed metadata is accepted from any DID but never from an x5c certificate chain.
+func NewMetadataVerificationPolicy() *MetadataVerificationPolicy {
+	return &MetadataVerificationPolicy{}
+}
+
+// RequireSignedMetadata causes issuer metadata without a signed_metadata parameter to be rejected.
+func (p *MetadataVerificationPolicy) RequireSignedMetadata() *MetadataVerificationPolicy {
+	p.requireSignedMetadata = true
+
+	return p
+}
+
+// AddAllowedDID adds a DID that may sign issuer metadata. Once at least one DID has been added, metadata signed with
+// a DID-based kid is only accepted if it's from one of the allowed DIDs.
+func (p *MetadataVerificationPolicy) AddAllowedDID(did string) *MetadataVerificationPolicy {
+	p.allowedDIDs = append(p.allowedDIDs, did)
+
+	return p
+}
+
+// SetTrustAnchors sets the trust anchors that the certificate chains (from the x5c header) of signed metadata are
+// verified against. If no trust anchors are set, then the x5c header is ignored and signed metadata is verified using
+// its kid header instead.
+func (p *MetadataVerificationPolicy) SetTrustAnchors(trustAnchors *api.TrustAnchors) *MetadataVerificationPolicy {
+	p.trustAnchors = trustAnchors
+
+	return p
+}
+
+func (p *MetadataVerificationPolicy) toGoAPIPolicy() *openid4cigoapi.MetadataVerificationPolicy {
+	goAPIPolicy := &openid4cigoapi.MetadataVerificationPolicy{
+		RequireSignedMetadata: p.requireSignedMetadata,
+		AllowedDIDs:           p.allowedDIDs,
+	}
+
+	if p.trustAnchors != nil {
+		goAPIPolicy.TrustAnchors = p.trustAnchors.Certificates
+	}
+
+	return goAPIPolicy
+}
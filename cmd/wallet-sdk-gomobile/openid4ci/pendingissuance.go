@@ -0,0 +1,214 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/verifiable"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/wrapper"
+	openid4cigoapi "github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+// PendingIssuance represents a credential that the issuer was unable to issue immediately.
+// It can be serialized, stored, and later redeemed using the RequestDeferredCredential function.
+type PendingIssuance struct {
+	pendingIssuance *openid4cigoapi.PendingIssuance
+}
+
+// NewPendingIssuance recreates a PendingIssuance object from its serialized state.
+func NewPendingIssuance(serialized string) (*PendingIssuance, error) {
+	pendingIssuance := &openid4cigoapi.PendingIssuance{}
+
+	err := json.Unmarshal([]byte(serialized), pendingIssuance)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pending issuance json structure: %w", err)
+	}
+
+	return &PendingIssuance{pendingIssuance: pendingIssuance}, nil
+}
+
+// Serialize serializes the PendingIssuance object so it can be restored later.
+func (p *PendingIssuance) Serialize() (string, error) {
+	data, err := json.Marshal(p.pendingIssuance)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// TransactionID returns the transaction ID that the issuer assigned to this pending issuance.
+func (p *PendingIssuance) TransactionID() string {
+	return p.pendingIssuance.TransactionID
+}
+
+// CredentialConfigurationID returns the ID of the credential configuration that was requested, if known.
+func (p *PendingIssuance) CredentialConfigurationID() string {
+	return p.pendingIssuance.CredentialConfigurationID
+}
+
+// IssuerURI returns the URI of the issuer that deferred the issuance.
+func (p *PendingIssuance) IssuerURI() string {
+	return p.pendingIssuance.IssuerURI
+}
+
+// IntervalSeconds returns the minimum amount of time in seconds that the wallet should wait before requesting
+// the credential. It's updated each time the issuer reports that issuance is still pending.
+func (p *PendingIssuance) IntervalSeconds() int {
+	return p.pendingIssuance.Interval
+}
+
+// NotificationID returns the notification ID that the issuer returned along with the credential. It's only set once
+// the credential has been issued by RequestDeferredCredential, and only if the issuer returned one.
+func (p *PendingIssuance) NotificationID() string {
+	return p.pendingIssuance.NotificationID
+}
+
+// Acknowledgment returns an Acknowledgment that can be used to notify the issuer about the credential once it has
+// been issued by RequestDeferredCredential. It returns an error if the issuer doesn't have a notification endpoint or
+// didn't return a notification ID for the credential.
+func (p *PendingIssuance) Acknowledgment() (*Acknowledgment, error) {
+	acknowledgment, err := p.pendingIssuance.Acknowledgment()
+	if err != nil {
+		return nil, wrapper.ToMobileError(err)
+	}
+
+	return &Acknowledgment{acknowledgment: acknowledgment}, nil
+}
+
+// PendingIssuances represents a set of PendingIssuance objects.
+type PendingIssuances struct {
+	pendingIssuances []*openid4cigoapi.PendingIssuance
+}
+
+// Length returns the number of PendingIssuance objects contained within this PendingIssuances object.
+func (p *PendingIssuances) Length() int {
+	return len(p.pendingIssuances)
+}
+
+// AtIndex returns the PendingIssuance at the given index.
+// If the index passed in is out of bounds, then nil is returned.
+func (p *PendingIssuances) AtIndex(index int) *PendingIssuance {
+	maxIndex := len(p.pendingIssuances) - 1
+	if index > maxIndex || index < 0 {
+		return nil
+	}
+
+	return &PendingIssuance{pendingIssuance: p.pendingIssuances[index]}
+}
+
+// RequestDeferredCredentialOpts contains all optional arguments that can be passed into the
+// RequestDeferredCredential function.
+type RequestDeferredCredentialOpts struct {
+	interactionOpts *InteractionOpts
+	pollingTimeout  time.Duration
+	dpopVM          *api.VerificationMethod
+	dpopCrypto      api.Crypto
+}
+
+// NewRequestDeferredCredentialOpts returns a new RequestDeferredCredentialOpts object.
+func NewRequestDeferredCredentialOpts() *RequestDeferredCredentialOpts {
+	return &RequestDeferredCredentialOpts{}
+}
+
+// SetInteractionOpts sets the options (HTTP timeout, headers, document loader, etc.) to use when requesting the
+// deferred credential and parsing it.
+func (r *RequestDeferredCredentialOpts) SetInteractionOpts(
+	interactionOpts *InteractionOpts,
+) *RequestDeferredCredentialOpts {
+	r.interactionOpts = interactionOpts
+
+	return r
+}
+
+// SetPollingTimeoutNanoseconds causes RequestDeferredCredential to keep polling the issuer for up to the given
+// duration (in nanoseconds) while the issuer reports that issuance is still pending.
+// By default, only a single request is made.
+func (r *RequestDeferredCredentialOpts) SetPollingTimeoutNanoseconds(timeout int64) *RequestDeferredCredentialOpts {
+	r.pollingTimeout = time.Duration(timeout)
+
+	return r
+}
+
+// SetDPoPSigningKey sets the key to use for DPoP proofs. It's required if the issuer issued a DPoP-bound access
+// token, in which case it must be the same key that was used to request the credential originally.
+func (r *RequestDeferredCredentialOpts) SetDPoPSigningKey(vm *api.VerificationMethod,
+	crypto api.Crypto,
+) *RequestDeferredCredentialOpts {
+	r.dpopVM = vm
+	r.dpopCrypto = crypto
+
+	return r
+}
+
+// RequestDeferredCredential requests a credential whose issuance was previously deferred by the issuer.
+// If the issuer reports that the credential is still not ready, then an ISSUANCE_PENDING error is returned and the
+// interval of the given PendingIssuance is updated. In that case, the PendingIssuance should be serialized again
+// and the request retried later.
+// If the issuer returns more than one credential, then an error is returned. Use RequestDeferredCredentials to
+// receive all of them.
+func RequestDeferredCredential(pendingIssuance *PendingIssuance, didResolver api.DIDResolver,
+	opts *RequestDeferredCredentialOpts,
+) (*verifiable.Credential, error) {
+	credentials, err := RequestDeferredCredentials(pendingIssuance, didResolver, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if credentials.Length() > 1 {
+		return nil, wrapper.ToMobileError(walleterror.NewInvalidSDKUsageError(openid4cigoapi.ErrorModule,
+			fmt.Errorf("the issuer returned %d credentials, but only one was expected: use "+
+				"RequestDeferredCredentials to receive all of them", credentials.Length())))
+	}
+
+	return credentials.AtIndex(0), nil
+}
+
+// RequestDeferredCredentials is the same as RequestDeferredCredential, but returns all the credentials that the
+// issuer returns, which may be several (e.g. copies of the same credential bound to different keys).
+func RequestDeferredCredentials(pendingIssuance *PendingIssuance, didResolver api.DIDResolver,
+	opts *RequestDeferredCredentialOpts,
+) (*verifiable.CredentialsArray, error) {
+	if pendingIssuance == nil {
+		return nil, wrapper.ToMobileError(walleterror.NewInvalidSDKUsageError(
+			openid4cigoapi.ErrorModule, errors.New("pending issuance object must be provided")))
+	}
+
+	if opts == nil {
+		opts = NewRequestDeferredCredentialOpts()
+	}
+
+	goAPIClientConfig, oTel, err := createGoAPIClientConfigWithTrace(didResolver, opts.interactionOpts)
+	if err != nil {
+		return nil, wrapper.ToMobileErrorWithTrace(err, oTel)
+	}
+
+	goAPIOpts := []openid4cigoapi.RequestDeferredCredentialOpt{openid4cigoapi.WithPollingTimeout(opts.pollingTimeout)}
+
+	if opts.dpopVM != nil {
+		signer, signerErr := createSigner(opts.dpopVM, opts.dpopCrypto)
+		if signerErr != nil {
+			return nil, wrapper.ToMobileErrorWithTrace(signerErr, oTel)
+		}
+
+		goAPIOpts = append(goAPIOpts, openid4cigoapi.WithDPoPSigner(signer))
+	}
+
+	credentials, err := openid4cigoapi.RequestDeferredCredentialsContext(
+		api.Context(opts.interactionOpts.getCancelHandle()), pendingIssuance.pendingIssuance, goAPIClientConfig, goAPIOpts...)
+	if err != nil {
+		return nil, wrapper.ToMobileErrorWithTrace(err, oTel)
+	}
+
+	return toGomobileCredentials(credentials), nil
+}
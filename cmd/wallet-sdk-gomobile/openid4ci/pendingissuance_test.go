@@ -0,0 +1,155 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	arieskms "github.com/trustbloc/kms-go/spi/kms"
+
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/localkms"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/openid4ci"
+	"github.com/trustbloc/wallet-sdk/internal/testutil"
+	"github.com/trustbloc/wallet-sdk/pkg/models"
+	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
+)
+
+type mockDeferredCredentialHandler struct {
+	t                *testing.T
+	issuancePending  bool
+	credentialResult []byte
+}
+
+func (m *mockDeferredCredentialHandler) ServeHTTP(writer http.ResponseWriter, _ *http.Request) {
+	var err error
+
+	if m.issuancePending {
+		writer.WriteHeader(http.StatusBadRequest)
+		_, err = writer.Write([]byte(`{"error":"issuance_pending","interval":30}`))
+	} else {
+		_, err = writer.Write(m.credentialResult)
+	}
+
+	assert.NoError(m.t, err)
+}
+
+func TestRequestDeferredCredential(t *testing.T) {
+	deferredHandler := &mockDeferredCredentialHandler{t: t, credentialResult: sampleCredentialResponse}
+
+	deferredServer := httptest.NewServer(deferredHandler)
+	defer deferredServer.Close()
+
+	issuerServerHandler := &mockIssuerServerHandler{
+		t:                  t,
+		credentialResponse: []byte(`{"transaction_id":"8xLOxBtZp8"}`),
+	}
+
+	server := httptest.NewServer(issuerServerHandler)
+	defer server.Close()
+
+	issuerMetadata := modifyCredentialMetadata(t, sampleIssuerMetadata, func(m *issuer.Metadata) {
+		m.DeferredCredentialEndpoint = deferredServer.URL
+	})
+
+	issuerServerHandler.issuerMetadata = strings.ReplaceAll(issuerMetadata, serverURLPlaceholder, server.URL)
+
+	kms, err := localkms.NewKMS(localkms.NewMemKMSStore())
+	require.NoError(t, err)
+
+	interaction := createIssuerInitiatedInteraction(t, kms, nil, nil,
+		createCredentialOfferIssuanceURI(t, server.URL, false), nil, false)
+
+	keyHandle, err := kms.Create(arieskms.ED25519)
+	require.NoError(t, err)
+
+	verificationMethod := &api.VerificationMethod{
+		ID:   mockKeyID,
+		Type: "JsonWebKey2020",
+		Key:  models.VerificationKey{JSONWebKey: keyHandle.JWK},
+	}
+
+	credentials, err := interaction.RequestCredentialWithPreAuthV2(verificationMethod,
+		openid4ci.NewRequestCredentialWithPreAuthOpts().SetPIN("1234"))
+	require.NoError(t, err)
+	require.Zero(t, credentials.Length())
+
+	pendingIssuances := interaction.PendingIssuances()
+	require.Equal(t, 1, pendingIssuances.Length())
+	require.Nil(t, pendingIssuances.AtIndex(1))
+
+	serialized, err := pendingIssuances.AtIndex(0).Serialize()
+	require.NoError(t, err)
+
+	pendingIssuance, err := openid4ci.NewPendingIssuance(serialized)
+	require.NoError(t, err)
+	require.Equal(t, "8xLOxBtZp8", pendingIssuance.TransactionID())
+	require.Equal(t, "PermanentResidentCard_jwt_vc_json-ld_v1", pendingIssuance.CredentialConfigurationID())
+	require.Equal(t, server.URL, pendingIssuance.IssuerURI())
+
+	opts := openid4ci.NewRequestDeferredCredentialOpts().SetInteractionOpts(
+		openid4ci.NewInteractionOpts().DisableVCProofChecks().
+			SetDocumentLoader(&documentLoaderWrapper{DocumentLoader: testutil.DocumentLoader(t)}))
+
+	t.Run("Issuance pending", func(t *testing.T) {
+		deferredHandler.issuancePending = true
+
+		credential, err := openid4ci.RequestDeferredCredential(pendingIssuance, &mockResolver{keyWriter: kms},
+			openid4ci.NewRequestDeferredCredentialOpts().SetPollingTimeoutNanoseconds(time.Millisecond.Nanoseconds()))
+		requireErrorContains(t, err, "ISSUANCE_PENDING")
+		require.Nil(t, credential)
+		require.Equal(t, 30, pendingIssuance.IntervalSeconds())
+	})
+	t.Run("Credential is ready", func(t *testing.T) {
+		deferredHandler.issuancePending = false
+
+		credential, err := openid4ci.RequestDeferredCredential(pendingIssuance, &mockResolver{keyWriter: kms}, opts)
+		require.NoError(t, err)
+		require.NotNil(t, credential)
+
+		// The sample credential response doesn't contain a notification ID.
+		require.Empty(t, pendingIssuance.NotificationID())
+
+		acknowledgment, err := pendingIssuance.Acknowledgment()
+		requireErrorContains(t, err, "no notification ID")
+		require.Nil(t, acknowledgment)
+	})
+	t.Run("All credentials", func(t *testing.T) {
+		credentials, err := openid4ci.RequestDeferredCredentials(pendingIssuance, &mockResolver{keyWriter: kms}, opts)
+		require.NoError(t, err)
+		require.Equal(t, 1, credentials.Length())
+		require.NotNil(t, credentials.AtIndex(0))
+	})
+	t.Run("With a DPoP signing key", func(t *testing.T) {
+		credential, err := openid4ci.RequestDeferredCredential(pendingIssuance, &mockResolver{keyWriter: kms},
+			opts.SetDPoPSigningKey(verificationMethod, kms.GetCrypto()))
+		require.NoError(t, err)
+		require.NotNil(t, credential)
+	})
+	t.Run("Invalid DPoP signing key", func(t *testing.T) {
+		credential, err := openid4ci.RequestDeferredCredential(pendingIssuance, &mockResolver{keyWriter: kms},
+			openid4ci.NewRequestDeferredCredentialOpts().SetDPoPSigningKey(
+				&api.VerificationMethod{ID: mockKeyID, Type: "UnsupportedType"}, kms.GetCrypto()))
+		requireErrorContains(t, err, "UNSUPPORTED_ALGORITHM")
+		require.Nil(t, credential)
+	})
+	t.Run("Missing pending issuance", func(t *testing.T) {
+		credential, err := openid4ci.RequestDeferredCredential(nil, &mockResolver{keyWriter: kms}, nil)
+		requireErrorContains(t, err, "pending issuance object must be provided")
+		require.Nil(t, credential)
+	})
+	t.Run("Invalid serialized pending issuance", func(t *testing.T) {
+		_, err := openid4ci.NewPendingIssuance("[")
+		require.Error(t, err)
+	})
+}
@@ -6,7 +6,14 @@ SPDX-License-Identifier: Apache-2.0
 
 package openid4ci
 
-import "github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
+import (
+	kmsspi "github.com/trustbloc/kms-go/spi/kms"
+
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/localkms"
+	goapi "github.com/trustbloc/wallet-sdk/pkg/api"
+	openid4cigoapi "github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
 
 // RequestCredentialWithPreAuthOpts contains all optional arguments that can be passed into the
 // RequestCredentialWithPreAuth method.
@@ -14,6 +21,14 @@ type RequestCredentialWithPreAuthOpts struct {
 	pin           string
 	attestationVM *api.VerificationMethod
 	attestationVC string
+	clientID      string
+	batchIssuance *batchIssuanceOpts
+}
+
+type batchIssuanceOpts struct {
+	copies  int
+	kms     *localkms.KMS
+	keyType string
 }
 
 // NewRequestCredentialWithPreAuthOpts returns a new RequestCredentialWithPreAuthOpts object.
@@ -40,6 +55,69 @@ func (r *RequestCredentialWithPreAuthOpts) SetAttestationVC(
 	return r
 }
 
+// SetClientID is an option for the RequestCredentialWithPreAuth method that allows you to specify the wallet's
+// client ID, which is included in the token request. A client ID is required if the issuer's authorization server
+// doesn't support anonymous access. Check the PreAuthorizedCodeGrantParams object's ClientIDRequired method first to
+// determine this.
+func (r *RequestCredentialWithPreAuthOpts) SetClientID(clientID string) *RequestCredentialWithPreAuthOpts {
+	r.clientID = clientID
+
+	return r
+}
+
+// SetBatchIssuance is an option for the RequestCredentialWithPreAuth method that requests the given number of copies
+// of each credential. Each copy is bound to a new key of the given type, which is created using the given KMS and
+// used through a did:jwk DID. Since the copies are bound to different keys, they can't be linked to each other when a
+// different copy is presented each time. Use the CredentialBatches method afterwards to get the copies along with the
+// keys they're bound to.
+func (r *RequestCredentialWithPreAuthOpts) SetBatchIssuance(copies int, kms *localkms.KMS,
+	keyType string,
+) *RequestCredentialWithPreAuthOpts {
+	r.batchIssuance = &batchIssuanceOpts{copies: copies, kms: kms, keyType: keyType}
+
+	return r
+}
+
 // RequestCredentialWithAuthOpts contains all optional arguments that can be passed into the
 // RequestCredentialWithAuth method.
-type RequestCredentialWithAuthOpts struct{}
+type RequestCredentialWithAuthOpts struct {
+	batchIssuance *batchIssuanceOpts
+}
+
+// NewRequestCredentialWithAuthOpts returns a new RequestCredentialWithAuthOpts object.
+func NewRequestCredentialWithAuthOpts() *RequestCredentialWithAuthOpts {
+	return &RequestCredentialWithAuthOpts{}
+}
+
+// SetBatchIssuance is the equivalent of RequestCredentialWithPreAuthOpts.SetBatchIssuance for the authorization code
+// flow.
+func (r *RequestCredentialWithAuthOpts) SetBatchIssuance(copies int, kms *localkms.KMS,
+	keyType string,
+) *RequestCredentialWithAuthOpts {
+	r.batchIssuance = &batchIssuanceOpts{copies: copies, kms: kms, keyType: keyType}
+
+	return r
+}
+
+func (r *RequestCredentialWithAuthOpts) goAPIOpts() []openid4cigoapi.RequestCredentialWithAuthOpt {
+	if r == nil || r.batchIssuance == nil {
+		return nil
+	}
+
+	keyWriter, crypto := r.batchIssuance.goAPIKMS()
+
+	return []openid4cigoapi.RequestCredentialWithAuthOpt{
+		openid4cigoapi.WithBatchIssuanceForAuth(r.batchIssuance.copies, keyWriter, crypto,
+			kmsspi.KeyType(r.batchIssuance.keyType)),
+	}
+}
+
+// goAPIKMS returns the Go API key writer and crypto to use for the new holder keys. They're nil if no KMS was given,
+// which the Go API reports as an error.
+func (b *batchIssuanceOpts) goAPIKMS() (goapi.KeyWriter, goapi.Crypto) {
+	if b.kms == nil {
+		return nil, nil
+	}
+
+	return b.kms.GoAPILocalKMS, b.kms.GoAPILocalKMS.GetCrypto()
+}
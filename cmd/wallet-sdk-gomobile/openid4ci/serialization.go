@@ -0,0 +1,87 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/wrapper"
+	openid4cigoapi "github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+// Serialize serializes this IssuerInitiatedInteraction so that it can be persisted (e.g. before opening the
+// authorization URL in a browser) and later restored using RestoreIssuerInitiatedInteraction, even if the app was
+// closed in the meantime. The serialized state is encrypted using the given key, which must be a 16, 24 or 32 byte
+// AES key.
+func (i *IssuerInitiatedInteraction) Serialize(key []byte) ([]byte, error) {
+	serializedState, err := i.goAPIInteraction.Serialize(key)
+	if err != nil {
+		return nil, wrapper.ToMobileErrorWithTrace(err, i.oTel)
+	}
+
+	return serializedState, nil
+}
+
+// RestoreIssuerInitiatedInteraction restores an IssuerInitiatedInteraction from a state that was serialized using
+// the Serialize method. The given key must be the same one that was used to serialize it.
+func RestoreIssuerInitiatedInteraction(serializedState, key []byte, crypto api.Crypto,
+	didResolver api.DIDResolver, opts *InteractionOpts,
+) (*IssuerInitiatedInteraction, error) {
+	goAPIClientConfig, oTel, err := createGoAPIClientConfigWithTrace(didResolver, opts)
+	if err != nil {
+		return nil, wrapper.ToMobileErrorWithTrace(err, oTel)
+	}
+
+	goAPIInteraction, err := openid4cigoapi.RestoreIssuerInitiatedInteractionContext(api.Context(opts.getCancelHandle()),
+		serializedState, key, goAPIClientConfig)
+	if err != nil {
+		return nil, wrapper.ToMobileErrorWithTrace(err, oTel)
+	}
+
+	return &IssuerInitiatedInteraction{
+		crypto:           crypto,
+		goAPIInteraction: goAPIInteraction,
+		oTel:             oTel,
+		cancelHandle:     opts.getCancelHandle(),
+	}, nil
+}
+
+// Serialize serializes this WalletInitiatedInteraction so that it can be persisted (e.g. before opening the
+// authorization URL in a browser) and later restored using RestoreWalletInitiatedInteraction, even if the app was
+// closed in the meantime. The serialized state is encrypted using the given key, which must be a 16, 24 or 32 byte
+// AES key.
+func (i *WalletInitiatedInteraction) Serialize(key []byte) ([]byte, error) {
+	serializedState, err := i.goAPIInteraction.Serialize(key)
+	if err != nil {
+		return nil, wrapper.ToMobileErrorWithTrace(err, i.oTel)
+	}
+
+	return serializedState, nil
+}
+
+// RestoreWalletInitiatedInteraction restores a WalletInitiatedInteraction from a state that was serialized using
+// the Serialize method. The given key must be the same one that was used to serialize it.
+func RestoreWalletInitiatedInteraction(serializedState, key []byte, crypto api.Crypto,
+	didResolver api.DIDResolver, opts *InteractionOpts,
+) (*WalletInitiatedInteraction, error) {
+	goAPIClientConfig, oTel, err := createGoAPIClientConfigWithTrace(didResolver, opts)
+	if err != nil {
+		return nil, wrapper.ToMobileErrorWithTrace(err, oTel)
+	}
+
+	goAPIInteraction, err := openid4cigoapi.RestoreWalletInitiatedInteractionContext(api.Context(opts.getCancelHandle()),
+		serializedState, key, goAPIClientConfig)
+	if err != nil {
+		return nil, wrapper.ToMobileErrorWithTrace(err, oTel)
+	}
+
+	return &WalletInitiatedInteraction{
+		crypto:           crypto,
+		goAPIInteraction: goAPIInteraction,
+		oTel:             oTel,
+		cancelHandle:     opts.getCancelHandle(),
+	}, nil
+}
@@ -0,0 +1,130 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	arieskms "github.com/trustbloc/kms-go/spi/kms"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/localkms"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/openid4ci"
+	"github.com/trustbloc/wallet-sdk/pkg/models"
+	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
+)
+
+func TestWalletInitiatedInteraction_SerializeAndRestore(t *testing.T) {
+	issuerServerHandler := &mockIssuerServerHandler{
+		t:                  t,
+		credentialResponse: sampleCredentialResponse,
+	}
+
+	server := httptest.NewServer(issuerServerHandler)
+	defer server.Close()
+
+	metadata := strings.ReplaceAll(sampleIssuerMetadata, serverURLPlaceholder, server.URL)
+	issuerServerHandler.issuerMetadata = modifyCredentialMetadata(t, metadata, func(m *issuer.Metadata) {
+		m.RegistrationEndpoint = nil
+	})
+
+	kms, err := localkms.NewKMS(localkms.NewMemKMSStore())
+	require.NoError(t, err)
+
+	resolver := &mockResolver{keyWriter: kms}
+
+	opts := openid4ci.NewInteractionOpts()
+	opts.DisableVCProofChecks()
+
+	interaction, err := openid4ci.NewWalletInitiatedInteraction(
+		openid4ci.NewWalletInitiatedInteractionArgs(server.URL, kms.GetCrypto(), resolver), opts)
+	require.NoError(t, err)
+
+	authURL, err := interaction.CreateAuthorizationURL("client", "redirectURI",
+		"format", api.NewStringArray().Append("type"), nil)
+	require.NoError(t, err)
+
+	key := bytes.Repeat([]byte{1}, 32)
+
+	serializedState, err := interaction.Serialize(key)
+	require.NoError(t, err)
+
+	t.Run("Success", func(t *testing.T) {
+		restoredInteraction, err := openid4ci.RestoreWalletInitiatedInteraction(serializedState, key,
+			kms.GetCrypto(), resolver, opts)
+		require.NoError(t, err)
+
+		keyHandle, err := kms.Create(arieskms.ED25519)
+		require.NoError(t, err)
+
+		pkBytes, err := keyHandle.JWK.PublicKeyBytes()
+		require.NoError(t, err)
+
+		result, err := restoredInteraction.RequestCredential(&api.VerificationMethod{
+			ID:   "did:example:12345#testId",
+			Type: "Ed25519VerificationKey2018",
+			Key:  models.VerificationKey{Raw: pkBytes},
+		}, "redirectURI?code=1234&state="+getStateFromAuthURL(t, authURL), nil)
+		require.NoError(t, err)
+		require.Equal(t, 1, result.Length())
+	})
+	t.Run("Wrong key", func(t *testing.T) {
+		restoredInteraction, err := openid4ci.RestoreWalletInitiatedInteraction(serializedState,
+			bytes.Repeat([]byte{2}, 32), kms.GetCrypto(), resolver, opts)
+		requireErrorContains(t, err, "INVALID_SERIALIZED_STATE")
+		require.Nil(t, restoredInteraction)
+	})
+	t.Run("Invalid key", func(t *testing.T) {
+		serialized, err := interaction.Serialize([]byte("short"))
+		requireErrorContains(t, err, "INVALID_SDK_USAGE")
+		require.Nil(t, serialized)
+	})
+	t.Run("State from a wallet-initiated interaction restored as an issuer-initiated interaction", func(t *testing.T) {
+		restoredInteraction, err := openid4ci.RestoreIssuerInitiatedInteraction(serializedState, key,
+			kms.GetCrypto(), resolver, opts)
+		requireErrorContains(t, err, "serialized state is not from an issuer-initiated interaction")
+		require.Nil(t, restoredInteraction)
+	})
+}
+
+func TestIssuerInitiatedInteraction_SerializeAndRestore(t *testing.T) {
+	issuerServerHandler := &mockIssuerServerHandler{t: t}
+
+	server := httptest.NewServer(issuerServerHandler)
+	defer server.Close()
+
+	issuerServerHandler.issuerMetadata = strings.ReplaceAll(sampleIssuerMetadata, serverURLPlaceholder, server.URL)
+
+	kms, err := localkms.NewKMS(localkms.NewMemKMSStore())
+	require.NoError(t, err)
+
+	interaction := createIssuerInitiatedInteraction(t, kms, nil, nil,
+		createCredentialOfferIssuanceURI(t, server.URL, true), nil, false)
+
+	_, err = interaction.CreateAuthorizationURL("clientID", "redirectURI", nil)
+	require.NoError(t, err)
+
+	key := bytes.Repeat([]byte{1}, 32)
+
+	serializedState, err := interaction.Serialize(key)
+	require.NoError(t, err)
+
+	restoredInteraction, err := openid4ci.RestoreIssuerInitiatedInteraction(serializedState, key, kms.GetCrypto(),
+		&mockResolver{keyWriter: kms}, nil)
+	require.NoError(t, err)
+	require.True(t, restoredInteraction.AuthorizationCodeGrantTypeSupported())
+
+	restoredInteraction, err = openid4ci.RestoreIssuerInitiatedInteraction([]byte("invalid"), key, kms.GetCrypto(),
+		&mockResolver{keyWriter: kms}, nil)
+	requireErrorContains(t, err, "INVALID_SERIALIZED_STATE")
+	require.Nil(t, restoredInteraction)
+}
@@ -63,7 +63,7 @@ func (s *SupportedCredential) Format() string {
 
 // Types returns this SupportedCredential's types.
 func (s *SupportedCredential) Types() *api.StringArray {
-	return &api.StringArray{Strings: s.credentialConfigurationSupported.CredentialDefinition.Type}
+	return &api.StringArray{Strings: s.credentialConfigurationSupported.CredentialTypes()}
 }
 
 // LocalizedDisplays returns an object that contains this SupportedCredential's
@@ -27,6 +27,7 @@ type WalletInitiatedInteraction struct {
 	goAPIInteraction *openid4cigoapi.WalletInitiatedInteraction
 	crypto           api.Crypto
 	oTel             *otel.Trace
+	cancelHandle     *api.CancelHandle
 }
 
 // WalletInitiatedInteractionArgs contains the required parameters for an WalletInitiatedInteraction.
@@ -88,6 +89,7 @@ func NewWalletInitiatedInteraction( //nolint: dupl // Similar looking but for di
 		crypto:           args.crypto,
 		goAPIInteraction: goAPIInteraction,
 		oTel:             oTel,
+		cancelHandle:     opts.cancelHandle,
 	}, nil
 }
 
@@ -106,8 +108,8 @@ func (i *WalletInitiatedInteraction) CreateAuthorizationURL(clientID, redirectUR
 		credentialTypes = api.NewStringArray()
 	}
 
-	authorizationURL, err := i.goAPIInteraction.CreateAuthorizationURL(clientID, redirectURI, credentialFormat,
-		credentialTypes.Strings, goAPIOpts...)
+	authorizationURL, err := i.goAPIInteraction.CreateAuthorizationURLContext(api.Context(i.cancelHandle), clientID,
+		redirectURI, credentialFormat, credentialTypes.Strings, goAPIOpts...)
 	if err != nil {
 		return "", wrapper.ToMobileErrorWithTrace(err, i.oTel)
 	}
@@ -122,14 +124,15 @@ func (i *WalletInitiatedInteraction) CreateAuthorizationURL(clientID, redirectUR
 // CreateAuthorizationURL, except that now it has some URL query parameters appended to it.
 func (i *WalletInitiatedInteraction) RequestCredential(vm *api.VerificationMethod,
 	redirectURIWithAuthCode string,
-	opts *RequestCredentialWithAuthOpts, //nolint: revive // The opts param is reserved for future use.
+	opts *RequestCredentialWithAuthOpts,
 ) (*verifiable.CredentialsArray, error) {
 	signer, err := createSigner(vm, i.crypto)
 	if err != nil {
 		return nil, wrapper.ToMobileErrorWithTrace(err, i.oTel)
 	}
 
-	credentials, err := i.goAPIInteraction.RequestCredential(signer, redirectURIWithAuthCode)
+	credentials, err := i.goAPIInteraction.RequestCredentialContext(api.Context(i.cancelHandle), signer,
+		redirectURIWithAuthCode, opts.goAPIOpts()...)
 	if err != nil {
 		return nil, wrapper.ToMobileErrorWithTrace(err, i.oTel)
 	}
@@ -137,9 +140,60 @@ func (i *WalletInitiatedInteraction) RequestCredential(vm *api.VerificationMetho
 	return toGomobileCredentials(credentials), nil
 }
 
+// IssuanceGrant returns an IssuanceGrant that can be serialized, stored, and later used with the RenewCredentials
+// function to request the same credentials again without any user interaction.
+// It can only be called after RequestCredential, and only if the issuer provided a refresh token.
+func (i *WalletInitiatedInteraction) IssuanceGrant() (*IssuanceGrant, error) {
+	issuanceGrant, err := i.goAPIInteraction.IssuanceGrant()
+	if err != nil {
+		return nil, wrapper.ToMobileErrorWithTrace(err, i.oTel)
+	}
+
+	return &IssuanceGrant{issuanceGrant: issuanceGrant}, nil
+}
+
+// CredentialBatches returns the copies of the credential that were requested during the last call to
+// RequestCredential using the SetBatchIssuance option. It's empty if batch issuance wasn't used.
+func (i *WalletInitiatedInteraction) CredentialBatches() *CredentialBatches {
+	return &CredentialBatches{batches: i.goAPIInteraction.CredentialBatches()}
+}
+
+// PendingIssuances returns the credentials that the issuer deferred during the last call to RequestCredential.
+// Each PendingIssuance can be serialized and later redeemed using the RequestDeferredCredential function.
+func (i *WalletInitiatedInteraction) PendingIssuances() *PendingIssuances {
+	return &PendingIssuances{pendingIssuances: i.goAPIInteraction.PendingIssuances()}
+}
+
+// Acknowledgment returns an Acknowledgment that can be used to notify the issuer about the credential that was
+// issued. It returns an error if the issuer doesn't have a notification endpoint, or if RequestCredential hasn't been
+// called yet.
+func (i *WalletInitiatedInteraction) Acknowledgment() (*Acknowledgment, error) {
+	acknowledgment, err := i.goAPIInteraction.Acknowledgment()
+	if err != nil {
+		return nil, wrapper.ToMobileErrorWithTrace(err, i.oTel)
+	}
+
+	return &Acknowledgment{
+		acknowledgment: acknowledgment,
+		cancelHandle:   i.cancelHandle,
+	}, nil
+}
+
+// NotificationID returns the notification ID that the issuer returned along with the given credential, which must be
+// one of the credentials returned by this interaction. It identifies the credential when calling
+// Acknowledgment.NotifyIssuer, so it should be stored along with the credential. An empty string is returned if the
+// issuer didn't return a notification ID for the credential.
+func (i *WalletInitiatedInteraction) NotificationID(credential *verifiable.Credential) string {
+	if credential == nil {
+		return ""
+	}
+
+	return i.goAPIInteraction.NotificationID(credential.VC)
+}
+
 // DynamicClientRegistrationSupported indicates whether the issuer supports dynamic client registration.
 func (i *WalletInitiatedInteraction) DynamicClientRegistrationSupported() (bool, error) {
-	supported, err := i.goAPIInteraction.DynamicClientRegistrationSupported()
+	supported, err := i.goAPIInteraction.DynamicClientRegistrationSupportedContext(api.Context(i.cancelHandle))
 	if err != nil {
 		return false, wrapper.ToMobileErrorWithTrace(err, i.oTel)
 	}
@@ -152,7 +206,7 @@ func (i *WalletInitiatedInteraction) DynamicClientRegistrationSupported() (bool,
 // if DynamicClientRegistrationSupported returns true.
 // This method will return an error if the issuer does not support dynamic client registration.
 func (i *WalletInitiatedInteraction) DynamicClientRegistrationEndpoint() (string, error) {
-	endpoint, err := i.goAPIInteraction.DynamicClientRegistrationEndpoint()
+	endpoint, err := i.goAPIInteraction.DynamicClientRegistrationEndpointContext(api.Context(i.cancelHandle))
 	if err != nil {
 		return "", wrapper.ToMobileErrorWithTrace(err, i.oTel)
 	}
@@ -162,7 +216,7 @@ func (i *WalletInitiatedInteraction) DynamicClientRegistrationEndpoint() (string
 
 // IssuerMetadata returns the issuer's metadata object.
 func (i *WalletInitiatedInteraction) IssuerMetadata() (*IssuerMetadata, error) {
-	goAPIIssuerMetadata, err := i.goAPIInteraction.IssuerMetadata()
+	goAPIIssuerMetadata, err := i.goAPIInteraction.IssuerMetadataContext(api.Context(i.cancelHandle))
 	if err != nil {
 		return nil, wrapper.ToMobileErrorWithTrace(err, i.oTel)
 	}
@@ -174,7 +228,7 @@ func (i *WalletInitiatedInteraction) IssuerMetadata() (*IssuerMetadata, error) {
 // An error means that either the issuer failed the verification check, or something went wrong during the
 // process (and so a verification status could not be determined).
 func (i *WalletInitiatedInteraction) VerifyIssuer() (string, error) {
-	serviceURL, err := i.goAPIInteraction.VerifyIssuer()
+	serviceURL, err := i.goAPIInteraction.VerifyIssuerContext(api.Context(i.cancelHandle))
 	if err != nil {
 		return "", wrapper.ToMobileErrorWithTrace(err, i.oTel)
 	}
@@ -11,12 +11,14 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
 	"github.com/trustbloc/wallet-sdk/pkg/openid4vp"
 )
 
 // Acknowledgment represents an object that allows to acknowledge the verifier on presentation request status.
 type Acknowledgment struct {
 	acknowledgment *openid4vp.Acknowledgment
+	cancelHandle   *api.CancelHandle
 }
 
 // NewAcknowledgment recreates acknowledgment object from serialized state.
@@ -50,9 +52,15 @@ func (a *Acknowledgment) SetInteractionDetails(serializedInteractionDetails stri
 	return nil
 }
 
+// SetCancelHandle sets a handle that can be used to abort acknowledgment requests that are in progress.
+func (a *Acknowledgment) SetCancelHandle(cancelHandle *api.CancelHandle) {
+	a.cancelHandle = cancelHandle
+}
+
 // NoConsent acknowledge verifier that user does not consent to the presentation request.
 func (a *Acknowledgment) NoConsent() error {
-	return a.acknowledgment.AcknowledgeVerifier(openid4vp.AccessDeniedErrorResponse,
+	return a.acknowledgment.AcknowledgeVerifierContext(api.Context(a.cancelHandle),
+		openid4vp.AccessDeniedErrorResponse,
 		openid4vp.NoConsentErrorDescription,
 		&http.Client{},
 	)
@@ -60,7 +68,8 @@ func (a *Acknowledgment) NoConsent() error {
 
 // NoMatchingCredential acknowledge verifier that no matching credential was found.
 func (a *Acknowledgment) NoMatchingCredential() error {
-	return a.acknowledgment.AcknowledgeVerifier(openid4vp.AccessDeniedErrorResponse,
+	return a.acknowledgment.AcknowledgeVerifierContext(api.Context(a.cancelHandle),
+		openid4vp.AccessDeniedErrorResponse,
 		openid4vp.NoMatchFoundErrorDescription,
 		&http.Client{},
 	)
@@ -68,5 +77,5 @@ func (a *Acknowledgment) NoMatchingCredential() error {
 
 // WithCode sends acknowledgment message to verifier with the custom error code and description.
 func (a *Acknowledgment) WithCode(code, desc string) error {
-	return a.acknowledgment.AcknowledgeVerifier(code, desc, &http.Client{})
+	return a.acknowledgment.AcknowledgeVerifierContext(api.Context(a.cancelHandle), code, desc, &http.Client{})
 }
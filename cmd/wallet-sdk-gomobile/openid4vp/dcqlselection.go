@@ -0,0 +1,45 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4vp
+
+import (
+	afgoverifiable "github.com/trustbloc/vc-go/verifiable"
+
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/verifiable"
+)
+
+// DCQLSelection holds the credentials that the user chose to present for each credential query of a DCQL query.
+type DCQLSelection struct {
+	selection map[string][]*afgoverifiable.Credential
+}
+
+// NewDCQLSelection returns a new, empty DCQLSelection.
+func NewDCQLSelection() *DCQLSelection {
+	return &DCQLSelection{selection: map[string][]*afgoverifiable.Credential{}}
+}
+
+// Add adds a credential to present for the credential query with the given ID. More than one credential can only be
+// added for credential queries that allow multiple credentials. Nil credentials are ignored.
+// It returns a reference to the DCQLSelection in order to allow a caller to chain together Add calls.
+func (d *DCQLSelection) Add(credentialQueryID string, credential *verifiable.Credential) *DCQLSelection {
+	if credential != nil {
+		d.selection[credentialQueryID] = append(d.selection[credentialQueryID], credential.VC)
+	}
+
+	return d
+}
+
+// Length returns the number of credentials in the selection.
+func (d *DCQLSelection) Length() int {
+	length := 0
+
+	for _, credentials := range d.selection {
+		length += len(credentials)
+	}
+
+	return length
+}
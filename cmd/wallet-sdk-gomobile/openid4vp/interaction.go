@@ -9,6 +9,7 @@ SPDX-License-Identifier: Apache-2.0
 package openid4vp
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -24,6 +25,7 @@ import (
 	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/verifiable"
 	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/wrapper"
 	"github.com/trustbloc/wallet-sdk/pkg/common"
+	"github.com/trustbloc/wallet-sdk/pkg/dcql"
 	"github.com/trustbloc/wallet-sdk/pkg/memstorage/legacy"
 	"github.com/trustbloc/wallet-sdk/pkg/openid4vp"
 )
@@ -31,15 +33,24 @@ import (
 type goAPIOpenID4VP interface {
 	GetQuery() *presexch.PresentationDefinition
 	CustomScope() []string
-	PresentCredential(
+	PresentCredentialContext(
+		ctx context.Context,
 		credentials []*afgoverifiable.Credential,
 		customClaims openid4vp.CustomClaims,
 		opts ...openid4vp.PresentOpt,
 	) error
+	DCQLQuery() *dcql.Query
+	PresentDCQLCredentialsContext(
+		ctx context.Context,
+		selection map[string][]*afgoverifiable.Credential,
+		customClaims openid4vp.CustomClaims,
+		opts ...openid4vp.PresentOpt,
+	) error
 	PresentedClaims(credential *afgoverifiable.Credential) (interface{}, error)
-	PresentCredentialUnsafe(credential *afgoverifiable.Credential, customClaims openid4vp.CustomClaims) error
+	PresentCredentialUnsafeContext(ctx context.Context, credential *afgoverifiable.Credential,
+		customClaims openid4vp.CustomClaims) error
 	VerifierDisplayData() *openid4vp.VerifierDisplayData
-	TrustInfo() (*openid4vp.VerifierTrustInfo, error)
+	TrustInfoContext(ctx context.Context) (*openid4vp.VerifierTrustInfo, error)
 	Acknowledgment() *openid4vp.Acknowledgment
 }
 
@@ -48,6 +59,15 @@ type VerifierTrustInfo struct {
 	DID         string
 	Domain      string
 	DomainValid bool
+	// The subject of the X.509 certificate that the request object was signed with. Only set if the verifier uses the
+	// x509_san_dns or x509_san_uri client_id scheme, in which case DID is empty.
+	CertificateSubject string
+	// The following are only set if the verifier uses the verifier_attestation client_id scheme, in which case DID is
+	// empty. They identify the issuer of the Verifier Attestation JWT, and the verifier's name and the scope it's
+	// permitted to request, as attested by that issuer.
+	AttestationIssuer string
+	AttestedName      string
+	AttestedScope     *api.StringArray
 }
 
 // CredentialClaimKeys represent credential claim keys.
@@ -64,6 +84,7 @@ type Interaction struct {
 	didResolver      api.DIDResolver
 	inquirer         *credentialInquirer.Inquirer
 	oTel             *otel.Trace
+	cancelHandle     *api.CancelHandle
 }
 
 // NewInteraction creates a new OpenID4VP Interaction.
@@ -119,7 +140,8 @@ func NewInteraction(args *Args, opts *Opts) (*Interaction, error) { //nolint:fun
 			DIDResolver: args.didRes,
 		}))
 
-	goAPIInteraction, err := openid4vp.NewInteraction(
+	goAPIInteraction, err := openid4vp.NewInteractionContext(
+		api.Context(opts.cancelHandle),
 		args.authorizationRequest,
 		jwtVerifier,
 		&wrapper.VDRResolverWrapper{DIDResolver: args.didRes},
@@ -138,6 +160,7 @@ func NewInteraction(args *Args, opts *Opts) (*Interaction, error) { //nolint:fun
 		didResolver:      args.didRes,
 		inquirer:         inquirer,
 		oTel:             oTel,
+		cancelHandle:     opts.cancelHandle,
 	}, nil
 }
 
@@ -154,6 +177,22 @@ func (o *Interaction) GetQuery() ([]byte, error) {
 	return pdBytes, nil
 }
 
+// DCQLQuery returns the DCQL query from the authorization request as JSON. If the verifier used a presentation
+// definition instead, then nil is returned.
+func (o *Interaction) DCQLQuery() ([]byte, error) {
+	query := o.goAPIOpenID4VP.DCQLQuery()
+	if query == nil {
+		return nil, nil
+	}
+
+	queryBytes, err := json.Marshal(query)
+	if err != nil {
+		return nil, wrapper.ToMobileErrorWithTrace(fmt.Errorf("DCQL query marshal: %w", err), o.oTel)
+	}
+
+	return queryBytes, nil
+}
+
 // CustomScope returns vp integration scope.
 func (o *Interaction) CustomScope() *Scope {
 	return NewScope(o.goAPIOpenID4VP.CustomScope())
@@ -161,21 +200,28 @@ func (o *Interaction) CustomScope() *Scope {
 
 // TrustInfo return verifier trust info.
 func (o *Interaction) TrustInfo() (*VerifierTrustInfo, error) {
-	info, err := o.goAPIOpenID4VP.TrustInfo()
+	info, err := o.goAPIOpenID4VP.TrustInfoContext(api.Context(o.cancelHandle))
 	if err != nil {
 		return nil, err
 	}
 
 	return &VerifierTrustInfo{
-		DID:         info.DID,
-		Domain:      info.Domain,
-		DomainValid: info.DomainValid,
+		DID:                info.DID,
+		Domain:             info.Domain,
+		DomainValid:        info.DomainValid,
+		CertificateSubject: info.CertificateSubject,
+		AttestationIssuer:  info.AttestationIssuer,
+		AttestedName:       info.AttestedName,
+		AttestedScope:      &api.StringArray{Strings: info.AttestedScope},
 	}, nil
 }
 
 // Acknowledgment returns acknowledgment object.
 func (o *Interaction) Acknowledgment() *Acknowledgment {
-	return &Acknowledgment{acknowledgment: o.goAPIOpenID4VP.Acknowledgment()}
+	return &Acknowledgment{
+		acknowledgment: o.goAPIOpenID4VP.Acknowledgment(),
+		cancelHandle:   o.cancelHandle,
+	}
 }
 
 // VerifierDisplayData returns display information about verifier.
@@ -202,7 +248,8 @@ func (o *Interaction) PresentCredential(credentials *verifiable.CredentialsArray
 		return wrapper.ToMobileErrorWithTrace(err, o.oTel)
 	}
 
-	return wrapper.ToMobileErrorWithTrace(o.goAPIOpenID4VP.PresentCredential(vcs, openid4vp.CustomClaims{}), o.oTel)
+	return wrapper.ToMobileErrorWithTrace(o.goAPIOpenID4VP.PresentCredentialContext(api.Context(o.cancelHandle), vcs,
+		openid4vp.CustomClaims{}), o.oTel)
 }
 
 // PresentCredentialOpts presents credentials to redirect uri from request object.
@@ -220,29 +267,35 @@ func (o *Interaction) PresentCredentialOpts(
 		return err
 	}
 
-	var presentOpts []openid4vp.PresentOpt
+	presentOpts, err := o.toGoAPIPresentOpts(opts)
+	if err != nil {
+		return err
+	}
 
-	if opts != nil { //nolint:nestif
-		if opts.serializedInteractionDetails != "" {
-			var interactionDetails map[string]interface{}
-			if err = json.Unmarshal([]byte(opts.serializedInteractionDetails), &interactionDetails); err != nil {
-				return fmt.Errorf("decode vp interaction details: %w", err)
-			}
+	return wrapper.ToMobileErrorWithTrace(o.goAPIOpenID4VP.PresentCredentialContext(api.Context(o.cancelHandle), vcs,
+		claims, presentOpts...), o.oTel)
+}
 
-			presentOpts = append(presentOpts, openid4vp.WithInteractionDetails(interactionDetails))
-		}
+// PresentDCQLCredentials presents credentials in response to an authorization request that contains a DCQL query.
+// The selection holds the credentials that the user chose for each credential query, and must satisfy the query.
+// opts is optional.
+func (o *Interaction) PresentDCQLCredentials(selection *DCQLSelection, opts *PresentCredentialOpts) error {
+	if selection == nil {
+		return wrapper.ToMobileErrorWithTrace(errors.New("DCQL selection object cannot be nil"), o.oTel)
+	}
 
-		if opts.attestationVM != nil {
-			attestationSigner, attErr := common.NewJWSSigner(opts.attestationVM.ToSDKVerificationMethod(), o.crypto)
-			if attErr != nil {
-				return wrapper.ToMobileErrorWithTrace(attErr, o.oTel)
-			}
+	claims, err := getCustomClaims(opts)
+	if err != nil {
+		return err
+	}
 
-			presentOpts = append(presentOpts, openid4vp.WithAttestationVC(attestationSigner, opts.attestationVC))
-		}
+	presentOpts, err := o.toGoAPIPresentOpts(opts)
+	if err != nil {
+		return err
 	}
 
-	return wrapper.ToMobileErrorWithTrace(o.goAPIOpenID4VP.PresentCredential(vcs, claims, presentOpts...), o.oTel)
+	return wrapper.ToMobileErrorWithTrace(o.goAPIOpenID4VP.PresentDCQLCredentialsContext(
+		api.Context(o.cancelHandle), selection.selection, claims, presentOpts...), o.oTel)
 }
 
 // PresentCredentialUnsafe presents a single credential to redirect uri from
@@ -253,8 +306,8 @@ func (o *Interaction) PresentCredentialOpts(
 // provided credential, at least in terms of issuer fields, and subject data
 // fields.
 func (o *Interaction) PresentCredentialUnsafe(credential *verifiable.Credential) error {
-	return wrapper.ToMobileErrorWithTrace(o.goAPIOpenID4VP.PresentCredentialUnsafe(credential.VC,
-		openid4vp.CustomClaims{}), o.oTel)
+	return wrapper.ToMobileErrorWithTrace(o.goAPIOpenID4VP.PresentCredentialUnsafeContext(
+		api.Context(o.cancelHandle), credential.VC, openid4vp.CustomClaims{}), o.oTel)
 }
 
 // OTelTraceID returns open telemetry trace id.
@@ -267,6 +320,34 @@ func (o *Interaction) OTelTraceID() string {
 	return traceID
 }
 
+func (o *Interaction) toGoAPIPresentOpts(opts *PresentCredentialOpts) ([]openid4vp.PresentOpt, error) {
+	if opts == nil {
+		return nil, nil
+	}
+
+	var presentOpts []openid4vp.PresentOpt
+
+	if opts.serializedInteractionDetails != "" {
+		var interactionDetails map[string]interface{}
+		if err := json.Unmarshal([]byte(opts.serializedInteractionDetails), &interactionDetails); err != nil {
+			return nil, fmt.Errorf("decode vp interaction details: %w", err)
+		}
+
+		presentOpts = append(presentOpts, openid4vp.WithInteractionDetails(interactionDetails))
+	}
+
+	if opts.attestationVM != nil {
+		attestationSigner, err := common.NewJWSSigner(opts.attestationVM.ToSDKVerificationMethod(), o.crypto)
+		if err != nil {
+			return nil, wrapper.ToMobileErrorWithTrace(err, o.oTel)
+		}
+
+		presentOpts = append(presentOpts, openid4vp.WithAttestationVC(attestationSigner, opts.attestationVC))
+	}
+
+	return presentOpts, nil
+}
+
 //nolint:unparam
 func toGoAPIOpts(opts *Opts) ([]openid4vp.Opt, error) {
 	httpClient := wrapper.NewHTTPClient(opts.httpTimeout, opts.additionalHeaders, opts.disableHTTPClientTLSVerification)
@@ -287,6 +368,14 @@ func toGoAPIOpts(opts *Opts) ([]openid4vp.Opt, error) {
 		goAPIOpts = append(goAPIOpts, openid4vp.WithMetricsLogger(mobileMetricsLoggerWrapper))
 	}
 
+	if opts.trustAnchors != nil {
+		goAPIOpts = append(goAPIOpts, openid4vp.WithTrustAnchors(opts.trustAnchors.Certificates))
+	}
+
+	if len(opts.verifierAttestationIssuers) > 0 {
+		goAPIOpts = append(goAPIOpts, openid4vp.WithVerifierAttestationIssuers(opts.verifierAttestationIssuers))
+	}
+
 	return goAPIOpts, nil
 }
 
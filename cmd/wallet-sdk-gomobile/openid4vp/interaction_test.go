@@ -8,6 +8,7 @@ SPDX-License-Identifier: Apache-2.0
 package openid4vp //nolint: testpackage
 
 import (
+	"context"
 	"crypto/ed25519"
 	"crypto/rand"
 	_ "embed" //nolint:gci // required for go:embed
@@ -32,6 +33,7 @@ import (
 	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/metricslogger/stderr"
 	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/verifiable"
 	"github.com/trustbloc/wallet-sdk/internal/testutil"
+	"github.com/trustbloc/wallet-sdk/pkg/dcql"
 	"github.com/trustbloc/wallet-sdk/pkg/models"
 	"github.com/trustbloc/wallet-sdk/pkg/openid4vp"
 )
@@ -258,6 +260,81 @@ func TestOpenID4VP_PresentCredential(t *testing.T) {
 	})
 }
 
+func TestOpenID4VP_PresentDCQLCredentials(t *testing.T) {
+	var credentialData []json.RawMessage
+
+	require.NoError(t, json.Unmarshal(credentialsJSONLD, &credentialData))
+
+	cred, err := afgoverifiable.ParseCredential(credentialData[0],
+		afgoverifiable.WithDisabledProofCheck(), afgoverifiable.WithCredDisableValidation())
+	require.NoError(t, err)
+
+	credential := verifiable.NewCredential(cred)
+
+	query, err := dcql.Parse([]byte(`{"credentials": [{"id": "degree", "format": "ldp_vc"}]}`))
+	require.NoError(t, err)
+
+	t.Run("Success", func(t *testing.T) {
+		goAPIInteraction := &mockGoAPIInteraction{DCQLQueryResult: query}
+		instance := &Interaction{crypto: &mockCrypto{}, goAPIOpenID4VP: goAPIInteraction}
+
+		queryBytes, err := instance.DCQLQuery()
+		require.NoError(t, err)
+		require.JSONEq(t, `{"credentials": [{"id": "degree", "format": "ldp_vc"}]}`, string(queryBytes))
+
+		selection := NewDCQLSelection().Add("degree", credential).Add("degree", nil)
+		require.Equal(t, 1, selection.Length())
+
+		err = instance.PresentDCQLCredentials(selection, NewPresentCredentialOpts().
+			AddScopeClaim("claim1", `{"key" : "val"}`).
+			SetInteractionDetails(`{"key1": "value1"}`))
+		require.NoError(t, err)
+		require.Equal(t, map[string][]*afgoverifiable.Credential{"degree": {cred}},
+			goAPIInteraction.PresentedDCQLSelection)
+	})
+
+	t.Run("No DCQL query", func(t *testing.T) {
+		instance := &Interaction{goAPIOpenID4VP: &mockGoAPIInteraction{}}
+
+		queryBytes, err := instance.DCQLQuery()
+		require.NoError(t, err)
+		require.Nil(t, queryBytes)
+	})
+
+	t.Run("Selection is nil", func(t *testing.T) {
+		instance := &Interaction{goAPIOpenID4VP: &mockGoAPIInteraction{DCQLQueryResult: query}}
+
+		err := instance.PresentDCQLCredentials(nil, nil)
+		testutil.RequireErrorContains(t, err, "DCQL selection object cannot be nil")
+	})
+
+	t.Run("Invalid scope value", func(t *testing.T) {
+		instance := &Interaction{goAPIOpenID4VP: &mockGoAPIInteraction{DCQLQueryResult: query}}
+
+		err := instance.PresentDCQLCredentials(NewDCQLSelection().Add("degree", credential),
+			NewPresentCredentialOpts().AddScopeClaim("claim1", `"key" : "val"`))
+		require.ErrorContains(t, err, `fail to parse "claim1" claim json`)
+	})
+
+	t.Run("Invalid interaction details", func(t *testing.T) {
+		instance := &Interaction{goAPIOpenID4VP: &mockGoAPIInteraction{DCQLQueryResult: query}}
+
+		err := instance.PresentDCQLCredentials(NewDCQLSelection().Add("degree", credential),
+			NewPresentCredentialOpts().SetInteractionDetails(`"key1": "value1"`))
+		require.ErrorContains(t, err, "decode vp interaction details")
+	})
+
+	t.Run("Present credentials failed", func(t *testing.T) {
+		instance := &Interaction{goAPIOpenID4VP: &mockGoAPIInteraction{
+			DCQLQueryResult:           query,
+			PresentDCQLCredentialsErr: errors.New("present credentials failed"),
+		}}
+
+		err := instance.PresentDCQLCredentials(NewDCQLSelection().Add("degree", credential), nil)
+		require.ErrorContains(t, err, "present credentials failed")
+	})
+}
+
 func TestGetCustomClaims(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		claims, err := getCustomClaims(NewPresentCredentialOpts().
@@ -304,10 +381,11 @@ func TestInteraction_VerifierDisplayData(t *testing.T) {
 		instance := &Interaction{
 			goAPIOpenID4VP: &mockGoAPIInteraction{
 				VerifierDisplayDataRes: &openid4vp.VerifierDisplayData{
-					DID:     "DID",
-					Name:    "testName",
-					Purpose: "purpose",
-					LogoURI: "logoURI",
+					DID:                "DID",
+					Name:               "testName",
+					Purpose:            "purpose",
+					LogoURI:            "logoURI",
+					CertificateSubject: "CN=Test Verifier",
 				},
 			},
 		}
@@ -318,6 +396,7 @@ func TestInteraction_VerifierDisplayData(t *testing.T) {
 		require.Equal(t, "testName", data.Name())
 		require.Equal(t, "purpose", data.Purpose())
 		require.Equal(t, "logoURI", data.LogoURI())
+		require.Equal(t, "CN=Test Verifier", data.CertificateSubject())
 	})
 }
 
@@ -326,8 +405,12 @@ func TestInteraction_TrustInfo(t *testing.T) {
 		instance := &Interaction{
 			goAPIOpenID4VP: &mockGoAPIInteraction{
 				VerifierTrustInfo: &openid4vp.VerifierTrustInfo{
-					DID:    "TestDID",
-					Domain: "TestDomain",
+					DID:                "TestDID",
+					Domain:             "TestDomain",
+					CertificateSubject: "CN=Test Verifier",
+					AttestationIssuer:  "did:example:attestation-issuer",
+					AttestedName:       "Test Verifier",
+					AttestedScope:      []string{"pid"},
 				},
 			},
 		}
@@ -337,6 +420,11 @@ func TestInteraction_TrustInfo(t *testing.T) {
 		require.NotNil(t, info)
 		require.Equal(t, "TestDID", info.DID)
 		require.Equal(t, "TestDomain", info.Domain)
+		require.Equal(t, "CN=Test Verifier", info.CertificateSubject)
+		require.Equal(t, "did:example:attestation-issuer", info.AttestationIssuer)
+		require.Equal(t, "Test Verifier", info.AttestedName)
+		require.Equal(t, 1, info.AttestedScope.Length())
+		require.Equal(t, "pid", info.AttestedScope.AtIndex(0))
 	})
 
 	t.Run("Failure", func(t *testing.T) {
@@ -423,6 +511,9 @@ type mockGoAPIInteraction struct {
 	ScopeResult                []string
 	PresentCredentialErr       error
 	PresentCredentialUnsafeErr error
+	DCQLQueryResult            *dcql.Query
+	PresentDCQLCredentialsErr  error
+	PresentedDCQLSelection     map[string][]*afgoverifiable.Credential
 	VerifierDisplayDataRes     *openid4vp.VerifierDisplayData
 	VerifierTrustInfo          *openid4vp.VerifierTrustInfo
 	VerifierTrustInfoErr       error
@@ -441,7 +532,8 @@ func (o *mockGoAPIInteraction) CustomScope() []string {
 	return o.ScopeResult
 }
 
-func (o *mockGoAPIInteraction) PresentCredential(
+func (o *mockGoAPIInteraction) PresentCredentialContext(
+	context.Context,
 	[]*afgoverifiable.Credential,
 	openid4vp.CustomClaims,
 	...openid4vp.PresentOpt,
@@ -449,11 +541,28 @@ func (o *mockGoAPIInteraction) PresentCredential(
 	return o.PresentCredentialErr
 }
 
+func (o *mockGoAPIInteraction) DCQLQuery() *dcql.Query {
+	return o.DCQLQueryResult
+}
+
+func (o *mockGoAPIInteraction) PresentDCQLCredentialsContext(
+	_ context.Context,
+	selection map[string][]*afgoverifiable.Credential,
+	_ openid4vp.CustomClaims,
+	_ ...openid4vp.PresentOpt,
+) error {
+	o.PresentedDCQLSelection = selection
+
+	return o.PresentDCQLCredentialsErr
+}
+
 func (o *mockGoAPIInteraction) PresentedClaims(*afgoverifiable.Credential) (interface{}, error) {
 	return o.PresentedClaimsResult, o.PresentedClaimsErr
 }
 
-func (o *mockGoAPIInteraction) PresentCredentialUnsafe(*afgoverifiable.Credential, openid4vp.CustomClaims) error {
+func (o *mockGoAPIInteraction) PresentCredentialUnsafeContext(context.Context, *afgoverifiable.Credential,
+	openid4vp.CustomClaims,
+) error {
 	return o.PresentCredentialUnsafeErr
 }
 
@@ -461,7 +570,7 @@ func (o *mockGoAPIInteraction) VerifierDisplayData() *openid4vp.VerifierDisplayD
 	return o.VerifierDisplayDataRes
 }
 
-func (o *mockGoAPIInteraction) TrustInfo() (*openid4vp.VerifierTrustInfo, error) {
+func (o *mockGoAPIInteraction) TrustInfoContext(context.Context) (*openid4vp.VerifierTrustInfo, error) {
 	return o.VerifierTrustInfo, o.VerifierTrustInfoErr
 }
 
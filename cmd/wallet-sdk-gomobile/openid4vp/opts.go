@@ -24,6 +24,9 @@ type Opts struct {
 	disableOpenTelemetry             bool
 	httpTimeout                      *time.Duration
 	kms                              *localkms.KMS
+	cancelHandle                     *api.CancelHandle
+	trustAnchors                     *api.TrustAnchors
+	verifierAttestationIssuers       []string
 }
 
 // NewOpts returns a new Opts object.
@@ -52,6 +55,14 @@ func (o *Opts) SetMetricsLogger(metricsLogger api.MetricsLogger) *Opts {
 	return o
 }
 
+// SetCancelHandle sets a handle that can be used to abort the interaction. Once the handle is cancelled, any request
+// to the verifier that's in progress is aborted, and all further calls that need to contact the verifier fail.
+func (o *Opts) SetCancelHandle(cancelHandle *api.CancelHandle) *Opts {
+	o.cancelHandle = cancelHandle
+
+	return o
+}
+
 // SetHTTPTimeoutNanoseconds sets the timeout (in nanoseconds) for HTTP calls.
 // Passing in 0 will disable timeouts.
 func (o *Opts) SetHTTPTimeoutNanoseconds(timeout int64) *Opts {
@@ -79,6 +90,23 @@ func (o *Opts) AddHeader(header *api.Header) *Opts {
 	return o
 }
 
+// SetTrustAnchors sets the trust anchors that the certificate chains (from the x5c header) of request objects are
+// verified against. These are needed to accept requests from verifiers that use the x509_san_dns or x509_san_uri
+// client_id schemes. If no trust anchors are set, then such requests are rejected.
+func (o *Opts) SetTrustAnchors(trustAnchors *api.TrustAnchors) *Opts {
+	o.trustAnchors = trustAnchors
+
+	return o
+}
+
+// AddVerifierAttestationIssuer adds the DID of an issuer whose Verifier Attestation JWTs are trusted. At least one
+// issuer is needed to accept requests from verifiers that use the verifier_attestation client_id scheme.
+func (o *Opts) AddVerifierAttestationIssuer(issuerDID string) *Opts {
+	o.verifierAttestationIssuers = append(o.verifierAttestationIssuers, issuerDID)
+
+	return o
+}
+
 // DisableHTTPClientTLSVerify disables tls verification, should be used only for test purposes.
 func (o *Opts) DisableHTTPClientTLSVerify() *Opts {
 	o.disableHTTPClientTLSVerification = true
@@ -26,3 +26,23 @@ func TestClientConfig_AddHeader(t *testing.T) {
 	require.Equal(t, "testName", headers[0].Name)
 	require.Equal(t, "testValue", headers[0].Value)
 }
+
+func TestOpts_SetTrustAnchors(t *testing.T) {
+	trustAnchors := api.NewTrustAnchors()
+
+	o := NewOpts().SetTrustAnchors(trustAnchors)
+	require.Equal(t, trustAnchors, o.trustAnchors)
+
+	goAPIOpts, err := toGoAPIOpts(o)
+	require.NoError(t, err)
+	require.Len(t, goAPIOpts, 2)
+}
+
+func TestOpts_AddVerifierAttestationIssuer(t *testing.T) {
+	o := NewOpts().AddVerifierAttestationIssuer("did:example:issuer1").AddVerifierAttestationIssuer("did:example:issuer2")
+	require.Equal(t, []string{"did:example:issuer1", "did:example:issuer2"}, o.verifierAttestationIssuers)
+
+	goAPIOpts, err := toGoAPIOpts(o)
+	require.NoError(t, err)
+	require.Len(t, goAPIOpts, 2)
+}
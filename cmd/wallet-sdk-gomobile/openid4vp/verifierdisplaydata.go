@@ -32,3 +32,9 @@ func (v *VerifierDisplayData) Purpose() string {
 func (v *VerifierDisplayData) LogoURI() string {
 	return v.displayData.LogoURI
 }
+
+// CertificateSubject returns the subject of the X.509 certificate that the verifier's request was signed with. It's
+// empty unless the verifier uses the x509_san_dns or x509_san_uri client_id scheme.
+func (v *VerifierDisplayData) CertificateSubject() string {
+	return v.displayData.CertificateSubject
+}
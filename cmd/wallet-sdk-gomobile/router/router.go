@@ -0,0 +1,224 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package router provides a single entry point for the requests that a wallet receives, typically by scanning a QR
+// code or by following a link. It works out whether a request is a credential offer or a presentation request and
+// creates the interaction object that handles it.
+package router
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/openid4ci"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/openid4vp"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/wrapper"
+	goapirouter "github.com/trustbloc/wallet-sdk/pkg/router"
+)
+
+// Request types that can be returned by Request.Type.
+const (
+	RequestTypeUnknown             = string(goapirouter.Unknown)
+	RequestTypeCredentialOffer     = string(goapirouter.CredentialOffer)
+	RequestTypePresentationRequest = string(goapirouter.PresentationRequest)
+)
+
+// Request is the result of parsing a payload.
+type Request struct {
+	request *goapirouter.Request
+}
+
+// Parse determines what kind of request the given payload contains. The following payloads are recognised:
+// openid-credential-offer:// URIs, openid4vp://, openid-vc:// and haip:// URIs, https universal links, raw request
+// objects and raw credential offers.
+// Only the structure of the request is checked. Nothing is fetched, so a request that's reported as valid can still
+// fail when the interaction is created.
+func Parse(payload string) *Request {
+	return &Request{request: goapirouter.Parse(payload)}
+}
+
+// Type returns the kind of request. It's one of the RequestType constants.
+func (r *Request) Type() string {
+	return string(r.request.Type)
+}
+
+// URI returns the request in the form that's accepted by the interaction that handles it.
+// For credential offers, this can be passed to openid4ci.NewIssuerInitiatedInteractionArgs.
+// For presentation requests, this can be passed to openid4vp.NewArgs.
+func (r *Request) URI() string {
+	return r.request.URI
+}
+
+// IsValid indicates whether the payload is a valid request.
+func (r *Request) IsValid() bool {
+	return r.request.ValidationError == nil
+}
+
+// ValidationError returns the reason why the payload isn't a valid request, or nil if it's valid.
+func (r *Request) ValidationError() error {
+	if r.request.ValidationError == nil {
+		return nil
+	}
+
+	return wrapper.ToMobileError(r.request.ValidationError)
+}
+
+// Args contains the required parameters for a RequestRouter.
+type Args struct {
+	crypto      api.Crypto
+	didResolver api.DIDResolver
+}
+
+// NewArgs creates a new Args object. All parameters are mandatory.
+func NewArgs(crypto api.Crypto, didResolver api.DIDResolver) *Args {
+	return &Args{
+		crypto:      crypto,
+		didResolver: didResolver,
+	}
+}
+
+// Opts contains all optional arguments that can be passed into the NewRequestRouter function.
+type Opts struct {
+	openID4CIOpts *openid4ci.InteractionOpts
+	openID4VPOpts *openid4vp.Opts
+}
+
+// NewOpts returns a new Opts object.
+func NewOpts() *Opts {
+	return &Opts{}
+}
+
+// SetOpenID4CIOpts sets the options used for every OpenID4CI interaction that the router creates.
+func (o *Opts) SetOpenID4CIOpts(opts *openid4ci.InteractionOpts) *Opts {
+	o.openID4CIOpts = opts
+
+	return o
+}
+
+// SetOpenID4VPOpts sets the options used for every OpenID4VP interaction that the router creates.
+func (o *Opts) SetOpenID4VPOpts(opts *openid4vp.Opts) *Opts {
+	o.openID4VPOpts = opts
+
+	return o
+}
+
+// RequestRouter creates interactions for the requests that a wallet receives. The same RequestRouter can be used for
+// any number of requests.
+type RequestRouter struct {
+	args *Args
+	opts *Opts
+}
+
+// NewRequestRouter creates a new RequestRouter.
+func NewRequestRouter(args *Args, opts *Opts) (*RequestRouter, error) {
+	if args == nil {
+		return nil, wrapper.ToMobileError(walleterror.NewInvalidSDKUsageError(
+			goapirouter.ErrorModule, errors.New("args object must be provided")))
+	}
+
+	if opts == nil {
+		opts = NewOpts()
+	}
+
+	return &RequestRouter{args: args, opts: opts}, nil
+}
+
+// Route parses the given payload and creates the interaction that handles it.
+// If the payload isn't a valid request, then the request's validation error is returned.
+func (r *RequestRouter) Route(payload string) (*Interaction, error) {
+	request := Parse(payload)
+	if !request.IsValid() {
+		return nil, request.ValidationError()
+	}
+
+	interaction := &Interaction{request: request}
+
+	var err error
+
+	switch request.request.Type {
+	case goapirouter.CredentialOffer:
+		interaction.issuerInitiatedInteraction, err = openid4ci.NewIssuerInitiatedInteraction(
+			openid4ci.NewIssuerInitiatedInteractionArgs(request.URI(), r.args.crypto, r.args.didResolver),
+			r.openID4CIOpts())
+	case goapirouter.PresentationRequest:
+		interaction.openID4VPInteraction, err = openid4vp.NewInteraction(
+			openid4vp.NewArgs(request.URI(), r.args.crypto, r.args.didResolver), r.openID4VPOpts())
+	default:
+		err = wrapper.ToMobileError(walleterror.NewValidationError(goapirouter.ErrorModule,
+			goapirouter.UnsupportedRequestCode, goapirouter.UnsupportedRequestError,
+			fmt.Errorf("unsupported request type: %s", request.Type())))
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return interaction, nil
+}
+
+// The interaction constructors add their own headers to the options they're given, so each interaction gets a copy
+// to keep those headers from building up across requests.
+func (r *RequestRouter) openID4CIOpts() *openid4ci.InteractionOpts {
+	if r.opts.openID4CIOpts == nil {
+		return nil
+	}
+
+	opts := *r.opts.openID4CIOpts
+
+	return &opts
+}
+
+func (r *RequestRouter) openID4VPOpts() *openid4vp.Opts {
+	if r.opts.openID4VPOpts == nil {
+		return nil
+	}
+
+	opts := *r.opts.openID4VPOpts
+
+	return &opts
+}
+
+// Interaction holds the interaction created for a request. Check its Type to see which interaction is available.
+type Interaction struct {
+	request                    *Request
+	issuerInitiatedInteraction *openid4ci.IssuerInitiatedInteraction
+	openID4VPInteraction       *openid4vp.Interaction
+}
+
+// Type returns the kind of request that the interaction handles. It's one of the RequestType constants.
+func (i *Interaction) Type() string {
+	return i.request.Type()
+}
+
+// Request returns the parsed request.
+func (i *Interaction) Request() *Request {
+	return i.request
+}
+
+// IssuerInitiatedInteraction returns the interaction for a credential offer.
+// It returns an error if the request isn't a credential offer.
+func (i *Interaction) IssuerInitiatedInteraction() (*openid4ci.IssuerInitiatedInteraction, error) {
+	if i.issuerInitiatedInteraction == nil {
+		return nil, wrapper.ToMobileError(walleterror.NewInvalidSDKUsageError(goapirouter.ErrorModule,
+			fmt.Errorf("request is a %s, not a credential offer", i.Type())))
+	}
+
+	return i.issuerInitiatedInteraction, nil
+}
+
+// OpenID4VPInteraction returns the interaction for a presentation request.
+// It returns an error if the request isn't a presentation request.
+func (i *Interaction) OpenID4VPInteraction() (*openid4vp.Interaction, error) {
+	if i.openID4VPInteraction == nil {
+		return nil, wrapper.ToMobileError(walleterror.NewInvalidSDKUsageError(goapirouter.ErrorModule,
+			fmt.Errorf("request is a %s, not a presentation request", i.Type())))
+	}
+
+	return i.openID4VPInteraction, nil
+}
@@ -0,0 +1,119 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package router_test
+
+import (
+	_ "embed"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/localkms"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/openid4ci"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/openid4vp"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/router"
+)
+
+const serverURLPlaceholder = "[SERVER_URL]"
+
+//go:embed testdata/sample_issuer_metadata.json
+var sampleIssuerMetadata string
+
+func TestParse(t *testing.T) {
+	t.Run("Credential offer", func(t *testing.T) {
+		request := router.Parse("https://wallet.example.com/offer?credential_offer_uri=https://issuer.example.com/offer")
+		require.Equal(t, router.RequestTypeCredentialOffer, request.Type())
+		require.Equal(t, "openid-credential-offer://?credential_offer_uri=https://issuer.example.com/offer",
+			request.URI())
+		require.True(t, request.IsValid())
+		require.NoError(t, request.ValidationError())
+	})
+	t.Run("Invalid presentation request", func(t *testing.T) {
+		request := router.Parse("haip://?client_id=verifier")
+		require.Equal(t, router.RequestTypePresentationRequest, request.Type())
+		require.False(t, request.IsValid())
+		require.ErrorContains(t, request.ValidationError(), "INVALID_PRESENTATION_REQUEST")
+	})
+	t.Run("Unknown payload", func(t *testing.T) {
+		request := router.Parse("hello")
+		require.Equal(t, router.RequestTypeUnknown, request.Type())
+		require.ErrorContains(t, request.ValidationError(), "UNSUPPORTED_REQUEST")
+	})
+}
+
+func TestRequestRouter_Route(t *testing.T) {
+	var issuerMetadata string
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path != "/.well-known/openid-credential-issuer" {
+			writer.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		_, err := writer.Write([]byte(issuerMetadata))
+		require.NoError(t, err)
+	}))
+	defer server.Close()
+
+	issuerMetadata = strings.ReplaceAll(sampleIssuerMetadata, serverURLPlaceholder, server.URL)
+
+	kms, err := localkms.NewKMS(localkms.NewMemKMSStore())
+	require.NoError(t, err)
+
+	opts := router.NewOpts().
+		SetOpenID4CIOpts(openid4ci.NewInteractionOpts().DisableOpenTelemetry()).
+		SetOpenID4VPOpts(openid4vp.NewOpts().DisableOpenTelemetry())
+
+	walletRouter, err := router.NewRequestRouter(router.NewArgs(kms.GetCrypto(), &mockResolver{}), opts)
+	require.NoError(t, err)
+
+	t.Run("Credential offer", func(t *testing.T) {
+		credentialOffer := `{"credential_issuer":"` + server.URL + `",` +
+			`"credential_configuration_ids":["PermanentResidentCard_jwt_vc_json-ld_v1"],` +
+			`"grants":{"urn:ietf:params:oauth:grant-type:pre-authorized_code":{"pre-authorized_code":"code"}}}`
+
+		interaction, err := walletRouter.Route("openid-credential-offer://?credential_offer=" +
+			url.QueryEscape(credentialOffer))
+		require.NoError(t, err)
+		require.Equal(t, router.RequestTypeCredentialOffer, interaction.Type())
+		require.True(t, interaction.Request().IsValid())
+
+		issuerInitiatedInteraction, err := interaction.IssuerInitiatedInteraction()
+		require.NoError(t, err)
+		require.NotNil(t, issuerInitiatedInteraction)
+
+		openID4VPInteraction, err := interaction.OpenID4VPInteraction()
+		require.ErrorContains(t, err, "request is a credential_offer, not a presentation request")
+		require.Nil(t, openID4VPInteraction)
+	})
+	t.Run("Presentation request is passed to OpenID4VP", func(t *testing.T) {
+		interaction, err := walletRouter.Route("haip://?request_uri=" + url.QueryEscape(server.URL+"/request"))
+		require.ErrorContains(t, err, "REQUEST_OBJECT_FETCH_FAILED")
+		require.Nil(t, interaction)
+	})
+	t.Run("Invalid payload", func(t *testing.T) {
+		interaction, err := walletRouter.Route("openid-credential-offer://?credential_offer=invalid")
+		require.ErrorContains(t, err, "INVALID_CREDENTIAL_OFFER")
+		require.Nil(t, interaction)
+	})
+	t.Run("Args not provided", func(t *testing.T) {
+		instance, err := router.NewRequestRouter(nil, nil)
+		require.ErrorContains(t, err, "args object must be provided")
+		require.Nil(t, instance)
+	})
+}
+
+type mockResolver struct{}
+
+func (*mockResolver) Resolve(string) ([]byte, error) {
+	return nil, nil
+}
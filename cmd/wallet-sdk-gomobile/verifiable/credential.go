@@ -55,7 +55,12 @@ func (v *Credential) Name() string {
 
 // IssuerID returns the ID of this VC's issuer.
 // While the ID is typically going to be a DID, the Verifiable Credential spec does not mandate this.
+// An empty string is returned if the VC doesn't specify an issuer, which is the case for mdocs.
 func (v *Credential) IssuerID() string {
+	if v.VC.Contents().Issuer == nil {
+		return ""
+	}
+
 	return v.VC.Contents().Issuer.ID
 }
 
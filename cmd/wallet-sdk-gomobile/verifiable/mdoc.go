@@ -0,0 +1,130 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifiable
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/trustbloc/wallet-sdk/pkg/mdoc"
+)
+
+// Mdoc represents an ISO/IEC 18013-5 mdoc (e.g. a mobile driving licence) that was issued in the mso_mdoc format.
+type Mdoc struct {
+	mdoc *mdoc.Mdoc
+}
+
+// IsMdoc indicates whether this credential is an mdoc. If it is, then the Mdoc method can be used to access the
+// mdoc's namespaces and data elements.
+func (v *Credential) IsMdoc() bool {
+	return mdoc.IsMdocCredential(v.VC)
+}
+
+// Mdoc returns the mdoc that this credential represents.
+// An error is returned if this credential is not an mdoc (see IsMdoc).
+func (v *Credential) Mdoc() (*Mdoc, error) {
+	// The mdoc was already verified when the credential was received or parsed.
+	goAPIMdoc, err := mdoc.FromCredential(v.VC, mdoc.WithDisabledProofCheck())
+	if err != nil {
+		return nil, err
+	}
+
+	return &Mdoc{mdoc: goAPIMdoc}, nil
+}
+
+// DocType returns the mdoc's document type, e.g. org.iso.18013.5.1.mDL.
+func (m *Mdoc) DocType() string {
+	return m.mdoc.DocType
+}
+
+// ValidFrom returns the time from which the mdoc is valid as a Unix timestamp.
+func (m *Mdoc) ValidFrom() int64 {
+	return m.mdoc.ValidFrom.Unix()
+}
+
+// ValidUntil returns the time until which the mdoc is valid as a Unix timestamp.
+func (m *Mdoc) ValidUntil() int64 {
+	return m.mdoc.ValidUntil.Unix()
+}
+
+// IssuerCertificateSubject returns the subject of the certificate used to sign the mdoc.
+func (m *Mdoc) IssuerCertificateSubject() string {
+	if len(m.mdoc.IssuerCertificateChain) == 0 {
+		return ""
+	}
+
+	return m.mdoc.IssuerCertificateChain[0].Subject.String()
+}
+
+// Elements returns the mdoc's data elements, sorted by namespace and then by element identifier.
+func (m *Mdoc) Elements() *MdocElementsArray {
+	var elements []*MdocElement
+
+	for nameSpace, nameSpaceElements := range m.mdoc.NameSpaces {
+		for identifier, value := range nameSpaceElements {
+			elements = append(elements, &MdocElement{nameSpace: nameSpace, identifier: identifier, value: value})
+		}
+	}
+
+	sort.Slice(elements, func(i, j int) bool {
+		if elements[i].nameSpace != elements[j].nameSpace {
+			return elements[i].nameSpace < elements[j].nameSpace
+		}
+
+		return elements[i].identifier < elements[j].identifier
+	})
+
+	return &MdocElementsArray{elements: elements}
+}
+
+// MdocElement represents a single data element in an mdoc.
+type MdocElement struct {
+	nameSpace  string
+	identifier string
+	value      interface{}
+}
+
+// NameSpace returns the namespace that this element belongs to, e.g. org.iso.18013.5.1.
+func (e *MdocElement) NameSpace() string {
+	return e.nameSpace
+}
+
+// Identifier returns this element's identifier, e.g. family_name.
+func (e *MdocElement) Identifier() string {
+	return e.identifier
+}
+
+// ValueJSON returns this element's value as JSON. Dates are represented as strings and binary data (e.g. a portrait)
+// as a base64-encoded string.
+func (e *MdocElement) ValueJSON() (string, error) {
+	valueBytes, err := json.Marshal(e.value)
+	if err != nil {
+		return "", err
+	}
+
+	return string(valueBytes), nil
+}
+
+// MdocElementsArray represents an array of MdocElements.
+type MdocElementsArray struct {
+	elements []*MdocElement
+}
+
+// Length returns the number of MdocElements contained within this MdocElementsArray.
+func (a *MdocElementsArray) Length() int {
+	return len(a.elements)
+}
+
+// AtIndex returns the MdocElement at the given index.
+// If the index passed in is out of bounds, then nil is returned.
+func (a *MdocElementsArray) AtIndex(index int) *MdocElement {
+	if index < 0 || index >= len(a.elements) {
+		return nil
+	}
+
+	return a.elements[index]
+}
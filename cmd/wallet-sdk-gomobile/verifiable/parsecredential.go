@@ -11,7 +11,9 @@ import (
 
 	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/wrapper"
 	"github.com/trustbloc/wallet-sdk/pkg/common"
+	"github.com/trustbloc/wallet-sdk/pkg/mdoc"
 	"github.com/trustbloc/wallet-sdk/pkg/memstorage/legacy"
+	"github.com/trustbloc/wallet-sdk/pkg/sdjwtvc"
 )
 
 // ParseCredential parses the given serialized VC into a VC object.
@@ -20,6 +22,14 @@ func ParseCredential(vc string, opts *Opts) (*Credential, error) {
 		opts = &Opts{}
 	}
 
+	if sdjwtvc.IsSDJWTVC([]byte(vc)) {
+		return parseSDJWTVC(vc, opts)
+	}
+
+	if mdoc.IsMdoc([]byte(vc)) {
+		return parseMdoc(vc, opts)
+	}
+
 	var parseCredentialOpts []verifiable.CredentialOpt
 
 	if opts.disableProofCheck {
@@ -51,3 +61,37 @@ func ParseCredential(vc string, opts *Opts) (*Credential, error) {
 
 	return NewCredential(verifiableCredential), nil
 }
+
+// parseSDJWTVC parses an IETF SD-JWT VC. As with other JWT VCs, no proof checker is available here, so it can only
+// be parsed with the proof check disabled.
+func parseSDJWTVC(vc string, opts *Opts) (*Credential, error) {
+	var parseOpts []sdjwtvc.Opt
+
+	if opts.disableProofCheck {
+		parseOpts = append(parseOpts, sdjwtvc.WithDisabledProofCheck())
+	}
+
+	verifiableCredential, err := sdjwtvc.Parse(vc, parseOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCredential(verifiableCredential), nil
+}
+
+// parseMdoc parses an mdoc, either as issued or as serialized by Credential.Serialize. Unlike other credentials, the
+// issuer's signature can be checked here since the issuer's certificate chain is contained in the mdoc itself.
+func parseMdoc(vc string, opts *Opts) (*Credential, error) {
+	var parseOpts []mdoc.Opt
+
+	if opts.disableProofCheck {
+		parseOpts = append(parseOpts, mdoc.WithDisabledProofCheck())
+	}
+
+	verifiableCredential, err := mdoc.ParseCredential([]byte(vc), parseOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCredential(verifiableCredential), nil
+}
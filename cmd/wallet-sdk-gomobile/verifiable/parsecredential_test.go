@@ -23,6 +23,12 @@ var universityDegreeCredential string
 //go:embed testdata/credential_university_degree_without_name.jsonld
 var universityDegreeCredentialWithoutName string
 
+//go:embed testdata/credential_pid.sd-jwt
+var pidSDJWTVC string
+
+//go:embed testdata/credential_mdl.mdoc
+var mDLMdoc string
+
 func TestParse(t *testing.T) {
 	t.Run("Success - default options", func(t *testing.T) {
 		opts := verifiable.NewOpts()
@@ -60,6 +66,76 @@ func TestParse(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, "http://example.edu/credentials/1872", universityDegreeVC.VC.Contents().ID)
 	})
+	t.Run("Success - SD-JWT VC", func(t *testing.T) {
+		opts := verifiable.NewOpts()
+		opts.DisableProofCheck()
+
+		pidVC, err := verifiable.ParseCredential(pidSDJWTVC, opts)
+		require.NoError(t, err)
+		require.Equal(t, []string{"VerifiableCredential", "urn:eu.europa.ec.eudi:pid:1"}, pidVC.Types().Strings)
+		require.Equal(t, "https://issuer.example.com", pidVC.IssuerID())
+		require.Equal(t, int64(1700000000), pidVC.IssuanceDate())
+
+		serializedVC, err := pidVC.Serialize()
+		require.NoError(t, err)
+		require.Equal(t, `"`+pidSDJWTVC+`"`, serializedVC)
+
+		reparsedVC, err := verifiable.ParseCredential(serializedVC, opts)
+		require.NoError(t, err)
+		require.Equal(t, pidVC.Types().Strings, reparsedVC.Types().Strings)
+	})
+	t.Run("Failure - SD-JWT VC with proof check enabled", func(t *testing.T) {
+		pidVC, err := verifiable.ParseCredential(pidSDJWTVC, nil)
+		require.ErrorContains(t, err, "jwt proofChecker is not defined")
+		require.Nil(t, pidVC)
+	})
+	t.Run("Success - mdoc", func(t *testing.T) {
+		mDLVC, err := verifiable.ParseCredential(mDLMdoc, nil)
+		require.NoError(t, err)
+		require.Equal(t, []string{"VerifiableCredential", "org.iso.18013.5.1.mDL"}, mDLVC.Types().Strings)
+		require.Empty(t, mDLVC.IssuerID())
+		require.True(t, mDLVC.HasExpirationDate())
+		require.True(t, mDLVC.IsMdoc())
+
+		mDL, err := mDLVC.Mdoc()
+		require.NoError(t, err)
+		require.Equal(t, "org.iso.18013.5.1.mDL", mDL.DocType())
+		require.Equal(t, "CN=Example Document Signer,C=US", mDL.IssuerCertificateSubject())
+		require.Equal(t, mDLVC.ExpirationDate(), mDL.ValidUntil())
+		require.Less(t, mDL.ValidFrom(), mDL.ValidUntil())
+
+		elements := mDL.Elements()
+		require.Equal(t, 5, elements.Length())
+		require.Nil(t, elements.AtIndex(5))
+
+		birthDate := elements.AtIndex(1)
+		require.Equal(t, "org.iso.18013.5.1", birthDate.NameSpace())
+		require.Equal(t, "birth_date", birthDate.Identifier())
+
+		birthDateJSON, err := birthDate.ValueJSON()
+		require.NoError(t, err)
+		require.Equal(t, `"1971-09-01"`, birthDateJSON)
+
+		serializedVC, err := mDLVC.Serialize()
+		require.NoError(t, err)
+
+		reparsedVC, err := verifiable.ParseCredential(serializedVC, nil)
+		require.NoError(t, err)
+		require.Equal(t, mDLVC.ID(), reparsedVC.ID())
+		require.True(t, reparsedVC.IsMdoc())
+	})
+	t.Run("Failure - credential that isn't an mdoc", func(t *testing.T) {
+		opts := verifiable.NewOpts()
+		opts.DisableProofCheck()
+
+		pidVC, err := verifiable.ParseCredential(pidSDJWTVC, opts)
+		require.NoError(t, err)
+		require.False(t, pidVC.IsMdoc())
+
+		mDL, err := pidVC.Mdoc()
+		require.EqualError(t, err, "credential is not an mdoc")
+		require.Nil(t, mDL)
+	})
 	t.Run("Failure - blank VC", func(t *testing.T) {
 		opts := &verifiable.Opts{}
 		opts.SetDocumentLoader(&documentLoaderMock{})
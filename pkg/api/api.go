@@ -76,6 +76,37 @@ type JWTSigner interface {
 	CreateJWTHeaders(sigParams jwt.SignParameters) (jose.Headers, error)
 }
 
+// PublicKeyProvider is an optional interface that a JWTSigner can implement in order to expose the public key
+// that corresponds to its signing key. Signers used for DPoP (RFC 9449) must implement it, since DPoP proofs embed
+// the signer's public key.
+type PublicKeyProvider interface {
+	PublicJWK() (*jwk.JWK, error)
+}
+
+// CryptoProvider is an optional interface that a JWTSigner can implement in order to expose the Crypto
+// implementation that holds its signing key. Signers used to create linked data (ldp_vp) or data integrity (di_vp)
+// key proofs for OpenID4CI credential requests must implement it, since those proofs aren't JWTs.
+type CryptoProvider interface {
+	Crypto() Crypto
+}
+
+// KeyAttestationRequirements describes the key attestations that an issuer requires in key proofs, as given by the
+// key_attestations_required parameter in its metadata. An empty list means that the issuer accepts any value.
+type KeyAttestationRequirements struct {
+	// The accepted attack potential resistance levels of the storage that the key is held in (e.g. iso_18045_high).
+	KeyStorage []string
+	// The accepted attack potential resistance levels of the user authentication that protects the key.
+	UserAuthentication []string
+}
+
+// KeyAttestationProvider provides key attestations, which vouch for how a wallet's key is stored and protected (e.g.
+// in secure hardware). Issuers may require a key attestation to be included in the key proofs of credential requests.
+type KeyAttestationProvider interface {
+	// GetKeyAttestation returns a key attestation JWT for the key that the given signer uses. The nonce is the
+	// issuer's c_nonce, which the attestation should include if it isn't empty.
+	GetKeyAttestation(signer JWTSigner, requirements *KeyAttestationRequirements, nonce string) (string, error)
+}
+
 // JSONWebKeySet represents a JWK Set object.
 // It uses the JWK type from aries-framework-go.
 type JSONWebKeySet struct {
@@ -8,7 +8,9 @@ package attestation
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -29,13 +31,17 @@ const (
 	jwtProofTypeHeader              = "openid4vci-proof+jwt"
 	attestationInitReqEventText     = "perform init attestation request"
 	attestationCompleteReqEventText = "perform complete attestation request"
+	keyAttestationReqEventText      = "perform key attestation request"
 	challengeVCLifetime             = 5
 )
 
 // ServerAPI separates the HTTP client from the main client logic.
 type ServerAPI interface {
-	AttestationInit(req AttestWalletInitRequest, attestationURL string) (*AttestWalletInitResponse, error)
-	AttestationComplete(req AttestWalletCompleteRequest, attestationURL string) (*AttestWalletCompleteResponse, error)
+	AttestationInit(ctx context.Context, req AttestWalletInitRequest,
+		attestationURL string) (*AttestWalletInitResponse, error)
+	AttestationComplete(ctx context.Context, req AttestWalletCompleteRequest,
+		attestationURL string) (*AttestWalletCompleteResponse, error)
+	KeyAttestation(ctx context.Context, req AttestKeyRequest, attestationURL string) (*AttestKeyResponse, error)
 }
 
 // Client is the client for the attestation service.
@@ -82,12 +88,21 @@ func (c *Client) GetAttestationVC(
 	attestationRequest AttestWalletInitRequest,
 	signer api.JWTSigner,
 ) (*verifiable.Credential, error) {
-	initResp, err := c.api.AttestationInit(attestationRequest, c.attestationURL)
+	return c.GetAttestationVCContext(context.Background(), attestationRequest, signer)
+}
+
+// GetAttestationVCContext is the same as GetAttestationVC, but calls the attestation service using the given context.
+func (c *Client) GetAttestationVCContext(
+	ctx context.Context,
+	attestationRequest AttestWalletInitRequest,
+	signer api.JWTSigner,
+) (*verifiable.Credential, error) {
+	initResp, err := c.api.AttestationInit(ctx, attestationRequest, c.attestationURL)
 	if err != nil {
 		return nil, err
 	}
 
-	completeResp, err := c.attestationComplete(initResp.SessionID, initResp.Challenge, signer)
+	completeResp, err := c.attestationComplete(ctx, initResp.SessionID, initResp.Challenge, signer)
 	if err != nil {
 		return nil, err
 	}
@@ -108,16 +123,104 @@ func (c *Client) GetAttestationVC(
 	return attestationVC, nil
 }
 
+// GetKeyAttestation requests a key attestation for the given signer's key from the attestation service. The returned
+// key attestation JWT can be included in the key proofs sent to issuers that require key attestations.
+// If set, the key attestation will meet the given requirements and include the given nonce.
+func (c *Client) GetKeyAttestation(
+	attestationRequest AttestWalletInitRequest,
+	signer api.JWTSigner,
+	requirements *api.KeyAttestationRequirements,
+	nonce string,
+) (string, error) {
+	return c.GetKeyAttestationContext(context.Background(), attestationRequest, signer, requirements, nonce)
+}
+
+// GetKeyAttestationContext is the same as GetKeyAttestation, but calls the attestation service using the given
+// context.
+func (c *Client) GetKeyAttestationContext(
+	ctx context.Context,
+	attestationRequest AttestWalletInitRequest,
+	signer api.JWTSigner,
+	requirements *api.KeyAttestationRequirements,
+	nonce string,
+) (string, error) {
+	initResp, err := c.api.AttestationInit(ctx, attestationRequest, c.attestationURL)
+	if err != nil {
+		return "", err
+	}
+
+	proofJWT, err := c.createProofJWT(initResp.Challenge, signer)
+	if err != nil {
+		return "", err
+	}
+
+	req := AttestKeyRequest{
+		Proof: Proof{
+			Jwt:       proofJWT,
+			ProofType: "jwt",
+		},
+		SessionID: initResp.SessionID,
+		Nonce:     nonce,
+	}
+
+	if requirements != nil {
+		req.KeyStorage = requirements.KeyStorage
+		req.UserAuthentication = requirements.UserAuthentication
+	}
+
+	resp, err := c.api.KeyAttestation(ctx, req, c.attestationURL)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.KeyAttestation == "" {
+		return "", walleterror.NewExecutionError(
+			ErrorModule,
+			KeyAttestationMissingCode,
+			KeyAttestationMissingError,
+			errors.New("the attestation service did not return a key attestation"))
+	}
+
+	return resp.KeyAttestation, nil
+}
+
+// KeyAttestationProvider returns an api.KeyAttestationProvider that gets key attestations from the attestation
+// service, using the given attestation request each time.
+func (c *Client) KeyAttestationProvider(attestationRequest AttestWalletInitRequest) api.KeyAttestationProvider {
+	return &keyAttestationProvider{client: c, attestationRequest: attestationRequest}
+}
+
 func (c *Client) attestationComplete(
+	ctx context.Context,
 	sessionID,
 	challenge string,
 	signer api.JWTSigner,
 ) (*AttestWalletCompleteResponse, error) {
-	did, err := getSignerDID(signer)
+	jws, err := c.createProofJWT(challenge, signer)
 	if err != nil {
 		return nil, err
 	}
 
+	req := AttestWalletCompleteRequest{
+		AssuranceLevel: "low",
+		Proof: Proof{
+			Jwt:       jws,
+			ProofType: "jwt",
+		},
+		SessionID: sessionID,
+	}
+
+	return c.api.AttestationComplete(ctx, req, c.attestationURL)
+}
+
+// createProofJWT creates a JWT that proves possession of the signer's key, using the challenge from the attestation
+// service as the nonce.
+func (c *Client) createProofJWT(challenge string, signer api.JWTSigner) (string, error) {
+	did, err := getSignerDID(signer)
+	if err != nil {
+		return "", err
+	}
+
 	claims := &jwtProofClaims{
 		Issuer:   did,
 		Audience: c.attestationURL,
@@ -132,7 +235,7 @@ func (c *Client) attestationComplete(
 
 	signedJWT, err := jwt.NewSigned(claims, jwt.SignParameters{AdditionalHeaders: headers}, signer)
 	if err != nil {
-		return nil, walleterror.NewExecutionError(
+		return "", walleterror.NewExecutionError(
 			ErrorModule,
 			JWTSigningFailedCode,
 			JWTSigningFailedError,
@@ -141,19 +244,21 @@ func (c *Client) attestationComplete(
 
 	jws, err := signedJWT.Serialize(false)
 	if err != nil {
-		return nil, fmt.Errorf("serialize signed jwt: %w", err)
+		return "", fmt.Errorf("serialize signed jwt: %w", err)
 	}
 
-	req := AttestWalletCompleteRequest{
-		AssuranceLevel: "low",
-		Proof: Proof{
-			Jwt:       jws,
-			ProofType: "jwt",
-		},
-		SessionID: sessionID,
-	}
+	return jws, nil
+}
 
-	return c.api.AttestationComplete(req, c.attestationURL)
+type keyAttestationProvider struct {
+	client             *Client
+	attestationRequest AttestWalletInitRequest
+}
+
+func (p *keyAttestationProvider) GetKeyAttestation(signer api.JWTSigner,
+	requirements *api.KeyAttestationRequirements, nonce string,
+) (string, error) {
+	return p.client.GetKeyAttestation(p.attestationRequest, signer, requirements, nonce)
 }
 
 func getSignerDID(jwtSigner api.JWTSigner) (string, error) {
@@ -179,6 +284,7 @@ type serverAPI struct {
 }
 
 func (a *serverAPI) AttestationInit(
+	ctx context.Context,
 	req AttestWalletInitRequest,
 	attestationURL string,
 ) (*AttestWalletInitResponse, error) {
@@ -189,7 +295,8 @@ func (a *serverAPI) AttestationInit(
 
 	var resp AttestWalletInitResponse
 
-	err = httprequest.New(a.httpClient, a.metricsLogger).DoAndParse(
+	err = httprequest.New(a.httpClient, a.metricsLogger).DoAndParseContext(
+		ctx,
 		http.MethodPost,
 		attestationURL+"/init",
 		"application/json",
@@ -202,6 +309,7 @@ func (a *serverAPI) AttestationInit(
 }
 
 func (a *serverAPI) AttestationComplete(
+	ctx context.Context,
 	req AttestWalletCompleteRequest,
 	attestationURL string,
 ) (*AttestWalletCompleteResponse, error) {
@@ -212,7 +320,8 @@ func (a *serverAPI) AttestationComplete(
 
 	var resp AttestWalletCompleteResponse
 
-	err = httprequest.New(a.httpClient, a.metricsLogger).DoAndParse(
+	err = httprequest.New(a.httpClient, a.metricsLogger).DoAndParseContext(
+		ctx,
 		http.MethodPost,
 		attestationURL+"/complete",
 		"application/json",
@@ -223,3 +332,28 @@ func (a *serverAPI) AttestationComplete(
 
 	return &resp, nil
 }
+
+func (a *serverAPI) KeyAttestation(
+	ctx context.Context,
+	req AttestKeyRequest,
+	attestationURL string,
+) (*AttestKeyResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	var resp AttestKeyResponse
+
+	err = httprequest.New(a.httpClient, a.metricsLogger).DoAndParseContext(
+		ctx,
+		http.MethodPost,
+		attestationURL+"/key-attestation",
+		"application/json",
+		bytes.NewBuffer(body), keyAttestationReqEventText, "", nil, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("do request: %w", err)
+	}
+
+	return &resp, nil
+}
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package attestation_test
 
 import (
+	"context"
 	_ "embed"
 	"errors"
 	"testing"
@@ -16,6 +17,7 @@ import (
 	"github.com/trustbloc/vc-go/jwt"
 
 	"github.com/trustbloc/wallet-sdk/internal/testutil"
+	"github.com/trustbloc/wallet-sdk/pkg/api"
 	"github.com/trustbloc/wallet-sdk/pkg/attestation"
 )
 
@@ -154,15 +156,107 @@ func TestClient_GetAttestationVC(t *testing.T) {
 	})
 }
 
+func TestClient_GetKeyAttestation(t *testing.T) {
+	initReq := attestation.AttestWalletInitRequest{
+		Payload: map[string]interface{}{
+			"type": "urn:attestation:application:trustbloc",
+		},
+	}
+
+	newServerAPIMock := func() *serverAPIMock {
+		return &serverAPIMock{
+			attestationInitResp: &attestation.AttestWalletInitResponse{
+				Challenge: "1224",
+				SessionID: "7446",
+			},
+			keyAttestationResp: &attestation.AttestKeyResponse{
+				KeyAttestation: "key-attestation-jwt",
+			},
+		}
+	}
+
+	newClient := func(serverAPI *serverAPIMock) *attestation.Client {
+		return attestation.NewClient(&attestation.ClientConfig{
+			DocumentLoader: testutil.DocumentLoader(t),
+			AttestationURL: "https://attestation.com",
+			CustomAPI:      serverAPI,
+		})
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		serverAPI := newServerAPIMock()
+
+		keyAttestation, err := newClient(serverAPI).GetKeyAttestation(initReq, &jwtSignerMock{keyID: mockKeyID},
+			&api.KeyAttestationRequirements{KeyStorage: []string{"iso_18045_high"}}, "nonce")
+		require.NoError(t, err)
+		require.Equal(t, "key-attestation-jwt", keyAttestation)
+
+		require.Equal(t, "7446", serverAPI.keyAttestationReq.SessionID)
+		require.Equal(t, "nonce", serverAPI.keyAttestationReq.Nonce)
+		require.Equal(t, []string{"iso_18045_high"}, serverAPI.keyAttestationReq.KeyStorage)
+		require.Empty(t, serverAPI.keyAttestationReq.UserAuthentication)
+		require.Equal(t, "jwt", serverAPI.keyAttestationReq.Proof.ProofType)
+		require.NotEmpty(t, serverAPI.keyAttestationReq.Proof.Jwt)
+	})
+
+	t.Run("Success using the client as a key attestation provider", func(t *testing.T) {
+		serverAPI := newServerAPIMock()
+
+		var provider api.KeyAttestationProvider = newClient(serverAPI).KeyAttestationProvider(initReq)
+
+		keyAttestation, err := provider.GetKeyAttestation(&jwtSignerMock{keyID: mockKeyID}, nil, "")
+		require.NoError(t, err)
+		require.Equal(t, "key-attestation-jwt", keyAttestation)
+		require.Empty(t, serverAPI.keyAttestationReq.KeyStorage)
+	})
+
+	t.Run("Init failed", func(t *testing.T) {
+		serverAPI := newServerAPIMock()
+		serverAPI.attestationInitErr = errors.New("init error")
+
+		_, err := newClient(serverAPI).GetKeyAttestation(initReq, &jwtSignerMock{keyID: mockKeyID}, nil, "")
+		require.ErrorContains(t, err, "init error")
+	})
+
+	t.Run("Sign failed", func(t *testing.T) {
+		_, err := newClient(newServerAPIMock()).GetKeyAttestation(initReq, &jwtSignerMock{
+			keyID: mockKeyID,
+			Err:   errors.New("sign error"),
+		}, nil, "")
+		require.ErrorContains(t, err, "JWT_SIGNING_FAILED")
+	})
+
+	t.Run("Key attestation request failed", func(t *testing.T) {
+		serverAPI := newServerAPIMock()
+		serverAPI.keyAttestationErr = errors.New("key attestation error")
+
+		_, err := newClient(serverAPI).GetKeyAttestation(initReq, &jwtSignerMock{keyID: mockKeyID}, nil, "")
+		require.ErrorContains(t, err, "key attestation error")
+	})
+
+	t.Run("No key attestation returned", func(t *testing.T) {
+		serverAPI := newServerAPIMock()
+		serverAPI.keyAttestationResp = &attestation.AttestKeyResponse{}
+
+		_, err := newClient(serverAPI).GetKeyAttestation(initReq, &jwtSignerMock{keyID: mockKeyID}, nil, "")
+		require.ErrorContains(t, err, "KEY_ATTESTATION_MISSING")
+	})
+}
+
 type serverAPIMock struct {
 	attestationInitResp *attestation.AttestWalletInitResponse
 	attestationInitErr  error
 
 	attestationCompleteResp *attestation.AttestWalletCompleteResponse
 	attestationCompleteErr  error
+
+	keyAttestationResp *attestation.AttestKeyResponse
+	keyAttestationErr  error
+	keyAttestationReq  *attestation.AttestKeyRequest
 }
 
 func (a *serverAPIMock) AttestationInit(
+	_ context.Context,
 	_ attestation.AttestWalletInitRequest,
 	_ string,
 ) (*attestation.AttestWalletInitResponse, error) {
@@ -174,6 +268,7 @@ func (a *serverAPIMock) AttestationInit(
 }
 
 func (a *serverAPIMock) AttestationComplete(
+	_ context.Context,
 	_ attestation.AttestWalletCompleteRequest,
 	_ string,
 ) (*attestation.AttestWalletCompleteResponse, error) {
@@ -184,6 +279,20 @@ func (a *serverAPIMock) AttestationComplete(
 	return a.attestationCompleteResp, nil
 }
 
+func (a *serverAPIMock) KeyAttestation(
+	_ context.Context,
+	req attestation.AttestKeyRequest,
+	_ string,
+) (*attestation.AttestKeyResponse, error) {
+	a.keyAttestationReq = &req
+
+	if a.keyAttestationErr != nil {
+		return nil, a.keyAttestationErr
+	}
+
+	return a.keyAttestationResp, nil
+}
+
 type jwtSignerMock struct {
 	keyID string
 	Err   error
@@ -14,6 +14,7 @@ const (
 	JWTSigningFailedError         = "JWT_SIGNING_FAILED"
 	KeyIDMissingDIDPartError      = "KEY_ID_MISSING_DID_PART"
 	ParseAttestationVCFailedError = "PARSE_ATTESTATION_VC_FAILED"
+	KeyAttestationMissingError    = "KEY_ATTESTATION_MISSING"
 )
 
 // Constants' names and reasons are obvious, so they do not require additional comments.
@@ -22,4 +23,5 @@ const (
 	JWTSigningFailedCode         = 1
 	KeyIDMissingDIDPartCode      = 2
 	ParseAttestationVCFailedCode = 3
+	KeyAttestationMissingCode    = 4
 )
@@ -42,3 +42,17 @@ type jwtProofClaims struct {
 type AttestWalletCompleteResponse struct {
 	WalletAttestationVC string `json:"wallet_attestation_vc"`
 }
+
+// AttestKeyRequest key attestation request.
+type AttestKeyRequest struct {
+	Proof              Proof    `json:"proof"`
+	SessionID          string   `json:"session_id"`
+	KeyStorage         []string `json:"key_storage,omitempty"`
+	UserAuthentication []string `json:"user_authentication,omitempty"`
+	Nonce              string   `json:"nonce,omitempty"`
+}
+
+// AttestKeyResponse key attestation response.
+type AttestKeyResponse struct {
+	KeyAttestation string `json:"key_attestation"`
+}
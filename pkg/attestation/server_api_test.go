@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package attestation //nolint: testpackage
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -46,7 +47,7 @@ func TestAttestationInitAPI(t *testing.T) {
 
 		api := &serverAPI{httpClient: client, metricsLogger: noopmetricslogger.NewMetricsLogger()}
 
-		resp, err := api.AttestationInit(sampleRequest, "https://testurl")
+		resp, err := api.AttestationInit(context.Background(), sampleRequest, "https://testurl")
 		require.NoError(t, err)
 		require.Equal(t, "challenge", resp.Challenge)
 		require.Equal(t, "session_id", resp.SessionID)
@@ -59,7 +60,7 @@ func TestAttestationInitAPI(t *testing.T) {
 
 		api := &serverAPI{httpClient: client, metricsLogger: noopmetricslogger.NewMetricsLogger()}
 
-		_, err := api.AttestationInit(sampleRequest, "https://testurl")
+		_, err := api.AttestationInit(context.Background(), sampleRequest, "https://testurl")
 		require.ErrorContains(t, err, "expected status code 200 but got status code 500")
 	})
 
@@ -70,7 +71,7 @@ func TestAttestationInitAPI(t *testing.T) {
 
 		api := &serverAPI{httpClient: client, metricsLogger: noopmetricslogger.NewMetricsLogger()}
 
-		_, err := api.AttestationInit(AttestWalletInitRequest{
+		_, err := api.AttestationInit(context.Background(), AttestWalletInitRequest{
 			Payload: map[string]interface{}{
 				"sss": make(chan string),
 			},
@@ -105,7 +106,7 @@ func TestAttestationCompletedAPI(t *testing.T) {
 
 		api := &serverAPI{httpClient: client, metricsLogger: noopmetricslogger.NewMetricsLogger()}
 
-		resp, err := api.AttestationComplete(sampleRequest, "https://testurl")
+		resp, err := api.AttestationComplete(context.Background(), sampleRequest, "https://testurl")
 		require.NoError(t, err)
 		require.Equal(t, "wallet_attestation_vc", resp.WalletAttestationVC)
 	})
@@ -117,7 +118,48 @@ func TestAttestationCompletedAPI(t *testing.T) {
 
 		api := &serverAPI{httpClient: client, metricsLogger: noopmetricslogger.NewMetricsLogger()}
 
-		_, err := api.AttestationComplete(sampleRequest, "https://testurl")
+		_, err := api.AttestationComplete(context.Background(), sampleRequest, "https://testurl")
+		require.ErrorContains(t, err, "expected status code 200 but got status code 500")
+	})
+}
+
+func TestKeyAttestationAPI(t *testing.T) {
+	sampleRequest := AttestKeyRequest{
+		Proof: Proof{
+			Jwt:       "jwt",
+			ProofType: "jwt",
+		},
+		SessionID:  "123",
+		KeyStorage: []string{"iso_18045_high"},
+		Nonce:      "nonce",
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		resultReq := AttestKeyRequest{}
+
+		client := &mock.HTTPClientMock{
+			StatusCode:          200,
+			ExpectedEndpoint:    "https://testurl/key-attestation",
+			Response:            `{"key_attestation": "key_attestation"}`,
+			SentBodyUnMarshaled: &resultReq,
+		}
+
+		api := &serverAPI{httpClient: client, metricsLogger: noopmetricslogger.NewMetricsLogger()}
+
+		resp, err := api.KeyAttestation(context.Background(), sampleRequest, "https://testurl")
+		require.NoError(t, err)
+		require.Equal(t, "key_attestation", resp.KeyAttestation)
+		require.Equal(t, sampleRequest, resultReq)
+	})
+
+	t.Run("Request failed", func(t *testing.T) {
+		client := &mock.HTTPClientMock{
+			StatusCode: 500,
+		}
+
+		api := &serverAPI{httpClient: client, metricsLogger: noopmetricslogger.NewMetricsLogger()}
+
+		_, err := api.KeyAttestation(context.Background(), sampleRequest, "https://testurl")
 		require.ErrorContains(t, err, "expected status code 200 but got status code 500")
 	})
 }
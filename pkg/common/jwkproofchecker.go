@@ -0,0 +1,37 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"github.com/trustbloc/kms-go/doc/jose/jwk"
+	"github.com/trustbloc/vc-go/crypto-ext/verifiers/ecdsa"
+	"github.com/trustbloc/vc-go/crypto-ext/verifiers/ed25519"
+	"github.com/trustbloc/vc-go/crypto-ext/verifiers/rsa"
+	"github.com/trustbloc/vc-go/proof"
+	"github.com/trustbloc/vc-go/proof/checker"
+	"github.com/trustbloc/vc-go/proof/jwtproofs/eddsa"
+	"github.com/trustbloc/vc-go/proof/jwtproofs/es256"
+	"github.com/trustbloc/vc-go/proof/jwtproofs/es256k"
+	"github.com/trustbloc/vc-go/proof/jwtproofs/es384"
+	"github.com/trustbloc/vc-go/proof/jwtproofs/es521"
+	"github.com/trustbloc/vc-go/proof/jwtproofs/ps256"
+	"github.com/trustbloc/vc-go/proof/jwtproofs/rs256"
+)
+
+// NewJWKProofChecker returns a proof checker that verifies JWT signatures using the given public key. Unlike the
+// checkers that resolve keys from DIDs, the kid header of the JWT (if any) isn't used to find the key.
+func NewJWKProofChecker(publicKey *jwk.JWK) *checker.EmbeddedVMProofChecker {
+	jwtCheckers := []proof.JWTProofDescriptor{
+		eddsa.New(), es256.New(), es256k.New(), es384.New(), es521.New(), rs256.New(), ps256.New(),
+	}
+
+	return checker.NewEmbeddedJWKProofChecker(publicKey,
+		checker.WithSignatureVerifiers(ed25519.New(), rsa.NewPS256(), rsa.NewRS256(),
+			ecdsa.NewSecp256k1(), ecdsa.NewES256(), ecdsa.NewES384(), ecdsa.NewES521()),
+		checker.WithJWTAlg(jwtCheckers...),
+	)
+}
@@ -8,12 +8,14 @@ package common
 
 import (
 	cryptolib "crypto"
+	"crypto/ed25519"
 	"encoding/base64"
 	"errors"
 	"fmt"
 
 	"github.com/trustbloc/kms-go/doc/jose"
 	"github.com/trustbloc/kms-go/doc/jose/jwk"
+	"github.com/trustbloc/kms-go/doc/jose/jwk/jwksupport"
 	"github.com/trustbloc/kms-go/doc/util/jwkkid"
 	"github.com/trustbloc/kms-go/spi/kms"
 	"github.com/trustbloc/vc-go/jwt"
@@ -41,6 +43,7 @@ type JWSSigner struct {
 	algorithm string
 	cryptoKID string
 	crypto    api.Crypto
+	vm        *models.VerificationMethod
 }
 
 // NewJWSSigner creates jwt signer.
@@ -69,6 +72,7 @@ func NewJWSSigner(vm *models.VerificationMethod, crypto api.Crypto) (*JWSSigner,
 		algorithm: algorithm,
 		crypto:    crypto,
 		cryptoKID: cryptoKID,
+		vm:        vm,
 	}, nil
 }
 
@@ -142,3 +146,27 @@ func (s *JWSSigner) CreateJWTHeaders(_ jwt.SignParameters) (jose.Headers, error)
 		jose.HeaderAlgorithm: s.algorithm,
 	}, nil
 }
+
+// Crypto returns the Crypto implementation used to sign.
+func (s *JWSSigner) Crypto() api.Crypto {
+	return s.crypto
+}
+
+// PublicJWK returns the public key that corresponds to the signing key.
+func (s *JWSSigner) PublicJWK() (*jwk.JWK, error) {
+	if s.vm.Type == Ed25519VerificationKey2018 {
+		return jwksupport.JWKFromKey(ed25519.PublicKey(s.vm.Key.Raw))
+	}
+
+	key := s.vm.Key.JSONWebKey
+	if key.IsPublic() {
+		return key, nil
+	}
+
+	publicKey := key.Public()
+	if !publicKey.Valid() {
+		return nil, errors.New("unable to determine the public key from the verification method's JWK")
+	}
+
+	return &jwk.JWK{JSONWebKey: publicKey, Kty: key.Kty, Crv: key.Crv}, nil
+}
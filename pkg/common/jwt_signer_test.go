@@ -82,6 +82,10 @@ func TestNewJWSSigner(t *testing.T) {
 				require.NotNil(t, signer)
 				alg := signer.Algorithm()
 				require.Equal(t, successCase.expectedAlg, alg)
+
+				publicJWK, err := signer.PublicJWK()
+				require.NoError(t, err)
+				require.True(t, publicJWK.IsPublic())
 			})
 		}
 	})
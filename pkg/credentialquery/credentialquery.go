@@ -4,7 +4,7 @@ Copyright Avast Software. All Rights Reserved.
 SPDX-License-Identifier: Apache-2.0
 */
 
-// Package credentialquery allows querying credentials using presentation definition.
+// Package credentialquery allows querying credentials using presentation definition or DCQL.
 package credentialquery
 
 import (
@@ -16,6 +16,7 @@ import (
 
 	"github.com/trustbloc/wallet-sdk/pkg/api"
 	"github.com/trustbloc/wallet-sdk/pkg/common"
+	"github.com/trustbloc/wallet-sdk/pkg/dcql"
 	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
 )
 
@@ -99,3 +100,24 @@ func (c *Instance) GetSubmissionRequirements(
 
 	return results, nil
 }
+
+// GetDCQLMatches returns the VCs that match each credential query of the given DCQL query, along with whether the
+// query can be satisfied using them.
+func (c *Instance) GetDCQLMatches(query *dcql.Query, opts ...QueryOpt) (*dcql.Result, error) {
+	qOpts := &queryOpts{}
+	for _, opt := range opts {
+		opt(qOpts)
+	}
+
+	result, err := dcql.Match(query, qOpts.credentials)
+	if err != nil {
+		return nil,
+			walleterror.NewValidationError(
+				module,
+				FailToMatchDCQLQueryCode,
+				FailToMatchDCQLQueryError,
+				err)
+	}
+
+	return result, nil
+}
@@ -19,6 +19,7 @@ import (
 
 	"github.com/trustbloc/wallet-sdk/internal/testutil"
 	"github.com/trustbloc/wallet-sdk/pkg/credentialquery"
+	"github.com/trustbloc/wallet-sdk/pkg/dcql"
 )
 
 var (
@@ -98,6 +99,41 @@ func TestInstance_GetSubmissionRequirements(t *testing.T) {
 	})
 }
 
+func TestInstance_GetDCQLMatches(t *testing.T) {
+	docLoader := testutil.DocumentLoader(t)
+
+	var credentials []*verifiable.Credential
+
+	for _, credContent := range [][]byte{universityDegreeVC, driverLicenseVC} {
+		cred, credErr := verifiable.ParseCredential(credContent, verifiable.WithDisabledProofCheck(),
+			verifiable.WithJSONLDDocumentLoader(docLoader))
+		require.NoError(t, credErr)
+
+		credentials = append(credentials, cred)
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		query, err := dcql.Parse([]byte(`{"credentials": [{"id": "degree", "format": "jwt_vc_json",
+			"meta": {"type_values": [["UniversityDegreeCredential"]]}}]}`))
+		require.NoError(t, err)
+
+		instance := credentialquery.NewInstance(docLoader)
+		result, err := instance.GetDCQLMatches(query, credentialquery.WithCredentialsArray(credentials))
+		require.NoError(t, err)
+		require.True(t, result.Satisfied)
+		require.Len(t, result.CredentialMatch("degree").Credentials, 1)
+		require.Equal(t, credentials[0], result.CredentialMatch("degree").Credentials[0].Credential)
+	})
+
+	t.Run("Query not provided", func(t *testing.T) {
+		instance := credentialquery.NewInstance(docLoader)
+		result, err := instance.GetDCQLMatches(nil, credentialquery.WithCredentialsArray(credentials))
+
+		testutil.RequireErrorContains(t, err, "FAIL_TO_MATCH_DCQL_QUERY")
+		require.Nil(t, result)
+	})
+}
+
 type didResolverMock struct {
 	ResolveValue *did.DocResolution
 	ResolveErr   error
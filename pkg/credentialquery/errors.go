@@ -10,4 +10,6 @@ const (
 	module                                 = "CRQ"
 	FailToGetMatchRequirementsResultsError = "FAIL_TO_GET_MATCH_REQUIREMENTS_RESULTS"
 	FailToGetMatchRequirementsResultsCode  = 4
+	FailToMatchDCQLQueryError              = "FAIL_TO_MATCH_DCQL_QUERY"
+	FailToMatchDCQLQueryCode               = 5
 )
@@ -110,7 +110,7 @@ func haveMatchingTypes(credentialConfSupported *issuer.CredentialConfigurationSu
 			continue
 		}
 
-		for _, typeFromSupportedCredential := range credentialConfSupported.CredentialDefinition.Type {
+		for _, typeFromSupportedCredential := range credentialConfSupported.CredentialTypes() {
 			if strings.EqualFold(typeFromVC, typeFromSupportedCredential) {
 				return true
 			}
@@ -181,7 +181,7 @@ func resolveClaims(
 ) ([]ResolvedClaim, error) {
 	var resolvedClaims []ResolvedClaim
 
-	for fieldName, claim := range credentialConfigurationSupported.CredentialDefinition.CredentialSubject {
+	for fieldName, claim := range credentialConfigurationSupported.CredentialSubjectClaims() {
 		resolvedClaim, err := resolveClaim(
 			fieldName, claim, vc, credentialSubject, credentialConfigurationSupported, preferredLocale, maskingString)
 		if err != nil && !errors.Is(err, errNoClaimDisplays) && !errors.Is(err, errClaimValueNotFoundInVC) {
@@ -362,7 +362,10 @@ func findMatchingClaimValueInMap(claims map[string]interface{}, fieldName string
 	for _, value := range claims {
 		valueAsMap, ok := value.(map[string]interface{})
 		if ok {
-			return findMatchingClaimValueInMap(valueAsMap, fieldName)
+			claim = findMatchingClaimValueInMap(valueAsMap, fieldName)
+			if claim != nil {
+				return claim
+			}
 		}
 	}
 
@@ -502,7 +505,7 @@ func resolveClaimsAllLocale(
 ) ([]Subject, error) {
 	var resolvedClaims []Subject
 
-	for fieldName, claim := range credentialConfigurationSupported.CredentialDefinition.CredentialSubject {
+	for fieldName, claim := range credentialConfigurationSupported.CredentialSubjectClaims() {
 		if skipNonClaimData &&
 			strings.HasPrefix(fieldName, "$.") &&
 			!strings.HasPrefix(fieldName, "$.credentialSubject.") {
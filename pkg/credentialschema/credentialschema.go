@@ -8,6 +8,8 @@ SPDX-License-Identifier: Apache-2.0
 package credentialschema
 
 import (
+	"context"
+
 	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
 )
 
@@ -16,7 +18,13 @@ import (
 // same order.
 // This method requires one VC source and one issuer metadata source. See opts.go for more information.
 func Resolve(opts ...ResolveOpt) (*ResolvedDisplayData, error) {
-	credentialConfigMappings, issuerMetadata, preferredLocale, maskingString, err := processOpts(opts)
+	return ResolveContext(context.Background(), opts...)
+}
+
+// ResolveContext is the same as Resolve, but uses the given context for fetching the issuer's metadata (if an issuer
+// URI is used as the issuer metadata source).
+func ResolveContext(ctx context.Context, opts ...ResolveOpt) (*ResolvedDisplayData, error) {
+	credentialConfigMappings, issuerMetadata, preferredLocale, maskingString, err := processOpts(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -42,7 +50,13 @@ func Resolve(opts ...ResolveOpt) (*ResolvedDisplayData, error) {
 
 // ResolveCredential resolves display information for some issued credentials based on an issuer's metadata.
 func ResolveCredential(opts ...ResolveOpt) (*ResolvedData, error) {
-	credentialConfigMappings, issuerMetadata, _, maskingString, err := processOpts(opts)
+	return ResolveCredentialContext(context.Background(), opts...)
+}
+
+// ResolveCredentialContext is the same as ResolveCredential, but uses the given context for fetching the issuer's
+// metadata (if an issuer URI is used as the issuer metadata source).
+func ResolveCredentialContext(ctx context.Context, opts ...ResolveOpt) (*ResolvedData, error) {
+	credentialConfigMappings, issuerMetadata, _, maskingString, err := processOpts(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -18,9 +18,12 @@ import (
 	"github.com/trustbloc/vc-go/verifiable"
 
 	"github.com/trustbloc/wallet-sdk/pkg/credentialschema"
+	"github.com/trustbloc/wallet-sdk/pkg/mdoc"
 	"github.com/trustbloc/wallet-sdk/pkg/memstorage"
+	"github.com/trustbloc/wallet-sdk/pkg/metadatacache"
 	"github.com/trustbloc/wallet-sdk/pkg/metricslogger/noop"
 	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
+	"github.com/trustbloc/wallet-sdk/pkg/sdjwtvc"
 )
 
 const (
@@ -52,6 +55,12 @@ var (
 
 	//go:embed testdata/bank_issuer_metadata.json
 	bankIssuerMetadata []byte
+
+	//go:embed testdata/credential_pid.sd-jwt
+	credentialPIDSDJWTVC string
+
+	//go:embed testdata/credential_mdl.mdoc
+	credentialMDL string
 )
 
 type mockIssuerServerHandler struct{}
@@ -137,6 +146,34 @@ func TestResolve(t *testing.T) { //nolint:gocognit
 
 				checkSuccessCaseMatchedDisplayData(t, resolvedDisplayData)
 			})
+			t.Run("With issuer URI and a metadata cache", func(t *testing.T) {
+				var metadataRequests int
+
+				server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+					metadataRequests++
+
+					writer.Header().Set("Cache-Control", "max-age=300")
+
+					(&mockIssuerServerHandler{}).ServeHTTP(writer, request)
+				}))
+
+				defer server.Close()
+
+				cache := metadatacache.New()
+
+				for range 2 {
+					resolvedDisplayData, errResolve := credentialschema.Resolve(
+						credentialschema.WithCredentials([]*verifiable.Credential{credential}),
+						credentialschema.WithIssuerURI(server.URL),
+						credentialschema.WithJWTSignatureVerifier(&mockSignatureVerifier{}),
+						credentialschema.WithMetadataCache(cache))
+					require.NoError(t, errResolve)
+
+					checkSuccessCaseMatchedDisplayData(t, resolvedDisplayData)
+				}
+
+				require.Equal(t, 1, metadataRequests)
+			})
 
 			t.Run("Skip non claim data", func(t *testing.T) {
 				resolvedDisplayData, errResolve := credentialschema.Resolve(
@@ -392,6 +429,78 @@ func TestResolve(t *testing.T) { //nolint:gocognit
 
 			checkSDVCMatchedDisplayData(t, resolvedDisplayData)
 		})
+
+		t.Run("Display info for an SD-JWT VC", func(t *testing.T) {
+			credential, err := sdjwtvc.Parse(credentialPIDSDJWTVC, sdjwtvc.WithDisabledProofCheck())
+			require.NoError(t, err)
+
+			claims := map[string]interface{}{
+				"given_name": map[string]interface{}{
+					"display": []interface{}{map[string]interface{}{"name": "Given Name", "locale": "en-US"}},
+				},
+			}
+
+			issuerMetadata := &issuer.Metadata{
+				CredentialConfigurationsSupported: map[string]*issuer.CredentialConfigurationSupported{
+					"pid": {
+						Format: "dc+sd-jwt",
+						Vct:    "urn:eu.europa.ec.eudi:pid:1",
+						Claims: &claims,
+						LocalizedCredentialDisplays: []issuer.LocalizedCredentialDisplay{
+							{Name: "Personal ID", Locale: "en-US"},
+						},
+					},
+				},
+			}
+
+			resolvedDisplayData, err := credentialschema.Resolve(
+				credentialschema.WithCredentials([]*verifiable.Credential{credential}),
+				credentialschema.WithIssuerMetadata(issuerMetadata))
+			require.NoError(t, err)
+			require.Len(t, resolvedDisplayData.CredentialDisplays, 1)
+			require.Equal(t, "Personal ID", resolvedDisplayData.CredentialDisplays[0].Overview.Name)
+			require.Len(t, resolvedDisplayData.CredentialDisplays[0].Claims, 1)
+			require.Equal(t, "Given Name", resolvedDisplayData.CredentialDisplays[0].Claims[0].Label)
+			require.Equal(t, "Erika", resolvedDisplayData.CredentialDisplays[0].Claims[0].RawValue)
+		})
+		t.Run("Display info for an mdoc from a credential reader", func(t *testing.T) {
+			credential, err := mdoc.ParseCredential([]byte(credentialMDL))
+			require.NoError(t, err)
+
+			credentialReader := memstorage.NewProvider()
+			require.NoError(t, credentialReader.Add(credential))
+
+			claims := map[string]interface{}{
+				"org.iso.18013.5.1": map[string]interface{}{
+					"family_name": map[string]interface{}{
+						"display": []interface{}{map[string]interface{}{"name": "Family Name", "locale": "en-US"}},
+					},
+				},
+			}
+
+			issuerMetadata := &issuer.Metadata{
+				CredentialConfigurationsSupported: map[string]*issuer.CredentialConfigurationSupported{
+					"mDL": {
+						Format:  "mso_mdoc",
+						Doctype: "org.iso.18013.5.1.mDL",
+						Claims:  &claims,
+						LocalizedCredentialDisplays: []issuer.LocalizedCredentialDisplay{
+							{Name: "Mobile Driving Licence", Locale: "en-US"},
+						},
+					},
+				},
+			}
+
+			resolvedDisplayData, err := credentialschema.Resolve(
+				credentialschema.WithCredentialReader(credentialReader, []string{credential.Contents().ID}),
+				credentialschema.WithIssuerMetadata(issuerMetadata))
+			require.NoError(t, err)
+			require.Len(t, resolvedDisplayData.CredentialDisplays, 1)
+			require.Equal(t, "Mobile Driving Licence", resolvedDisplayData.CredentialDisplays[0].Overview.Name)
+			require.Len(t, resolvedDisplayData.CredentialDisplays[0].Claims, 1)
+			require.Equal(t, "Family Name", resolvedDisplayData.CredentialDisplays[0].Claims[0].Label)
+			require.Equal(t, "Mustermann", resolvedDisplayData.CredentialDisplays[0].Claims[0].RawValue)
+		})
 	})
 	t.Run("Invalid options:", func(t *testing.T) {
 		t.Run("No credentials specified", func(t *testing.T) {
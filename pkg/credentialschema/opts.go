@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package credentialschema
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -16,6 +17,7 @@ import (
 
 	"github.com/trustbloc/wallet-sdk/pkg/api"
 	metadatafetcher "github.com/trustbloc/wallet-sdk/pkg/internal/issuermetadata"
+	"github.com/trustbloc/wallet-sdk/pkg/metadatacache"
 	"github.com/trustbloc/wallet-sdk/pkg/metricslogger/noop"
 	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
 )
@@ -65,6 +67,7 @@ type resolveOpts struct {
 	maskingString        *string
 	signatureVerifier    jwt.ProofChecker
 	skipNonClaimData     bool
+	metadataCache        *metadatacache.Cache
 }
 
 // ResolveOpt represents an option for the Resolve function.
@@ -111,6 +114,14 @@ func WithIssuerURI(uri string) ResolveOpt {
 	}
 }
 
+// WithMetadataCache is an option allowing a caller to specify the cache that's used when fetching an issuer's metadata
+// (see WithIssuerURI). If this option isn't used, then metadatacache.Default() is used.
+func WithMetadataCache(cache *metadatacache.Cache) ResolveOpt {
+	return func(opts *resolveOpts) {
+		opts.metadataCache = cache
+	}
+}
+
 // WithIssuerMetadata is an option allowing a caller to directly pass in the issuer's metadata to use for resolving VCs.
 func WithIssuerMetadata(metadata *issuer.Metadata) ResolveOpt {
 	return func(opts *resolveOpts) {
@@ -180,7 +191,8 @@ func WithSkipNonClaimData() ResolveOpt {
 	}
 }
 
-func processOpts(opts []ResolveOpt) ([]*credentialConfigMapping, *issuer.Metadata, string, *string, error) {
+func processOpts(ctx context.Context, opts []ResolveOpt,
+) ([]*credentialConfigMapping, *issuer.Metadata, string, *string, error) {
 	mergedOpts := mergeOpts(opts)
 
 	err := validateOpts(mergedOpts)
@@ -188,7 +200,7 @@ func processOpts(opts []ResolveOpt) ([]*credentialConfigMapping, *issuer.Metadat
 		return nil, nil, "", nil, err
 	}
 
-	return processValidatedOpts(mergedOpts)
+	return processValidatedOpts(ctx, mergedOpts)
 }
 
 func mergeOpts(opts []ResolveOpt) *resolveOpts {
@@ -245,7 +257,8 @@ func validateIssuerMetadataOpts(issuerMetadataSource *issuerMetadataSource) erro
 }
 
 //nolint:gocyclo
-func processValidatedOpts(opts *resolveOpts) ([]*credentialConfigMapping, *issuer.Metadata, string, *string, error) {
+func processValidatedOpts(ctx context.Context, opts *resolveOpts,
+) ([]*credentialConfigMapping, *issuer.Metadata, string, *string, error) {
 	credentialConfigMappings, err := processVCOpts(&opts.credentialSource)
 	if err != nil {
 		return nil, nil, "", nil, err
@@ -263,8 +276,8 @@ func processValidatedOpts(opts *resolveOpts) ([]*credentialConfigMapping, *issue
 		opts.httpClient = &http.Client{Timeout: api.DefaultHTTPTimeout}
 	}
 
-	issuerMetadata, err := processIssuerMetadataOpts(&opts.issuerMetadataSource, opts.httpClient, metricsLogger,
-		opts.signatureVerifier)
+	issuerMetadata, err := processIssuerMetadataOpts(ctx, &opts.issuerMetadataSource, opts.httpClient, metricsLogger,
+		opts.signatureVerifier, opts.metadataCache)
 	if err != nil {
 		return nil, nil, "", nil, err
 	}
@@ -337,15 +350,19 @@ func processVCOpts(credentialSource *credentialSource) ([]*credentialConfigMappi
 	return credentialConfigMappings, nil
 }
 
-func processIssuerMetadataOpts(issuerMetadataSource *issuerMetadataSource, httpClient httpClient,
-	metricsLogger api.MetricsLogger, signatureVerifier jwt.ProofChecker,
+func processIssuerMetadataOpts(ctx context.Context, issuerMetadataSource *issuerMetadataSource, httpClient httpClient,
+	metricsLogger api.MetricsLogger, signatureVerifier jwt.ProofChecker, cache *metadatacache.Cache,
 ) (*issuer.Metadata, error) {
 	if issuerMetadataSource.metadata != nil {
 		return issuerMetadataSource.metadata, nil
 	}
 
-	metadata, err := metadatafetcher.Get(issuerMetadataSource.issuerURI,
-		httpClient, metricsLogger, "Resolve display", signatureVerifier)
+	if cache == nil {
+		cache = metadatacache.Default()
+	}
+
+	metadata, err := metadatafetcher.Get(ctx, issuerMetadataSource.issuerURI,
+		httpClient, metricsLogger, "Resolve display", signatureVerifier, cache, nil)
 	if err != nil {
 		return nil, err
 	}
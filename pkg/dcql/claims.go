@@ -0,0 +1,282 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package dcql
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/trustbloc/vc-go/verifiable"
+
+	"github.com/trustbloc/wallet-sdk/pkg/mdoc"
+)
+
+const vctFieldName = "vct"
+
+// CredentialFormat returns the DCQL format identifier of the given credential: FormatMdoc for credentials created
+// from mdocs, FormatSDJWTVC for SD-JWT VCs, FormatJWTVC for other JWT credentials and FormatLDPVC otherwise.
+func CredentialFormat(credential *verifiable.Credential) string {
+	switch {
+	case mdoc.IsMdocCredential(credential):
+		return FormatMdoc
+	case credential.IsJWT() && vct(credential) != "":
+		return FormatSDJWTVC
+	case credential.IsJWT():
+		return FormatJWTVC
+	default:
+		return FormatLDPVC
+	}
+}
+
+// formatMatches checks whether a credential in the given format can be used for a query with the given format.
+func formatMatches(queryFormat, credentialFormat string) bool {
+	switch queryFormat {
+	case FormatSDJWTVC, FormatLegacySDJWTVC:
+		return credentialFormat == FormatSDJWTVC
+	case FormatJWTVC, FormatJWTVCJSONLD:
+		return credentialFormat == FormatJWTVC
+	default:
+		return queryFormat == credentialFormat
+	}
+}
+
+func vct(credential *verifiable.Credential) string {
+	value, _ := credential.CustomField(vctFieldName).(string) //nolint:errcheck // Not a string means no vct.
+
+	return value
+}
+
+// metaMatches checks the format-specific constraints of a credential query.
+func metaMatches(meta *Meta, credential *verifiable.Credential, format string) bool {
+	if meta == nil {
+		return true
+	}
+
+	switch format {
+	case FormatSDJWTVC:
+		return len(meta.VCTValues) == 0 || slices.Contains(meta.VCTValues, vct(credential))
+	case FormatMdoc:
+		return meta.DoctypeValue == "" || slices.Contains(credential.Contents().Types, meta.DoctypeValue)
+	default:
+		if len(meta.TypeValues) == 0 {
+			return true
+		}
+
+		types := credential.Contents().Types
+
+		for _, typeValues := range meta.TypeValues {
+			if containsAll(types, typeValues) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// claimsObject returns the JSON object that claims paths are evaluated against. For SD-JWT VCs, this is the SD-JWT's
+// claims with all disclosures applied. For mdocs, it has an object for each namespace. For W3C credentials, it's the
+// credential itself.
+func claimsObject(credential *verifiable.Credential, format string) (map[string]interface{}, error) {
+	contents := credential.Contents()
+
+	switch format {
+	case FormatMdoc, FormatSDJWTVC:
+		if len(contents.Subject) == 0 {
+			return nil, errors.New("credential has no subject")
+		}
+
+		claims := map[string]interface{}{}
+
+		for name, value := range contents.Subject[0].CustomFields {
+			claims[name] = value
+		}
+
+		if format == FormatSDJWTVC {
+			claims[vctFieldName] = vct(credential)
+
+			if contents.Issuer != nil {
+				claims["iss"] = contents.Issuer.ID
+			}
+
+			if contents.Subject[0].ID != "" {
+				claims["sub"] = contents.Subject[0].ID
+			}
+		}
+
+		return claims, nil
+	default:
+		if len(credential.SDJWTDisclosures()) > 0 {
+			displayCredential, err := credential.CreateDisplayCredentialMap(verifiable.DisplayAllDisclosures())
+			if err != nil {
+				return nil, fmt.Errorf("apply disclosures: %w", err)
+			}
+
+			return displayCredential, nil
+		}
+
+		raw := credential.ToRawJSON()
+
+		// JWT credentials may be represented by their JWT claims, in which case the credential is in the vc claim.
+		if vc, ok := raw["vc"].(map[string]interface{}); ok && raw["credentialSubject"] == nil {
+			return vc, nil
+		}
+
+		return raw, nil
+	}
+}
+
+// resolvePath returns the values that the given claims path pointer selects, as defined in
+// https://openid.net/specs/openid-4-verifiable-presentations-1_0.html#name-claims-path-pointer.
+// Null values are treated as missing.
+func resolvePath(claims interface{}, path []interface{}) []interface{} {
+	selected := []interface{}{claims}
+
+	for _, element := range path {
+		var next []interface{}
+
+		for _, value := range selected {
+			next = append(next, selectChildren(value, element)...)
+		}
+
+		if len(next) == 0 {
+			return nil
+		}
+
+		selected = next
+	}
+
+	return selected
+}
+
+func selectChildren(value, element interface{}) []interface{} {
+	if key, ok := element.(string); ok {
+		object, isObject := toObject(value)
+		if !isObject || object[key] == nil {
+			return nil
+		}
+
+		return []interface{}{object[key]}
+	}
+
+	array, ok := toArray(value)
+	if !ok {
+		return nil
+	}
+
+	if element == nil {
+		var children []interface{}
+
+		for _, child := range array {
+			if child != nil {
+				children = append(children, child)
+			}
+		}
+
+		return children
+	}
+
+	index, ok := arrayIndex(element)
+	if !ok || index >= len(array) || array[index] == nil {
+		return nil
+	}
+
+	return []interface{}{array[index]}
+}
+
+func toObject(value interface{}) (map[string]interface{}, bool) {
+	switch object := value.(type) {
+	case map[string]interface{}:
+		return object, true
+	case verifiable.CustomFields:
+		return object, true
+	default:
+		return nil, false
+	}
+}
+
+func toArray(value interface{}) ([]interface{}, bool) {
+	if array, ok := value.([]interface{}); ok {
+		return array, true
+	}
+
+	// Claims that weren't decoded from JSON (such as mdoc data elements) may use other slice types.
+	reflected := reflect.ValueOf(value)
+	if reflected.Kind() != reflect.Slice {
+		return nil, false
+	}
+
+	array := make([]interface{}, reflected.Len())
+
+	for i := range array {
+		array[i] = reflected.Index(i).Interface()
+	}
+
+	return array, true
+}
+
+func arrayIndex(element interface{}) (int, bool) {
+	switch index := element.(type) {
+	case float64:
+		if index < 0 || index != float64(int(index)) {
+			return 0, false
+		}
+
+		return int(index), true
+	case int:
+		return index, index >= 0
+	default:
+		return 0, false
+	}
+}
+
+// valueMatches checks whether a claim value equals one of the values in a claims query. Numbers are compared by value
+// regardless of their Go type.
+func valueMatches(value interface{}, expectedValues []interface{}) bool {
+	for _, expected := range expectedValues {
+		if expectedNumber, ok := toFloat(expected); ok {
+			if actualNumber, isNumber := toFloat(value); isNumber && actualNumber == expectedNumber {
+				return true
+			}
+
+			continue
+		}
+
+		if value == expected {
+			return true
+		}
+	}
+
+	return false
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	reflected := reflect.ValueOf(value)
+
+	switch reflected.Kind() { //nolint:exhaustive // Other kinds aren't numbers.
+	case reflect.Float32, reflect.Float64:
+		return reflected.Float(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(reflected.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(reflected.Uint()), true
+	default:
+		return 0, false
+	}
+}
+
+func containsAll(values, required []string) bool {
+	for _, value := range required {
+		if !slices.Contains(values, value) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,250 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package dcql implements the Digital Credentials Query Language (DCQL), which verifiers use in OpenID4VP requests
+// to describe the credentials they want presented, as defined in
+// https://openid.net/specs/openid-4-verifiable-presentations-1_0.html#name-digital-credentials-query-l.
+package dcql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// Credential formats that can be used in credential queries.
+const (
+	FormatSDJWTVC       = "dc+sd-jwt"
+	FormatLegacySDJWTVC = "vc+sd-jwt" // The identifier used for SD-JWT VCs by earlier drafts of OpenID4VP.
+	FormatJWTVC         = "jwt_vc_json"
+	FormatJWTVCJSONLD   = "jwt_vc_json-ld"
+	FormatLDPVC         = "ldp_vc"
+	FormatMdoc          = "mso_mdoc"
+)
+
+//nolint:gochecknoglobals
+var idPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Query is a DCQL query. It lists the credentials that the verifier is asking for and, optionally, which
+// combinations of them are acceptable.
+type Query struct {
+	Credentials    []*CredentialQuery    `json:"credentials"`
+	CredentialSets []*CredentialSetQuery `json:"credential_sets,omitempty"`
+}
+
+// CredentialQuery describes a single credential that the verifier is asking for.
+type CredentialQuery struct {
+	ID     string `json:"id"`
+	Format string `json:"format"`
+	// If set, then more than one credential may be presented for this query.
+	Multiple bool `json:"multiple,omitempty"`
+	// Format-specific constraints on the credential, such as vct_values, doctype_value and type_values.
+	Meta   *Meta          `json:"meta,omitempty"`
+	Claims []*ClaimsQuery `json:"claims,omitempty"`
+	// Each entry is a list of IDs of the claims in Claims. The first entry that can be satisfied is used.
+	ClaimSets [][]string `json:"claim_sets,omitempty"`
+	// Trusted authorities aren't evaluated, but they're kept so that the query can be shown to the user.
+	TrustedAuthorities []json.RawMessage `json:"trusted_authorities,omitempty"`
+	// Defaults to true if not set.
+	RequireCryptographicHolderBinding *bool `json:"require_cryptographic_holder_binding,omitempty"`
+}
+
+// Meta contains the format-specific constraints of a CredentialQuery.
+type Meta struct {
+	// For SD-JWT VCs: the credential's vct must be one of these values.
+	VCTValues []string `json:"vct_values,omitempty"`
+	// For mdocs: the credential's doctype must be this value.
+	DoctypeValue string `json:"doctype_value,omitempty"`
+	// For W3C credentials: the credential's types must include all the types of at least one of these entries.
+	TypeValues [][]string `json:"type_values,omitempty"`
+}
+
+// ClaimsQuery describes a claim that the credential must contain.
+type ClaimsQuery struct {
+	ID string `json:"id,omitempty"`
+	// A claims path pointer. Each element is a string (an object key), a non-negative integer (an array index) or
+	// nil (all array elements).
+	Path []interface{} `json:"path"`
+	// If not empty, then the claim must have one of these values.
+	Values []interface{} `json:"values,omitempty"`
+	// For mdocs: requests that the verifier intends to retain the claim.
+	IntentToRetain *bool `json:"intent_to_retain,omitempty"`
+}
+
+// CredentialSetQuery describes a combination of credentials that the verifier will accept.
+type CredentialSetQuery struct {
+	// Each option is a list of credential query IDs. Presenting the credentials for any one option satisfies the set.
+	Options [][]string `json:"options"`
+	// Defaults to true if not set.
+	Required *bool `json:"required,omitempty"`
+	// Why the verifier is asking for these credentials. It's either a string or an object.
+	Purpose interface{} `json:"purpose,omitempty"`
+}
+
+// IsRequired indicates whether the credential set must be satisfied.
+func (c *CredentialSetQuery) IsRequired() bool {
+	return c.Required == nil || *c.Required
+}
+
+// Parse parses and validates the given DCQL query.
+func Parse(data []byte) (*Query, error) {
+	query := &Query{}
+
+	err := json.Unmarshal(data, query)
+	if err != nil {
+		return nil, fmt.Errorf("decode DCQL query: %w", err)
+	}
+
+	err = query.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return query, nil
+}
+
+// CredentialQuery returns the credential query with the given ID, or nil if there isn't one.
+func (q *Query) CredentialQuery(id string) *CredentialQuery {
+	for _, credentialQuery := range q.Credentials {
+		if credentialQuery.ID == id {
+			return credentialQuery
+		}
+	}
+
+	return nil
+}
+
+// Validate checks that the query is well-formed.
+func (q *Query) Validate() error {
+	if len(q.Credentials) == 0 {
+		return errors.New("DCQL query must contain at least one credential query")
+	}
+
+	credentialQueryIDs := map[string]struct{}{}
+
+	for i, credentialQuery := range q.Credentials {
+		if credentialQuery == nil {
+			return fmt.Errorf("credential query at index %d is null", i)
+		}
+
+		if !idPattern.MatchString(credentialQuery.ID) {
+			return fmt.Errorf("credential query at index %d has an invalid id: %q", i, credentialQuery.ID)
+		}
+
+		if _, exists := credentialQueryIDs[credentialQuery.ID]; exists {
+			return fmt.Errorf("duplicate credential query id: %s", credentialQuery.ID)
+		}
+
+		credentialQueryIDs[credentialQuery.ID] = struct{}{}
+
+		err := credentialQuery.validate()
+		if err != nil {
+			return fmt.Errorf("credential query %s: %w", credentialQuery.ID, err)
+		}
+	}
+
+	for i, credentialSet := range q.CredentialSets {
+		if credentialSet == nil || len(credentialSet.Options) == 0 {
+			return fmt.Errorf("credential set at index %d must have at least one option", i)
+		}
+
+		for _, option := range credentialSet.Options {
+			if len(option) == 0 {
+				return fmt.Errorf("credential set at index %d has an empty option", i)
+			}
+
+			for _, id := range option {
+				if _, exists := credentialQueryIDs[id]; !exists {
+					return fmt.Errorf("credential set at index %d refers to an unknown credential query: %s", i, id)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *CredentialQuery) validate() error {
+	if c.Format == "" {
+		return errors.New("format is required")
+	}
+
+	claimIDs := map[string]struct{}{}
+
+	for i, claim := range c.Claims {
+		if claim == nil {
+			return fmt.Errorf("claim at index %d is null", i)
+		}
+
+		err := claim.validate()
+		if err != nil {
+			return fmt.Errorf("claim at index %d: %w", i, err)
+		}
+
+		if claim.ID == "" {
+			if len(c.ClaimSets) > 0 {
+				return fmt.Errorf("claim at index %d must have an id since claim_sets is used", i)
+			}
+
+			continue
+		}
+
+		if _, exists := claimIDs[claim.ID]; exists {
+			return fmt.Errorf("duplicate claim id: %s", claim.ID)
+		}
+
+		claimIDs[claim.ID] = struct{}{}
+	}
+
+	if len(c.ClaimSets) > 0 && len(c.Claims) == 0 {
+		return errors.New("claim_sets must not be used without claims")
+	}
+
+	for i, claimSet := range c.ClaimSets {
+		if len(claimSet) == 0 {
+			return fmt.Errorf("claim set at index %d is empty", i)
+		}
+
+		for _, id := range claimSet {
+			if _, exists := claimIDs[id]; !exists {
+				return fmt.Errorf("claim set at index %d refers to an unknown claim: %s", i, id)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *ClaimsQuery) validate() error {
+	if c.ID != "" && !idPattern.MatchString(c.ID) {
+		return fmt.Errorf("invalid id: %q", c.ID)
+	}
+
+	if len(c.Path) == 0 {
+		return errors.New("path must not be empty")
+	}
+
+	for _, element := range c.Path {
+		switch element.(type) {
+		case string, nil:
+		default:
+			if _, ok := arrayIndex(element); !ok {
+				return fmt.Errorf("path contains an element that isn't a string, array index or null: %v", element)
+			}
+		}
+	}
+
+	for _, value := range c.Values {
+		switch value.(type) {
+		case string, float64, bool:
+		default:
+			return fmt.Errorf("values must be strings, numbers or booleans, got %v", value)
+		}
+	}
+
+	return nil
+}
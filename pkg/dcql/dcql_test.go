@@ -0,0 +1,331 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package dcql_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/kms-go/doc/jose"
+	"github.com/trustbloc/vc-go/sdjwt/common"
+	"github.com/trustbloc/vc-go/sdjwt/issuer"
+	"github.com/trustbloc/vc-go/verifiable"
+
+	"github.com/trustbloc/wallet-sdk/pkg/dcql"
+	"github.com/trustbloc/wallet-sdk/pkg/sdjwtvc"
+)
+
+const (
+	pidVct      = "urn:eu.europa.ec.eudi:pid:1"
+	degreeType  = "UniversityDegreeCredential"
+	issuerURL   = "https://issuer.example.com"
+	sampleQuery = `{
+		"credentials": [
+			{
+				"id": "pid",
+				"format": "dc+sd-jwt",
+				"meta": {"vct_values": ["urn:eu.europa.ec.eudi:pid:1"]},
+				"claims": [
+					{"id": "given_name", "path": ["given_name"]},
+					{"id": "country", "path": ["address", "country"], "values": ["DE", "AT"]},
+					{"id": "age_over_18", "path": ["age_equal_or_over", "18"], "values": [true]}
+				],
+				"claim_sets": [["given_name", "country"], ["age_over_18"]]
+			},
+			{
+				"id": "degree",
+				"format": "ldp_vc",
+				"meta": {"type_values": [["VerifiableCredential", "UniversityDegreeCredential"]]},
+				"claims": [{"path": ["credentialSubject", "degrees", null, "type"], "values": ["BachelorDegree"]}]
+			},
+			{
+				"id": "other_degree",
+				"format": "jwt_vc_json",
+				"multiple": true
+			}
+		],
+		"credential_sets": [
+			{"options": [["pid"]], "purpose": "Identification"},
+			{"options": [["degree"], ["other_degree"]], "required": false}
+		]
+	}`
+)
+
+func TestParse(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		query, err := dcql.Parse([]byte(sampleQuery))
+		require.NoError(t, err)
+		require.Len(t, query.Credentials, 3)
+		require.Equal(t, "pid", query.Credentials[0].ID)
+		require.Equal(t, []interface{}{"address", "country"}, query.Credentials[0].Claims[1].Path)
+		require.True(t, query.CredentialSets[0].IsRequired())
+		require.False(t, query.CredentialSets[1].IsRequired())
+		require.Equal(t, "degree", query.CredentialQuery("degree").ID)
+		require.Nil(t, query.CredentialQuery("unknown"))
+	})
+
+	tests := []struct {
+		name          string
+		query         string
+		expectedError string
+	}{
+		{
+			name:          "Not JSON",
+			query:         "query",
+			expectedError: "decode DCQL query",
+		},
+		{
+			name:          "No credential queries",
+			query:         `{"credentials": []}`,
+			expectedError: "DCQL query must contain at least one credential query",
+		},
+		{
+			name:          "Invalid credential query ID",
+			query:         `{"credentials": [{"id": "pid 1", "format": "dc+sd-jwt"}]}`,
+			expectedError: `credential query at index 0 has an invalid id: "pid 1"`,
+		},
+		{
+			name: "Duplicate credential query ID",
+			query: `{"credentials": [{"id": "pid", "format": "dc+sd-jwt"},
+				{"id": "pid", "format": "mso_mdoc"}]}`,
+			expectedError: "duplicate credential query id: pid",
+		},
+		{
+			name:          "Missing format",
+			query:         `{"credentials": [{"id": "pid"}]}`,
+			expectedError: "credential query pid: format is required",
+		},
+		{
+			name:          "Empty claims path",
+			query:         `{"credentials": [{"id": "pid", "format": "dc+sd-jwt", "claims": [{"path": []}]}]}`,
+			expectedError: "claim at index 0: path must not be empty",
+		},
+		{
+			name: "Invalid claims path element",
+			query: `{"credentials": [{"id": "pid", "format": "dc+sd-jwt",
+				"claims": [{"path": ["address", -1]}]}]}`,
+			expectedError: "path contains an element that isn't a string, array index or null: -1",
+		},
+		{
+			name: "Invalid value",
+			query: `{"credentials": [{"id": "pid", "format": "dc+sd-jwt",
+				"claims": [{"path": ["address"], "values": [{"country": "DE"}]}]}]}`,
+			expectedError: "values must be strings, numbers or booleans",
+		},
+		{
+			name: "Claim without ID used with claim sets",
+			query: `{"credentials": [{"id": "pid", "format": "dc+sd-jwt",
+				"claims": [{"path": ["given_name"]}], "claim_sets": [["given_name"]]}]}`,
+			expectedError: "claim at index 0 must have an id since claim_sets is used",
+		},
+		{
+			name: "Unknown claim in claim set",
+			query: `{"credentials": [{"id": "pid", "format": "dc+sd-jwt",
+				"claims": [{"id": "given_name", "path": ["given_name"]}], "claim_sets": [["family_name"]]}]}`,
+			expectedError: "claim set at index 0 refers to an unknown claim: family_name",
+		},
+		{
+			name: "Unknown credential query in credential set",
+			query: `{"credentials": [{"id": "pid", "format": "dc+sd-jwt"}],
+				"credential_sets": [{"options": [["mdl"]]}]}`,
+			expectedError: "credential set at index 0 refers to an unknown credential query: mdl",
+		},
+		{
+			name: "Credential set without options",
+			query: `{"credentials": [{"id": "pid", "format": "dc+sd-jwt"}],
+				"credential_sets": [{"options": []}]}`,
+			expectedError: "credential set at index 0 must have at least one option",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query, err := dcql.Parse([]byte(test.query))
+			require.ErrorContains(t, err, test.expectedError)
+			require.Nil(t, query)
+		})
+	}
+}
+
+func TestCredentialFormat(t *testing.T) {
+	require.Equal(t, dcql.FormatSDJWTVC, dcql.CredentialFormat(createSDJWTVC(t, "DE", true)))
+	require.Equal(t, dcql.FormatLDPVC, dcql.CredentialFormat(createDegreeCredential(t, "BachelorDegree")))
+
+	jwtCredential, err := createDegreeCredential(t, "BachelorDegree").CreateUnsecuredJWTVC(false)
+	require.NoError(t, err)
+	require.Equal(t, dcql.FormatJWTVC, dcql.CredentialFormat(jwtCredential))
+}
+
+func TestMatch(t *testing.T) {
+	query, err := dcql.Parse([]byte(sampleQuery))
+	require.NoError(t, err)
+
+	germanPID := createSDJWTVC(t, "DE", false)
+	frenchPID := createSDJWTVC(t, "FR", true)
+	bachelorDegree := createDegreeCredential(t, "BachelorDegree")
+	masterDegree := createDegreeCredential(t, "MasterDegree")
+
+	t.Run("Query is satisfied", func(t *testing.T) {
+		result, err := dcql.Match(query, []*verifiable.Credential{germanPID, frenchPID, bachelorDegree, masterDegree})
+		require.NoError(t, err)
+		require.True(t, result.Satisfied)
+		require.Len(t, result.CredentialMatches, 3)
+
+		pidMatch := result.CredentialMatch("pid")
+		require.Len(t, pidMatch.Credentials, 2)
+
+		// The German PID satisfies the first claim set, so its given name and country are disclosed.
+		require.Equal(t, germanPID, pidMatch.Credentials[0].Credential)
+		require.Equal(t, [][]interface{}{{"given_name"}, {"address", "country"}}, pidMatch.Credentials[0].ClaimPaths)
+
+		// The French PID only satisfies the second claim set.
+		require.Equal(t, frenchPID, pidMatch.Credentials[1].Credential)
+		require.Equal(t, [][]interface{}{{"age_equal_or_over", "18"}}, pidMatch.Credentials[1].ClaimPaths)
+
+		degreeMatch := result.CredentialMatch("degree")
+		require.Len(t, degreeMatch.Credentials, 1)
+		require.Equal(t, bachelorDegree, degreeMatch.Credentials[0].Credential)
+
+		require.Empty(t, result.CredentialMatch("other_degree").Credentials)
+		require.Nil(t, result.CredentialMatch("unknown"))
+
+		require.Len(t, result.CredentialSets, 2)
+		require.Equal(t, [][]string{{"pid"}}, result.CredentialSets[0].SatisfiableOptions)
+		require.Equal(t, [][]string{{"degree"}}, result.CredentialSets[1].SatisfiableOptions)
+	})
+	t.Run("Optional credential set isn't needed", func(t *testing.T) {
+		result, err := dcql.Match(query, []*verifiable.Credential{germanPID})
+		require.NoError(t, err)
+		require.True(t, result.Satisfied)
+		require.Empty(t, result.CredentialSets[1].SatisfiableOptions)
+	})
+	t.Run("Required credential set isn't satisfied", func(t *testing.T) {
+		result, err := dcql.Match(query, []*verifiable.Credential{bachelorDegree})
+		require.NoError(t, err)
+		require.False(t, result.Satisfied)
+	})
+	t.Run("Without credential sets, every credential query is required", func(t *testing.T) {
+		queryWithoutSets := *query
+		queryWithoutSets.CredentialSets = nil
+
+		result, err := dcql.Match(&queryWithoutSets, []*verifiable.Credential{germanPID, bachelorDegree})
+		require.NoError(t, err)
+		require.False(t, result.Satisfied)
+		require.Nil(t, result.CredentialSets)
+	})
+	t.Run("Query not provided", func(t *testing.T) {
+		result, err := dcql.Match(nil, nil)
+		require.ErrorContains(t, err, "DCQL query must be provided")
+		require.Nil(t, result)
+	})
+}
+
+func TestSelect(t *testing.T) {
+	query, err := dcql.Parse([]byte(sampleQuery))
+	require.NoError(t, err)
+
+	germanPID := createSDJWTVC(t, "DE", false)
+	frenchPID := createSDJWTVC(t, "FR", true)
+	bachelorDegree := createDegreeCredential(t, "BachelorDegree")
+
+	t.Run("Success", func(t *testing.T) {
+		selected, err := dcql.Select(query, map[string][]*verifiable.Credential{
+			"pid":    {germanPID},
+			"degree": {bachelorDegree},
+		})
+		require.NoError(t, err)
+		require.Len(t, selected, 2)
+		require.Equal(t, [][]interface{}{{"given_name"}, {"address", "country"}}, selected["pid"][0].ClaimPaths)
+		require.Equal(t, [][]interface{}{{"credentialSubject", "degrees", nil, "type"}},
+			selected["degree"][0].ClaimPaths)
+	})
+	t.Run("Required credential not selected", func(t *testing.T) {
+		selected, err := dcql.Select(query, map[string][]*verifiable.Credential{"degree": {bachelorDegree}})
+		require.ErrorContains(t, err, "the selected credentials don't satisfy the DCQL query")
+		require.Nil(t, selected)
+	})
+	t.Run("Too many credentials", func(t *testing.T) {
+		selected, err := dcql.Select(query, map[string][]*verifiable.Credential{"pid": {germanPID, frenchPID}})
+		require.ErrorContains(t, err, "only one credential may be presented for credential query pid")
+		require.Nil(t, selected)
+	})
+	t.Run("Credential doesn't match", func(t *testing.T) {
+		selected, err := dcql.Select(query, map[string][]*verifiable.Credential{"pid": {bachelorDegree}})
+		require.ErrorContains(t, err, "credential at index 0 doesn't match credential query pid")
+		require.Nil(t, selected)
+	})
+	t.Run("Unknown credential query", func(t *testing.T) {
+		selected, err := dcql.Select(query, map[string][]*verifiable.Credential{"mdl": {germanPID}})
+		require.ErrorContains(t, err, "no credential query with id mdl")
+		require.Nil(t, selected)
+	})
+}
+
+func createSDJWTVC(t *testing.T, country string, over18 bool) *verifiable.Credential {
+	t.Helper()
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	claims := map[string]interface{}{
+		"vct":               pidVct,
+		"given_name":        "Erika",
+		"address":           map[string]interface{}{"country": country, "locality": "Berlin"},
+		"age_equal_or_over": map[string]interface{}{"18": over18},
+	}
+
+	sdJWT, err := issuer.New(issuerURL, claims, nil, &ed25519Signer{privateKey: privateKey},
+		issuer.WithSDJWTVersion(common.SDJWTVersionV5),
+		issuer.WithNonSelectivelyDisclosableClaims([]string{"vct"}),
+		issuer.WithStructuredClaims(true))
+	require.NoError(t, err)
+
+	serialized, err := sdJWT.Serialize(false)
+	require.NoError(t, err)
+
+	credential, err := sdjwtvc.Parse(serialized, sdjwtvc.WithDisabledProofCheck())
+	require.NoError(t, err)
+
+	return credential
+}
+
+func createDegreeCredential(t *testing.T, degreeTypes ...string) *verifiable.Credential {
+	t.Helper()
+
+	var degrees []interface{}
+
+	for _, degreeType := range degreeTypes {
+		degrees = append(degrees, map[string]interface{}{"type": degreeType})
+	}
+
+	credential, err := verifiable.CreateCredential(verifiable.CredentialContents{
+		Context: []string{verifiable.V1ContextURI},
+		Types:   []string{"VerifiableCredential", degreeType},
+		Issuer:  &verifiable.Issuer{ID: "did:example:issuer"},
+		Subject: []verifiable.Subject{{
+			ID:           "did:example:holder",
+			CustomFields: verifiable.CustomFields{"degrees": degrees},
+		}},
+	}, nil)
+	require.NoError(t, err)
+
+	return credential
+}
+
+type ed25519Signer struct {
+	privateKey ed25519.PrivateKey
+}
+
+func (s *ed25519Signer) Sign(data []byte) ([]byte, error) {
+	return ed25519.Sign(s.privateKey, data), nil
+}
+
+func (s *ed25519Signer) Headers() jose.Headers {
+	return jose.Headers{jose.HeaderAlgorithm: "EdDSA", jose.HeaderType: "dc+sd-jwt"}
+}
@@ -0,0 +1,264 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package dcql
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/trustbloc/vc-go/verifiable"
+)
+
+// Result is the result of matching credentials against a Query.
+type Result struct {
+	// One entry for each credential query, in the same order as in the query.
+	CredentialMatches []*CredentialMatch
+	// One entry for each credential set, in the same order as in the query.
+	CredentialSets []*CredentialSetMatch
+	// Indicates whether the matched credentials are enough to satisfy the query.
+	Satisfied bool
+}
+
+// CredentialMatch holds the credentials that match a single credential query.
+type CredentialMatch struct {
+	Query       *CredentialQuery
+	Credentials []*MatchedCredential
+}
+
+// MatchedCredential is a credential that matches a credential query.
+type MatchedCredential struct {
+	Credential *verifiable.Credential
+	// The paths of the claims that satisfied the credential query. These are the claims that are disclosed when the
+	// credential is presented.
+	ClaimPaths [][]interface{}
+}
+
+// CredentialSetMatch indicates which options of a credential set can be satisfied.
+type CredentialSetMatch struct {
+	Query *CredentialSetQuery
+	// The options for which every credential query has at least one matching credential.
+	SatisfiableOptions [][]string
+}
+
+// CredentialMatch returns the match for the credential query with the given ID, or nil if there isn't one.
+func (r *Result) CredentialMatch(id string) *CredentialMatch {
+	for _, match := range r.CredentialMatches {
+		if match.Query.ID == id {
+			return match
+		}
+	}
+
+	return nil
+}
+
+// Match finds the credentials that match each credential query, and determines whether the query can be satisfied
+// using them. Credentials that can't be evaluated (for example, because their disclosures can't be processed) are
+// treated as not matching.
+func Match(query *Query, credentials []*verifiable.Credential) (*Result, error) {
+	if query == nil {
+		return nil, errors.New("DCQL query must be provided")
+	}
+
+	result := &Result{}
+
+	matchedIDs := map[string]struct{}{}
+
+	for _, credentialQuery := range query.Credentials {
+		match := &CredentialMatch{Query: credentialQuery}
+
+		for _, credential := range credentials {
+			claimPaths, ok := matchCredential(credentialQuery, credential)
+			if ok {
+				match.Credentials = append(match.Credentials,
+					&MatchedCredential{Credential: credential, ClaimPaths: claimPaths})
+			}
+		}
+
+		if len(match.Credentials) > 0 {
+			matchedIDs[credentialQuery.ID] = struct{}{}
+		}
+
+		result.CredentialMatches = append(result.CredentialMatches, match)
+	}
+
+	result.CredentialSets, result.Satisfied = evaluateCredentialSets(query, matchedIDs)
+
+	return result, nil
+}
+
+// Select checks that the credentials that the user selected, keyed by credential query ID, satisfy the query. It
+// returns the selected credentials along with the claims to disclose for each of them.
+func Select(query *Query,
+	selection map[string][]*verifiable.Credential,
+) (map[string][]*MatchedCredential, error) {
+	if query == nil {
+		return nil, errors.New("DCQL query must be provided")
+	}
+
+	selected := map[string][]*MatchedCredential{}
+	selectedIDs := map[string]struct{}{}
+
+	for id, credentials := range selection {
+		credentialQuery := query.CredentialQuery(id)
+		if credentialQuery == nil {
+			return nil, fmt.Errorf("no credential query with id %s", id)
+		}
+
+		if len(credentials) == 0 {
+			continue
+		}
+
+		if len(credentials) > 1 && !credentialQuery.Multiple {
+			return nil, fmt.Errorf("only one credential may be presented for credential query %s", id)
+		}
+
+		for i, credential := range credentials {
+			if credential == nil {
+				return nil, fmt.Errorf("credential at index %d for credential query %s is nil", i, id)
+			}
+
+			claimPaths, ok := matchCredential(credentialQuery, credential)
+			if !ok {
+				return nil, fmt.Errorf("credential at index %d doesn't match credential query %s", i, id)
+			}
+
+			selected[id] = append(selected[id], &MatchedCredential{Credential: credential, ClaimPaths: claimPaths})
+		}
+
+		selectedIDs[id] = struct{}{}
+	}
+
+	if _, satisfied := evaluateCredentialSets(query, selectedIDs); !satisfied {
+		return nil, errors.New("the selected credentials don't satisfy the DCQL query")
+	}
+
+	return selected, nil
+}
+
+// evaluateCredentialSets determines which credential set options are satisfied by the given credential query IDs,
+// and whether all required credential sets are satisfied. If the query has no credential sets, then every credential
+// query must be satisfied.
+func evaluateCredentialSets(query *Query, satisfiedIDs map[string]struct{}) ([]*CredentialSetMatch, bool) {
+	if len(query.CredentialSets) == 0 {
+		for _, credentialQuery := range query.Credentials {
+			if _, ok := satisfiedIDs[credentialQuery.ID]; !ok {
+				return nil, false
+			}
+		}
+
+		return nil, true
+	}
+
+	satisfied := true
+
+	credentialSetMatches := make([]*CredentialSetMatch, 0, len(query.CredentialSets))
+
+	for _, credentialSet := range query.CredentialSets {
+		credentialSetMatch := &CredentialSetMatch{Query: credentialSet}
+
+		for _, option := range credentialSet.Options {
+			if allSatisfied(option, satisfiedIDs) {
+				credentialSetMatch.SatisfiableOptions = append(credentialSetMatch.SatisfiableOptions, option)
+			}
+		}
+
+		if credentialSet.IsRequired() && len(credentialSetMatch.SatisfiableOptions) == 0 {
+			satisfied = false
+		}
+
+		credentialSetMatches = append(credentialSetMatches, credentialSetMatch)
+	}
+
+	return credentialSetMatches, satisfied
+}
+
+func allSatisfied(ids []string, satisfiedIDs map[string]struct{}) bool {
+	for _, id := range ids {
+		if _, ok := satisfiedIDs[id]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchCredential checks whether the credential matches the credential query. If it does, then the paths of the
+// claims that satisfied the query are returned.
+func matchCredential(credentialQuery *CredentialQuery, credential *verifiable.Credential) ([][]interface{}, bool) {
+	format := CredentialFormat(credential)
+
+	if !formatMatches(credentialQuery.Format, format) || !metaMatches(credentialQuery.Meta, credential, format) {
+		return nil, false
+	}
+
+	if len(credentialQuery.Claims) == 0 {
+		return nil, true
+	}
+
+	claims, err := claimsObject(credential, format)
+	if err != nil {
+		return nil, false
+	}
+
+	if len(credentialQuery.ClaimSets) == 0 {
+		var claimPaths [][]interface{}
+
+		for _, claimsQuery := range credentialQuery.Claims {
+			if !claimMatches(claimsQuery, claims) {
+				return nil, false
+			}
+
+			claimPaths = append(claimPaths, claimsQuery.Path)
+		}
+
+		return claimPaths, true
+	}
+
+	for _, claimSet := range credentialQuery.ClaimSets {
+		var claimPaths [][]interface{}
+
+		for _, claimsQuery := range credentialQuery.Claims {
+			if !slices.Contains(claimSet, claimsQuery.ID) {
+				continue
+			}
+
+			if !claimMatches(claimsQuery, claims) {
+				claimPaths = nil
+
+				break
+			}
+
+			claimPaths = append(claimPaths, claimsQuery.Path)
+		}
+
+		if claimPaths != nil {
+			return claimPaths, true
+		}
+	}
+
+	return nil, false
+}
+
+func claimMatches(claimsQuery *ClaimsQuery, claims map[string]interface{}) bool {
+	values := resolvePath(claims, claimsQuery.Path)
+	if len(values) == 0 {
+		return false
+	}
+
+	if len(claimsQuery.Values) == 0 {
+		return true
+	}
+
+	for _, value := range values {
+		if valueMatches(value, claimsQuery.Values) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -9,6 +9,7 @@ SPDX-License-Identifier: Apache-2.0
 package wellknown
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -37,6 +38,15 @@ type HTTPClient interface {
 // The HTTP client parameter is optional. If not provided, then a default client will be used.
 func ValidateLinkedDomains(did string, resolver api.DIDResolver,
 	httpClient HTTPClient,
+) (bool, string, error) {
+	return ValidateLinkedDomainsContext(context.Background(), did, resolver, httpClient)
+}
+
+// ValidateLinkedDomainsContext is the same as ValidateLinkedDomains, but the well-known DID configuration is fetched
+// using the given context. An error is returned if the context is cancelled or its deadline passes before the
+// validation finishes.
+func ValidateLinkedDomainsContext(ctx context.Context, did string, resolver api.DIDResolver,
+	httpClient HTTPClient,
 ) (bool, string, error) {
 	if resolver == nil {
 		return false, "",
@@ -69,7 +79,7 @@ func ValidateLinkedDomains(did string, resolver api.DIDResolver,
 		return false, "", nil
 	}
 
-	client := didconfig.New(didconfig.WithHTTPClient(httpClient),
+	client := didconfig.New(didconfig.WithHTTPClient(&contextHTTPClient{ctx: ctx, httpClient: httpClient}),
 		didconfig.WithVDRegistry(&didResolverWrapper{didResolver: resolver}))
 
 	// Note that in the case of multiple origins, this method will only return the first one.
@@ -86,12 +96,26 @@ func ValidateLinkedDomains(did string, resolver api.DIDResolver,
 
 	verErr := client.VerifyDIDAndDomain(did, uri)
 	if verErr != nil {
+		if ctx.Err() != nil {
+			return false, "", ctx.Err()
+		}
+
 		didBelongsToDomain = false
 	}
 
 	return didBelongsToDomain, uri, nil
 }
 
+// contextHTTPClient sends requests in its context, since the DID configuration client doesn't accept one.
+type contextHTTPClient struct {
+	ctx        context.Context //nolint:containedctx
+	httpClient HTTPClient
+}
+
+func (c *contextHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	return c.httpClient.Do(req.WithContext(c.ctx))
+}
+
 func getLinkedDomainsService(didDoc *diddoc.Doc) (*diddoc.Service, error) {
 	var linkedDomainsService *diddoc.Service
 
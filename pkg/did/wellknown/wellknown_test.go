@@ -8,6 +8,7 @@ package wellknown_test
 
 import (
 	"bytes"
+	"context"
 	_ "embed"
 	"fmt"
 	"io"
@@ -144,6 +145,34 @@ func TestValidate(t *testing.T) {
 		require.True(t, valid)
 		require.Equal(t, "https://did.rohitgulati.com", domain)
 	})
+	t.Run("Context is cancelled", func(t *testing.T) {
+		httpClient := &mockHTTPClient{
+			DoFunc: func(req *http.Request) (*http.Response, error) {
+				return nil, req.Context().Err()
+			},
+		}
+
+		testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			res.Header().Add("Content-type", "application/did+ld+json")
+			res.WriteHeader(http.StatusOK)
+			_, err := res.Write([]byte(resolutionResponse))
+			assert.NoError(t, err)
+		}))
+
+		defer func() { testServer.Close() }()
+
+		didResolver, err := httpbinding.New(testServer.URL)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		valid, domain, err := wellknown.ValidateLinkedDomainsContext(ctx, testDID, &resolverWrapper{vdr: didResolver},
+			httpClient)
+		require.ErrorIs(t, err, context.Canceled)
+		require.False(t, valid)
+		require.Empty(t, domain)
+	})
 	t.Run("No resolver provided", func(t *testing.T) {
 		valid, domain, err := wellknown.ValidateLinkedDomains(testDID, nil, nil)
 		testutil.RequireErrorContains(t, err, "no resolver provided")
@@ -51,12 +51,35 @@ func (r *Request) Do(method, endpointURL, contentType string, body io.Reader,
 var defaultAcceptableStatuses = []int{http.StatusOK}
 
 // DoContext is the same as Do, but also accept context and headers.
-//
-//nolint:gocyclo
 func (r *Request) DoContext(ctx context.Context, method, endpointURL, contentType string,
 	additionalHeaders http.Header, body io.Reader, event, parentEvent string, acceptableStatuses []int,
 	errorResponseHandler func(statusCode int, responseBody []byte) error,
 ) ([]byte, error) {
+	resp, err := r.DoContextWithResponse(ctx, method, endpointURL, contentType, additionalHeaders, body, event,
+		parentEvent, acceptableStatuses, errorResponseHandler)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+// Response is a response received by DoContextWithResponse.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// DoContextWithResponse is the same as DoContext, but also returns the response's status code and headers. This is
+// needed when the acceptable statuses include ones that don't have a body (e.g. 304 Not Modified), or when the
+// response headers are needed to interpret the body.
+//
+//nolint:gocyclo
+func (r *Request) DoContextWithResponse(ctx context.Context, method, endpointURL, contentType string,
+	additionalHeaders http.Header, body io.Reader, event, parentEvent string, acceptableStatuses []int,
+	errorResponseHandler func(statusCode int, responseBody []byte) error,
+) (*Response, error) {
 	req, err := http.NewRequestWithContext(ctx, method, endpointURL, body)
 	if err != nil {
 		return nil, err
@@ -113,7 +136,7 @@ func (r *Request) DoContext(ctx context.Context, method, endpointURL, contentTyp
 		return nil, errorResponseHandler(resp.StatusCode, respBytes)
 	}
 
-	return respBytes, nil
+	return &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: respBytes}, nil
 }
 
 // DoAndParse executes the request in the background context and reads the response body.
@@ -123,8 +146,17 @@ func (r *Request) DoAndParse(method, endpointURL, contentType string, body io.Re
 	event, parentEvent string, errorResponseHandler func(statusCode int, responseBody []byte) error,
 	response interface{},
 ) error {
-	respBytes, err := r.Do(method, endpointURL, contentType, body,
-		event, parentEvent, errorResponseHandler)
+	return r.DoAndParseContext(context.Background(), method, endpointURL, contentType, body, event, parentEvent,
+		errorResponseHandler, response)
+}
+
+// DoAndParseContext is the same as DoAndParse, but executes the request in the given context.
+func (r *Request) DoAndParseContext(ctx context.Context, method, endpointURL, contentType string, body io.Reader,
+	event, parentEvent string, errorResponseHandler func(statusCode int, responseBody []byte) error,
+	response interface{},
+) error {
+	respBytes, err := r.DoContext(ctx, method, endpointURL, contentType, nil, body,
+		event, parentEvent, nil, errorResponseHandler)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,110 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package issuermetadata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/metadatacache"
+	"github.com/trustbloc/wallet-sdk/pkg/metricslogger/noop"
+	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
+)
+
+const fetchAuthorizationServerMetadataViaGETReqEventText = "Fetch authorization server metadata via an HTTP GET " +
+	"request to %s"
+
+// GetAuthorizationServerMetadata gets an OAuth 2.0 authorization server's metadata from the well-known location
+// defined in RFC 8414. If the authorization server doesn't publish its metadata there, then the OpenID Connect
+// discovery location is tried as well, since many authorization servers only publish their metadata there.
+// authorizationServerURI is expected to be the authorization server's issuer identifier. If a cache is given, then
+// it's used to avoid fetching the metadata again while it's still fresh.
+func GetAuthorizationServerMetadata(ctx context.Context, authorizationServerURI string, httpClient httpClient,
+	metricsLogger api.MetricsLogger, parentEvent string, cache *metadatacache.Cache,
+) (*issuer.AuthorizationServerMetadata, error) {
+	if metricsLogger == nil {
+		metricsLogger = noop.NewMetricsLogger()
+	}
+
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: api.DefaultHTTPTimeout}
+	}
+
+	metadataEndpoints, err := authorizationServerMetadataEndpoints(authorizationServerURI)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+
+	for _, metadataEndpoint := range metadataEndpoints {
+		responseBytes, errGet := fetchDocument(ctx, metadataEndpoint, httpClient, metricsLogger, cache,
+			fmt.Sprintf(fetchAuthorizationServerMetadataViaGETReqEventText, metadataEndpoint), parentEvent)
+		if errGet != nil {
+			// There's no point in trying the other location if the context was cancelled or its deadline passed.
+			if ctx.Err() != nil {
+				return nil, errGet
+			}
+
+			errs = append(errs, errGet)
+
+			continue
+		}
+
+		return responseBytesToAuthorizationServerMetadata(responseBytes, authorizationServerURI)
+	}
+
+	return nil, fmt.Errorf("failed to get response from the authorization server's metadata endpoint: %w",
+		errors.Join(errs...))
+}
+
+// authorizationServerMetadataEndpoints returns the locations where the authorization server's metadata may be
+// published. Per RFC 8414, the well-known path is inserted between the host and path components of the
+// authorization server's identifier.
+func authorizationServerMetadataEndpoints(authorizationServerURI string) ([]string, error) {
+	parsedURI, err := url.Parse(authorizationServerURI)
+	if err != nil {
+		return nil, fmt.Errorf("invalid authorization server identifier: %w", err)
+	}
+
+	if parsedURI.Scheme == "" || parsedURI.Host == "" {
+		return nil, fmt.Errorf("invalid authorization server identifier: %s", authorizationServerURI)
+	}
+
+	path := strings.TrimSuffix(parsedURI.Path, "/")
+
+	return []string{
+		fmt.Sprintf("%s://%s/.well-known/oauth-authorization-server%s", parsedURI.Scheme, parsedURI.Host, path),
+		strings.TrimSuffix(authorizationServerURI, "/") + "/.well-known/openid-configuration",
+	}, nil
+}
+
+func responseBytesToAuthorizationServerMetadata(responseBytes []byte, authorizationServerURI string,
+) (*issuer.AuthorizationServerMetadata, error) {
+	var metadata issuer.AuthorizationServerMetadata
+
+	err := json.Unmarshal(responseBytes, &metadata)
+	if err != nil {
+		return nil, fmt.Errorf("decode authorization server metadata: %w", err)
+	}
+
+	// RFC 8414 requires the issuer in the metadata to be identical to the identifier used to fetch it, in order to
+	// prevent one authorization server from impersonating another. Only a trailing slash is tolerated.
+	if metadata.Issuer != "" &&
+		strings.TrimSuffix(metadata.Issuer, "/") != strings.TrimSuffix(authorizationServerURI, "/") {
+		return nil, fmt.Errorf("the issuer in the authorization server metadata (%s) does not match the "+
+			"authorization server identifier (%s)", metadata.Issuer, authorizationServerURI)
+	}
+
+	return &metadata, nil
+}
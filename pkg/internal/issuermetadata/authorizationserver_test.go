@@ -0,0 +1,119 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package issuermetadata_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/pkg/internal/issuermetadata"
+)
+
+// mockAuthorizationServerHandler serves authorization server metadata at a single path and records the paths of all
+// requests it receives.
+type mockAuthorizationServerHandler struct {
+	metadataPath   string
+	metadata       string
+	requestedPaths []string
+}
+
+func (m *mockAuthorizationServerHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	m.requestedPaths = append(m.requestedPaths, request.URL.Path)
+
+	if request.URL.Path != m.metadataPath {
+		writer.WriteHeader(http.StatusNotFound)
+
+		return
+	}
+
+	_, err := writer.Write([]byte(m.metadata))
+	if err != nil {
+		println(err.Error())
+	}
+}
+
+func TestGetAuthorizationServerMetadata(t *testing.T) {
+	t.Run("RFC 8414 location", func(t *testing.T) {
+		handler := &mockAuthorizationServerHandler{metadataPath: "/.well-known/oauth-authorization-server/tenant"}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		handler.metadata = fmt.Sprintf(`{"issuer":"%s/tenant","token_endpoint":"%s/tenant/token"}`,
+			server.URL, server.URL)
+
+		metadata, err := issuermetadata.GetAuthorizationServerMetadata(context.Background(), server.URL+"/tenant",
+			http.DefaultClient,
+			nil, "", nil)
+		require.NoError(t, err)
+		require.Equal(t, server.URL+"/tenant/token", metadata.TokenEndpoint)
+		require.Equal(t, []string{"/.well-known/oauth-authorization-server/tenant"}, handler.requestedPaths)
+	})
+	t.Run("OpenID Connect discovery location", func(t *testing.T) {
+		handler := &mockAuthorizationServerHandler{metadataPath: "/tenant/.well-known/openid-configuration"}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		handler.metadata = fmt.Sprintf(`{"issuer":"%s/tenant/","authorization_endpoint":"%s/tenant/auth"}`,
+			server.URL, server.URL)
+
+		metadata, err := issuermetadata.GetAuthorizationServerMetadata(context.Background(), server.URL+"/tenant",
+			http.DefaultClient,
+			nil, "", nil)
+		require.NoError(t, err)
+		require.Equal(t, server.URL+"/tenant/auth", metadata.AuthorizationEndpoint)
+		require.Len(t, handler.requestedPaths, 2)
+	})
+	t.Run("Issuer mismatch", func(t *testing.T) {
+		handler := &mockAuthorizationServerHandler{
+			metadataPath: "/.well-known/oauth-authorization-server",
+			metadata:     `{"issuer":"https://other.example.com"}`,
+		}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		metadata, err := issuermetadata.GetAuthorizationServerMetadata(context.Background(), server.URL, http.DefaultClient,
+			nil, "", nil)
+		require.ErrorContains(t, err, "the issuer in the authorization server metadata (https://other.example.com) "+
+			"does not match the authorization server identifier")
+		require.Nil(t, metadata)
+	})
+	t.Run("Metadata not found", func(t *testing.T) {
+		server := httptest.NewServer(&mockAuthorizationServerHandler{})
+		defer server.Close()
+
+		metadata, err := issuermetadata.GetAuthorizationServerMetadata(context.Background(), server.URL, http.DefaultClient,
+			nil, "", nil)
+		require.ErrorContains(t, err, "failed to get response from the authorization server's metadata endpoint")
+		require.ErrorContains(t, err, "expected status code 200 but got status code 404")
+		require.Nil(t, metadata)
+	})
+	t.Run("Fail to decode metadata", func(t *testing.T) {
+		server := httptest.NewServer(&mockAuthorizationServerHandler{
+			metadataPath: "/.well-known/oauth-authorization-server",
+			metadata:     `["response1","response2"]`,
+		})
+		defer server.Close()
+
+		metadata, err := issuermetadata.GetAuthorizationServerMetadata(context.Background(), server.URL, nil, nil, "", nil)
+		require.ErrorContains(t, err, "decode authorization server metadata")
+		require.Nil(t, metadata)
+	})
+	t.Run("Invalid authorization server identifier", func(t *testing.T) {
+		metadata, err := issuermetadata.GetAuthorizationServerMetadata(context.Background(), "not a URL",
+			http.DefaultClient, nil, "", nil)
+		require.EqualError(t, err, "invalid authorization server identifier: not a URL")
+		require.Nil(t, metadata)
+	})
+}
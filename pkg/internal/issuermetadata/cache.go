@@ -0,0 +1,115 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package issuermetadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/internal/httprequest"
+	"github.com/trustbloc/wallet-sdk/pkg/metadatacache"
+)
+
+const (
+	metadataCacheHitEventText         = "Metadata cache hit for %s"
+	metadataCacheRevalidatedEventText = "Metadata cache entry for %s revalidated"
+	metadataCacheErrorEventText       = "Metadata cache unavailable for %s: %s"
+)
+
+// fetchDocument gets the document at the given URL. If a cache is given, then a fresh cached copy of the document is
+// used if there is one. Otherwise, a stale cached copy is revalidated using its ETag (if it has one), and the cache is
+// updated with the result. The cache is only an optimization, so if its store fails, then the failure is logged as a
+// metrics event and the document is fetched (or used) as though there were no cache.
+func fetchDocument(ctx context.Context, documentURL string, httpClient httpClient, metricsLogger api.MetricsLogger,
+	cache *metadatacache.Cache, event, parentEvent string,
+) ([]byte, error) {
+	if cache == nil {
+		return httprequest.New(httpClient, metricsLogger).DoContext(ctx, http.MethodGet, documentURL, "", nil, nil,
+			event, parentEvent, nil, nil)
+	}
+
+	timeStartCacheLookup := time.Now()
+
+	entry, err := cache.Get(documentURL)
+	if err != nil {
+		err = logCacheError(metricsLogger, documentURL, parentEvent, err)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if entry != nil && cache.Fresh(entry) {
+		return entry.Document, metricsLogger.Log(&api.MetricsEvent{
+			Event:       fmt.Sprintf(metadataCacheHitEventText, documentURL),
+			ParentEvent: parentEvent,
+			Duration:    time.Since(timeStartCacheLookup),
+		})
+	}
+
+	var additionalHeaders http.Header
+
+	if entry != nil && entry.ETag != "" {
+		additionalHeaders = http.Header{"If-None-Match": []string{entry.ETag}}
+	}
+
+	timeStartRevalidation := time.Now()
+
+	resp, err := httprequest.New(httpClient, metricsLogger).DoContextWithResponse(ctx,
+		http.MethodGet, documentURL, "", additionalHeaders, nil, event, parentEvent,
+		[]int{http.StatusOK, http.StatusNotModified}, errorResponseHandler)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusOK {
+		err = cache.Put(documentURL, resp.Body, resp.Header)
+		if err != nil {
+			return resp.Body, logCacheError(metricsLogger, documentURL, parentEvent, err)
+		}
+
+		return resp.Body, nil
+	}
+
+	if additionalHeaders == nil {
+		return nil, errors.New("received a 304 Not Modified response to an unconditional request")
+	}
+
+	header := resp.Header.Clone()
+
+	if header.Get("ETag") == "" {
+		header.Set("ETag", entry.ETag)
+	}
+
+	err = cache.Put(documentURL, entry.Document, header)
+	if err != nil {
+		return entry.Document, logCacheError(metricsLogger, documentURL, parentEvent, err)
+	}
+
+	return entry.Document, metricsLogger.Log(&api.MetricsEvent{
+		Event:       fmt.Sprintf(metadataCacheRevalidatedEventText, documentURL),
+		ParentEvent: parentEvent,
+		Duration:    time.Since(timeStartRevalidation),
+	})
+}
+
+func logCacheError(metricsLogger api.MetricsLogger, documentURL, parentEvent string, cacheErr error) error {
+	return metricsLogger.Log(&api.MetricsEvent{
+		Event:       fmt.Sprintf(metadataCacheErrorEventText, documentURL, cacheErr),
+		ParentEvent: parentEvent,
+	})
+}
+
+// errorResponseHandler gives the same error as when a 200 status is the only acceptable one, since a 304 status is
+// only acceptable because it's a possible response to a conditional request.
+func errorResponseHandler(statusCode int, responseBody []byte) error {
+	return fmt.Errorf("expected status code %d but got status code %d with response body %s instead",
+		http.StatusOK, statusCode, responseBody)
+}
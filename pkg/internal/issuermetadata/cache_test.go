@@ -0,0 +1,194 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package issuermetadata_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/internal/issuermetadata"
+	"github.com/trustbloc/wallet-sdk/pkg/metadatacache"
+)
+
+// mockCachingIssuerServerHandler serves issuer metadata with the given caching headers, and responds to conditional
+// requests with a matching ETag with 304 Not Modified.
+type mockCachingIssuerServerHandler struct {
+	cacheControl        string
+	etag                string
+	requests            int
+	conditionalRequests int
+}
+
+func (m *mockCachingIssuerServerHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	m.requests++
+
+	if m.cacheControl != "" {
+		writer.Header().Set("Cache-Control", m.cacheControl)
+	}
+
+	if m.etag != "" {
+		writer.Header().Set("ETag", m.etag)
+	}
+
+	if ifNoneMatch := request.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		m.conditionalRequests++
+
+		if ifNoneMatch == m.etag {
+			writer.WriteHeader(http.StatusNotModified)
+
+			return
+		}
+	}
+
+	_, err := writer.Write([]byte(sampleIssuerMetadataJSON))
+	if err != nil {
+		println(err.Error())
+	}
+}
+
+type failingStore struct{}
+
+func (f *failingStore) Get(string) ([]byte, error) {
+	return nil, errors.New("get failed")
+}
+
+func (f *failingStore) Put(string, []byte) error {
+	return errors.New("put failed")
+}
+
+func (f *failingStore) Delete(string) error {
+	return errors.New("delete failed")
+}
+
+type recordingMetricsLogger struct {
+	events []string
+}
+
+func (r *recordingMetricsLogger) Log(metricsEvent *api.MetricsEvent) error {
+	r.events = append(r.events, metricsEvent.Event)
+
+	return nil
+}
+
+func TestGet_Cache(t *testing.T) {
+	t.Run("Fresh cached metadata is used", func(t *testing.T) {
+		handler := &mockCachingIssuerServerHandler{cacheControl: "max-age=300"}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		cache := metadatacache.New()
+		metricsLogger := &recordingMetricsLogger{}
+
+		for range 3 {
+			issuerMetadata, err := issuermetadata.Get(context.Background(), server.URL, http.DefaultClient, metricsLogger, "",
+				nil, cache, nil)
+			require.NoError(t, err)
+			require.NotNil(t, issuerMetadata)
+		}
+
+		require.Equal(t, 1, handler.requests)
+		require.Len(t, metricsLogger.events, 3)
+		require.True(t, strings.HasPrefix(metricsLogger.events[1], "Metadata cache hit for "+server.URL))
+	})
+	t.Run("Stale cached metadata is revalidated", func(t *testing.T) {
+		handler := &mockCachingIssuerServerHandler{cacheControl: "no-cache", etag: `"v1"`}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		cache := metadatacache.New()
+		metricsLogger := &recordingMetricsLogger{}
+
+		for range 2 {
+			issuerMetadata, err := issuermetadata.Get(context.Background(), server.URL, http.DefaultClient, metricsLogger, "",
+				nil, cache, nil)
+			require.NoError(t, err)
+			require.NotNil(t, issuerMetadata)
+		}
+
+		require.Equal(t, 2, handler.requests)
+		require.Equal(t, 1, handler.conditionalRequests)
+		require.True(t, strings.HasPrefix(metricsLogger.events[2], "Metadata cache entry for "+server.URL))
+
+		// If the metadata changed, then the new metadata is used.
+		handler.etag = `"v2"`
+
+		issuerMetadata, err := issuermetadata.Get(context.Background(), server.URL, http.DefaultClient, nil, "", nil, cache,
+			nil)
+		require.NoError(t, err)
+		require.NotNil(t, issuerMetadata)
+
+		entry, err := cache.Get(server.URL + "/.well-known/openid-credential-issuer")
+		require.NoError(t, err)
+		require.Equal(t, `"v2"`, entry.ETag)
+	})
+	t.Run("Metadata that must not be stored", func(t *testing.T) {
+		handler := &mockCachingIssuerServerHandler{cacheControl: "no-store", etag: `"v1"`}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		cache := metadatacache.New()
+
+		for range 2 {
+			_, err := issuermetadata.Get(context.Background(), server.URL, http.DefaultClient, nil, "", nil, cache, nil)
+			require.NoError(t, err)
+		}
+
+		require.Equal(t, 2, handler.requests)
+		require.Zero(t, handler.conditionalRequests)
+	})
+	t.Run("Cache store errors", func(t *testing.T) {
+		handler := &mockCachingIssuerServerHandler{cacheControl: "max-age=300"}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		cache := metadatacache.New(metadatacache.WithStore(&failingStore{}))
+		metricsLogger := &recordingMetricsLogger{}
+
+		// The metadata is still fetched, and the cache's failures are logged.
+		issuerMetadata, err := issuermetadata.Get(context.Background(), server.URL, http.DefaultClient, metricsLogger,
+			"", nil, cache, nil)
+		require.NoError(t, err)
+		require.NotNil(t, issuerMetadata)
+		require.Equal(t, 1, handler.requests)
+		require.Contains(t, metricsLogger.events, "Metadata cache unavailable for "+server.URL+
+			"/.well-known/openid-credential-issuer: failed to get metadata cache entry: get failed")
+		require.Contains(t, metricsLogger.events, "Metadata cache unavailable for "+server.URL+
+			"/.well-known/openid-credential-issuer: failed to put metadata cache entry: put failed")
+	})
+	t.Run("Server error", func(t *testing.T) {
+		server := httptest.NewServer(&mockIssuerServerHandler{metadataRequestShouldFail: true})
+		defer server.Close()
+
+		issuerMetadata, err := issuermetadata.Get(context.Background(), server.URL, http.DefaultClient, nil, "", nil,
+			metadatacache.New(), nil)
+		require.ErrorContains(t, err, "expected status code 200 but got status code 500 with response body "+
+			"test failure instead")
+		require.Nil(t, issuerMetadata)
+	})
+	t.Run("Unexpected 304 response", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+			writer.WriteHeader(http.StatusNotModified)
+		}))
+		defer server.Close()
+
+		issuerMetadata, err := issuermetadata.Get(context.Background(), server.URL, http.DefaultClient, nil, "", nil,
+			metadatacache.New(), nil)
+		require.ErrorContains(t, err, "received a 304 Not Modified response to an unconditional request")
+		require.Nil(t, issuerMetadata)
+	})
+}
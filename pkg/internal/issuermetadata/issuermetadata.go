@@ -8,16 +8,21 @@ SPDX-License-Identifier: Apache-2.0
 package issuermetadata
 
 import (
+	"context"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 
+	"github.com/trustbloc/kms-go/doc/jose"
 	"github.com/trustbloc/vc-go/jwt"
 
 	"github.com/trustbloc/wallet-sdk/pkg/api"
-	"github.com/trustbloc/wallet-sdk/pkg/internal/httprequest"
+	"github.com/trustbloc/wallet-sdk/pkg/internal/x5c"
+	"github.com/trustbloc/wallet-sdk/pkg/metadatacache"
 	"github.com/trustbloc/wallet-sdk/pkg/metricslogger/noop"
 	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
 )
@@ -28,10 +33,22 @@ type httpClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
+// VerificationPolicy controls which issuer metadata is accepted.
+type VerificationPolicy struct {
+	// If set, then metadata without a signed_metadata parameter is rejected.
+	RequireSignedMetadata bool
+	// If not empty, then signed metadata whose kid header is a DID URL is only accepted if the DID is in this list.
+	AllowedDIDs []string
+	// Used to verify the certificate chain of signed metadata that has an x5c header. If empty, then the x5c header
+	// is ignored, and the metadata is verified using its kid header like any other signed metadata.
+	TrustAnchors []*x509.Certificate
+}
+
 // Get gets an issuer's metadata by doing a lookup on its OpenID configuration endpoint.
-// issuerURI is expected to be the base URL for the issuer.
-func Get(issuerURI string, httpClient httpClient, metricsLogger api.MetricsLogger, parentEvent string,
-	signatureVerifier jwt.ProofChecker,
+// issuerURI is expected to be the base URL for the issuer. If a cache is given, then it's used to avoid fetching the
+// metadata again while it's still fresh. If a policy is given, then the metadata is rejected if it doesn't satisfy it.
+func Get(ctx context.Context, issuerURI string, httpClient httpClient, metricsLogger api.MetricsLogger,
+	parentEvent string, signatureVerifier jwt.ProofChecker, cache *metadatacache.Cache, policy *VerificationPolicy,
 ) (*issuer.Metadata, error) {
 	if metricsLogger == nil {
 		metricsLogger = noop.NewMetricsLogger()
@@ -43,18 +60,21 @@ func Get(issuerURI string, httpClient httpClient, metricsLogger api.MetricsLogge
 
 	metadataEndpoint := strings.TrimSuffix(issuerURI, "/") + "/.well-known/openid-credential-issuer"
 
-	responseBytes, err := httprequest.New(httpClient, metricsLogger).Do(
-		http.MethodGet, metadataEndpoint, "", nil,
-		fmt.Sprintf(fetchIssuerMetadataViaGETReqEventText, metadataEndpoint), parentEvent, nil)
+	responseBytes, err := fetchDocument(ctx, metadataEndpoint, httpClient, metricsLogger, cache,
+		fmt.Sprintf(fetchIssuerMetadataViaGETReqEventText, metadataEndpoint), parentEvent)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get response from the issuer's metadata endpoint: %w", err)
 	}
 
-	return responseBytesToIssuerMetadataObject(responseBytes, signatureVerifier)
+	if policy == nil {
+		policy = &VerificationPolicy{}
+	}
+
+	return responseBytesToIssuerMetadataObject(responseBytes, signatureVerifier, policy)
 }
 
 func responseBytesToIssuerMetadataObject(responseBytes []byte,
-	signatureVerifier jwt.ProofChecker,
+	signatureVerifier jwt.ProofChecker, policy *VerificationPolicy,
 ) (*issuer.Metadata, error) {
 	// The issuer metadata can come in one of two formats - either directly as JSON, or as a JWT.
 	var metadata issuer.Metadata
@@ -66,7 +86,11 @@ func responseBytesToIssuerMetadataObject(responseBytes []byte,
 	}
 
 	if metadata.SignedMetadata != "" {
-		return issuerMetadataObjectFromJWT(metadata.SignedMetadata, signatureVerifier, err)
+		return issuerMetadataObjectFromJWT(metadata.SignedMetadata, signatureVerifier, policy, err)
+	}
+
+	if policy.RequireSignedMetadata {
+		return nil, errors.New("the issuer's metadata is not signed, but signed metadata is required")
 	}
 
 	return &metadata, nil
@@ -77,8 +101,15 @@ func responseBytesToIssuerMetadataObject(responseBytes []byte,
 // is also not a JWT. This gives the caller additional information that can help them to more easily debug the cause
 // of the parsing failure.
 func issuerMetadataObjectFromJWT(signedMetadata string, signatureVerifier jwt.ProofChecker,
-	errUnmarshal error,
+	policy *VerificationPolicy, errUnmarshal error,
 ) (*issuer.Metadata, error) {
+	// If trust anchors are configured, then metadata with a certificate chain in its x5c header is verified using the
+	// chain (which must lead to one of them) instead of by resolving its kid header. Without trust anchors, the chain
+	// can't be verified, so metadata that has a kid header as well (e.g. a DID URL) is still verified that way.
+	if len(policy.TrustAnchors) > 0 && hasX5CHeader(signedMetadata) {
+		return issuerMetadataObjectFromX5CJWT(signedMetadata, policy)
+	}
+
 	// Try to parse it as a JWT.
 	// But first, make sure a signature verifier was passed in. If it wasn't, then the jwt.Parse call below will
 	// panic.
@@ -106,7 +137,45 @@ func issuerMetadataObjectFromJWT(signedMetadata string, signatureVerifier jwt.Pr
 			"value is missing or is not a string")
 	}
 
+	if len(policy.AllowedDIDs) > 0 {
+		did := strings.Split(kid, "#")[0]
+
+		if !slices.Contains(policy.AllowedDIDs, did) {
+			return nil, fmt.Errorf("the issuer's metadata is signed by %s, which is not an allowed DID", did)
+		}
+	}
+
 	metadata.SetJWTKID(kid)
 
 	return &metadata, nil
 }
+
+func issuerMetadataObjectFromX5CJWT(signedMetadata string, policy *VerificationPolicy) (*issuer.Metadata, error) {
+	_, payload, chain, err := x5c.ParseAndCheckProof(signedMetadata, policy.TrustAnchors)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify the issuer's signed metadata: %w", err)
+	}
+
+	var metadata issuer.Metadata
+
+	err = json.Unmarshal(payload, &metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the marshalled issuer metadata JSON into an "+
+			"issuer metadata object OpenID configuration endpoint: %w", err)
+	}
+
+	metadata.SetSigningCertificateChain(chain)
+
+	return &metadata, nil
+}
+
+func hasX5CHeader(signedMetadata string) bool {
+	jsonWebToken, _, err := jwt.Parse(signedMetadata)
+	if err != nil {
+		return false
+	}
+
+	_, ok := jsonWebToken.Headers[jose.HeaderX509CertificateChain]
+
+	return ok
+}
@@ -7,13 +7,24 @@ SPDX-License-Identifier: Apache-2.0
 package issuermetadata_test
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	_ "embed"
+	"encoding/base64"
 	"fmt"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/trustbloc/kms-go/doc/jose"
+	"github.com/trustbloc/vc-go/jwt"
 	"github.com/trustbloc/vc-go/proof/defaults"
 
 	"github.com/stretchr/testify/require"
@@ -76,8 +87,8 @@ func TestGet(t *testing.T) {
 
 			defer server.Close()
 
-			issuerMetadata, err := issuermetadata.Get(server.URL, nil, nil,
-				"", nil)
+			issuerMetadata, err := issuermetadata.Get(context.Background(), server.URL, nil, nil,
+				"", nil, nil, nil)
 			require.NoError(t, err)
 			require.NotNil(t, issuerMetadata)
 
@@ -107,8 +118,8 @@ func TestGet(t *testing.T) {
 
 			jwtVerifier := defaults.NewDefaultProofChecker(common.NewVDRKeyResolver(didResolver))
 
-			issuerMetadata, err := issuermetadata.Get(server.URL, http.DefaultClient, nil,
-				"", jwtVerifier)
+			issuerMetadata, err := issuermetadata.Get(context.Background(), server.URL, http.DefaultClient, nil,
+				"", jwtVerifier, nil, nil)
 			require.NoError(t, err)
 			require.NotNil(t, issuerMetadata)
 		})
@@ -119,8 +130,8 @@ func TestGet(t *testing.T) {
 			defer server.Close()
 
 			// For this sample, the signature is not actually valid, so we need to pass in a mock verifier.
-			issuerMetadata, err := issuermetadata.Get(server.URL, http.DefaultClient, nil,
-				"", &mockVerifier{})
+			issuerMetadata, err := issuermetadata.Get(context.Background(), server.URL, http.DefaultClient, nil,
+				"", &mockVerifier{}, nil, nil)
 			require.NoError(t, err)
 			require.NotNil(t, issuerMetadata)
 
@@ -143,8 +154,8 @@ func TestGet(t *testing.T) {
 		})
 	})
 	t.Run("Fail to reach issuer OpenID config endpoint", func(t *testing.T) {
-		issuerMetadata, err := issuermetadata.Get("http://BadURL", http.DefaultClient, nil,
-			"", nil)
+		issuerMetadata, err := issuermetadata.Get(context.Background(), "http://BadURL", http.DefaultClient, nil,
+			"", nil, nil, nil)
 		require.Contains(t, err.Error(), `Get "http://BadURL/.well-known/openid-credential-issuer":`+
 			` dial tcp: lookup BadURL`)
 		require.Nil(t, issuerMetadata)
@@ -155,8 +166,8 @@ func TestGet(t *testing.T) {
 
 		defer server.Close()
 
-		issuerMetadata, err := issuermetadata.Get(server.URL, http.DefaultClient, nil,
-			"", nil)
+		issuerMetadata, err := issuermetadata.Get(context.Background(), server.URL, http.DefaultClient, nil,
+			"", nil, nil, nil)
 		require.Contains(t, err.Error(), "failed to get response from the issuer's metadata endpoint: "+
 			"expected status code 200 but got status code 500 with response body test failure instead")
 		require.Nil(t, issuerMetadata)
@@ -167,8 +178,8 @@ func TestGet(t *testing.T) {
 
 		defer server.Close()
 
-		issuerMetadata, err := issuermetadata.Get(server.URL, http.DefaultClient, nil,
-			"", nil)
+		issuerMetadata, err := issuermetadata.Get(context.Background(), server.URL, http.DefaultClient, nil,
+			"", nil, nil, nil)
 		require.Contains(t, err.Error(), "decode metadata")
 		require.Nil(t, issuerMetadata)
 	})
@@ -178,8 +189,8 @@ func TestGet(t *testing.T) {
 
 		defer server.Close()
 
-		issuerMetadata, err := issuermetadata.Get(server.URL, http.DefaultClient, nil, "",
-			nil)
+		issuerMetadata, err := issuermetadata.Get(context.Background(), server.URL, http.DefaultClient, nil, "",
+			nil, nil, nil)
 		require.Contains(t, err.Error(), "missing signature verifier")
 		require.Nil(t, issuerMetadata)
 	})
@@ -189,8 +200,9 @@ func TestGet(t *testing.T) {
 
 		defer server.Close()
 
-		issuerMetadata, err := issuermetadata.Get(server.URL, http.DefaultClient, &failingMetricsLogger{},
-			"", nil)
+		issuerMetadata, err := issuermetadata.Get(context.Background(), server.URL, http.DefaultClient,
+			&failingMetricsLogger{},
+			"", nil, nil, nil)
 		require.Contains(t, err.Error(), "failed to log event (Event=Fetch issuer metadata via an HTTP GET "+
 			"request to http://127.0.0.1:")
 		require.Nil(t, issuerMetadata)
@@ -201,8 +213,8 @@ func TestGet(t *testing.T) {
 
 		defer server.Close()
 
-		issuerMetadata, err := issuermetadata.Get(server.URL, http.DefaultClient, nil,
-			"", &mockVerifier{})
+		issuerMetadata, err := issuermetadata.Get(context.Background(), server.URL, http.DefaultClient, nil,
+			"", &mockVerifier{}, nil, nil)
 		require.EqualError(t, err, "failed to parse the response from the issuer's OpenID Credential "+
 			"Issuer endpoint as JSON or as a JWT: JWT of compacted JWS form is "+
 			"supported only")
@@ -214,13 +226,198 @@ func TestGet(t *testing.T) {
 
 		defer server.Close()
 
-		issuerMetadata, err := issuermetadata.Get(server.URL, http.DefaultClient, nil,
-			"", &mockVerifier{})
+		issuerMetadata, err := issuermetadata.Get(context.Background(), server.URL, http.DefaultClient, nil,
+			"", &mockVerifier{}, nil, nil)
 		require.NoError(t, err)
 		require.NotNil(t, issuerMetadata)
 	})
 }
 
+func TestGet_VerificationPolicy(t *testing.T) {
+	t.Run("Unsigned metadata when signed metadata is required", func(t *testing.T) {
+		server := httptest.NewServer(&mockIssuerServerHandler{issuerMetadata: sampleIssuerMetadataJSON})
+
+		defer server.Close()
+
+		issuerMetadata, err := issuermetadata.Get(context.Background(), server.URL, http.DefaultClient, nil, "", nil, nil,
+			&issuermetadata.VerificationPolicy{RequireSignedMetadata: true})
+		require.EqualError(t, err, "the issuer's metadata is not signed, but signed metadata is required")
+		require.Nil(t, issuerMetadata)
+	})
+	t.Run("Allowed DIDs", func(t *testing.T) {
+		server := httptest.NewServer(&mockIssuerServerHandler{issuerMetadata: sampleIssuerMetadataWithOrderJWT})
+
+		defer server.Close()
+
+		const signingDID = "did:key:zDnaep4HZwjgbtD2Xu2dPLgzygwKKg1CNBNEXN1n5aTbQsBKU"
+
+		issuerMetadata, err := issuermetadata.Get(context.Background(), server.URL, http.DefaultClient, nil, "",
+			&mockVerifier{}, nil,
+			&issuermetadata.VerificationPolicy{RequireSignedMetadata: true, AllowedDIDs: []string{signingDID}})
+		require.NoError(t, err)
+		require.NotNil(t, issuerMetadata.GetJWTKID())
+
+		issuerMetadata, err = issuermetadata.Get(context.Background(), server.URL, http.DefaultClient, nil, "",
+			&mockVerifier{}, nil,
+			&issuermetadata.VerificationPolicy{AllowedDIDs: []string{"did:example:other"}})
+		require.EqualError(t, err, fmt.Sprintf("the issuer's metadata is signed by %s, which is not an "+
+			"allowed DID", signingDID))
+		require.Nil(t, issuerMetadata)
+	})
+	t.Run("x5c certificate chain", func(t *testing.T) {
+		rootKey, root := createCertificate(t, "Test Root CA", nil, nil)
+		signingKey, signingCertificate := createCertificate(t, "Test Issuer", root, rootKey)
+
+		signedMetadata := createX5CJWT(t, map[string]interface{}{"credential_issuer": "https://issuer.example.com"},
+			signingKey, signingCertificate)
+
+		server := httptest.NewServer(&mockIssuerServerHandler{
+			issuerMetadata: fmt.Sprintf(`{"signed_metadata":%q}`, signedMetadata),
+		})
+
+		defer server.Close()
+
+		t.Run("Trusted", func(t *testing.T) {
+			issuerMetadata, err := issuermetadata.Get(context.Background(), server.URL, http.DefaultClient, nil, "", nil, nil,
+				&issuermetadata.VerificationPolicy{
+					RequireSignedMetadata: true,
+					TrustAnchors:          []*x509.Certificate{root},
+				})
+			require.NoError(t, err)
+			require.Equal(t, "https://issuer.example.com", issuerMetadata.CredentialIssuer)
+			require.Equal(t, []*x509.Certificate{signingCertificate}, issuerMetadata.GetSigningCertificateChain())
+			require.Nil(t, issuerMetadata.GetJWTKID())
+		})
+		t.Run("No trust anchors", func(t *testing.T) {
+			// The x5c header is ignored, and there's no kid header to verify the metadata with instead.
+			issuerMetadata, err := issuermetadata.Get(context.Background(), server.URL, http.DefaultClient, nil, "",
+				&mockVerifier{}, nil, nil)
+			require.ErrorContains(t, err, "missed kid in jwt header")
+			require.Nil(t, issuerMetadata)
+		})
+		t.Run("Untrusted certificate chain", func(t *testing.T) {
+			otherRootKey, otherRoot := createCertificate(t, "Other Root CA", nil, nil)
+			require.NotNil(t, otherRootKey)
+
+			issuerMetadata, err := issuermetadata.Get(context.Background(), server.URL, http.DefaultClient, nil, "", nil, nil,
+				&issuermetadata.VerificationPolicy{TrustAnchors: []*x509.Certificate{otherRoot}})
+			require.ErrorContains(t, err, "failed to verify the issuer's signed metadata")
+			require.Nil(t, issuerMetadata)
+		})
+	})
+	t.Run("x5c and kid headers without a policy", func(t *testing.T) {
+		rootKey, root := createCertificate(t, "Test Root CA", nil, nil)
+		signingKey, signingCertificate := createCertificate(t, "Test Issuer", root, rootKey)
+
+		const kid = "did:example:issuer#key-1"
+
+		token, err := jwt.NewJoseSigned(map[string]interface{}{"credential_issuer": "https://issuer.example.com"}, nil,
+			&es256Signer{
+				key: signingKey,
+				x5c: []string{base64.StdEncoding.EncodeToString(signingCertificate.Raw)},
+				kid: kid,
+			})
+		require.NoError(t, err)
+
+		signedMetadata, err := token.Serialize(false)
+		require.NoError(t, err)
+
+		server := httptest.NewServer(&mockIssuerServerHandler{
+			issuerMetadata: fmt.Sprintf(`{"signed_metadata":%q}`, signedMetadata),
+		})
+
+		defer server.Close()
+
+		// Without trust anchors, the metadata is verified using its kid header.
+		issuerMetadata, err := issuermetadata.Get(context.Background(), server.URL, http.DefaultClient, nil, "",
+			&mockVerifier{}, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, "https://issuer.example.com", issuerMetadata.CredentialIssuer)
+		require.Equal(t, kid, *issuerMetadata.GetJWTKID())
+		require.Nil(t, issuerMetadata.GetSigningCertificateChain())
+	})
+}
+
+func createCertificate(t *testing.T, commonName string, parent *x509.Certificate,
+	parentKey *ecdsa.PrivateKey,
+) (*ecdsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  parent == nil,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	certificateBytes, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+
+	certificate, err := x509.ParseCertificate(certificateBytes)
+	require.NoError(t, err)
+
+	return key, certificate
+}
+
+func createX5CJWT(t *testing.T, claims interface{}, key *ecdsa.PrivateKey, certificate *x509.Certificate) string {
+	t.Helper()
+
+	token, err := jwt.NewJoseSigned(claims, nil, &es256Signer{
+		key: key,
+		x5c: []string{base64.StdEncoding.EncodeToString(certificate.Raw)},
+	})
+	require.NoError(t, err)
+
+	signedJWT, err := token.Serialize(false)
+	require.NoError(t, err)
+
+	return signedJWT
+}
+
+type es256Signer struct {
+	key *ecdsa.PrivateKey
+	x5c []string
+	kid string
+}
+
+func (s *es256Signer) Sign(data []byte) ([]byte, error) {
+	digest := sha256.Sum256(data)
+
+	r, sig, err := ecdsa.Sign(rand.Reader, s.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	sig.FillBytes(signature[32:])
+
+	return signature, nil
+}
+
+func (s *es256Signer) Headers() jose.Headers {
+	headers := jose.Headers{
+		jose.HeaderAlgorithm:            "ES256",
+		jose.HeaderX509CertificateChain: s.x5c,
+	}
+
+	if s.kid != "" {
+		headers[jose.HeaderKeyID] = s.kid
+	}
+
+	return headers
+}
+
 type mockVerifier struct{}
 
 func (m *mockVerifier) CheckJWTProof(jose.Headers, string, []byte, []byte) error {
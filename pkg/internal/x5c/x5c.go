@@ -0,0 +1,128 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package x5c contains functions for verifying JWTs that are signed with the key of an X.509 certificate whose chain
+// is given in the JWT's x5c header.
+package x5c
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/trustbloc/kms-go/doc/jose"
+	"github.com/trustbloc/kms-go/doc/jose/jwk/jwksupport"
+	"github.com/trustbloc/vc-go/jwt"
+
+	"github.com/trustbloc/wallet-sdk/pkg/common"
+)
+
+// ChainFromHeaders returns the certificate chain in the given JWT headers' x5c header, starting with the signing
+// certificate. An error is returned if there's no x5c header.
+func ChainFromHeaders(headers jose.Headers) ([]*x509.Certificate, error) {
+	x5cHeader, ok := headers[jose.HeaderX509CertificateChain]
+	if !ok {
+		return nil, errors.New("x5c header is missing")
+	}
+
+	encodedCertificates, ok := x5cHeader.([]interface{})
+	if !ok || len(encodedCertificates) == 0 {
+		return nil, errors.New("x5c header must be a non-empty array")
+	}
+
+	chain := make([]*x509.Certificate, len(encodedCertificates))
+
+	for i, encodedCertificate := range encodedCertificates {
+		encodedCertificateString, ok := encodedCertificate.(string)
+		if !ok {
+			return nil, fmt.Errorf("certificate at index %d in the x5c header is not a string", i)
+		}
+
+		certificateBytes, err := base64.StdEncoding.DecodeString(encodedCertificateString)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode certificate at index %d in the x5c header: %w", i, err)
+		}
+
+		chain[i], err = x509.ParseCertificate(certificateBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate at index %d in the x5c header: %w", i, err)
+		}
+	}
+
+	return chain, nil
+}
+
+// VerifyChain checks that the given certificate chain (which starts with the signing certificate) leads to one of the
+// given trust anchors, and that all the certificates in it are currently valid.
+func VerifyChain(chain, trustAnchors []*x509.Certificate) error {
+	if len(chain) == 0 {
+		return errors.New("certificate chain is empty")
+	}
+
+	if len(trustAnchors) == 0 {
+		return errors.New("no trust anchors are configured for verifying certificate chains")
+	}
+
+	roots := x509.NewCertPool()
+
+	for _, trustAnchor := range trustAnchors {
+		roots.AddCert(trustAnchor)
+	}
+
+	intermediates := x509.NewCertPool()
+
+	for _, certificate := range chain[1:] {
+		intermediates.AddCert(certificate)
+	}
+
+	_, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return fmt.Errorf("certificate chain of %s is not trusted: %w", chain[0].Subject, err)
+	}
+
+	return nil
+}
+
+// ParseAndCheckProof parses the given JWT and checks its signature using the key of the signing certificate from its
+// x5c header, after verifying the certificate chain against the given trust anchors. The verified chain is returned
+// along with the JWT and its payload. The JWT's kid header (if any) isn't used.
+func ParseAndCheckProof(jwtSerialized string, trustAnchors []*x509.Certificate,
+) (*jwt.JSONWebToken, []byte, []*x509.Certificate, error) {
+	jsonWebToken, payload, err := jwt.Parse(jwtSerialized)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	chain, err := ChainFromHeaders(jsonWebToken.Headers)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	err = VerifyChain(chain, trustAnchors)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	publicKey, err := jwksupport.JWKFromKey(chain[0].PublicKey)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("unsupported signing certificate key: %w", err)
+	}
+
+	// An expected proof issuer is passed in so that the kid header isn't required. It's ignored by the proof checker.
+	var expectedProofIssuer string
+
+	err = jwt.CheckProof(jwtSerialized, common.NewJWKProofChecker(publicKey), &expectedProofIssuer, nil)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid signature: %w", err)
+	}
+
+	return jsonWebToken, payload, chain, nil
+}
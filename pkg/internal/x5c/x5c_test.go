@@ -0,0 +1,162 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package x5c_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/kms-go/doc/jose"
+	"github.com/trustbloc/vc-go/jwt"
+
+	"github.com/trustbloc/wallet-sdk/pkg/internal/x5c"
+)
+
+func TestParseAndCheckProof(t *testing.T) {
+	rootKey, root := createCertificate(t, "Test Root CA", nil, nil, true)
+	intermediateKey, intermediate := createCertificate(t, "Test Intermediate CA", root, rootKey, true)
+	signingKey, signingCertificate := createCertificate(t, "Test Signer", intermediate, intermediateKey, false)
+
+	t.Run("Success", func(t *testing.T) {
+		signedJWT := createJWT(t, signingKey, signingCertificate, intermediate)
+
+		_, payload, chain, err := x5c.ParseAndCheckProof(signedJWT, []*x509.Certificate{root})
+		require.NoError(t, err)
+		require.JSONEq(t, `{"sub":"test"}`, string(payload))
+		require.Equal(t, []*x509.Certificate{signingCertificate, intermediate}, chain)
+	})
+	t.Run("Untrusted chain", func(t *testing.T) {
+		_, otherRoot := createCertificate(t, "Other Root CA", nil, nil, true)
+
+		signedJWT := createJWT(t, signingKey, signingCertificate, intermediate)
+
+		_, _, _, err := x5c.ParseAndCheckProof(signedJWT, []*x509.Certificate{otherRoot})
+		require.ErrorContains(t, err, "certificate chain of CN=Test Signer is not trusted")
+	})
+	t.Run("No trust anchors", func(t *testing.T) {
+		signedJWT := createJWT(t, signingKey, signingCertificate, intermediate)
+
+		_, _, _, err := x5c.ParseAndCheckProof(signedJWT, nil)
+		require.ErrorContains(t, err, "no trust anchors are configured")
+	})
+	t.Run("Signed with a different key", func(t *testing.T) {
+		otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		signedJWT := createJWT(t, otherKey, signingCertificate, intermediate)
+
+		_, _, _, err = x5c.ParseAndCheckProof(signedJWT, []*x509.Certificate{root})
+		require.ErrorContains(t, err, "invalid signature")
+	})
+}
+
+func TestChainFromHeaders(t *testing.T) {
+	t.Run("Missing header", func(t *testing.T) {
+		_, err := x5c.ChainFromHeaders(jose.Headers{})
+		require.EqualError(t, err, "x5c header is missing")
+	})
+	t.Run("Not an array", func(t *testing.T) {
+		_, err := x5c.ChainFromHeaders(jose.Headers{jose.HeaderX509CertificateChain: "certificate"})
+		require.EqualError(t, err, "x5c header must be a non-empty array")
+	})
+	t.Run("Not a string", func(t *testing.T) {
+		_, err := x5c.ChainFromHeaders(jose.Headers{jose.HeaderX509CertificateChain: []interface{}{1}})
+		require.EqualError(t, err, "certificate at index 0 in the x5c header is not a string")
+	})
+	t.Run("Invalid certificate", func(t *testing.T) {
+		_, err := x5c.ChainFromHeaders(jose.Headers{
+			jose.HeaderX509CertificateChain: []interface{}{base64.StdEncoding.EncodeToString([]byte("invalid"))},
+		})
+		require.ErrorContains(t, err, "failed to parse certificate at index 0 in the x5c header")
+	})
+}
+
+func createCertificate(t *testing.T, commonName string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey,
+	isCA bool,
+) (*ecdsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	certificateBytes, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+
+	certificate, err := x509.ParseCertificate(certificateBytes)
+	require.NoError(t, err)
+
+	return key, certificate
+}
+
+func createJWT(t *testing.T, key *ecdsa.PrivateKey, chain ...*x509.Certificate) string {
+	t.Helper()
+
+	encodedChain := make([]string, len(chain))
+
+	for i, certificate := range chain {
+		encodedChain[i] = base64.StdEncoding.EncodeToString(certificate.Raw)
+	}
+
+	token, err := jwt.NewJoseSigned(map[string]interface{}{"sub": "test"}, nil,
+		&es256Signer{key: key, x5c: encodedChain})
+	require.NoError(t, err)
+
+	signedJWT, err := token.Serialize(false)
+	require.NoError(t, err)
+
+	return signedJWT
+}
+
+type es256Signer struct {
+	key *ecdsa.PrivateKey
+	x5c []string
+}
+
+func (s *es256Signer) Sign(data []byte) ([]byte, error) {
+	digest := sha256.Sum256(data)
+
+	r, sig, err := ecdsa.Sign(rand.Reader, s.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	sig.FillBytes(signature[32:])
+
+	return signature, nil
+}
+
+func (s *es256Signer) Headers() jose.Headers {
+	return jose.Headers{
+		jose.HeaderAlgorithm:            "ES256",
+		jose.HeaderX509CertificateChain: s.x5c,
+	}
+}
@@ -0,0 +1,533 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package mdoc contains functions for parsing ISO/IEC 18013-5 mdocs (mobile documents), as issued in the mso_mdoc
+// credential format, and for converting them to and from Credential objects so that they can be stored and
+// displayed alongside other credentials.
+package mdoc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	afgotime "github.com/trustbloc/did-go/doc/util/time"
+	"github.com/trustbloc/vc-go/verifiable"
+	"github.com/veraison/go-cose"
+)
+
+const (
+	// credentialFieldName is the name of the field that holds the serialized mdoc in the Credential representation.
+	credentialFieldName = "mso_mdoc"
+
+	credentialIDPrefix       = "urn:mdoc:"
+	verifiableCredentialType = "VerifiableCredential"
+
+	// encodedCBORDataTag is the CBOR tag (24) used to wrap embedded CBOR data items.
+	encodedCBORDataTag = 24
+)
+
+// Mdoc represents an mdoc that was issued to the holder.
+type Mdoc struct {
+	// The document type, e.g. org.iso.18013.5.1.mDL.
+	DocType string
+	// The data elements in the mdoc, keyed by namespace and then by element identifier.
+	NameSpaces map[string]map[string]interface{}
+	// The time at which the issuer signed the mdoc.
+	Signed time.Time
+	// The time from which the mdoc is valid.
+	ValidFrom time.Time
+	// The time until which the mdoc is valid.
+	ValidUntil time.Time
+	// The issuer's X.509 certificate chain from the issuer's signature, starting with the signing certificate.
+	IssuerCertificateChain []*x509.Certificate
+
+	issuerSigned []byte
+}
+
+type issuerSigned struct {
+	NameSpaces map[string][]cbor.RawMessage `cbor:"nameSpaces"`
+	IssuerAuth cbor.RawMessage              `cbor:"issuerAuth"`
+}
+
+type issuerSignedItem struct {
+	DigestID          uint64      `cbor:"digestID"`
+	Random            []byte      `cbor:"random"`
+	ElementIdentifier string      `cbor:"elementIdentifier"`
+	ElementValue      interface{} `cbor:"elementValue"`
+}
+
+type mobileSecurityObject struct {
+	Version         string                       `cbor:"version"`
+	DigestAlgorithm string                       `cbor:"digestAlgorithm"`
+	ValueDigests    map[string]map[uint64][]byte `cbor:"valueDigests"`
+	DocType         string                       `cbor:"docType"`
+	ValidityInfo    validityInfo                 `cbor:"validityInfo"`
+}
+
+type validityInfo struct {
+	Signed     time.Time `cbor:"signed"`
+	ValidFrom  time.Time `cbor:"validFrom"`
+	ValidUntil time.Time `cbor:"validUntil"`
+}
+
+// IsMdoc indicates whether the given data is an mdoc, either as a base64url-encoded IssuerSigned structure
+// (optionally as a JSON string) or as a serialized Credential created by this package.
+// The issuer's signature is not checked.
+func IsMdoc(data []byte) bool {
+	_, err := Parse(data, WithDisabledProofCheck())
+
+	return err == nil
+}
+
+// Parse parses the given mdoc, which can either be a base64url-encoded IssuerSigned structure (optionally as a JSON
+// string), as returned by an issuer for the mso_mdoc format, or a serialized Credential created by this package.
+// The issuer's signature is verified using the certificate chain in its x5chain header, and the data elements
+// are checked against the value digests in the mobile security object.
+func Parse(data []byte, opts ...Opt) (*Mdoc, error) {
+	issuerSignedBytes, err := extractIssuerSigned(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseIssuerSigned(issuerSignedBytes, processOpts(opts))
+}
+
+// ParseCredential parses the given mdoc (see Parse) into a Credential object.
+func ParseCredential(data []byte, opts ...Opt) (*verifiable.Credential, error) {
+	mdoc, err := Parse(data, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return mdoc.Credential()
+}
+
+// FromCredential returns the mdoc that the given Credential object was created from.
+// An error is returned if the credential is not an mdoc.
+func FromCredential(vc *verifiable.Credential, opts ...Opt) (*Mdoc, error) {
+	encodedIssuerSigned, ok := vc.CustomField(credentialFieldName).(string)
+	if !ok {
+		return nil, errors.New("credential is not an mdoc")
+	}
+
+	issuerSignedBytes, err := decodeBase64URL(encodedIssuerSigned)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseIssuerSigned(issuerSignedBytes, processOpts(opts))
+}
+
+// IsMdocCredential indicates whether the given Credential object was created from an mdoc by this package.
+func IsMdocCredential(vc *verifiable.Credential) bool {
+	_, ok := vc.CustomField(credentialFieldName).(string)
+
+	return ok
+}
+
+// Serialize returns the mdoc as a base64url-encoded IssuerSigned structure.
+func (m *Mdoc) Serialize() string {
+	return base64.RawURLEncoding.EncodeToString(m.issuerSigned)
+}
+
+// Credential returns a Credential object representing this mdoc, so that it can be stored and handled like other
+// credentials. Its types are VerifiableCredential and the doctype, and its credential subject contains an object
+// for each namespace with the data elements in that namespace. Its ID is derived from the mdoc's contents.
+func (m *Mdoc) Credential() (*verifiable.Credential, error) {
+	subject := verifiable.Subject{CustomFields: verifiable.CustomFields{}}
+
+	for nameSpace, elements := range m.NameSpaces {
+		subject.CustomFields[nameSpace] = elements
+	}
+
+	contents := verifiable.CredentialContents{
+		Context: []string{verifiable.V1ContextURI},
+		ID:      m.credentialID(),
+		Types:   []string{verifiableCredentialType, m.DocType},
+		Subject: []verifiable.Subject{subject},
+	}
+
+	if !m.Signed.IsZero() {
+		contents.Issued = afgotime.NewTime(m.Signed)
+	}
+
+	if !m.ValidUntil.IsZero() {
+		contents.Expired = afgotime.NewTime(m.ValidUntil)
+	}
+
+	vc, err := verifiable.CreateCredential(contents, verifiable.CustomFields{credentialFieldName: m.Serialize()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create credential from mdoc: %w", err)
+	}
+
+	return vc, nil
+}
+
+func (m *Mdoc) credentialID() string {
+	digest := sha256.Sum256(m.issuerSigned)
+
+	return credentialIDPrefix + hex.EncodeToString(digest[:])
+}
+
+func extractIssuerSigned(data []byte) ([]byte, error) {
+	var credential map[string]interface{}
+
+	if json.Unmarshal(data, &credential) == nil {
+		encodedIssuerSigned, ok := credential[credentialFieldName].(string)
+		if !ok {
+			return nil, errors.New("credential is not an mdoc")
+		}
+
+		return decodeBase64URL(encodedIssuerSigned)
+	}
+
+	var encodedIssuerSigned string
+
+	if json.Unmarshal(data, &encodedIssuerSigned) != nil {
+		encodedIssuerSigned = string(data)
+	}
+
+	return decodeBase64URL(encodedIssuerSigned)
+}
+
+func decodeBase64URL(encoded string) ([]byte, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode mdoc: %w", err)
+	}
+
+	return decoded, nil
+}
+
+func parseIssuerSigned(issuerSignedBytes []byte, opts *options) (*Mdoc, error) {
+	decMode, err := cbor.DecOptions{DefaultMapType: reflect.TypeOf(map[string]interface{}{})}.DecMode()
+	if err != nil {
+		return nil, err
+	}
+
+	var signed issuerSigned
+
+	err = decMode.Unmarshal(issuerSignedBytes, &signed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode mdoc: %w", err)
+	}
+
+	if len(signed.IssuerAuth) == 0 {
+		return nil, errors.New("mdoc is missing issuerAuth")
+	}
+
+	issuerAuth, err := decodeIssuerAuth(signed.IssuerAuth)
+	if err != nil {
+		return nil, err
+	}
+
+	mso, err := decodeMobileSecurityObject(issuerAuth.Payload, decMode)
+	if err != nil {
+		return nil, err
+	}
+
+	certificateChain, err := getCertificateChain(&issuerAuth.Headers)
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.disabledProofCheck {
+		err = verifyIssuerAuth(issuerAuth, certificateChain, mso)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	nameSpaces, err := decodeNameSpaces(signed.NameSpaces, mso, !opts.disabledProofCheck, decMode)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Mdoc{
+		DocType:                mso.DocType,
+		NameSpaces:             nameSpaces,
+		Signed:                 mso.ValidityInfo.Signed,
+		ValidFrom:              mso.ValidityInfo.ValidFrom,
+		ValidUntil:             mso.ValidityInfo.ValidUntil,
+		IssuerCertificateChain: certificateChain,
+		issuerSigned:           issuerSignedBytes,
+	}, nil
+}
+
+// decodeIssuerAuth decodes the issuer's COSE_Sign1 signature, which may or may not be tagged.
+func decodeIssuerAuth(issuerAuthBytes []byte) (*cose.Sign1Message, error) {
+	var issuerAuth cose.Sign1Message
+
+	if issuerAuth.UnmarshalCBOR(issuerAuthBytes) == nil {
+		return &issuerAuth, nil
+	}
+
+	err := (*cose.UntaggedSign1Message)(&issuerAuth).UnmarshalCBOR(issuerAuthBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode mdoc issuerAuth: %w", err)
+	}
+
+	return &issuerAuth, nil
+}
+
+// decodeMobileSecurityObject decodes the mobile security object (MSO) from the issuerAuth payload, which normally
+// wraps it with the encoded CBOR data tag.
+func decodeMobileSecurityObject(payload []byte, decMode cbor.DecMode) (*mobileSecurityObject, error) {
+	if len(payload) == 0 {
+		return nil, errors.New("mdoc issuerAuth has no payload")
+	}
+
+	msoBytes := payload
+
+	var taggedMSO cbor.Tag
+
+	if decMode.Unmarshal(payload, &taggedMSO) == nil && taggedMSO.Number == encodedCBORDataTag {
+		if content, ok := taggedMSO.Content.([]byte); ok {
+			msoBytes = content
+		}
+	}
+
+	var mso mobileSecurityObject
+
+	err := decMode.Unmarshal(msoBytes, &mso)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode mdoc mobile security object: %w", err)
+	}
+
+	if mso.DocType == "" {
+		return nil, errors.New("mdoc mobile security object is missing the docType")
+	}
+
+	return &mso, nil
+}
+
+// getCertificateChain returns the certificates from the x5chain header, which contains either a single certificate
+// or an array of certificates. The signing certificate comes first.
+func getCertificateChain(headers *cose.Headers) ([]*x509.Certificate, error) {
+	x5chain, ok := headers.Unprotected[cose.HeaderLabelX5Chain]
+	if !ok {
+		x5chain, ok = headers.Protected[cose.HeaderLabelX5Chain]
+		if !ok {
+			return nil, errors.New("mdoc issuerAuth is missing the x5chain header")
+		}
+	}
+
+	var encodedCertificates [][]byte
+
+	switch value := x5chain.(type) {
+	case []byte:
+		encodedCertificates = [][]byte{value}
+	case []interface{}:
+		for _, element := range value {
+			encodedCertificate, isBytes := element.([]byte)
+			if !isBytes {
+				return nil, errors.New("mdoc x5chain header contains an invalid certificate")
+			}
+
+			encodedCertificates = append(encodedCertificates, encodedCertificate)
+		}
+	default:
+		return nil, errors.New("mdoc x5chain header contains an invalid certificate")
+	}
+
+	if len(encodedCertificates) == 0 {
+		return nil, errors.New("mdoc x5chain header is empty")
+	}
+
+	certificateChain := make([]*x509.Certificate, len(encodedCertificates))
+
+	for i, encodedCertificate := range encodedCertificates {
+		certificate, err := x509.ParseCertificate(encodedCertificate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate in mdoc x5chain header: %w", err)
+		}
+
+		certificateChain[i] = certificate
+	}
+
+	return certificateChain, nil
+}
+
+// verifyIssuerAuth verifies the issuer's signature using the signing certificate, checks that each certificate in
+// the chain is signed by the next one and that all of them are currently valid, and checks the mdoc's validity
+// period.
+func verifyIssuerAuth(issuerAuth *cose.Sign1Message, certificateChain []*x509.Certificate,
+	mso *mobileSecurityObject,
+) error {
+	algorithm, err := issuerAuth.Headers.Protected.Algorithm()
+	if err != nil {
+		return fmt.Errorf("failed to get mdoc signature algorithm: %w", err)
+	}
+
+	verifier, err := cose.NewVerifier(algorithm, certificateChain[0].PublicKey)
+	if err != nil {
+		return fmt.Errorf("failed to verify the issuer's signature on the mdoc: %w", err)
+	}
+
+	err = issuerAuth.Verify(nil, verifier)
+	if err != nil {
+		return fmt.Errorf("failed to verify the issuer's signature on the mdoc: %w", err)
+	}
+
+	now := time.Now()
+
+	for i, certificate := range certificateChain {
+		if now.Before(certificate.NotBefore) || now.After(certificate.NotAfter) {
+			return fmt.Errorf("certificate %s in the mdoc x5chain header is not currently valid",
+				certificate.Subject)
+		}
+
+		if i < len(certificateChain)-1 {
+			err = certificate.CheckSignatureFrom(certificateChain[i+1])
+			if err != nil {
+				return fmt.Errorf("invalid mdoc x5chain header: %w", err)
+			}
+		}
+	}
+
+	if now.Before(mso.ValidityInfo.ValidFrom) {
+		return fmt.Errorf("mdoc is not valid until %s", mso.ValidityInfo.ValidFrom.Format(time.RFC3339))
+	}
+
+	if !mso.ValidityInfo.ValidUntil.IsZero() && now.After(mso.ValidityInfo.ValidUntil) {
+		return fmt.Errorf("mdoc expired at %s", mso.ValidityInfo.ValidUntil.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// decodeNameSpaces decodes the data elements in each namespace. If verifyDigests is true, then each element is checked
+// against the corresponding value digest in the mobile security object.
+func decodeNameSpaces(signedNameSpaces map[string][]cbor.RawMessage, mso *mobileSecurityObject, verifyDigests bool,
+	decMode cbor.DecMode,
+) (map[string]map[string]interface{}, error) {
+	nameSpaces := make(map[string]map[string]interface{}, len(signedNameSpaces))
+
+	for nameSpace, encodedItems := range signedNameSpaces {
+		elements := make(map[string]interface{}, len(encodedItems))
+
+		for _, encodedItem := range encodedItems {
+			item, err := decodeIssuerSignedItem(encodedItem, decMode)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode element in mdoc namespace %s: %w", nameSpace, err)
+			}
+
+			if verifyDigests {
+				err = verifyDigest(encodedItem, item, nameSpace, mso)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			elements[item.ElementIdentifier] = normalizeElementValue(item.ElementValue)
+		}
+
+		nameSpaces[nameSpace] = elements
+	}
+
+	return nameSpaces, nil
+}
+
+func decodeIssuerSignedItem(encodedItem cbor.RawMessage, decMode cbor.DecMode) (*issuerSignedItem, error) {
+	var taggedItem cbor.Tag
+
+	err := decMode.Unmarshal(encodedItem, &taggedItem)
+	if err != nil {
+		return nil, err
+	}
+
+	itemBytes, ok := taggedItem.Content.([]byte)
+	if taggedItem.Number != encodedCBORDataTag || !ok {
+		return nil, errors.New("element is not encoded CBOR data")
+	}
+
+	var item issuerSignedItem
+
+	err = decMode.Unmarshal(itemBytes, &item)
+	if err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+// verifyDigest checks that the digest of the encoded item (including its encoded CBOR data tag) matches the value
+// digest with the item's digest ID in the mobile security object.
+func verifyDigest(encodedItem cbor.RawMessage, item *issuerSignedItem, nameSpace string,
+	mso *mobileSecurityObject,
+) error {
+	expectedDigest, ok := mso.ValueDigests[nameSpace][item.DigestID]
+	if !ok {
+		return fmt.Errorf("mdoc mobile security object has no digest for element %s in namespace %s",
+			item.ElementIdentifier, nameSpace)
+	}
+
+	digest, err := computeDigest(mso.DigestAlgorithm, encodedItem)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(digest, expectedDigest) {
+		return fmt.Errorf("digest mismatch for element %s in mdoc namespace %s", item.ElementIdentifier, nameSpace)
+	}
+
+	return nil
+}
+
+func computeDigest(digestAlgorithm string, data []byte) ([]byte, error) {
+	switch digestAlgorithm {
+	case "SHA-256":
+		digest := sha256.Sum256(data)
+
+		return digest[:], nil
+	case "SHA-384":
+		digest := sha512.Sum384(data)
+
+		return digest[:], nil
+	case "SHA-512":
+		digest := sha512.Sum512(data)
+
+		return digest[:], nil
+	default:
+		return nil, fmt.Errorf("unsupported mdoc digest algorithm: %s", digestAlgorithm)
+	}
+}
+
+// normalizeElementValue converts CBOR-specific values to their JSON-friendly equivalents: tagged values (such as
+// full-dates) are replaced with their content and date-times are formatted as RFC 3339 strings.
+func normalizeElementValue(value interface{}) interface{} {
+	switch typedValue := value.(type) {
+	case cbor.Tag:
+		return normalizeElementValue(typedValue.Content)
+	case time.Time:
+		return typedValue.UTC().Format(time.RFC3339)
+	case map[string]interface{}:
+		for key, nestedValue := range typedValue {
+			typedValue[key] = normalizeElementValue(nestedValue)
+		}
+
+		return typedValue
+	case []interface{}:
+		for i, nestedValue := range typedValue {
+			typedValue[i] = normalizeElementValue(nestedValue)
+		}
+
+		return typedValue
+	default:
+		return value
+	}
+}
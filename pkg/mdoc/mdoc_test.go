@@ -0,0 +1,376 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mdoc_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/vc-go/verifiable"
+	"github.com/veraison/go-cose"
+
+	"github.com/trustbloc/wallet-sdk/pkg/mdoc"
+)
+
+const (
+	mDLDocType   = "org.iso.18013.5.1.mDL"
+	mDLNameSpace = "org.iso.18013.5.1"
+)
+
+func TestIsMdoc(t *testing.T) {
+	issuer := newTestIssuer(t)
+
+	encodedMdoc := issuer.createMdoc(t, &mdocParams{})
+
+	require.True(t, mdoc.IsMdoc([]byte(encodedMdoc)))
+
+	quotedMdoc, err := json.Marshal(encodedMdoc)
+	require.NoError(t, err)
+	require.True(t, mdoc.IsMdoc(quotedMdoc))
+
+	require.False(t, mdoc.IsMdoc([]byte(`{"@context":["https://www.w3.org/2018/credentials/v1"]}`)))
+	require.False(t, mdoc.IsMdoc([]byte("eyJhbGciOiJFUzI1NiJ9.e30.c2ln")))
+	require.False(t, mdoc.IsMdoc([]byte("")))
+}
+
+func TestParse(t *testing.T) {
+	issuer := newTestIssuer(t)
+
+	t.Run("Success", func(t *testing.T) {
+		encodedMdoc := issuer.createMdoc(t, &mdocParams{})
+
+		parsedMdoc, err := mdoc.Parse([]byte(encodedMdoc))
+		require.NoError(t, err)
+
+		require.Equal(t, mDLDocType, parsedMdoc.DocType)
+		require.Len(t, parsedMdoc.NameSpaces, 1)
+		require.Equal(t, "Mustermann", parsedMdoc.NameSpaces[mDLNameSpace]["family_name"])
+		require.Equal(t, "1971-09-01", parsedMdoc.NameSpaces[mDLNameSpace]["birth_date"])
+		require.Equal(t, uint64(1), parsedMdoc.NameSpaces[mDLNameSpace]["sex"])
+		require.Equal(t, true, parsedMdoc.NameSpaces[mDLNameSpace]["age_over_18"])
+		require.Len(t, parsedMdoc.IssuerCertificateChain, 2)
+		require.Equal(t, "Test Document Signer", parsedMdoc.IssuerCertificateChain[0].Subject.CommonName)
+		require.False(t, parsedMdoc.Signed.IsZero())
+		require.True(t, parsedMdoc.ValidUntil.After(time.Now()))
+		require.Equal(t, encodedMdoc, parsedMdoc.Serialize())
+	})
+	t.Run("Tagged issuerAuth", func(t *testing.T) {
+		encodedMdoc := issuer.createMdoc(t, &mdocParams{taggedIssuerAuth: true})
+
+		parsedMdoc, err := mdoc.Parse([]byte(encodedMdoc))
+		require.NoError(t, err)
+		require.Equal(t, mDLDocType, parsedMdoc.DocType)
+	})
+	t.Run("Element doesn't match its digest", func(t *testing.T) {
+		encodedMdoc := issuer.createMdoc(t, &mdocParams{tamperedElement: "family_name"})
+
+		_, err := mdoc.Parse([]byte(encodedMdoc))
+		require.EqualError(t, err, "digest mismatch for element family_name in mdoc namespace "+mDLNameSpace)
+
+		_, err = mdoc.Parse([]byte(encodedMdoc), mdoc.WithDisabledProofCheck())
+		require.NoError(t, err)
+	})
+	t.Run("Invalid signature", func(t *testing.T) {
+		otherIssuer := newTestIssuer(t)
+
+		encodedMdoc := issuer.createMdoc(t, &mdocParams{signingKey: otherIssuer.signingKey})
+
+		_, err := mdoc.Parse([]byte(encodedMdoc))
+		require.ErrorContains(t, err, "failed to verify the issuer's signature on the mdoc")
+	})
+	t.Run("Certificate not signed by the next one in the chain", func(t *testing.T) {
+		otherIssuer := newTestIssuer(t)
+
+		encodedMdoc := issuer.createMdoc(t, &mdocParams{
+			certificateChain: [][]byte{issuer.certificateChain[0], otherIssuer.certificateChain[1]},
+		})
+
+		_, err := mdoc.Parse([]byte(encodedMdoc))
+		require.ErrorContains(t, err, "invalid mdoc x5chain header")
+	})
+	t.Run("Missing x5chain header", func(t *testing.T) {
+		encodedMdoc := issuer.createMdoc(t, &mdocParams{certificateChain: [][]byte{}})
+
+		_, err := mdoc.Parse([]byte(encodedMdoc), mdoc.WithDisabledProofCheck())
+		require.EqualError(t, err, "mdoc issuerAuth is missing the x5chain header")
+	})
+	t.Run("Expired", func(t *testing.T) {
+		encodedMdoc := issuer.createMdoc(t, &mdocParams{validUntil: time.Now().Add(-time.Hour)})
+
+		_, err := mdoc.Parse([]byte(encodedMdoc))
+		require.ErrorContains(t, err, "mdoc expired at")
+	})
+	t.Run("Not yet valid", func(t *testing.T) {
+		encodedMdoc := issuer.createMdoc(t, &mdocParams{validFrom: time.Now().Add(time.Hour)})
+
+		_, err := mdoc.Parse([]byte(encodedMdoc))
+		require.ErrorContains(t, err, "mdoc is not valid until")
+	})
+	t.Run("Not base64url", func(t *testing.T) {
+		_, err := mdoc.Parse([]byte("not an mdoc"))
+		require.ErrorContains(t, err, "failed to decode mdoc")
+	})
+	t.Run("Missing issuerAuth", func(t *testing.T) {
+		issuerSigned, err := cbor.Marshal(map[string]interface{}{"nameSpaces": map[string]interface{}{}})
+		require.NoError(t, err)
+
+		_, err = mdoc.Parse([]byte(base64.RawURLEncoding.EncodeToString(issuerSigned)))
+		require.EqualError(t, err, "mdoc is missing issuerAuth")
+	})
+	t.Run("Credential that isn't an mdoc", func(t *testing.T) {
+		_, err := mdoc.Parse([]byte(`{"@context":["https://www.w3.org/2018/credentials/v1"]}`))
+		require.EqualError(t, err, "credential is not an mdoc")
+	})
+}
+
+func TestMdoc_Credential(t *testing.T) {
+	issuer := newTestIssuer(t)
+
+	encodedMdoc := issuer.createMdoc(t, &mdocParams{})
+
+	vc, err := mdoc.ParseCredential([]byte(encodedMdoc))
+	require.NoError(t, err)
+
+	contents := vc.Contents()
+	require.Equal(t, []string{"VerifiableCredential", mDLDocType}, contents.Types)
+	require.True(t, strings.HasPrefix(contents.ID, "urn:mdoc:"))
+	require.NotNil(t, contents.Issued)
+	require.NotNil(t, contents.Expired)
+	require.Len(t, contents.Subject, 1)
+
+	elements, ok := contents.Subject[0].CustomFields[mDLNameSpace].(map[string]interface{})
+	require.True(t, ok)
+	require.Equal(t, "Erika", elements["given_name"])
+
+	serializedVC, err := vc.MarshalJSON()
+	require.NoError(t, err)
+
+	t.Run("Parse serialized credential", func(t *testing.T) {
+		reparsedVC, err := mdoc.ParseCredential(serializedVC)
+		require.NoError(t, err)
+		require.Equal(t, contents.ID, reparsedVC.Contents().ID)
+		require.Equal(t, contents.Types, reparsedVC.Contents().Types)
+	})
+	t.Run("From credential", func(t *testing.T) {
+		mdocFromVC, err := mdoc.FromCredential(vc)
+		require.NoError(t, err)
+		require.Equal(t, encodedMdoc, mdocFromVC.Serialize())
+		require.Equal(t, "Mustermann", mdocFromVC.NameSpaces[mDLNameSpace]["family_name"])
+	})
+	t.Run("From credential that isn't an mdoc", func(t *testing.T) {
+		otherVC, err := verifiable.CreateCredential(verifiable.CredentialContents{
+			Context: []string{verifiable.V1ContextURI},
+			Types:   []string{"VerifiableCredential"},
+		}, nil)
+		require.NoError(t, err)
+
+		_, err = mdoc.FromCredential(otherVC)
+		require.EqualError(t, err, "credential is not an mdoc")
+	})
+}
+
+type testIssuer struct {
+	signingKey       *ecdsa.PrivateKey
+	certificateChain [][]byte
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	rootTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test IACA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	rootCertificate, err := x509.CreateCertificate(rand.Reader, rootTemplate, rootTemplate, &rootKey.PublicKey, rootKey)
+	require.NoError(t, err)
+
+	signerTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "Test Document Signer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+
+	signerCertificate, err := x509.CreateCertificate(rand.Reader, signerTemplate, rootTemplate,
+		&signingKey.PublicKey, rootKey)
+	require.NoError(t, err)
+
+	return &testIssuer{
+		signingKey:       signingKey,
+		certificateChain: [][]byte{signerCertificate, rootCertificate},
+	}
+}
+
+type mdocParams struct {
+	signingKey       *ecdsa.PrivateKey
+	certificateChain [][]byte
+	validFrom        time.Time
+	validUntil       time.Time
+	tamperedElement  string
+	taggedIssuerAuth bool
+}
+
+// createMdoc returns a base64url-encoded IssuerSigned structure for a sample mDL.
+func (i *testIssuer) createMdoc(t *testing.T, params *mdocParams) string { //nolint:funlen // test helper
+	t.Helper()
+
+	elements := []struct {
+		identifier string
+		value      interface{}
+	}{
+		{"family_name", "Mustermann"},
+		{"given_name", "Erika"},
+		{"birth_date", cbor.Tag{Number: 1004, Content: "1971-09-01"}},
+		{"sex", 1},
+		{"age_over_18", true},
+	}
+
+	encodedItems := make([]cbor.RawMessage, len(elements))
+	digests := map[uint64][]byte{}
+
+	for digestID, element := range elements {
+		encodedItem := encodeTagged(t, map[string]interface{}{
+			"digestID":          digestID,
+			"random":            []byte("random-" + element.identifier),
+			"elementIdentifier": element.identifier,
+			"elementValue":      element.value,
+		})
+
+		digest := sha256.Sum256(encodedItem)
+		digests[uint64(digestID)] = digest[:]
+
+		if element.identifier == params.tamperedElement {
+			encodedItem = encodeTagged(t, map[string]interface{}{
+				"digestID":          digestID,
+				"random":            []byte("random-" + element.identifier),
+				"elementIdentifier": element.identifier,
+				"elementValue":      "Tampered",
+			})
+		}
+
+		encodedItems[digestID] = encodedItem
+	}
+
+	validFrom := params.validFrom
+	if validFrom.IsZero() {
+		validFrom = time.Now().Add(-time.Minute)
+	}
+
+	validUntil := params.validUntil
+	if validUntil.IsZero() {
+		validUntil = time.Now().Add(time.Hour)
+	}
+
+	timeEncMode, err := cbor.EncOptions{Time: cbor.TimeRFC3339, TimeTag: cbor.EncTagRequired}.EncMode()
+	require.NoError(t, err)
+
+	mso, err := timeEncMode.Marshal(map[string]interface{}{
+		"version":         "1.0",
+		"digestAlgorithm": "SHA-256",
+		"valueDigests":    map[string]interface{}{mDLNameSpace: digests},
+		"deviceKeyInfo":   map[string]interface{}{"deviceKey": map[int]int{1: 2}},
+		"docType":         mDLDocType,
+		"validityInfo": map[string]interface{}{
+			"signed":     validFrom.Truncate(time.Second),
+			"validFrom":  validFrom.Truncate(time.Second),
+			"validUntil": validUntil.Truncate(time.Second),
+		},
+	})
+	require.NoError(t, err)
+
+	issuerAuth := i.signMSO(t, mso, params)
+
+	issuerSigned, err := cbor.Marshal(map[string]interface{}{
+		"nameSpaces": map[string]interface{}{mDLNameSpace: encodedItems},
+		"issuerAuth": cbor.RawMessage(issuerAuth),
+	})
+	require.NoError(t, err)
+
+	return base64.RawURLEncoding.EncodeToString(issuerSigned)
+}
+
+func (i *testIssuer) signMSO(t *testing.T, mso []byte, params *mdocParams) []byte {
+	t.Helper()
+
+	signingKey := params.signingKey
+	if signingKey == nil {
+		signingKey = i.signingKey
+	}
+
+	certificateChain := params.certificateChain
+	if certificateChain == nil {
+		certificateChain = i.certificateChain
+	}
+
+	signer, err := cose.NewSigner(cose.AlgorithmES256, signingKey)
+	require.NoError(t, err)
+
+	message := cose.NewSign1Message()
+	message.Headers.Protected.SetAlgorithm(cose.AlgorithmES256)
+	message.Payload = encodeTagged(t, cbor.RawMessage(mso))
+
+	if len(certificateChain) > 0 {
+		x5chain := make([]interface{}, len(certificateChain))
+		for j := range certificateChain {
+			x5chain[j] = certificateChain[j]
+		}
+
+		message.Headers.Unprotected[cose.HeaderLabelX5Chain] = x5chain
+	}
+
+	require.NoError(t, message.Sign(rand.Reader, nil, signer))
+
+	var issuerAuth []byte
+
+	if params.taggedIssuerAuth {
+		issuerAuth, err = message.MarshalCBOR()
+	} else {
+		issuerAuth, err = (*cose.UntaggedSign1Message)(message).MarshalCBOR()
+	}
+
+	require.NoError(t, err)
+
+	return issuerAuth
+}
+
+// encodeTagged encodes the given value and wraps it in a bstr with the encoded CBOR data tag (24).
+func encodeTagged(t *testing.T, value interface{}) []byte {
+	t.Helper()
+
+	encodedValue, err := cbor.Marshal(value)
+	require.NoError(t, err)
+
+	encodedTag, err := cbor.Marshal(cbor.Tag{Number: 24, Content: encodedValue})
+	require.NoError(t, err)
+
+	return encodedTag
+}
@@ -0,0 +1,34 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mdoc
+
+type options struct {
+	disabledProofCheck bool
+}
+
+// Opt is an option for the Parse, ParseCredential and FromCredential functions.
+type Opt func(opts *options)
+
+// WithDisabledProofCheck disables the verification of the issuer's signature, certificate chain, value digests and
+// validity period.
+func WithDisabledProofCheck() Opt {
+	return func(opts *options) {
+		opts.disabledProofCheck = true
+	}
+}
+
+func processOpts(opts []Opt) *options {
+	processedOpts := &options{}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(processedOpts)
+		}
+	}
+
+	return processedOpts
+}
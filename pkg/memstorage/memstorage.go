@@ -17,11 +17,15 @@ import (
 // A Provider allows for credential storage and retrieval using in-memory storage only.
 type Provider struct {
 	credentialStore map[string]verifiable.Credential
+	batches         map[string][]verifiable.Credential
 }
 
 // NewProvider returns a new Provider.
 func NewProvider() *Provider {
-	return &Provider{credentialStore: map[string]verifiable.Credential{}}
+	return &Provider{
+		credentialStore: map[string]verifiable.Credential{},
+		batches:         map[string][]verifiable.Credential{},
+	}
 }
 
 // Get returns a credential with the given id. An error is returned if no credential exists with the given id.
@@ -65,3 +69,42 @@ func (p *Provider) Remove(id string) error {
 
 	return nil
 }
+
+// AddBatch stores the given copies of a credential (e.g. from openid4ci.CredentialBatch) as a group with the given
+// batch ID. Since copies of a credential may share the same id, they're kept separately from the credentials stored
+// using Add. If a batch with the given ID already exists, then the copies are added to it.
+func (p *Provider) AddBatch(batchID string, vcs []*verifiable.Credential) error {
+	for _, vc := range vcs {
+		if vc == nil {
+			return errors.New("VC cannot be nil")
+		}
+
+		p.batches[batchID] = append(p.batches[batchID], *vc)
+	}
+
+	return nil
+}
+
+// TakeFromBatch returns one of the copies in the batch with the given ID and removes it from the batch, so that each
+// copy is only presented once. An error is returned if there are no copies left in the batch.
+func (p *Provider) TakeFromBatch(batchID string) (*verifiable.Credential, error) {
+	batch := p.batches[batchID]
+	if len(batch) == 0 {
+		return nil, fmt.Errorf("no credentials left in the batch with an id of %s", batchID)
+	}
+
+	credential := batch[0]
+
+	if len(batch) == 1 {
+		delete(p.batches, batchID)
+	} else {
+		p.batches[batchID] = batch[1:]
+	}
+
+	return &credential, nil
+}
+
+// BatchSize returns the number of copies left in the batch with the given ID.
+func (p *Provider) BatchSize(batchID string) int {
+	return len(p.batches[batchID])
+}
@@ -71,3 +71,47 @@ func TestProvider(t *testing.T) {
 	err = provider.Add(nil)
 	require.EqualError(t, err, "VC cannot be nil")
 }
+
+func TestProvider_Batches(t *testing.T) {
+	provider := memstorage.NewProvider()
+
+	vcCopy1, err := verifiable.CreateCredential(verifiable.CredentialContents{
+		ID:     "VC",
+		Issuer: &verifiable.Issuer{ID: "1"},
+	}, nil)
+	require.NoError(t, err)
+
+	vcCopy2, err := verifiable.CreateCredential(verifiable.CredentialContents{
+		ID:     "VC",
+		Issuer: &verifiable.Issuer{ID: "2"},
+	}, nil)
+	require.NoError(t, err)
+
+	err = provider.AddBatch("batch", []*verifiable.Credential{vcCopy1, vcCopy2})
+	require.NoError(t, err)
+	require.Equal(t, 2, provider.BatchSize("batch"))
+
+	// Batches are kept separate from individually stored credentials.
+	retrievedVCs, err := provider.GetAll()
+	require.NoError(t, err)
+	require.Empty(t, retrievedVCs)
+
+	// Each copy can only be taken once.
+	retrievedVC, err := provider.TakeFromBatch("batch")
+	require.NoError(t, err)
+	require.Equal(t, "1", retrievedVC.Contents().Issuer.ID)
+	require.Equal(t, 1, provider.BatchSize("batch"))
+
+	retrievedVC, err = provider.TakeFromBatch("batch")
+	require.NoError(t, err)
+	require.Equal(t, "2", retrievedVC.Contents().Issuer.ID)
+	require.Equal(t, 0, provider.BatchSize("batch"))
+
+	retrievedVC, err = provider.TakeFromBatch("batch")
+	require.EqualError(t, err, "no credentials left in the batch with an id of batch")
+	require.Nil(t, retrievedVC)
+
+	// Attempt to store a nil VC
+	err = provider.AddBatch("batch", []*verifiable.Credential{nil})
+	require.EqualError(t, err, "VC cannot be nil")
+}
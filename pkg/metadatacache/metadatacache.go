@@ -0,0 +1,293 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package metadatacache contains a cache for metadata documents (such as issuer metadata and authorization server
+// metadata) that can be shared across interactions. It honours the Cache-Control and ETag headers of the responses
+// the documents came from, and stores its entries in a pluggable Store so that they can be persisted.
+package metadatacache
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store is a storage mechanism for cache entries. Implementations may persist entries (e.g. to disk) so that they
+// survive app restarts. Implementations must be safe for concurrent use.
+type Store interface {
+	// Get returns the value stored under the given key. nil (and no error) must be returned if there isn't one.
+	Get(key string) ([]byte, error)
+	// Put stores the given value under the given key, replacing any existing value.
+	Put(key string, value []byte) error
+	// Delete deletes the value stored under the given key. No error is returned if there isn't one.
+	Delete(key string) error
+}
+
+// Entry is a cached metadata document.
+type Entry struct {
+	Document []byte `json:"document"`
+	// The ETag of the response the document came from, which is used to revalidate the document once it's stale.
+	ETag string `json:"etag,omitempty"`
+	// The time after which the document is stale and must be revalidated (or fetched again) before being used.
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// A Cache caches metadata documents by URL. A Cache is safe for concurrent use, and is intended to be shared.
+type Cache struct {
+	store      Store
+	defaultTTL time.Duration
+	now        func() time.Time
+}
+
+// Opt represents an option for the New function.
+type Opt func(cache *Cache)
+
+// DefaultMaxMemoryEntries is the maximum number of entries kept by the MemoryStore that a Cache uses if no other Store
+// is specified. This bounds the memory used by the Default cache, which is shared by the whole process.
+const DefaultMaxMemoryEntries = 100
+
+// WithStore is an option for the New function that allows a caller to specify the Store that the cache's entries
+// are kept in. If this option isn't used, then entries are only kept in memory, in a MemoryStore that holds up to
+// DefaultMaxMemoryEntries entries.
+func WithStore(store Store) Opt {
+	return func(cache *Cache) {
+		cache.store = store
+	}
+}
+
+// WithDefaultTTL is an option for the New function that allows a caller to specify how long a document stays fresh
+// if the response it came from doesn't say so itself (using the max-age Cache-Control directive). If this option
+// isn't used, then such documents are always revalidated before being used again (if they have an ETag), or are
+// not cached at all (if they don't).
+func WithDefaultTTL(ttl time.Duration) Opt {
+	return func(cache *Cache) {
+		cache.defaultTTL = ttl
+	}
+}
+
+// New returns a new Cache.
+func New(opts ...Opt) *Cache {
+	cache := &Cache{now: time.Now}
+
+	for _, opt := range opts {
+		opt(cache)
+	}
+
+	if cache.store == nil {
+		cache.store = NewBoundedMemoryStore(DefaultMaxMemoryEntries)
+	}
+
+	return cache
+}
+
+//nolint:gochecknoglobals
+var defaultCache = New()
+
+// Default returns the cache that's used when no other cache is specified. It keeps up to DefaultMaxMemoryEntries
+// entries in memory (evicting the least recently used ones), and only caches documents for as long as the responses
+// they came from allow.
+func Default() *Cache {
+	return defaultCache
+}
+
+// Get returns the cached entry for the document at the given URL, or nil if there isn't one. The returned entry may
+// be stale. Use the Fresh method to check.
+func (c *Cache) Get(documentURL string) (*Entry, error) {
+	entryBytes, err := c.store.Get(documentURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata cache entry: %w", err)
+	}
+
+	if entryBytes == nil {
+		return nil, nil
+	}
+
+	var entry Entry
+
+	err = json.Unmarshal(entryBytes, &entry)
+	if err != nil {
+		// A corrupted entry is treated as a cache miss so that it gets replaced.
+		return nil, nil //nolint:nilerr
+	}
+
+	return &entry, nil
+}
+
+// Fresh indicates whether the given entry can still be used without being revalidated.
+func (c *Cache) Fresh(entry *Entry) bool {
+	return c.now().Before(entry.ExpiresAt)
+}
+
+// Put caches the given document, which was just fetched from (or revalidated with) the given URL. The headers of the
+// response determine how long it stays fresh. If they don't allow the document to be cached (using the no-store
+// Cache-Control directive), then any existing entry for the URL is deleted instead. When revalidating a document, the
+// cached document should be passed in along with the headers of the 304 Not Modified response.
+func (c *Cache) Put(documentURL string, document []byte, header http.Header) error {
+	directives := parseCacheControl(header.Get("Cache-Control"))
+
+	if _, noStore := directives["no-store"]; noStore {
+		return c.delete(documentURL)
+	}
+
+	entry := &Entry{
+		Document:  document,
+		ETag:      header.Get("ETag"),
+		ExpiresAt: c.now().Add(c.freshnessLifetime(directives)),
+	}
+
+	// An entry that's already stale and can't be revalidated would never be used.
+	if entry.ETag == "" && !c.Fresh(entry) {
+		return c.delete(documentURL)
+	}
+
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	err = c.store.Put(documentURL, entryBytes)
+	if err != nil {
+		return fmt.Errorf("failed to put metadata cache entry: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Cache) delete(documentURL string) error {
+	err := c.store.Delete(documentURL)
+	if err != nil {
+		return fmt.Errorf("failed to delete metadata cache entry: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Cache) freshnessLifetime(directives map[string]string) time.Duration {
+	if _, noCache := directives["no-cache"]; noCache {
+		return 0
+	}
+
+	if maxAge, exists := directives["max-age"]; exists {
+		seconds, err := strconv.Atoi(maxAge)
+		if err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+
+		return 0
+	}
+
+	return c.defaultTTL
+}
+
+func parseCacheControl(cacheControl string) map[string]string {
+	directives := map[string]string{}
+
+	for _, directive := range strings.Split(cacheControl, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if name != "" {
+			directives[strings.ToLower(name)] = strings.Trim(value, `"`)
+		}
+	}
+
+	return directives
+}
+
+// MemoryStore is a Store that keeps entries in memory only. It can optionally be limited to a maximum number of
+// entries, in which case the least recently used entry is evicted to make room for a new one.
+type MemoryStore struct {
+	maxEntries int
+	entries    map[string]*list.Element
+	// Ordered from most to least recently used.
+	recentlyUsed *list.List
+	mutex        sync.Mutex
+}
+
+type memoryStoreEntry struct {
+	key   string
+	value []byte
+}
+
+// NewMemoryStore returns a new MemoryStore with no limit on the number of entries.
+func NewMemoryStore() *MemoryStore {
+	return NewBoundedMemoryStore(0)
+}
+
+// NewBoundedMemoryStore returns a new MemoryStore that holds up to maxEntries entries. If maxEntries is zero or
+// negative, then there's no limit.
+func NewBoundedMemoryStore(maxEntries int) *MemoryStore {
+	return &MemoryStore{
+		maxEntries:   maxEntries,
+		entries:      map[string]*list.Element{},
+		recentlyUsed: list.New(),
+	}
+}
+
+// Get returns the value stored under the given key, or nil if there isn't one.
+func (m *MemoryStore) Get(key string) ([]byte, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	element, exists := m.entries[key]
+	if !exists {
+		return nil, nil
+	}
+
+	m.recentlyUsed.MoveToFront(element)
+
+	return element.Value.(*memoryStoreEntry).value, nil //nolint:forcetypeassert // only entries are stored
+}
+
+// Put stores the given value under the given key. If the store is full, then the least recently used entry is
+// evicted.
+func (m *MemoryStore) Put(key string, value []byte) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if element, exists := m.entries[key]; exists {
+		element.Value.(*memoryStoreEntry).value = value //nolint:forcetypeassert // only entries are stored
+		m.recentlyUsed.MoveToFront(element)
+
+		return nil
+	}
+
+	m.entries[key] = m.recentlyUsed.PushFront(&memoryStoreEntry{key: key, value: value})
+
+	if m.maxEntries > 0 && m.recentlyUsed.Len() > m.maxEntries {
+		m.remove(m.recentlyUsed.Back())
+	}
+
+	return nil
+}
+
+// Delete deletes the value stored under the given key.
+func (m *MemoryStore) Delete(key string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if element, exists := m.entries[key]; exists {
+		m.remove(element)
+	}
+
+	return nil
+}
+
+// Len returns the number of entries in the store.
+func (m *MemoryStore) Len() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.recentlyUsed.Len()
+}
+
+func (m *MemoryStore) remove(element *list.Element) {
+	m.recentlyUsed.Remove(element)
+	delete(m.entries, element.Value.(*memoryStoreEntry).key) //nolint:forcetypeassert // only entries are stored
+}
@@ -0,0 +1,191 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package metadatacache_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/pkg/metadatacache"
+)
+
+const documentURL = "https://issuer.example.com/.well-known/openid-credential-issuer"
+
+type failingStore struct{}
+
+func (f *failingStore) Get(string) ([]byte, error) {
+	return nil, errors.New("get failed")
+}
+
+func (f *failingStore) Put(string, []byte) error {
+	return errors.New("put failed")
+}
+
+func (f *failingStore) Delete(string) error {
+	return errors.New("delete failed")
+}
+
+func TestCache(t *testing.T) {
+	document := []byte(`{"credential_issuer":"https://issuer.example.com"}`)
+
+	t.Run("max-age", func(t *testing.T) {
+		cache := metadatacache.New()
+
+		err := cache.Put(documentURL, document, http.Header{"Cache-Control": []string{"public, max-age=3600"}})
+		require.NoError(t, err)
+
+		entry, err := cache.Get(documentURL)
+		require.NoError(t, err)
+		require.Equal(t, document, entry.Document)
+		require.True(t, cache.Fresh(entry))
+	})
+	t.Run("ETag with no-cache", func(t *testing.T) {
+		cache := metadatacache.New(metadatacache.WithDefaultTTL(time.Hour))
+
+		err := cache.Put(documentURL, document, http.Header{
+			"Cache-Control": []string{"no-cache"},
+			"Etag":          []string{`"v1"`},
+		})
+		require.NoError(t, err)
+
+		entry, err := cache.Get(documentURL)
+		require.NoError(t, err)
+		require.Equal(t, `"v1"`, entry.ETag)
+		require.False(t, cache.Fresh(entry))
+	})
+	t.Run("Default TTL", func(t *testing.T) {
+		cache := metadatacache.New(metadatacache.WithDefaultTTL(time.Hour))
+
+		err := cache.Put(documentURL, document, http.Header{})
+		require.NoError(t, err)
+
+		entry, err := cache.Get(documentURL)
+		require.NoError(t, err)
+		require.True(t, cache.Fresh(entry))
+	})
+	t.Run("No caching headers and no default TTL", func(t *testing.T) {
+		cache := metadatacache.New()
+
+		err := cache.Put(documentURL, document, http.Header{})
+		require.NoError(t, err)
+
+		entry, err := cache.Get(documentURL)
+		require.NoError(t, err)
+		require.Nil(t, entry)
+	})
+	t.Run("no-store replaces an existing entry", func(t *testing.T) {
+		store := metadatacache.NewMemoryStore()
+		cache := metadatacache.New(metadatacache.WithStore(store), metadatacache.WithDefaultTTL(time.Hour))
+
+		require.NoError(t, cache.Put(documentURL, document, http.Header{}))
+		require.NoError(t, cache.Put(documentURL, document, http.Header{"Cache-Control": []string{"no-store"}}))
+
+		value, err := store.Get(documentURL)
+		require.NoError(t, err)
+		require.Nil(t, value)
+	})
+	t.Run("Invalid max-age", func(t *testing.T) {
+		cache := metadatacache.New(metadatacache.WithDefaultTTL(time.Hour))
+
+		err := cache.Put(documentURL, document, http.Header{
+			"Cache-Control": []string{"max-age=soon"},
+			"Etag":          []string{`"v1"`},
+		})
+		require.NoError(t, err)
+
+		entry, err := cache.Get(documentURL)
+		require.NoError(t, err)
+		require.False(t, cache.Fresh(entry))
+	})
+	t.Run("Corrupted entry", func(t *testing.T) {
+		store := metadatacache.NewMemoryStore()
+		require.NoError(t, store.Put(documentURL, []byte("invalid")))
+
+		entry, err := metadatacache.New(metadatacache.WithStore(store)).Get(documentURL)
+		require.NoError(t, err)
+		require.Nil(t, entry)
+	})
+	t.Run("Store errors", func(t *testing.T) {
+		cache := metadatacache.New(metadatacache.WithStore(&failingStore{}))
+
+		entry, err := cache.Get(documentURL)
+		require.EqualError(t, err, "failed to get metadata cache entry: get failed")
+		require.Nil(t, entry)
+
+		err = cache.Put(documentURL, document, http.Header{"Cache-Control": []string{"max-age=60"}})
+		require.EqualError(t, err, "failed to put metadata cache entry: put failed")
+
+		err = cache.Put(documentURL, document, http.Header{"Cache-Control": []string{"no-store"}})
+		require.EqualError(t, err, "failed to delete metadata cache entry: delete failed")
+	})
+	t.Run("Default cache", func(t *testing.T) {
+		require.Same(t, metadatacache.Default(), metadatacache.Default())
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	t.Run("Least recently used entry is evicted", func(t *testing.T) {
+		store := metadatacache.NewBoundedMemoryStore(2)
+
+		require.NoError(t, store.Put("a", []byte("1")))
+		require.NoError(t, store.Put("b", []byte("2")))
+
+		// Using "a" makes "b" the least recently used entry.
+		value, err := store.Get("a")
+		require.NoError(t, err)
+		require.Equal(t, []byte("1"), value)
+
+		require.NoError(t, store.Put("c", []byte("3")))
+		require.Equal(t, 2, store.Len())
+
+		value, err = store.Get("b")
+		require.NoError(t, err)
+		require.Nil(t, value)
+
+		value, err = store.Get("a")
+		require.NoError(t, err)
+		require.Equal(t, []byte("1"), value)
+
+		value, err = store.Get("c")
+		require.NoError(t, err)
+		require.Equal(t, []byte("3"), value)
+	})
+	t.Run("Replacing an entry doesn't evict another one", func(t *testing.T) {
+		store := metadatacache.NewBoundedMemoryStore(2)
+
+		require.NoError(t, store.Put("a", []byte("1")))
+		require.NoError(t, store.Put("b", []byte("2")))
+		require.NoError(t, store.Put("a", []byte("3")))
+		require.Equal(t, 2, store.Len())
+
+		value, err := store.Get("a")
+		require.NoError(t, err)
+		require.Equal(t, []byte("3"), value)
+	})
+	t.Run("Delete", func(t *testing.T) {
+		store := metadatacache.NewBoundedMemoryStore(2)
+
+		require.NoError(t, store.Put("a", []byte("1")))
+		require.NoError(t, store.Delete("a"))
+		require.NoError(t, store.Delete("a"))
+		require.Zero(t, store.Len())
+	})
+	t.Run("Unbounded", func(t *testing.T) {
+		store := metadatacache.NewMemoryStore()
+
+		for index := range metadatacache.DefaultMaxMemoryEntries + 1 {
+			require.NoError(t, store.Put(fmt.Sprint(index), []byte("value")))
+		}
+
+		require.Equal(t, metadatacache.DefaultMaxMemoryEntries+1, store.Len())
+	})
+}
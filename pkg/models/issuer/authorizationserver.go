@@ -0,0 +1,89 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package issuer
+
+// AuthorizationServerMetadata represents metadata about an OAuth 2.0 authorization server, as defined in RFC 8414.
+// Only the parameters that are relevant to OpenID4VCI are included.
+type AuthorizationServerMetadata struct {
+	// The authorization server's issuer identifier. It must be identical to the identifier used to fetch the
+	// metadata.
+	Issuer string `json:"issuer,omitempty"`
+
+	// URL of the authorization server's authorization endpoint.
+	AuthorizationEndpoint string `json:"authorization_endpoint,omitempty"`
+
+	// URL of the authorization server's token endpoint.
+	TokenEndpoint string `json:"token_endpoint,omitempty"`
+
+	// URL of the authorization server's Pushed Authorization Request Endpoint (RFC 9126).
+	PushedAuthorizationRequestEndpoint string `json:"pushed_authorization_request_endpoint,omitempty"`
+
+	// URL of the authorization server's Dynamic Client Registration Endpoint.
+	RegistrationEndpoint *string `json:"registration_endpoint,omitempty"`
+
+	// JSON array containing a list of the OAuth 2.0 grant type values that this authorization server supports.
+	GrantTypesSupported []string `json:"grant_types_supported,omitempty"`
+
+	// Boolean indicating whether the authorization server accepts a Token Request with a Pre-Authorized Code but
+	// without a client id.
+	PreAuthorizedGrantAnonymousAccessSupported *bool `json:"pre-authorized_grant_anonymous_access_supported,omitempty"`
+
+	// JSON array containing a list of the JWS alg values supported by the authorization server for DPoP proof JWTs.
+	DPoPSigningAlgValuesSupported []string `json:"dpop_signing_alg_values_supported,omitempty"`
+
+	// JSON array containing a list of the OAuth 2.0 response_type values that this authorization server supports.
+	ResponseTypesSupported []string `json:"response_types_supported,omitempty"`
+
+	// JSON array containing a list of the OAuth 2.0 scope values that this authorization server supports.
+	ScopesSupported []string `json:"scopes_supported,omitempty"`
+
+	// JSON array containing a list of client authentication methods supported by this token endpoint.
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported,omitempty"`
+}
+
+// MergeAuthorizationServerMetadata merges the given authorization server metadata into this issuer metadata.
+// Older issuers include authorization server parameters (like the token endpoint) directly in their issuer metadata.
+// Merging allows the rest of Wallet-SDK to find them in the same place regardless of where they were published.
+// Parameters set in the authorization server metadata take precedence, since it's the authoritative source for them.
+func (m *Metadata) MergeAuthorizationServerMetadata(asMetadata *AuthorizationServerMetadata) {
+	m.AuthorizationServer = mergeString(m.AuthorizationServer, asMetadata.AuthorizationEndpoint)
+	m.TokenEndpoint = mergeString(m.TokenEndpoint, asMetadata.TokenEndpoint)
+	m.PushedAuthorizationRequestEndpoint = mergeString(m.PushedAuthorizationRequestEndpoint,
+		asMetadata.PushedAuthorizationRequestEndpoint)
+
+	if asMetadata.RegistrationEndpoint != nil {
+		m.RegistrationEndpoint = asMetadata.RegistrationEndpoint
+	}
+
+	if asMetadata.PreAuthorizedGrantAnonymousAccessSupported != nil {
+		m.PreAuthorizedGrantAnonymousAccessSupported = asMetadata.PreAuthorizedGrantAnonymousAccessSupported
+	}
+
+	m.GrantTypesSupported = mergeStrings(m.GrantTypesSupported, asMetadata.GrantTypesSupported)
+	m.DPoPSigningAlgValuesSupported = mergeStrings(m.DPoPSigningAlgValuesSupported,
+		asMetadata.DPoPSigningAlgValuesSupported)
+	m.ResponseTypesSupported = mergeStrings(m.ResponseTypesSupported, asMetadata.ResponseTypesSupported)
+	m.ScopesSupported = mergeStrings(m.ScopesSupported, asMetadata.ScopesSupported)
+	m.TokenEndpointAuthMethodsSupported = mergeStrings(m.TokenEndpointAuthMethodsSupported,
+		asMetadata.TokenEndpointAuthMethodsSupported)
+}
+
+func mergeString(issuerValue, asValue string) string {
+	if asValue != "" {
+		return asValue
+	}
+
+	return issuerValue
+}
+
+func mergeStrings(issuerValues, asValues []string) []string {
+	if len(asValues) > 0 {
+		return asValues
+	}
+
+	return issuerValues
+}
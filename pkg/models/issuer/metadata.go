@@ -8,19 +8,34 @@ SPDX-License-Identifier: Apache-2.0
 package issuer
 
 import (
+	"crypto/x509"
+	"encoding/json"
 	"errors"
 )
 
+const msoMdocFormat = "mso_mdoc"
+
 // CredentialConfigurationID is an alias for type "string" introduced to simplify understanding of the specification.
 type CredentialConfigurationID = string
 
 // Metadata represents metadata about an issuer as obtained from their .well-known OpenID configuration.
 type Metadata struct {
-	jwtKID *string
+	jwtKID                  *string
+	signingCertificateChain []*x509.Certificate
 
 	// URL of the OP's OAuth 2.0 Authorization Endpoint.
 	AuthorizationServer string `json:"authorization_endpoint,omitempty"`
 
+	// Array of strings, where each string is an identifier of the OAuth 2.0 Authorization Server (as defined in
+	// RFC 8414) the Credential Issuer relies on for authorization. If omitted, the entity providing the Credential
+	// Issuer is also acting as the Authorization Server.
+	AuthorizationServers []string `json:"authorization_servers,omitempty"`
+
+	// Object containing information about the Credential Issuer's support for issuing multiple copies of a Credential,
+	// each bound to a different key, in response to a single Credential Request. If omitted, the Credential Issuer
+	// does not support batch issuance.
+	BatchCredentialIssuance *BatchCredentialIssuance `json:"batch_credential_issuance,omitempty"`
+
 	// URL of the Credential Issuer's Batch Credential Endpoint. This URL MUST use the https scheme and MAY contain
 	// port, path and query parameter components.
 	// If omitted, the Credential Issuer does not support the Batch Credential Endpoint.
@@ -52,12 +67,20 @@ type Metadata struct {
 	// If omitted, the Credential Issuer does not support the Deferred Credential Endpoint.
 	DeferredCredentialEndpoint string `json:"deferred_credential_endpoint,omitempty"`
 
+	// JSON array containing a list of the JWS alg values supported by the authorization server for DPoP proof JWTs.
+	// If present, then the wallet will use DPoP (RFC 9449) to bind its access tokens to its signing key.
+	DPoPSigningAlgValuesSupported []string `json:"dpop_signing_alg_values_supported,omitempty"`
+
 	// An array of objects, where each object contains display properties of a Credential Issuer for a certain language.
 	LocalizedIssuerDisplays []LocalizedIssuerDisplay `json:"display,omitempty"`
 
 	// JSON array containing a list of the OAuth 2.0 Grant Type values that this OP supports.
 	GrantTypesSupported []string `json:"grant_types_supported,omitempty"`
 
+	// URL of the Credential Issuer's Nonce Endpoint, from which a c_nonce value can be obtained. Only used by issuers
+	// that implement draft 15 or later of the OpenID4VCI specification.
+	NonceEndpoint string `json:"nonce_endpoint,omitempty"`
+
 	// URL of the Credential Issuer's Notification Endpoint. This URL MUST use the https scheme and MAY contain
 	// port, path, and query parameter components.
 	// If omitted, the Credential Issuer does not support the Notification Endpoint.
@@ -67,6 +90,11 @@ type Metadata struct {
 	// The default is false.
 	PreAuthorizedGrantAnonymousAccessSupported *bool `json:"pre-authorized_grant_anonymous_access_supported,omitempty"`
 
+	// URL of the authorization server's Pushed Authorization Request Endpoint (RFC 9126).
+	// If present, then authorization requests are pushed to this endpoint instead of being sent in the
+	// authorization URL.
+	PushedAuthorizationRequestEndpoint string `json:"pushed_authorization_request_endpoint,omitempty"`
+
 	// URL of the OP's Dynamic Client Registration Endpoint.
 	RegistrationEndpoint *string `json:"registration_endpoint,omitempty"`
 
@@ -141,6 +169,76 @@ func (c *CredentialConfigurationSupported) ClaimOrderAsInt(claimName string) (in
 	return -1, errors.New("order is not specified")
 }
 
+// CredentialTypes returns the types of the credentials issued using this configuration.
+// SD-JWT VC and mdoc configurations identify their credential type using vct or doctype instead of a credential
+// definition. For those, the types are VerifiableCredential followed by the vct or doctype value, which is how
+// SD-JWT VCs and mdocs are represented once parsed.
+func (c *CredentialConfigurationSupported) CredentialTypes() []string {
+	if c.CredentialDefinition != nil {
+		return c.CredentialDefinition.Type
+	}
+
+	if c.Vct != "" {
+		return []string{"VerifiableCredential", c.Vct}
+	}
+
+	if c.Doctype != "" {
+		return []string{"VerifiableCredential", c.Doctype}
+	}
+
+	return nil
+}
+
+// CredentialSubjectClaims returns the display information for the claims in the credentials issued using this
+// configuration. For SD-JWT VC configurations, this comes from the top-level claims object. For mdoc configurations,
+// the claims object groups the data elements by namespace, so the elements from all namespaces are returned.
+func (c *CredentialConfigurationSupported) CredentialSubjectClaims() map[string]*Claim {
+	if c.CredentialDefinition != nil {
+		return c.CredentialDefinition.CredentialSubject
+	}
+
+	if c.Claims == nil {
+		return nil
+	}
+
+	claimsBytes, err := json.Marshal(c.Claims)
+	if err != nil {
+		return nil
+	}
+
+	if c.Format == msoMdocFormat {
+		return mdocElementClaims(claimsBytes)
+	}
+
+	var claims map[string]*Claim
+
+	err = json.Unmarshal(claimsBytes, &claims)
+	if err != nil {
+		return nil
+	}
+
+	return claims
+}
+
+func mdocElementClaims(claimsBytes []byte) map[string]*Claim {
+	var claimsByNameSpace map[string]map[string]*Claim
+
+	err := json.Unmarshal(claimsBytes, &claimsByNameSpace)
+	if err != nil {
+		return nil
+	}
+
+	claims := map[string]*Claim{}
+
+	for _, nameSpaceClaims := range claimsByNameSpace {
+		for elementIdentifier, claim := range nameSpaceClaims {
+			claims[elementIdentifier] = claim
+		}
+	}
+
+	return claims
+}
+
 // CredentialDefinition containing the detailed description of the credential type.
 type CredentialDefinition struct {
 	// For ldp_vc only. Array as defined in https://www.w3.org/TR/vc-data-model/#contexts.
@@ -177,6 +275,25 @@ type CredentialResponseEncryptionSupported struct {
 type ProofTypeSupported struct {
 	// Array of case-sensitive strings that identify the algorithms that the Issuer supports for this proof type.
 	ProofSigningAlgValuesSupported []string `json:"proof_signing_alg_values_supported"`
+	// If present, the Issuer requires proofs of this type to include a key attestation that meets these requirements.
+	KeyAttestationsRequired *KeyAttestationsRequired `json:"key_attestations_required,omitempty"`
+}
+
+// BatchCredentialIssuance contains information about the Credential Issuer's support for batch issuance.
+type BatchCredentialIssuance struct {
+	// The maximum number of Credentials that can be issued in response to a single Credential Request.
+	BatchSize int `json:"batch_size"`
+}
+
+// KeyAttestationsRequired contains the Issuer's requirements for the key attestations included in key proofs.
+// If a list is empty, then the Issuer accepts any level for it.
+type KeyAttestationsRequired struct {
+	// Array of case-sensitive strings that identify the accepted attack potential resistance levels of the storage
+	// that the key is held in, e.g. iso_18045_high.
+	KeyStorage []string `json:"key_storage,omitempty"`
+	// Array of case-sensitive strings that identify the accepted attack potential resistance levels of the user
+	// authentication that protects the key.
+	UserAuthentication []string `json:"user_authentication,omitempty"`
 }
 
 // GetJWTKID returns the jwtKID field. This is exposed via this method instead of with an exported field because
@@ -191,6 +308,17 @@ func (m *Metadata) SetJWTKID(jwtKID string) {
 	m.jwtKID = &jwtKID
 }
 
+// GetSigningCertificateChain returns the X.509 certificate chain that the signed metadata was verified with, starting
+// with the signing certificate. It's nil if the metadata wasn't signed using an x5c certificate chain.
+func (m *Metadata) GetSigningCertificateChain() []*x509.Certificate {
+	return m.signingCertificateChain
+}
+
+// SetSigningCertificateChain sets the signingCertificateChain field.
+func (m *Metadata) SetSigningCertificateChain(chain []*x509.Certificate) {
+	m.signingCertificateChain = chain
+}
+
 // LocalizedCredentialDisplay represents display data for a credential as a whole for a certain locale.
 // Display data for specific claims (e.g. first name, date of birth, etc.) are in SupportedCredential.CredentialSubject
 // (in the parent object above).
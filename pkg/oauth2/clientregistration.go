@@ -28,6 +28,13 @@ const (
 // If the server requires an initial access token, then use the WithInitialAccessBearerToken option.
 func RegisterClient(registrationEndpoint string, clientMetadata *ClientMetadata,
 	opts ...Opt,
+) (*RegisterClientResponse, error) {
+	return RegisterClientContext(context.Background(), registrationEndpoint, clientMetadata, opts...)
+}
+
+// RegisterClientContext is the same as RegisterClient, but sends the registration request using the given context.
+func RegisterClientContext(ctx context.Context, registrationEndpoint string, clientMetadata *ClientMetadata,
+	opts ...Opt,
 ) (*RegisterClientResponse, error) {
 	if registrationEndpoint == "" {
 		return nil, errors.New("registration endpoint cannot be blank")
@@ -46,7 +53,7 @@ func RegisterClient(registrationEndpoint string, clientMetadata *ClientMetadata,
 		return nil, err
 	}
 
-	respBody, err := getRawResponse(clientMetadataBytes, registrationEndpoint, processedOpts)
+	respBody, err := getRawResponse(ctx, clientMetadataBytes, registrationEndpoint, processedOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -61,7 +68,7 @@ func RegisterClient(registrationEndpoint string, clientMetadata *ClientMetadata,
 	return response, nil
 }
 
-func getRawResponse(requestBytes []byte, registrationEndpoint string, opts *opts) ([]byte, error) {
+func getRawResponse(ctx context.Context, requestBytes []byte, registrationEndpoint string, opts *opts) ([]byte, error) {
 	headers := http.Header{}
 	if opts.initialAccessBearerToken != "" {
 		headers.Set("Authorization", "Bearer "+opts.initialAccessBearerToken)
@@ -69,7 +76,7 @@ func getRawResponse(requestBytes []byte, registrationEndpoint string, opts *opts
 
 	metricsEvent := fmt.Sprintf(fetchRequestObjectEventText, registrationEndpoint)
 
-	return httprequest.New(opts.httpClient, opts.metricsLogger).DoContext(context.TODO(),
+	return httprequest.New(opts.httpClient, opts.metricsLogger).DoContext(ctx,
 		http.MethodPost, registrationEndpoint, "application/json", headers,
 		bytes.NewReader(requestBytes), metricsEvent, newRegisterClientEventText,
 		[]int{http.StatusCreated}, nil)
@@ -14,48 +14,140 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
+	"time"
 
 	"golang.org/x/oauth2"
+
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
 )
 
 // Acknowledgment represents an object that allows to acknowledge the issuer the user's accepted or rejected credential.
+// It can be serialized (e.g. with json.Marshal) and stored, so that the issuer can be notified much later about what
+// happened to a credential (e.g. when the user deletes it). Since it contains the access token (and possibly a refresh
+// token), it should be stored securely.
 type Acknowledgment struct {
-	AckIDs                []string               `json:"ack_ids,omitempty"`
-	CredentialAckEndpoint string                 `json:"credential_ack_endpoint,omitempty"`
-	IssuerURI             string                 `json:"issuer_uri,omitempty"`
-	AuthToken             *universalAuthToken    `json:"auth_token,omitempty"`
-	InteractionDetails    map[string]interface{} `json:"interaction_details,omitempty"`
+	AckIDs                []string `json:"ack_ids,omitempty"`
+	CredentialAckEndpoint string   `json:"credential_ack_endpoint,omitempty"`
+	IssuerURI             string   `json:"issuer_uri,omitempty"`
+	// TokenEndpoint and ClientID are used to refresh the access token if it has expired by the time a notification
+	// is sent. Refreshing is only possible if the issuer provided a refresh token.
+	TokenEndpoint      string                 `json:"token_endpoint,omitempty"`
+	ClientID           string                 `json:"client_id,omitempty"`
+	AuthToken          *AuthToken             `json:"auth_token,omitempty"`
+	InteractionDetails map[string]interface{} `json:"interaction_details,omitempty"`
+	// DPoPSigner is used to create DPoP proofs if the access token is DPoP-bound. It must be the same signer that
+	// was used to request the credentials. It isn't serialized, so it must be set again after deserialization.
+	DPoPSigner api.JWTSigner `json:"-"`
 }
 
 // AcknowledgeIssuer acknowledge issuer that client accepts or rejects credentials using first existing AckIDs.
+// The ID is only removed from AckIDs once the issuer has received the acknowledgment, so it can be retried if sending
+// it fails.
 func (a *Acknowledgment) AcknowledgeIssuer(
 	eventStatus EventStatus, httpClient *http.Client,
+) error {
+	return a.AcknowledgeIssuerContext(context.Background(), eventStatus, httpClient)
+}
+
+// AcknowledgeIssuerContext is the same as AcknowledgeIssuer, but sends the acknowledgment using the given context.
+func (a *Acknowledgment) AcknowledgeIssuerContext(ctx context.Context,
+	eventStatus EventStatus, httpClient *http.Client,
 ) error {
 	if len(a.AckIDs) == 0 {
 		return errors.New("ack list is empty")
 	}
 
-	// Pull first ackID
 	ackID := a.AckIDs[0]
 
-	// Reduce slice size
-	a.AckIDs = a.AckIDs[1:]
+	err := a.sendAcknowledge(ctx, ackID, eventStatus, nil, httpClient)
+	if err != nil {
+		return err
+	}
+
+	a.removeAckID(ackID)
+
+	return nil
+}
+
+// NotifyIssuer notifies the issuer about an event concerning the credential with the given notification ID (see
+// IssuerInitiatedInteraction.NotificationID). Unlike AcknowledgeIssuer, it can be called any number of times and at
+// any time, e.g. to send a credential_deleted event when the user deletes a credential long after it was issued.
+// The event must be one of the EventStatus constants. The event description is optional, and must only contain
+// printable ASCII characters other than '"' and '\'.
+// If the access token has expired, it's refreshed first (if possible), in which case this Acknowledgment should be
+// stored again afterwards.
+func (a *Acknowledgment) NotifyIssuer(notificationID string, event EventStatus, eventDescription string,
+	httpClient *http.Client,
+) error {
+	return a.NotifyIssuerContext(context.Background(), notificationID, event, eventDescription, httpClient)
+}
+
+// NotifyIssuerContext is the same as NotifyIssuer, but sends the notification using the given context.
+func (a *Acknowledgment) NotifyIssuerContext(ctx context.Context, notificationID string, event EventStatus,
+	eventDescription string, httpClient *http.Client,
+) error {
+	err := validateNotification(notificationID, event, eventDescription)
+	if err != nil {
+		return walleterror.NewInvalidSDKUsageError(ErrorModule, err)
+	}
+
+	var description *string
+
+	if eventDescription != "" {
+		description = &eventDescription
+	}
+
+	err = a.sendAcknowledge(ctx, notificationID, event, description, httpClient)
+	if err != nil {
+		return err
+	}
+
+	// Once the issuer has been notified about a credential individually, it mustn't be acknowledged again by
+	// AcknowledgeIssuer. If sending the notification failed, then it's kept so that it can be retried either way.
+	a.removeAckID(notificationID)
+
+	return nil
+}
+
+func (a *Acknowledgment) removeAckID(ackID string) {
+	a.AckIDs = slices.DeleteFunc(a.AckIDs, func(id string) bool { return id == ackID })
+}
+
+func validateNotification(notificationID string, event EventStatus, eventDescription string) error {
+	if notificationID == "" {
+		return errors.New("notification ID must be provided")
+	}
+
+	switch event {
+	case EventStatusCredentialAccepted, EventStatusCredentialFailure, EventStatusCredentialDeleted:
+	default:
+		return fmt.Errorf("unsupported notification event: %s", event)
+	}
 
-	return a.sendAcknowledge(ackID, eventStatus, httpClient)
+	for _, character := range eventDescription {
+		if character < 0x20 || character > 0x7E || character == '"' || character == '\\' {
+			return errors.New("event description must only contain printable ASCII characters other than " +
+				`'"' and '\'`)
+		}
+	}
+
+	return nil
 }
 
-func (a *Acknowledgment) sendAcknowledge(
-	ackID string, eventStatus EventStatus, httpClient *http.Client,
+func (a *Acknowledgment) sendAcknowledge(ctx context.Context,
+	ackID string, eventStatus EventStatus, eventDescription *string, httpClient *http.Client,
 ) error {
 	ackRequest := acknowledgementRequest{
 		Event:              eventStatus,
-		EventDescription:   nil,
+		EventDescription:   eventDescription,
 		IssuerIdentifier:   a.IssuerURI,
 		NotificationID:     ackID,
 		InteractionDetails: a.InteractionDetails,
 	}
 
-	err := a.sendAcknowledgeRequest(ackRequest, httpClient)
+	err := a.sendAcknowledgeRequest(ctx, ackRequest, httpClient)
 	if err != nil {
 		return fmt.Errorf("send acknowledge request id %s: %w", ackID, err)
 	}
@@ -63,7 +155,7 @@ func (a *Acknowledgment) sendAcknowledge(
 	return nil
 }
 
-func (a *Acknowledgment) sendAcknowledgeRequest(
+func (a *Acknowledgment) sendAcknowledgeRequest(ctx context.Context,
 	acknowledgementRequest acknowledgementRequest, httpClient *http.Client,
 ) error {
 	askEndpointURL := a.CredentialAckEndpoint
@@ -73,7 +165,7 @@ func (a *Acknowledgment) sendAcknowledgeRequest(
 		return fmt.Errorf("fail to marshal acknowledgementRequest: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(context.Background(),
+	req, err := http.NewRequestWithContext(ctx,
 		http.MethodPost, askEndpointURL, bytes.NewBuffer(requestBytes))
 	if err != nil {
 		return err
@@ -81,7 +173,23 @@ func (a *Acknowledgment) sendAcknowledgeRequest(
 
 	req.Header.Add("Content-Type", "application/json")
 
-	httpClient = createOAuthHTTPClient(&oauth2.Config{}, a.AuthToken, httpClient)
+	if a.AuthToken.isDPoPBound() {
+		if a.DPoPSigner == nil {
+			return errors.New("the access token is DPoP-bound, but no DPoP signer was set")
+		}
+
+		httpClient, err = newDPoPHTTPClient(httpClient, a.DPoPSigner, a.TokenEndpoint)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = a.refreshExpiredAccessToken(ctx, httpClient)
+	if err != nil {
+		return err
+	}
+
+	httpClient = createOAuthHTTPClient(ctx, &oauth2.Config{}, a.AuthToken, httpClient)
 
 	resp, err := httpClient.Do(req)
 	if err != nil {
@@ -106,3 +214,27 @@ func (a *Acknowledgment) sendAcknowledgeRequest(
 
 	return nil
 }
+
+// refreshExpiredAccessToken gets a new access token if the current one has expired and the issuer provided a refresh
+// token. Otherwise, the current access token is used as-is.
+func (a *Acknowledgment) refreshExpiredAccessToken(ctx context.Context, httpClient *http.Client) error {
+	if a.AuthToken == nil || a.AuthToken.RefreshToken == "" || a.TokenEndpoint == "" ||
+		a.AuthToken.ExpiresAt.IsZero() || time.Now().Before(a.AuthToken.ExpiresAt) {
+		return nil
+	}
+
+	token, err := refreshToken(ctx, refreshOAuth2Config(a.ClientID, a.TokenEndpoint), httpClient,
+		a.AuthToken.RefreshToken)
+	if err != nil {
+		return err
+	}
+
+	a.AuthToken = &AuthToken{
+		AccessToken:  token.AccessToken,
+		TokenType:    token.TokenType,
+		ExpiresAt:    token.Expiry,
+		RefreshToken: token.RefreshToken,
+	}
+
+	return nil
+}
@@ -0,0 +1,240 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
+)
+
+const secondCredentialConfigID = "credential_configuration_id_2"
+
+type receivedCredentialRequest struct {
+	CredentialIdentifier      string `json:"credential_identifier"`
+	CredentialConfigurationID string `json:"credential_configuration_id"`
+	Format                    string `json:"format"`
+	Vct                       string `json:"vct"`
+	Doctype                   string `json:"doctype"`
+	CredentialDefinition      *struct {
+		Type []string `json:"type"`
+	} `json:"credential_definition"`
+	Proof *struct {
+		ProofType string          `json:"proof_type"`
+		JWT       string          `json:"jwt"`
+		LdpVP     json.RawMessage `json:"ldp_vp"`
+		DiVP      json.RawMessage `json:"di_vp"`
+	} `json:"proof"`
+}
+
+// mockAuthorizationDetailsHandler returns the given authorization_details in token responses and records the
+// credential requests it receives. All other requests are passed on to the wrapped mockIssuerServerHandler.
+type mockAuthorizationDetailsHandler struct {
+	*mockIssuerServerHandler
+	grantedAuthorizationDetails string
+	credentialRequests          []*receivedCredentialRequest
+}
+
+func (m *mockAuthorizationDetailsHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	switch request.URL.Path {
+	case "/oidc/token":
+		tokenResponse := strings.TrimSuffix(sampleTokenResponse, "}")
+
+		if m.grantedAuthorizationDetails != "" {
+			tokenResponse += `,"authorization_details":` + m.grantedAuthorizationDetails
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+
+		_, err := writer.Write([]byte(tokenResponse + "}"))
+		assert.NoError(m.t, err)
+	case "/oidc/credential":
+		var credentialRequest receivedCredentialRequest
+
+		err := json.NewDecoder(request.Body).Decode(&credentialRequest)
+		assert.NoError(m.t, err)
+
+		m.credentialRequests = append(m.credentialRequests, &credentialRequest)
+
+		m.mockIssuerServerHandler.ServeHTTP(writer, request)
+	default:
+		m.mockIssuerServerHandler.ServeHTTP(writer, request)
+	}
+}
+
+// credentialRequestTypes returns the credential types from the legacy-style credential requests received so far.
+func (m *mockAuthorizationDetailsHandler) credentialRequestTypes() [][]string {
+	var credentialTypes [][]string
+
+	for _, credentialRequest := range m.credentialRequests {
+		if credentialRequest.CredentialDefinition != nil {
+			credentialTypes = append(credentialTypes, credentialRequest.CredentialDefinition.Type)
+		}
+	}
+
+	return credentialTypes
+}
+
+// newMultipleCredentialsServer returns a server for an issuer that supports two credential configurations, along
+// with an issuance URI for a credential offer that offers both of them.
+func newMultipleCredentialsServer(t *testing.T) (*mockAuthorizationDetailsHandler, *httptest.Server, string) {
+	t.Helper()
+
+	handler := &mockAuthorizationDetailsHandler{
+		mockIssuerServerHandler: &mockIssuerServerHandler{
+			t:                  t,
+			credentialResponse: sampleCredentialResponse,
+		},
+	}
+
+	server := httptest.NewServer(handler)
+
+	issuerMetadata := modifyCredentialMetadata(t, sampleIssuerMetadata, func(m *issuer.Metadata) {
+		firstConfig := m.CredentialConfigurationsSupported["credential_configuration_id_1"]
+
+		secondConfig := *firstConfig
+		secondConfig.CredentialDefinition = &issuer.CredentialDefinition{
+			Type: []string{"VerifiableCredential", "UniversityDegreeCredential"},
+		}
+
+		m.CredentialConfigurationsSupported[secondCredentialConfigID] = &secondConfig
+	})
+
+	handler.issuerMetadata = strings.ReplaceAll(issuerMetadata, serverURLPlaceholder, server.URL)
+
+	credentialOffer := createCredentialOffer(t, server.URL, true, true)
+	credentialOffer.CredentialConfigurationIDs = append(credentialOffer.CredentialConfigurationIDs,
+		secondCredentialConfigID)
+
+	credentialOfferBytes, err := json.Marshal(credentialOffer)
+	require.NoError(t, err)
+
+	return handler, server, "openid-credential-offer://?credential_offer=" + url.QueryEscape(string(credentialOfferBytes))
+}
+
+func TestIssuerInitiatedInteraction_MultipleCredentialsWithAuth(t *testing.T) {
+	t.Run("All offered credentials granted", func(t *testing.T) {
+		handler, server, issuanceURI := newMultipleCredentialsServer(t)
+		defer server.Close()
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI)
+
+		require.Nil(t, interaction.GrantedCredentialConfigIDs())
+
+		authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+		require.NoError(t, err)
+
+		parsedAuthURL, err := url.Parse(authURL)
+		require.NoError(t, err)
+
+		var requestedAuthorizationDetails []struct {
+			Type                 string                       `json:"type"`
+			Format               string                       `json:"format"`
+			CredentialDefinition *issuer.CredentialDefinition `json:"credential_definition"`
+		}
+
+		err = json.Unmarshal([]byte(parsedAuthURL.Query().Get("authorization_details")),
+			&requestedAuthorizationDetails)
+		require.NoError(t, err)
+		require.Len(t, requestedAuthorizationDetails, 2)
+		require.Equal(t, "openid_credential", requestedAuthorizationDetails[0].Type)
+		require.Equal(t, "jwt_vc_json", requestedAuthorizationDetails[0].Format)
+		require.Equal(t, []string{"VerifiableCredential", "VerifiedEmployee"},
+			requestedAuthorizationDetails[0].CredentialDefinition.Type)
+		require.Equal(t, []string{"VerifiableCredential", "UniversityDegreeCredential"},
+			requestedAuthorizationDetails[1].CredentialDefinition.Type)
+
+		credentials, err := interaction.RequestCredentialWithAuth(&jwtSignerMock{keyID: mockKeyID},
+			"redirectURI?code=1234&state="+getStateFromAuthURL(t, authURL))
+		require.NoError(t, err)
+		require.Len(t, credentials, 2)
+		require.Len(t, handler.credentialRequestTypes(), 2)
+		require.Equal(t, []string{"credential_configuration_id_1", secondCredentialConfigID},
+			interaction.GrantedCredentialConfigIDs())
+	})
+	t.Run("Only some of the offered credentials granted", func(t *testing.T) {
+		handler, server, issuanceURI := newMultipleCredentialsServer(t)
+		defer server.Close()
+
+		handler.grantedAuthorizationDetails = `[{"type":"openid_credential","format":"jwt_vc_json",` +
+			`"credential_definition":{"type":["VerifiableCredential","UniversityDegreeCredential"]}}]`
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI)
+
+		authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+		require.NoError(t, err)
+
+		credentials, err := interaction.RequestCredentialWithAuth(&jwtSignerMock{keyID: mockKeyID},
+			"redirectURI?code=1234&state="+getStateFromAuthURL(t, authURL))
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+		require.Equal(t, [][]string{{"VerifiableCredential", "UniversityDegreeCredential"}},
+			handler.credentialRequestTypes())
+		require.Equal(t, []string{secondCredentialConfigID}, interaction.GrantedCredentialConfigIDs())
+	})
+	t.Run("Granted credential identified by credential configuration ID", func(t *testing.T) {
+		handler, server, issuanceURI := newMultipleCredentialsServer(t)
+		defer server.Close()
+
+		handler.grantedAuthorizationDetails = `[{"type":"openid_credential",` +
+			`"credential_configuration_id":"credential_configuration_id_1"}]`
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI)
+
+		authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+		require.NoError(t, err)
+
+		credentials, err := interaction.RequestCredentialWithAuth(&jwtSignerMock{keyID: mockKeyID},
+			"redirectURI?code=1234&state="+getStateFromAuthURL(t, authURL))
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+		require.Equal(t, [][]string{{"VerifiableCredential", "VerifiedEmployee"}}, handler.credentialRequestTypes())
+		require.Equal(t, []string{"credential_configuration_id_1"}, interaction.GrantedCredentialConfigIDs())
+	})
+	t.Run("None of the offered credentials granted", func(t *testing.T) {
+		handler, server, issuanceURI := newMultipleCredentialsServer(t)
+		defer server.Close()
+
+		handler.grantedAuthorizationDetails = `[{"type":"openid_credential","credential_configuration_id":"other"}]`
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI)
+
+		authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+		require.NoError(t, err)
+
+		credentials, err := interaction.RequestCredentialWithAuth(&jwtSignerMock{keyID: mockKeyID},
+			"redirectURI?code=1234&state="+getStateFromAuthURL(t, authURL))
+		require.ErrorContains(t, err, "NO_CREDENTIALS_GRANTED(OCI1-0030)")
+		require.Nil(t, credentials)
+		require.Empty(t, handler.credentialRequestTypes())
+		require.Empty(t, interaction.GrantedCredentialConfigIDs())
+	})
+	t.Run("Invalid authorization_details in token response", func(t *testing.T) {
+		handler, server, issuanceURI := newMultipleCredentialsServer(t)
+		defer server.Close()
+
+		handler.grantedAuthorizationDetails = `"invalid"`
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI)
+
+		authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+		require.NoError(t, err)
+
+		credentials, err := interaction.RequestCredentialWithAuth(&jwtSignerMock{keyID: mockKeyID},
+			"redirectURI?code=1234&state="+getStateFromAuthURL(t, authURL))
+		require.ErrorContains(t, err, "failed to parse the authorization_details in the token response")
+		require.Nil(t, credentials)
+	})
+}
@@ -0,0 +1,273 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+const authorizationServerMetadataPath = "/.well-known/oauth-authorization-server"
+
+// mockAuthorizationServersHandler acts as any number of authorization servers (identified by the path that follows
+// the RFC 8414 well-known path) in addition to the issuer. Each authorization server's token endpoint is the issuer's
+// token endpoint with an "as" query parameter, which is recorded. All other requests are passed on to the wrapped
+// mockIssuerServerHandler.
+type mockAuthorizationServersHandler struct {
+	*mockIssuerServerHandler
+	serverURL                    string
+	tokenRequestAuthServers      []string
+	authorizationServerRequested bool
+}
+
+func (m *mockAuthorizationServersHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if strings.HasPrefix(request.URL.Path, authorizationServerMetadataPath) {
+		m.authorizationServerRequested = true
+
+		name := strings.TrimPrefix(request.URL.Path, authorizationServerMetadataPath)
+		if name == "/missing" {
+			writer.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		_, err := fmt.Fprintf(writer, `{"issuer":"%[1]s%[2]s","authorization_endpoint":"%[1]s%[2]s/authorize",`+
+			`"token_endpoint":"%[1]s/oidc/token?as=%[2]s"}`, m.serverURL, name)
+		assert.NoError(m.t, err)
+
+		return
+	}
+
+	if request.URL.Path == "/oidc/token" {
+		m.tokenRequestAuthServers = append(m.tokenRequestAuthServers, request.URL.Query().Get("as"))
+	}
+
+	m.mockIssuerServerHandler.ServeHTTP(writer, request)
+}
+
+// newAuthorizationServersServer creates an issuer that relies on the given authorization servers. If set, the
+// preAuthAuthorizationServer and authCodeAuthorizationServer are specified in the credential offer's pre-authorized
+// code grant and authorization code grant respectively.
+func newAuthorizationServersServer(t *testing.T, authorizationServers []string,
+	preAuthAuthorizationServer, authCodeAuthorizationServer string,
+) (*mockAuthorizationServersHandler, *httptest.Server, string) {
+	t.Helper()
+
+	handler := &mockAuthorizationServersHandler{
+		mockIssuerServerHandler: &mockIssuerServerHandler{t: t, credentialResponse: sampleCredentialResponse},
+	}
+
+	server := httptest.NewServer(handler)
+
+	handler.serverURL = server.URL
+
+	handler.issuerMetadata = modifyCredentialMetadata(t,
+		strings.ReplaceAll(sampleIssuerMetadata, serverURLPlaceholder, server.URL), func(m *issuer.Metadata) {
+			m.AuthorizationServer = ""
+			m.TokenEndpoint = ""
+
+			for _, authorizationServer := range authorizationServers {
+				m.AuthorizationServers = append(m.AuthorizationServers, server.URL+authorizationServer)
+			}
+		})
+
+	credentialOffer := createCredentialOffer(t, server.URL, true, false)
+
+	if preAuthAuthorizationServer != "" {
+		credentialOffer.Grants["urn:ietf:params:oauth:grant-type:pre-authorized_code"]["authorization_server"] =
+			server.URL + preAuthAuthorizationServer
+	}
+
+	if authCodeAuthorizationServer != "" {
+		credentialOffer.Grants["authorization_code"]["authorization_server"] = server.URL + authCodeAuthorizationServer
+	}
+
+	credentialOfferBytes, err := json.Marshal(credentialOffer)
+	require.NoError(t, err)
+
+	return handler, server, "openid-credential-offer://?credential_offer=" + url.QueryEscape(string(credentialOfferBytes))
+}
+
+func TestIssuerInitiatedInteraction_AuthorizationServers(t *testing.T) {
+	t.Run("First authorization server is used by default", func(t *testing.T) {
+		handler, server, issuanceURI := newAuthorizationServersServer(t, []string{"/as1", "/as2"}, "", "")
+		defer server.Close()
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI)
+
+		authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(authURL, server.URL+"/as1/authorize?"))
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+		require.Equal(t, []string{"/as1"}, handler.tokenRequestAuthServers)
+	})
+	t.Run("Authorization server specified in the credential offer", func(t *testing.T) {
+		handler, server, issuanceURI := newAuthorizationServersServer(t, []string{"/as1", "/as2"}, "/as2", "")
+		defer server.Close()
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI)
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+		require.Equal(t, []string{"/as2"}, handler.tokenRequestAuthServers)
+	})
+	t.Run("Different authorization servers for each grant", func(t *testing.T) {
+		handler, server, issuanceURI := newAuthorizationServersServer(t, []string{"/as1", "/as2", "/as3"}, "/as2",
+			"/as3")
+		defer server.Close()
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI)
+
+		authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(authURL, server.URL+"/as3/authorize?"))
+
+		_, err = interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+		require.Equal(t, []string{"/as2"}, handler.tokenRequestAuthServers)
+	})
+	t.Run("Authorization server specified only for the pre-authorized code grant", func(t *testing.T) {
+		_, server, issuanceURI := newAuthorizationServersServer(t, []string{"/as1", "/as2"}, "/as2", "")
+		defer server.Close()
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI)
+
+		// The authorization code flow uses the default authorization server.
+		authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(authURL, server.URL+"/as1/authorize?"))
+	})
+	t.Run("Issuer acting as its own authorization server", func(t *testing.T) {
+		handler, server, issuanceURI := newAuthorizationServersServer(t, nil, "", "")
+		defer server.Close()
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI)
+
+		_, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+		require.Equal(t, []string{""}, handler.tokenRequestAuthServers)
+	})
+	t.Run("Authorization server metadata not needed", func(t *testing.T) {
+		handler, server, _ := newAuthorizationServersServer(t, nil, "", "")
+		defer server.Close()
+
+		handler.issuerMetadata = strings.ReplaceAll(sampleIssuerMetadata, serverURLPlaceholder, server.URL)
+
+		newIssuerInitiatedInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false, true))
+		require.False(t, handler.authorizationServerRequested)
+	})
+	t.Run("Authorization server in the credential offer not listed in the issuer's metadata", func(t *testing.T) {
+		_, server, issuanceURI := newAuthorizationServersServer(t, []string{"/as1"}, "/as2", "")
+		defer server.Close()
+
+		interaction, err := openid4ci.NewIssuerInitiatedInteraction(issuanceURI, getTestClientConfig(t))
+		require.ErrorContains(t, err, "INVALID_CREDENTIAL_OFFER")
+		require.ErrorContains(t, err, "is not one of the authorization servers listed in the issuer's metadata")
+		require.Nil(t, interaction)
+	})
+	t.Run("Authorization code grant's authorization server not listed in the issuer's metadata", func(t *testing.T) {
+		_, server, issuanceURI := newAuthorizationServersServer(t, []string{"/as1"}, "", "/as2")
+		defer server.Close()
+
+		interaction, err := openid4ci.NewIssuerInitiatedInteraction(issuanceURI, getTestClientConfig(t))
+		require.ErrorContains(t, err, "INVALID_CREDENTIAL_OFFER")
+		require.Nil(t, interaction)
+	})
+	t.Run("Fail to get authorization server metadata", func(t *testing.T) {
+		_, server, issuanceURI := newAuthorizationServersServer(t, []string{"/missing"}, "", "")
+		defer server.Close()
+
+		interaction, err := openid4ci.NewIssuerInitiatedInteraction(issuanceURI, getTestClientConfig(t))
+		require.ErrorContains(t, err, "METADATA_FETCH_FAILED")
+		require.ErrorContains(t, err, "failed to get authorization server metadata")
+		require.Nil(t, interaction)
+	})
+}
+
+func TestIssuerInitiatedInteraction_AuthorizationServers_SerializeAndRestore(t *testing.T) {
+	t.Run("Authorization code grant", func(t *testing.T) {
+		handler, server, issuanceURI := newAuthorizationServersServer(t, []string{"/as1", "/as2"}, "", "/as2")
+		defer server.Close()
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI)
+
+		authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(authURL, server.URL+"/as2/authorize?"))
+
+		serializedState, err := interaction.Serialize(serializationKey)
+		require.NoError(t, err)
+
+		restoredInteraction, err := openid4ci.RestoreIssuerInitiatedInteraction(serializedState, serializationKey,
+			getTestClientConfig(t))
+		require.NoError(t, err)
+
+		_, err = restoredInteraction.RequestCredentialWithAuth(&jwtSignerMock{keyID: mockKeyID},
+			"redirectURI?code=1234&state="+getStateFromAuthURL(t, authURL))
+		require.NoError(t, err)
+		require.Equal(t, []string{"/as2"}, handler.tokenRequestAuthServers)
+	})
+	t.Run("Each grant keeps its authorization server", func(t *testing.T) {
+		handler, server, issuanceURI := newAuthorizationServersServer(t, []string{"/as1", "/as2", "/as3"}, "/as3",
+			"/as2")
+		defer server.Close()
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI)
+
+		_, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+		require.NoError(t, err)
+
+		serializedState, err := interaction.Serialize(serializationKey)
+		require.NoError(t, err)
+
+		restoredInteraction, err := openid4ci.RestoreIssuerInitiatedInteraction(serializedState, serializationKey,
+			getTestClientConfig(t))
+		require.NoError(t, err)
+
+		// Checking for dynamic client registration selects the authorization code grant's authorization server,
+		// which must still be the one named in the credential offer.
+		_, err = restoredInteraction.DynamicClientRegistrationSupported()
+		require.NoError(t, err)
+
+		restoredAuthURL, err := restoredInteraction.CreateAuthorizationURL("clientID", "redirectURI")
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(restoredAuthURL, server.URL+"/as2/authorize?"))
+
+		_, err = restoredInteraction.RequestCredentialWithAuth(&jwtSignerMock{keyID: mockKeyID},
+			"redirectURI?code=1234&state="+getStateFromAuthURL(t, restoredAuthURL))
+		require.NoError(t, err)
+		require.Equal(t, []string{"/as2"}, handler.tokenRequestAuthServers)
+
+		restoredInteraction, err = openid4ci.RestoreIssuerInitiatedInteraction(serializedState, serializationKey,
+			getTestClientConfig(t))
+		require.NoError(t, err)
+
+		_, err = restoredInteraction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+		require.Equal(t, []string{"/as2", "/as3"}, handler.tokenRequestAuthServers)
+	})
+}
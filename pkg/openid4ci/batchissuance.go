@@ -0,0 +1,417 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/trustbloc/vc-go/verifiable"
+
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/common"
+	didjwk "github.com/trustbloc/wallet-sdk/pkg/did/creator/jwk"
+	"github.com/trustbloc/wallet-sdk/pkg/internal/httprequest"
+	"github.com/trustbloc/wallet-sdk/pkg/models"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+const (
+	fetchCredentialBatchEventText = "Fetch %d copies of credential %d of %d via an HTTP POST request to %s"
+	fetchNonceEventText           = "Fetch nonce via an HTTP POST request to %s"
+)
+
+// CredentialBatch contains copies of the same credential that were requested using batch issuance. Each copy is bound
+// to a different holder key. To prevent the copies from being linked to each other, a different copy should be used
+// for each presentation.
+type CredentialBatch struct {
+	CredentialConfigurationID string
+	Credentials               []*verifiable.Credential
+	// The IDs of the keys that the credentials are bound to, in the same order as Credentials.
+	HolderKeyIDs []string
+}
+
+// UnusedHolderKeysError is returned when batch issuance fails after new holder keys have already been created. No
+// credentials are returned in that case, so none of the keys are used. The KeyWriter has no way of deleting keys, so
+// the IDs of the keys are returned so that the caller can delete them. The error from the failed step is wrapped.
+type UnusedHolderKeysError struct {
+	// The IDs of the keys that were created, as returned by api.KeyWriter.Create.
+	KeyIDs []string
+	Err    error
+}
+
+// Error returns the message of the wrapped error.
+func (e *UnusedHolderKeysError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *UnusedHolderKeysError) Unwrap() error {
+	return e.Err
+}
+
+// withUnusedHolderKeys wraps the given error in an UnusedHolderKeysError if any holder keys were created.
+func withUnusedHolderKeys(keyIDs []string, err error) error {
+	if len(keyIDs) == 0 {
+		return err
+	}
+
+	return &UnusedHolderKeysError{KeyIDs: keyIDs, Err: err}
+}
+
+// credentialToRequest identifies a credential that copies are requested of.
+type credentialToRequest struct {
+	configID string
+	format   string
+	types    []string
+	contexts []string
+}
+
+// credentialsToRequest returns the credentials to request copies of. configIDs is optional.
+func credentialsToRequest(credentialFormats []string, credentialTypes, credentialContexts [][]string,
+	configIDs []string,
+) []*credentialToRequest {
+	credentials := make([]*credentialToRequest, len(credentialTypes))
+
+	for index := range credentialTypes {
+		credentials[index] = &credentialToRequest{
+			format:   credentialFormats[index],
+			types:    credentialTypes[index],
+			contexts: credentialContexts[index],
+		}
+
+		if len(configIDs) == len(credentialTypes) {
+			credentials[index].configID = configIDs[index]
+		}
+	}
+
+	return credentials
+}
+
+// requestCredentialBatchesWithAuth requests copies of each of the given credentials using the access token from the
+// authorization code flow. The copies are kept, grouped by credential, so that they can be retrieved using the
+// CredentialBatches method. configIDs is optional.
+func (i *interaction) requestCredentialBatchesWithAuth(ctx context.Context, credentialFormats []string,
+	credentialTypes, credentialContexts [][]string, configIDs []string, opts *batchIssuanceOpts,
+) ([]*verifiable.Credential, error) {
+	var err error
+
+	i.credentialBatches, err = i.requestCredentialBatches(ctx, i.authToken, i.authTokenResponseNonce,
+		credentialsToRequest(credentialFormats, credentialTypes, credentialContexts, configIDs), opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credential response: %w", err)
+	}
+
+	return batchedCredentials(i.credentialBatches), nil
+}
+
+// requestCredentialBatches requests copies of each of the given credentials. The key proofs use the given c_nonce at
+// first, and then the fresh c_nonce that the issuer returns in each credential response. If the issuer has a nonce
+// endpoint, then a new nonce is fetched for each proof instead.
+//
+// The holder keys for all the copies are created up front. If anything fails after that, then the error is an
+// UnusedHolderKeysError containing the IDs of the created keys, since the caller is responsible for deleting them.
+func (i *interaction) requestCredentialBatches(ctx context.Context, authToken *AuthToken, nonce any,
+	credentials []*credentialToRequest, opts *batchIssuanceOpts,
+) ([]*CredentialBatch, error) {
+	signers, keyIDs, err := createHolderSigners(opts, len(credentials)*opts.copies)
+	if err != nil {
+		return nil, withUnusedHolderKeys(keyIDs, walleterror.NewExecutionError(ErrorModule,
+			BatchIssuanceFailedCode,
+			BatchIssuanceFailedError,
+			fmt.Errorf("failed to create holder keys: %w", err)))
+	}
+
+	headers := http.Header{}
+	headers.Add("Authorization", authorizationHeader(authToken.TokenType, authToken.AccessToken))
+
+	batches := make([]*CredentialBatch, len(credentials))
+
+	for batchIndex, credential := range credentials {
+		fetchCredentialBatchEventText := fmt.Sprintf(fetchCredentialBatchEventText, opts.copies, batchIndex+1,
+			len(credentials), i.issuerMetadata.CredentialEndpoint)
+
+		batchSigners := signers[batchIndex*opts.copies : (batchIndex+1)*opts.copies]
+
+		batch, nextNonce, err := i.requestCredentialBatch(ctx, headers, nonce, credential, batchSigners,
+			fetchCredentialBatchEventText)
+		if err != nil {
+			return nil, withUnusedHolderKeys(keyIDs, err)
+		}
+
+		batches[batchIndex] = batch
+		nonce = nextNonce
+	}
+
+	return batches, nil
+}
+
+// batchedCredentials returns the copies from all the given batches.
+func batchedCredentials(batches []*CredentialBatch) []*verifiable.Credential {
+	var vcs []*verifiable.Credential
+
+	for _, batch := range batches {
+		vcs = append(vcs, batch.Credentials...)
+	}
+
+	return vcs
+}
+
+// requestCredentialBatch requests copies of the given credential, one bound to each of the given holder signers' keys.
+// If the issuer supports batch issuance, then up to batch_size copies are requested at once. Otherwise, they're
+// requested one by one. The c_nonce to use for the next key proofs is returned along with the copies.
+func (i *interaction) requestCredentialBatch(ctx context.Context, headers http.Header, nonce any,
+	credential *credentialToRequest, signers []api.JWTSigner, eventText string,
+) (*CredentialBatch, any, error) {
+	var err error
+
+	batchSize := 1
+
+	if i.issuerMetadata.BatchCredentialIssuance != nil && i.issuerMetadata.BatchCredentialIssuance.BatchSize > 1 {
+		batchSize = i.issuerMetadata.BatchCredentialIssuance.BatchSize
+	}
+
+	batch := &CredentialBatch{CredentialConfigurationID: credential.configID}
+
+	for start := 0; start < len(signers); start += batchSize {
+		batchSigners := signers[start:min(start+batchSize, len(signers))]
+
+		var vcs []*verifiable.Credential
+
+		vcs, nonce, err = i.requestCredentialCopies(ctx, headers, nonce, credential, batchSigners, eventText)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		batch.Credentials = append(batch.Credentials, vcs...)
+
+		for _, signer := range batchSigners {
+			batch.HolderKeyIDs = append(batch.HolderKeyIDs, signer.GetKeyID())
+		}
+	}
+
+	return batch, nonce, nil
+}
+
+// requestCredentialCopies sends a single credential request for one copy of the given credential per signer. When
+// requesting more than one copy, the key proofs are sent in the proofs parameter. If the issuer rejects the key
+// proofs, then they're created again using the c_nonce from the error response and the request is retried once.
+// The c_nonce to use for the next key proofs is returned along with the copies.
+func (i *interaction) requestCredentialCopies(ctx context.Context, headers http.Header, nonce any,
+	credential *credentialToRequest, signers []api.JWTSigner, eventText string,
+) ([]*verifiable.Credential, any, error) {
+	credentialReq, err := i.newCredentialCopiesRequest(ctx, nonce, credential, signers)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	credentialResponse, err := i.sendCredentialRequest(ctx, headers, credentialReq, eventText)
+
+	proofError := &InvalidProofError{}
+	if errors.As(err, &proofError) {
+		nonce = proofError.CNonce
+
+		credentialReq, err = i.newCredentialCopiesRequest(ctx, nonce, credential, signers)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		credentialResponse, err = i.sendCredentialRequest(ctx, headers, credentialReq, eventText)
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if credentialResponse.CNonce != "" {
+		nonce = credentialResponse.CNonce
+	}
+
+	credentialResponses := credentialResponse.split()
+
+	if len(credentialResponses) != len(signers) {
+		return nil, nil, walleterror.NewExecutionError(ErrorModule,
+			BatchIssuanceFailedCode,
+			BatchIssuanceFailedError,
+			fmt.Errorf("requested %d copies of the credential, but the issuer returned %d",
+				len(signers), len(credentialResponses)))
+	}
+
+	vcs := make([]*verifiable.Credential, len(signers))
+
+	for index, signer := range signers {
+		// Each copy is bound to a different key, so each one is checked against the key it was requested for.
+		parsedVCs, err := i.getVCsFromCredentialResponses(credentialResponses[index:index+1], signer.GetKeyID())
+		if err != nil {
+			return nil, nil, walleterror.NewExecutionError(ErrorModule,
+				CredentialParseFailedCode,
+				CredentialParseError, err)
+		}
+
+		vcs[index] = parsedVCs[0]
+	}
+
+	return vcs, nonce, nil
+}
+
+// newCredentialCopiesRequest creates a credential request for one copy of the given credential per signer.
+func (i *interaction) newCredentialCopiesRequest(ctx context.Context, nonce any, credential *credentialToRequest,
+	signers []api.JWTSigner,
+) (*credentialRequest, error) {
+	credentialReq := i.newCredentialRequest(credential.configID, credential.format, credential.types,
+		credential.contexts)
+
+	keyProofs := &proofs{}
+
+	for _, signer := range signers {
+		keyProof, err := i.createKeyProofForCopy(ctx, nonce, signer, credential)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(signers) == 1 {
+			credentialReq.Proof = keyProof
+		} else {
+			keyProofs.add(keyProof)
+			credentialReq.Proofs = keyProofs
+		}
+	}
+
+	return &credentialReq, nil
+}
+
+func (i *interaction) createKeyProofForCopy(ctx context.Context, nonce any, signer api.JWTSigner,
+	credential *credentialToRequest,
+) (*proof, error) {
+	if i.issuerMetadata.NonceEndpoint != "" {
+		var err error
+
+		nonce, err = i.fetchNonce(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return i.newKeyProofCreator(nonce, signer).proofForCredential(credential.configID, credential.format,
+		credential.types)
+}
+
+func (i *interaction) sendCredentialRequest(ctx context.Context, headers http.Header,
+	credentialReq *credentialRequest, eventText string,
+) (*CredentialResponse, error) {
+	responseEncryption, err := i.credentialResponseEncryptionParams()
+	if err != nil {
+		return nil, err
+	}
+
+	credentialReq.CredentialResponseEncryption = responseEncryption
+
+	requestBody, err := json.Marshal(credentialReq)
+	if err != nil {
+		return nil, err
+	}
+
+	responseBytes, err := httprequest.New(i.httpClient, i.metricsLogger).DoContext(ctx,
+		http.MethodPost, i.issuerMetadata.CredentialEndpoint, "application/json", headers,
+		bytes.NewReader(requestBody), eventText, requestCredentialEventText,
+		[]int{http.StatusOK, http.StatusCreated}, processCredentialErrorResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	responseBytes, err = i.decryptCredentialResponse(responseBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	var credentialResponse CredentialResponse
+
+	err = json.Unmarshal(responseBytes, &credentialResponse)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response from the issuer's credential endpoint: %w", err)
+	}
+
+	if credentialResponse.deferred() {
+		return nil, walleterror.NewExecutionError(ErrorModule,
+			BatchIssuanceFailedCode,
+			BatchIssuanceFailedError,
+			errors.New("the issuer deferred the issuance of a credential batch, which isn't supported"))
+	}
+
+	i.storeAcknowledgmentID(credentialResponse.AckID)
+
+	return &credentialResponse, nil
+}
+
+// fetchNonce gets a new c_nonce from the issuer's nonce endpoint.
+func (i *interaction) fetchNonce(ctx context.Context) (string, error) {
+	responseBytes, err := httprequest.New(i.httpClient, i.metricsLogger).DoContext(ctx, http.MethodPost,
+		i.issuerMetadata.NonceEndpoint, "", nil, nil,
+		fmt.Sprintf(fetchNonceEventText, i.issuerMetadata.NonceEndpoint), requestCredentialEventText, nil, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch nonce: %w", err)
+	}
+
+	var response nonceResponse
+
+	err = json.Unmarshal(responseBytes, &response)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal response from the issuer's nonce endpoint: %w", err)
+	}
+
+	return response.CNonce, nil
+}
+
+func validateBatchIssuanceOpts(opts *batchIssuanceOpts) error {
+	if opts.copies < 1 {
+		return errors.New("the number of copies to request must be at least 1")
+	}
+
+	if opts.keyWriter == nil {
+		return errors.New("a key writer is required for batch issuance")
+	}
+
+	if opts.crypto == nil {
+		return errors.New("a crypto implementation is required for batch issuance")
+	}
+
+	return nil
+}
+
+// createHolderSigners creates the given number of new keys, along with a signer for each one that uses the key through
+// a did:jwk DID. The IDs of the keys that were created are returned even if an error occurs part of the way through.
+func createHolderSigners(opts *batchIssuanceOpts, count int) ([]api.JWTSigner, []string, error) {
+	signers := make([]api.JWTSigner, count)
+
+	var keyIDs []string
+
+	for index := range signers {
+		keyID, publicKey, err := opts.keyWriter.Create(opts.keyType)
+		if err != nil {
+			return nil, keyIDs, err
+		}
+
+		keyIDs = append(keyIDs, keyID)
+
+		didDocResolution, err := didjwk.Create(publicKey)
+		if err != nil {
+			return nil, keyIDs, err
+		}
+
+		signers[index], err = common.NewJWSSigner(
+			models.VerificationMethodFromDoc(&didDocResolution.DIDDocument.VerificationMethod[0]), opts.crypto)
+		if err != nil {
+			return nil, keyIDs, err
+		}
+	}
+
+	return signers, keyIDs, nil
+}
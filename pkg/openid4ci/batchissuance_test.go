@@ -0,0 +1,486 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/kms-go/doc/jose/jwk"
+	"github.com/trustbloc/kms-go/spi/kms"
+
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/localkms"
+	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+type receivedBatchCredentialRequest struct {
+	CredentialConfigurationID string `json:"credential_configuration_id"`
+	Proof                     *struct {
+		ProofType string `json:"proof_type"`
+		JWT       string `json:"jwt"`
+	} `json:"proof"`
+	Proofs *struct {
+		JWT []string `json:"jwt"`
+	} `json:"proofs"`
+}
+
+// mockBatchIssuanceHandler issues one credential per key proof in the credential requests it receives and records
+// those requests. New nonces are returned from the nonce endpoint and in each credential response. All other requests
+// are passed on to the wrapped mockIssuerServerHandler.
+type mockBatchIssuanceHandler struct {
+	*mockIssuerServerHandler
+	credential         string
+	credentialRequests []*receivedBatchCredentialRequest
+	noncesIssued       int
+	// If set, then this number of credentials is returned regardless of the number of key proofs.
+	credentialsToReturn int
+	// If set, then the key proofs in the first credential request are rejected.
+	rejectFirstProofs bool
+}
+
+func (m *mockBatchIssuanceHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	switch request.URL.Path {
+	case "/oidc/nonce":
+		m.noncesIssued++
+
+		_, err := fmt.Fprintf(writer, `{"c_nonce":"nonce-%d"}`, m.noncesIssued)
+		assert.NoError(m.t, err)
+	case "/oidc/credential":
+		var credentialRequest receivedBatchCredentialRequest
+
+		err := json.NewDecoder(request.Body).Decode(&credentialRequest)
+		assert.NoError(m.t, err)
+
+		m.credentialRequests = append(m.credentialRequests, &credentialRequest)
+
+		if m.rejectFirstProofs && len(m.credentialRequests) == 1 {
+			writer.WriteHeader(http.StatusBadRequest)
+
+			_, err = writer.Write([]byte(`{"error":"invalid_proof","c_nonce":"retry-nonce"}`))
+			assert.NoError(m.t, err)
+
+			return
+		}
+
+		numberOfCredentials := 1
+
+		if credentialRequest.Proofs != nil {
+			numberOfCredentials = len(credentialRequest.Proofs.JWT)
+		}
+
+		if m.credentialsToReturn != 0 {
+			numberOfCredentials = m.credentialsToReturn
+		}
+
+		credentials := make([]map[string]string, numberOfCredentials)
+
+		for index := range credentials {
+			credentials[index] = map[string]string{"credential": m.credential}
+		}
+
+		responseBytes, err := json.Marshal(map[string]interface{}{
+			"credentials": credentials,
+			"c_nonce":     fmt.Sprintf("credential-nonce-%d", len(m.credentialRequests)),
+		})
+		assert.NoError(m.t, err)
+
+		_, err = writer.Write(responseBytes)
+		assert.NoError(m.t, err)
+	default:
+		m.mockIssuerServerHandler.ServeHTTP(writer, request)
+	}
+}
+
+// proofKeyIDs returns the key IDs from the headers of all the JWT key proofs received so far, along with the nonces
+// from their claims.
+func (m *mockBatchIssuanceHandler) proofKeyIDs() (keyIDs, nonces []string) {
+	for _, credentialRequest := range m.credentialRequests {
+		proofJWTs := []string{}
+
+		if credentialRequest.Proof != nil {
+			proofJWTs = append(proofJWTs, credentialRequest.Proof.JWT)
+		}
+
+		if credentialRequest.Proofs != nil {
+			proofJWTs = append(proofJWTs, credentialRequest.Proofs.JWT...)
+		}
+
+		for _, proofJWT := range proofJWTs {
+			parts := strings.Split(proofJWT, ".")
+
+			var header struct {
+				KeyID string `json:"kid"`
+			}
+
+			var claims struct {
+				Nonce string `json:"nonce"`
+			}
+
+			decodeJWTPart(m.t, parts[0], &header)
+			decodeJWTPart(m.t, parts[1], &claims)
+
+			keyIDs = append(keyIDs, header.KeyID)
+			nonces = append(nonces, claims.Nonce)
+		}
+	}
+
+	return keyIDs, nonces
+}
+
+func decodeJWTPart(t *testing.T, part string, value interface{}) {
+	t.Helper()
+
+	partBytes, err := base64.RawURLEncoding.DecodeString(part)
+	require.NoError(t, err)
+
+	require.NoError(t, json.Unmarshal(partBytes, value))
+}
+
+// recordingKeyWriter creates keys using the wrapped KeyWriter and records their IDs. If failAfter is set, then key
+// creation fails once that many keys have been created.
+type recordingKeyWriter struct {
+	api.KeyWriter
+	keyIDs    []string
+	failAfter int
+}
+
+func (r *recordingKeyWriter) Create(keyType kms.KeyType) (string, *jwk.JWK, error) {
+	if r.failAfter != 0 && len(r.keyIDs) == r.failAfter {
+		return "", nil, errors.New("key storage is full")
+	}
+
+	keyID, publicKey, err := r.KeyWriter.Create(keyType)
+	if err != nil {
+		return "", nil, err
+	}
+
+	r.keyIDs = append(r.keyIDs, keyID)
+
+	return keyID, publicKey, nil
+}
+
+func newBatchIssuanceServer(t *testing.T, modifyFunc func(m *issuer.Metadata, serverURL string),
+) (*mockBatchIssuanceHandler, func(), string) {
+	t.Helper()
+
+	baseHandler, server, issuanceURI := newMultipleCredentialsServer(t)
+
+	var sampleResponse struct {
+		Credential string `json:"credential"`
+	}
+
+	require.NoError(t, json.Unmarshal(sampleCredentialResponse, &sampleResponse))
+
+	handler := &mockBatchIssuanceHandler{
+		mockIssuerServerHandler: baseHandler.mockIssuerServerHandler,
+		credential:              sampleResponse.Credential,
+	}
+
+	handler.issuerMetadata = modifyCredentialMetadata(t, baseHandler.issuerMetadata, func(m *issuer.Metadata) {
+		m.BatchCredentialEndpoint = ""
+
+		modifyFunc(m, server.URL)
+	})
+
+	server.Config.Handler = handler
+
+	return handler, server.Close, issuanceURI
+}
+
+func TestIssuerInitiatedInteraction_RequestCredentialWithPreAuth_BatchIssuance(t *testing.T) {
+	localKMS, err := localkms.NewLocalKMS(localkms.Config{Storage: localkms.NewMemKMSStore()})
+	require.NoError(t, err)
+
+	t.Run("Issuer supports batch issuance", func(t *testing.T) {
+		handler, closeServer, issuanceURI := newBatchIssuanceServer(t, func(m *issuer.Metadata, _ string) {
+			m.BatchCredentialIssuance = &issuer.BatchCredentialIssuance{BatchSize: 2}
+		})
+		defer closeServer()
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI)
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"),
+			openid4ci.WithBatchIssuance(3, localKMS, localKMS.GetCrypto(), kms.ED25519Type))
+		require.NoError(t, err)
+		require.Len(t, credentials, 6)
+
+		// For each offered credential, two copies are requested at once and then one more.
+		require.Len(t, handler.credentialRequests, 4)
+		require.Len(t, handler.credentialRequests[0].Proofs.JWT, 2)
+		require.Nil(t, handler.credentialRequests[0].Proof)
+		require.NotNil(t, handler.credentialRequests[1].Proof)
+		require.Nil(t, handler.credentialRequests[1].Proofs)
+
+		batches := interaction.CredentialBatches()
+		require.Len(t, batches, 2)
+		require.Equal(t, "credential_configuration_id_1", batches[0].CredentialConfigurationID)
+		require.Equal(t, secondCredentialConfigID, batches[1].CredentialConfigurationID)
+
+		keyIDs, nonces := handler.proofKeyIDs()
+		require.Equal(t, append(batches[0].HolderKeyIDs, batches[1].HolderKeyIDs...), keyIDs)
+
+		// Each copy is bound to a different key.
+		uniqueKeyIDs := map[string]bool{}
+
+		for _, batch := range batches {
+			require.Len(t, batch.Credentials, 3)
+			require.Len(t, batch.HolderKeyIDs, 3)
+
+			for _, keyID := range batch.HolderKeyIDs {
+				require.True(t, strings.HasPrefix(keyID, "did:jwk:"))
+
+				uniqueKeyIDs[keyID] = true
+			}
+		}
+
+		require.Len(t, uniqueKeyIDs, 6)
+
+		// Without a nonce endpoint, the c_nonce from the token response is used in the first proofs, and then the
+		// c_nonce from each credential response is used in the proofs of the next request.
+		require.Equal(t, []string{
+			"tZignsnFbp", "tZignsnFbp", "credential-nonce-1", "credential-nonce-2", "credential-nonce-2",
+			"credential-nonce-3",
+		}, nonces)
+	})
+	t.Run("Key proofs rejected", func(t *testing.T) {
+		handler, closeServer, issuanceURI := newBatchIssuanceServer(t, func(m *issuer.Metadata, _ string) {
+			m.BatchCredentialIssuance = &issuer.BatchCredentialIssuance{BatchSize: 2}
+		})
+		defer closeServer()
+
+		handler.rejectFirstProofs = true
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI)
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"),
+			openid4ci.WithBatchIssuance(2, localKMS, localKMS.GetCrypto(), kms.ED25519Type))
+		require.NoError(t, err)
+		require.Len(t, credentials, 4)
+
+		// The rejected request is retried once using the c_nonce from the error response.
+		require.Len(t, handler.credentialRequests, 3)
+
+		_, nonces := handler.proofKeyIDs()
+		require.Equal(t, []string{
+			"tZignsnFbp", "tZignsnFbp", "retry-nonce", "retry-nonce", "credential-nonce-2", "credential-nonce-2",
+		}, nonces)
+	})
+	t.Run("Authorization code flow", func(t *testing.T) {
+		handler, closeServer, issuanceURI := newBatchIssuanceServer(t, func(m *issuer.Metadata, _ string) {
+			m.BatchCredentialIssuance = &issuer.BatchCredentialIssuance{BatchSize: 2}
+		})
+		defer closeServer()
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI)
+
+		authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+		require.NoError(t, err)
+
+		credentials, err := interaction.RequestCredentialWithAuth(&jwtSignerMock{keyID: mockKeyID},
+			"redirectURI?code=1234&state="+getStateFromAuthURL(t, authURL),
+			openid4ci.WithBatchIssuanceForAuth(2, localKMS, localKMS.GetCrypto(), kms.ED25519Type))
+		require.NoError(t, err)
+		require.Len(t, credentials, 4)
+
+		require.Len(t, handler.credentialRequests, 2)
+		require.Len(t, handler.credentialRequests[0].Proofs.JWT, 2)
+
+		batches := interaction.CredentialBatches()
+		require.Len(t, batches, 2)
+		require.Equal(t, "credential_configuration_id_1", batches[0].CredentialConfigurationID)
+		require.Equal(t, secondCredentialConfigID, batches[1].CredentialConfigurationID)
+
+		keyIDs, nonces := handler.proofKeyIDs()
+		require.Equal(t, append(batches[0].HolderKeyIDs, batches[1].HolderKeyIDs...), keyIDs)
+		require.Equal(t, []string{"tZignsnFbp", "tZignsnFbp", "credential-nonce-1", "credential-nonce-1"}, nonces)
+	})
+	t.Run("Wallet-initiated flow", func(t *testing.T) {
+		var issuerURI string
+
+		handler, closeServer, _ := newBatchIssuanceServer(t, func(m *issuer.Metadata, serverURL string) {
+			m.BatchCredentialIssuance = &issuer.BatchCredentialIssuance{BatchSize: 2}
+			issuerURI = serverURL
+		})
+		defer closeServer()
+
+		interaction, err := openid4ci.NewWalletInitiatedInteraction(issuerURI, getTestClientConfig(t))
+		require.NoError(t, err)
+
+		authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI", "jwt_vc_json",
+			[]string{"VerifiableCredential", "UniversityDegreeCredential"})
+		require.NoError(t, err)
+
+		credentials, err := interaction.RequestCredential(&jwtSignerMock{keyID: mockKeyID},
+			"redirectURI?code=1234&state="+getStateFromAuthURL(t, authURL),
+			openid4ci.WithBatchIssuanceForAuth(2, localKMS, localKMS.GetCrypto(), kms.ED25519Type))
+		require.NoError(t, err)
+		require.Len(t, credentials, 2)
+
+		require.Len(t, handler.credentialRequests, 1)
+
+		batches := interaction.CredentialBatches()
+		require.Len(t, batches, 1)
+		require.Len(t, batches[0].HolderKeyIDs, 2)
+	})
+	t.Run("Nonce endpoint", func(t *testing.T) {
+		handler, closeServer, issuanceURI := newBatchIssuanceServer(t, func(m *issuer.Metadata, serverURL string) {
+			m.BatchCredentialIssuance = &issuer.BatchCredentialIssuance{BatchSize: 5}
+			m.NonceEndpoint = serverURL + "/oidc/nonce"
+		})
+		defer closeServer()
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI)
+
+		_, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"),
+			openid4ci.WithBatchIssuance(2, localKMS, localKMS.GetCrypto(), kms.ED25519Type))
+		require.NoError(t, err)
+
+		require.Len(t, handler.credentialRequests, 2)
+
+		_, nonces := handler.proofKeyIDs()
+		require.Equal(t, []string{"nonce-1", "nonce-2", "nonce-3", "nonce-4"}, nonces)
+	})
+	t.Run("Issuer doesn't support batch issuance", func(t *testing.T) {
+		handler, closeServer, issuanceURI := newBatchIssuanceServer(t, func(*issuer.Metadata, string) {})
+		defer closeServer()
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI)
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"),
+			openid4ci.WithBatchIssuance(2, localKMS, localKMS.GetCrypto(), kms.ED25519Type))
+		require.NoError(t, err)
+		require.Len(t, credentials, 4)
+
+		// Each copy is requested individually.
+		require.Len(t, handler.credentialRequests, 4)
+
+		for _, credentialRequest := range handler.credentialRequests {
+			require.NotNil(t, credentialRequest.Proof)
+			require.Nil(t, credentialRequest.Proofs)
+		}
+	})
+	t.Run("Issuer returns the wrong number of credentials", func(t *testing.T) {
+		handler, closeServer, issuanceURI := newBatchIssuanceServer(t, func(m *issuer.Metadata, _ string) {
+			m.BatchCredentialIssuance = &issuer.BatchCredentialIssuance{BatchSize: 2}
+		})
+		defer closeServer()
+
+		handler.credentialsToReturn = 1
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI)
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"),
+			openid4ci.WithBatchIssuance(2, localKMS, localKMS.GetCrypto(), kms.ED25519Type))
+		require.ErrorContains(t, err, "requested 2 copies of the credential, but the issuer returned 1")
+		require.Nil(t, credentials)
+
+		var walletErr *walleterror.Error
+
+		require.ErrorAs(t, err, &walletErr)
+		require.Equal(t, openid4ci.BatchIssuanceFailedError, walletErr.Category)
+	})
+	t.Run("Unused holder keys are returned when issuance fails", func(t *testing.T) {
+		handler, closeServer, issuanceURI := newBatchIssuanceServer(t, func(m *issuer.Metadata, _ string) {
+			m.BatchCredentialIssuance = &issuer.BatchCredentialIssuance{BatchSize: 2}
+		})
+		defer closeServer()
+
+		handler.credentialsToReturn = 1
+
+		keyWriter := &recordingKeyWriter{KeyWriter: localKMS}
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI)
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"),
+			openid4ci.WithBatchIssuance(2, keyWriter, localKMS.GetCrypto(), kms.ED25519Type))
+		require.ErrorContains(t, err, "requested 2 copies of the credential, but the issuer returned 1")
+		require.Nil(t, credentials)
+
+		// The keys for the copies of both offered credentials were created before the first request failed.
+		var unusedKeysErr *openid4ci.UnusedHolderKeysError
+
+		require.ErrorAs(t, err, &unusedKeysErr)
+		require.Len(t, keyWriter.keyIDs, 4)
+		require.Equal(t, keyWriter.keyIDs, unusedKeysErr.KeyIDs)
+
+		var walletErr *walleterror.Error
+
+		require.ErrorAs(t, err, &walletErr)
+		require.Equal(t, openid4ci.BatchIssuanceFailedError, walletErr.Category)
+	})
+	t.Run("Key creation fails part of the way through", func(t *testing.T) {
+		handler, closeServer, issuanceURI := newBatchIssuanceServer(t, func(*issuer.Metadata, string) {})
+		defer closeServer()
+
+		keyWriter := &recordingKeyWriter{KeyWriter: localKMS, failAfter: 3}
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI)
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"),
+			openid4ci.WithBatchIssuance(2, keyWriter, localKMS.GetCrypto(), kms.ED25519Type))
+		require.ErrorContains(t, err, "failed to create holder keys: key storage is full")
+		require.Nil(t, credentials)
+		require.Empty(t, handler.credentialRequests)
+
+		var unusedKeysErr *openid4ci.UnusedHolderKeysError
+
+		require.ErrorAs(t, err, &unusedKeysErr)
+		require.Len(t, unusedKeysErr.KeyIDs, 3)
+		require.Equal(t, keyWriter.keyIDs, unusedKeysErr.KeyIDs)
+	})
+	t.Run("Invalid number of copies", func(t *testing.T) {
+		_, closeServer, issuanceURI := newBatchIssuanceServer(t, func(*issuer.Metadata, string) {})
+		defer closeServer()
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI)
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"),
+			openid4ci.WithBatchIssuance(0, localKMS, localKMS.GetCrypto(), kms.ED25519Type))
+		require.ErrorContains(t, err, "the number of copies to request must be at least 1")
+		require.Nil(t, credentials)
+	})
+	t.Run("Missing key writer", func(t *testing.T) {
+		_, closeServer, issuanceURI := newBatchIssuanceServer(t, func(*issuer.Metadata, string) {})
+		defer closeServer()
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI)
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"), openid4ci.WithBatchIssuance(1, nil, localKMS.GetCrypto(), kms.ED25519Type))
+		require.ErrorContains(t, err, "a key writer is required for batch issuance")
+		require.Nil(t, credentials)
+	})
+	t.Run("Invalid options in the authorization code flow", func(t *testing.T) {
+		_, closeServer, issuanceURI := newBatchIssuanceServer(t, func(*issuer.Metadata, string) {})
+		defer closeServer()
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI)
+
+		credentials, err := interaction.RequestCredentialWithAuth(&jwtSignerMock{keyID: mockKeyID},
+			"redirectURI?code=1234", openid4ci.WithBatchIssuanceForAuth(1, localKMS, nil, kms.ED25519Type))
+		require.ErrorContains(t, err, "a crypto implementation is required for batch issuance")
+		require.Nil(t, credentials)
+	})
+}
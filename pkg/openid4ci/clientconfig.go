@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package openid4ci
 
 import (
+	"crypto/x509"
 	"errors"
 	"net/http"
 	"time"
@@ -17,6 +18,8 @@ import (
 
 	noopactivitylogger "github.com/trustbloc/wallet-sdk/pkg/activitylogger/noop"
 	"github.com/trustbloc/wallet-sdk/pkg/api"
+	metadatafetcher "github.com/trustbloc/wallet-sdk/pkg/internal/issuermetadata"
+	"github.com/trustbloc/wallet-sdk/pkg/metadatacache"
 	noopmetricslogger "github.com/trustbloc/wallet-sdk/pkg/metricslogger/noop"
 )
 
@@ -43,6 +46,48 @@ type ClientConfig struct {
 	DocumentLoader                   ld.DocumentLoader // If not specified, then a network-based loader will be used.
 	NetworkDocumentLoaderHTTPTimeout *time.Duration    // Only used if the default network-based loader is used.
 	HTTPClient                       *http.Client
+	// If set, then credential responses will be encrypted whenever the issuer supports it. If the issuer requires
+	// encrypted responses, then they will be used regardless of this setting.
+	EnableCredentialResponseEncryption bool
+	// If set, then credentials are identified by their credential_configuration_id in authorization_details and
+	// credential requests, as required by draft 15 and later of the OpenID4VCI specification. Otherwise, they're
+	// only identified that way if the issuer's metadata clearly belongs to a draft 15 or later issuer (i.e. it has a
+	// nonce_endpoint, or its credential configurations don't have any of the claim display fields from earlier
+	// drafts). Otherwise, they're identified by their format and type, which is what earlier drafts expect.
+	UseCredentialConfigurationIDs bool
+	// Used to get key attestations for issuers that require them in key proofs. If not specified, then credentials
+	// can't be requested from such issuers.
+	KeyAttestationProvider api.KeyAttestationProvider
+	// Used to cache issuer and authorization server metadata across interactions. If not specified, then
+	// metadatacache.Default() is used.
+	MetadataCache *metadatacache.Cache
+	// Controls which issuer metadata is accepted. If not specified, then unsigned metadata is accepted, and signed
+	// metadata is accepted from any DID but never from an x5c certificate chain.
+	MetadataVerificationPolicy *MetadataVerificationPolicy
+}
+
+// MetadataVerificationPolicy controls which issuer metadata is accepted. Signed metadata is always rejected if its
+// signature is invalid.
+type MetadataVerificationPolicy struct {
+	// If set, then issuer metadata without a signed_metadata parameter is rejected.
+	RequireSignedMetadata bool
+	// If not empty, then signed metadata whose kid header is a DID URL is only accepted if the DID is in this list.
+	AllowedDIDs []string
+	// Used to verify the certificate chain of signed metadata that has an x5c header. If empty, then the x5c header
+	// is ignored, and the metadata is verified using its kid header like any other signed metadata.
+	TrustAnchors []*x509.Certificate
+}
+
+func (p *MetadataVerificationPolicy) toFetcherPolicy() *metadatafetcher.VerificationPolicy {
+	if p == nil {
+		return nil
+	}
+
+	return &metadatafetcher.VerificationPolicy{
+		RequireSignedMetadata: p.RequireSignedMetadata,
+		AllowedDIDs:           p.AllowedDIDs,
+		TrustAnchors:          p.TrustAnchors,
+	}
 }
 
 func validateRequiredParameters(config *ClientConfig) error {
@@ -81,4 +126,8 @@ func setDefaults(config *ClientConfig) {
 	if config.HTTPClient == nil {
 		config.HTTPClient = &http.Client{Timeout: api.DefaultHTTPTimeout}
 	}
+
+	if config.MetadataCache == nil {
+		config.MetadataCache = metadatacache.Default()
+	}
 }
@@ -11,6 +11,7 @@ type createAuthorizationURLOpts struct {
 	issuerState                        *string
 	useOAuthDiscoverableClientIDScheme bool
 	context                            []string
+	requirePushedAuthorizationRequest  bool
 }
 
 // CreateAuthorizationURLOpt is an option for the CreateAuthorizationURL method.
@@ -66,6 +67,17 @@ func WithCredentialContext(context []string) CreateAuthorizationURLOpt {
 	}
 }
 
+// WithPushedAuthorizationRequest is an option for the CreateAuthorizationURL method that requires the authorization
+// request to be pushed to the authorization server (RFC 9126). By default, a pushed authorization request is only made
+// if the authorization server advertises a pushed authorization request endpoint, and otherwise all the parameters
+// are put in the authorization URL. With this option, an error is returned instead if the authorization server
+// doesn't support pushed authorization requests.
+func WithPushedAuthorizationRequest() CreateAuthorizationURLOpt {
+	return func(opts *createAuthorizationURLOpts) {
+		opts.requirePushedAuthorizationRequest = true
+	}
+}
+
 func processCreateAuthorizationURLOpts(opts []CreateAuthorizationURLOpt) *createAuthorizationURLOpts {
 	processedOpts := &createAuthorizationURLOpts{}
 
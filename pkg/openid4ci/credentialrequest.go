@@ -0,0 +1,151 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"slices"
+
+	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
+)
+
+// newCredentialRequest creates a credential request (without a proof) for the credential with the given
+// configuration ID, format, types and context. configID may be empty if it isn't known.
+//
+// The credential is identified in one of three ways, depending on what the issuer supports:
+//  1. By credential_identifier, if the token response included credential identifiers for the credential.
+//  2. By credential_configuration_id, if the client was configured to use them (see
+//     ClientConfig.UseCredentialConfigurationIDs) or the issuer's metadata indicates that it implements draft 15 or
+//     later of the OpenID4VCI specification, and the credential's configuration ID is known.
+//  3. By format and credential_definition otherwise (or by format and vct or doctype for SD-JWT VCs and mdocs).
+func (i *interaction) newCredentialRequest(configID, credentialFormat string,
+	credentialTypes, credentialContext []string,
+) credentialRequest {
+	credentialIdentifiers := i.grantedCredentialIdentifiers(configID, credentialFormat, credentialTypes)
+
+	if len(credentialIdentifiers) > 0 {
+		return credentialRequest{CredentialIdentifier: credentialIdentifiers[0]}
+	}
+
+	if configID = i.credentialConfigurationIDToUse(configID, credentialFormat, credentialTypes); configID != "" {
+		return credentialRequest{CredentialConfigurationID: configID}
+	}
+
+	if isSDJWTVCFormat(credentialFormat) {
+		return credentialRequest{Format: credentialFormat, Vct: typeIdentifierFromTypes(credentialTypes)}
+	}
+
+	if credentialFormat == msoMdocCredentialFormat {
+		return credentialRequest{Format: credentialFormat, Doctype: typeIdentifierFromTypes(credentialTypes)}
+	}
+
+	var credentialContextToSend *[]string
+
+	if len(credentialContext) > 0 {
+		credentialContextToSend = &credentialContext
+	}
+
+	return credentialRequest{
+		CredentialDefinition: &credentialDefinition{
+			Type:    credentialTypes,
+			Context: credentialContextToSend,
+		},
+		Format: credentialFormat,
+	}
+}
+
+// grantedCredentialIdentifiers returns the credential identifiers that the token response's authorization_details
+// listed for the given credential, if any.
+func (i *interaction) grantedCredentialIdentifiers(configID, credentialFormat string,
+	credentialTypes []string,
+) []string {
+	var credentialIdentifiers []string
+
+	for _, details := range i.grantedAuthorizationDetails {
+		if details.grants(configID, credentialFormat, credentialTypes) {
+			credentialIdentifiers = append(credentialIdentifiers, details.CredentialIdentifiers...)
+		}
+	}
+
+	return credentialIdentifiers
+}
+
+// credentialConfigurationIDToUse returns the configuration ID to identify the given credential by, or an empty string
+// if it should be identified by its format and type instead. The configuration ID from the credential offer is
+// preferred. If it isn't known (e.g. in the wallet-initiated flow), then it's looked up in the issuer's metadata.
+func (i *interaction) credentialConfigurationIDToUse(configID, credentialFormat string,
+	credentialTypes []string,
+) string {
+	if !i.useCredentialConfigurationIDs && !implementsDraft15OrLater(i.issuerMetadata) {
+		return ""
+	}
+
+	if configID != "" {
+		return configID
+	}
+
+	return i.findCredentialConfigurationID(credentialFormat, credentialTypes)
+}
+
+// implementsDraft15OrLater indicates whether the given issuer metadata clearly belongs to an issuer that implements
+// draft 15 or later of the OpenID4VCI specification, and so expects credentials to be identified by their
+// credential_configuration_id. This is the case if the issuer has a nonce endpoint, which was introduced in draft 14,
+// or if none of its credential configurations use the claim display fields that draft 15 removed
+// (credential_definition.credentialSubject, order and the claims object).
+func implementsDraft15OrLater(metadata *issuer.Metadata) bool {
+	if metadata.NonceEndpoint != "" {
+		return true
+	}
+
+	if len(metadata.CredentialConfigurationsSupported) == 0 {
+		return false
+	}
+
+	for _, config := range metadata.CredentialConfigurationsSupported {
+		if hasLegacyClaimFields(config) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func hasLegacyClaimFields(config *issuer.CredentialConfigurationSupported) bool {
+	if config.CredentialDefinition != nil && config.CredentialDefinition.CredentialSubject != nil {
+		return true
+	}
+
+	return config.Order != nil || config.Claims != nil
+}
+
+// findCredentialConfigurationID returns the ID of the issuer's credential configuration with the given format
+// and types, or an empty string if there's no such configuration.
+func (i *interaction) findCredentialConfigurationID(credentialFormat string, credentialTypes []string) string {
+	for configID, config := range i.issuerMetadata.CredentialConfigurationsSupported {
+		if config.Format == credentialFormat && slices.Equal(config.CredentialTypes(), credentialTypes) {
+			return configID
+		}
+	}
+
+	return ""
+}
+
+// isSDJWTVCFormat indicates whether the given credential format is one of the IETF SD-JWT VC formats.
+// vc+sd-jwt is the identifier used by earlier drafts of the OpenID4VCI specification.
+func isSDJWTVCFormat(credentialFormat string) bool {
+	return credentialFormat == dcSDJWTCredentialFormat || credentialFormat == vcSDJWTCredentialFormat
+}
+
+// typeIdentifierFromTypes returns the vct or doctype value from the types of an SD-JWT VC or mdoc, which are
+// VerifiableCredential followed by the vct or doctype value
+// (see issuer.CredentialConfigurationSupported.CredentialTypes).
+func typeIdentifierFromTypes(credentialTypes []string) string {
+	if len(credentialTypes) == 0 {
+		return ""
+	}
+
+	return credentialTypes[len(credentialTypes)-1]
+}
@@ -0,0 +1,200 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+func TestIssuerInitiatedInteraction_CredentialRequestShape(t *testing.T) {
+	t.Run("Credential identifiers in the token response", func(t *testing.T) {
+		handler, server, issuanceURI := newMultipleCredentialsServer(t)
+		defer server.Close()
+
+		handler.grantedAuthorizationDetails = `[{"type":"openid_credential",` +
+			`"credential_configuration_id":"credential_configuration_id_1",` +
+			`"credential_identifiers":["employee-1","employee-2"]}]`
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI)
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+
+		require.Len(t, handler.credentialRequests, 1)
+		require.Equal(t, "employee-1", handler.credentialRequests[0].CredentialIdentifier)
+		require.Empty(t, handler.credentialRequests[0].CredentialConfigurationID)
+		require.Empty(t, handler.credentialRequests[0].Format)
+		require.Nil(t, handler.credentialRequests[0].CredentialDefinition)
+
+		require.Equal(t, []string{"credential_configuration_id_1"}, interaction.GrantedCredentialConfigIDs())
+		require.Equal(t, []string{"employee-1", "employee-2"},
+			interaction.GrantedCredentialIdentifiers("credential_configuration_id_1"))
+		require.Nil(t, interaction.GrantedCredentialIdentifiers(secondCredentialConfigID))
+		require.Nil(t, interaction.GrantedCredentialIdentifiers("unknown"))
+	})
+	t.Run("Client uses credential configuration IDs", func(t *testing.T) {
+		handler, server, issuanceURI := newMultipleCredentialsServer(t)
+		defer server.Close()
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI, useCredentialConfigurationIDs())
+
+		authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+		require.NoError(t, err)
+
+		requestedAuthorizationDetails := authorizationDetailsFromAuthURL(t, authURL)
+		require.Len(t, requestedAuthorizationDetails, 2)
+		require.Equal(t, "credential_configuration_id_1",
+			requestedAuthorizationDetails[0]["credential_configuration_id"])
+		require.Equal(t, secondCredentialConfigID, requestedAuthorizationDetails[1]["credential_configuration_id"])
+		require.NotContains(t, requestedAuthorizationDetails[0], "format")
+		require.NotContains(t, requestedAuthorizationDetails[0], "credential_definition")
+
+		credentials, err := interaction.RequestCredentialWithAuth(&jwtSignerMock{keyID: mockKeyID},
+			"redirectURI?code=1234&state="+getStateFromAuthURL(t, authURL))
+		require.NoError(t, err)
+		require.Len(t, credentials, 2)
+
+		require.Len(t, handler.credentialRequests, 2)
+		require.Equal(t, "credential_configuration_id_1", handler.credentialRequests[0].CredentialConfigurationID)
+		require.Equal(t, secondCredentialConfigID, handler.credentialRequests[1].CredentialConfigurationID)
+		require.Empty(t, handler.credentialRequestTypes())
+	})
+	t.Run("Issuer metadata has a nonce endpoint", func(t *testing.T) {
+		handler, server, issuanceURI := newMultipleCredentialsServer(t)
+		defer server.Close()
+
+		// Nonce endpoints were introduced in draft 14, so configuration IDs are used even though the credential
+		// configurations still have fields from earlier drafts.
+		handler.issuerMetadata = modifyCredentialMetadata(t, handler.issuerMetadata, func(m *issuer.Metadata) {
+			m.NonceEndpoint = server.URL + "/oidc/nonce"
+		})
+
+		requireCredentialConfigurationIDRequests(t, handler, issuanceURI)
+	})
+	t.Run("Issuer metadata has no legacy credential configuration fields", func(t *testing.T) {
+		handler, server, issuanceURI := newMultipleCredentialsServer(t)
+		defer server.Close()
+
+		handler.issuerMetadata = modifyCredentialMetadata(t, handler.issuerMetadata, func(m *issuer.Metadata) {
+			for _, config := range m.CredentialConfigurationsSupported {
+				config.CredentialDefinition.CredentialSubject = nil
+				config.Order = nil
+				config.Claims = nil
+			}
+		})
+
+		requireCredentialConfigurationIDRequests(t, handler, issuanceURI)
+	})
+	t.Run("Legacy credential requests", func(t *testing.T) {
+		handler, server, issuanceURI := newMultipleCredentialsServer(t)
+		defer server.Close()
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI)
+
+		authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+		require.NoError(t, err)
+
+		requestedAuthorizationDetails := authorizationDetailsFromAuthURL(t, authURL)
+		require.Len(t, requestedAuthorizationDetails, 2)
+		require.Equal(t, "jwt_vc_json", requestedAuthorizationDetails[0]["format"])
+		require.NotContains(t, requestedAuthorizationDetails[0], "credential_configuration_id")
+
+		_, err = interaction.RequestCredentialWithAuth(&jwtSignerMock{keyID: mockKeyID},
+			"redirectURI?code=1234&state="+getStateFromAuthURL(t, authURL))
+		require.NoError(t, err)
+
+		require.Len(t, handler.credentialRequests, 2)
+		require.Equal(t, "jwt_vc_json", handler.credentialRequests[0].Format)
+		require.Empty(t, handler.credentialRequests[0].CredentialConfigurationID)
+		require.Empty(t, handler.credentialRequests[0].CredentialIdentifier)
+	})
+}
+
+func TestWalletInitiatedInteraction_CredentialConfigurationIDRequest(t *testing.T) {
+	handler, server, _ := newMultipleCredentialsServer(t)
+	defer server.Close()
+
+	config := getTestClientConfig(t)
+	useCredentialConfigurationIDs()(config)
+
+	interaction, err := openid4ci.NewWalletInitiatedInteraction(server.URL, config)
+	require.NoError(t, err)
+
+	authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI", "jwt_vc_json",
+		[]string{"VerifiableCredential", "UniversityDegreeCredential"})
+	require.NoError(t, err)
+
+	requestedAuthorizationDetails := authorizationDetailsFromAuthURL(t, authURL)
+	require.Len(t, requestedAuthorizationDetails, 1)
+	require.Equal(t, secondCredentialConfigID, requestedAuthorizationDetails[0]["credential_configuration_id"])
+
+	credentials, err := interaction.RequestCredential(&jwtSignerMock{keyID: mockKeyID},
+		"redirectURI?code=1234&state="+getStateFromAuthURL(t, authURL))
+	require.NoError(t, err)
+	require.Len(t, credentials, 1)
+
+	// The credential configuration ID is looked up in the issuer's metadata using the format and types.
+	require.Len(t, handler.credentialRequests, 1)
+	require.Equal(t, secondCredentialConfigID, handler.credentialRequests[0].CredentialConfigurationID)
+}
+
+// requireCredentialConfigurationIDRequests checks that credentials are identified by their credential configuration
+// IDs in both the authorization request and the credential requests, even though the client wasn't configured to use
+// them.
+func requireCredentialConfigurationIDRequests(t *testing.T, handler *mockAuthorizationDetailsHandler,
+	issuanceURI string,
+) {
+	t.Helper()
+
+	interaction := newIssuerInitiatedInteraction(t, issuanceURI)
+
+	authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+	require.NoError(t, err)
+
+	requestedAuthorizationDetails := authorizationDetailsFromAuthURL(t, authURL)
+	require.Len(t, requestedAuthorizationDetails, 2)
+	require.Equal(t, "credential_configuration_id_1", requestedAuthorizationDetails[0]["credential_configuration_id"])
+	require.NotContains(t, requestedAuthorizationDetails[0], "format")
+
+	_, err = interaction.RequestCredentialWithAuth(&jwtSignerMock{keyID: mockKeyID},
+		"redirectURI?code=1234&state="+getStateFromAuthURL(t, authURL))
+	require.NoError(t, err)
+
+	require.Len(t, handler.credentialRequests, 2)
+	require.Equal(t, "credential_configuration_id_1", handler.credentialRequests[0].CredentialConfigurationID)
+	require.Equal(t, secondCredentialConfigID, handler.credentialRequests[1].CredentialConfigurationID)
+	require.Empty(t, handler.credentialRequestTypes())
+}
+
+func useCredentialConfigurationIDs() clientConfigOpt {
+	return func(config *openid4ci.ClientConfig) {
+		config.UseCredentialConfigurationIDs = true
+	}
+}
+
+func authorizationDetailsFromAuthURL(t *testing.T, authURL string) []map[string]interface{} {
+	t.Helper()
+
+	parsedAuthURL, err := url.Parse(authURL)
+	require.NoError(t, err)
+
+	var authorizationDetails []map[string]interface{}
+
+	require.NoError(t, json.Unmarshal([]byte(parsedAuthURL.Query().Get("authorization_details")),
+		&authorizationDetails))
+
+	return authorizationDetails
+}
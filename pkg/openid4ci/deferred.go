@@ -0,0 +1,410 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/trustbloc/vc-go/verifiable"
+
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/internal/httprequest"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+// The amount of time to wait between polls of the deferred credential endpoint if the issuer doesn't say how long to
+// wait (interval is optional in issuance_pending responses).
+const defaultDeferredPollingInterval = 5 * time.Second
+
+const (
+	//nolint:gosec //false positive
+	requestDeferredCredentialEventText = "Request deferred credential from issuer"
+	//nolint:gosec //false positive
+	fetchDeferredCredentialViaPOSTReqEventText = "Fetch deferred credential via an HTTP POST request to %s"
+)
+
+// PendingIssuance represents a credential that the issuer was unable to issue immediately. The issuer returned a
+// transaction ID instead, which can be redeemed later at the issuer's deferred credential endpoint.
+// A PendingIssuance can be serialized (e.g. with json.Marshal) and stored so that the credential can be requested
+// after the interaction object has been discarded. See RequestDeferredCredential.
+type PendingIssuance struct {
+	TransactionID              string     `json:"transaction_id"`
+	CredentialConfigurationID  string     `json:"credential_configuration_id,omitempty"`
+	IssuerURI                  string     `json:"issuer_uri"`
+	DeferredCredentialEndpoint string     `json:"deferred_credential_endpoint"`
+	AuthToken                  *AuthToken `json:"auth_token,omitempty"`
+	// The ID of the key that the credential will be bound to. Used to check the key binding of SD-JWT VCs.
+	HolderKeyID string `json:"holder_key_id,omitempty"`
+	// The minimum amount of time in seconds that the wallet should wait before polling the deferred credential
+	// endpoint. Updated whenever the issuer responds with an issuance_pending error.
+	Interval int `json:"interval,omitempty"`
+	// The issuer's notification endpoint (if any), and the details needed to refresh the access token when sending
+	// notifications. See the Acknowledgment method.
+	NotificationEndpoint string `json:"notification_endpoint,omitempty"`
+	TokenEndpoint        string `json:"token_endpoint,omitempty"`
+	ClientID             string `json:"client_id,omitempty"`
+	// The notification ID that the issuer returned along with the credential. Set once the credential has been issued.
+	NotificationID string `json:"notification_id,omitempty"`
+}
+
+// Acknowledgment returns an Acknowledgment that can be used to notify the issuer about the deferred credential once
+// it has been issued, using the NotificationID of this PendingIssuance. It returns an error if the issuer doesn't
+// have a notification endpoint or didn't return a notification ID for the credential.
+func (p *PendingIssuance) Acknowledgment() (*Acknowledgment, error) {
+	if p.NotificationEndpoint == "" {
+		return nil, errors.New("issuer not support credential acknowledgement")
+	}
+
+	if p.NotificationID == "" {
+		return nil, errors.New("no notification ID: the issuer didn't return one, or the credential hasn't been " +
+			"issued yet")
+	}
+
+	return &Acknowledgment{
+		AckIDs:                []string{p.NotificationID},
+		CredentialAckEndpoint: p.NotificationEndpoint,
+		IssuerURI:             p.IssuerURI,
+		TokenEndpoint:         p.TokenEndpoint,
+		ClientID:              p.ClientID,
+		AuthToken:             p.AuthToken,
+	}, nil
+}
+
+type requestDeferredCredentialOpts struct {
+	pollingTimeout time.Duration
+	dpopSigner     api.JWTSigner
+}
+
+// RequestDeferredCredentialOpt is an option for the RequestDeferredCredential function.
+type RequestDeferredCredentialOpt func(opts *requestDeferredCredentialOpts)
+
+// WithPollingTimeout is an option for the RequestDeferredCredential function that causes it to keep polling the
+// deferred credential endpoint (honouring the interval returned by the issuer) for up to the given duration while
+// the issuer reports that issuance is still pending. By default, only a single request is made.
+func WithPollingTimeout(timeout time.Duration) RequestDeferredCredentialOpt {
+	return func(opts *requestDeferredCredentialOpts) {
+		opts.pollingTimeout = timeout
+	}
+}
+
+// WithDPoPSigner is an option for the RequestDeferredCredential function that sets the signer to use for DPoP
+// proofs. It's required if the pending issuance's access token is DPoP-bound, in which case it must be the same
+// signer that was used to request the credential originally.
+func WithDPoPSigner(signer api.JWTSigner) RequestDeferredCredentialOpt {
+	return func(opts *requestDeferredCredentialOpts) {
+		opts.dpopSigner = signer
+	}
+}
+
+func processRequestDeferredCredentialOpts(opts []RequestDeferredCredentialOpt) *requestDeferredCredentialOpts {
+	processedOpts := &requestDeferredCredentialOpts{}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(processedOpts)
+		}
+	}
+
+	return processedOpts
+}
+
+// RequestDeferredCredential requests a credential whose issuance was previously deferred by the issuer.
+// If the issuer responds that the credential is still not ready, then a CredentialIssuancePendingError is returned
+// (with the ISSUANCE_PENDING category) and the Interval field of the given PendingIssuance is updated with the value
+// provided by the issuer. The caller should then try again later.
+// If the WithPollingTimeout option is used, then the endpoint will be polled until either the credential is
+// received, a different error occurs, or waiting for the next attempt would exceed the timeout. If the issuer doesn't
+// say how long to wait between attempts, then it's polled every 5 seconds.
+// If the issuer returns more than one credential, then an error is returned. Use RequestDeferredCredentials to
+// receive all of them.
+func RequestDeferredCredential(pendingIssuance *PendingIssuance, config *ClientConfig,
+	opts ...RequestDeferredCredentialOpt,
+) (*verifiable.Credential, error) {
+	return RequestDeferredCredentialContext(context.Background(), pendingIssuance, config, opts...)
+}
+
+// RequestDeferredCredentialContext is the same as RequestDeferredCredential, but uses the given context for the
+// requests to the issuer's metadata and deferred credential endpoints. Polling stops as soon as the context is done,
+// or if waiting for the next attempt would go past the context's deadline.
+func RequestDeferredCredentialContext(ctx context.Context, pendingIssuance *PendingIssuance, config *ClientConfig,
+	opts ...RequestDeferredCredentialOpt,
+) (*verifiable.Credential, error) {
+	credentials, err := RequestDeferredCredentialsContext(ctx, pendingIssuance, config, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(credentials) > 1 {
+		return nil, walleterror.NewInvalidSDKUsageError(ErrorModule,
+			fmt.Errorf("the issuer returned %d credentials, but only one was expected: use "+
+				"RequestDeferredCredentials to receive all of them", len(credentials)))
+	}
+
+	return credentials[0], nil
+}
+
+// RequestDeferredCredentials is the same as RequestDeferredCredential, but returns all the credentials that the
+// issuer returns, which may be several (e.g. copies of the same credential bound to different keys).
+func RequestDeferredCredentials(pendingIssuance *PendingIssuance, config *ClientConfig,
+	opts ...RequestDeferredCredentialOpt,
+) ([]*verifiable.Credential, error) {
+	return RequestDeferredCredentialsContext(context.Background(), pendingIssuance, config, opts...)
+}
+
+// RequestDeferredCredentialsContext is the same as RequestDeferredCredentials, but uses the given context in the
+// same way as RequestDeferredCredentialContext.
+//
+//nolint:gocyclo
+func RequestDeferredCredentialsContext(ctx context.Context, pendingIssuance *PendingIssuance, config *ClientConfig,
+	opts ...RequestDeferredCredentialOpt,
+) ([]*verifiable.Credential, error) {
+	err := validateRequiredParameters(config)
+	if err != nil {
+		return nil, walleterror.NewInvalidSDKUsageError(ErrorModule, err)
+	}
+
+	err = validatePendingIssuance(pendingIssuance)
+	if err != nil {
+		return nil, walleterror.NewInvalidSDKUsageError(ErrorModule, err)
+	}
+
+	setDefaults(config)
+
+	processedOpts := processRequestDeferredCredentialOpts(opts)
+
+	deferredInteraction := newInteraction(pendingIssuance.IssuerURI, config)
+	deferredInteraction.clientID = pendingIssuance.ClientID
+
+	err = deferredInteraction.enableDPoP(processedOpts.dpopSigner, pendingIssuance.AuthToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// The issuer's metadata is needed to know whether the deferred credential response must be encrypted. It's
+	// loaded after DPoP is enabled so that DPoP is only used if the pending issuance's access token is DPoP-bound.
+	err = deferredInteraction.populateIssuerMetadata(ctx, requestDeferredCredentialEventText)
+	if err != nil {
+		return nil, err
+	}
+
+	pollingDeadline := time.Now().Add(processedOpts.pollingTimeout)
+
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(pollingDeadline) {
+		pollingDeadline = ctxDeadline
+	}
+
+	for {
+		credentialResponse, err := deferredInteraction.getDeferredCredentialResponse(ctx, pendingIssuance)
+		if err == nil {
+			pendingIssuance.NotificationID = credentialResponse.AckID
+
+			return deferredInteraction.getVCsFromDeferredCredentialResponse(credentialResponse,
+				pendingIssuance.HolderKeyID)
+		}
+
+		pendingErr := &CredentialIssuancePendingError{}
+		if !errors.As(err, &pendingErr) {
+			return nil, err
+		}
+
+		pendingIssuance.Interval = pendingErr.Interval
+
+		wait := time.Duration(pendingErr.Interval) * time.Second
+		if wait <= 0 {
+			wait = defaultDeferredPollingInterval
+		}
+
+		if time.Now().Add(wait).After(pollingDeadline) {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func validatePendingIssuance(pendingIssuance *PendingIssuance) error {
+	switch {
+	case pendingIssuance == nil:
+		return errors.New("pending issuance object must be provided")
+	case pendingIssuance.TransactionID == "":
+		return errors.New("pending issuance is missing a transaction ID")
+	case pendingIssuance.DeferredCredentialEndpoint == "":
+		return errors.New("pending issuance is missing the deferred credential endpoint")
+	case pendingIssuance.AuthToken == nil:
+		return errors.New("pending issuance is missing an access token")
+	default:
+		return nil
+	}
+}
+
+func (i *interaction) getDeferredCredentialResponse(ctx context.Context, pendingIssuance *PendingIssuance,
+) (*CredentialResponse, error) {
+	responseEncryption, err := i.credentialResponseEncryptionParams()
+	if err != nil {
+		return nil, err
+	}
+
+	requestBody, err := json.Marshal(&deferredCredentialRequest{
+		TransactionID:                pendingIssuance.TransactionID,
+		CredentialResponseEncryption: responseEncryption,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	headers := http.Header{}
+	headers.Add("Authorization",
+		authorizationHeader(pendingIssuance.AuthToken.TokenType, pendingIssuance.AuthToken.AccessToken))
+
+	responseBytes, err := httprequest.New(i.httpClient, i.metricsLogger).DoContext(ctx,
+		http.MethodPost, pendingIssuance.DeferredCredentialEndpoint, "application/json", headers,
+		bytes.NewReader(requestBody),
+		fmt.Sprintf(fetchDeferredCredentialViaPOSTReqEventText, pendingIssuance.DeferredCredentialEndpoint),
+		requestDeferredCredentialEventText, []int{http.StatusOK, http.StatusCreated},
+		processDeferredCredentialErrorResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	responseBytes, err = i.decryptCredentialResponse(responseBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	var credentialResponse CredentialResponse
+
+	err = json.Unmarshal(responseBytes, &credentialResponse)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response from the issuer's deferred credential endpoint: %w", err)
+	}
+
+	return &credentialResponse, nil
+}
+
+func (i *interaction) getVCsFromDeferredCredentialResponse(
+	credentialResponse *CredentialResponse, holderKeyID string,
+) ([]*verifiable.Credential, error) {
+	timeStartParseCredential := time.Now()
+
+	credentialResponses := credentialResponse.split()
+	if len(credentialResponses) == 0 {
+		return nil, walleterror.NewExecutionError(
+			ErrorModule,
+			CredentialParseFailedCode,
+			CredentialParseError,
+			errors.New("the response from the issuer's deferred credential endpoint contains no credentials"))
+	}
+
+	vcs, err := i.getVCsFromCredentialResponses(credentialResponses, holderKeyID)
+	if err != nil {
+		return nil, walleterror.NewExecutionError(
+			ErrorModule,
+			CredentialParseFailedCode,
+			CredentialParseError, err)
+	}
+
+	err = i.metricsLogger.Log(&api.MetricsEvent{
+		Event:    requestDeferredCredentialEventText,
+		Duration: time.Since(timeStartParseCredential),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return vcs, nil
+}
+
+func processDeferredCredentialErrorResponse(statusCode int, respBytes []byte) error {
+	var errResponse errorResponse
+
+	err := json.Unmarshal(respBytes, &errResponse)
+	if err != nil {
+		return processCredentialErrorResponse(statusCode, respBytes)
+	}
+
+	detailedErr := fmt.Errorf("received status code [%d] with body [%s] from issuer's deferred credential endpoint",
+		statusCode, string(respBytes))
+
+	switch errResponse.Error {
+	case "issuance_pending":
+		return NewCredentialIssuancePendingError(
+			walleterror.NewExecutionError(ErrorModule,
+				IssuancePendingCode,
+				IssuancePendingError,
+				detailedErr,
+				walleterror.WithServerErrorCode(errResponse.Error),
+				walleterror.WithServerErrorMessage(errResponse.ErrorDescription)),
+			errResponse.Interval)
+	case "invalid_transaction_id":
+		return walleterror.NewExecutionError(ErrorModule,
+			InvalidTransactionIDCode,
+			InvalidTransactionIDError,
+			detailedErr,
+			walleterror.WithServerErrorCode(errResponse.Error),
+			walleterror.WithServerErrorMessage(errResponse.ErrorDescription))
+	default:
+		return processCredentialErrorResponse(statusCode, respBytes)
+	}
+}
+
+// splitCredentialResponses separates the credential responses containing an issued credential from the ones
+// that the issuer deferred. A PendingIssuance object is created for each deferred response.
+// configIDs is optional, but if set it must be the same length as credentialResponses.
+func (i *interaction) splitCredentialResponses(credentialResponses []CredentialResponse, configIDs []string,
+	authToken *AuthToken, holderKeyID string,
+) ([]CredentialResponse, []*PendingIssuance, error) {
+	var (
+		issuedResponses  []CredentialResponse
+		pendingIssuances []*PendingIssuance
+	)
+
+	for index := range credentialResponses {
+		if !credentialResponses[index].deferred() {
+			issuedResponses = append(issuedResponses, credentialResponses[index])
+
+			continue
+		}
+
+		if i.issuerMetadata.DeferredCredentialEndpoint == "" {
+			return nil, nil, walleterror.NewExecutionError(ErrorModule,
+				DeferredIssuanceNotSupportedCode,
+				DeferredIssuanceNotSupportedError,
+				fmt.Errorf("issuer deferred the issuance of credential %d, but its metadata does not specify "+
+					"a deferred credential endpoint", index+1))
+		}
+
+		pendingIssuance := &PendingIssuance{
+			TransactionID:              credentialResponses[index].TransactionID,
+			IssuerURI:                  i.issuerURI,
+			DeferredCredentialEndpoint: i.issuerMetadata.DeferredCredentialEndpoint,
+			AuthToken:                  authToken,
+			HolderKeyID:                holderKeyID,
+			Interval:                   credentialResponses[index].Interval,
+			NotificationEndpoint:       i.issuerMetadata.NotificationEndpoint,
+			TokenEndpoint:              i.issuerMetadata.TokenEndpoint,
+			ClientID:                   i.clientID,
+		}
+
+		if len(configIDs) == len(credentialResponses) {
+			pendingIssuance.CredentialConfigurationID = configIDs[index]
+		}
+
+		pendingIssuances = append(pendingIssuances, pendingIssuance)
+	}
+
+	return issuedResponses, pendingIssuances, nil
+}
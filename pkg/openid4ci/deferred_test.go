@@ -0,0 +1,318 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+const sampleDeferredCredentialResponse = `{"transaction_id":"8xLOxBtZp8","interval":3}`
+
+type mockDeferredCredentialHandler struct {
+	t                     *testing.T
+	pendingResponsesLeft  int
+	interval              int
+	errorResponse         string
+	credentialResponse    []byte
+	receivedTransactionID string
+	receivedAuthorization string
+	requestCount          int
+}
+
+func (m *mockDeferredCredentialHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	var deferredRequest map[string]interface{}
+
+	err := json.NewDecoder(request.Body).Decode(&deferredRequest)
+	assert.NoError(m.t, err)
+
+	m.receivedTransactionID, _ = deferredRequest["transaction_id"].(string) //nolint:errcheck // checked by tests
+	m.receivedAuthorization = request.Header.Get("Authorization")
+	m.requestCount++
+
+	switch {
+	case m.errorResponse != "":
+		writer.WriteHeader(http.StatusBadRequest)
+		_, err = writer.Write([]byte(m.errorResponse))
+	case m.pendingResponsesLeft > 0:
+		m.pendingResponsesLeft--
+
+		writer.WriteHeader(http.StatusBadRequest)
+
+		pendingResponse := map[string]interface{}{"error": "issuance_pending"}
+
+		// The interval is optional, so it's left out unless set.
+		if m.interval != 0 {
+			pendingResponse["interval"] = m.interval
+		}
+
+		var body []byte
+
+		body, err = json.Marshal(pendingResponse)
+		assert.NoError(m.t, err)
+
+		_, err = writer.Write(body)
+	default:
+		_, err = writer.Write(m.credentialResponse)
+	}
+
+	assert.NoError(m.t, err)
+}
+
+func TestIssuerInitiatedInteraction_DeferredIssuance(t *testing.T) {
+	deferredHandler := &mockDeferredCredentialHandler{t: t, credentialResponse: sampleCredentialResponse}
+
+	deferredServer := httptest.NewServer(deferredHandler)
+	defer deferredServer.Close()
+
+	issuerServerHandler := &mockIssuerServerHandler{
+		t:                  t,
+		credentialResponse: []byte(sampleDeferredCredentialResponse),
+	}
+
+	server := httptest.NewServer(issuerServerHandler)
+	defer server.Close()
+
+	issuerMetadata := modifyCredentialMetadata(t, sampleIssuerMetadata, func(m *issuer.Metadata) {
+		m.DeferredCredentialEndpoint = deferredServer.URL
+	})
+
+	issuerServerHandler.issuerMetadata = strings.ReplaceAll(issuerMetadata, serverURLPlaceholder, server.URL)
+
+	interaction := newIssuerInitiatedInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false, true))
+
+	credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+		openid4ci.WithPIN("1234"))
+	require.NoError(t, err)
+	require.Empty(t, credentials)
+
+	pendingIssuances := interaction.PendingIssuances()
+	require.Len(t, pendingIssuances, 1)
+	require.Equal(t, "8xLOxBtZp8", pendingIssuances[0].TransactionID)
+	require.Equal(t, "credential_configuration_id_1", pendingIssuances[0].CredentialConfigurationID)
+	require.Equal(t, deferredServer.URL, pendingIssuances[0].DeferredCredentialEndpoint)
+	require.Equal(t, 3, pendingIssuances[0].Interval)
+
+	// The pending issuance must survive a round trip through storage.
+	pendingIssuanceBytes, err := json.Marshal(pendingIssuances[0])
+	require.NoError(t, err)
+
+	var pendingIssuance openid4ci.PendingIssuance
+
+	require.NoError(t, json.Unmarshal(pendingIssuanceBytes, &pendingIssuance))
+
+	t.Run("Credential is ready", func(t *testing.T) {
+		credential, err := openid4ci.RequestDeferredCredential(&pendingIssuance, getTestClientConfig(t))
+		require.NoError(t, err)
+		require.NotNil(t, credential)
+		require.Equal(t, "8xLOxBtZp8", deferredHandler.receivedTransactionID)
+		require.True(t, strings.HasPrefix(deferredHandler.receivedAuthorization, "Bearer "))
+	})
+	t.Run("Notification about the issued credential", func(t *testing.T) {
+		_, err := pendingIssuance.Acknowledgment()
+		require.ErrorContains(t, err, "no notification ID")
+
+		deferredHandler.credentialResponse = sampleCredentialResponseAsk
+		defer func() { deferredHandler.credentialResponse = sampleCredentialResponse }()
+
+		credential, err := openid4ci.RequestDeferredCredential(&pendingIssuance, getTestClientConfig(t))
+		require.NoError(t, err)
+		require.NotNil(t, credential)
+		require.Equal(t, "ack_id1", pendingIssuance.NotificationID)
+
+		acknowledgment, err := pendingIssuance.Acknowledgment()
+		require.NoError(t, err)
+		require.Equal(t, []string{"ack_id1"}, acknowledgment.AckIDs)
+		require.Equal(t, server.URL+"/oidc/ack_endpoint", acknowledgment.CredentialAckEndpoint)
+
+		issuerServerHandler.ackRequestExpectedCalls = 1
+
+		err = acknowledgment.NotifyIssuer("ack_id1", openid4ci.EventStatusCredentialAccepted, "", &http.Client{})
+		require.NoError(t, err)
+		require.Zero(t, issuerServerHandler.ackRequestExpectedCalls)
+	})
+	t.Run("Issuance pending", func(t *testing.T) {
+		deferredHandler.pendingResponsesLeft = 1
+		deferredHandler.interval = 60
+
+		credential, err := openid4ci.RequestDeferredCredential(&pendingIssuance, getTestClientConfig(t))
+		require.ErrorContains(t, err, "ISSUANCE_PENDING(OCI1-0023)")
+		require.Nil(t, credential)
+
+		pendingErr := &openid4ci.CredentialIssuancePendingError{}
+		require.ErrorAs(t, err, &pendingErr)
+		require.Equal(t, 60, pendingErr.Interval)
+		require.Equal(t, 60, pendingIssuance.Interval)
+	})
+	t.Run("Polling until the credential is ready", func(t *testing.T) {
+		deferredHandler.pendingResponsesLeft = 1
+		deferredHandler.interval = 1
+
+		credential, err := openid4ci.RequestDeferredCredential(&pendingIssuance, getTestClientConfig(t),
+			openid4ci.WithPollingTimeout(3*time.Second))
+		require.NoError(t, err)
+		require.NotNil(t, credential)
+		require.Zero(t, deferredHandler.pendingResponsesLeft)
+	})
+	t.Run("Issuer leaves out the polling interval", func(t *testing.T) {
+		deferredHandler.pendingResponsesLeft = 100
+		deferredHandler.interval = 0
+		deferredHandler.requestCount = 0
+
+		defer func() { deferredHandler.pendingResponsesLeft = 0 }()
+
+		// The default interval is longer than the timeout, so only one request is made instead of the endpoint
+		// being polled continuously until the timeout.
+		credential, err := openid4ci.RequestDeferredCredential(&pendingIssuance, getTestClientConfig(t),
+			openid4ci.WithPollingTimeout(2*time.Second))
+		require.ErrorContains(t, err, "ISSUANCE_PENDING(OCI1-0023)")
+		require.Nil(t, credential)
+		require.Equal(t, 1, deferredHandler.requestCount)
+		require.Zero(t, pendingIssuance.Interval)
+	})
+	t.Run("Issuer returns multiple credentials", func(t *testing.T) {
+		var credentialResponse struct {
+			Credential string `json:"credential"`
+		}
+
+		require.NoError(t, json.Unmarshal(sampleCredentialResponse, &credentialResponse))
+
+		multiCredentialResponse, err := json.Marshal(map[string]interface{}{
+			"credentials": []map[string]string{
+				{"credential": credentialResponse.Credential},
+				{"credential": credentialResponse.Credential},
+			},
+			"notification_id": "ack_id2",
+		})
+		require.NoError(t, err)
+
+		deferredHandler.credentialResponse = multiCredentialResponse
+		defer func() { deferredHandler.credentialResponse = sampleCredentialResponse }()
+
+		credentials, err := openid4ci.RequestDeferredCredentials(&pendingIssuance, getTestClientConfig(t))
+		require.NoError(t, err)
+		require.Len(t, credentials, 2)
+		require.Equal(t, "ack_id2", pendingIssuance.NotificationID)
+
+		// The extra credential mustn't be silently dropped.
+		credential, err := openid4ci.RequestDeferredCredential(&pendingIssuance, getTestClientConfig(t))
+		require.ErrorContains(t, err, "the issuer returned 2 credentials, but only one was expected")
+		require.Nil(t, credential)
+	})
+	t.Run("Issuer returns no credentials", func(t *testing.T) {
+		deferredHandler.credentialResponse = []byte(`{"credentials":[]}`)
+		defer func() { deferredHandler.credentialResponse = sampleCredentialResponse }()
+
+		credentials, err := openid4ci.RequestDeferredCredentials(&pendingIssuance, getTestClientConfig(t))
+		require.ErrorContains(t, err, "CREDENTIAL_PARSE_FAILED")
+		require.ErrorContains(t, err, "contains no credentials")
+		require.Nil(t, credentials)
+	})
+	t.Run("Polling stops when the context is cancelled", func(t *testing.T) {
+		deferredHandler.pendingResponsesLeft = 5
+		deferredHandler.interval = 1
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(100*time.Millisecond, cancel)
+
+		credential, err := openid4ci.RequestDeferredCredentialContext(ctx, &pendingIssuance, getTestClientConfig(t),
+			openid4ci.WithPollingTimeout(time.Minute))
+		require.ErrorIs(t, err, context.Canceled)
+		require.Nil(t, credential)
+
+		deferredHandler.pendingResponsesLeft = 0
+	})
+	t.Run("Invalid transaction ID", func(t *testing.T) {
+		deferredHandler.errorResponse = `{"error":"invalid_transaction_id"}`
+		defer func() { deferredHandler.errorResponse = "" }()
+
+		credential, err := openid4ci.RequestDeferredCredential(&pendingIssuance, getTestClientConfig(t),
+			openid4ci.WithPollingTimeout(time.Second))
+		require.ErrorContains(t, err, "INVALID_TRANSACTION_ID(OCI1-0024)")
+		require.Nil(t, credential)
+	})
+	t.Run("Other error", func(t *testing.T) {
+		deferredHandler.errorResponse = `{"error":"invalid_token"}`
+		defer func() { deferredHandler.errorResponse = "" }()
+
+		credential, err := openid4ci.RequestDeferredCredential(&pendingIssuance, getTestClientConfig(t))
+		require.ErrorContains(t, err, "INVALID_TOKEN(OCI1-0015)")
+		require.Nil(t, credential)
+	})
+}
+
+func TestIssuerInitiatedInteraction_DeferredIssuanceNotSupported(t *testing.T) {
+	issuerServerHandler := &mockIssuerServerHandler{
+		t:                  t,
+		credentialResponse: []byte(sampleDeferredCredentialResponse),
+	}
+
+	server := httptest.NewServer(issuerServerHandler)
+	defer server.Close()
+
+	issuerServerHandler.issuerMetadata = strings.ReplaceAll(sampleIssuerMetadata, serverURLPlaceholder, server.URL)
+
+	interaction := newIssuerInitiatedInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false, true))
+
+	credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+		openid4ci.WithPIN("1234"))
+	require.ErrorContains(t, err, "DEFERRED_ISSUANCE_NOT_SUPPORTED(OCI1-0025)")
+	require.Nil(t, credentials)
+}
+
+func TestRequestDeferredCredential_InvalidArgs(t *testing.T) {
+	validPendingIssuance := func() *openid4ci.PendingIssuance {
+		var pendingIssuance openid4ci.PendingIssuance
+
+		err := json.Unmarshal([]byte(`{"transaction_id":"tx","deferred_credential_endpoint":"https://example.com",`+
+			`"auth_token":{"access_token":"token"}}`), &pendingIssuance)
+		require.NoError(t, err)
+
+		return &pendingIssuance
+	}
+
+	t.Run("Missing client config", func(t *testing.T) {
+		_, err := openid4ci.RequestDeferredCredential(validPendingIssuance(), nil)
+		require.ErrorContains(t, err, "no client config provided")
+	})
+	t.Run("Missing pending issuance", func(t *testing.T) {
+		_, err := openid4ci.RequestDeferredCredential(nil, getTestClientConfig(t))
+		require.ErrorContains(t, err, "pending issuance object must be provided")
+	})
+	t.Run("Missing transaction ID", func(t *testing.T) {
+		pendingIssuance := validPendingIssuance()
+		pendingIssuance.TransactionID = ""
+
+		_, err := openid4ci.RequestDeferredCredential(pendingIssuance, getTestClientConfig(t))
+		require.ErrorContains(t, err, "pending issuance is missing a transaction ID")
+	})
+	t.Run("Missing endpoint", func(t *testing.T) {
+		pendingIssuance := validPendingIssuance()
+		pendingIssuance.DeferredCredentialEndpoint = ""
+
+		_, err := openid4ci.RequestDeferredCredential(pendingIssuance, getTestClientConfig(t))
+		require.ErrorContains(t, err, "pending issuance is missing the deferred credential endpoint")
+	})
+	t.Run("Missing access token", func(t *testing.T) {
+		pendingIssuance := validPendingIssuance()
+		pendingIssuance.AuthToken = nil
+
+		_, err := openid4ci.RequestDeferredCredential(pendingIssuance, getTestClientConfig(t))
+		require.ErrorContains(t, err, "pending issuance is missing an access token")
+	})
+}
@@ -0,0 +1,366 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/trustbloc/kms-go/doc/jose/jwk"
+	"github.com/trustbloc/vc-go/jwt"
+
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+const (
+	dpopTokenType     = "DPoP"
+	dpopHeader        = "DPoP"
+	dpopNonceHeader   = "DPoP-Nonce"
+	dpopProofJWTType  = "dpop+jwt"
+	useDPoPNonceError = "use_dpop_nonce"
+)
+
+// dpopTransport is an http.RoundTripper that adds DPoP proofs (RFC 9449) to token requests and to any requests
+// that use a DPoP-bound access token. If the server responds with a use_dpop_nonce error, then the request is
+// retried once using the nonce that the server provided.
+type dpopTransport struct {
+	base          http.RoundTripper
+	signer        api.JWTSigner
+	publicKey     *jwk.JWK
+	alg           string
+	tokenEndpoint string
+	// The most recent nonce received from each server, keyed by origin.
+	nonces map[string]string
+	mutex  sync.Mutex
+}
+
+func newDPoPTransport(base http.RoundTripper, signer api.JWTSigner, tokenEndpoint string) (*dpopTransport, error) {
+	publicKeyProvider, ok := signer.(api.PublicKeyProvider)
+	if !ok {
+		return nil, errors.New("the signer is unable to provide its public key, which is required for DPoP")
+	}
+
+	publicKey, err := publicKeyProvider.PublicJWK()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the signer's public key: %w", err)
+	}
+
+	headers, err := signer.CreateJWTHeaders(jwt.SignParameters{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JWT headers: %w", err)
+	}
+
+	alg, ok := headers.Algorithm()
+	if !ok {
+		return nil, errors.New("the signer did not provide a signing algorithm")
+	}
+
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &dpopTransport{
+		base:          base,
+		signer:        signer,
+		publicKey:     publicKey,
+		alg:           alg,
+		tokenEndpoint: tokenEndpoint,
+		nonces:        make(map[string]string),
+	}, nil
+}
+
+// newDPoPHTTPClient returns a copy of the given HTTP client that adds DPoP proofs created using the given signer.
+func newDPoPHTTPClient(httpClient *http.Client, signer api.JWTSigner, tokenEndpoint string) (*http.Client, error) {
+	base := httpClient.Transport
+
+	if existingDPoPTransport, ok := base.(*dpopTransport); ok {
+		base = existingDPoPTransport.base
+	}
+
+	transport, err := newDPoPTransport(base, signer, tokenEndpoint)
+	if err != nil {
+		return nil, walleterror.NewExecutionError(ErrorModule,
+			DPoPProofCreationFailedCode,
+			DPoPProofCreationFailedError,
+			err)
+	}
+
+	dpopHTTPClient := *httpClient
+	dpopHTTPClient.Transport = transport
+
+	return &dpopHTTPClient, nil
+}
+
+// enableDPoP causes all subsequent token and credential requests made by this interaction to include DPoP proofs
+// signed by the given signer. DPoP is only used if the issuer advertises support for it, or if the given access
+// token (if any) is already DPoP-bound.
+func (i *interaction) enableDPoP(signer api.JWTSigner, authToken *AuthToken) error {
+	dpopSupported := i.issuerMetadata != nil && len(i.issuerMetadata.DPoPSigningAlgValuesSupported) > 0
+
+	if !dpopSupported && !authToken.isDPoPBound() {
+		return nil
+	}
+
+	if signer == nil {
+		return walleterror.NewInvalidSDKUsageError(ErrorModule,
+			errors.New("a signer must be provided since the issuer uses DPoP-bound access tokens"))
+	}
+
+	var tokenEndpoint string
+
+	if i.issuerMetadata != nil {
+		tokenEndpoint = i.issuerMetadata.TokenEndpoint
+
+		if dpopSupported {
+			err := checkDPoPAlgSupported(signer, i.issuerMetadata.DPoPSigningAlgValuesSupported)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	dpopHTTPClient, err := newDPoPHTTPClient(i.httpClient, signer, tokenEndpoint)
+	if err != nil {
+		return err
+	}
+
+	i.httpClient = dpopHTTPClient
+	i.dpopSigner = signer
+
+	return nil
+}
+
+func checkDPoPAlgSupported(signer api.JWTSigner, supportedAlgs []string) error {
+	headers, err := signer.CreateJWTHeaders(jwt.SignParameters{})
+	if err != nil {
+		return fmt.Errorf("failed to create JWT headers: %w", err)
+	}
+
+	alg, _ := headers.Algorithm()
+
+	if !slices.Contains(supportedAlgs, alg) {
+		return walleterror.NewExecutionError(ErrorModule,
+			DPoPProofCreationFailedCode,
+			DPoPProofCreationFailedError,
+			fmt.Errorf("the signer's algorithm (%s) is not one of the issuer's supported DPoP algorithms %v",
+				alg, supportedAlgs))
+	}
+
+	return nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *dpopTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	scheme, accessToken, _ := strings.Cut(req.Header.Get("Authorization"), " ")
+
+	usesBoundToken := strings.EqualFold(scheme, dpopTokenType)
+
+	if !usesBoundToken && !t.isTokenRequest(req) {
+		return t.base.RoundTrip(req)
+	}
+
+	if !usesBoundToken {
+		accessToken = ""
+	}
+
+	resp, err := t.roundTripWithProof(req, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	retry, err := nonceRequired(resp)
+	if err != nil || !retry || (req.Body != nil && req.GetBody == nil) {
+		return resp, err
+	}
+
+	closeResponseBody(resp)
+
+	retryReq := req.Clone(req.Context())
+
+	if req.GetBody != nil {
+		retryReq.Body, err = req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return t.roundTripWithProof(retryReq, accessToken)
+}
+
+func (t *dpopTransport) roundTripWithProof(req *http.Request, accessToken string) (*http.Response, error) {
+	origin := req.URL.Scheme + "://" + req.URL.Host
+
+	t.mutex.Lock()
+	nonce := t.nonces[origin]
+	t.mutex.Unlock()
+
+	proof, err := t.createProof(req.Method, targetURI(req.URL), nonce, accessToken)
+	if err != nil {
+		return nil, walleterror.NewExecutionError(ErrorModule,
+			DPoPProofCreationFailedCode,
+			DPoPProofCreationFailedError,
+			err)
+	}
+
+	// A RoundTripper must not modify the original request.
+	req = req.Clone(req.Context())
+	req.Header.Set(dpopHeader, proof)
+
+	if accessToken != "" {
+		req.Header.Set("Authorization", authorizationHeader(dpopTokenType, accessToken))
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if newNonce := resp.Header.Get(dpopNonceHeader); newNonce != "" {
+		t.mutex.Lock()
+		t.nonces[origin] = newNonce
+		t.mutex.Unlock()
+	}
+
+	return resp, nil
+}
+
+func (t *dpopTransport) isTokenRequest(req *http.Request) bool {
+	if t.tokenEndpoint == "" {
+		return false
+	}
+
+	tokenEndpointURL, err := url.Parse(t.tokenEndpoint)
+	if err != nil {
+		return false
+	}
+
+	return targetURI(req.URL) == targetURI(tokenEndpointURL)
+}
+
+// createProof creates a DPoP proof JWT. If an access token is given, then its hash is included in the proof.
+func (t *dpopTransport) createProof(method, htu, nonce, accessToken string) (string, error) {
+	header := map[string]interface{}{
+		"typ": dpopProofJWTType,
+		"alg": t.alg,
+		"jwk": t.publicKey,
+	}
+
+	claims := map[string]interface{}{
+		"jti": uuid.NewString(),
+		"htm": method,
+		"htu": htu,
+		"iat": time.Now().Unix(),
+	}
+
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+
+	if accessToken != "" {
+		accessTokenHash := sha256.Sum256([]byte(accessToken))
+		claims["ath"] = base64.RawURLEncoding.EncodeToString(accessTokenHash[:])
+	}
+
+	headerBytes, err := json.Marshal(header)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal DPoP proof header: %w", err)
+	}
+
+	claimsBytes, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal DPoP proof claims: %w", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerBytes) + "." +
+		base64.RawURLEncoding.EncodeToString(claimsBytes)
+
+	signature, err := t.signer.SignJWT(jwt.SignParameters{}, []byte(signingInput))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign DPoP proof: %w", err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// nonceRequired indicates whether the given response is a use_dpop_nonce error from either an authorization server
+// (400 with an error response body) or a resource server (401 with a WWW-Authenticate header).
+func nonceRequired(resp *http.Response) (bool, error) {
+	if resp.Header.Get(dpopNonceHeader) == "" {
+		return false, nil
+	}
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		return strings.Contains(resp.Header.Get("WWW-Authenticate"), useDPoPNonceError), nil
+	case http.StatusBadRequest:
+		respBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return false, err
+		}
+
+		closeResponseBody(resp)
+
+		// The body has been consumed, so put it back in case the caller needs it.
+		resp.Body = io.NopCloser(bytes.NewReader(respBytes))
+
+		var errResponse errorResponse
+
+		err = json.Unmarshal(respBytes, &errResponse)
+		if err != nil {
+			return false, nil //nolint:nilerr // not a use_dpop_nonce error response
+		}
+
+		return errResponse.Error == useDPoPNonceError, nil
+	default:
+		return false, nil
+	}
+}
+
+// targetURI returns the given URL without its query and fragment, as used for the htu claim.
+func targetURI(requestURL *url.URL) string {
+	htu := *requestURL
+	htu.RawQuery = ""
+	htu.Fragment = ""
+	htu.User = nil
+
+	return htu.String()
+}
+
+func closeResponseBody(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, resp.Body) //nolint:errcheck // the body isn't needed
+
+	errClose := resp.Body.Close()
+	if errClose != nil {
+		fmt.Printf("failed to close response body: %s\n", errClose.Error())
+	}
+}
+
+// authorizationHeader returns the value to use for the Authorization header with the given access token.
+func authorizationHeader(tokenType, accessToken string) string {
+	if strings.EqualFold(tokenType, dpopTokenType) {
+		return dpopTokenType + " " + accessToken
+	}
+
+	return "Bearer " + accessToken
+}
+
+func (t *AuthToken) isDPoPBound() bool {
+	return t != nil && strings.EqualFold(t.TokenType, dpopTokenType)
+}
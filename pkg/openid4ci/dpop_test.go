@@ -0,0 +1,321 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	kmsjose "github.com/trustbloc/kms-go/doc/jose"
+	"github.com/trustbloc/kms-go/doc/jose/jwk"
+	"github.com/trustbloc/kms-go/doc/jose/jwk/jwksupport"
+	"github.com/trustbloc/vc-go/jwt"
+
+	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+const (
+	dpopAccessToken   = "dpop-access-token"
+	dpopTokenResponse = `{"access_token":"` + dpopAccessToken + `","token_type":"DPoP","expires_in":86400,` +
+		`"c_nonce":"tZignsnFbp"}`
+	dpopServerNonce = "server-nonce"
+)
+
+// dpopSignerMock is a JWTSigner that signs using a real ES256 key, so that DPoP proofs can be verified.
+type dpopSignerMock struct {
+	privateKey *ecdsa.PrivateKey
+}
+
+func newDPoPSignerMock(t *testing.T) *dpopSignerMock {
+	t.Helper()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	return &dpopSignerMock{privateKey: privateKey}
+}
+
+func (s *dpopSignerMock) GetKeyID() string {
+	return mockKeyID
+}
+
+func (s *dpopSignerMock) SignJWT(_ jwt.SignParameters, data []byte) ([]byte, error) {
+	hash := sha256.Sum256(data)
+
+	r, sigS, err := ecdsa.Sign(rand.Reader, s.privateKey, hash[:])
+	if err != nil {
+		return nil, err
+	}
+
+	const coordinateLength = 32
+
+	signature := make([]byte, 2*coordinateLength)
+	r.FillBytes(signature[:coordinateLength])
+	sigS.FillBytes(signature[coordinateLength:])
+
+	return signature, nil
+}
+
+func (s *dpopSignerMock) CreateJWTHeaders(_ jwt.SignParameters) (kmsjose.Headers, error) {
+	return kmsjose.Headers{
+		kmsjose.HeaderKeyID:     mockKeyID,
+		kmsjose.HeaderAlgorithm: "ES256",
+	}, nil
+}
+
+func (s *dpopSignerMock) PublicJWK() (*jwk.JWK, error) {
+	return jwksupport.JWKFromKey(&s.privateKey.PublicKey)
+}
+
+type dpopProofClaims struct {
+	JTI   string `json:"jti"`
+	HTM   string `json:"htm"`
+	HTU   string `json:"htu"`
+	Nonce string `json:"nonce"`
+	ATH   string `json:"ath"`
+}
+
+// mockDPoPIssuerHandler verifies the DPoP proofs sent to the token, credential and notification endpoints, and
+// requires the use of a server-provided nonce. All other requests are passed on to the wrapped
+// mockIssuerServerHandler.
+type mockDPoPIssuerHandler struct {
+	*mockIssuerServerHandler
+	receivedProofs map[string][]*dpopProofClaims
+}
+
+func (m *mockDPoPIssuerHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	isTokenRequest := request.URL.Path == "/oidc/token"
+
+	if !isTokenRequest && request.URL.Path != "/oidc/credential" && request.URL.Path != "/oidc/ack_endpoint" {
+		m.mockIssuerServerHandler.ServeHTTP(writer, request)
+
+		return
+	}
+
+	claims := m.verifyProof(request)
+	if claims == nil {
+		writer.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	m.receivedProofs[request.URL.Path] = append(m.receivedProofs[request.URL.Path], claims)
+
+	if claims.Nonce != dpopServerNonce {
+		writer.Header().Set("DPoP-Nonce", dpopServerNonce)
+
+		if isTokenRequest {
+			writer.WriteHeader(http.StatusBadRequest)
+			_, err := writer.Write([]byte(`{"error":"use_dpop_nonce"}`))
+			assert.NoError(m.t, err)
+		} else {
+			writer.Header().Set("WWW-Authenticate", `DPoP error="use_dpop_nonce"`)
+			writer.WriteHeader(http.StatusUnauthorized)
+		}
+
+		return
+	}
+
+	if isTokenRequest {
+		writer.Header().Set("Content-Type", "application/json")
+		_, err := writer.Write([]byte(dpopTokenResponse))
+		assert.NoError(m.t, err)
+
+		return
+	}
+
+	accessTokenHash := sha256.Sum256([]byte(dpopAccessToken))
+
+	assert.Equal(m.t, "DPoP "+dpopAccessToken, request.Header.Get("Authorization"))
+	assert.Equal(m.t, base64.RawURLEncoding.EncodeToString(accessTokenHash[:]), claims.ATH)
+
+	m.mockIssuerServerHandler.ServeHTTP(writer, request)
+}
+
+func (m *mockDPoPIssuerHandler) verifyProof(request *http.Request) *dpopProofClaims {
+	proof := request.Header.Get("DPoP")
+	if proof == "" {
+		return nil
+	}
+
+	jws, err := jose.ParseSigned(proof)
+	if !assert.NoError(m.t, err) {
+		return nil
+	}
+
+	header := jws.Signatures[0].Header
+
+	assert.Equal(m.t, "dpop+jwt", header.ExtraHeaders["typ"])
+
+	if !assert.NotNil(m.t, header.JSONWebKey) || !assert.True(m.t, header.JSONWebKey.IsPublic()) {
+		return nil
+	}
+
+	payload, err := jws.Verify(header.JSONWebKey)
+	if !assert.NoError(m.t, err) {
+		return nil
+	}
+
+	var claims dpopProofClaims
+
+	err = json.Unmarshal(payload, &claims)
+	if !assert.NoError(m.t, err) {
+		return nil
+	}
+
+	assert.Equal(m.t, http.MethodPost, claims.HTM)
+	assert.Equal(m.t, "http://"+request.Host+request.URL.Path, claims.HTU)
+	assert.NotEmpty(m.t, claims.JTI)
+
+	return &claims
+}
+
+func newDPoPIssuerServer(t *testing.T, dpopSigningAlgs []string) (*mockDPoPIssuerHandler, *httptest.Server) {
+	t.Helper()
+
+	issuerServerHandler := &mockDPoPIssuerHandler{
+		mockIssuerServerHandler: &mockIssuerServerHandler{
+			t:                       t,
+			credentialResponse:      sampleCredentialResponseAsk,
+			ackRequestExpectedCalls: 1,
+		},
+		receivedProofs: make(map[string][]*dpopProofClaims),
+	}
+
+	server := httptest.NewServer(issuerServerHandler)
+
+	issuerMetadata := modifyCredentialMetadata(t, sampleIssuerMetadata, func(m *issuer.Metadata) {
+		m.DPoPSigningAlgValuesSupported = dpopSigningAlgs
+	})
+
+	issuerServerHandler.issuerMetadata = strings.ReplaceAll(issuerMetadata, serverURLPlaceholder, server.URL)
+
+	return issuerServerHandler, server
+}
+
+func TestIssuerInitiatedInteraction_DPoP(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		issuerServerHandler, server := newDPoPIssuerServer(t, []string{"ES256", "EdDSA"})
+		defer server.Close()
+
+		interaction := newIssuerInitiatedInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false, true))
+
+		signer := newDPoPSignerMock(t)
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(signer, openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+
+		// The first token request is made without a nonce, and then retried with the nonce provided by the server.
+		tokenProofs := issuerServerHandler.receivedProofs["/oidc/token"]
+		require.Len(t, tokenProofs, 2)
+		require.Empty(t, tokenProofs[0].Nonce)
+		require.Equal(t, dpopServerNonce, tokenProofs[1].Nonce)
+		require.Empty(t, tokenProofs[1].ATH)
+		require.NotEqual(t, tokenProofs[0].JTI, tokenProofs[1].JTI)
+
+		// The nonce from the token endpoint is reused, since the credential endpoint is on the same server.
+		require.Len(t, issuerServerHandler.receivedProofs["/oidc/credential"], 1)
+
+		acknowledgment, err := interaction.Acknowledgment()
+		require.NoError(t, err)
+
+		err = acknowledgment.AcknowledgeIssuer(openid4ci.EventStatusCredentialAccepted, &http.Client{})
+		require.NoError(t, err)
+		require.Len(t, issuerServerHandler.receivedProofs["/oidc/ack_endpoint"], 2)
+		require.Zero(t, issuerServerHandler.ackRequestExpectedCalls)
+	})
+	t.Run("Issuer does not support DPoP", func(t *testing.T) {
+		issuerServerHandler := &mockIssuerServerHandler{t: t, credentialResponse: sampleCredentialResponse}
+
+		server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			assert.Empty(t, request.Header.Get("DPoP"))
+
+			issuerServerHandler.ServeHTTP(writer, request)
+		}))
+		defer server.Close()
+
+		issuerServerHandler.issuerMetadata = strings.ReplaceAll(sampleIssuerMetadata, serverURLPlaceholder, server.URL)
+
+		interaction := newIssuerInitiatedInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false, true))
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(newDPoPSignerMock(t), openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+	})
+	t.Run("Signer algorithm not supported by the issuer", func(t *testing.T) {
+		_, server := newDPoPIssuerServer(t, []string{"EdDSA"})
+		defer server.Close()
+
+		interaction := newIssuerInitiatedInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false, true))
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(newDPoPSignerMock(t), openid4ci.WithPIN("1234"))
+		require.ErrorContains(t, err, "DPOP_PROOF_CREATION_FAILED(OCI1-0028)")
+		require.ErrorContains(t, err, "not one of the issuer's supported DPoP algorithms")
+		require.Nil(t, credentials)
+	})
+	t.Run("Signer can't provide its public key", func(t *testing.T) {
+		_, server := newDPoPIssuerServer(t, []string{"ES384"})
+		defer server.Close()
+
+		interaction := newIssuerInitiatedInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false, true))
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		require.ErrorContains(t, err, "DPOP_PROOF_CREATION_FAILED(OCI1-0028)")
+		require.ErrorContains(t, err, "unable to provide its public key")
+		require.Nil(t, credentials)
+	})
+	t.Run("Deserialized acknowledgment without a DPoP signer", func(t *testing.T) {
+		_, server := newDPoPIssuerServer(t, []string{"ES256"})
+		defer server.Close()
+
+		interaction := newIssuerInitiatedInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false, true))
+
+		_, err := interaction.RequestCredentialWithPreAuth(newDPoPSignerMock(t), openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+
+		acknowledgment, err := interaction.Acknowledgment()
+		require.NoError(t, err)
+
+		acknowledgmentBytes, err := json.Marshal(acknowledgment)
+		require.NoError(t, err)
+
+		var deserializedAcknowledgment openid4ci.Acknowledgment
+
+		err = json.Unmarshal(acknowledgmentBytes, &deserializedAcknowledgment)
+		require.NoError(t, err)
+
+		err = deserializedAcknowledgment.AcknowledgeIssuer(openid4ci.EventStatusCredentialAccepted, &http.Client{})
+		require.ErrorContains(t, err, "no DPoP signer was set")
+	})
+}
+
+func TestRequestDeferredCredential_DPoPBoundTokenWithoutSigner(t *testing.T) {
+	var pendingIssuance openid4ci.PendingIssuance
+
+	err := json.Unmarshal([]byte(`{"transaction_id":"transaction-id","issuer_uri":"https://issuer.example.com",`+
+		`"deferred_credential_endpoint":"https://issuer.example.com/deferred_credential",`+
+		`"auth_token":{"access_token":"`+dpopAccessToken+`","token_type":"DPoP"}}`), &pendingIssuance)
+	require.NoError(t, err)
+
+	credential, err := openid4ci.RequestDeferredCredential(&pendingIssuance, getTestClientConfig(t))
+	require.ErrorContains(t, err, "a signer must be provided since the issuer uses DPoP-bound access tokens")
+	require.Nil(t, credential)
+}
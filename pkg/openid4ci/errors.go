@@ -32,6 +32,19 @@ const (
 	UnsupportedIssuanceURISchemeError         = "UNSUPPORTED_ISSUANCE_URI_SCHEME"
 	NoTokenEndpointAvailableError             = "NO_TOKEN_ENDPOINT_AVAILABLE" //nolint:gosec //false positive
 	AcknowledgmentExpiredError                = "ACKNOWLEDGMENT_EXPIRED"
+	IssuancePendingError                      = "ISSUANCE_PENDING"
+	InvalidTransactionIDError                 = "INVALID_TRANSACTION_ID"
+	DeferredIssuanceNotSupportedError         = "DEFERRED_ISSUANCE_NOT_SUPPORTED"
+	NoRefreshTokenError                       = "NO_REFRESH_TOKEN"
+	UnsupportedResponseEncryptionError        = "UNSUPPORTED_CREDENTIAL_RESPONSE_ENCRYPTION"
+	DPoPProofCreationFailedError              = "DPOP_PROOF_CREATION_FAILED"
+	PushedAuthorizationRequestFailedError     = "PUSHED_AUTHORIZATION_REQUEST_FAILED"
+	NoCredentialsGrantedError                 = "NO_CREDENTIALS_GRANTED"
+	UnsupportedProofTypeError                 = "UNSUPPORTED_PROOF_TYPE"
+	KeyProofCreationFailedError               = "KEY_PROOF_CREATION_FAILED"
+	KeyAttestationFailedError                 = "KEY_ATTESTATION_FAILED"
+	BatchIssuanceFailedError                  = "BATCH_ISSUANCE_FAILED"
+	InvalidSerializedStateError               = "INVALID_SERIALIZED_STATE"
 )
 
 // Constants' names and reasons are obvious, so they do not require additional comments.
@@ -60,4 +73,17 @@ const (
 	NoTokenEndpointAvailableErrorCode        = 20
 	AcknowledgmentExpiredErrorCode           = 21
 	InvalidCredentialConfigurationIDCode     = 22
+	IssuancePendingCode                      = 23
+	InvalidTransactionIDCode                 = 24
+	DeferredIssuanceNotSupportedCode         = 25
+	NoRefreshTokenCode                       = 26
+	UnsupportedResponseEncryptionCode        = 27
+	DPoPProofCreationFailedCode              = 28
+	PushedAuthorizationRequestFailedCode     = 29
+	NoCredentialsGrantedCode                 = 30
+	UnsupportedProofTypeCode                 = 31
+	KeyProofCreationFailedCode               = 32
+	KeyAttestationFailedCode                 = 33
+	BatchIssuanceFailedCode                  = 34
+	InvalidSerializedStateCode               = 35
 )
@@ -6,12 +6,21 @@ SPDX-License-Identifier: Apache-2.0
 
 package openid4ci
 
-import "errors"
+import (
+	"errors"
+	"slices"
+
+	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
 
 // PreAuthorizedCodeGrantParams represents an issuer's pre-authorized code grant parameters.
 type PreAuthorizedCodeGrantParams struct {
 	preAuthorizedCode string
 	txCode            *TxCode
+	clientIDRequired  bool
+	// The authorization server to use for this grant, if the credential offer specified one.
+	authorizationServer string
 }
 
 // TxCode is a code intended to bind the pre-authorized code to a certain transaction to prevent replay attack.
@@ -26,14 +35,29 @@ func (p *PreAuthorizedCodeGrantParams) PINRequired() bool {
 	return p.txCode != nil
 }
 
+// ClientIDRequired indicates whether the issuer's authorization server requires a client ID in the token request
+// when using the pre-authorized code (i.e. it doesn't support anonymous access).
+func (p *PreAuthorizedCodeGrantParams) ClientIDRequired() bool {
+	return p.clientIDRequired
+}
+
 // AuthorizationCodeGrantParams represents an issuer's authorization code grant parameters.
 type AuthorizationCodeGrantParams struct {
 	IssuerState *string
+	// The authorization server to use for this grant, if the credential offer specified one.
+	authorizationServer string
 }
 
-func determineIssuerGrantCapabilities(
-	credentialOffer *CredentialOffer,
+// determineIssuerGrantCapabilities determines the grants that can be used from the credential offer. If the offer
+// doesn't specify any grants, then they're determined from the issuer's metadata instead.
+func determineIssuerGrantCapabilities(credentialOffer *CredentialOffer, issuerMetadata *issuer.Metadata,
 ) (*PreAuthorizedCodeGrantParams, *AuthorizationCodeGrantParams, error) {
+	if len(credentialOffer.Grants) == 0 {
+		authorizationCodeGrantParams, err := determineGrantCapabilitiesFromMetadata(issuerMetadata)
+
+		return nil, authorizationCodeGrantParams, err
+	}
+
 	rawPreAuthorizedCodeGrantParams, preAuthorizedCodeGrantExists := credentialOffer.Grants[preAuthorizedGrantType]
 	rawAuthorizationCodeGrantParams, authorizationCodeGrantExists := credentialOffer.Grants[authorizationCodeGrantType]
 
@@ -52,6 +76,10 @@ func determineIssuerGrantCapabilities(
 		if err != nil {
 			return nil, nil, err
 		}
+
+		anonymousAccessSupported := issuerMetadata.PreAuthorizedGrantAnonymousAccessSupported
+
+		preAuthorizedCodeGrantParams.clientIDRequired = anonymousAccessSupported != nil && !*anonymousAccessSupported
 	}
 
 	if authorizationCodeGrantExists {
@@ -65,6 +93,73 @@ func determineIssuerGrantCapabilities(
 	return preAuthorizedCodeGrantParams, authorizationCodeGrantParams, nil
 }
 
+// determineGrantCapabilitiesFromMetadata is used for credential offers that don't specify any grants. Since a
+// pre-authorized code can only come from a credential offer, only the authorization code grant can be used, and only
+// if the issuer's authorization server supports it. Per RFC 8414, the authorization code grant is supported by
+// default if the metadata doesn't list any grant types.
+func determineGrantCapabilitiesFromMetadata(issuerMetadata *issuer.Metadata) (*AuthorizationCodeGrantParams, error) {
+	if len(issuerMetadata.GrantTypesSupported) > 0 &&
+		!slices.Contains(issuerMetadata.GrantTypesSupported, authorizationCodeGrantType) {
+		return nil, errors.New("no supported grant types found: the credential offer does not specify any grants " +
+			"and the issuer's authorization server does not support the authorization code grant")
+	}
+
+	return &AuthorizationCodeGrantParams{}, nil
+}
+
+// initialAuthorizationServer returns the authorization server whose metadata is merged into the issuer's metadata when
+// the interaction is created, before it's known which grant will be used. Issuers only specify one when they rely on
+// more than one authorization server. The pre-authorized code grant's is preferred, since its metadata determines
+// whether a client ID is required for that grant. Once a flow is started, the authorization server for that flow's
+// grant is used instead.
+func initialAuthorizationServer(credentialOffer *CredentialOffer) string {
+	for _, grantType := range []string{preAuthorizedGrantType, authorizationCodeGrantType} {
+		authorizationServer := grantAuthorizationServer(credentialOffer.Grants[grantType])
+		if authorizationServer != "" {
+			return authorizationServer
+		}
+	}
+
+	return ""
+}
+
+// grantAuthorizationServer returns the authorization_server specified in the given grant's parameters, if any.
+func grantAuthorizationServer(rawParams map[string]interface{}) string {
+	authorizationServer, _ := rawParams["authorization_server"].(string)
+
+	return authorizationServer
+}
+
+// validateGrantAuthorizationServers checks that the authorization servers specified in the credential offer's grants
+// are listed in the issuer's metadata.
+func validateGrantAuthorizationServers(issuerMetadata *issuer.Metadata,
+	preAuthorizedCodeGrantParams *PreAuthorizedCodeGrantParams,
+	authorizationCodeGrantParams *AuthorizationCodeGrantParams,
+) error {
+	var authorizationServers []string
+
+	if preAuthorizedCodeGrantParams != nil {
+		authorizationServers = append(authorizationServers, preAuthorizedCodeGrantParams.authorizationServer)
+	}
+
+	if authorizationCodeGrantParams != nil {
+		authorizationServers = append(authorizationServers, authorizationCodeGrantParams.authorizationServer)
+	}
+
+	for _, authorizationServer := range authorizationServers {
+		_, err := selectAuthorizationServer(issuerMetadata, authorizationServer)
+		if err != nil {
+			return walleterror.NewExecutionError(
+				ErrorModule,
+				InvalidCredentialOfferCode,
+				InvalidCredentialOfferError,
+				err)
+		}
+	}
+
+	return nil
+}
+
 func processPreAuthorizedCodeGrantParams(rawParams map[string]interface{}) (*PreAuthorizedCodeGrantParams, error) {
 	preAuthorizedCodeUntyped, exists := rawParams["pre-authorized_code"]
 	if !exists {
@@ -106,7 +201,11 @@ func processPreAuthorizedCodeGrantParams(rawParams map[string]interface{}) (*Pre
 		}
 	}
 
-	return &PreAuthorizedCodeGrantParams{preAuthorizedCode: preAuthorizedCode, txCode: txCode}, nil
+	return &PreAuthorizedCodeGrantParams{
+		preAuthorizedCode:   preAuthorizedCode,
+		txCode:              txCode,
+		authorizationServer: grantAuthorizationServer(rawParams),
+	}, nil
 }
 
 func processAuthorizationCodeGrantParams(rawParams map[string]interface{}) (*AuthorizationCodeGrantParams, error) {
@@ -122,5 +221,8 @@ func processAuthorizationCodeGrantParams(rawParams map[string]interface{}) (*Aut
 		issuerState = &issuerStateAsString
 	}
 
-	return &AuthorizationCodeGrantParams{IssuerState: issuerState}, nil
+	return &AuthorizationCodeGrantParams{
+		IssuerState:         issuerState,
+		authorizationServer: grantAuthorizationServer(rawParams),
+	}, nil
 }
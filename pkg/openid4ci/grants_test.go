@@ -0,0 +1,169 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+// mockTokenFormHandler records the form parameters of the token requests it receives and passes all requests on to
+// the wrapped mockIssuerServerHandler.
+type mockTokenFormHandler struct {
+	*mockIssuerServerHandler
+	tokenRequestForms []url.Values
+}
+
+func (m *mockTokenFormHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.URL.Path == "/oidc/token" {
+		assert.NoError(m.t, request.ParseForm())
+
+		m.tokenRequestForms = append(m.tokenRequestForms, request.PostForm)
+	}
+
+	m.mockIssuerServerHandler.ServeHTTP(writer, request)
+}
+
+func newOfferWithoutGrantsIssuanceURI(t *testing.T, serverURL string) string {
+	t.Helper()
+
+	credentialOffer := createCredentialOffer(t, serverURL, false, false)
+	credentialOffer.Grants = nil
+
+	credentialOfferBytes, err := json.Marshal(credentialOffer)
+	require.NoError(t, err)
+
+	return "openid-credential-offer://?credential_offer=" + url.QueryEscape(string(credentialOfferBytes))
+}
+
+func TestIssuerInitiatedInteraction_OfferWithoutGrants(t *testing.T) {
+	t.Run("Authorization details", func(t *testing.T) {
+		issuerServerHandler := &mockIssuerServerHandler{t: t, credentialResponse: sampleCredentialResponse}
+
+		server := httptest.NewServer(issuerServerHandler)
+		defer server.Close()
+
+		issuerServerHandler.issuerMetadata = strings.ReplaceAll(sampleIssuerMetadata, serverURLPlaceholder,
+			server.URL)
+
+		interaction := newIssuerInitiatedInteraction(t, newOfferWithoutGrantsIssuanceURI(t, server.URL))
+
+		require.True(t, interaction.AuthorizationCodeGrantTypeSupported())
+		require.False(t, interaction.PreAuthorizedCodeGrantTypeSupported())
+
+		authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+		require.NoError(t, err)
+
+		parsedAuthURL, err := url.Parse(authURL)
+		require.NoError(t, err)
+		require.NotEmpty(t, parsedAuthURL.Query().Get("authorization_details"))
+		require.Empty(t, parsedAuthURL.Query().Get("issuer_state"))
+
+		credentials, err := interaction.RequestCredentialWithAuth(&jwtSignerMock{keyID: mockKeyID},
+			"redirectURI?code=1234&state="+getStateFromAuthURL(t, authURL))
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+	})
+	t.Run("Scope", func(t *testing.T) {
+		issuerServerHandler := &mockIssuerServerHandler{t: t, credentialResponse: sampleCredentialResponse}
+
+		server := httptest.NewServer(issuerServerHandler)
+		defer server.Close()
+
+		issuerServerHandler.issuerMetadata = modifyCredentialMetadata(t,
+			strings.ReplaceAll(sampleIssuerMetadata, serverURLPlaceholder, server.URL), func(m *issuer.Metadata) {
+				m.CredentialConfigurationsSupported["credential_configuration_id_1"].Scope = "UniversityDegree"
+			})
+
+		interaction := newIssuerInitiatedInteraction(t, newOfferWithoutGrantsIssuanceURI(t, server.URL))
+
+		authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI",
+			openid4ci.WithScopes([]string{"openid", "UniversityDegree"}))
+		require.NoError(t, err)
+
+		parsedAuthURL, err := url.Parse(authURL)
+		require.NoError(t, err)
+		require.Equal(t, "openid UniversityDegree", parsedAuthURL.Query().Get("scope"))
+		require.Empty(t, parsedAuthURL.Query().Get("authorization_details"))
+
+		credentials, err := interaction.RequestCredentialWithAuth(&jwtSignerMock{keyID: mockKeyID},
+			"redirectURI?code=1234&state="+getStateFromAuthURL(t, authURL))
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+	})
+	t.Run("Scope is not used when there's an issuer state", func(t *testing.T) {
+		issuerServerHandler := &mockIssuerServerHandler{t: t, credentialResponse: sampleCredentialResponse}
+
+		server := httptest.NewServer(issuerServerHandler)
+		defer server.Close()
+
+		issuerServerHandler.issuerMetadata = modifyCredentialMetadata(t,
+			strings.ReplaceAll(sampleIssuerMetadata, serverURLPlaceholder, server.URL), func(m *issuer.Metadata) {
+				m.CredentialConfigurationsSupported["credential_configuration_id_1"].Scope = "UniversityDegree"
+			})
+
+		interaction := newIssuerInitiatedInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, true, true))
+
+		authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+		require.NoError(t, err)
+
+		parsedAuthURL, err := url.Parse(authURL)
+		require.NoError(t, err)
+		require.Equal(t, "1234", parsedAuthURL.Query().Get("issuer_state"))
+		require.NotEmpty(t, parsedAuthURL.Query().Get("authorization_details"))
+		require.Empty(t, parsedAuthURL.Query().Get("scope"))
+	})
+}
+
+func TestIssuerInitiatedInteraction_RequestCredentialWithPreAuth_ClientIDRequired(t *testing.T) {
+	issuerServerHandler := &mockTokenFormHandler{
+		mockIssuerServerHandler: &mockIssuerServerHandler{t: t, credentialResponse: sampleCredentialResponse},
+	}
+
+	server := httptest.NewServer(issuerServerHandler)
+	defer server.Close()
+
+	issuerServerHandler.issuerMetadata = modifyCredentialMetadata(t,
+		strings.ReplaceAll(sampleIssuerMetadata, serverURLPlaceholder, server.URL), func(m *issuer.Metadata) {
+			anonymousAccessSupported := false
+			m.PreAuthorizedGrantAnonymousAccessSupported = &anonymousAccessSupported
+		})
+
+	interaction := newIssuerInitiatedInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false, true))
+
+	preAuthorizedCodeGrantParams, err := interaction.PreAuthorizedCodeGrantParams()
+	require.NoError(t, err)
+	require.True(t, preAuthorizedCodeGrantParams.ClientIDRequired())
+
+	t.Run("Client ID not provided", func(t *testing.T) {
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		require.ErrorContains(t, err,
+			"the issuer's authorization server requires a client ID, but none was provided")
+		require.Nil(t, credentials)
+		require.Empty(t, issuerServerHandler.tokenRequestForms)
+	})
+	t.Run("Client ID provided", func(t *testing.T) {
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"), openid4ci.WithClientID("clientID"))
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+
+		require.Len(t, issuerServerHandler.tokenRequestForms, 1)
+		require.Equal(t, "clientID", issuerServerHandler.tokenRequestForms[0].Get("client_id"))
+	})
+}
@@ -11,17 +11,20 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/piprate/json-gold/ld"
+	"github.com/trustbloc/kms-go/doc/jose"
 	"github.com/trustbloc/vc-go/dataintegrity"
 	"github.com/trustbloc/vc-go/dataintegrity/suite/ecdsa2019"
 	"github.com/trustbloc/vc-go/proof/defaults"
@@ -34,7 +37,10 @@ import (
 	"github.com/trustbloc/wallet-sdk/pkg/did/wellknown"
 	"github.com/trustbloc/wallet-sdk/pkg/internal/httprequest"
 	metadatafetcher "github.com/trustbloc/wallet-sdk/pkg/internal/issuermetadata"
+	"github.com/trustbloc/wallet-sdk/pkg/mdoc"
+	"github.com/trustbloc/wallet-sdk/pkg/metadatacache"
 	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
+	"github.com/trustbloc/wallet-sdk/pkg/sdjwtvc"
 	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
 )
 
@@ -45,12 +51,19 @@ type IssuerTrustInfo struct {
 	DID                  string
 	Domain               string
 	CredentialsSupported []SupportedCredential
+	// Indicates whether the issuer's metadata was signed. Signed metadata is only accepted if its signature is valid.
+	MetadataSigned bool
+	// The subject of the X.509 certificate that the issuer's metadata was signed with. Only set if the metadata was
+	// signed using an x5c certificate chain, in which case DID is empty.
+	CertificateSubject string
 }
 
 type basicTrustInfo struct {
-	DID         string
-	Domain      string
-	DomainValid bool
+	DID                string
+	Domain             string
+	DomainValid        bool
+	MetadataSigned     bool
+	CertificateSubject string
 }
 
 // This is a common object shared by both the IssuerInitiatedInteraction and WalletInitiatedInteraction objects.
@@ -65,11 +78,32 @@ type interaction struct {
 	issuerMetadata          *issuer.Metadata
 	oAuth2Config            *oauth2.Config
 	authTokenResponseNonce  interface{}
-	authToken               *universalAuthToken
+	authToken               *AuthToken
 	httpClient              *http.Client
 	authCodeURLState        string
 	codeVerifier            string
 	requestedAcknowledgment *requestedAcknowledgment
+	pendingIssuances        []*PendingIssuance
+	// The copies of each credential that were requested using batch issuance, if it was used.
+	credentialBatches []*CredentialBatch
+	// The notification IDs that the issuer returned along with the credentials that were issued.
+	notificationIDs map[*verifiable.Credential]string
+	// The authorization_details returned in the token response (if any) in the authorization code flow.
+	grantedAuthorizationDetails []authorizationDetails
+	// Set if DPoP is being used, in which case it's also needed for sending acknowledgments.
+	dpopSigner api.JWTSigner
+
+	enableCredentialResponseEncryption bool
+	useCredentialConfigurationIDs      bool
+	responseEncryptionKey              *responseEncryptionKey
+
+	keyAttestationProvider     api.KeyAttestationProvider
+	metadataCache              *metadatacache.Cache
+	metadataVerificationPolicy *MetadataVerificationPolicy
+
+	// The identifier of the authorization server being used. Before the issuer's metadata has been fetched, this is
+	// the authorization server requested by the credential offer (if any).
+	authorizationServer string
 }
 
 type requestedAcknowledgment struct {
@@ -79,10 +113,31 @@ type requestedAcknowledgment struct {
 	ackIDs []string
 }
 
-func (i *interaction) createAuthorizationURL(clientID, redirectURI, format string, types, credentialContext []string,
-	issuerState *string, scopes []string, useOAuthDiscoverableClientIDScheme bool,
+// newInteraction creates a new interaction object. The given config must have already had its defaults set.
+func newInteraction(issuerURI string, config *ClientConfig) *interaction {
+	return &interaction{
+		issuerURI:                          issuerURI,
+		didResolver:                        config.DIDResolver,
+		activityLogger:                     config.ActivityLogger,
+		metricsLogger:                      config.MetricsLogger,
+		disableVCProofChecks:               config.DisableVCProofChecks,
+		documentLoader:                     config.DocumentLoader,
+		httpClient:                         config.HTTPClient,
+		enableCredentialResponseEncryption: config.EnableCredentialResponseEncryption,
+		useCredentialConfigurationIDs:      config.UseCredentialConfigurationIDs,
+		keyAttestationProvider:             config.KeyAttestationProvider,
+		metadataCache:                      config.MetadataCache,
+		metadataVerificationPolicy:         config.MetadataVerificationPolicy,
+	}
+}
+
+// credentialFormats, credentialTypes and credentialContexts must have the same length. One authorization_details
+// entry is generated for each credential. configIDs is optional, but if set it must be the same length too.
+func (i *interaction) createAuthorizationURL(ctx context.Context, clientID, redirectURI string,
+	configIDs, credentialFormats []string, credentialTypes, credentialContexts [][]string, issuerState *string,
+	scopes []string, useOAuthDiscoverableClientIDScheme, requirePushedAuthorizationRequest bool,
 ) (string, error) {
-	err := i.populateIssuerMetadata("Authorization")
+	err := i.populateIssuerMetadata(ctx, authorizationEventText)
 	if err != nil {
 		return "", err
 	}
@@ -94,9 +149,15 @@ func (i *interaction) createAuthorizationURL(clientID, redirectURI, format strin
 		return "", err
 	}
 
-	authorizationDetails, err := i.generateAuthorizationDetails(format, types, credentialContext)
-	if err != nil {
-		return "", err
+	var authorizationDetails []byte
+
+	// If no credentials are given, then they're requested using scopes instead.
+	if len(credentialTypes) > 0 {
+		authorizationDetails, err = i.generateAuthorizationDetails(configIDs, credentialFormats, credentialTypes,
+			credentialContexts)
+		if err != nil {
+			return "", err
+		}
 	}
 
 	authCodeOptions := i.generateAuthCodeOptions(authorizationDetails, issuerState, useOAuthDiscoverableClientIDScheme)
@@ -105,7 +166,20 @@ func (i *interaction) createAuthorizationURL(clientID, redirectURI, format strin
 
 	i.clientID = clientID
 
-	return i.oAuth2Config.AuthCodeURL(i.authCodeURLState, authCodeOptions...), nil
+	authorizationURL := i.oAuth2Config.AuthCodeURL(i.authCodeURLState, authCodeOptions...)
+
+	if i.issuerMetadata.PushedAuthorizationRequestEndpoint == "" {
+		if requirePushedAuthorizationRequest {
+			return "", walleterror.NewExecutionError(ErrorModule,
+				PushedAuthorizationRequestFailedCode,
+				PushedAuthorizationRequestFailedError,
+				errors.New("the authorization server does not support pushed authorization requests"))
+		}
+
+		return authorizationURL, nil
+	}
+
+	return i.pushAuthorizationRequest(ctx, authorizationURL)
 }
 
 func (i *interaction) instantiateOAuth2Config(clientID, redirectURI string, scopes []string) {
@@ -137,26 +211,73 @@ func (i *interaction) instantiateCodeVerifier() error {
 	return nil
 }
 
-func (i *interaction) generateAuthorizationDetails(format string, types, credentialContext []string) ([]byte, error) {
-	// TODO: Add support for requesting multiple credentials at once (by sending an array).
-	// Currently we always use the first credential type specified in the offer.
-	authorizationDetailsDTO := authorizationDetails{
-		CredentialConfigurationID: "",
-		CredentialDefinition: &issuer.CredentialDefinition{
-			Context:           credentialContext,
-			CredentialSubject: nil,
-			Type:              types,
-		},
-		Format:    format,
-		Locations: nil,
-		Type:      "openid_credential",
-	}
+// generateAuthorizationDetails generates an authorization_details entry for each credential. Credentials are
+// identified in the same way as in credential requests (see newCredentialRequest), except that credential
+// identifiers aren't known yet.
+func (i *interaction) generateAuthorizationDetails(configIDs, credentialFormats []string,
+	credentialTypes, credentialContexts [][]string,
+) ([]byte, error) {
+	var locations []string
 
 	if i.issuerMetadata.AuthorizationServer != "" {
-		authorizationDetailsDTO.Locations = []string{i.issuerMetadata.CredentialIssuer}
+		locations = []string{i.issuerMetadata.CredentialIssuer}
+	}
+
+	authorizationDetailsDTOs := make([]authorizationDetails, len(credentialTypes))
+
+	for index := range credentialTypes {
+		var configID string
+
+		if len(configIDs) == len(credentialTypes) {
+			configID = configIDs[index]
+		}
+
+		configID = i.credentialConfigurationIDToUse(configID, credentialFormats[index], credentialTypes[index])
+		if configID != "" {
+			authorizationDetailsDTOs[index] = authorizationDetails{
+				CredentialConfigurationID: configID,
+				Locations:                 locations,
+				Type:                      "openid_credential",
+			}
+
+			continue
+		}
+
+		if isSDJWTVCFormat(credentialFormats[index]) {
+			authorizationDetailsDTOs[index] = authorizationDetails{
+				Format:    credentialFormats[index],
+				Locations: locations,
+				Type:      "openid_credential",
+				Vct:       typeIdentifierFromTypes(credentialTypes[index]),
+			}
+
+			continue
+		}
+
+		if credentialFormats[index] == msoMdocCredentialFormat {
+			authorizationDetailsDTOs[index] = authorizationDetails{
+				Doctype:   typeIdentifierFromTypes(credentialTypes[index]),
+				Format:    credentialFormats[index],
+				Locations: locations,
+				Type:      "openid_credential",
+			}
+
+			continue
+		}
+
+		authorizationDetailsDTOs[index] = authorizationDetails{
+			CredentialDefinition: &issuer.CredentialDefinition{
+				Context:           credentialContexts[index],
+				CredentialSubject: nil,
+				Type:              credentialTypes[index],
+			},
+			Format:    credentialFormats[index],
+			Locations: locations,
+			Type:      "openid_credential",
+		}
 	}
 
-	authorizationDetailsBytes, err := json.Marshal([]authorizationDetails{authorizationDetailsDTO})
+	authorizationDetailsBytes, err := json.Marshal(authorizationDetailsDTOs)
 	if err != nil {
 		return nil, err
 	}
@@ -170,7 +291,11 @@ func (i *interaction) generateAuthCodeOptions(authorizationDetails []byte,
 	authCodeOptions := []oauth2.AuthCodeOption{
 		oauth2.SetAuthURLParam("code_challenge", i.generateCodeChallenge()),
 		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
-		oauth2.SetAuthURLParam("authorization_details", string(authorizationDetails)),
+	}
+
+	if authorizationDetails != nil {
+		authCodeOptions = append(authCodeOptions,
+			oauth2.SetAuthURLParam("authorization_details", string(authorizationDetails)))
 	}
 
 	if issuerState != nil {
@@ -193,11 +318,18 @@ func (i *interaction) generateCodeChallenge() string {
 	return codeChallenge
 }
 
-func (i *interaction) requestAccessToken(redirectURIWithAuthCode string) error {
+func (i *interaction) requestAccessToken(ctx context.Context, signer api.JWTSigner,
+	redirectURIWithAuthCode string,
+) error {
 	if i.oAuth2Config == nil {
 		return errors.New("authorization URL must be created first")
 	}
 
+	err := i.enableDPoP(signer, nil)
+	if err != nil {
+		return err
+	}
+
 	parsedURI, err := url.Parse(redirectURIWithAuthCode)
 	if err != nil {
 		return err
@@ -229,7 +361,7 @@ func (i *interaction) requestAccessToken(redirectURIWithAuthCode string) error {
 
 	i.oAuth2Config.Endpoint.TokenURL = tokenEndpoint
 
-	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, i.httpClient)
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, i.httpClient)
 
 	authTokenResponse, err := i.oAuth2Config.Exchange(ctx, parsedURI.Query().Get("code"),
 		oauth2.SetAuthURLParam("code_verifier", i.codeVerifier))
@@ -237,7 +369,7 @@ func (i *interaction) requestAccessToken(redirectURIWithAuthCode string) error {
 		return err
 	}
 
-	i.authToken = &universalAuthToken{
+	i.authToken = &AuthToken{
 		AccessToken:  authTokenResponse.AccessToken,
 		TokenType:    authTokenResponse.TokenType,
 		ExpiresAt:    authTokenResponse.Expiry,
@@ -246,7 +378,33 @@ func (i *interaction) requestAccessToken(redirectURIWithAuthCode string) error {
 
 	i.authTokenResponseNonce = authTokenResponse.Extra("c_nonce")
 
-	return nil
+	i.grantedAuthorizationDetails, err = parseGrantedAuthorizationDetails(
+		authTokenResponse.Extra("authorization_details"))
+
+	return err
+}
+
+// parseGrantedAuthorizationDetails parses the authorization_details parameter from a token response, which
+// indicates which of the requested credentials the authorization server actually granted access to.
+// nil is returned if the parameter isn't present.
+func parseGrantedAuthorizationDetails(authorizationDetailsParam interface{}) ([]authorizationDetails, error) {
+	if authorizationDetailsParam == nil {
+		return nil, nil
+	}
+
+	authorizationDetailsBytes, err := json.Marshal(authorizationDetailsParam)
+	if err != nil {
+		return nil, err
+	}
+
+	var grantedAuthorizationDetails []authorizationDetails
+
+	err = json.Unmarshal(authorizationDetailsBytes, &grantedAuthorizationDetails)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the authorization_details in the token response: %w", err)
+	}
+
+	return grantedAuthorizationDetails, nil
 }
 
 func (i *interaction) getTokenEndpoint() (string, error) {
@@ -261,8 +419,8 @@ func (i *interaction) getTokenEndpoint() (string, error) {
 		errors.New("no token endpoint specified in issuer's metadata"))
 }
 
-func (i *interaction) dynamicClientRegistrationSupported() (bool, error) {
-	err := i.populateIssuerMetadata("Dynamic client registration supported")
+func (i *interaction) dynamicClientRegistrationSupported(ctx context.Context) (bool, error) {
+	err := i.populateIssuerMetadata(ctx, "Dynamic client registration supported")
 	if err != nil {
 		return false, err
 	}
@@ -270,8 +428,8 @@ func (i *interaction) dynamicClientRegistrationSupported() (bool, error) {
 	return i.issuerMetadata.RegistrationEndpoint != nil, nil
 }
 
-func (i *interaction) dynamicClientRegistrationEndpoint() (string, error) {
-	err := i.populateIssuerMetadata("Dynamic client registration endpoint")
+func (i *interaction) dynamicClientRegistrationEndpoint(ctx context.Context) (string, error) {
+	err := i.populateIssuerMetadata(ctx, "Dynamic client registration endpoint")
 	if err != nil {
 		return "", err
 	}
@@ -285,13 +443,15 @@ func (i *interaction) dynamicClientRegistrationEndpoint() (string, error) {
 }
 
 // If the issuer's metadata has not been fetched before in this interaction's lifespan, then this method fetches the
-// issuer's metadata and stores it within this interaction object. If the issuer's metadata has already been fetched
-// before, then this method does nothing in order to avoid making an unnecessary GET call.
-func (i *interaction) populateIssuerMetadata(parentEvent string) error {
+// issuer's metadata (merged with the metadata of the authorization server it relies on) and stores it within this
+// interaction object. If the issuer's metadata has already been fetched before, then this method does nothing in
+// order to avoid making unnecessary GET calls.
+func (i *interaction) populateIssuerMetadata(ctx context.Context, parentEvent string) error {
 	if i.issuerMetadata == nil {
 		jwtVerifier := defaults.NewDefaultProofChecker(common.NewVDRKeyResolver(i.didResolver))
 
-		issuerMetadata, err := metadatafetcher.Get(i.issuerURI, i.httpClient, i.metricsLogger, parentEvent, jwtVerifier)
+		issuerMetadata, err := metadatafetcher.Get(ctx, i.issuerURI, i.httpClient, i.metricsLogger, parentEvent, jwtVerifier,
+			i.metadataCache, i.metadataVerificationPolicy.toFetcherPolicy())
 		if err != nil {
 			return walleterror.NewExecutionError(
 				ErrorModule,
@@ -300,29 +460,139 @@ func (i *interaction) populateIssuerMetadata(parentEvent string) error {
 				fmt.Errorf("failed to get issuer metadata: %w", err))
 		}
 
+		err = i.populateAuthorizationServerMetadata(ctx, issuerMetadata, parentEvent)
+		if err != nil {
+			return err
+		}
+
 		i.issuerMetadata = issuerMetadata
 	}
 
 	return nil
 }
 
-func (i *interaction) requestCredentialWithAuth(jwtSigner api.JWTSigner, credentialFormats []string,
-	credentialTypes, credentialContexts [][]string,
-) ([]*verifiable.Credential, error) {
-	timeStartRequestCredential := time.Now()
+// useAuthorizationServer selects the authorization server for the flow that's about to be started. If the credential
+// offer's grant for that flow doesn't specify one, then the default one is used. The issuer's metadata is fetched
+// again if it was merged with the metadata of a different authorization server.
+func (i *interaction) useAuthorizationServer(ctx context.Context, requestedAuthorizationServer, parentEvent string,
+) error {
+	err := i.populateIssuerMetadata(ctx, parentEvent)
+	if err != nil {
+		return err
+	}
 
-	credentialResponses, err := i.getCredentialResponsesWithAuth(jwtSigner, credentialFormats, credentialTypes,
-		credentialContexts)
+	authorizationServer, err := selectAuthorizationServer(i.issuerMetadata, requestedAuthorizationServer)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get credential response: %w", err)
+		return walleterror.NewExecutionError(
+			ErrorModule,
+			InvalidCredentialOfferCode,
+			InvalidCredentialOfferError,
+			err)
+	}
+
+	if authorizationServer == i.authorizationServer {
+		return nil
 	}
 
-	vcs, err := i.getVCsFromCredentialResponses(credentialResponses)
+	i.authorizationServer = requestedAuthorizationServer
+	i.issuerMetadata = nil
+
+	return i.populateIssuerMetadata(ctx, parentEvent)
+}
+
+// populateAuthorizationServerMetadata fetches the metadata of the authorization server that the issuer relies on
+// (as listed in its authorization_servers metadata parameter) and merges it into the given issuer metadata.
+// If the issuer doesn't list any authorization servers, then the issuer is also acting as the authorization server.
+// In that case, its authorization server metadata is only fetched if the issuer metadata doesn't include a token
+// endpoint, and failures are ignored, since older issuers don't necessarily publish it.
+func (i *interaction) populateAuthorizationServerMetadata(ctx context.Context, issuerMetadata *issuer.Metadata,
+	parentEvent string,
+) error {
+	authorizationServer, err := selectAuthorizationServer(issuerMetadata, i.authorizationServer)
 	if err != nil {
-		return nil, walleterror.NewExecutionError(
+		return walleterror.NewExecutionError(
 			ErrorModule,
-			CredentialParseFailedCode,
-			CredentialParseError, err)
+			InvalidCredentialOfferCode,
+			InvalidCredentialOfferError,
+			err)
+	}
+
+	if authorizationServer == "" {
+		if issuerMetadata.TokenEndpoint != "" {
+			return nil
+		}
+
+		asMetadata, errGet := metadatafetcher.GetAuthorizationServerMetadata(ctx, i.issuerURI, i.httpClient,
+			i.metricsLogger, parentEvent, i.metadataCache)
+		if errGet == nil {
+			issuerMetadata.MergeAuthorizationServerMetadata(asMetadata)
+		}
+
+		return nil
+	}
+
+	asMetadata, err := metadatafetcher.GetAuthorizationServerMetadata(ctx, authorizationServer, i.httpClient,
+		i.metricsLogger, parentEvent, i.metadataCache)
+	if err != nil {
+		return walleterror.NewExecutionError(
+			ErrorModule,
+			MetadataFetchFailedCode,
+			MetadataFetchFailedError,
+			fmt.Errorf("failed to get authorization server metadata: %w", err))
+	}
+
+	issuerMetadata.MergeAuthorizationServerMetadata(asMetadata)
+
+	i.authorizationServer = authorizationServer
+
+	return nil
+}
+
+// selectAuthorizationServer returns the authorization server to use. If one was requested (in the credential offer),
+// then it must be one of the authorization servers listed in the issuer's metadata. Otherwise, the first listed
+// authorization server is used. An empty string is returned if the issuer doesn't list any.
+func selectAuthorizationServer(issuerMetadata *issuer.Metadata, requestedAuthorizationServer string) (string, error) {
+	if requestedAuthorizationServer != "" {
+		if !slices.Contains(issuerMetadata.AuthorizationServers, requestedAuthorizationServer) {
+			return "", fmt.Errorf("the requested authorization server (%s) is not one of the authorization "+
+				"servers listed in the issuer's metadata", requestedAuthorizationServer)
+		}
+
+		return requestedAuthorizationServer, nil
+	}
+
+	if len(issuerMetadata.AuthorizationServers) > 0 {
+		return issuerMetadata.AuthorizationServers[0], nil
+	}
+
+	return "", nil
+}
+
+// configIDs is optional. If set, it's used to record which credential configuration each pending issuance is for.
+// If batchIssuance is set, then copies of each credential are requested instead, each bound to a new holder key.
+func (i *interaction) requestCredentialWithAuth(ctx context.Context, jwtSigner api.JWTSigner,
+	credentialFormats []string, credentialTypes, credentialContexts [][]string, configIDs []string,
+	batchIssuance *batchIssuanceOpts,
+) ([]*verifiable.Credential, error) {
+	timeStartRequestCredential := time.Now()
+
+	i.credentialBatches = nil
+
+	var (
+		vcs []*verifiable.Credential
+		err error
+	)
+
+	if batchIssuance != nil {
+		vcs, err = i.requestCredentialBatchesWithAuth(ctx, credentialFormats, credentialTypes, credentialContexts,
+			configIDs, batchIssuance)
+	} else {
+		vcs, err = i.requestCredentialsWithAuth(ctx, jwtSigner, credentialFormats, credentialTypes,
+			credentialContexts, configIDs)
+	}
+
+	if err != nil {
+		return nil, err
 	}
 
 	subjectIDs := getSubjectIDs(vcs)
@@ -348,17 +618,43 @@ func (i *interaction) requestCredentialWithAuth(jwtSigner api.JWTSigner, credent
 	})
 }
 
-// credentialsFormats and credentialTypes need to have the same length.
-func (i *interaction) getCredentialResponsesWithAuth(signer api.JWTSigner, credentialFormats []string,
-	credentialTypes, credentialContexts [][]string,
+func (i *interaction) requestCredentialsWithAuth(ctx context.Context, jwtSigner api.JWTSigner,
+	credentialFormats []string, credentialTypes, credentialContexts [][]string, configIDs []string,
+) ([]*verifiable.Credential, error) {
+	credentialResponses, err := i.getCredentialResponsesWithAuth(ctx, jwtSigner, credentialFormats, credentialTypes,
+		credentialContexts, configIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credential response: %w", err)
+	}
+
+	credentialResponses, i.pendingIssuances, err = i.splitCredentialResponses(credentialResponses, configIDs,
+		i.authToken, jwtSigner.GetKeyID())
+	if err != nil {
+		return nil, err
+	}
+
+	vcs, err := i.getVCsFromCredentialResponses(credentialResponses, jwtSigner.GetKeyID())
+	if err != nil {
+		return nil, walleterror.NewExecutionError(
+			ErrorModule,
+			CredentialParseFailedCode,
+			CredentialParseError, err)
+	}
+
+	return vcs, nil
+}
+
+// credentialsFormats and credentialTypes need to have the same length. configIDs is optional.
+func (i *interaction) getCredentialResponsesWithAuth(ctx context.Context, signer api.JWTSigner,
+	credentialFormats []string, credentialTypes, credentialContexts [][]string, configIDs []string,
 ) ([]CredentialResponse, error) {
-	return i.getCredentialResponse(signer, i.authTokenResponseNonce,
-		credentialFormats, credentialTypes, credentialContexts, true)
+	return i.getCredentialResponse(ctx, signer, i.authTokenResponseNonce,
+		credentialFormats, credentialTypes, credentialContexts, configIDs, true)
 }
 
 //nolint:nonamedreturns
-func (i *interaction) getCredentialResponse(signer api.JWTSigner, nonce any,
-	credentialFormats []string, credentialTypes, credentialContexts [][]string, allowRetry bool,
+func (i *interaction) getCredentialResponse(ctx context.Context, signer api.JWTSigner, nonce any,
+	credentialFormats []string, credentialTypes, credentialContexts [][]string, configIDs []string, allowRetry bool,
 ) (credentialResponse []CredentialResponse, err error) {
 	defer func() {
 		if err == nil || !allowRetry {
@@ -367,22 +663,30 @@ func (i *interaction) getCredentialResponse(signer api.JWTSigner, nonce any,
 
 		proofError := &InvalidProofError{}
 		if errors.As(err, &proofError) {
-			credentialResponse, err = i.getCredentialResponse(signer, proofError.CNonce,
-				credentialFormats, credentialTypes, credentialContexts, false)
+			credentialResponse, err = i.getCredentialResponse(ctx, signer, proofError.CNonce,
+				credentialFormats, credentialTypes, credentialContexts, configIDs, false)
 		}
 	}()
 
-	proofJWT, err := i.createClaimsProof(nonce, signer)
-	if err != nil {
-		return nil, err
-	}
+	keyProofs := i.newKeyProofCreator(nonce, signer)
 
 	credentialResponses := make([]CredentialResponse, len(credentialTypes))
 
-	oAuthHTTPClient := createOAuthHTTPClient(i.oAuth2Config, i.authToken, i.httpClient)
+	oAuthHTTPClient := createOAuthHTTPClient(ctx, i.oAuth2Config, i.authToken, i.httpClient)
 
 	for index := range credentialTypes {
-		requestBody, err := i.createCredentialRequestBody(proofJWT, credentialFormats[index],
+		var configID string
+
+		if len(configIDs) == len(credentialTypes) {
+			configID = configIDs[index]
+		}
+
+		keyProof, err := keyProofs.proofForCredential(configID, credentialFormats[index], credentialTypes[index])
+		if err != nil {
+			return nil, err
+		}
+
+		requestBody, err := i.createCredentialRequestBody(keyProof, configID, credentialFormats[index],
 			credentialTypes[index], credentialContexts[index])
 		if err != nil {
 			return nil, err
@@ -393,7 +697,7 @@ func (i *interaction) getCredentialResponse(signer api.JWTSigner, nonce any,
 
 		// The access token header will be injected automatically by the OAuth HTTP client, so there's no need to
 		// explicitly set it on the request object generated by the method call above.
-		responseBytes, err := httprequest.New(oAuthHTTPClient, i.metricsLogger).DoContext(context.TODO(),
+		responseBytes, err := httprequest.New(oAuthHTTPClient, i.metricsLogger).DoContext(ctx,
 			http.MethodPost, i.issuerMetadata.CredentialEndpoint, "application/json", nil,
 			bytes.NewReader(requestBody), fetchCredentialResponseEventText, requestCredentialEventText,
 			[]int{http.StatusOK, http.StatusCreated}, processCredentialErrorResponse)
@@ -401,6 +705,11 @@ func (i *interaction) getCredentialResponse(signer api.JWTSigner, nonce any,
 			return nil, err
 		}
 
+		responseBytes, err = i.decryptCredentialResponse(responseBytes)
+		if err != nil {
+			return nil, err
+		}
+
 		var credentialResponse CredentialResponse
 
 		err = json.Unmarshal(responseBytes, &credentialResponse)
@@ -416,7 +725,9 @@ func (i *interaction) getCredentialResponse(signer api.JWTSigner, nonce any,
 	return credentialResponses, nil
 }
 
-func (i *interaction) createClaimsProof(nonce interface{}, signer api.JWTSigner) (string, error) {
+// keyAttestation is included in the proof's header if it isn't empty.
+func (i *interaction) createClaimsProof(nonce interface{}, signer api.JWTSigner, keyAttestation string,
+) (string, error) {
 	claims := map[string]interface{}{
 		"aud":   i.issuerURI,
 		"iat":   time.Now().Unix(),
@@ -427,7 +738,13 @@ func (i *interaction) createClaimsProof(nonce interface{}, signer api.JWTSigner)
 		claims["iss"] = i.clientID // Only used in the authorization code flow.
 	}
 
-	proofJWT, err := signToken(claims, signer)
+	headers := jose.Headers{}
+
+	if keyAttestation != "" {
+		headers[keyAttestationHeader] = keyAttestation
+	}
+
+	proofJWT, err := signTokenWithHeaders(claims, signer, headers)
 	if err != nil {
 		return "", walleterror.NewExecutionError(
 			ErrorModule,
@@ -442,10 +759,10 @@ func (i *interaction) createClaimsProof(nonce interface{}, signer api.JWTSigner)
 // createOAuthHTTPClient creates the OAuth2 client wrapper using the OAuth2 library.
 // Due to some peculiarities with the OAuth2 library, we need to do some things here to ensure our custom HTTP client
 // settings get preserved. Check the comments in the method below for more details.
-func createOAuthHTTPClient(
-	oAuth2Config *oauth2.Config, token *universalAuthToken, httpClient *http.Client,
+func createOAuthHTTPClient(ctx context.Context,
+	oAuth2Config *oauth2.Config, token *AuthToken, httpClient *http.Client,
 ) *http.Client {
-	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, httpClient)
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, httpClient)
 
 	// The HTTP client below only retains the Transport, so we have to set the timeout again.
 	// The docs say that the returned client shouldn't be modified, but there doesn't seem to be a clear reason
@@ -465,56 +782,34 @@ func createOAuthHTTPClient(
 	return oAuthHTTPClient
 }
 
-func (i *interaction) createCredentialRequestBody(proofJWT, credentialFormat string,
+// configID may be empty if it isn't known.
+func (i *interaction) createCredentialRequestBody(keyProof *proof, configID, credentialFormat string,
 	credentialTypes, credentialContext []string,
 ) ([]byte, error) {
-	var credentialContextToSend *[]string
-
-	if len(credentialContext) > 0 {
-		credentialContextToSend = &credentialContext
+	responseEncryption, err := i.credentialResponseEncryptionParams()
+	if err != nil {
+		return nil, err
 	}
 
-	credentialReq := &credentialRequest{
-		CredentialDefinition: &credentialDefinition{
-			Type:    credentialTypes,
-			Context: credentialContextToSend,
-		},
-		Format: credentialFormat,
-		Proof: proof{
-			ProofType: "jwt",
-			JWT:       proofJWT,
-		},
-	}
+	credentialReq := i.newCredentialRequest(configID, credentialFormat, credentialTypes, credentialContext)
+	credentialReq.Proof = keyProof
+	credentialReq.CredentialResponseEncryption = responseEncryption
 
 	return json.Marshal(credentialReq)
 }
 
+// holderKeyID is the ID of the wallet's key that SD-JWT VCs must be bound to. If empty, the key binding of SD-JWT VCs
+// isn't checked.
 func (i *interaction) getVCsFromCredentialResponses(
-	credentialResponses []CredentialResponse,
+	credentialResponses []CredentialResponse, holderKeyID string,
 ) ([]*verifiable.Credential, error) {
 	var vcs []*verifiable.Credential
 
-	credentialOpts := []verifiable.CredentialOpt{
-		verifiable.WithJSONLDDocumentLoader(i.documentLoader),
-		verifiable.WithProofChecker(defaults.NewDefaultProofChecker(common.NewVDRKeyResolver(i.didResolver))),
-	}
-
-	opts := dataintegrity.Options{DIDResolver: &didResolverWrapper{didResolver: i.didResolver}}
-
-	dataIntegrityVerifier, err := dataintegrity.NewVerifier(&opts,
-		ecdsa2019.NewVerifierInitializer(&ecdsa2019.VerifierInitializerOptions{
-			LDDocumentLoader: i.documentLoader,
-		}))
+	parser, err := i.newCredentialParser(holderKeyID)
 	if err != nil {
 		return nil, err
 	}
 
-	credentialOpts = append(credentialOpts, verifiable.WithDataIntegrityVerifier(dataIntegrityVerifier))
-
-	if i.disableVCProofChecks {
-		credentialOpts = append(credentialOpts, verifiable.WithDisabledProofCheck())
-	}
-
 	for j := range credentialResponses {
 		timeStartParseCredential := time.Now()
 
@@ -523,7 +818,7 @@ func (i *interaction) getVCsFromCredentialResponses(
 			return nil, fmt.Errorf("failed to parse credential from credential response at index %d: %w", j, err)
 		}
 
-		vc, err := verifiable.ParseCredential(credentialResponseBytes, credentialOpts...)
+		vc, err := parser.parse(credentialResponseBytes)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse credential from credential response at index %d: %w", j, err)
 		}
@@ -537,12 +832,73 @@ func (i *interaction) getVCsFromCredentialResponses(
 			return nil, err
 		}
 
+		if credentialResponses[j].AckID != "" {
+			if i.notificationIDs == nil {
+				i.notificationIDs = make(map[*verifiable.Credential]string)
+			}
+
+			i.notificationIDs[vc] = credentialResponses[j].AckID
+		}
+
 		vcs = append(vcs, vc)
 	}
 
 	return vcs, nil
 }
 
+// credentialParser parses issued credentials of any of the supported formats.
+type credentialParser struct {
+	credentialOpts []verifiable.CredentialOpt
+	sdJWTVCOpts    []sdjwtvc.Opt
+	mdocOpts       []mdoc.Opt
+}
+
+func (i *interaction) newCredentialParser(holderKeyID string) (*credentialParser, error) {
+	proofChecker := defaults.NewDefaultProofChecker(common.NewVDRKeyResolver(i.didResolver))
+
+	parser := &credentialParser{
+		credentialOpts: []verifiable.CredentialOpt{
+			verifiable.WithJSONLDDocumentLoader(i.documentLoader),
+			verifiable.WithProofChecker(proofChecker),
+		},
+		sdJWTVCOpts: []sdjwtvc.Opt{
+			sdjwtvc.WithProofChecker(proofChecker),
+			sdjwtvc.WithHolderBinding(holderKeyID, i.didResolver),
+		},
+	}
+
+	opts := dataintegrity.Options{DIDResolver: &didResolverWrapper{didResolver: i.didResolver}}
+
+	dataIntegrityVerifier, err := dataintegrity.NewVerifier(&opts,
+		ecdsa2019.NewVerifierInitializer(&ecdsa2019.VerifierInitializerOptions{
+			LDDocumentLoader: i.documentLoader,
+		}))
+	if err != nil {
+		return nil, err
+	}
+
+	parser.credentialOpts = append(parser.credentialOpts, verifiable.WithDataIntegrityVerifier(dataIntegrityVerifier))
+
+	if i.disableVCProofChecks {
+		parser.credentialOpts = append(parser.credentialOpts, verifiable.WithDisabledProofCheck())
+		parser.sdJWTVCOpts = append(parser.sdJWTVCOpts, sdjwtvc.WithDisabledProofCheck())
+		parser.mdocOpts = append(parser.mdocOpts, mdoc.WithDisabledProofCheck())
+	}
+
+	return parser, nil
+}
+
+func (p *credentialParser) parse(credentialBytes []byte) (*verifiable.Credential, error) {
+	switch {
+	case sdjwtvc.IsSDJWTVC(credentialBytes):
+		return sdjwtvc.Parse(string(credentialBytes), p.sdJWTVCOpts...)
+	case mdoc.IsMdoc(credentialBytes):
+		return mdoc.ParseCredential(credentialBytes, p.mdocOpts...)
+	default:
+		return verifiable.ParseCredential(credentialBytes, p.credentialOpts...)
+	}
+}
+
 //nolint:funlen
 func processCredentialErrorResponse(statusCode int, respBytes []byte) error {
 	detailedErr := fmt.Errorf("received status code [%d] with body [%s] from issuer's credential endpoint",
@@ -621,10 +977,10 @@ func processCredentialErrorResponse(statusCode int, respBytes []byte) error {
 	}
 }
 
-func (i *interaction) issuerFullTrustInfo(
+func (i *interaction) issuerFullTrustInfo(ctx context.Context,
 	credentialTypes [][]string, credentialFormats []string,
 ) (*IssuerTrustInfo, error) {
-	trustInfo, err := i.issuerBasicTrustInfo()
+	trustInfo, err := i.issuerBasicTrustInfo(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -642,15 +998,21 @@ func (i *interaction) issuerFullTrustInfo(
 		DID:                  trustInfo.DID,
 		Domain:               trustInfo.Domain,
 		CredentialsSupported: supportedCredentials,
+		MetadataSigned:       trustInfo.MetadataSigned,
+		CertificateSubject:   trustInfo.CertificateSubject,
 	}, nil
 }
 
-func (i *interaction) issuerBasicTrustInfo() (*basicTrustInfo, error) {
-	err := i.populateIssuerMetadata("Verify issuer")
+func (i *interaction) issuerBasicTrustInfo(ctx context.Context) (*basicTrustInfo, error) {
+	err := i.populateIssuerMetadata(ctx, "Verify issuer")
 	if err != nil {
 		return nil, err
 	}
 
+	if chain := i.issuerMetadata.GetSigningCertificateChain(); chain != nil {
+		return i.issuerCertificateTrustInfo(chain[0])
+	}
+
 	jwtKID := i.issuerMetadata.GetJWTKID()
 
 	if jwtKID == nil {
@@ -670,20 +1032,38 @@ func (i *interaction) issuerBasicTrustInfo() (*basicTrustInfo, error) {
 
 	did := jwtKIDSplit[0]
 
-	valid, linkedDomain, err := wellknown.ValidateLinkedDomains(did, i.didResolver, i.httpClient)
+	valid, linkedDomain, err := wellknown.ValidateLinkedDomainsContext(ctx, did, i.didResolver, i.httpClient)
 	if err != nil {
 		return nil, err
 	}
 
 	return &basicTrustInfo{
-		DID:         did,
-		Domain:      linkedDomain,
-		DomainValid: valid,
+		DID:            did,
+		Domain:         linkedDomain,
+		DomainValid:    valid,
+		MetadataSigned: true,
+	}, nil
+}
+
+// issuerCertificateTrustInfo is used when the issuer's metadata was signed using an x5c certificate chain (which has
+// already been verified against the configured trust anchors). The issuer's domain is only considered valid if the
+// signing certificate was issued for it.
+func (i *interaction) issuerCertificateTrustInfo(signingCertificate *x509.Certificate) (*basicTrustInfo, error) {
+	issuerURI, err := url.Parse(i.issuerURI)
+	if err != nil {
+		return nil, fmt.Errorf("parse issuer uri: %w", err)
+	}
+
+	return &basicTrustInfo{
+		Domain:             issuerURI.Host,
+		DomainValid:        signingCertificate.VerifyHostname(issuerURI.Hostname()) == nil,
+		MetadataSigned:     true,
+		CertificateSubject: signingCertificate.Subject.String(),
 	}, nil
 }
 
-func (i *interaction) verifyIssuer() (string, error) {
-	trustInfo, err := i.issuerBasicTrustInfo()
+func (i *interaction) verifyIssuer(ctx context.Context) (string, error) {
+	trustInfo, err := i.issuerBasicTrustInfo(ctx)
 	if err != nil {
 		return "", err
 	}
@@ -699,7 +1079,7 @@ func (i *interaction) verifyIssuer() (string, error) {
 	return trustInfo.Domain, nil
 }
 
-func (i *interaction) requestedAcknowledgmentObj(authToken *universalAuthToken) (*Acknowledgment, error) {
+func (i *interaction) requestedAcknowledgmentObj(authToken *AuthToken) (*Acknowledgment, error) {
 	require, err := i.requireAcknowledgment()
 	if err != nil {
 		return nil, err
@@ -713,7 +1093,10 @@ func (i *interaction) requestedAcknowledgmentObj(authToken *universalAuthToken)
 		AckIDs:                i.requestedAcknowledgment.ackIDs,
 		CredentialAckEndpoint: i.issuerMetadata.NotificationEndpoint,
 		IssuerURI:             i.issuerURI,
+		TokenEndpoint:         i.issuerMetadata.TokenEndpoint,
+		ClientID:              i.clientID,
 		AuthToken:             authToken,
+		DPoPSigner:            i.dpopSigner,
 	}, nil
 }
 
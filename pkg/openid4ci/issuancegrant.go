@@ -0,0 +1,209 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/trustbloc/vc-go/verifiable"
+	"golang.org/x/oauth2"
+
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+const renewCredentialsEventText = "Renew credential(s) using an issuance grant" //nolint:gosec //false positive
+
+// IssuanceGrant holds the refresh token that an issuer returned during an OpenID4CI interaction, along with the
+// details of the credentials that were requested. It allows the same credentials to be requested again later
+// (e.g. once they've expired) without any user interaction. See RenewCredentials.
+// An IssuanceGrant can be serialized (e.g. with json.Marshal) and stored. Since it contains a refresh token, it
+// should be stored securely.
+type IssuanceGrant struct {
+	IssuerURI                  string     `json:"issuer_uri"`
+	AuthorizationServer        string     `json:"authorization_server,omitempty"`
+	ClientID                   string     `json:"client_id,omitempty"`
+	CredentialConfigurationIDs []string   `json:"credential_configuration_ids,omitempty"`
+	CredentialFormats          []string   `json:"credential_formats"`
+	CredentialTypes            [][]string `json:"credential_types"`
+	CredentialContexts         [][]string `json:"credential_contexts"`
+	AuthToken                  *AuthToken `json:"auth_token"`
+}
+
+// RenewCredentials uses the refresh token in the given IssuanceGrant to get a new access token from the issuer,
+// and then requests the same credentials that were requested in the interaction that the grant came from.
+// If the issuer rotates the refresh token, then the given IssuanceGrant is updated, so it should be stored again
+// after calling this function (even if an error is returned).
+// Any credentials deferred by the issuer are returned as PendingIssuance objects instead.
+func RenewCredentials(grant *IssuanceGrant, jwtSigner api.JWTSigner, config *ClientConfig,
+) ([]*verifiable.Credential, []*PendingIssuance, error) {
+	return RenewCredentialsContext(context.Background(), grant, jwtSigner, config)
+}
+
+// RenewCredentialsContext is the same as RenewCredentials, but uses the given context for the network requests it
+// makes, so that they can be cancelled or given a deadline.
+func RenewCredentialsContext(ctx context.Context, grant *IssuanceGrant, jwtSigner api.JWTSigner,
+	config *ClientConfig,
+) ([]*verifiable.Credential, []*PendingIssuance, error) {
+	err := validateRequiredParameters(config)
+	if err != nil {
+		return nil, nil, walleterror.NewInvalidSDKUsageError(ErrorModule, err)
+	}
+
+	err = validateIssuanceGrant(grant)
+	if err != nil {
+		return nil, nil, walleterror.NewInvalidSDKUsageError(ErrorModule, err)
+	}
+
+	err = validateSignerKeyID(jwtSigner)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	setDefaults(config)
+
+	renewalInteraction := newInteraction(grant.IssuerURI, config)
+	renewalInteraction.authorizationServer = grant.AuthorizationServer
+	renewalInteraction.clientID = grant.ClientID
+
+	err = renewalInteraction.populateIssuerMetadata(ctx, renewCredentialsEventText)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = renewalInteraction.enableDPoP(jwtSigner, grant.AuthToken)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = renewalInteraction.refreshAccessToken(ctx, grant)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vcs, err := renewalInteraction.requestCredentialWithAuth(ctx, jwtSigner, grant.CredentialFormats,
+		grant.CredentialTypes, grant.CredentialContexts, grant.CredentialConfigurationIDs, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return vcs, renewalInteraction.pendingIssuances, nil
+}
+
+func validateIssuanceGrant(grant *IssuanceGrant) error {
+	switch {
+	case grant == nil:
+		return errors.New("issuance grant object must be provided")
+	case grant.AuthToken == nil || grant.AuthToken.RefreshToken == "":
+		return errors.New("issuance grant is missing a refresh token")
+	case len(grant.CredentialTypes) == 0:
+		return errors.New("issuance grant does not specify any credentials")
+	case len(grant.CredentialFormats) != len(grant.CredentialTypes) ||
+		len(grant.CredentialContexts) != len(grant.CredentialTypes):
+		return errors.New("issuance grant has mismatched numbers of credential formats, types and contexts")
+	default:
+		return nil
+	}
+}
+
+// issuanceGrant creates an IssuanceGrant for the credentials requested in this interaction.
+// An error is returned if credentials haven't been requested yet, or if the issuer didn't provide a refresh token.
+func (i *interaction) issuanceGrant(authToken *AuthToken, configIDs, credentialFormats []string,
+	credentialTypes, credentialContexts [][]string,
+) (*IssuanceGrant, error) {
+	if authToken == nil {
+		return nil, walleterror.NewInvalidSDKUsageError(ErrorModule,
+			errors.New("credentials must be requested before an issuance grant can be created"))
+	}
+
+	if authToken.RefreshToken == "" {
+		return nil, walleterror.NewExecutionError(ErrorModule,
+			NoRefreshTokenCode,
+			NoRefreshTokenError,
+			errors.New("the issuer did not provide a refresh token"))
+	}
+
+	if len(credentialContexts) != len(credentialTypes) {
+		credentialContexts = make([][]string, len(credentialTypes))
+	}
+
+	return &IssuanceGrant{
+		IssuerURI:                  i.issuerURI,
+		AuthorizationServer:        i.authorizationServer,
+		ClientID:                   i.clientID,
+		CredentialConfigurationIDs: configIDs,
+		CredentialFormats:          credentialFormats,
+		CredentialTypes:            credentialTypes,
+		CredentialContexts:         credentialContexts,
+		AuthToken:                  authToken,
+	}, nil
+}
+
+// refreshAccessToken exchanges the grant's refresh token for a new access token, which is then used for
+// subsequent credential requests made by this interaction.
+func (i *interaction) refreshAccessToken(ctx context.Context, grant *IssuanceGrant) error {
+	tokenEndpoint, err := i.getTokenEndpoint()
+	if err != nil {
+		return err
+	}
+
+	i.oAuth2Config = refreshOAuth2Config(grant.ClientID, tokenEndpoint)
+
+	token, err := refreshToken(ctx, i.oAuth2Config, i.httpClient, grant.AuthToken.RefreshToken)
+	if err != nil {
+		return err
+	}
+
+	i.authToken = &AuthToken{
+		AccessToken:  token.AccessToken,
+		TokenType:    token.TokenType,
+		ExpiresAt:    token.Expiry,
+		RefreshToken: token.RefreshToken,
+	}
+
+	i.authTokenResponseNonce = token.Extra("c_nonce")
+
+	grant.AuthToken = i.authToken
+
+	i.grantedAuthorizationDetails, err = parseGrantedAuthorizationDetails(token.Extra("authorization_details"))
+
+	return err
+}
+
+// refreshOAuth2Config returns the OAuth2 config to use for refreshing access tokens. The wallet is a public client, so
+// the client ID (if there is one) is sent in the request body.
+func refreshOAuth2Config(clientID, tokenEndpoint string) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID: clientID,
+		Endpoint: oauth2.Endpoint{
+			TokenURL:  tokenEndpoint,
+			AuthStyle: oauth2.AuthStyleInParams,
+		},
+	}
+}
+
+// refreshToken exchanges the given refresh token for a new access token.
+func refreshToken(ctx context.Context, oAuth2Config *oauth2.Config, httpClient *http.Client, refreshToken string,
+) (*oauth2.Token, error) {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, httpClient)
+
+	// Since the token passed in below has no access token, the token source will always use the refresh token.
+	token, err := oAuth2Config.TokenSource(ctx, &oauth2.Token{RefreshToken: refreshToken}).Token()
+	if err != nil {
+		retrieveErr := &oauth2.RetrieveError{}
+		if errors.As(err, &retrieveErr) && retrieveErr.Response != nil {
+			return nil, tokenErrorResponseHandler(retrieveErr.Response.StatusCode, retrieveErr.Body)
+		}
+
+		return nil, fmt.Errorf("failed to refresh access token: %w", err)
+	}
+
+	return token, nil
+}
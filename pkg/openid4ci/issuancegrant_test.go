@@ -0,0 +1,202 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+// mockRefreshTokenHandler handles token requests itself (issuing a new refresh token each time) and passes all
+// other requests on to the wrapped mockIssuerServerHandler.
+type mockRefreshTokenHandler struct {
+	*mockIssuerServerHandler
+	refreshErrorResponse  string
+	tokensIssued          int
+	receivedRefreshTokens []string
+}
+
+func (m *mockRefreshTokenHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.URL.Path != "/oidc/token" {
+		m.mockIssuerServerHandler.ServeHTTP(writer, request)
+
+		return
+	}
+
+	err := request.ParseForm()
+	assert.NoError(m.t, err)
+
+	if request.Form.Get("grant_type") == "refresh_token" {
+		m.receivedRefreshTokens = append(m.receivedRefreshTokens, request.Form.Get("refresh_token"))
+
+		if m.refreshErrorResponse != "" {
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusBadRequest)
+			_, err = writer.Write([]byte(m.refreshErrorResponse))
+			assert.NoError(m.t, err)
+
+			return
+		}
+	}
+
+	m.tokensIssued++
+
+	writer.Header().Set("Content-Type", "application/json")
+	_, err = fmt.Fprintf(writer, `{"access_token":"access-token-%d","token_type":"bearer","expires_in":86400,`+
+		`"refresh_token":"refresh-token-%d","c_nonce":"tZignsnFbp"}`, m.tokensIssued, m.tokensIssued)
+	assert.NoError(m.t, err)
+}
+
+func TestIssuerInitiatedInteraction_IssuanceGrant(t *testing.T) {
+	issuerServerHandler := &mockRefreshTokenHandler{
+		mockIssuerServerHandler: &mockIssuerServerHandler{t: t, credentialResponse: sampleCredentialResponse},
+	}
+
+	server := httptest.NewServer(issuerServerHandler)
+	defer server.Close()
+
+	issuerServerHandler.issuerMetadata = strings.ReplaceAll(sampleIssuerMetadata, serverURLPlaceholder, server.URL)
+
+	interaction := newIssuerInitiatedInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false, true))
+
+	grant, err := interaction.IssuanceGrant()
+	require.ErrorContains(t, err, "credentials must be requested before an issuance grant can be created")
+	require.Nil(t, grant)
+
+	credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+		openid4ci.WithPIN("1234"))
+	require.NoError(t, err)
+	require.Len(t, credentials, 1)
+
+	grant, err = interaction.IssuanceGrant()
+	require.NoError(t, err)
+	require.Equal(t, server.URL, grant.IssuerURI)
+	require.Equal(t, []string{"credential_configuration_id_1"}, grant.CredentialConfigurationIDs)
+
+	// The grant must survive a round trip through storage.
+	grantBytes, err := json.Marshal(grant)
+	require.NoError(t, err)
+
+	var storedGrant openid4ci.IssuanceGrant
+
+	require.NoError(t, json.Unmarshal(grantBytes, &storedGrant))
+
+	t.Run("Success", func(t *testing.T) {
+		renewedCredentials, pendingIssuances, err := openid4ci.RenewCredentials(&storedGrant,
+			&jwtSignerMock{keyID: mockKeyID}, getTestClientConfig(t))
+		require.NoError(t, err)
+		require.Len(t, renewedCredentials, 1)
+		require.Empty(t, pendingIssuances)
+		require.Equal(t, []string{"refresh-token-1"}, issuerServerHandler.receivedRefreshTokens)
+
+		// The issuer rotated the refresh token, so the next renewal must use the new one.
+		renewedCredentials, _, err = openid4ci.RenewCredentials(&storedGrant,
+			&jwtSignerMock{keyID: mockKeyID}, getTestClientConfig(t))
+		require.NoError(t, err)
+		require.Len(t, renewedCredentials, 1)
+		require.Equal(t, []string{"refresh-token-1", "refresh-token-2"}, issuerServerHandler.receivedRefreshTokens)
+	})
+	t.Run("Refresh token rejected", func(t *testing.T) {
+		issuerServerHandler.refreshErrorResponse = `{"error":"invalid_grant"}`
+		defer func() { issuerServerHandler.refreshErrorResponse = "" }()
+
+		renewedCredentials, _, err := openid4ci.RenewCredentials(&storedGrant,
+			&jwtSignerMock{keyID: mockKeyID}, getTestClientConfig(t))
+		require.ErrorContains(t, err, "INVALID_GRANT(OCI1-0010)")
+		require.Nil(t, renewedCredentials)
+	})
+	t.Run("Credential request fails", func(t *testing.T) {
+		issuerServerHandler.credentialRequestShouldFail = true
+		defer func() { issuerServerHandler.credentialRequestShouldFail = false }()
+
+		renewedCredentials, _, err := openid4ci.RenewCredentials(&storedGrant,
+			&jwtSignerMock{keyID: mockKeyID}, getTestClientConfig(t))
+		require.ErrorContains(t, err, "failed to get credential response")
+		require.Nil(t, renewedCredentials)
+	})
+}
+
+func TestIssuerInitiatedInteraction_IssuanceGrant_NoRefreshToken(t *testing.T) {
+	issuerServerHandler := &mockIssuerServerHandler{t: t, credentialResponse: sampleCredentialResponse}
+
+	server := httptest.NewServer(issuerServerHandler)
+	defer server.Close()
+
+	issuerServerHandler.issuerMetadata = strings.ReplaceAll(sampleIssuerMetadata, serverURLPlaceholder, server.URL)
+
+	interaction := newIssuerInitiatedInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false, true))
+
+	_, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID}, openid4ci.WithPIN("1234"))
+	require.NoError(t, err)
+
+	grant, err := interaction.IssuanceGrant()
+	require.ErrorContains(t, err, "NO_REFRESH_TOKEN(OCI1-0026)")
+	require.Nil(t, grant)
+}
+
+func TestRenewCredentials_InvalidArgs(t *testing.T) {
+	validGrant := func() *openid4ci.IssuanceGrant {
+		var grant openid4ci.IssuanceGrant
+
+		err := json.Unmarshal([]byte(`{"issuer_uri":"https://example.com","credential_formats":["jwt_vc_json"],`+
+			`"credential_types":[["VerifiableCredential"]],"credential_contexts":[null],`+
+			`"auth_token":{"refresh_token":"token"}}`), &grant)
+		require.NoError(t, err)
+
+		return &grant
+	}
+
+	t.Run("Missing client config", func(t *testing.T) {
+		_, _, err := openid4ci.RenewCredentials(validGrant(), &jwtSignerMock{keyID: mockKeyID}, nil)
+		require.ErrorContains(t, err, "no client config provided")
+	})
+	t.Run("Missing grant", func(t *testing.T) {
+		_, _, err := openid4ci.RenewCredentials(nil, &jwtSignerMock{keyID: mockKeyID}, getTestClientConfig(t))
+		require.ErrorContains(t, err, "issuance grant object must be provided")
+	})
+	t.Run("Missing refresh token", func(t *testing.T) {
+		grant := validGrant()
+		grant.AuthToken = nil
+
+		_, _, err := openid4ci.RenewCredentials(grant, &jwtSignerMock{keyID: mockKeyID}, getTestClientConfig(t))
+		require.ErrorContains(t, err, "issuance grant is missing a refresh token")
+	})
+	t.Run("No credentials", func(t *testing.T) {
+		grant := validGrant()
+		grant.CredentialTypes = nil
+
+		_, _, err := openid4ci.RenewCredentials(grant, &jwtSignerMock{keyID: mockKeyID}, getTestClientConfig(t))
+		require.ErrorContains(t, err, "issuance grant does not specify any credentials")
+	})
+	t.Run("Mismatched credential parameters", func(t *testing.T) {
+		grant := validGrant()
+		grant.CredentialFormats = nil
+
+		_, _, err := openid4ci.RenewCredentials(grant, &jwtSignerMock{keyID: mockKeyID}, getTestClientConfig(t))
+		require.ErrorContains(t, err, "issuance grant has mismatched numbers of credential formats")
+	})
+	t.Run("Signer key ID missing DID part", func(t *testing.T) {
+		_, _, err := openid4ci.RenewCredentials(validGrant(), &jwtSignerMock{keyID: "noDID"}, getTestClientConfig(t))
+		require.ErrorContains(t, err, "KEY_ID_MISSING_DID_PART")
+	})
+	t.Run("Metadata fetch fails", func(t *testing.T) {
+		grant := validGrant()
+		grant.IssuerURI = "invalid url"
+
+		_, _, err := openid4ci.RenewCredentials(grant, &jwtSignerMock{keyID: mockKeyID}, getTestClientConfig(t))
+		require.ErrorContains(t, err, "METADATA_FETCH_FAILED")
+	})
+}
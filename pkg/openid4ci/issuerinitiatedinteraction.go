@@ -16,6 +16,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 	"time"
 
@@ -36,6 +37,9 @@ const (
 	jwtVCJSONCredentialFormat   = "jwt_vc_json"    //nolint:gosec // false positive
 	jwtVCJSONLDCredentialFormat = "jwt_vc_json-ld" //nolint:gosec // false positive
 	ldpVCCredentialFormat       = "ldp_vc"
+	vcSDJWTCredentialFormat     = "vc+sd-jwt"
+	dcSDJWTCredentialFormat     = "dc+sd-jwt"
+	msoMdocCredentialFormat     = "mso_mdoc"
 
 	newInteractionEventText = "Instantiating OpenID4CI interaction object"
 	//nolint:gosec //false positive
@@ -69,14 +73,25 @@ type IssuerInitiatedInteraction struct {
 	preAuthorizedCodeGrantParams *PreAuthorizedCodeGrantParams
 	authorizationCodeGrantParams *AuthorizationCodeGrantParams
 
-	authToken *universalAuthToken
+	authToken *AuthToken
 }
 
 // NewIssuerInitiatedInteraction creates a new OpenID4CI IssuerInitiatedInteraction.
 // If no ActivityLogger is provided (via the ClientConfig object), then no activity logging will take place.
+func NewIssuerInitiatedInteraction(
+	initiateIssuanceURI string,
+	config *ClientConfig,
+) (*IssuerInitiatedInteraction, error) {
+	return NewIssuerInitiatedInteractionContext(context.Background(), initiateIssuanceURI, config)
+}
+
+// NewIssuerInitiatedInteractionContext is the same as NewIssuerInitiatedInteraction, but uses the given context for
+// fetching the credential offer and the issuer's metadata, so that those requests can be cancelled or given a
+// deadline.
 //
 //nolint:funlen
-func NewIssuerInitiatedInteraction(
+func NewIssuerInitiatedInteractionContext(
+	ctx context.Context,
 	initiateIssuanceURI string,
 	config *ClientConfig,
 ) (*IssuerInitiatedInteraction, error) {
@@ -89,30 +104,27 @@ func NewIssuerInitiatedInteraction(
 
 	setDefaults(config)
 
-	credentialOffer, err := getCredentialOffer(initiateIssuanceURI, config.HTTPClient, config.MetricsLogger)
+	credentialOffer, err := getCredentialOffer(ctx, initiateIssuanceURI, config.HTTPClient, config.MetricsLogger)
 	if err != nil {
 		return nil, err
 	}
 
-	issuerInteraction := &interaction{
-		issuerURI:            credentialOffer.CredentialIssuer,
-		didResolver:          config.DIDResolver,
-		activityLogger:       config.ActivityLogger,
-		metricsLogger:        config.MetricsLogger,
-		disableVCProofChecks: config.DisableVCProofChecks,
-		documentLoader:       config.DocumentLoader,
-		httpClient:           config.HTTPClient,
+	issuerInteraction := newInteraction(credentialOffer.CredentialIssuer, config)
+	issuerInteraction.authorizationServer = initialAuthorizationServer(credentialOffer)
+
+	err = issuerInteraction.populateIssuerMetadata(ctx, getIssuerMetadataEventText)
+	if err != nil {
+		return nil, err
 	}
 
-	err = issuerInteraction.populateIssuerMetadata(getIssuerMetadataEventText)
+	preAuthorizedCodeGrantParams, authorizationCodeGrantParams, err := determineIssuerGrantCapabilities(credentialOffer,
+		issuerInteraction.issuerMetadata)
 	if err != nil {
 		return nil, err
 	}
 
-	// TODO https://github.com/trustbloc/wallet-sdk/issues/457 Add support for determining
-	// grant types when no grants are specified.
-	// See https://openid.net/specs/openid-4-verifiable-credential-issuance-1_0-11.html#section-4.1.1 for more info.
-	preAuthorizedCodeGrantParams, authorizationCodeGrantParams, err := determineIssuerGrantCapabilities(credentialOffer)
+	err = validateGrantAuthorizationServers(issuerInteraction.issuerMetadata, preAuthorizedCodeGrantParams,
+		authorizationCodeGrantParams)
 	if err != nil {
 		return nil, err
 	}
@@ -143,17 +155,31 @@ func NewIssuerInitiatedInteraction(
 // CreateAuthorizationURL creates an authorization URL that can be opened in a browser to proceed to the login page.
 // It is the first step in the authorization code flow.
 // It creates the authorization URL that can be opened in a browser to proceed to the login page.
+// Authorization is requested for all the offered credentials at once.
 // This method can only be used if the issuer supports authorization code grants.
 // Check the issuer's capabilities first using the methods available on this IssuerInitiatedInteraction object.
 // If scopes are needed, pass them in using the WithScopes option.
 func (i *IssuerInitiatedInteraction) CreateAuthorizationURL(clientID, redirectURI string,
 	opts ...CreateAuthorizationURLOpt,
+) (string, error) {
+	return i.CreateAuthorizationURLContext(context.Background(), clientID, redirectURI, opts...)
+}
+
+// CreateAuthorizationURLContext is the same as CreateAuthorizationURL, but uses the given context for the network
+// requests it makes (such as a pushed authorization request), so that they can be cancelled or given a deadline.
+func (i *IssuerInitiatedInteraction) CreateAuthorizationURLContext(ctx context.Context, clientID, redirectURI string,
+	opts ...CreateAuthorizationURLOpt,
 ) (string, error) {
 	if !i.AuthorizationCodeGrantTypeSupported() {
 		return "", walleterror.NewInvalidSDKUsageError(ErrorModule,
 			errors.New("issuer does not support the authorization code grant type"))
 	}
 
+	err := i.useAuthorizationCodeGrantAuthorizationServer(ctx, authorizationEventText)
+	if err != nil {
+		return "", err
+	}
+
 	processedOpts := processCreateAuthorizationURLOpts(opts)
 
 	issuerState, err := i.determineIssuerStateToUse(processedOpts.issuerState)
@@ -161,8 +187,54 @@ func (i *IssuerInitiatedInteraction) CreateAuthorizationURL(clientID, redirectUR
 		return "", err
 	}
 
-	return i.interaction.createAuthorizationURL(clientID, redirectURI, i.credentialFormats[0], i.credentialTypes[0],
-		i.credentialContexts[0], issuerState, processedOpts.scopes, processedOpts.useOAuthDiscoverableClientIDScheme)
+	scopes := processedOpts.scopes
+	configIDs, credentialFormats, credentialTypes, credentialContexts := i.credentialConfigIDs, i.credentialFormats,
+		i.credentialTypes, i.credentialContexts
+
+	// Without an issuer state, the authorization request isn't tied to the credential offer. In that case, if the
+	// issuer's metadata defines a scope for each of the offered credentials, then they're requested using those
+	// scopes instead of authorization_details.
+	if issuerState == nil {
+		if credentialScopes := i.offeredCredentialScopes(); credentialScopes != nil {
+			scopes = appendMissing(scopes, credentialScopes)
+			configIDs, credentialFormats, credentialTypes, credentialContexts = nil, nil, nil, nil
+		}
+	}
+
+	return i.interaction.createAuthorizationURL(ctx, clientID, redirectURI, configIDs, credentialFormats,
+		credentialTypes, credentialContexts, issuerState, scopes, processedOpts.useOAuthDiscoverableClientIDScheme,
+		processedOpts.requirePushedAuthorizationRequest)
+}
+
+// offeredCredentialScopes returns the scopes defined in the issuer's metadata for the offered credentials.
+// nil is returned if any of the offered credentials doesn't have a scope.
+func (i *IssuerInitiatedInteraction) offeredCredentialScopes() []string {
+	if len(i.credentialConfigIDs) != len(i.credentialTypes) {
+		return nil
+	}
+
+	var scopes []string
+
+	for _, configID := range i.credentialConfigIDs {
+		config := i.interaction.issuerMetadata.CredentialConfigurationsSupported[configID]
+		if config == nil || config.Scope == "" {
+			return nil
+		}
+
+		scopes = appendMissing(scopes, []string{config.Scope})
+	}
+
+	return scopes
+}
+
+func appendMissing(values, valuesToAdd []string) []string {
+	for _, value := range valuesToAdd {
+		if !slices.Contains(values, value) {
+			values = append(values, value)
+		}
+	}
+
+	return values
 }
 
 // RequestCredentialWithPreAuth requests credential(s) from the issuer. This method can only be used for the
@@ -170,8 +242,18 @@ func (i *IssuerInitiatedInteraction) CreateAuthorizationURL(clientID, redirectUR
 // For the equivalent method for the authorization code flow, see RequestCredentialWithAuth instead.
 // If a PIN is required (which can be checked via the PreAuthorizedCodeGrantParams method), then it must be passed
 // into this method via the WithPIN option.
+// If the issuer defers issuance of any of the offered credentials, then those credentials won't be in the returned
+// slice. Instead, pending issuance handles can be retrieved using the PendingIssuances method.
 func (i *IssuerInitiatedInteraction) RequestCredentialWithPreAuth(jwtSigner api.JWTSigner,
 	opts ...RequestCredentialWithPreAuthOpt,
+) ([]*verifiable.Credential, error) {
+	return i.RequestCredentialWithPreAuthContext(context.Background(), jwtSigner, opts...)
+}
+
+// RequestCredentialWithPreAuthContext is the same as RequestCredentialWithPreAuth, but uses the given context for the
+// network requests it makes, so that they can be cancelled or given a deadline.
+func (i *IssuerInitiatedInteraction) RequestCredentialWithPreAuthContext(ctx context.Context,
+	jwtSigner api.JWTSigner, opts ...RequestCredentialWithPreAuthOpt,
 ) ([]*verifiable.Credential, error) {
 	processedOpts := processRequestCredentialWithPreAuthOpts(opts)
 
@@ -180,12 +262,24 @@ func (i *IssuerInitiatedInteraction) RequestCredentialWithPreAuth(jwtSigner api.
 			return nil, walleterror.NewInvalidSDKUsageError(ErrorModule,
 				errors.New("the credential offer requires a user PIN, but none was provided"))
 		}
+
+		if i.preAuthorizedCodeGrantParams.ClientIDRequired() && processedOpts.clientID == "" {
+			return nil, walleterror.NewInvalidSDKUsageError(ErrorModule,
+				errors.New("the issuer's authorization server requires a client ID, but none was provided"))
+		}
 	} else {
 		return nil, walleterror.NewInvalidSDKUsageError(ErrorModule,
 			errors.New("issuer does not support the pre-authorized code grant"))
 	}
 
-	return i.requestCredentialWithPreAuth(jwtSigner, processedOpts)
+	if processedOpts.batchIssuance != nil {
+		err := validateBatchIssuanceOpts(processedOpts.batchIssuance)
+		if err != nil {
+			return nil, walleterror.NewInvalidSDKUsageError(ErrorModule, err)
+		}
+	}
+
+	return i.requestCredentialWithPreAuth(ctx, jwtSigner, processedOpts)
 }
 
 // RequestCredentialWithAuth requests credential(s) from the issuer. This method can only be used for the
@@ -195,24 +289,147 @@ func (i *IssuerInitiatedInteraction) RequestCredentialWithPreAuth(jwtSigner api.
 // RequestCredentialWithAuth should be called only once all authorization pre-requisite steps have been completed.
 // The redirect URI that you pass in here should look like the redirect URI that you passed in to the
 // CreateAuthorizationURL, except that now it has some URL query parameters appended to it.
+// Deferred credentials are handled the same way as in RequestCredentialWithPreAuth.
+// Only the credentials that the authorization server granted access to are requested. Use the
+// GrantedCredentialConfigIDs method afterwards to check which of the offered credentials those were.
 func (i *IssuerInitiatedInteraction) RequestCredentialWithAuth(jwtSigner api.JWTSigner, redirectURIWithParams string,
+	opts ...RequestCredentialWithAuthOpt,
+) ([]*verifiable.Credential, error) {
+	return i.RequestCredentialWithAuthContext(context.Background(), jwtSigner, redirectURIWithParams, opts...)
+}
+
+// RequestCredentialWithAuthContext is the same as RequestCredentialWithAuth, but uses the given context for the
+// network requests it makes, so that they can be cancelled or given a deadline.
+func (i *IssuerInitiatedInteraction) RequestCredentialWithAuthContext(ctx context.Context, jwtSigner api.JWTSigner,
+	redirectURIWithParams string, opts ...RequestCredentialWithAuthOpt,
 ) ([]*verifiable.Credential, error) {
 	if !i.AuthorizationCodeGrantTypeSupported() {
 		return nil, walleterror.NewInvalidSDKUsageError(ErrorModule,
 			errors.New("issuer does not support the authorization code grant type"))
 	}
 
-	err := validateSignerKeyID(jwtSigner)
+	processedOpts, err := processRequestCredentialWithAuthOpts(opts)
 	if err != nil {
 		return nil, err
 	}
 
-	err = i.interaction.requestAccessToken(redirectURIWithParams)
+	err = validateSignerKeyID(jwtSigner)
 	if err != nil {
 		return nil, err
 	}
 
-	return i.interaction.requestCredentialWithAuth(jwtSigner, i.credentialFormats, i.credentialTypes, i.credentialContexts)
+	err = i.interaction.requestAccessToken(ctx, jwtSigner, redirectURIWithParams)
+	if err != nil {
+		return nil, err
+	}
+
+	grantedIndices := i.grantedCredentialIndices()
+
+	if len(grantedIndices) == 0 {
+		return nil, walleterror.NewExecutionError(ErrorModule,
+			NoCredentialsGrantedCode,
+			NoCredentialsGrantedError,
+			errors.New("the authorization server did not grant access to any of the offered credentials"))
+	}
+
+	return i.interaction.requestCredentialWithAuth(ctx, jwtSigner, selectIndices(i.credentialFormats, grantedIndices),
+		selectIndices(i.credentialTypes, grantedIndices), selectIndices(i.credentialContexts, grantedIndices),
+		selectIndices(i.credentialConfigIDs, grantedIndices), processedOpts.batchIssuance)
+}
+
+// GrantedCredentialConfigIDs returns the IDs of the offered credential configurations that the authorization
+// server granted access to, as indicated by the authorization_details in the token response. This may be a subset
+// of the offered credential configurations. nil is returned if no access token has been obtained yet.
+func (i *IssuerInitiatedInteraction) GrantedCredentialConfigIDs() []string {
+	if i.authToken == nil && i.interaction.authToken == nil {
+		return nil
+	}
+
+	return selectIndices(i.credentialConfigIDs, i.grantedCredentialIndices())
+}
+
+// GrantedCredentialIdentifiers returns the credential identifiers that the token response's authorization_details
+// listed for the offered credential configuration with the given ID. Each credential identifier refers to a
+// distinct credential dataset that can be issued using the access token. If the issuer provided credential
+// identifiers, then credentials are requested using the first identifier listed for each credential configuration.
+// nil is returned if the issuer didn't provide any credential identifiers for the given credential configuration.
+func (i *IssuerInitiatedInteraction) GrantedCredentialIdentifiers(configID string) []string {
+	index := slices.Index(i.credentialConfigIDs, configID)
+	if index == -1 {
+		return nil
+	}
+
+	return i.interaction.grantedCredentialIdentifiers(configID, i.credentialFormats[index],
+		i.credentialTypes[index])
+}
+
+// grantedCredentialIndices returns the indices of the offered credentials that the authorization server granted
+// access to, based on the authorization_details in the token response. If no authorization_details were returned,
+// then access was granted to all the offered credentials.
+func (i *IssuerInitiatedInteraction) grantedCredentialIndices() []int {
+	indices := make([]int, 0, len(i.credentialTypes))
+
+	for index := range i.credentialTypes {
+		if i.credentialGranted(index) {
+			indices = append(indices, index)
+		}
+	}
+
+	return indices
+}
+
+func (i *IssuerInitiatedInteraction) credentialGranted(index int) bool {
+	grantedAuthorizationDetails := i.interaction.grantedAuthorizationDetails
+
+	if grantedAuthorizationDetails == nil {
+		return true
+	}
+
+	for _, details := range grantedAuthorizationDetails {
+		if details.grants(i.credentialConfigIDs[index], i.credentialFormats[index], i.credentialTypes[index]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func selectIndices[T any](values []T, indices []int) []T {
+	selected := make([]T, len(indices))
+
+	for i, index := range indices {
+		selected[i] = values[index]
+	}
+
+	return selected
+}
+
+// PendingIssuances returns the credentials that the issuer deferred during the last credential request.
+// Each PendingIssuance can be stored and later redeemed using the RequestDeferredCredential function.
+func (i *IssuerInitiatedInteraction) PendingIssuances() []*PendingIssuance {
+	return i.interaction.pendingIssuances
+}
+
+// CredentialBatches returns the copies of each credential that were requested during the last credential request
+// using the WithBatchIssuance or WithBatchIssuanceForAuth option. It returns nil if batch issuance wasn't used.
+func (i *IssuerInitiatedInteraction) CredentialBatches() []*CredentialBatch {
+	return i.interaction.credentialBatches
+}
+
+// IssuanceGrant returns an IssuanceGrant that can be stored and later used with the RenewCredentials function to
+// request the same credentials again without any user interaction. It can only be called after credentials have
+// been requested, and only if the issuer provided a refresh token.
+func (i *IssuerInitiatedInteraction) IssuanceGrant() (*IssuanceGrant, error) {
+	authToken := i.interaction.authToken
+	if i.authToken != nil {
+		authToken = i.authToken
+	}
+
+	grantedIndices := i.grantedCredentialIndices()
+
+	return i.interaction.issuanceGrant(authToken, selectIndices(i.credentialConfigIDs, grantedIndices),
+		selectIndices(i.credentialFormats, grantedIndices), selectIndices(i.credentialTypes, grantedIndices),
+		selectIndices(i.credentialContexts, grantedIndices))
 }
 
 // IssuerURI returns the issuer's URI from the initiation request. It's useful to store this somewhere in case
@@ -264,7 +481,18 @@ func (i *IssuerInitiatedInteraction) AuthorizationCodeGrantParams() (*Authorizat
 
 // DynamicClientRegistrationSupported indicates whether the issuer supports dynamic client registration.
 func (i *IssuerInitiatedInteraction) DynamicClientRegistrationSupported() (bool, error) {
-	return i.interaction.dynamicClientRegistrationSupported()
+	return i.DynamicClientRegistrationSupportedContext(context.Background())
+}
+
+// DynamicClientRegistrationSupportedContext is the same as DynamicClientRegistrationSupported, but uses the given
+// context for fetching the issuer's metadata if it hasn't been fetched yet.
+func (i *IssuerInitiatedInteraction) DynamicClientRegistrationSupportedContext(ctx context.Context) (bool, error) {
+	err := i.useAuthorizationCodeGrantAuthorizationServer(ctx, getIssuerMetadataEventText)
+	if err != nil {
+		return false, err
+	}
+
+	return i.interaction.dynamicClientRegistrationSupported(ctx)
 }
 
 // DynamicClientRegistrationEndpoint returns the issuer's dynamic client registration endpoint.
@@ -272,12 +500,42 @@ func (i *IssuerInitiatedInteraction) DynamicClientRegistrationSupported() (bool,
 // if DynamicClientRegistrationSupported returns true.
 // This method will return an error if the issuer does not support dynamic client registration.
 func (i *IssuerInitiatedInteraction) DynamicClientRegistrationEndpoint() (string, error) {
-	return i.interaction.dynamicClientRegistrationEndpoint()
+	return i.DynamicClientRegistrationEndpointContext(context.Background())
+}
+
+// DynamicClientRegistrationEndpointContext is the same as DynamicClientRegistrationEndpoint, but uses the given
+// context for fetching the issuer's metadata if it hasn't been fetched yet.
+func (i *IssuerInitiatedInteraction) DynamicClientRegistrationEndpointContext(ctx context.Context) (string, error) {
+	err := i.useAuthorizationCodeGrantAuthorizationServer(ctx, getIssuerMetadataEventText)
+	if err != nil {
+		return "", err
+	}
+
+	return i.interaction.dynamicClientRegistrationEndpoint(ctx)
+}
+
+// useAuthorizationCodeGrantAuthorizationServer selects the authorization server for the authorization code grant,
+// which is also the one that dynamic client registration is done with. It does nothing if the issuer doesn't support
+// the authorization code grant.
+func (i *IssuerInitiatedInteraction) useAuthorizationCodeGrantAuthorizationServer(ctx context.Context,
+	parentEvent string,
+) error {
+	if i.authorizationCodeGrantParams == nil {
+		return nil
+	}
+
+	return i.interaction.useAuthorizationServer(ctx, i.authorizationCodeGrantParams.authorizationServer, parentEvent)
 }
 
 // IssuerMetadata returns the issuer's metadata.
 func (i *IssuerInitiatedInteraction) IssuerMetadata() (*issuer.Metadata, error) {
-	err := i.interaction.populateIssuerMetadata(getIssuerMetadataEventText)
+	return i.IssuerMetadataContext(context.Background())
+}
+
+// IssuerMetadataContext is the same as IssuerMetadata, but uses the given context for fetching the issuer's metadata
+// if it hasn't been fetched yet.
+func (i *IssuerInitiatedInteraction) IssuerMetadataContext(ctx context.Context) (*issuer.Metadata, error) {
+	err := i.interaction.populateIssuerMetadata(ctx, getIssuerMetadataEventText)
 	if err != nil {
 		return nil, err
 	}
@@ -294,12 +552,23 @@ func (i *IssuerInitiatedInteraction) OfferedCredentialsTypes() [][]string {
 // An error means that either the issuer failed the verification check, or something went wrong during the
 // process (and so a verification status could not be determined).
 func (i *IssuerInitiatedInteraction) VerifyIssuer() (string, error) {
-	return i.interaction.verifyIssuer()
+	return i.VerifyIssuerContext(context.Background())
+}
+
+// VerifyIssuerContext is the same as VerifyIssuer, but uses the given context for the network requests it makes.
+func (i *IssuerInitiatedInteraction) VerifyIssuerContext(ctx context.Context) (string, error) {
+	return i.interaction.verifyIssuer(ctx)
 }
 
 // IssuerTrustInfo returns issuer trust info like, did, domain, credential type, format.
 func (i *IssuerInitiatedInteraction) IssuerTrustInfo() (*IssuerTrustInfo, error) {
-	return i.interaction.issuerFullTrustInfo(i.credentialTypes, i.credentialFormats)
+	return i.IssuerTrustInfoContext(context.Background())
+}
+
+// IssuerTrustInfoContext is the same as IssuerTrustInfo, but uses the given context for the network requests it
+// makes.
+func (i *IssuerInitiatedInteraction) IssuerTrustInfoContext(ctx context.Context) (*IssuerTrustInfo, error) {
+	return i.interaction.issuerFullTrustInfo(ctx, i.credentialTypes, i.credentialFormats)
 }
 
 // RequireAcknowledgment if true indicates that the issuer requires to be acknowledged if
@@ -319,8 +588,16 @@ func (i *IssuerInitiatedInteraction) Acknowledgment() (*Acknowledgment, error) {
 	return i.interaction.requestedAcknowledgmentObj(authToken)
 }
 
+// NotificationID returns the notification ID that the issuer returned along with the given credential, which must be
+// one of the credentials returned by this interaction (including copies from CredentialBatches). It identifies the
+// credential when notifying the issuer about it using Acknowledgment.NotifyIssuer. An empty string is returned if
+// the issuer didn't return a notification ID for the credential.
+func (i *IssuerInitiatedInteraction) NotificationID(credential *verifiable.Credential) string {
+	return i.interaction.notificationIDs[credential]
+}
+
 //nolint:funlen
-func (i *IssuerInitiatedInteraction) requestCredentialWithPreAuth(jwtSigner api.JWTSigner,
+func (i *IssuerInitiatedInteraction) requestCredentialWithPreAuth(ctx context.Context, jwtSigner api.JWTSigner,
 	opts *requestCredentialWithPreAuthOpts,
 ) ([]*verifiable.Credential, error) {
 	timeStartRequestCredential := time.Now()
@@ -330,7 +607,13 @@ func (i *IssuerInitiatedInteraction) requestCredentialWithPreAuth(jwtSigner api.
 		return nil, err
 	}
 
-	err = i.interaction.populateIssuerMetadata(requestCredentialEventText)
+	err = i.interaction.useAuthorizationServer(ctx, i.preAuthorizedCodeGrantParams.authorizationServer,
+		requestCredentialEventText)
+	if err != nil {
+		return nil, err
+	}
+
+	err = i.interaction.enableDPoP(jwtSigner, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -358,17 +641,18 @@ func (i *IssuerInitiatedInteraction) requestCredentialWithPreAuth(jwtSigner api.
 		}
 	}
 
-	credentialResponses, err := i.getCredentialResponsesWithPreAuth(opts.pin, jwtSigner, attestationVP)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get credential response: %w", err)
+	var vcs []*verifiable.Credential
+
+	i.interaction.credentialBatches = nil
+
+	if opts.batchIssuance != nil {
+		vcs, err = i.requestCredentialBatchesWithPreAuth(ctx, opts, attestationVP)
+	} else {
+		vcs, err = i.requestCredentialsWithPreAuth(ctx, opts, jwtSigner, attestationVP)
 	}
 
-	vcs, err := i.interaction.getVCsFromCredentialResponses(credentialResponses)
 	if err != nil {
-		return nil, walleterror.NewExecutionError(
-			ErrorModule,
-			CredentialParseFailedCode,
-			CredentialParseError, err)
+		return nil, err
 	}
 
 	subjectIDs := getSubjectIDs(vcs)
@@ -394,29 +678,102 @@ func (i *IssuerInitiatedInteraction) requestCredentialWithPreAuth(jwtSigner api.
 	})
 }
 
-func (i *IssuerInitiatedInteraction) getCredentialResponsesWithPreAuth(
-	pin string, signer api.JWTSigner, attestationVP string,
+func (i *IssuerInitiatedInteraction) requestCredentialsWithPreAuth(ctx context.Context,
+	opts *requestCredentialWithPreAuthOpts,
+	jwtSigner api.JWTSigner, attestationVP string,
+) ([]*verifiable.Credential, error) {
+	credentialResponses, err := i.getCredentialResponsesWithPreAuth(ctx, opts, jwtSigner, attestationVP)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credential response: %w", err)
+	}
+
+	credentialResponses, i.interaction.pendingIssuances, err = i.interaction.splitCredentialResponses(
+		credentialResponses, i.GrantedCredentialConfigIDs(), i.authToken, jwtSigner.GetKeyID())
+	if err != nil {
+		return nil, err
+	}
+
+	vcs, err := i.interaction.getVCsFromCredentialResponses(credentialResponses, jwtSigner.GetKeyID())
+	if err != nil {
+		return nil, walleterror.NewExecutionError(
+			ErrorModule,
+			CredentialParseFailedCode,
+			CredentialParseError, err)
+	}
+
+	return vcs, nil
+}
+
+// requestCredentialBatchesWithPreAuth requests copies of each granted credential and returns all the copies.
+// The copies are also kept, grouped by credential, so that they can be retrieved using the CredentialBatches method.
+func (i *IssuerInitiatedInteraction) requestCredentialBatchesWithPreAuth(ctx context.Context,
+	opts *requestCredentialWithPreAuthOpts,
+	attestationVP string,
+) ([]*verifiable.Credential, error) {
+	tokenResponse, err := i.getPreAuthTokenResponseAndGrants(ctx, opts, attestationVP)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credential response: %w", err)
+	}
+
+	grantedIndices := i.grantedCredentialIndices()
+
+	i.interaction.credentialBatches, err = i.interaction.requestCredentialBatches(ctx, i.authToken,
+		tokenResponse.CNonce, credentialsToRequest(selectIndices(i.credentialFormats, grantedIndices),
+			selectIndices(i.credentialTypes, grantedIndices), selectIndices(i.credentialContexts, grantedIndices),
+			selectIndices(i.credentialConfigIDs, grantedIndices)), opts.batchIssuance)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credential response: %w", err)
+	}
+
+	return batchedCredentials(i.interaction.credentialBatches), nil
+}
+
+func (i *IssuerInitiatedInteraction) getCredentialResponsesWithPreAuth(ctx context.Context,
+	opts *requestCredentialWithPreAuthOpts, signer api.JWTSigner, attestationVP string,
 ) ([]CredentialResponse, error) {
+	tokenResponse, err := i.getPreAuthTokenResponseAndGrants(ctx, opts, attestationVP)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.getCredentialResponse(ctx, tokenResponse, tokenResponse.CNonce, signer, true)
+}
+
+// getPreAuthTokenResponseAndGrants gets an access token using the pre-authorized code and records which of the
+// offered credentials it grants access to.
+func (i *IssuerInitiatedInteraction) getPreAuthTokenResponseAndGrants(ctx context.Context,
+	opts *requestCredentialWithPreAuthOpts, attestationVP string,
+) (*preAuthTokenResponse, error) {
 	tokenEndpoint, err := i.interaction.getTokenEndpoint()
 	if err != nil {
 		return nil, err
 	}
 
-	tokenResponse, err := i.getPreAuthTokenResponse(pin, tokenEndpoint, attestationVP)
+	tokenResponse, err := i.getPreAuthTokenResponse(ctx, opts, tokenEndpoint, attestationVP)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get token response: %w", err)
 	}
 
-	i.authToken = &universalAuthToken{
+	i.authToken = &AuthToken{
 		AccessToken: tokenResponse.AccessToken, TokenType: tokenResponse.TokenType,
 		ExpiresAt: tokenResponse.expiry(), RefreshToken: tokenResponse.RefreshToken,
 	}
 
-	return i.getCredentialResponse(tokenResponse, tokenResponse.CNonce, signer, true)
+	i.interaction.grantedAuthorizationDetails = tokenResponse.AuthorizationDetails
+
+	if len(i.grantedCredentialIndices()) == 0 {
+		return nil, walleterror.NewExecutionError(ErrorModule,
+			NoCredentialsGrantedCode,
+			NoCredentialsGrantedError,
+			errors.New("the token response did not grant access to any of the offered credentials"))
+	}
+
+	return tokenResponse, nil
 }
 
 //nolint:funlen,gocyclo,nonamedreturns
 func (i *IssuerInitiatedInteraction) getCredentialResponse(
+	ctx context.Context,
 	tokenResponse *preAuthTokenResponse,
 	nonce any,
 	signer api.JWTSigner,
@@ -424,11 +781,6 @@ func (i *IssuerInitiatedInteraction) getCredentialResponse(
 	credentialResponse []CredentialResponse,
 	err error,
 ) {
-	proofJWT, err := i.interaction.createClaimsProof(nonce, signer)
-	if err != nil {
-		return nil, err
-	}
-
 	defer func() {
 		if err == nil || !allowRetry {
 			return
@@ -436,30 +788,39 @@ func (i *IssuerInitiatedInteraction) getCredentialResponse(
 
 		proofError := &InvalidProofError{}
 		if errors.As(err, &proofError) {
-			credentialResponse, err = i.getCredentialResponse(tokenResponse, nonce, signer, false)
+			credentialResponse, err = i.getCredentialResponse(ctx, tokenResponse, nonce, signer, false)
 		}
 	}()
 
-	if len(i.credentialTypes) > 1 && i.interaction.issuerMetadata.BatchCredentialEndpoint != "" {
-		return i.getCredentialResponsesBatch(proofJWT, tokenResponse)
+	grantedIndices := i.grantedCredentialIndices()
+	keyProofs := i.interaction.newKeyProofCreator(nonce, signer)
+
+	if len(grantedIndices) > 1 && i.interaction.issuerMetadata.BatchCredentialEndpoint != "" {
+		return i.getCredentialResponsesBatch(ctx, keyProofs, tokenResponse, grantedIndices)
 	}
 
-	credentialResponses := make([]CredentialResponse, len(i.credentialTypes))
+	credentialResponses := make([]CredentialResponse, len(grantedIndices))
 
-	for index := range i.credentialTypes {
-		requestBody, err := i.interaction.createCredentialRequestBody(proofJWT, i.credentialFormats[index],
-			i.credentialTypes[index], i.credentialContexts[index])
+	for responseIndex, index := range grantedIndices {
+		keyProof, err := keyProofs.proofForCredential(i.credentialConfigIDs[index], i.credentialFormats[index],
+			i.credentialTypes[index])
+		if err != nil {
+			return nil, err
+		}
+
+		requestBody, err := i.interaction.createCredentialRequestBody(keyProof, i.credentialConfigIDs[index],
+			i.credentialFormats[index], i.credentialTypes[index], i.credentialContexts[index])
 		if err != nil {
 			return nil, err
 		}
 
 		headers := http.Header{}
-		headers.Add("Authorization", "Bearer "+tokenResponse.AccessToken)
+		headers.Add("Authorization", authorizationHeader(tokenResponse.TokenType, tokenResponse.AccessToken))
 
-		fetchCredentialResponseEventText := fmt.Sprintf(fetchCredentialViaGETReqEventText, index+1,
-			len(i.credentialTypes), i.interaction.issuerMetadata.CredentialEndpoint)
+		fetchCredentialResponseEventText := fmt.Sprintf(fetchCredentialViaGETReqEventText, responseIndex+1,
+			len(grantedIndices), i.interaction.issuerMetadata.CredentialEndpoint)
 
-		responseBytes, err := httprequest.New(i.interaction.httpClient, i.interaction.metricsLogger).DoContext(context.TODO(),
+		responseBytes, err := httprequest.New(i.interaction.httpClient, i.interaction.metricsLogger).DoContext(ctx,
 			http.MethodPost, i.interaction.issuerMetadata.CredentialEndpoint, "application/json", headers,
 			bytes.NewReader(requestBody), fetchCredentialResponseEventText, requestCredentialEventText,
 			[]int{http.StatusOK, http.StatusCreated}, processCredentialErrorResponse)
@@ -467,6 +828,11 @@ func (i *IssuerInitiatedInteraction) getCredentialResponse(
 			return nil, err
 		}
 
+		responseBytes, err = i.interaction.decryptCredentialResponse(responseBytes)
+		if err != nil {
+			return nil, err
+		}
+
 		var credentialResponse CredentialResponse
 
 		err = json.Unmarshal(responseBytes, &credentialResponse)
@@ -474,7 +840,7 @@ func (i *IssuerInitiatedInteraction) getCredentialResponse(
 			return nil, fmt.Errorf("failed to unmarshal response from the issuer's credential endpoint: %w", err)
 		}
 
-		credentialResponses[index] = credentialResponse
+		credentialResponses[responseIndex] = credentialResponse
 
 		i.interaction.storeAcknowledgmentID(credentialResponse.AckID)
 	}
@@ -484,27 +850,36 @@ func (i *IssuerInitiatedInteraction) getCredentialResponse(
 
 //nolint:funlen
 func (i *IssuerInitiatedInteraction) getCredentialResponsesBatch(
-	proofJWT string,
+	ctx context.Context,
+	keyProofs *keyProofCreator,
 	tokenResponse *preAuthTokenResponse,
+	grantedIndices []int,
 ) ([]CredentialResponse, error) {
-	numberOfCredentials := len(i.credentialTypes)
+	numberOfCredentials := len(grantedIndices)
 	credentialResponses := make([]CredentialResponse, numberOfCredentials)
 
+	responseEncryption, err := i.interaction.credentialResponseEncryptionParams()
+	if err != nil {
+		return nil, err
+	}
+
 	batchCredentialReq := &batchCredentialRequest{
-		CredentialRequests: make([]credentialRequest, numberOfCredentials),
+		CredentialRequests:           make([]credentialRequest, numberOfCredentials),
+		CredentialResponseEncryption: responseEncryption,
 	}
 
-	for index := range i.credentialTypes {
-		batchCredentialReq.CredentialRequests[index] = credentialRequest{
-			CredentialDefinition: &credentialDefinition{
-				Type: i.credentialTypes[index],
-			},
-			Format: i.credentialFormats[index],
-			Proof: proof{
-				ProofType: "jwt",
-				JWT:       proofJWT,
-			},
+	for requestIndex, index := range grantedIndices {
+		keyProof, err := keyProofs.proofForCredential(i.credentialConfigIDs[index], i.credentialFormats[index],
+			i.credentialTypes[index])
+		if err != nil {
+			return nil, err
 		}
+
+		credentialReq := i.interaction.newCredentialRequest(i.credentialConfigIDs[index], i.credentialFormats[index],
+			i.credentialTypes[index], i.credentialContexts[index])
+		credentialReq.Proof = keyProof
+
+		batchCredentialReq.CredentialRequests[requestIndex] = credentialReq
 	}
 
 	b, err := json.Marshal(batchCredentialReq)
@@ -513,12 +888,12 @@ func (i *IssuerInitiatedInteraction) getCredentialResponsesBatch(
 	}
 
 	headers := http.Header{}
-	headers.Add("Authorization", "Bearer "+tokenResponse.AccessToken)
+	headers.Add("Authorization", authorizationHeader(tokenResponse.TokenType, tokenResponse.AccessToken))
 
 	fetchCredentialResponseEventText := fmt.Sprintf(fetchCredentialViaGETReqEventText, numberOfCredentials,
 		numberOfCredentials, i.interaction.issuerMetadata.BatchCredentialEndpoint)
 
-	b, err = httprequest.New(i.interaction.httpClient, i.interaction.metricsLogger).DoContext(context.TODO(),
+	b, err = httprequest.New(i.interaction.httpClient, i.interaction.metricsLogger).DoContext(ctx,
 		http.MethodPost, i.interaction.issuerMetadata.BatchCredentialEndpoint, "application/json", headers,
 		bytes.NewReader(b), fetchCredentialResponseEventText, requestCredentialEventText,
 		[]int{http.StatusOK, http.StatusCreated}, processCredentialErrorResponse)
@@ -526,6 +901,11 @@ func (i *IssuerInitiatedInteraction) getCredentialResponsesBatch(
 		return nil, err
 	}
 
+	b, err = i.interaction.decryptCredentialResponse(b)
+	if err != nil {
+		return nil, err
+	}
+
 	var response batchCredentialResponse
 
 	err = json.Unmarshal(b, &response)
@@ -535,11 +915,19 @@ func (i *IssuerInitiatedInteraction) getCredentialResponsesBatch(
 
 	for index, credentialResp := range response.CredentialResponses {
 		credentialResponses[index] = CredentialResponse{
-			Credential:      credentialResp.Credential,
-			TransactionID:   credentialResp.TransactionID,
-			CNonce:          *response.CNonce,
-			CNonceExpiresIn: *response.CNonceExpiresIn,
-			AckID:           credentialResp.AckID,
+			Credential:    credentialResp.Credential,
+			Credentials:   credentialResp.Credentials,
+			TransactionID: credentialResp.TransactionID,
+			Interval:      credentialResp.Interval,
+			AckID:         credentialResp.AckID,
+		}
+
+		if response.CNonce != nil {
+			credentialResponses[index].CNonce = *response.CNonce
+		}
+
+		if response.CNonceExpiresIn != nil {
+			credentialResponses[index].CNonceExpiresIn = *response.CNonceExpiresIn
 		}
 
 		i.interaction.storeAcknowledgmentID(credentialResp.AckID)
@@ -548,15 +936,19 @@ func (i *IssuerInitiatedInteraction) getCredentialResponsesBatch(
 	return credentialResponses, nil
 }
 
-func (i *IssuerInitiatedInteraction) getPreAuthTokenResponse(
-	pin, tokenEndpoint, attestationVP string,
+func (i *IssuerInitiatedInteraction) getPreAuthTokenResponse(ctx context.Context,
+	opts *requestCredentialWithPreAuthOpts, tokenEndpoint, attestationVP string,
 ) (*preAuthTokenResponse, error) {
 	params := url.Values{}
 	params.Add("grant_type", preAuthorizedGrantType)
 	params.Add("pre-authorized_code", i.preAuthorizedCodeGrantParams.preAuthorizedCode)
 
-	if pin != "" {
-		params.Add("tx_code", pin)
+	if opts.pin != "" {
+		params.Add("tx_code", opts.pin)
+	}
+
+	if opts.clientID != "" {
+		params.Add("client_id", opts.clientID)
 	}
 
 	if attestationVP != "" {
@@ -566,10 +958,10 @@ func (i *IssuerInitiatedInteraction) getPreAuthTokenResponse(
 
 	paramsReader := strings.NewReader(params.Encode())
 
-	responseBytes, err := httprequest.New(i.interaction.httpClient, i.interaction.metricsLogger).Do(
-		http.MethodPost, tokenEndpoint, "application/x-www-form-urlencoded", paramsReader,
+	responseBytes, err := httprequest.New(i.interaction.httpClient, i.interaction.metricsLogger).DoContext(ctx,
+		http.MethodPost, tokenEndpoint, "application/x-www-form-urlencoded", nil, paramsReader,
 		fmt.Sprintf(fetchTokenViaPOSTReqEventText, tokenEndpoint),
-		requestCredentialEventText, tokenErrorResponseHandler)
+		requestCredentialEventText, nil, tokenErrorResponseHandler)
 	if err != nil {
 		return nil, fmt.Errorf("issuer's token endpoint %s: %w", attestationVP, err)
 	}
@@ -652,7 +1044,8 @@ func tokenErrorResponseHandler(statusCode int, respBody []byte) error {
 	}
 }
 
-func getCredentialOffer(initiateIssuanceURI string, httpClient *http.Client, metricsLogger api.MetricsLogger,
+func getCredentialOffer(ctx context.Context, initiateIssuanceURI string, httpClient *http.Client,
+	metricsLogger api.MetricsLogger,
 ) (*CredentialOffer, error) {
 	requestURIParsed, err := url.Parse(initiateIssuanceURI)
 	if err != nil {
@@ -679,7 +1072,7 @@ func getCredentialOffer(initiateIssuanceURI string, httpClient *http.Client, met
 	case requestURIParsed.Query().Has("credential_offer_uri"):
 		credentialOfferURI := requestURIParsed.Query().Get("credential_offer_uri")
 
-		credentialOfferJSON, err = getCredentialOfferJSONFromCredentialOfferURI(
+		credentialOfferJSON, err = getCredentialOfferJSONFromCredentialOfferURI(ctx,
 			credentialOfferURI, httpClient, metricsLogger)
 		if err != nil {
 			return nil, err
@@ -707,12 +1100,12 @@ func getCredentialOffer(initiateIssuanceURI string, httpClient *http.Client, met
 	return &credentialOffer, nil
 }
 
-func getCredentialOfferJSONFromCredentialOfferURI(credentialOfferURI string,
+func getCredentialOfferJSONFromCredentialOfferURI(ctx context.Context, credentialOfferURI string,
 	httpClient *http.Client, metricsLogger api.MetricsLogger,
 ) ([]byte, error) {
-	responseBytes, err := httprequest.New(httpClient, metricsLogger).Do(
-		http.MethodGet, credentialOfferURI, "", nil,
-		fmt.Sprintf(fetchCredOfferViaGETReqEventText, credentialOfferURI), newInteractionEventText, nil)
+	responseBytes, err := httprequest.New(httpClient, metricsLogger).DoContext(ctx,
+		http.MethodGet, credentialOfferURI, "", nil, nil,
+		fmt.Sprintf(fetchCredOfferViaGETReqEventText, credentialOfferURI), newInteractionEventText, nil, nil)
 	if err != nil {
 		return nil, walleterror.NewValidationError(
 			ErrorModule,
@@ -746,21 +1139,33 @@ func determineCredentialParameters(
 			)
 		}
 
-		types[i] = configuration.CredentialDefinition.Type
-
 		if configuration.Format != jwtVCJSONCredentialFormat &&
 			configuration.Format != jwtVCJSONLDCredentialFormat &&
-			configuration.Format != ldpVCCredentialFormat {
+			configuration.Format != ldpVCCredentialFormat &&
+			!isSDJWTVCFormat(configuration.Format) &&
+			configuration.Format != msoMdocCredentialFormat {
 			return nil, nil, nil, walleterror.NewValidationError(
 				ErrorModule,
 				UnsupportedCredentialTypeInOfferCode,
 				UnsupportedCredentialTypeInOfferError,
 				fmt.Errorf("unsupported credential type (%s) in credential offer at index %d of "+
-					"credential_configurations_supported (must be jwt_vc_json or jwt_vc_json-ld)",
+					"credential_configurations_supported (must be jwt_vc_json, jwt_vc_json-ld, ldp_vc, "+
+					"vc+sd-jwt, dc+sd-jwt or mso_mdoc)",
 					configuration.Format, i),
 			)
 		}
 
+		types[i] = configuration.CredentialTypes()
+		if len(types[i]) == 0 {
+			return nil, nil, nil, walleterror.NewValidationError(
+				ErrorModule,
+				UnsupportedCredentialTypeInOfferCode,
+				UnsupportedCredentialTypeInOfferError,
+				fmt.Errorf("credential configuration (%s) in credential offer does not specify "+
+					"the credential type", id),
+			)
+		}
+
 		formats[i] = configuration.Format
 
 		if configuration.CredentialDefinition != nil && configuration.Format == ldpVCCredentialFormat {
@@ -834,7 +1239,10 @@ func createAttestationVP(
 }
 
 func signToken(claims interface{}, signer api.JWTSigner) (string, error) {
-	headers := jose.Headers{}
+	return signTokenWithHeaders(claims, signer, jose.Headers{})
+}
+
+func signTokenWithHeaders(claims interface{}, signer api.JWTSigner, headers jose.Headers) (string, error) {
 	headers["typ"] = "openid4vci-proof+jwt"
 
 	token, err := jwt.NewSigned(claims, jwt.SignParameters{AdditionalHeaders: headers}, signer)
@@ -8,6 +8,7 @@ package openid4ci_test
 
 import (
 	"bytes"
+	"context"
 	_ "embed"
 	"encoding/json"
 	"errors"
@@ -34,6 +35,7 @@ import (
 	"github.com/trustbloc/wallet-sdk/pkg/common"
 	"github.com/trustbloc/wallet-sdk/pkg/did/resolver"
 	"github.com/trustbloc/wallet-sdk/pkg/localkms"
+	"github.com/trustbloc/wallet-sdk/pkg/metadatacache"
 	"github.com/trustbloc/wallet-sdk/pkg/models"
 	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
 	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
@@ -232,6 +234,15 @@ func TestNewIssuerInitiatedInteraction(t *testing.T) {
 			newIssuerInitiatedInteraction(t, credentialOfferIssuanceURI)
 		})
 	})
+	t.Run("Context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		interaction, err := openid4ci.NewIssuerInitiatedInteractionContext(ctx,
+			createCredentialOfferIssuanceURI(t, "https://example.com", false, true), getTestClientConfig(t))
+		require.ErrorContains(t, err, "context canceled")
+		require.Nil(t, interaction)
+	})
 	t.Run("Fail to populate issuer metadata", func(t *testing.T) {
 		requestURI := createCredentialOfferIssuanceURI(t, "invalid url", true, true)
 		config := getTestClientConfig(t)
@@ -321,20 +332,25 @@ func TestNewIssuerInitiatedInteraction(t *testing.T) {
 
 		credentialOfferIssuanceURI := "openid-credential-offer://?credential_offer=" + credentialOfferEscaped
 
+		issuerMetadata := modifyCredentialMetadata(t, sampleIssuerMetadata, func(m *issuer.Metadata) {
+			m.GrantTypesSupported = []string{"urn:ietf:params:oauth:grant-type:pre-authorized_code"}
+		})
+
 		clientConfig := getTestClientConfig(t)
 		clientConfig.HTTPClient = &http.Client{
 			Transport: &mockTransport{
 				roundTripFunc: func(req *http.Request) (*http.Response, error) {
 					return &http.Response{
 						StatusCode: http.StatusOK,
-						Body:       io.NopCloser(bytes.NewBufferString(sampleIssuerMetadata)),
+						Body:       io.NopCloser(bytes.NewBufferString(issuerMetadata)),
 					}, nil
 				},
 			},
 		}
 
 		interaction, err := openid4ci.NewIssuerInitiatedInteraction(credentialOfferIssuanceURI, clientConfig)
-		require.EqualError(t, err, "no supported grant types found")
+		require.EqualError(t, err, "no supported grant types found: the credential offer does not specify "+
+			"any grants and the issuer's authorization server does not support the authorization code grant")
 		require.Nil(t, interaction)
 	})
 	t.Run("Unsupported credential type", func(t *testing.T) {
@@ -535,6 +551,36 @@ func (m *inMemoryMetricsLogger) Log(metricsEvent *api.MetricsEvent) error {
 	return nil
 }
 
+func TestNewIssuerInitiatedInteraction_MetadataCache(t *testing.T) {
+	issuerServerHandler := &mockIssuerServerHandler{t: t}
+
+	var metadataRequests int
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path == "/.well-known/openid-credential-issuer" {
+			metadataRequests++
+
+			writer.Header().Set("Cache-Control", "max-age=300")
+		}
+
+		issuerServerHandler.ServeHTTP(writer, request)
+	}))
+	defer server.Close()
+
+	issuerServerHandler.issuerMetadata = strings.ReplaceAll(sampleIssuerMetadata, serverURLPlaceholder, server.URL)
+
+	cache := metadatacache.New()
+
+	for range 3 {
+		newIssuerInitiatedInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false, true),
+			func(config *openid4ci.ClientConfig) {
+				config.MetadataCache = cache
+			})
+	}
+
+	require.Equal(t, 1, metadataRequests)
+}
+
 func TestIssuerInitiatedInteraction_CreateAuthorizationURL(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		issuerServerHandler := &mockIssuerServerHandler{
@@ -0,0 +1,308 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	diddoc "github.com/trustbloc/did-go/doc/did"
+	"github.com/trustbloc/vc-go/dataintegrity/suite/ecdsa2019"
+	"github.com/trustbloc/vc-go/dataintegrity/suite/eddsa2022"
+	"github.com/trustbloc/vc-go/jwt"
+	"github.com/trustbloc/vc-go/presexch"
+	"github.com/trustbloc/vc-go/proof/ldproofs/ecdsasecp256k1signature2019"
+	"github.com/trustbloc/vc-go/proof/ldproofs/ed25519signature2018"
+	"github.com/trustbloc/vc-go/proof/ldproofs/ed25519signature2020"
+	"github.com/trustbloc/vc-go/proof/ldproofs/jsonwebsignature2020"
+	"github.com/trustbloc/vc-go/verifiable"
+
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/ldproof"
+	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+const (
+	jwtProofType   = "jwt"
+	ldpVPProofType = "ldp_vp"
+	diVPProofType  = "di_vp"
+
+	keyAttestationHeader = "key_attestation"
+)
+
+// The signing algorithms used for ldp_vp and di_vp proofs if the issuer's metadata doesn't list any.
+//
+//nolint:gochecknoglobals
+var defaultProofSigningAlgs = map[string][]string{
+	ldpVPProofType: {
+		ed25519signature2018.ProofType, ed25519signature2020.ProofType, jsonwebsignature2020.ProofType,
+		ecdsasecp256k1signature2019.ProofType,
+	},
+	diVPProofType: {ecdsa2019.SuiteTypeNew, eddsa2022.SuiteType},
+}
+
+// keyProofCreator creates the key proofs that are included in credential requests. Since a single nonce is used for
+// all the credentials requested at once, each distinct proof is only created once and then reused.
+type keyProofCreator struct {
+	interaction *interaction
+	nonce       any
+	signer      api.JWTSigner
+	proofs      map[string]*proof
+}
+
+func (i *interaction) newKeyProofCreator(nonce any, signer api.JWTSigner) *keyProofCreator {
+	return &keyProofCreator{
+		interaction: i,
+		nonce:       nonce,
+		signer:      signer,
+		proofs:      map[string]*proof{},
+	}
+}
+
+// proofForCredential returns a key proof for the credential with the given configuration ID, format and types,
+// using a proof type that the issuer supports for that credential. configID may be empty if it isn't known.
+func (k *keyProofCreator) proofForCredential(configID, credentialFormat string,
+	credentialTypes []string,
+) (*proof, error) {
+	if configID == "" {
+		configID = k.interaction.findCredentialConfigurationID(credentialFormat, credentialTypes)
+	}
+
+	proofType, proofTypeSupported, err := k.selectProofType(
+		k.interaction.issuerMetadata.CredentialConfigurationsSupported[configID])
+	if err != nil {
+		return nil, err
+	}
+
+	cacheKey := proofCacheKey(proofType, proofTypeSupported)
+
+	if existingProof, ok := k.proofs[cacheKey]; ok {
+		return existingProof, nil
+	}
+
+	var keyProof *proof
+
+	if proofType == jwtProofType {
+		keyProof, err = k.createJWTProof(proofTypeSupported.KeyAttestationsRequired)
+	} else {
+		keyProof, err = k.createPresentationProof(proofType, proofTypeSupported.ProofSigningAlgValuesSupported)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	k.proofs[cacheKey] = keyProof
+
+	return keyProof, nil
+}
+
+// selectProofType determines which key proof type to use based on the proof types that the issuer supports for the
+// given credential configuration. It returns the proof type along with the issuer's metadata for it. For ldp_vp and
+// di_vp proofs, the signing algorithms (LD proof types or data integrity cryptosuites) to choose from are always set.
+//
+// JWT proofs are preferred, unless the issuer only supports them with algorithms that the signer doesn't use.
+// ldp_vp and di_vp proofs can only be used if the signer implements api.CryptoProvider.
+func (k *keyProofCreator) selectProofType(
+	config *issuer.CredentialConfigurationSupported,
+) (string, issuer.ProofTypeSupported, error) {
+	if config == nil || len(config.ProofTypesSupported) == 0 {
+		return jwtProofType, issuer.ProofTypeSupported{}, nil
+	}
+
+	jwtProofTypeSupported, jwtSupported := config.ProofTypesSupported[jwtProofType]
+
+	if jwtSupported && k.signerUsesOneOf(jwtProofTypeSupported.ProofSigningAlgValuesSupported) {
+		return jwtProofType, jwtProofTypeSupported, nil
+	}
+
+	if _, canSignPresentations := k.signer.(api.CryptoProvider); canSignPresentations {
+		for _, proofType := range []string{diVPProofType, ldpVPProofType} {
+			if proofTypeSupported, ok := config.ProofTypesSupported[proofType]; ok {
+				if len(proofTypeSupported.ProofSigningAlgValuesSupported) == 0 {
+					proofTypeSupported.ProofSigningAlgValuesSupported = defaultProofSigningAlgs[proofType]
+				}
+
+				return proofType, proofTypeSupported, nil
+			}
+		}
+	}
+
+	if jwtSupported {
+		return jwtProofType, jwtProofTypeSupported, nil
+	}
+
+	return "", issuer.ProofTypeSupported{}, walleterror.NewExecutionError(ErrorModule,
+		UnsupportedProofTypeCode,
+		UnsupportedProofTypeError,
+		fmt.Errorf("none of the proof types supported by the issuer (%s) can be created with the given signer",
+			strings.Join(sortedKeys(config.ProofTypesSupported), ", ")))
+}
+
+// signerUsesOneOf indicates whether the signer's algorithm is one of the given algorithms. If no algorithms are
+// given, or if the signer's algorithm can't be determined, then it's assumed to be usable.
+func (k *keyProofCreator) signerUsesOneOf(algs []string) bool {
+	if len(algs) == 0 {
+		return true
+	}
+
+	headers, err := k.signer.CreateJWTHeaders(jwt.SignParameters{})
+	if err != nil {
+		return true
+	}
+
+	alg, ok := headers.Algorithm()
+
+	return !ok || slices.Contains(algs, alg)
+}
+
+// createJWTProof creates a jwt proof. If the issuer requires a key attestation, then one is obtained from the
+// key attestation provider and included in the proof's header.
+func (k *keyProofCreator) createJWTProof(keyAttestationsRequired *issuer.KeyAttestationsRequired) (*proof, error) {
+	var keyAttestation string
+
+	if keyAttestationsRequired != nil {
+		var err error
+
+		keyAttestation, err = k.getKeyAttestation(keyAttestationsRequired)
+		if err != nil {
+			return nil, walleterror.NewExecutionError(ErrorModule,
+				KeyAttestationFailedCode,
+				KeyAttestationFailedError,
+				err)
+		}
+	}
+
+	proofJWT, err := k.interaction.createClaimsProof(k.nonce, k.signer, keyAttestation)
+	if err != nil {
+		return nil, err
+	}
+
+	return &proof{ProofType: jwtProofType, JWT: proofJWT}, nil
+}
+
+func (k *keyProofCreator) getKeyAttestation(keyAttestationsRequired *issuer.KeyAttestationsRequired) (string, error) {
+	if k.interaction.keyAttestationProvider == nil {
+		return "", errors.New("the issuer requires a key attestation, but no key attestation provider was set")
+	}
+
+	nonce, _ := k.nonce.(string) //nolint:errcheck // A missing nonce isn't included in the attestation.
+
+	keyAttestation, err := k.interaction.keyAttestationProvider.GetKeyAttestation(k.signer,
+		&api.KeyAttestationRequirements{
+			KeyStorage:         keyAttestationsRequired.KeyStorage,
+			UserAuthentication: keyAttestationsRequired.UserAuthentication,
+		}, nonce)
+	if err != nil {
+		return "", fmt.Errorf("failed to get key attestation: %w", err)
+	}
+
+	return keyAttestation, nil
+}
+
+// createPresentationProof creates an ldp_vp or di_vp proof, which is a verifiable presentation from the holder that's
+// signed with a linked data proof or data integrity proof, using the c_nonce as the challenge and the issuer as
+// the domain.
+func (k *keyProofCreator) createPresentationProof(proofType string, proofSigningAlgs []string) (*proof, error) {
+	presentationJSON, err := k.signPresentation(proofSigningAlgs)
+	if err != nil {
+		return nil, walleterror.NewExecutionError(ErrorModule,
+			KeyProofCreationFailedCode,
+			KeyProofCreationFailedError,
+			fmt.Errorf("failed to create %s proof: %w", proofType, err))
+	}
+
+	keyProof := &proof{ProofType: proofType}
+
+	if proofType == diVPProofType {
+		keyProof.DiVP = presentationJSON
+	} else {
+		keyProof.LdpVP = presentationJSON
+	}
+
+	return keyProof, nil
+}
+
+func (k *keyProofCreator) signPresentation(proofSigningAlgs []string) ([]byte, error) {
+	keyID := k.signer.GetKeyID()
+
+	holderDID := strings.Split(keyID, "#")[0]
+
+	verificationMethod, err := k.resolveVerificationMethod(holderDID, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	presentation, err := verifiable.NewPresentation()
+	if err != nil {
+		return nil, err
+	}
+
+	presentation.Holder = holderDID
+
+	challenge, _ := k.nonce.(string) //nolint:errcheck // A missing nonce results in an empty challenge.
+
+	err = ldproof.New(k.signer.(api.CryptoProvider).Crypto(), //nolint:forcetypeassert // checked by selectProofType
+		k.interaction.documentLoader, k.interaction.didResolver).Add(presentation,
+		ldproof.WithLdpType(&presexch.LdpType{ProofType: proofSigningAlgs}),
+		ldproof.WithVerificationMethod(verificationMethod),
+		ldproof.WithDID(holderDID),
+		ldproof.WithChallenge(challenge),
+		ldproof.WithDomain(k.interaction.issuerURI),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return presentation.MarshalJSON()
+}
+
+func (k *keyProofCreator) resolveVerificationMethod(holderDID, keyID string) (*diddoc.VerificationMethod, error) {
+	if k.interaction.didResolver == nil {
+		return nil, errors.New("a DID resolver is required to create the proof")
+	}
+
+	docResolution, err := k.interaction.didResolver.Resolve(holderDID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve the holder's DID: %w", err)
+	}
+
+	for _, verificationMethod := range docResolution.DIDDocument.VerificationMethod {
+		if verificationMethod.ID == keyID || holderDID+verificationMethod.ID == keyID {
+			return &verificationMethod, nil
+		}
+	}
+
+	return nil, fmt.Errorf("verification method %s not found in the holder's DID document", keyID)
+}
+
+// proofCacheKey returns a key that identifies proofs that can be reused for credentials with the same requirements.
+func proofCacheKey(proofType string, proofTypeSupported issuer.ProofTypeSupported) string {
+	cacheKey := proofType + " " + strings.Join(proofTypeSupported.ProofSigningAlgValuesSupported, " ")
+
+	if keyAttestationsRequired := proofTypeSupported.KeyAttestationsRequired; keyAttestationsRequired != nil {
+		cacheKey += " key attestation: " + strings.Join(keyAttestationsRequired.KeyStorage, " ") + " / " +
+			strings.Join(keyAttestationsRequired.UserAuthentication, " ")
+	}
+
+	return cacheKey
+}
+
+func sortedKeys(proofTypesSupported map[string]issuer.ProofTypeSupported) []string {
+	keys := make([]string, 0, len(proofTypesSupported))
+
+	for key := range proofTypesSupported {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
@@ -0,0 +1,314 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/did-go/doc/did"
+	arieskms "github.com/trustbloc/kms-go/spi/kms"
+
+	"github.com/trustbloc/wallet-sdk/internal/testutil"
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/common"
+	"github.com/trustbloc/wallet-sdk/pkg/localkms"
+	"github.com/trustbloc/wallet-sdk/pkg/models"
+	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+type receivedPresentationProof struct {
+	Holder string `json:"holder"`
+	Proof  struct {
+		Type               string `json:"type"`
+		Cryptosuite        string `json:"cryptosuite"`
+		VerificationMethod string `json:"verificationMethod"`
+		Challenge          string `json:"challenge"`
+		Domain             string `json:"domain"`
+	} `json:"proof"`
+}
+
+// setProofTypesSupported makes the issuer support only the given proof types for all of its credentials.
+func setProofTypesSupported(t *testing.T, handler *mockAuthorizationDetailsHandler,
+	proofTypesSupported map[string]issuer.ProofTypeSupported,
+) {
+	t.Helper()
+
+	handler.issuerMetadata = modifyCredentialMetadata(t, handler.issuerMetadata, func(m *issuer.Metadata) {
+		// Credentials are requested one at a time so that each credential request gets recorded.
+		m.BatchCredentialEndpoint = ""
+
+		for _, config := range m.CredentialConfigurationsSupported {
+			config.ProofTypesSupported = proofTypesSupported
+		}
+	})
+}
+
+// newKeyProofTestInteraction returns an interaction with a DID resolver that resolves the DID of the returned signer.
+func newKeyProofTestInteraction(t *testing.T, issuanceURI string,
+) (*openid4ci.IssuerInitiatedInteraction, *common.JWSSigner) {
+	t.Helper()
+
+	localKMS, err := localkms.NewLocalKMS(localkms.Config{Storage: localkms.NewMemKMSStore()})
+	require.NoError(t, err)
+
+	_, publicKey, err := localKMS.Create(arieskms.ED25519Type)
+	require.NoError(t, err)
+
+	publicKey.KeyID = mockDID + "#key-1"
+
+	didResolver := &holderDIDResolver{mockResolver: &mockResolver{keyWriter: localKMS, pubJWK: publicKey}}
+
+	didDocResolution, err := didResolver.Resolve(mockDID)
+	require.NoError(t, err)
+
+	signer, err := common.NewJWSSigner(
+		models.VerificationMethodFromDoc(&didDocResolution.DIDDocument.VerificationMethod[0]), localKMS.GetCrypto())
+	require.NoError(t, err)
+
+	interaction := newIssuerInitiatedInteraction(t, issuanceURI, func(config *openid4ci.ClientConfig) {
+		config.DIDResolver = didResolver
+		config.DocumentLoader = testutil.DocumentLoader(t)
+	})
+
+	return interaction, signer
+}
+
+func TestIssuerInitiatedInteraction_KeyProofs(t *testing.T) {
+	t.Run("ldp_vp proof", func(t *testing.T) {
+		handler, server, issuanceURI := newMultipleCredentialsServer(t)
+		defer server.Close()
+
+		setProofTypesSupported(t, handler, map[string]issuer.ProofTypeSupported{
+			"ldp_vp": {ProofSigningAlgValuesSupported: []string{"Ed25519Signature2018"}},
+		})
+
+		interaction, signer := newKeyProofTestInteraction(t, issuanceURI)
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(signer, openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+		require.Len(t, credentials, 2)
+
+		require.Len(t, handler.credentialRequests, 2)
+
+		for _, credentialRequest := range handler.credentialRequests {
+			require.Equal(t, "ldp_vp", credentialRequest.Proof.ProofType)
+			require.Empty(t, credentialRequest.Proof.JWT)
+
+			var presentation receivedPresentationProof
+
+			require.NoError(t, json.Unmarshal(credentialRequest.Proof.LdpVP, &presentation))
+			require.Equal(t, mockDID, presentation.Holder)
+			require.Equal(t, "Ed25519Signature2018", presentation.Proof.Type)
+			require.Equal(t, mockDID+"#key-1", presentation.Proof.VerificationMethod)
+			require.Equal(t, "tZignsnFbp", presentation.Proof.Challenge)
+			require.Equal(t, server.URL, presentation.Proof.Domain)
+		}
+	})
+	t.Run("di_vp proof", func(t *testing.T) {
+		handler, server, issuanceURI := newMultipleCredentialsServer(t)
+		defer server.Close()
+
+		setProofTypesSupported(t, handler, map[string]issuer.ProofTypeSupported{
+			"di_vp": {ProofSigningAlgValuesSupported: []string{"eddsa-rdfc-2022"}},
+			"jwt":   {ProofSigningAlgValuesSupported: []string{"ES256"}},
+		})
+
+		interaction, signer := newKeyProofTestInteraction(t, issuanceURI)
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(signer, openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+		require.Len(t, credentials, 2)
+
+		require.Equal(t, "di_vp", handler.credentialRequests[0].Proof.ProofType)
+
+		var presentation receivedPresentationProof
+
+		require.NoError(t, json.Unmarshal(handler.credentialRequests[0].Proof.DiVP, &presentation))
+		require.Equal(t, "DataIntegrityProof", presentation.Proof.Type)
+		require.Equal(t, "eddsa-rdfc-2022", presentation.Proof.Cryptosuite)
+		require.Equal(t, "tZignsnFbp", presentation.Proof.Challenge)
+		require.Equal(t, server.URL, presentation.Proof.Domain)
+	})
+	t.Run("JWT proof preferred when the signer's algorithm is supported", func(t *testing.T) {
+		handler, server, issuanceURI := newMultipleCredentialsServer(t)
+		defer server.Close()
+
+		setProofTypesSupported(t, handler, map[string]issuer.ProofTypeSupported{
+			"jwt":    {ProofSigningAlgValuesSupported: []string{"EdDSA"}},
+			"ldp_vp": {},
+		})
+
+		interaction, signer := newKeyProofTestInteraction(t, issuanceURI)
+
+		_, err := interaction.RequestCredentialWithPreAuth(signer, openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+
+		require.Equal(t, "jwt", handler.credentialRequests[0].Proof.ProofType)
+		require.NotEmpty(t, handler.credentialRequests[0].Proof.JWT)
+		require.Nil(t, handler.credentialRequests[0].Proof.LdpVP)
+	})
+	t.Run("JWT proof with key attestation", func(t *testing.T) {
+		handler, server, issuanceURI := newMultipleCredentialsServer(t)
+		defer server.Close()
+
+		setProofTypesSupported(t, handler, map[string]issuer.ProofTypeSupported{
+			"jwt": {KeyAttestationsRequired: &issuer.KeyAttestationsRequired{KeyStorage: []string{"iso_18045_high"}}},
+		})
+
+		keyAttestationProvider := &keyAttestationProviderMock{keyAttestation: "key-attestation-jwt"}
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI, func(config *openid4ci.ClientConfig) {
+			config.KeyAttestationProvider = keyAttestationProvider
+		})
+
+		_, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+
+		// The same proof is used for both credentials, so only one key attestation is needed.
+		require.Equal(t, 1, keyAttestationProvider.calls)
+		require.Equal(t, []string{"iso_18045_high"}, keyAttestationProvider.requirements.KeyStorage)
+		require.Equal(t, "tZignsnFbp", keyAttestationProvider.nonce)
+
+		require.Len(t, handler.credentialRequests, 2)
+
+		for _, credentialRequest := range handler.credentialRequests {
+			require.Equal(t, "jwt", credentialRequest.Proof.ProofType)
+
+			headerBytes, err := base64.RawURLEncoding.DecodeString(
+				strings.Split(credentialRequest.Proof.JWT, ".")[0])
+			require.NoError(t, err)
+
+			var header map[string]interface{}
+
+			require.NoError(t, json.Unmarshal(headerBytes, &header))
+			require.Equal(t, "key-attestation-jwt", header["key_attestation"])
+		}
+	})
+	t.Run("Key attestation required but no provider set", func(t *testing.T) {
+		handler, server, issuanceURI := newMultipleCredentialsServer(t)
+		defer server.Close()
+
+		setProofTypesSupported(t, handler, map[string]issuer.ProofTypeSupported{
+			"jwt": {KeyAttestationsRequired: &issuer.KeyAttestationsRequired{}},
+		})
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI)
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		require.ErrorContains(t, err, "KEY_ATTESTATION_FAILED(OCI1-0033)")
+		require.ErrorContains(t, err, "no key attestation provider was set")
+		require.Nil(t, credentials)
+	})
+	t.Run("Key attestation provider fails", func(t *testing.T) {
+		handler, server, issuanceURI := newMultipleCredentialsServer(t)
+		defer server.Close()
+
+		setProofTypesSupported(t, handler, map[string]issuer.ProofTypeSupported{
+			"jwt": {KeyAttestationsRequired: &issuer.KeyAttestationsRequired{}},
+		})
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI, func(config *openid4ci.ClientConfig) {
+			config.KeyAttestationProvider = &keyAttestationProviderMock{err: errors.New("attestation error")}
+		})
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		require.ErrorContains(t, err, "KEY_ATTESTATION_FAILED(OCI1-0033)")
+		require.ErrorContains(t, err, "failed to get key attestation: attestation error")
+		require.Nil(t, credentials)
+	})
+	t.Run("No supported proof type", func(t *testing.T) {
+		handler, server, issuanceURI := newMultipleCredentialsServer(t)
+		defer server.Close()
+
+		setProofTypesSupported(t, handler, map[string]issuer.ProofTypeSupported{
+			"ldp_vp": {},
+			"cwt":    {},
+		})
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI)
+
+		// The mock signer doesn't provide access to a crypto implementation, so it can only create JWT proofs.
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		require.ErrorContains(t, err, "UNSUPPORTED_PROOF_TYPE(OCI1-0031)")
+		require.ErrorContains(t, err, "(cwt, ldp_vp)")
+		require.Nil(t, credentials)
+	})
+	t.Run("Holder's verification method not found", func(t *testing.T) {
+		handler, server, issuanceURI := newMultipleCredentialsServer(t)
+		defer server.Close()
+
+		setProofTypesSupported(t, handler, map[string]issuer.ProofTypeSupported{"ldp_vp": {}})
+
+		interaction, signer := newKeyProofTestInteraction(t, issuanceURI)
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&signerWithKeyID{
+			JWSSigner: signer, keyID: mockDID + "#unknown",
+		}, openid4ci.WithPIN("1234"))
+		require.ErrorContains(t, err, "KEY_PROOF_CREATION_FAILED(OCI1-0032)")
+		require.ErrorContains(t, err, "verification method did:test:foo#unknown not found")
+		require.Nil(t, credentials)
+	})
+}
+
+// holderDIDResolver resolves DID documents whose key can also be used for authentication, as required for signing
+// presentations.
+type holderDIDResolver struct {
+	*mockResolver
+}
+
+func (r *holderDIDResolver) Resolve(id string) (*did.DocResolution, error) {
+	docResolution, err := r.mockResolver.Resolve(id)
+	if err != nil {
+		return nil, err
+	}
+
+	docResolution.DIDDocument.Authentication = []did.Verification{{
+		VerificationMethod: docResolution.DIDDocument.VerificationMethod[0],
+		Relationship:       did.Authentication,
+	}}
+
+	return docResolution, nil
+}
+
+type keyAttestationProviderMock struct {
+	keyAttestation string
+	err            error
+
+	calls        int
+	requirements *api.KeyAttestationRequirements
+	nonce        string
+}
+
+func (m *keyAttestationProviderMock) GetKeyAttestation(_ api.JWTSigner,
+	requirements *api.KeyAttestationRequirements, nonce string,
+) (string, error) {
+	m.calls++
+	m.requirements = requirements
+	m.nonce = nonce
+
+	return m.keyAttestation, m.err
+}
+
+// signerWithKeyID overrides the key ID of a signer.
+type signerWithKeyID struct {
+	*common.JWSSigner
+	keyID string
+}
+
+func (s *signerWithKeyID) GetKeyID() string {
+	return s.keyID
+}
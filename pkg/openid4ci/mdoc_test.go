@@ -0,0 +1,141 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/pkg/mdoc"
+	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+const mDLDocType = "org.iso.18013.5.1.mDL"
+
+//go:embed testdata/sample_credential_mdl.mdoc
+var sampleMDL string
+
+// newMdocServer returns a server for an issuer that offers an mDL in the mso_mdoc format, along with an
+// issuance URI for a credential offer.
+func newMdocServer(t *testing.T) (*mockAuthorizationDetailsHandler, string) {
+	t.Helper()
+
+	handler, server, issuanceURI := newMultipleCredentialsServer(t)
+	t.Cleanup(server.Close)
+
+	credentialResponse, err := json.Marshal(map[string]interface{}{"credential": sampleMDL})
+	require.NoError(t, err)
+
+	handler.credentialResponse = credentialResponse
+	handler.issuerMetadata = modifyCredentialMetadata(t, handler.issuerMetadata, func(m *issuer.Metadata) {
+		config := m.CredentialConfigurationsSupported["credential_configuration_id_1"]
+		config.Format = "mso_mdoc"
+		config.CredentialDefinition = nil
+		config.Doctype = mDLDocType
+		// A claims object is only used by issuers that implement drafts of the specification before draft 15.
+		config.Claims = &map[string]interface{}{"org.iso.18013.5.1": map[string]interface{}{}}
+
+		delete(m.CredentialConfigurationsSupported, secondCredentialConfigID)
+	})
+
+	parsedURI, err := url.Parse(issuanceURI)
+	require.NoError(t, err)
+
+	var credentialOffer openid4ci.CredentialOffer
+
+	err = json.Unmarshal([]byte(parsedURI.Query().Get("credential_offer")), &credentialOffer)
+	require.NoError(t, err)
+
+	credentialOffer.CredentialConfigurationIDs = []string{"credential_configuration_id_1"}
+
+	credentialOfferBytes, err := json.Marshal(credentialOffer)
+	require.NoError(t, err)
+
+	return handler, "openid-credential-offer://?credential_offer=" + url.QueryEscape(string(credentialOfferBytes))
+}
+
+func TestIssuerInitiatedInteraction_Mdoc(t *testing.T) {
+	t.Run("Pre-authorized code flow", func(t *testing.T) {
+		handler, issuanceURI := newMdocServer(t)
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI, enableVCProofChecks())
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+
+		require.Equal(t, []string{"VerifiableCredential", mDLDocType}, credentials[0].Contents().Types)
+
+		issuedMDL, err := mdoc.FromCredential(credentials[0])
+		require.NoError(t, err)
+		require.Equal(t, "Mustermann", issuedMDL.NameSpaces["org.iso.18013.5.1"]["family_name"])
+
+		require.Len(t, handler.credentialRequests, 1)
+		require.Equal(t, "mso_mdoc", handler.credentialRequests[0].Format)
+		require.Equal(t, mDLDocType, handler.credentialRequests[0].Doctype)
+		require.Nil(t, handler.credentialRequests[0].CredentialDefinition)
+	})
+	t.Run("Authorization code flow", func(t *testing.T) {
+		handler, issuanceURI := newMdocServer(t)
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI, enableVCProofChecks())
+
+		authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+		require.NoError(t, err)
+
+		parsedAuthURL, err := url.Parse(authURL)
+		require.NoError(t, err)
+
+		var requestedAuthorizationDetails []map[string]interface{}
+
+		err = json.Unmarshal([]byte(parsedAuthURL.Query().Get("authorization_details")),
+			&requestedAuthorizationDetails)
+		require.NoError(t, err)
+		require.Len(t, requestedAuthorizationDetails, 1)
+		require.Equal(t, "mso_mdoc", requestedAuthorizationDetails[0]["format"])
+		require.Equal(t, mDLDocType, requestedAuthorizationDetails[0]["doctype"])
+		require.NotContains(t, requestedAuthorizationDetails[0], "credential_definition")
+
+		handler.grantedAuthorizationDetails = `[{"type":"openid_credential","format":"mso_mdoc",` +
+			`"doctype":"` + mDLDocType + `"}]`
+
+		credentials, err := interaction.RequestCredentialWithAuth(&jwtSignerMock{keyID: mockKeyID},
+			"redirectURI?code=1234&state="+getStateFromAuthURL(t, authURL))
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+		require.Equal(t, []string{"credential_configuration_id_1"}, interaction.GrantedCredentialConfigIDs())
+	})
+	t.Run("Tampered mdoc", func(t *testing.T) {
+		handler, issuanceURI := newMdocServer(t)
+
+		issuerSigned, err := base64.RawURLEncoding.DecodeString(sampleMDL)
+		require.NoError(t, err)
+
+		// Modify the issuer's signature, which is at the end of the IssuerSigned structure.
+		issuerSigned[len(issuerSigned)-1] ^= 1
+
+		handler.credentialResponse, err = json.Marshal(map[string]interface{}{
+			"credential": base64.RawURLEncoding.EncodeToString(issuerSigned),
+		})
+		require.NoError(t, err)
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI, enableVCProofChecks())
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		require.ErrorContains(t, err, "CREDENTIAL_PARSE_FAILED(OCI1-0007)")
+		require.ErrorContains(t, err, "failed to verify the issuer's signature on the mdoc")
+		require.Nil(t, credentials)
+	})
+}
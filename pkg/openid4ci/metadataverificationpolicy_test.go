@@ -0,0 +1,174 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/kms-go/doc/jose"
+	"github.com/trustbloc/vc-go/jwt"
+
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+func TestMetadataVerificationPolicy(t *testing.T) {
+	issuerServerHandler := &mockIssuerServerHandler{t: t}
+
+	server := httptest.NewServer(issuerServerHandler)
+	defer server.Close()
+
+	rootKey, root := createTestCertificate(t, &x509.Certificate{
+		Subject: pkix.Name{CommonName: "Test Root CA"},
+		IsCA:    true,
+	}, nil, nil)
+
+	t.Run("x5c-signed metadata", func(t *testing.T) {
+		signingKey, signingCertificate := createTestCertificate(t, &x509.Certificate{
+			Subject:     pkix.Name{CommonName: "Test Issuer", Organization: []string{"Test Org"}},
+			IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		}, root, rootKey)
+
+		issuerServerHandler.issuerMetadata = createX5CSignedMetadata(t, signingKey, signingCertificate, server.URL)
+
+		config := &openid4ci.ClientConfig{
+			DIDResolver: &mockResolver{},
+			MetadataVerificationPolicy: &openid4ci.MetadataVerificationPolicy{
+				RequireSignedMetadata: true,
+				TrustAnchors:          []*x509.Certificate{root},
+			},
+		}
+
+		interaction, err := openid4ci.NewIssuerInitiatedInteraction(
+			createCredentialOfferIssuanceURI(t, server.URL, false, true), config)
+		require.NoError(t, err)
+
+		trustInfo, err := interaction.IssuerTrustInfo()
+		require.NoError(t, err)
+		require.True(t, trustInfo.MetadataSigned)
+		require.Equal(t, "CN=Test Issuer,O=Test Org", trustInfo.CertificateSubject)
+		require.Empty(t, trustInfo.DID)
+
+		domain, err := interaction.VerifyIssuer()
+		require.NoError(t, err)
+		require.Equal(t, strings.TrimPrefix(server.URL, "http://"), domain)
+	})
+	t.Run("x5c-signed metadata without trust anchors", func(t *testing.T) {
+		signingKey, signingCertificate := createTestCertificate(t, &x509.Certificate{
+			Subject: pkix.Name{CommonName: "Test Issuer"},
+		}, root, rootKey)
+
+		issuerServerHandler.issuerMetadata = createX5CSignedMetadata(t, signingKey, signingCertificate, server.URL)
+
+		interaction, err := openid4ci.NewIssuerInitiatedInteraction(
+			createCredentialOfferIssuanceURI(t, server.URL, false, true),
+			&openid4ci.ClientConfig{DIDResolver: &mockResolver{}})
+		// The x5c header is ignored, and there's no kid header to verify the metadata with instead.
+		require.ErrorContains(t, err, "missed kid in jwt header")
+		require.Nil(t, interaction)
+	})
+	t.Run("Unsigned metadata when signed metadata is required", func(t *testing.T) {
+		issuerServerHandler.issuerMetadata = strings.ReplaceAll(sampleIssuerMetadata, serverURLPlaceholder,
+			server.URL)
+
+		config := &openid4ci.ClientConfig{
+			DIDResolver:                &mockResolver{},
+			MetadataVerificationPolicy: &openid4ci.MetadataVerificationPolicy{RequireSignedMetadata: true},
+		}
+
+		interaction, err := openid4ci.NewIssuerInitiatedInteraction(
+			createCredentialOfferIssuanceURI(t, server.URL, false, true), config)
+		require.ErrorContains(t, err, "the issuer's metadata is not signed, but signed metadata is required")
+		require.Nil(t, interaction)
+	})
+}
+
+func createTestCertificate(t *testing.T, template, parent *x509.Certificate, parentKey *ecdsa.PrivateKey,
+) (*ecdsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign
+
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	certificateBytes, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+
+	certificate, err := x509.ParseCertificate(certificateBytes)
+	require.NoError(t, err)
+
+	return key, certificate
+}
+
+func createX5CSignedMetadata(t *testing.T, key *ecdsa.PrivateKey, certificate *x509.Certificate,
+	serverURL string,
+) string {
+	t.Helper()
+
+	claims := map[string]interface{}{}
+
+	err := json.Unmarshal([]byte(strings.ReplaceAll(sampleIssuerMetadata, serverURLPlaceholder, serverURL)), &claims)
+	require.NoError(t, err)
+
+	token, err := jwt.NewJoseSigned(claims, nil, &x5cSigner{key: key, certificate: certificate})
+	require.NoError(t, err)
+
+	tokenSerialised, err := token.Serialize(false)
+	require.NoError(t, err)
+
+	return fmt.Sprintf(`{"signed_metadata": %q}`, tokenSerialised)
+}
+
+type x5cSigner struct {
+	key         *ecdsa.PrivateKey
+	certificate *x509.Certificate
+}
+
+func (s *x5cSigner) Sign(data []byte) ([]byte, error) {
+	digest := sha256.Sum256(data)
+
+	r, sig, err := ecdsa.Sign(rand.Reader, s.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	sig.FillBytes(signature[32:])
+
+	return signature, nil
+}
+
+func (s *x5cSigner) Headers() jose.Headers {
+	return jose.Headers{
+		jose.HeaderAlgorithm:            "ES256",
+		jose.HeaderX509CertificateChain: []string{base64.StdEncoding.EncodeToString(s.certificate.Raw)},
+	}
+}
@@ -37,6 +37,26 @@ func TestCredentialResponse_SerializeToCredentialsBytes(t *testing.T) {
 		require.JSONEq(t, "{\"fld1\":\"val1\",\"fld2\":\"val2\",\"fld3\":\"val3\"}", string(res))
 	})
 
+	t.Run("Success credentials array", func(t *testing.T) {
+		credRes := &openid4ci.CredentialResponse{
+			Credentials: []openid4ci.CredentialResponseCredentialObject{{Credential: "test.jwt.sign"}},
+		}
+		res, err := credRes.SerializeToCredentialsBytes()
+		require.NoError(t, err)
+		require.Equal(t, "test.jwt.sign", string(res))
+	})
+
+	t.Run("More than one credential in credentials array", func(t *testing.T) {
+		credRes := &openid4ci.CredentialResponse{
+			Credentials: []openid4ci.CredentialResponseCredentialObject{
+				{Credential: "test.jwt.sign1"},
+				{Credential: "test.jwt.sign2"},
+			},
+		}
+		_, err := credRes.SerializeToCredentialsBytes()
+		require.EqualError(t, err, "credential response contains 2 credentials, but only one was expected")
+	})
+
 	t.Run("Unsupported type", func(t *testing.T) {
 		credRes := &openid4ci.CredentialResponse{
 			Credential: make(chan int),
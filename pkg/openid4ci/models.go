@@ -8,8 +8,12 @@ package openid4ci
 
 import (
 	"encoding/json"
+	"fmt"
+	"slices"
 	"time"
 
+	"github.com/go-jose/go-jose/v3"
+
 	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
 	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
 )
@@ -55,6 +59,10 @@ type authorizationDetails struct {
 	// Object containing the detailed description of the credential type.
 	CredentialDefinition *issuer.CredentialDefinition `json:"credential_definition,omitempty"`
 
+	// Only used in token responses. An array of strings, each uniquely identifying a Credential Dataset that can be
+	// issued using the access token. Each of them can be used in a credential request as the credential_identifier.
+	CredentialIdentifiers []string `json:"credential_identifiers,omitempty"`
+
 	// REQUIRED when CredentialConfigurationID parameter is not present.
 	// String identifying the format of the Credential the Wallet needs.
 	// This Credential format identifier determines further claims in the authorization details object needed
@@ -68,6 +76,38 @@ type authorizationDetails struct {
 
 	// String that determines the authorization details type. MUST be set to "openid_credential" for OIDC4VC.
 	Type string `json:"type"`
+
+	// For SD-JWT VCs only. String designating the type of the Credential.
+	Vct string `json:"vct,omitempty"`
+
+	// For mdocs only. String identifying the Credential type, as defined in ISO/IEC 18013-5.
+	Doctype string `json:"doctype,omitempty"`
+}
+
+// grants indicates whether these authorization details (as returned in a token response) cover the credential
+// with the given configuration ID, format and types.
+func (a *authorizationDetails) grants(configID, format string, types []string) bool {
+	if a.Type != "openid_credential" {
+		return false
+	}
+
+	if a.CredentialConfigurationID != "" {
+		return a.CredentialConfigurationID == configID
+	}
+
+	if a.Format != format {
+		return false
+	}
+
+	if a.Vct != "" {
+		return a.Vct == typeIdentifierFromTypes(types)
+	}
+
+	if a.Doctype != "" {
+		return a.Doctype == typeIdentifierFromTypes(types)
+	}
+
+	return a.CredentialDefinition == nil || slices.Equal(a.CredentialDefinition.Type, types)
 }
 
 // CredentialResponse is the object returned from the Client.Callback method.
@@ -93,6 +133,9 @@ type CredentialResponse struct {
 	AckID string `json:"notification_id"`
 	// Contains an array of one or more issued Credentials.
 	Credentials []CredentialResponseCredentialObject `json:"credentials"`
+	// OPTIONAL. Number denoting the minimum amount of time in seconds that the Wallet needs to wait before
+	// sending a new request to the Deferred Credential Endpoint.
+	Interval int `json:"interval,omitempty"`
 }
 
 // CredentialResponseCredentialObject is a model for credentials field from credential response.
@@ -101,11 +144,24 @@ type CredentialResponseCredentialObject struct {
 }
 
 // SerializeToCredentialsBytes serializes underlying credential to proper bytes representation depending on
-// credential format.
+// credential format. If the response uses the credentials array, then it must contain exactly one credential.
 func (r *CredentialResponse) SerializeToCredentialsBytes() ([]byte, error) {
 	// TODO: https://github.com/trustbloc/wallet-sdk/issues/456 check response.Format after
 	// VCS starts return valid value.
-	switch cred := r.Credential.(type) {
+	credential := r.Credential
+
+	if credential == nil {
+		if len(r.Credentials) > 1 {
+			return nil, fmt.Errorf("credential response contains %d credentials, but only one was expected",
+				len(r.Credentials))
+		}
+
+		if len(r.Credentials) == 1 {
+			credential = r.Credentials[0].Credential
+		}
+	}
+
+	switch cred := credential.(type) {
 	case string:
 		return []byte(cred), nil
 	default:
@@ -113,6 +169,35 @@ func (r *CredentialResponse) SerializeToCredentialsBytes() ([]byte, error) {
 	}
 }
 
+// split returns one credential response for each of the credentials in this response, which may contain several if
+// it uses the credentials array. Each one has this response's notification ID, since it applies to all of them.
+func (r *CredentialResponse) split() []CredentialResponse {
+	if len(r.Credentials) == 0 {
+		if r.Credential == nil {
+			return nil
+		}
+
+		return []CredentialResponse{*r}
+	}
+
+	credentialResponses := make([]CredentialResponse, len(r.Credentials))
+
+	for index := range r.Credentials {
+		credentialResponses[index] = CredentialResponse{
+			Credential: r.Credentials[index].Credential,
+			AckID:      r.AckID,
+		}
+	}
+
+	return credentialResponses
+}
+
+// deferred returns true if the issuer was unable to immediately issue the credential and returned a transaction ID
+// that can be used to request the credential later via the deferred credential endpoint.
+func (r *CredentialResponse) deferred() bool {
+	return r.TransactionID != "" && r.Credential == nil && len(r.Credentials) == 0
+}
+
 type batchCredentialResponse struct {
 	CNonce              *string              `json:"c_nonce,omitempty"`
 	CNonceExpiresIn     *int                 `json:"c_nonce_expires_in,omitempty"`
@@ -120,15 +205,19 @@ type batchCredentialResponse struct {
 }
 
 type preAuthTokenResponse struct {
-	AccessToken     string `json:"access_token,omitempty"`
-	TokenType       string `json:"token_type,omitempty"`
-	ExpiresIn       int    `json:"expires_in,omitempty"`
-	RefreshToken    string `json:"refresh_token,omitempty"`
-	CNonce          string `json:"c_nonce,omitempty"`
-	CNonceExpiresIn int    `json:"c_nonce_expires_in,omitempty"`
+	AccessToken          string                 `json:"access_token,omitempty"`
+	TokenType            string                 `json:"token_type,omitempty"`
+	ExpiresIn            int                    `json:"expires_in,omitempty"`
+	RefreshToken         string                 `json:"refresh_token,omitempty"`
+	CNonce               string                 `json:"c_nonce,omitempty"`
+	CNonceExpiresIn      int                    `json:"c_nonce_expires_in,omitempty"`
+	AuthorizationDetails []authorizationDetails `json:"authorization_details,omitempty"`
 }
 
-type universalAuthToken struct {
+// AuthToken is the access token (and refresh token, if any) that the wallet got from the issuer's authorization
+// server. It's part of the objects that can be stored and used later without the interaction, such as
+// PendingIssuance, IssuanceGrant and Acknowledgment. The TokenType is DPoP if the token is bound to a DPoP key.
+type AuthToken struct {
 	AccessToken  string    `json:"access_token,omitempty"`
 	TokenType    string    `json:"token_type,omitempty"`
 	ExpiresAt    time.Time `json:"expires_at,omitempty"`
@@ -136,9 +225,21 @@ type universalAuthToken struct {
 }
 
 type credentialRequest struct {
-	CredentialDefinition *credentialDefinition `json:"credential_definition,omitempty"`
-	Format               string                `json:"format,omitempty"`
-	Proof                proof                 `json:"proof,omitempty"`
+	CredentialIdentifier         string                        `json:"credential_identifier,omitempty"`
+	CredentialConfigurationID    string                        `json:"credential_configuration_id,omitempty"`
+	CredentialDefinition         *credentialDefinition         `json:"credential_definition,omitempty"`
+	Format                       string                        `json:"format,omitempty"`
+	Vct                          string                        `json:"vct,omitempty"`
+	Doctype                      string                        `json:"doctype,omitempty"`
+	Proof                        *proof                        `json:"proof,omitempty"`
+	Proofs                       *proofs                       `json:"proofs,omitempty"`
+	CredentialResponseEncryption *credentialResponseEncryption `json:"credential_response_encryption,omitempty"`
+}
+
+type credentialResponseEncryption struct {
+	JWK *jose.JSONWebKey `json:"jwk"`
+	Alg string           `json:"alg"`
+	Enc string           `json:"enc"`
 }
 
 type credentialDefinition struct {
@@ -148,14 +249,50 @@ type credentialDefinition struct {
 }
 
 type proof struct {
-	ProofType       string `json:"proof_type,omitempty"`
-	JWT             string `json:"jwt,omitempty"`
-	CNonce          string `json:"c_nonce,omitempty"`
-	CNonceExpiresIn int    `json:"c_nonce_expires_in,omitempty"`
+	ProofType       string          `json:"proof_type,omitempty"`
+	JWT             string          `json:"jwt,omitempty"`
+	LdpVP           json.RawMessage `json:"ldp_vp,omitempty"`
+	DiVP            json.RawMessage `json:"di_vp,omitempty"`
+	CNonce          string          `json:"c_nonce,omitempty"`
+	CNonceExpiresIn int             `json:"c_nonce_expires_in,omitempty"`
+}
+
+// proofs holds multiple key proofs, one for each copy of a credential requested using batch issuance.
+type proofs struct {
+	JWT   []string          `json:"jwt,omitempty"`
+	LdpVP []json.RawMessage `json:"ldp_vp,omitempty"`
+	DiVP  []json.RawMessage `json:"di_vp,omitempty"`
+}
+
+func (p *proofs) add(keyProof *proof) {
+	switch keyProof.ProofType {
+	case ldpVPProofType:
+		p.LdpVP = append(p.LdpVP, keyProof.LdpVP)
+	case diVPProofType:
+		p.DiVP = append(p.DiVP, keyProof.DiVP)
+	default:
+		p.JWT = append(p.JWT, keyProof.JWT)
+	}
+}
+
+type nonceResponse struct {
+	CNonce string `json:"c_nonce"`
+}
+
+type deferredCredentialRequest struct {
+	TransactionID                string                        `json:"transaction_id"`
+	CredentialResponseEncryption *credentialResponseEncryption `json:"credential_response_encryption,omitempty"`
 }
 
 type batchCredentialRequest struct {
-	CredentialRequests []credentialRequest `json:"credential_requests"`
+	CredentialRequests           []credentialRequest           `json:"credential_requests"`
+	CredentialResponseEncryption *credentialResponseEncryption `json:"credential_response_encryption,omitempty"`
+}
+
+// pushedAuthorizationResponse is the response from a pushed authorization request endpoint (RFC 9126).
+type pushedAuthorizationResponse struct {
+	RequestURI string `json:"request_uri"`
+	ExpiresIn  int    `json:"expires_in"`
 }
 
 type errorResponse struct {
@@ -166,6 +303,9 @@ type errorResponse struct {
 	CNonce string `json:"c_nonce"`
 	// number denoting the lifetime in seconds of the c_nonce.
 	CNonceExpiresIn int `json:"c_nonce_expires_in"`
+	// number denoting the minimum amount of time in seconds that the wallet needs to wait before polling
+	// the deferred credential endpoint again. Only used with the issuance_pending error.
+	Interval int `json:"interval"`
 }
 
 type acknowledgementRequest struct {
@@ -223,3 +363,35 @@ func (e InvalidProofError) Error() string {
 func (e *InvalidProofError) Unwrap() error {
 	return e.ParentError
 }
+
+// CredentialIssuancePendingError is returned by RequestDeferredCredential when the issuer has not yet finished issuing
+// the credential. The caller should wait for at least Interval seconds before trying again.
+// See https://openid.net/specs/openid-4-verifiable-credential-issuance-1_0.html#section-9.3
+//
+//nolint:recvcheck
+type CredentialIssuancePendingError struct {
+	ParentError *walleterror.Error
+	Interval    int
+}
+
+// NewCredentialIssuancePendingError creates a new CredentialIssuancePendingError.
+func NewCredentialIssuancePendingError(
+	parentError *walleterror.Error, interval int,
+) *CredentialIssuancePendingError {
+	return &CredentialIssuancePendingError{
+		ParentError: parentError,
+		Interval:    interval,
+	}
+}
+
+func (e CredentialIssuancePendingError) Error() string {
+	if e.ParentError == nil {
+		return ""
+	}
+
+	return e.ParentError.Error()
+}
+
+func (e *CredentialIssuancePendingError) Unwrap() error {
+	return e.ParentError
+}
@@ -0,0 +1,235 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+type notificationRecorder struct {
+	t              *testing.T
+	notifications  []map[string]interface{}
+	authorizations []string
+	// The number of upcoming notifications to fail with a server error.
+	failuresLeft int
+}
+
+func (n *notificationRecorder) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	var notification map[string]interface{}
+
+	err := json.NewDecoder(request.Body).Decode(&notification)
+	assert.NoError(n.t, err)
+
+	n.notifications = append(n.notifications, notification)
+	n.authorizations = append(n.authorizations, request.Header.Get("Authorization"))
+
+	if n.failuresLeft > 0 {
+		n.failuresLeft--
+
+		writer.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func TestAcknowledgment_NotifyIssuer(t *testing.T) {
+	recorder := &notificationRecorder{t: t}
+	issuerServerHandler := &mockIssuerServerHandler{t: t, batchCredentialResponse: sampleCredentialResponseBatch}
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path == "/oidc/ack_endpoint" {
+			recorder.ServeHTTP(writer, request)
+
+			return
+		}
+
+		issuerServerHandler.ServeHTTP(writer, request)
+	}))
+	defer server.Close()
+
+	issuerServerHandler.issuerMetadata = strings.ReplaceAll(sampleIssuerMetadata, serverURLPlaceholder, server.URL)
+
+	credentialOffer := createSampleCredentialOffer(t, false, false)
+	credentialOffer.CredentialIssuer = server.URL
+	credentialOffer.CredentialConfigurationIDs = append(credentialOffer.CredentialConfigurationIDs,
+		"credential_configuration_id_1")
+
+	credentialOfferBytes, err := json.Marshal(credentialOffer)
+	require.NoError(t, err)
+
+	interaction := newIssuerInitiatedInteraction(t,
+		"openid-credential-offer://?credential_offer="+url.QueryEscape(string(credentialOfferBytes)))
+
+	credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+		openid4ci.WithPIN("1234"))
+	require.NoError(t, err)
+	require.Len(t, credentials, 2)
+
+	require.Equal(t, "ack_id1", interaction.NotificationID(credentials[0]))
+	require.Equal(t, "ack_id2", interaction.NotificationID(credentials[1]))
+	require.Empty(t, interaction.NotificationID(nil))
+
+	acknowledgment, err := interaction.Acknowledgment()
+	require.NoError(t, err)
+
+	t.Run("Notify about a specific credential", func(t *testing.T) {
+		err = acknowledgment.NotifyIssuer("ack_id2", openid4ci.EventStatusCredentialAccepted, "", &http.Client{})
+		require.NoError(t, err)
+
+		require.Equal(t, []string{"ack_id1"}, acknowledgment.AckIDs)
+
+		notification := recorder.notifications[len(recorder.notifications)-1]
+		require.Equal(t, "ack_id2", notification["notification_id"])
+		require.Equal(t, "credential_accepted", notification["event"])
+		require.NotContains(t, notification, "event_description")
+	})
+	t.Run("Notify about a deleted credential using a stored acknowledgment", func(t *testing.T) {
+		acknowledgmentBytes, err := json.Marshal(acknowledgment)
+		require.NoError(t, err)
+
+		var storedAcknowledgment openid4ci.Acknowledgment
+
+		require.NoError(t, json.Unmarshal(acknowledgmentBytes, &storedAcknowledgment))
+
+		err = storedAcknowledgment.NotifyIssuer("ack_id2", openid4ci.EventStatusCredentialDeleted,
+			"The user deleted the credential", &http.Client{})
+		require.NoError(t, err)
+
+		notification := recorder.notifications[len(recorder.notifications)-1]
+		require.Equal(t, "ack_id2", notification["notification_id"])
+		require.Equal(t, "credential_deleted", notification["event"])
+		require.Equal(t, "The user deleted the credential", notification["event_description"])
+	})
+	t.Run("Invalid notifications", func(t *testing.T) {
+		notificationCount := len(recorder.notifications)
+
+		err = acknowledgment.NotifyIssuer("", openid4ci.EventStatusCredentialAccepted, "", &http.Client{})
+		require.ErrorContains(t, err, "notification ID must be provided")
+
+		err = acknowledgment.NotifyIssuer("ack_id1", "credential_lost", "", &http.Client{})
+		require.ErrorContains(t, err, "unsupported notification event: credential_lost")
+
+		err = acknowledgment.NotifyIssuer("ack_id1", openid4ci.EventStatusCredentialFailure, "Invalid \"quote\"",
+			&http.Client{})
+		require.ErrorContains(t, err, "event description must only contain printable ASCII characters")
+
+		err = acknowledgment.NotifyIssuer("ack_id1", openid4ci.EventStatusCredentialFailure, "Ungültig",
+			&http.Client{})
+		require.ErrorContains(t, err, "event description must only contain printable ASCII characters")
+
+		require.Len(t, recorder.notifications, notificationCount)
+		require.Equal(t, []string{"ack_id1"}, acknowledgment.AckIDs)
+	})
+	t.Run("Retry after failing to send a notification", func(t *testing.T) {
+		recorder.failuresLeft = 1
+
+		err = acknowledgment.NotifyIssuer("ack_id1", openid4ci.EventStatusCredentialAccepted, "", &http.Client{})
+		require.ErrorContains(t, err, "send acknowledge request id ack_id1")
+
+		// The notification ID is kept, so the notification can be retried.
+		require.Equal(t, []string{"ack_id1"}, acknowledgment.AckIDs)
+
+		err = acknowledgment.NotifyIssuer("ack_id1", openid4ci.EventStatusCredentialAccepted, "", &http.Client{})
+		require.NoError(t, err)
+		require.Empty(t, acknowledgment.AckIDs)
+
+		notification := recorder.notifications[len(recorder.notifications)-1]
+		require.Equal(t, "ack_id1", notification["notification_id"])
+	})
+	t.Run("Retry after failing to send an acknowledgment", func(t *testing.T) {
+		acknowledgment.AckIDs = []string{"ack_id1", "ack_id2"}
+		recorder.failuresLeft = 1
+
+		err = acknowledgment.AcknowledgeIssuer(openid4ci.EventStatusCredentialAccepted, &http.Client{})
+		require.Error(t, err)
+		require.Equal(t, []string{"ack_id1", "ack_id2"}, acknowledgment.AckIDs)
+
+		err = acknowledgment.AcknowledgeIssuer(openid4ci.EventStatusCredentialAccepted, &http.Client{})
+		require.NoError(t, err)
+		require.Equal(t, []string{"ack_id2"}, acknowledgment.AckIDs)
+
+		notification := recorder.notifications[len(recorder.notifications)-1]
+		require.Equal(t, "ack_id1", notification["notification_id"])
+	})
+}
+
+func TestAcknowledgment_NotifyIssuerWithExpiredAccessToken(t *testing.T) {
+	recorder := &notificationRecorder{t: t}
+
+	var tokenRequests []url.Values
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		switch request.URL.Path {
+		case "/token":
+			assert.NoError(t, request.ParseForm())
+
+			tokenRequests = append(tokenRequests, request.PostForm)
+
+			writer.Header().Set("Content-Type", "application/json")
+
+			_, err := writer.Write([]byte(`{"access_token":"new-access-token","token_type":"Bearer",` +
+				`"expires_in":3600,"refresh_token":"new-refresh-token"}`))
+			assert.NoError(t, err)
+		case "/notification":
+			recorder.ServeHTTP(writer, request)
+		default:
+			writer.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	acknowledgment := &openid4ci.Acknowledgment{}
+
+	err := json.Unmarshal([]byte(fmt.Sprintf(`{
+		"credential_ack_endpoint": "%[1]s/notification",
+		"issuer_uri": "%[1]s",
+		"token_endpoint": "%[1]s/token",
+		"client_id": "wallet",
+		"auth_token": {
+			"access_token": "expired-access-token",
+			"token_type": "Bearer",
+			"expires_at": %[2]q,
+			"refresh_token": "refresh-token"
+		}
+	}`, server.URL, time.Now().Add(-time.Hour).Format(time.RFC3339))), acknowledgment)
+	require.NoError(t, err)
+
+	err = acknowledgment.NotifyIssuer("notification-id", openid4ci.EventStatusCredentialDeleted, "",
+		&http.Client{})
+	require.NoError(t, err)
+
+	require.Len(t, tokenRequests, 1)
+	require.Equal(t, "refresh_token", tokenRequests[0].Get("grant_type"))
+	require.Equal(t, "refresh-token", tokenRequests[0].Get("refresh_token"))
+	require.Equal(t, "wallet", tokenRequests[0].Get("client_id"))
+
+	require.Equal(t, []string{"Bearer new-access-token"}, recorder.authorizations)
+
+	// The refreshed token is kept, so it's stored along with the acknowledgment.
+	acknowledgmentBytes, err := json.Marshal(acknowledgment)
+	require.NoError(t, err)
+	require.Contains(t, string(acknowledgmentBytes), "new-refresh-token")
+
+	err = acknowledgment.NotifyIssuer("notification-id", openid4ci.EventStatusCredentialDeleted, "",
+		&http.Client{})
+	require.NoError(t, err)
+	require.Len(t, tokenRequests, 1)
+}
@@ -0,0 +1,96 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/trustbloc/wallet-sdk/pkg/internal/httprequest"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+const (
+	authorizationEventText = "Authorization"
+	//nolint:gosec //false positive
+	pushAuthorizationRequestViaPOSTReqEventText = "Push authorization request via an HTTP POST request to %s"
+)
+
+// pushAuthorizationRequest sends the parameters from the given authorization URL to the authorization server's
+// pushed authorization request endpoint (RFC 9126). A shorter authorization URL is returned, which only contains
+// the client ID and the request URI that the authorization server returned.
+func (i *interaction) pushAuthorizationRequest(ctx context.Context, authorizationURL string) (string, error) {
+	parsedAuthorizationURL, err := url.Parse(authorizationURL)
+	if err != nil {
+		return "", err
+	}
+
+	params := parsedAuthorizationURL.Query()
+
+	parEndpoint := i.issuerMetadata.PushedAuthorizationRequestEndpoint
+
+	responseBytes, err := httprequest.New(i.httpClient, i.metricsLogger).DoContext(ctx,
+		http.MethodPost, parEndpoint, "application/x-www-form-urlencoded", nil,
+		strings.NewReader(params.Encode()), fmt.Sprintf(pushAuthorizationRequestViaPOSTReqEventText, parEndpoint),
+		authorizationEventText, []int{http.StatusCreated, http.StatusOK}, pushedAuthorizationErrorResponseHandler)
+	if err != nil {
+		return "", err
+	}
+
+	var parResponse pushedAuthorizationResponse
+
+	err = json.Unmarshal(responseBytes, &parResponse)
+	if err != nil {
+		return "", walleterror.NewExecutionError(ErrorModule,
+			PushedAuthorizationRequestFailedCode,
+			PushedAuthorizationRequestFailedError,
+			fmt.Errorf("failed to unmarshal response from the pushed authorization request endpoint: %w", err))
+	}
+
+	if parResponse.RequestURI == "" {
+		return "", walleterror.NewExecutionError(ErrorModule,
+			PushedAuthorizationRequestFailedCode,
+			PushedAuthorizationRequestFailedError,
+			errors.New("the pushed authorization request endpoint did not return a request URI"))
+	}
+
+	shortParams := url.Values{}
+	shortParams.Set("client_id", params.Get("client_id"))
+	shortParams.Set("request_uri", parResponse.RequestURI)
+
+	parsedAuthorizationURL.RawQuery = shortParams.Encode()
+
+	return parsedAuthorizationURL.String(), nil
+}
+
+func pushedAuthorizationErrorResponseHandler(statusCode int, respBody []byte) error {
+	detailedErr := fmt.Errorf(
+		"received status code [%d] with body [%s] from the pushed authorization request endpoint",
+		statusCode, respBody)
+
+	var errResponse errorResponse
+
+	err := json.Unmarshal(respBody, &errResponse)
+	if err != nil {
+		return walleterror.NewExecutionError(ErrorModule,
+			PushedAuthorizationRequestFailedCode,
+			PushedAuthorizationRequestFailedError,
+			detailedErr)
+	}
+
+	return walleterror.NewExecutionError(ErrorModule,
+		PushedAuthorizationRequestFailedCode,
+		PushedAuthorizationRequestFailedError,
+		detailedErr,
+		walleterror.WithServerErrorCode(errResponse.Error),
+		walleterror.WithServerErrorMessage(errResponse.ErrorDescription))
+}
@@ -0,0 +1,158 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+const sampleRequestURI = "urn:ietf:params:oauth:request_uri:6esc_11ACC5bwc014ltc14eY22c"
+
+// mockPARHandler handles requests to the pushed authorization request endpoint. All other requests are passed on
+// to the wrapped mockIssuerServerHandler.
+type mockPARHandler struct {
+	*mockIssuerServerHandler
+	receivedParams url.Values
+	statusCode     int
+	response       string
+}
+
+func (m *mockPARHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.URL.Path != "/oidc/par" {
+		m.mockIssuerServerHandler.ServeHTTP(writer, request)
+
+		return
+	}
+
+	assert.Equal(m.t, http.MethodPost, request.Method)
+	assert.Equal(m.t, "application/x-www-form-urlencoded", request.Header.Get("Content-Type"))
+
+	err := request.ParseForm()
+	assert.NoError(m.t, err)
+
+	m.receivedParams = request.PostForm
+
+	writer.WriteHeader(m.statusCode)
+
+	_, err = writer.Write([]byte(m.response))
+	assert.NoError(m.t, err)
+}
+
+func newPARServer(t *testing.T, parSupported bool) (*mockPARHandler, *httptest.Server) {
+	t.Helper()
+
+	parHandler := &mockPARHandler{
+		mockIssuerServerHandler: &mockIssuerServerHandler{
+			t:                  t,
+			credentialResponse: sampleCredentialResponse,
+		},
+		statusCode: http.StatusCreated,
+		response:   `{"request_uri":"` + sampleRequestURI + `","expires_in":60}`,
+	}
+
+	server := httptest.NewServer(parHandler)
+
+	issuerMetadata := modifyCredentialMetadata(t, sampleIssuerMetadata, func(m *issuer.Metadata) {
+		if parSupported {
+			m.PushedAuthorizationRequestEndpoint = serverURLPlaceholder + "/oidc/par"
+		}
+	})
+
+	parHandler.issuerMetadata = strings.ReplaceAll(issuerMetadata, serverURLPlaceholder, server.URL)
+
+	return parHandler, server
+}
+
+func TestIssuerInitiatedInteraction_CreateAuthorizationURL_PAR(t *testing.T) {
+	t.Run("Authorization server supports PAR", func(t *testing.T) {
+		parHandler, server := newPARServer(t, true)
+		defer server.Close()
+
+		interaction := newIssuerInitiatedInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, true, true))
+
+		authorizationURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI",
+			openid4ci.WithScopes([]string{"custom_scope"}))
+		require.NoError(t, err)
+
+		parsedURL, err := url.Parse(authorizationURL)
+		require.NoError(t, err)
+		require.Equal(t, server.URL+"/oidc/authorize", parsedURL.Scheme+"://"+parsedURL.Host+parsedURL.Path)
+		require.Equal(t, url.Values{"client_id": {"clientID"}, "request_uri": {sampleRequestURI}},
+			parsedURL.Query())
+
+		require.Equal(t, "clientID", parHandler.receivedParams.Get("client_id"))
+		require.Equal(t, "code", parHandler.receivedParams.Get("response_type"))
+		require.Equal(t, "redirectURI", parHandler.receivedParams.Get("redirect_uri"))
+		require.Equal(t, "custom_scope", parHandler.receivedParams.Get("scope"))
+		require.Equal(t, "S256", parHandler.receivedParams.Get("code_challenge_method"))
+		require.NotEmpty(t, parHandler.receivedParams.Get("code_challenge"))
+		require.NotEmpty(t, parHandler.receivedParams.Get("state"))
+		require.NotEmpty(t, parHandler.receivedParams.Get("issuer_state"))
+		require.Contains(t, parHandler.receivedParams.Get("authorization_details"), "VerifiedEmployee")
+	})
+	t.Run("PAR required, but not supported by the authorization server", func(t *testing.T) {
+		_, server := newPARServer(t, false)
+		defer server.Close()
+
+		interaction := newIssuerInitiatedInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, true, true))
+
+		authorizationURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI",
+			openid4ci.WithPushedAuthorizationRequest())
+		require.ErrorContains(t, err, "PUSHED_AUTHORIZATION_REQUEST_FAILED(OCI1-0029)")
+		require.ErrorContains(t, err, "does not support pushed authorization requests")
+		require.Empty(t, authorizationURL)
+	})
+	t.Run("Authorization server returns an error", func(t *testing.T) {
+		parHandler, server := newPARServer(t, true)
+		defer server.Close()
+
+		parHandler.statusCode = http.StatusBadRequest
+		parHandler.response = `{"error":"invalid_request","error_description":"unknown redirect URI"}`
+
+		interaction := newIssuerInitiatedInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, true, true))
+
+		authorizationURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI",
+			openid4ci.WithPushedAuthorizationRequest())
+		require.ErrorContains(t, err, "PUSHED_AUTHORIZATION_REQUEST_FAILED(OCI1-0029)")
+		require.ErrorContains(t, err, "unknown redirect URI")
+		require.Empty(t, authorizationURL)
+	})
+	t.Run("Response is missing the request URI", func(t *testing.T) {
+		parHandler, server := newPARServer(t, true)
+		defer server.Close()
+
+		parHandler.response = `{"expires_in":60}`
+
+		interaction := newIssuerInitiatedInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, true, true))
+
+		authorizationURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+		require.ErrorContains(t, err, "did not return a request URI")
+		require.Empty(t, authorizationURL)
+	})
+	t.Run("Invalid response", func(t *testing.T) {
+		parHandler, server := newPARServer(t, true)
+		defer server.Close()
+
+		parHandler.response = "invalid"
+
+		interaction := newIssuerInitiatedInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, true, true))
+
+		authorizationURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+		require.ErrorContains(t, err, "failed to unmarshal response from the pushed authorization request endpoint")
+		require.Empty(t, authorizationURL)
+	})
+}
@@ -6,17 +6,39 @@ SPDX-License-Identifier: Apache-2.0
 
 package openid4ci
 
-import "github.com/trustbloc/wallet-sdk/pkg/api"
+import (
+	"github.com/trustbloc/kms-go/spi/kms"
+
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
 
 type requestCredentialWithPreAuthOpts struct {
 	pin                 string
+	clientID            string
 	attestationVPSigner api.JWTSigner
 	attestationVC       string
+	batchIssuance       *batchIssuanceOpts
+}
+
+type requestCredentialWithAuthOpts struct {
+	batchIssuance *batchIssuanceOpts
+}
+
+type batchIssuanceOpts struct {
+	copies    int
+	keyWriter api.KeyWriter
+	crypto    api.Crypto
+	keyType   kms.KeyType
 }
 
 // RequestCredentialWithPreAuthOpt is an option for the RequestCredentialWithPreAuth method.
 type RequestCredentialWithPreAuthOpt func(opts *requestCredentialWithPreAuthOpts)
 
+// RequestCredentialWithAuthOpt is an option for the RequestCredentialWithAuth method of IssuerInitiatedInteraction
+// and the RequestCredential method of WalletInitiatedInteraction.
+type RequestCredentialWithAuthOpt func(opts *requestCredentialWithAuthOpts)
+
 // WithPIN is an option for the RequestCredentialWithPreAuth method that allows you to specify a PIN, which may be
 // required by the issuer. Check the issuer capabilities object first to determine this.
 func WithPIN(pin string) RequestCredentialWithPreAuthOpt {
@@ -25,6 +47,15 @@ func WithPIN(pin string) RequestCredentialWithPreAuthOpt {
 	}
 }
 
+// WithClientID is an option for the RequestCredentialWithPreAuth method that allows you to specify a client ID to
+// include in the token request. This is required if the issuer's authorization server doesn't support anonymous
+// access. Check the PreAuthorizedCodeGrantParams object's ClientIDRequired method first to determine this.
+func WithClientID(clientID string) RequestCredentialWithPreAuthOpt {
+	return func(opts *requestCredentialWithPreAuthOpts) {
+		opts.clientID = clientID
+	}
+}
+
 // WithAttestationVC is an option for the RequestCredentialWithPreAuth method that allows you to specify
 // attestation VC, which may be required by the issuer.
 func WithAttestationVC(attestationVPSigner api.JWTSigner, attestationVC string) RequestCredentialWithPreAuthOpt {
@@ -34,6 +65,42 @@ func WithAttestationVC(attestationVPSigner api.JWTSigner, attestationVC string)
 	}
 }
 
+// WithBatchIssuance is an option for the RequestCredentialWithPreAuth method that requests the given number of copies
+// of each credential. Each copy is bound to a new key of the given type, which is created using the given KeyWriter
+// and used through a did:jwk DID. The given crypto is used to sign with the new keys. Since the copies are bound to
+// different keys, they can't be linked to each other when a different copy is presented each time.
+// Use the CredentialBatches method afterwards to get the copies along with the keys they're bound to.
+// If issuance fails after the keys have been created, then an UnusedHolderKeysError with the IDs of the keys is
+// returned, and it's up to the caller to delete them.
+func WithBatchIssuance(copies int, keyWriter api.KeyWriter, crypto api.Crypto,
+	keyType kms.KeyType,
+) RequestCredentialWithPreAuthOpt {
+	return func(opts *requestCredentialWithPreAuthOpts) {
+		opts.batchIssuance = &batchIssuanceOpts{
+			copies:    copies,
+			keyWriter: keyWriter,
+			crypto:    crypto,
+			keyType:   keyType,
+		}
+	}
+}
+
+// WithBatchIssuanceForAuth is the equivalent of WithBatchIssuance for the authorization code flow. It's an option for
+// the RequestCredentialWithAuth method of IssuerInitiatedInteraction and the RequestCredential method of
+// WalletInitiatedInteraction.
+func WithBatchIssuanceForAuth(copies int, keyWriter api.KeyWriter, crypto api.Crypto,
+	keyType kms.KeyType,
+) RequestCredentialWithAuthOpt {
+	return func(opts *requestCredentialWithAuthOpts) {
+		opts.batchIssuance = &batchIssuanceOpts{
+			copies:    copies,
+			keyWriter: keyWriter,
+			crypto:    crypto,
+			keyType:   keyType,
+		}
+	}
+}
+
 func processRequestCredentialWithPreAuthOpts(opts []RequestCredentialWithPreAuthOpt) *requestCredentialWithPreAuthOpts {
 	processedOpts := &requestCredentialWithPreAuthOpts{}
 
@@ -45,3 +112,22 @@ func processRequestCredentialWithPreAuthOpts(opts []RequestCredentialWithPreAuth
 
 	return processedOpts
 }
+
+func processRequestCredentialWithAuthOpts(opts []RequestCredentialWithAuthOpt) (*requestCredentialWithAuthOpts, error) {
+	processedOpts := &requestCredentialWithAuthOpts{}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(processedOpts)
+		}
+	}
+
+	if processedOpts.batchIssuance != nil {
+		err := validateBatchIssuanceOpts(processedOpts.batchIssuance)
+		if err != nil {
+			return nil, walleterror.NewInvalidSDKUsageError(ErrorModule, err)
+		}
+	}
+
+	return processedOpts, nil
+}
@@ -0,0 +1,131 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"slices"
+
+	"github.com/go-jose/go-jose/v3"
+
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+// Key management algorithms that can be used for encrypted credential responses, in order of preference.
+//
+//nolint:gochecknoglobals
+var supportedResponseEncryptionAlgs = []string{
+	string(jose.ECDH_ES), string(jose.ECDH_ES_A256KW), string(jose.ECDH_ES_A192KW), string(jose.ECDH_ES_A128KW),
+}
+
+// Content encryption algorithms that can be used for encrypted credential responses, in order of preference.
+//
+//nolint:gochecknoglobals
+var supportedResponseEncryptionEncs = []string{
+	string(jose.A256GCM), string(jose.A192GCM), string(jose.A128GCM),
+	string(jose.A256CBC_HS512), string(jose.A192CBC_HS384), string(jose.A128CBC_HS256),
+}
+
+// responseEncryptionKey is an ephemeral key pair used to receive encrypted credential responses.
+type responseEncryptionKey struct {
+	privateKey *ecdsa.PrivateKey
+	params     *credentialResponseEncryption
+}
+
+// credentialResponseEncryptionParams returns the credential_response_encryption parameters to send in credential
+// requests, or nil if responses shouldn't be encrypted. The same ephemeral key is used for all credential requests
+// made by this interaction.
+func (i *interaction) credentialResponseEncryptionParams() (*credentialResponseEncryption, error) {
+	if i.responseEncryptionKey != nil {
+		return i.responseEncryptionKey.params, nil
+	}
+
+	encryptionSupported := i.issuerMetadata.CredentialResponseEncryption
+	if encryptionSupported == nil ||
+		(!encryptionSupported.EncryptionRequired && !i.enableCredentialResponseEncryption) {
+		return nil, nil //nolint:nilnil // nil means that encryption isn't used
+	}
+
+	alg, algFound := firstSupported(supportedResponseEncryptionAlgs, encryptionSupported.AlgValuesSupported)
+	enc, encFound := firstSupported(supportedResponseEncryptionEncs, encryptionSupported.EncValuesSupported)
+
+	if !algFound || !encFound {
+		if !encryptionSupported.EncryptionRequired {
+			return nil, nil //nolint:nilnil // encryption is optional, so fall back to unencrypted responses
+		}
+
+		return nil, walleterror.NewExecutionError(ErrorModule,
+			UnsupportedResponseEncryptionCode,
+			UnsupportedResponseEncryptionError,
+			fmt.Errorf("issuer requires credential response encryption, but none of its supported algorithms "+
+				"(alg: %v, enc: %v) are supported", encryptionSupported.AlgValuesSupported,
+				encryptionSupported.EncValuesSupported))
+	}
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate credential response encryption key: %w", err)
+	}
+
+	i.responseEncryptionKey = &responseEncryptionKey{
+		privateKey: privateKey,
+		params: &credentialResponseEncryption{
+			JWK: &jose.JSONWebKey{Key: &privateKey.PublicKey, Algorithm: alg, Use: "enc"},
+			Alg: alg,
+			Enc: enc,
+		},
+	}
+
+	return i.responseEncryptionKey.params, nil
+}
+
+// decryptCredentialResponse decrypts the given response body if encryption was requested. Otherwise, the response
+// body is returned as-is.
+func (i *interaction) decryptCredentialResponse(responseBytes []byte) ([]byte, error) {
+	if i.responseEncryptionKey == nil {
+		return responseBytes, nil
+	}
+
+	jwe, err := jose.ParseEncrypted(string(responseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("expected an encrypted credential response: %w", err)
+	}
+
+	if jwe.Header.Algorithm != i.responseEncryptionKey.params.Alg {
+		return nil, fmt.Errorf("credential response was encrypted using %s, but %s was requested",
+			jwe.Header.Algorithm, i.responseEncryptionKey.params.Alg)
+	}
+
+	// go-jose doesn't have a field for the enc header, so it ends up with the other unrecognized headers.
+	enc, _ := jwe.Header.ExtraHeaders["enc"].(string)
+
+	if enc != i.responseEncryptionKey.params.Enc {
+		return nil, fmt.Errorf("credential response was encrypted using %s, but %s was requested",
+			enc, i.responseEncryptionKey.params.Enc)
+	}
+
+	decrypted, err := jwe.Decrypt(i.responseEncryptionKey.privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt credential response: %w", err)
+	}
+
+	return decrypted, nil
+}
+
+// firstSupported returns the first value in supported (which is ordered by preference) that's also in offered.
+func firstSupported(supported, offered []string) (string, bool) {
+	for _, value := range supported {
+		if slices.Contains(offered, value) {
+			return value, true
+		}
+	}
+
+	return "", false
+}
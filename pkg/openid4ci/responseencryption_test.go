@@ -0,0 +1,307 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+type credentialResponseEncryptionParams struct {
+	JWK *jose.JSONWebKey `json:"jwk"`
+	Alg string           `json:"alg"`
+	Enc string           `json:"enc"`
+}
+
+// mockEncryptingIssuerHandler encrypts credential, batch credential and deferred credential responses using the
+// parameters from the request (if any). All other requests are passed on to the wrapped mockIssuerServerHandler.
+type mockEncryptingIssuerHandler struct {
+	*mockIssuerServerHandler
+	deferredCredentialResponse []byte
+	receivedEncryptionParams   *credentialResponseEncryptionParams
+	skipEncryption             bool
+	// If set, then responses are encrypted using this enc value instead of the requested one.
+	encOverride string
+}
+
+func (m *mockEncryptingIssuerHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	var responseBody []byte
+
+	switch request.URL.Path {
+	case "/oidc/credential":
+		responseBody = m.credentialResponse
+	case "/oidc/batch_credential":
+		responseBody = m.batchCredentialResponse
+	case "/oidc/deferred_credential":
+		responseBody = m.deferredCredentialResponse
+	default:
+		m.mockIssuerServerHandler.ServeHTTP(writer, request)
+
+		return
+	}
+
+	var credentialRequest struct {
+		CredentialResponseEncryption *credentialResponseEncryptionParams `json:"credential_response_encryption"`
+	}
+
+	err := json.NewDecoder(request.Body).Decode(&credentialRequest)
+	assert.NoError(m.t, err)
+
+	m.receivedEncryptionParams = credentialRequest.CredentialResponseEncryption
+
+	if m.receivedEncryptionParams != nil && !m.skipEncryption {
+		enc := m.receivedEncryptionParams.Enc
+		if m.encOverride != "" {
+			enc = m.encOverride
+		}
+
+		encrypter, encErr := jose.NewEncrypter(jose.ContentEncryption(enc),
+			jose.Recipient{
+				Algorithm: jose.KeyAlgorithm(m.receivedEncryptionParams.Alg),
+				Key:       m.receivedEncryptionParams.JWK,
+			}, nil)
+		assert.NoError(m.t, encErr)
+
+		jwe, encErr := encrypter.Encrypt(responseBody)
+		assert.NoError(m.t, encErr)
+
+		serialized, encErr := jwe.CompactSerialize()
+		assert.NoError(m.t, encErr)
+
+		writer.Header().Set("Content-Type", "application/jwt")
+
+		responseBody = []byte(serialized)
+	}
+
+	_, err = writer.Write(responseBody)
+	assert.NoError(m.t, err)
+}
+
+func newEncryptingIssuerServer(t *testing.T,
+	encryptionSupported *issuer.CredentialResponseEncryptionSupported,
+) (*mockEncryptingIssuerHandler, *httptest.Server) {
+	t.Helper()
+
+	issuerServerHandler := &mockEncryptingIssuerHandler{
+		mockIssuerServerHandler: &mockIssuerServerHandler{
+			t:                       t,
+			credentialResponse:      sampleCredentialResponse,
+			batchCredentialResponse: sampleCredentialResponseBatch,
+		},
+		deferredCredentialResponse: sampleCredentialResponse,
+	}
+
+	server := httptest.NewServer(issuerServerHandler)
+
+	issuerMetadata := modifyCredentialMetadata(t, sampleIssuerMetadata, func(m *issuer.Metadata) {
+		m.CredentialResponseEncryption = encryptionSupported
+		m.DeferredCredentialEndpoint = serverURLPlaceholder + "/oidc/deferred_credential"
+	})
+
+	issuerServerHandler.issuerMetadata = strings.ReplaceAll(issuerMetadata, serverURLPlaceholder, server.URL)
+
+	return issuerServerHandler, server
+}
+
+func enableCredentialResponseEncryption() clientConfigOpt {
+	return func(config *openid4ci.ClientConfig) {
+		config.EnableCredentialResponseEncryption = true
+	}
+}
+
+func TestIssuerInitiatedInteraction_CredentialResponseEncryption(t *testing.T) {
+	t.Run("Encryption required", func(t *testing.T) {
+		issuerServerHandler, server := newEncryptingIssuerServer(t, &issuer.CredentialResponseEncryptionSupported{
+			AlgValuesSupported: []string{"RSA-OAEP", "ECDH-ES+A128KW"},
+			EncValuesSupported: []string{"A128CBC-HS256", "A256GCM"},
+			EncryptionRequired: true,
+		})
+		defer server.Close()
+
+		interaction := newIssuerInitiatedInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false, true))
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+
+		require.NotNil(t, issuerServerHandler.receivedEncryptionParams)
+		require.Equal(t, "ECDH-ES+A128KW", issuerServerHandler.receivedEncryptionParams.Alg)
+		require.Equal(t, "A256GCM", issuerServerHandler.receivedEncryptionParams.Enc)
+		require.True(t, issuerServerHandler.receivedEncryptionParams.JWK.IsPublic())
+	})
+	t.Run("Encryption optional and not enabled", func(t *testing.T) {
+		issuerServerHandler, server := newEncryptingIssuerServer(t, &issuer.CredentialResponseEncryptionSupported{
+			AlgValuesSupported: []string{"ECDH-ES"},
+			EncValuesSupported: []string{"A256GCM"},
+		})
+		defer server.Close()
+
+		interaction := newIssuerInitiatedInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false, true))
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+		require.Nil(t, issuerServerHandler.receivedEncryptionParams)
+	})
+	t.Run("Encryption optional and enabled", func(t *testing.T) {
+		issuerServerHandler, server := newEncryptingIssuerServer(t, &issuer.CredentialResponseEncryptionSupported{
+			AlgValuesSupported: []string{"ECDH-ES"},
+			EncValuesSupported: []string{"A256GCM"},
+		})
+		defer server.Close()
+
+		interaction := newIssuerInitiatedInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false, true),
+			enableCredentialResponseEncryption())
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+		require.NotNil(t, issuerServerHandler.receivedEncryptionParams)
+		require.Equal(t, "ECDH-ES", issuerServerHandler.receivedEncryptionParams.Alg)
+	})
+	t.Run("Encryption optional and enabled, but no supported algorithms", func(t *testing.T) {
+		issuerServerHandler, server := newEncryptingIssuerServer(t, &issuer.CredentialResponseEncryptionSupported{
+			AlgValuesSupported: []string{"RSA-OAEP"},
+			EncValuesSupported: []string{"A256GCM"},
+		})
+		defer server.Close()
+
+		interaction := newIssuerInitiatedInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false, true),
+			enableCredentialResponseEncryption())
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+		require.Nil(t, issuerServerHandler.receivedEncryptionParams)
+	})
+	t.Run("Encryption required, but no supported algorithms", func(t *testing.T) {
+		_, server := newEncryptingIssuerServer(t, &issuer.CredentialResponseEncryptionSupported{
+			AlgValuesSupported: []string{"ECDH-ES"},
+			EncValuesSupported: []string{"XC20P"},
+			EncryptionRequired: true,
+		})
+		defer server.Close()
+
+		interaction := newIssuerInitiatedInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false, true))
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		require.ErrorContains(t, err, "UNSUPPORTED_CREDENTIAL_RESPONSE_ENCRYPTION(OCI1-0027)")
+		require.Nil(t, credentials)
+	})
+	t.Run("Issuer returns an unencrypted response", func(t *testing.T) {
+		issuerServerHandler, server := newEncryptingIssuerServer(t, &issuer.CredentialResponseEncryptionSupported{
+			AlgValuesSupported: []string{"ECDH-ES"},
+			EncValuesSupported: []string{"A256GCM"},
+			EncryptionRequired: true,
+		})
+		defer server.Close()
+
+		issuerServerHandler.skipEncryption = true
+
+		interaction := newIssuerInitiatedInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false, true))
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		require.ErrorContains(t, err, "expected an encrypted credential response")
+		require.Nil(t, credentials)
+	})
+	t.Run("Issuer encrypts the response using a different enc value", func(t *testing.T) {
+		issuerServerHandler, server := newEncryptingIssuerServer(t, &issuer.CredentialResponseEncryptionSupported{
+			AlgValuesSupported: []string{"ECDH-ES"},
+			EncValuesSupported: []string{"A256GCM", "A128GCM"},
+			EncryptionRequired: true,
+		})
+		defer server.Close()
+
+		issuerServerHandler.encOverride = "A128GCM"
+
+		interaction := newIssuerInitiatedInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false, true))
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		require.ErrorContains(t, err, "credential response was encrypted using A128GCM, but A256GCM was requested")
+		require.Nil(t, credentials)
+	})
+	t.Run("Batch credential endpoint", func(t *testing.T) {
+		issuerServerHandler, server := newEncryptingIssuerServer(t, &issuer.CredentialResponseEncryptionSupported{
+			AlgValuesSupported: []string{"ECDH-ES"},
+			EncValuesSupported: []string{"A128GCM"},
+			EncryptionRequired: true,
+		})
+		defer server.Close()
+
+		credentialOffer := createSampleCredentialOffer(t, false, false)
+		credentialOffer.CredentialIssuer = server.URL
+
+		credentialOffer.CredentialConfigurationIDs = append(credentialOffer.CredentialConfigurationIDs,
+			"credential_configuration_id_1")
+
+		credentialOfferBytes, err := json.Marshal(credentialOffer)
+		require.NoError(t, err)
+
+		interaction := newIssuerInitiatedInteraction(t,
+			"openid-credential-offer://?credential_offer="+url.QueryEscape(string(credentialOfferBytes)))
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+		require.Len(t, credentials, 2)
+		require.NotNil(t, issuerServerHandler.receivedEncryptionParams)
+		require.Equal(t, "A128GCM", issuerServerHandler.receivedEncryptionParams.Enc)
+	})
+	t.Run("Deferred credential endpoint", func(t *testing.T) {
+		issuerServerHandler, server := newEncryptingIssuerServer(t, &issuer.CredentialResponseEncryptionSupported{
+			AlgValuesSupported: []string{"ECDH-ES"},
+			EncValuesSupported: []string{"A256GCM"},
+			EncryptionRequired: true,
+		})
+		defer server.Close()
+
+		issuerServerHandler.credentialResponse = []byte(sampleDeferredCredentialResponse)
+
+		interaction := newIssuerInitiatedInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false, true))
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+		require.Empty(t, credentials)
+
+		pendingIssuances := interaction.PendingIssuances()
+		require.Len(t, pendingIssuances, 1)
+
+		issuerServerHandler.receivedEncryptionParams = nil
+
+		credential, err := openid4ci.RequestDeferredCredential(pendingIssuances[0], getTestClientConfig(t))
+		require.NoError(t, err)
+		require.NotNil(t, credential)
+		require.NotNil(t, issuerServerHandler.receivedEncryptionParams)
+		require.Equal(t, "ECDH-ES", issuerServerHandler.receivedEncryptionParams.Alg)
+		require.Equal(t, "A256GCM", issuerServerHandler.receivedEncryptionParams.Enc)
+
+		issuerServerHandler.skipEncryption = true
+
+		credential, err = openid4ci.RequestDeferredCredential(pendingIssuances[0], getTestClientConfig(t))
+		require.ErrorContains(t, err, "expected an encrypted credential response")
+		require.Nil(t, credential)
+	})
+}
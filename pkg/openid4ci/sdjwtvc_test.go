@@ -0,0 +1,173 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/kms-go/doc/jose"
+	"github.com/trustbloc/vc-go/sdjwt/common"
+	sdjwtissuer "github.com/trustbloc/vc-go/sdjwt/issuer"
+
+	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+const samplePIDVct = "urn:eu.europa.ec.eudi:pid:1"
+
+type ed25519JOSESigner struct {
+	privateKey ed25519.PrivateKey
+}
+
+func (s *ed25519JOSESigner) Sign(data []byte) ([]byte, error) {
+	return ed25519.Sign(s.privateKey, data), nil
+}
+
+func (s *ed25519JOSESigner) Headers() jose.Headers {
+	return jose.Headers{
+		jose.HeaderAlgorithm: "EdDSA",
+		jose.HeaderKeyID:     mockDID + "#issuer-key",
+		jose.HeaderType:      "dc+sd-jwt",
+	}
+}
+
+// createSDJWTVCCredentialResponse returns a credential response containing a PID SD-JWT VC bound to the given key ID.
+func createSDJWTVCCredentialResponse(t *testing.T, holderKeyID string) []byte {
+	t.Helper()
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	claims := map[string]interface{}{
+		"vct":         samplePIDVct,
+		"cnf":         map[string]interface{}{"kid": holderKeyID},
+		"given_name":  "Erika",
+		"family_name": "Mustermann",
+	}
+
+	sdJWT, err := sdjwtissuer.New("https://pid-issuer.example.com", claims, nil,
+		&ed25519JOSESigner{privateKey: privateKey}, sdjwtissuer.WithSDJWTVersion(common.SDJWTVersionV5),
+		sdjwtissuer.WithNonSelectivelyDisclosableClaims([]string{"vct", "cnf", "cnf.kid"}))
+	require.NoError(t, err)
+
+	sdJWTVC, err := sdJWT.Serialize(false)
+	require.NoError(t, err)
+
+	credentialResponse, err := json.Marshal(map[string]interface{}{"credential": sdJWTVC})
+	require.NoError(t, err)
+
+	return credentialResponse
+}
+
+// newSDJWTVCServer returns a server for an issuer that offers a credential in the dc+sd-jwt format, along with an
+// issuance URI for a credential offer.
+func newSDJWTVCServer(t *testing.T) (*mockAuthorizationDetailsHandler, string) {
+	t.Helper()
+
+	handler, server, issuanceURI := newMultipleCredentialsServer(t)
+	t.Cleanup(server.Close)
+
+	handler.credentialResponse = createSDJWTVCCredentialResponse(t, mockKeyID)
+	handler.issuerMetadata = modifyCredentialMetadata(t, handler.issuerMetadata, func(m *issuer.Metadata) {
+		config := m.CredentialConfigurationsSupported["credential_configuration_id_1"]
+		config.Format = "dc+sd-jwt"
+		config.CredentialDefinition = nil
+		config.Vct = samplePIDVct
+		// A claims object is only used by issuers that implement drafts of the specification before draft 15.
+		config.Claims = &map[string]interface{}{"given_name": map[string]interface{}{}}
+
+		delete(m.CredentialConfigurationsSupported, secondCredentialConfigID)
+	})
+
+	parsedURI, err := url.Parse(issuanceURI)
+	require.NoError(t, err)
+
+	var credentialOffer openid4ci.CredentialOffer
+
+	err = json.Unmarshal([]byte(parsedURI.Query().Get("credential_offer")), &credentialOffer)
+	require.NoError(t, err)
+
+	credentialOffer.CredentialConfigurationIDs = []string{"credential_configuration_id_1"}
+
+	credentialOfferBytes, err := json.Marshal(credentialOffer)
+	require.NoError(t, err)
+
+	return handler, "openid-credential-offer://?credential_offer=" + url.QueryEscape(string(credentialOfferBytes))
+}
+
+func TestIssuerInitiatedInteraction_SDJWTVC(t *testing.T) {
+	t.Run("Pre-authorized code flow", func(t *testing.T) {
+		handler, issuanceURI := newSDJWTVCServer(t)
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI)
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+
+		require.Equal(t, []string{"VerifiableCredential", samplePIDVct}, credentials[0].Contents().Types)
+		require.Equal(t, "Erika", credentials[0].Contents().Subject[0].CustomFields["given_name"])
+
+		require.Len(t, handler.credentialRequests, 1)
+		require.Equal(t, "dc+sd-jwt", handler.credentialRequests[0].Format)
+		require.Equal(t, samplePIDVct, handler.credentialRequests[0].Vct)
+		require.Nil(t, handler.credentialRequests[0].CredentialDefinition)
+	})
+	t.Run("Authorization code flow", func(t *testing.T) {
+		handler, issuanceURI := newSDJWTVCServer(t)
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI)
+
+		authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+		require.NoError(t, err)
+
+		parsedAuthURL, err := url.Parse(authURL)
+		require.NoError(t, err)
+
+		var requestedAuthorizationDetails []map[string]interface{}
+
+		err = json.Unmarshal([]byte(parsedAuthURL.Query().Get("authorization_details")),
+			&requestedAuthorizationDetails)
+		require.NoError(t, err)
+		require.Len(t, requestedAuthorizationDetails, 1)
+		require.Equal(t, "dc+sd-jwt", requestedAuthorizationDetails[0]["format"])
+		require.Equal(t, samplePIDVct, requestedAuthorizationDetails[0]["vct"])
+		require.NotContains(t, requestedAuthorizationDetails[0], "credential_definition")
+
+		handler.grantedAuthorizationDetails = `[{"type":"openid_credential","format":"dc+sd-jwt",` +
+			`"vct":"` + samplePIDVct + `"}]`
+
+		credentials, err := interaction.RequestCredentialWithAuth(&jwtSignerMock{keyID: mockKeyID},
+			"redirectURI?code=1234&state="+getStateFromAuthURL(t, authURL))
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+		require.Equal(t, []string{"credential_configuration_id_1"}, interaction.GrantedCredentialConfigIDs())
+
+		serializedCredential, err := credentials[0].MarshalJSON()
+		require.NoError(t, err)
+		require.Contains(t, string(handler.credentialResponse), string(serializedCredential))
+	})
+	t.Run("Credential bound to a different key", func(t *testing.T) {
+		handler, issuanceURI := newSDJWTVCServer(t)
+
+		handler.credentialResponse = createSDJWTVCCredentialResponse(t, "did:example:12345#otherKey")
+
+		interaction := newIssuerInitiatedInteraction(t, issuanceURI)
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		require.ErrorContains(t, err, "CREDENTIAL_PARSE_FAILED(OCI1-0007)")
+		require.ErrorContains(t, err, "instead of the holder's key")
+		require.Nil(t, credentials)
+	})
+}
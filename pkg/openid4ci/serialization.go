@@ -0,0 +1,384 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+// The version of the format used for serialized interactions. It's stored unencrypted as the first byte of the
+// serialized state so that the format can be changed in the future while still being able to restore (or at least
+// recognize) interactions serialized by older versions.
+const serializedStateVersion byte = 1
+
+const restoreInteractionEventText = "Restore interaction"
+
+// interactionState is the state of an interaction that's needed to continue an authorization code flow after the
+// user has been redirected back to the wallet. Access tokens aren't included, since an interaction is serialized
+// before they're obtained.
+type interactionState struct {
+	IssuerURI           string                `json:"issuer_uri"`
+	AuthorizationServer string                `json:"authorization_server,omitempty"`
+	ClientID            string                `json:"client_id,omitempty"`
+	OAuth2Config        *oAuth2ConfigState    `json:"oauth2_config,omitempty"`
+	AuthCodeURLState    string                `json:"auth_code_url_state,omitempty"`
+	CodeVerifier        string                `json:"code_verifier,omitempty"`
+	IssuerInitiated     *issuerInitiatedState `json:"issuer_initiated,omitempty"`
+	WalletInitiated     *walletInitiatedState `json:"wallet_initiated,omitempty"`
+}
+
+type oAuth2ConfigState struct {
+	ClientID    string   `json:"client_id"`
+	RedirectURL string   `json:"redirect_url"`
+	AuthURL     string   `json:"auth_url"`
+	Scopes      []string `json:"scopes,omitempty"`
+}
+
+type issuerInitiatedState struct {
+	CredentialTypes        [][]string    `json:"credential_types"`
+	CredentialFormats      []string      `json:"credential_formats"`
+	CredentialConfigIDs    []string      `json:"credential_configuration_ids,omitempty"`
+	CredentialContexts     [][]string    `json:"credential_contexts"`
+	PreAuthorizedCodeGrant *preAuthState `json:"pre_authorized_code_grant,omitempty"`
+	AuthorizationCodeGrant *authState    `json:"authorization_code_grant,omitempty"`
+}
+
+type preAuthState struct {
+	PreAuthorizedCode   string       `json:"pre_authorized_code"`
+	TxCode              *txCodeState `json:"tx_code,omitempty"`
+	ClientIDRequired    bool         `json:"client_id_required,omitempty"`
+	AuthorizationServer string       `json:"authorization_server,omitempty"`
+}
+
+type txCodeState struct {
+	InputMode   string `json:"input_mode,omitempty"`
+	Length      int    `json:"length,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type authState struct {
+	IssuerState         *string `json:"issuer_state,omitempty"`
+	AuthorizationServer string  `json:"authorization_server,omitempty"`
+}
+
+type walletInitiatedState struct {
+	CredentialFormat  string   `json:"credential_format"`
+	CredentialTypes   []string `json:"credential_types"`
+	CredentialContext []string `json:"credential_context,omitempty"`
+}
+
+// Serialize serializes this IssuerInitiatedInteraction so that it can be persisted and later restored using
+// RestoreIssuerInitiatedInteraction. This allows an authorization code flow to be continued even if the app was
+// closed while the user was logging in. The serialized state is encrypted using the given key, which must be a
+// 16, 24 or 32 byte AES key. Serialize should be called after CreateAuthorizationURL.
+func (i *IssuerInitiatedInteraction) Serialize(key []byte) ([]byte, error) {
+	state := i.interaction.state()
+
+	state.IssuerInitiated = &issuerInitiatedState{
+		CredentialTypes:     i.credentialTypes,
+		CredentialFormats:   i.credentialFormats,
+		CredentialConfigIDs: i.credentialConfigIDs,
+		CredentialContexts:  i.credentialContexts,
+	}
+
+	if i.preAuthorizedCodeGrantParams != nil {
+		state.IssuerInitiated.PreAuthorizedCodeGrant = &preAuthState{
+			PreAuthorizedCode:   i.preAuthorizedCodeGrantParams.preAuthorizedCode,
+			ClientIDRequired:    i.preAuthorizedCodeGrantParams.clientIDRequired,
+			AuthorizationServer: i.preAuthorizedCodeGrantParams.authorizationServer,
+		}
+
+		if txCode := i.preAuthorizedCodeGrantParams.txCode; txCode != nil {
+			state.IssuerInitiated.PreAuthorizedCodeGrant.TxCode = &txCodeState{
+				InputMode:   txCode.inputMode,
+				Length:      txCode.length,
+				Description: txCode.description,
+			}
+		}
+	}
+
+	if i.authorizationCodeGrantParams != nil {
+		state.IssuerInitiated.AuthorizationCodeGrant = &authState{
+			IssuerState:         i.authorizationCodeGrantParams.IssuerState,
+			AuthorizationServer: i.authorizationCodeGrantParams.authorizationServer,
+		}
+	}
+
+	return encryptState(state, key)
+}
+
+// RestoreIssuerInitiatedInteraction restores an IssuerInitiatedInteraction that was serialized using the
+// IssuerInitiatedInteraction.Serialize method. The given key must be the same one that was used to serialize it.
+// The issuer's metadata is fetched again.
+func RestoreIssuerInitiatedInteraction(serializedState, key []byte,
+	config *ClientConfig,
+) (*IssuerInitiatedInteraction, error) {
+	return RestoreIssuerInitiatedInteractionContext(context.Background(), serializedState, key, config)
+}
+
+// RestoreIssuerInitiatedInteractionContext is the same as RestoreIssuerInitiatedInteraction, but uses the given
+// context for fetching the issuer's metadata.
+func RestoreIssuerInitiatedInteractionContext(ctx context.Context, serializedState, key []byte,
+	config *ClientConfig,
+) (*IssuerInitiatedInteraction, error) {
+	state, err := decryptState(serializedState, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if state.IssuerInitiated == nil {
+		return nil, walleterror.NewExecutionError(ErrorModule,
+			InvalidSerializedStateCode,
+			InvalidSerializedStateError,
+			errors.New("serialized state is not from an issuer-initiated interaction"))
+	}
+
+	restoredInteraction, err := restoreInteraction(ctx, state, config)
+	if err != nil {
+		return nil, err
+	}
+
+	issuerInteraction := &IssuerInitiatedInteraction{
+		interaction:         restoredInteraction,
+		credentialTypes:     state.IssuerInitiated.CredentialTypes,
+		credentialFormats:   state.IssuerInitiated.CredentialFormats,
+		credentialConfigIDs: state.IssuerInitiated.CredentialConfigIDs,
+		credentialContexts:  state.IssuerInitiated.CredentialContexts,
+	}
+
+	if preAuthGrant := state.IssuerInitiated.PreAuthorizedCodeGrant; preAuthGrant != nil {
+		issuerInteraction.preAuthorizedCodeGrantParams = &PreAuthorizedCodeGrantParams{
+			preAuthorizedCode:   preAuthGrant.PreAuthorizedCode,
+			clientIDRequired:    preAuthGrant.ClientIDRequired,
+			authorizationServer: preAuthGrant.AuthorizationServer,
+		}
+
+		if preAuthGrant.TxCode != nil {
+			issuerInteraction.preAuthorizedCodeGrantParams.txCode = &TxCode{
+				inputMode:   preAuthGrant.TxCode.InputMode,
+				length:      preAuthGrant.TxCode.Length,
+				description: preAuthGrant.TxCode.Description,
+			}
+		}
+	}
+
+	if authGrant := state.IssuerInitiated.AuthorizationCodeGrant; authGrant != nil {
+		issuerInteraction.authorizationCodeGrantParams = &AuthorizationCodeGrantParams{
+			IssuerState:         authGrant.IssuerState,
+			authorizationServer: authGrant.AuthorizationServer,
+		}
+	}
+
+	return issuerInteraction, nil
+}
+
+// Serialize serializes this WalletInitiatedInteraction so that it can be persisted and later restored using
+// RestoreWalletInitiatedInteraction. This allows the flow to be continued even if the app was closed while the user
+// was logging in. The serialized state is encrypted using the given key, which must be a 16, 24 or 32 byte AES key.
+// Serialize should be called after CreateAuthorizationURL.
+func (i *WalletInitiatedInteraction) Serialize(key []byte) ([]byte, error) {
+	state := i.interaction.state()
+
+	state.WalletInitiated = &walletInitiatedState{
+		CredentialFormat:  i.credentialFormat,
+		CredentialTypes:   i.credentialTypes,
+		CredentialContext: i.credentialContext,
+	}
+
+	return encryptState(state, key)
+}
+
+// RestoreWalletInitiatedInteraction restores a WalletInitiatedInteraction that was serialized using the
+// WalletInitiatedInteraction.Serialize method. The given key must be the same one that was used to serialize it.
+// The issuer's metadata is fetched again.
+func RestoreWalletInitiatedInteraction(serializedState, key []byte,
+	config *ClientConfig,
+) (*WalletInitiatedInteraction, error) {
+	return RestoreWalletInitiatedInteractionContext(context.Background(), serializedState, key, config)
+}
+
+// RestoreWalletInitiatedInteractionContext is the same as RestoreWalletInitiatedInteraction, but uses the given
+// context for fetching the issuer's metadata.
+func RestoreWalletInitiatedInteractionContext(ctx context.Context, serializedState, key []byte,
+	config *ClientConfig,
+) (*WalletInitiatedInteraction, error) {
+	state, err := decryptState(serializedState, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if state.WalletInitiated == nil {
+		return nil, walleterror.NewExecutionError(ErrorModule,
+			InvalidSerializedStateCode,
+			InvalidSerializedStateError,
+			errors.New("serialized state is not from a wallet-initiated interaction"))
+	}
+
+	restoredInteraction, err := restoreInteraction(ctx, state, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WalletInitiatedInteraction{
+		interaction:       restoredInteraction,
+		credentialFormat:  state.WalletInitiated.CredentialFormat,
+		credentialTypes:   state.WalletInitiated.CredentialTypes,
+		credentialContext: state.WalletInitiated.CredentialContext,
+	}, nil
+}
+
+func (i *interaction) state() *interactionState {
+	state := &interactionState{
+		IssuerURI:           i.issuerURI,
+		AuthorizationServer: i.authorizationServer,
+		ClientID:            i.clientID,
+		AuthCodeURLState:    i.authCodeURLState,
+		CodeVerifier:        i.codeVerifier,
+	}
+
+	if i.oAuth2Config != nil {
+		state.OAuth2Config = &oAuth2ConfigState{
+			ClientID:    i.oAuth2Config.ClientID,
+			RedirectURL: i.oAuth2Config.RedirectURL,
+			AuthURL:     i.oAuth2Config.Endpoint.AuthURL,
+			Scopes:      i.oAuth2Config.Scopes,
+		}
+	}
+
+	return state
+}
+
+func restoreInteraction(ctx context.Context, state *interactionState, config *ClientConfig,
+) (*interaction, error) {
+	timeStartRestoreInteraction := time.Now()
+
+	err := validateRequiredParameters(config)
+	if err != nil {
+		return nil, walleterror.NewInvalidSDKUsageError(ErrorModule, err)
+	}
+
+	setDefaults(config)
+
+	restoredInteraction := newInteraction(state.IssuerURI, config)
+
+	restoredInteraction.authorizationServer = state.AuthorizationServer
+	restoredInteraction.clientID = state.ClientID
+	restoredInteraction.authCodeURLState = state.AuthCodeURLState
+	restoredInteraction.codeVerifier = state.CodeVerifier
+
+	if state.OAuth2Config != nil {
+		restoredInteraction.oAuth2Config = &oauth2.Config{
+			ClientID: state.OAuth2Config.ClientID,
+			Endpoint: oauth2.Endpoint{
+				AuthURL:   state.OAuth2Config.AuthURL,
+				AuthStyle: oauth2.AuthStyleInHeader,
+			},
+			RedirectURL: state.OAuth2Config.RedirectURL,
+			Scopes:      state.OAuth2Config.Scopes,
+		}
+	}
+
+	err = restoredInteraction.populateIssuerMetadata(ctx, restoreInteractionEventText)
+	if err != nil {
+		return nil, err
+	}
+
+	return restoredInteraction, config.MetricsLogger.Log(&api.MetricsEvent{
+		Event:    restoreInteractionEventText,
+		Duration: time.Since(timeStartRestoreInteraction),
+	})
+}
+
+// encryptState encrypts the given state using AES-GCM. The result is the version byte, followed by the nonce and
+// then the ciphertext. The version byte is also authenticated.
+func encryptState(state *interactionState, key []byte) ([]byte, error) {
+	aead, err := newStateCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	stateBytes, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	serializedState := append([]byte{serializedStateVersion}, nonce...)
+
+	return aead.Seal(serializedState, nonce, stateBytes, []byte{serializedStateVersion}), nil
+}
+
+func decryptState(serializedState, key []byte) (*interactionState, error) {
+	aead, err := newStateCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(serializedState) == 0 || serializedState[0] != serializedStateVersion {
+		return nil, walleterror.NewExecutionError(ErrorModule,
+			InvalidSerializedStateCode,
+			InvalidSerializedStateError,
+			errors.New("unsupported serialized state version"))
+	}
+
+	if len(serializedState) < 1+aead.NonceSize() {
+		return nil, walleterror.NewExecutionError(ErrorModule,
+			InvalidSerializedStateCode,
+			InvalidSerializedStateError,
+			errors.New("serialized state is too short"))
+	}
+
+	nonce := serializedState[1 : 1+aead.NonceSize()]
+
+	stateBytes, err := aead.Open(nil, nonce, serializedState[1+aead.NonceSize():], serializedState[:1])
+	if err != nil {
+		return nil, walleterror.NewExecutionError(ErrorModule,
+			InvalidSerializedStateCode,
+			InvalidSerializedStateError,
+			fmt.Errorf("failed to decrypt serialized state: %w", err))
+	}
+
+	var state interactionState
+
+	err = json.Unmarshal(stateBytes, &state)
+	if err != nil {
+		return nil, walleterror.NewExecutionError(ErrorModule,
+			InvalidSerializedStateCode,
+			InvalidSerializedStateError,
+			fmt.Errorf("failed to unmarshal serialized state: %w", err))
+	}
+
+	return &state, nil
+}
+
+func newStateCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, walleterror.NewInvalidSDKUsageError(ErrorModule,
+			fmt.Errorf("invalid serialization key: %w", err))
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,159 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+//nolint:gochecknoglobals
+var serializationKey = bytes.Repeat([]byte{1}, 32)
+
+func TestIssuerInitiatedInteraction_SerializeAndRestore(t *testing.T) {
+	handler, server, issuanceURI := newMultipleCredentialsServer(t)
+	defer server.Close()
+
+	interaction := newIssuerInitiatedInteraction(t, issuanceURI)
+
+	authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI",
+		openid4ci.WithScopes([]string{"scope1"}))
+	require.NoError(t, err)
+
+	serializedState, err := interaction.Serialize(serializationKey)
+	require.NoError(t, err)
+
+	// The serialized state is encrypted.
+	require.NotContains(t, string(serializedState), "clientID")
+
+	t.Run("Success", func(t *testing.T) {
+		restoredInteraction, err := openid4ci.RestoreIssuerInitiatedInteraction(serializedState, serializationKey,
+			getTestClientConfig(t))
+		require.NoError(t, err)
+
+		require.Equal(t, interaction.AuthorizationCodeGrantTypeSupported(),
+			restoredInteraction.AuthorizationCodeGrantTypeSupported())
+		require.Equal(t, interaction.PreAuthorizedCodeGrantTypeSupported(),
+			restoredInteraction.PreAuthorizedCodeGrantTypeSupported())
+
+		credentials, err := restoredInteraction.RequestCredentialWithAuth(&jwtSignerMock{keyID: mockKeyID},
+			"redirectURI?code=1234&state="+getStateFromAuthURL(t, authURL))
+		require.NoError(t, err)
+		require.Len(t, credentials, 2)
+		require.Len(t, handler.credentialRequests, 2)
+	})
+	t.Run("Wrong key", func(t *testing.T) {
+		restoredInteraction, err := openid4ci.RestoreIssuerInitiatedInteraction(serializedState,
+			bytes.Repeat([]byte{2}, 32), getTestClientConfig(t))
+		requireInvalidSerializedStateError(t, err, "failed to decrypt serialized state")
+		require.Nil(t, restoredInteraction)
+	})
+	t.Run("Invalid key", func(t *testing.T) {
+		_, err := interaction.Serialize([]byte("short"))
+		require.ErrorContains(t, err, "invalid serialization key")
+	})
+	t.Run("Unsupported version", func(t *testing.T) {
+		modifiedState := bytes.Clone(serializedState)
+		modifiedState[0] = 2
+
+		restoredInteraction, err := openid4ci.RestoreIssuerInitiatedInteraction(modifiedState, serializationKey,
+			getTestClientConfig(t))
+		requireInvalidSerializedStateError(t, err, "unsupported serialized state version")
+		require.Nil(t, restoredInteraction)
+	})
+	t.Run("Truncated state", func(t *testing.T) {
+		restoredInteraction, err := openid4ci.RestoreIssuerInitiatedInteraction(serializedState[:5],
+			serializationKey, getTestClientConfig(t))
+		requireInvalidSerializedStateError(t, err, "serialized state is too short")
+		require.Nil(t, restoredInteraction)
+	})
+	t.Run("State from a wallet-initiated interaction", func(t *testing.T) {
+		walletInteraction, err := openid4ci.NewWalletInitiatedInteraction(server.URL, getTestClientConfig(t))
+		require.NoError(t, err)
+
+		walletSerializedState, err := walletInteraction.Serialize(serializationKey)
+		require.NoError(t, err)
+
+		restoredInteraction, err := openid4ci.RestoreIssuerInitiatedInteraction(walletSerializedState,
+			serializationKey, getTestClientConfig(t))
+		requireInvalidSerializedStateError(t, err, "serialized state is not from an issuer-initiated interaction")
+		require.Nil(t, restoredInteraction)
+	})
+	t.Run("Missing client config", func(t *testing.T) {
+		restoredInteraction, err := openid4ci.RestoreIssuerInitiatedInteraction(serializedState, serializationKey,
+			nil)
+		require.ErrorContains(t, err, "no client config provided")
+		require.Nil(t, restoredInteraction)
+	})
+}
+
+func TestWalletInitiatedInteraction_SerializeAndRestore(t *testing.T) {
+	handler, server, _ := newMultipleCredentialsServer(t)
+	defer server.Close()
+
+	interaction, err := openid4ci.NewWalletInitiatedInteraction(server.URL, getTestClientConfig(t))
+	require.NoError(t, err)
+
+	authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI", "jwt_vc_json",
+		[]string{"VerifiableCredential", "UniversityDegreeCredential"})
+	require.NoError(t, err)
+
+	serializedState, err := interaction.Serialize(serializationKey)
+	require.NoError(t, err)
+
+	t.Run("Success", func(t *testing.T) {
+		restoredInteraction, err := openid4ci.RestoreWalletInitiatedInteraction(serializedState, serializationKey,
+			getTestClientConfig(t))
+		require.NoError(t, err)
+
+		credentials, err := restoredInteraction.RequestCredential(&jwtSignerMock{keyID: mockKeyID},
+			"redirectURI?code=1234&state="+getStateFromAuthURL(t, authURL))
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+
+		require.Len(t, handler.credentialRequests, 1)
+		require.Equal(t, []string{"VerifiableCredential", "UniversityDegreeCredential"},
+			handler.credentialRequests[0].CredentialDefinition.Type)
+	})
+	t.Run("State mismatch", func(t *testing.T) {
+		restoredInteraction, err := openid4ci.RestoreWalletInitiatedInteraction(serializedState, serializationKey,
+			getTestClientConfig(t))
+		require.NoError(t, err)
+
+		_, err = restoredInteraction.RequestCredential(&jwtSignerMock{keyID: mockKeyID},
+			"redirectURI?code=1234&state=other")
+		require.ErrorContains(t, err, "state in redirect URI does not match the state from the authorization URL")
+	})
+	t.Run("State from an issuer-initiated interaction", func(t *testing.T) {
+		_, issuerServer, issuanceURI := newMultipleCredentialsServer(t)
+		defer issuerServer.Close()
+
+		issuerSerializedState, err := newIssuerInitiatedInteraction(t, issuanceURI).Serialize(serializationKey)
+		require.NoError(t, err)
+
+		restoredInteraction, err := openid4ci.RestoreWalletInitiatedInteraction(issuerSerializedState,
+			serializationKey, getTestClientConfig(t))
+		requireInvalidSerializedStateError(t, err, "serialized state is not from a wallet-initiated interaction")
+		require.Nil(t, restoredInteraction)
+	})
+}
+
+func requireInvalidSerializedStateError(t *testing.T, err error, expectedErrMsg string) {
+	t.Helper()
+
+	require.ErrorContains(t, err, expectedErrMsg)
+
+	var walletErr *walleterror.Error
+
+	require.ErrorAs(t, err, &walletErr)
+	require.Equal(t, openid4ci.InvalidSerializedStateError, walletErr.Category)
+}
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package openid4ci
 
 import (
+	"context"
 	"time"
 
 	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
@@ -44,19 +45,11 @@ func NewWalletInitiatedInteraction(issuerURI string, config *ClientConfig) (*Wal
 	setDefaults(config)
 
 	return &WalletInitiatedInteraction{
-			interaction: &interaction{
-				issuerURI:            issuerURI,
-				didResolver:          config.DIDResolver,
-				activityLogger:       config.ActivityLogger,
-				metricsLogger:        config.MetricsLogger,
-				disableVCProofChecks: config.DisableVCProofChecks,
-				documentLoader:       config.DocumentLoader,
-				httpClient:           config.HTTPClient,
-			},
-		}, config.MetricsLogger.Log(&api.MetricsEvent{
-			Event:    newInteractionEventText,
-			Duration: time.Since(timeStartNewInteraction),
-		})
+		interaction: newInteraction(issuerURI, config),
+	}, config.MetricsLogger.Log(&api.MetricsEvent{
+		Event:    newInteractionEventText,
+		Duration: time.Since(timeStartNewInteraction),
+	})
 }
 
 // SupportedCredential represents a specific credential (type and format) that an issuer can issue.
@@ -67,7 +60,14 @@ type SupportedCredential struct {
 
 // SupportedCredentials returns the credential types and formats that an issuer can issue.
 func (i *WalletInitiatedInteraction) SupportedCredentials() ([]SupportedCredential, error) {
-	err := i.interaction.populateIssuerMetadata("Get supported credentials")
+	return i.SupportedCredentialsContext(context.Background())
+}
+
+// SupportedCredentialsContext is the same as SupportedCredentials, but uses the given context for fetching the
+// issuer's metadata.
+func (i *WalletInitiatedInteraction) SupportedCredentialsContext(ctx context.Context,
+) ([]SupportedCredential, error) {
+	err := i.interaction.populateIssuerMetadata(ctx, "Get supported credentials")
 	if err != nil {
 		return nil, err
 	}
@@ -78,7 +78,7 @@ func (i *WalletInitiatedInteraction) SupportedCredentials() ([]SupportedCredenti
 	for _, credentialConfiguration := range credentialConf {
 		supportedCredentials = append(supportedCredentials, SupportedCredential{
 			Format: credentialConfiguration.Format,
-			Types:  credentialConfiguration.CredentialDefinition.Type,
+			Types:  credentialConfiguration.CredentialTypes(),
 		})
 	}
 
@@ -93,12 +93,22 @@ func (i *WalletInitiatedInteraction) SupportedCredentials() ([]SupportedCredenti
 // If scopes are needed, pass them in using the WithScopes option.
 func (i *WalletInitiatedInteraction) CreateAuthorizationURL(clientID, redirectURI, credentialFormat string,
 	credentialTypes []string, opts ...CreateAuthorizationURLOpt,
+) (string, error) {
+	return i.CreateAuthorizationURLContext(context.Background(), clientID, redirectURI, credentialFormat,
+		credentialTypes, opts...)
+}
+
+// CreateAuthorizationURLContext is the same as CreateAuthorizationURL, but uses the given context for the network
+// requests it makes, so that they can be cancelled or given a deadline.
+func (i *WalletInitiatedInteraction) CreateAuthorizationURLContext(ctx context.Context, clientID, redirectURI,
+	credentialFormat string, credentialTypes []string, opts ...CreateAuthorizationURLOpt,
 ) (string, error) {
 	processedOpts := processCreateAuthorizationURLOpts(opts)
 
-	authorizationURL, err := i.interaction.createAuthorizationURL(clientID, redirectURI, credentialFormat,
-		credentialTypes, processedOpts.context, processedOpts.issuerState, processedOpts.scopes,
-		processedOpts.useOAuthDiscoverableClientIDScheme)
+	authorizationURL, err := i.interaction.createAuthorizationURL(ctx, clientID, redirectURI, nil,
+		[]string{credentialFormat}, [][]string{credentialTypes}, [][]string{processedOpts.context},
+		processedOpts.issuerState, processedOpts.scopes, processedOpts.useOAuthDiscoverableClientIDScheme,
+		processedOpts.requirePushedAuthorizationRequest)
 	if err != nil {
 		return "", err
 	}
@@ -112,23 +122,80 @@ func (i *WalletInitiatedInteraction) CreateAuthorizationURL(clientID, redirectUR
 
 // RequestCredential requests credential(s) from the issuer. This method is the final step in the
 // interaction with the issuer.
+// If the issuer defers issuance, then the returned slice won't contain that credential. Instead, a pending issuance
+// handle can be retrieved using the PendingIssuances method.
 // This method must be called only once all authorization pre-requisite steps have been completed.
 // The redirect URI that you pass in here should look like the redirect URI that you passed in to the
 // CreateAuthorizationURL, except that now it has some URL query parameters appended to it.
 func (i *WalletInitiatedInteraction) RequestCredential(jwtSigner api.JWTSigner, redirectURIWithParams string,
+	opts ...RequestCredentialWithAuthOpt,
+) ([]*verifiable.Credential, error) {
+	return i.RequestCredentialContext(context.Background(), jwtSigner, redirectURIWithParams, opts...)
+}
+
+// RequestCredentialContext is the same as RequestCredential, but uses the given context for the network requests it
+// makes, so that they can be cancelled or given a deadline.
+func (i *WalletInitiatedInteraction) RequestCredentialContext(ctx context.Context, jwtSigner api.JWTSigner,
+	redirectURIWithParams string, opts ...RequestCredentialWithAuthOpt,
 ) ([]*verifiable.Credential, error) {
-	err := i.interaction.requestAccessToken(redirectURIWithParams)
+	processedOpts, err := processRequestCredentialWithAuthOpts(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	err = i.interaction.requestAccessToken(ctx, jwtSigner, redirectURIWithParams)
 	if err != nil {
 		return nil, err
 	}
 
-	return i.interaction.requestCredentialWithAuth(jwtSigner, []string{i.credentialFormat},
+	return i.interaction.requestCredentialWithAuth(ctx, jwtSigner, []string{i.credentialFormat},
+		[][]string{i.credentialTypes}, [][]string{i.credentialContext}, nil, processedOpts.batchIssuance)
+}
+
+// CredentialBatches returns the copies of the credential that were requested during the last call to
+// RequestCredential using the WithBatchIssuanceForAuth option. It returns nil if batch issuance wasn't used.
+func (i *WalletInitiatedInteraction) CredentialBatches() []*CredentialBatch {
+	return i.interaction.credentialBatches
+}
+
+// PendingIssuances returns the credentials that the issuer deferred during the last call to RequestCredential.
+// Each PendingIssuance can be stored and later redeemed using the RequestDeferredCredential function.
+func (i *WalletInitiatedInteraction) PendingIssuances() []*PendingIssuance {
+	return i.interaction.pendingIssuances
+}
+
+// IssuanceGrant returns an IssuanceGrant that can be stored and later used with the RenewCredentials function to
+// request the same credential again without any user interaction. It can only be called after RequestCredential,
+// and only if the issuer provided a refresh token.
+func (i *WalletInitiatedInteraction) IssuanceGrant() (*IssuanceGrant, error) {
+	return i.interaction.issuanceGrant(i.interaction.authToken, nil, []string{i.credentialFormat},
 		[][]string{i.credentialTypes}, [][]string{i.credentialContext})
 }
 
+// Acknowledgment returns an Acknowledgment that can be used to notify the issuer about the credential that was
+// issued. It returns an error if the issuer doesn't have a notification endpoint, or if RequestCredential hasn't been
+// called yet.
+func (i *WalletInitiatedInteraction) Acknowledgment() (*Acknowledgment, error) {
+	return i.interaction.requestedAcknowledgmentObj(i.interaction.authToken)
+}
+
+// NotificationID returns the notification ID that the issuer returned along with the given credential, which must be
+// one of the credentials returned by this interaction. It identifies the credential when notifying the issuer about
+// it using Acknowledgment.NotifyIssuer. An empty string is returned if the issuer didn't return a notification ID for
+// the credential.
+func (i *WalletInitiatedInteraction) NotificationID(credential *verifiable.Credential) string {
+	return i.interaction.notificationIDs[credential]
+}
+
 // DynamicClientRegistrationSupported indicates whether the issuer supports dynamic client registration.
 func (i *WalletInitiatedInteraction) DynamicClientRegistrationSupported() (bool, error) {
-	return i.interaction.dynamicClientRegistrationSupported()
+	return i.DynamicClientRegistrationSupportedContext(context.Background())
+}
+
+// DynamicClientRegistrationSupportedContext is the same as DynamicClientRegistrationSupported, but uses the given
+// context for fetching the issuer's metadata if it hasn't been fetched yet.
+func (i *WalletInitiatedInteraction) DynamicClientRegistrationSupportedContext(ctx context.Context) (bool, error) {
+	return i.interaction.dynamicClientRegistrationSupported(ctx)
 }
 
 // DynamicClientRegistrationEndpoint returns the issuer's dynamic client registration endpoint.
@@ -136,12 +203,23 @@ func (i *WalletInitiatedInteraction) DynamicClientRegistrationSupported() (bool,
 // if DynamicClientRegistrationSupported returns true.
 // This method will return an error if the issuer does not support dynamic client registration.
 func (i *WalletInitiatedInteraction) DynamicClientRegistrationEndpoint() (string, error) {
-	return i.interaction.dynamicClientRegistrationEndpoint()
+	return i.DynamicClientRegistrationEndpointContext(context.Background())
+}
+
+// DynamicClientRegistrationEndpointContext is the same as DynamicClientRegistrationEndpoint, but uses the given
+// context for fetching the issuer's metadata if it hasn't been fetched yet.
+func (i *WalletInitiatedInteraction) DynamicClientRegistrationEndpointContext(ctx context.Context) (string, error) {
+	return i.interaction.dynamicClientRegistrationEndpoint(ctx)
 }
 
 // IssuerMetadata returns the issuer's metadata.
 func (i *WalletInitiatedInteraction) IssuerMetadata() (*issuer.Metadata, error) {
-	err := i.interaction.populateIssuerMetadata(getIssuerMetadataEventText)
+	return i.IssuerMetadataContext(context.Background())
+}
+
+// IssuerMetadataContext is the same as IssuerMetadata, but uses the given context for fetching the issuer's metadata.
+func (i *WalletInitiatedInteraction) IssuerMetadataContext(ctx context.Context) (*issuer.Metadata, error) {
+	err := i.interaction.populateIssuerMetadata(ctx, getIssuerMetadataEventText)
 	if err != nil {
 		return nil, err
 	}
@@ -153,5 +231,10 @@ func (i *WalletInitiatedInteraction) IssuerMetadata() (*issuer.Metadata, error)
 // An error means that either the issuer failed the verification check, or something went wrong during the
 // process (and so a verification status could not be determined).
 func (i *WalletInitiatedInteraction) VerifyIssuer() (string, error) {
-	return i.interaction.verifyIssuer()
+	return i.VerifyIssuerContext(context.Background())
+}
+
+// VerifyIssuerContext is the same as VerifyIssuer, but uses the given context for the network requests it makes.
+func (i *WalletInitiatedInteraction) VerifyIssuerContext(ctx context.Context) (string, error) {
+	return i.interaction.verifyIssuer(ctx)
 }
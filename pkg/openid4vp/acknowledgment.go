@@ -8,6 +8,7 @@ package openid4vp
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -39,6 +40,13 @@ type Acknowledgment struct {
 
 // AcknowledgeVerifier sends acknowledgment to the verifier.
 func (a *Acknowledgment) AcknowledgeVerifier(errStr, desc string, httpClient httpClient) error {
+	return a.AcknowledgeVerifierContext(context.Background(), errStr, desc, httpClient)
+}
+
+// AcknowledgeVerifierContext is the same as AcknowledgeVerifier, but sends the acknowledgment using the given context.
+func (a *Acknowledgment) AcknowledgeVerifierContext(ctx context.Context, errStr, desc string,
+	httpClient httpClient,
+) error {
 	// https://openid.github.io/OpenID4VP/openid-4-verifiable-presentations-wg-draft.html#section-6.2-16
 	v := url.Values{}
 	v.Set("error", errStr)
@@ -54,8 +62,8 @@ func (a *Acknowledgment) AcknowledgeVerifier(errStr, desc string, httpClient htt
 		v.Add("interaction_details", base64.StdEncoding.EncodeToString(interactionDetailsBytes))
 	}
 
-	_, err := httprequest.New(httpClient, noop.NewMetricsLogger()).Do(http.MethodPost, a.ResponseURI,
-		"application/x-www-form-urlencoded", bytes.NewBufferString(v.Encode()), "", "", nil)
+	_, err := httprequest.New(httpClient, noop.NewMetricsLogger()).DoContext(ctx, http.MethodPost, a.ResponseURI,
+		"application/x-www-form-urlencoded", nil, bytes.NewBufferString(v.Encode()), "", "", nil, nil)
 	if err != nil {
 		return err
 	}
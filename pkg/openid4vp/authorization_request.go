@@ -0,0 +1,314 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4vp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/google/uuid"
+	"github.com/trustbloc/vc-go/presexch"
+
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/internal/httprequest"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+const (
+	requestURIMethodGet  = "get"
+	requestURIMethodPost = "post"
+
+	requestObjectMediaType = "application/oauth-authz-req+jwt"
+)
+
+// walletMetadata describes the wallet's capabilities to a verifier that uses request_uri_method=post, so that the
+// verifier can tailor the request object to the wallet.
+type walletMetadata struct {
+	VPFormatsSupported                     map[string]vpFormatAlgs  `json:"vp_formats_supported"`
+	PresentationDefinitionURISupported     bool                     `json:"presentation_definition_uri_supported"`
+	ClientIDSchemesSupported               []clientIDScheme         `json:"client_id_schemes_supported"`
+	ResponseTypesSupported                 []string                 `json:"response_types_supported"`
+	ResponseModesSupported                 []string                 `json:"response_modes_supported"`
+	RequestObjectSigningAlgValuesSupported []string                 `json:"request_object_signing_alg_values_supported"`
+	AuthorizationEncryptionAlgsSupported   []jose.KeyAlgorithm      `json:"authorization_encryption_alg_values_supported"`
+	AuthorizationEncryptionEncsSupported   []jose.ContentEncryption `json:"authorization_encryption_enc_values_supported"`
+}
+
+type vpFormatAlgs struct {
+	AlgValuesSupported []string `json:"alg_values_supported,omitempty"`
+}
+
+func newWalletMetadata() *walletMetadata {
+	algs := vpFormatAlgs{AlgValuesSupported: []string{"EdDSA", "ES256", "ES384"}}
+
+	return &walletMetadata{
+		VPFormatsSupported: map[string]vpFormatAlgs{
+			"jwt_vc_json": algs,
+			"jwt_vp_json": algs,
+			"ldp_vc":      {},
+			"ldp_vp":      {},
+			"vc+sd-jwt":   algs,
+			"dc+sd-jwt":   algs,
+		},
+		PresentationDefinitionURISupported: true,
+		ClientIDSchemesSupported: []clientIDScheme{
+			didScheme, redirectURIScheme, x509SANDNSScheme, x509SANURIScheme, verifierAttestationScheme,
+		},
+		ResponseTypesSupported:                 []string{"vp_token", "vp_token id_token"},
+		ResponseModesSupported:                 []string{"direct_post", directPostJWTResponseMode},
+		RequestObjectSigningAlgValuesSupported: algs.AlgValuesSupported,
+		AuthorizationEncryptionAlgsSupported:   supportedResponseEncryptionAlgs,
+		AuthorizationEncryptionEncsSupported:   supportedResponseEncryptionEncs,
+	}
+}
+
+// loadRequestObject gets the request object from an authorization request. The authorization request can be a request
+// object JWT, or a URI that carries the request object by value (request), by reference (request_uri) or as plain
+// URL parameters. If the request references its presentation definition by presentation_definition_uri, then the
+// presentation definition is fetched too.
+func loadRequestObject(
+	ctx context.Context,
+	authorizationRequest string,
+	verifier *requestObjectVerifier,
+	client httpClient,
+	metricsLogger api.MetricsLogger,
+) (*requestObject, error) {
+	var (
+		reqObject *requestObject
+		err       error
+	)
+
+	if strings.HasPrefix(authorizationRequest, "openid-vc://") ||
+		strings.HasPrefix(authorizationRequest, "openid4vp://") {
+		reqObject, err = loadRequestObjectFromURI(ctx, authorizationRequest, verifier, client, metricsLogger)
+	} else {
+		reqObject, err = verifyRequestObject("", authorizationRequest, verifier)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if reqObject.PresentationDefinitionURI != "" {
+		reqObject.PresentationDefinition, err = fetchPresentationDefinition(ctx,
+			reqObject.PresentationDefinitionURI, client, metricsLogger)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return reqObject, nil
+}
+
+func loadRequestObjectFromURI(
+	ctx context.Context,
+	authorizationRequest string,
+	verifier *requestObjectVerifier,
+	client httpClient,
+	metricsLogger api.MetricsLogger,
+) (*requestObject, error) {
+	authorizationRequestURL, err := url.Parse(authorizationRequest)
+	if err != nil {
+		return nil, invalidAuthorizationRequest(err)
+	}
+
+	query := authorizationRequestURL.Query()
+	clientID := query.Get("client_id")
+
+	switch {
+	case query.Has("request") && query.Has("request_uri"):
+		return nil, invalidAuthorizationRequest(
+			errors.New("authorization request URI must not contain both request and request_uri"))
+	case query.Has("request"):
+		return verifyRequestObject(clientID, query.Get("request"), verifier)
+	case query.Has("request_uri"):
+		rawRequestObject, walletNonce, err := fetchRequestObject(ctx, query, client, metricsLogger)
+		if err != nil {
+			return nil, err
+		}
+
+		reqObject, err := verifyRequestObject(clientID, rawRequestObject, verifier)
+		if err != nil {
+			return nil, err
+		}
+
+		if walletNonce != "" && reqObject.WalletNonce != walletNonce {
+			return nil, invalidAuthorizationRequest(
+				errors.New("wallet_nonce in request object doesn't match the one sent by the wallet"))
+		}
+
+		return reqObject, nil
+	default:
+		reqObject, err := requestObjectFromParameters(query)
+		if err != nil {
+			return nil, invalidAuthorizationRequest(err)
+		}
+
+		return reqObject, nil
+	}
+}
+
+func verifyRequestObject(
+	authorizationRequestClientID string,
+	rawRequestObject string,
+	verifier *requestObjectVerifier,
+) (*requestObject, error) {
+	reqObject, err := parseRequestObject(authorizationRequestClientID, rawRequestObject, verifier)
+	if err != nil {
+		return nil, invalidAuthorizationRequest(fmt.Errorf("verify request object: %w", err))
+	}
+
+	return reqObject, nil
+}
+
+// fetchRequestObject fetches the request object from the request_uri. If the verifier asked for
+// request_uri_method=post, then the wallet's metadata and a fresh wallet nonce are posted to the request_uri, and
+// the wallet nonce is returned so that it can be checked against the request object.
+func fetchRequestObject(ctx context.Context, query url.Values, client httpClient,
+	metricsLogger api.MetricsLogger,
+) (string, string, error) {
+	requestURI := query.Get("request_uri")
+
+	var (
+		respBytes   []byte
+		walletNonce string
+		err         error
+	)
+
+	switch query.Get("request_uri_method") {
+	case "", requestURIMethodGet:
+		respBytes, err = httprequest.New(client, metricsLogger).DoContext(ctx, http.MethodGet, requestURI, "", nil,
+			nil, fmt.Sprintf(fetchRequestObjectEventText, requestURI), newInteractionEventText, nil, nil)
+	case requestURIMethodPost:
+		var metadata []byte
+
+		metadata, err = json.Marshal(newWalletMetadata())
+		if err != nil {
+			return "", "", fmt.Errorf("encode wallet metadata: %w", err)
+		}
+
+		walletNonce = uuid.NewString()
+
+		body := url.Values{
+			"wallet_metadata": {string(metadata)},
+			"wallet_nonce":    {walletNonce},
+		}
+
+		respBytes, err = httprequest.New(client, metricsLogger).DoContext(ctx, http.MethodPost, requestURI,
+			"application/x-www-form-urlencoded", http.Header{"Accept": {requestObjectMediaType}},
+			strings.NewReader(body.Encode()), fmt.Sprintf(postWalletMetadataEventText, requestURI),
+			newInteractionEventText, nil, nil)
+	default:
+		return "", "", invalidAuthorizationRequest(
+			fmt.Errorf("unsupported request_uri_method: %s", query.Get("request_uri_method")))
+	}
+
+	if err != nil {
+		return "", "", walleterror.NewExecutionError(
+			ErrorModule,
+			RequestObjectFetchFailedCode,
+			RequestObjectFetchFailedError,
+			fmt.Errorf("fetch request object: %w", err))
+	}
+
+	return string(respBytes), walletNonce, nil
+}
+
+// requestObjectFromParameters builds a request object from an authorization request that passes its parameters
+// directly in the URI. Such requests can't be signed, so the verifier is identified by its response URI, as with
+// the redirect_uri client_id_scheme.
+func requestObjectFromParameters(query url.Values) (*requestObject, error) {
+	reqObject := &requestObject{
+		ResponseType:              query.Get("response_type"),
+		ResponseMode:              query.Get("response_mode"),
+		ResponseURI:               query.Get("response_uri"),
+		RedirectURI:               query.Get("redirect_uri"),
+		Scope:                     query.Get("scope"),
+		Nonce:                     query.Get("nonce"),
+		ClientID:                  query.Get("client_id"),
+		ClientIDScheme:            clientIDScheme(query.Get("client_id_scheme")),
+		State:                     query.Get("state"),
+		PresentationDefinitionURI: query.Get("presentation_definition_uri"),
+	}
+
+	jsonParameters := map[string]interface{}{
+		"client_metadata":         &reqObject.ClientMetadata,
+		"presentation_definition": &reqObject.PresentationDefinition,
+		"dcql_query":              &reqObject.DCQLQuery,
+	}
+
+	for name, target := range jsonParameters {
+		if !query.Has(name) {
+			continue
+		}
+
+		if err := json.Unmarshal([]byte(query.Get(name)), target); err != nil {
+			return nil, fmt.Errorf("decode %s: %w", name, err)
+		}
+	}
+
+	if reqObject.ClientID == "" {
+		return nil, errors.New("client_id missing from authorization request URI")
+	}
+
+	switch reqObject.ClientIDScheme {
+	case "", redirectURIScheme:
+		reqObject.ClientIDScheme = redirectURIScheme
+	default:
+		return nil, fmt.Errorf("client_id_scheme %s requires a signed request object", reqObject.ClientIDScheme)
+	}
+
+	err := finalizeRequestObject(reqObject)
+	if err != nil {
+		return nil, err
+	}
+
+	if !matchClientIDAndResponseURI(reqObject.ClientID, reqObject.ResponseURI) {
+		return nil, errors.New("client_id mismatch between authorization request and response_uri")
+	}
+
+	return reqObject, nil
+}
+
+func fetchPresentationDefinition(ctx context.Context, presentationDefinitionURI string, client httpClient,
+	metricsLogger api.MetricsLogger,
+) (*presexch.PresentationDefinition, error) {
+	respBytes, err := httprequest.New(client, metricsLogger).DoContext(ctx, http.MethodGet,
+		presentationDefinitionURI, "", nil, nil,
+		fmt.Sprintf(fetchPresentationDefinitionEventText, presentationDefinitionURI), newInteractionEventText,
+		nil, nil)
+	if err != nil {
+		return nil, walleterror.NewExecutionError(
+			ErrorModule,
+			PresentationDefinitionFetchFailedCode,
+			PresentationDefinitionFetchFailedError,
+			fmt.Errorf("fetch presentation definition: %w", err))
+	}
+
+	presentationDefinition := &presexch.PresentationDefinition{}
+
+	err = json.Unmarshal(respBytes, presentationDefinition)
+	if err != nil {
+		return nil, invalidAuthorizationRequest(fmt.Errorf("decode presentation definition: %w", err))
+	}
+
+	return presentationDefinition, nil
+}
+
+func invalidAuthorizationRequest(err error) error {
+	return walleterror.NewValidationError(
+		ErrorModule,
+		InvalidAuthorizationRequestErrorCode,
+		InvalidAuthorizationRequestError,
+		err)
+}
@@ -0,0 +1,292 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4vp //nolint: testpackage
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/vc-go/jwt"
+	"github.com/trustbloc/vc-go/presexch"
+
+	"github.com/trustbloc/wallet-sdk/pkg/internal/mock"
+)
+
+const (
+	testVerifierURI              = "https://verifier.example.com/response"
+	testPresentationDefinition   = `{"id":"test-pd","input_descriptors":[{"id":"test-descriptor"}]}`
+	testPresentationDefinitionID = "test-pd"
+)
+
+func TestNewInteraction_RequestByValue(t *testing.T) {
+	t.Run("Signed request object", func(t *testing.T) {
+		interaction, err := NewInteraction("openid4vp://?request="+url.QueryEscape(requestObjectJWT),
+			&jwtSignatureVerifierMock{}, nil, nil, nil)
+		require.NoError(t, err)
+		require.NotNil(t, interaction.GetQuery())
+	})
+
+	t.Run("Unsigned request object with redirect_uri client id scheme", func(t *testing.T) {
+		reqObjectJWT := serializeRequestObject(t, &requestObject{
+			ClientIDScheme:         redirectURIScheme,
+			ResponseURI:            testVerifierURI,
+			PresentationDefinition: testPD(t),
+		})
+
+		interaction, err := NewInteraction("openid4vp://?client_id="+url.QueryEscape(testVerifierURI)+
+			"&request="+reqObjectJWT, &jwtSignatureVerifierMock{}, nil, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, testPresentationDefinitionID, interaction.GetQuery().ID)
+	})
+
+	t.Run("Invalid signature", func(t *testing.T) {
+		interaction, err := NewInteraction("openid4vp://?request="+url.QueryEscape(requestObjectJWT),
+			&jwtSignatureVerifierMock{err: errors.New("sig verification err")}, nil, nil, nil)
+		require.ErrorContains(t, err, "INVALID_AUTHORIZATION_REQUEST")
+		require.ErrorContains(t, err, "sig verification err")
+		require.Nil(t, interaction)
+	})
+
+	t.Run("Both request and request_uri", func(t *testing.T) {
+		interaction, err := NewInteraction("openid4vp://?request="+url.QueryEscape(requestObjectJWT)+
+			"&request_uri=https://example.com/request-object", &jwtSignatureVerifierMock{}, nil, nil, nil)
+		require.ErrorContains(t, err, "must not contain both request and request_uri")
+		require.Nil(t, interaction)
+	})
+}
+
+func TestNewInteraction_URLParameters(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		interaction, err := NewInteraction(parametersRequest(url.Values{
+			"presentation_definition": {testPresentationDefinition},
+			"client_metadata":         {`{"client_name":"Test Verifier"}`},
+		}), &jwtSignatureVerifierMock{}, nil, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, testPresentationDefinitionID, interaction.GetQuery().ID)
+		require.Equal(t, "Test Verifier", interaction.VerifierDisplayData().Name)
+		require.Equal(t, "test-nonce", interaction.requestObject.Nonce)
+
+		trustInfo, err := interaction.TrustInfo()
+		require.NoError(t, err)
+		require.Equal(t, "verifier.example.com", trustInfo.Domain)
+	})
+
+	t.Run("DCQL query", func(t *testing.T) {
+		interaction, err := NewInteraction(parametersRequest(url.Values{
+			"dcql_query": {`{"credentials":[{"id":"pid","format":"dc+sd-jwt"}]}`},
+		}), &jwtSignatureVerifierMock{}, nil, nil, nil)
+		require.NoError(t, err)
+		require.NotNil(t, interaction.DCQLQuery())
+		require.Nil(t, interaction.GetQuery())
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		tests := []struct {
+			name          string
+			params        url.Values
+			expectedError string
+		}{
+			{
+				name:          "client_id doesn't match response_uri",
+				params:        url.Values{"client_id": {"https://other.example.com/response"}},
+				expectedError: "client_id mismatch between authorization request and response_uri",
+			},
+			{
+				name:          "client_id_scheme that requires a signature",
+				params:        url.Values{"client_id_scheme": {"did"}},
+				expectedError: "client_id_scheme did requires a signed request object",
+			},
+			{
+				name:          "invalid presentation_definition",
+				params:        url.Values{"presentation_definition": {"{"}},
+				expectedError: "decode presentation_definition",
+			},
+			{
+				name: "presentation_definition and presentation_definition_uri",
+				params: url.Values{
+					"presentation_definition":     {testPresentationDefinition},
+					"presentation_definition_uri": {"https://verifier.example.com/pd"},
+				},
+				expectedError: "must not contain both presentation_definition and presentation_definition_uri",
+			},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				interaction, err := NewInteraction(parametersRequest(test.params), &jwtSignatureVerifierMock{}, nil,
+					nil, nil)
+				require.ErrorContains(t, err, "INVALID_AUTHORIZATION_REQUEST")
+				require.ErrorContains(t, err, test.expectedError)
+				require.Nil(t, interaction)
+			})
+		}
+	})
+}
+
+func TestNewInteraction_PresentationDefinitionURI(t *testing.T) {
+	request := parametersRequest(url.Values{"presentation_definition_uri": {"https://verifier.example.com/pd"}})
+
+	t.Run("Success", func(t *testing.T) {
+		interaction, err := NewInteraction(request, &jwtSignatureVerifierMock{}, nil, nil, nil,
+			WithHTTPClient(&mock.HTTPClientMock{
+				Response:         testPresentationDefinition,
+				StatusCode:       http.StatusOK,
+				ExpectedEndpoint: "https://verifier.example.com/pd",
+			}))
+		require.NoError(t, err)
+		require.Equal(t, testPresentationDefinitionID, interaction.GetQuery().ID)
+	})
+
+	t.Run("Referenced from a request object", func(t *testing.T) {
+		reqObjectJWT := serializeRequestObject(t, &requestObject{
+			ClientIDScheme:            redirectURIScheme,
+			ResponseURI:               testVerifierURI,
+			PresentationDefinitionURI: "https://verifier.example.com/pd",
+		})
+
+		interaction, err := NewInteraction("openid4vp://?client_id="+url.QueryEscape(testVerifierURI)+
+			"&request="+reqObjectJWT, &jwtSignatureVerifierMock{}, nil, nil, nil,
+			WithHTTPClient(&mock.HTTPClientMock{
+				Response:         testPresentationDefinition,
+				StatusCode:       http.StatusOK,
+				ExpectedEndpoint: "https://verifier.example.com/pd",
+			}))
+		require.NoError(t, err)
+		require.Equal(t, testPresentationDefinitionID, interaction.GetQuery().ID)
+	})
+
+	t.Run("Fetch failed", func(t *testing.T) {
+		interaction, err := NewInteraction(request, &jwtSignatureVerifierMock{}, nil, nil, nil,
+			WithHTTPClient(&mock.HTTPClientMock{
+				StatusCode: http.StatusNotFound,
+			}))
+		require.ErrorContains(t, err, "PRESENTATION_DEFINITION_FETCH_FAILED(OVP1-0014)")
+		require.Nil(t, interaction)
+	})
+
+	t.Run("Invalid presentation definition", func(t *testing.T) {
+		interaction, err := NewInteraction(request, &jwtSignatureVerifierMock{}, nil, nil, nil,
+			WithHTTPClient(&mock.HTTPClientMock{
+				Response:   "not a presentation definition",
+				StatusCode: http.StatusOK,
+			}))
+		require.ErrorContains(t, err, "INVALID_AUTHORIZATION_REQUEST")
+		require.ErrorContains(t, err, "decode presentation definition")
+		require.Nil(t, interaction)
+	})
+}
+
+func TestNewInteraction_RequestURIMethodPost(t *testing.T) {
+	newServer := func(t *testing.T, echoNonce bool) *httptest.Server {
+		t.Helper()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, http.MethodPost, r.Method)
+			require.Equal(t, requestObjectMediaType, r.Header.Get("Accept"))
+			require.NoError(t, r.ParseForm())
+
+			metadata := &walletMetadata{}
+			require.NoError(t, json.Unmarshal([]byte(r.PostForm.Get("wallet_metadata")), metadata))
+			require.True(t, metadata.PresentationDefinitionURISupported)
+			require.Contains(t, metadata.ClientIDSchemesSupported, redirectURIScheme)
+			require.Contains(t, metadata.ResponseModesSupported, directPostJWTResponseMode)
+
+			reqObject := &requestObject{
+				ClientIDScheme:         redirectURIScheme,
+				ResponseURI:            testVerifierURI,
+				PresentationDefinition: testPD(t),
+				WalletNonce:            "other-nonce",
+			}
+
+			if echoNonce {
+				reqObject.WalletNonce = r.PostForm.Get("wallet_nonce")
+			}
+
+			_, err := w.Write([]byte(serializeRequestObject(t, reqObject)))
+			require.NoError(t, err)
+		}))
+
+		t.Cleanup(server.Close)
+
+		return server
+	}
+
+	request := func(server *httptest.Server, method string) string {
+		return "openid4vp://?client_id=" + url.QueryEscape(testVerifierURI) +
+			"&request_uri=" + url.QueryEscape(server.URL) + "&request_uri_method=" + method
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		server := newServer(t, true)
+
+		interaction, err := NewInteraction(request(server, "post"), &jwtSignatureVerifierMock{}, nil, nil, nil,
+			WithHTTPClient(server.Client()))
+		require.NoError(t, err)
+		require.Equal(t, testPresentationDefinitionID, interaction.GetQuery().ID)
+	})
+
+	t.Run("wallet_nonce mismatch", func(t *testing.T) {
+		server := newServer(t, false)
+
+		interaction, err := NewInteraction(request(server, "post"), &jwtSignatureVerifierMock{}, nil, nil, nil,
+			WithHTTPClient(server.Client()))
+		require.ErrorContains(t, err, "wallet_nonce in request object doesn't match the one sent by the wallet")
+		require.Nil(t, interaction)
+	})
+
+	t.Run("Unsupported request_uri_method", func(t *testing.T) {
+		server := newServer(t, true)
+
+		interaction, err := NewInteraction(request(server, "put"), &jwtSignatureVerifierMock{}, nil, nil, nil,
+			WithHTTPClient(server.Client()))
+		require.ErrorContains(t, err, "INVALID_AUTHORIZATION_REQUEST")
+		require.ErrorContains(t, err, "unsupported request_uri_method: put")
+		require.Nil(t, interaction)
+	})
+}
+
+func parametersRequest(params url.Values) string {
+	query := url.Values{
+		"client_id":     {testVerifierURI},
+		"response_type": {"vp_token"},
+		"response_mode": {"direct_post"},
+		"response_uri":  {testVerifierURI},
+		"nonce":         {"test-nonce"},
+	}
+
+	for name, values := range params {
+		query[name] = values
+	}
+
+	return "openid4vp://?" + query.Encode()
+}
+
+func serializeRequestObject(t *testing.T, reqObject *requestObject) string {
+	t.Helper()
+
+	token, err := jwt.NewUnsecured(reqObject)
+	require.NoError(t, err)
+
+	reqObjectJWT, err := token.Serialize(false)
+	require.NoError(t, err)
+
+	return reqObjectJWT
+}
+
+func testPD(t *testing.T) *presexch.PresentationDefinition {
+	t.Helper()
+
+	pd := &presexch.PresentationDefinition{}
+	require.NoError(t, json.Unmarshal([]byte(testPresentationDefinition), pd))
+
+	return pd
+}
@@ -0,0 +1,650 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4vp
+
+import (
+	"context"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/piprate/json-gold/ld"
+	diddoc "github.com/trustbloc/did-go/doc/did"
+	"github.com/trustbloc/kms-go/doc/jose"
+	"github.com/trustbloc/kms-go/doc/jose/jwk"
+	"github.com/trustbloc/vc-go/jwt"
+	sdjwtcommon "github.com/trustbloc/vc-go/sdjwt/common"
+	"github.com/trustbloc/vc-go/verifiable"
+
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/common"
+	"github.com/trustbloc/wallet-sdk/pkg/dcql"
+	"github.com/trustbloc/wallet-sdk/pkg/models"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+const (
+	keyBindingJWTType = "kb+jwt"
+
+	sdDigestsKey     = "_sd"
+	arrayElementKey  = "..."
+	disclosureLength = 3
+)
+
+// DCQLQuery returns the DCQL query from the request object, or nil if the verifier used a presentation definition
+// instead.
+func (o *Interaction) DCQLQuery() *dcql.Query {
+	return o.requestObject.DCQLQuery
+}
+
+// PresentDCQLCredentials presents credentials in response to a request that contains a DCQL query. The selection
+// maps credential query IDs to the credentials that the user chose for them, and must satisfy the query.
+//
+// SD-JWT VCs only disclose the claims that the credential query asks for, and are bound to the request with a
+// key binding JWT. Other credentials are presented in full. Presenting mdocs isn't supported.
+func (o *Interaction) PresentDCQLCredentials(
+	selection map[string][]*verifiable.Credential,
+	customClaims CustomClaims,
+	opts ...PresentOpt,
+) error {
+	return o.PresentDCQLCredentialsContext(context.Background(), selection, customClaims, opts...)
+}
+
+// PresentDCQLCredentialsContext is the same as PresentDCQLCredentials, but sends the authorized response using the
+// given context.
+func (o *Interaction) PresentDCQLCredentialsContext(
+	ctx context.Context,
+	selection map[string][]*verifiable.Credential,
+	customClaims CustomClaims,
+	opts ...PresentOpt,
+) error {
+	if o.requestObject.DCQLQuery == nil {
+		return walleterror.NewInvalidSDKUsageError(ErrorModule,
+			errors.New("the request doesn't contain a DCQL query, so credentials must be presented with "+
+				"PresentCredential"))
+	}
+
+	resolveOpts := &presentOpts{}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(resolveOpts)
+		}
+	}
+
+	timeStartPresentCredential := time.Now()
+
+	response, err := createDCQLAuthorizedResponse(
+		selection,
+		o.requestObject,
+		customClaims,
+		o.didResolver,
+		o.crypto,
+		o.documentLoader,
+		resolveOpts,
+	)
+	if err != nil {
+		return walleterror.NewExecutionError(
+			ErrorModule,
+			CreateAuthorizedResponseFailedCode,
+			CreateAuthorizedResponseFailedError,
+			fmt.Errorf("create authorized response failed: %w", err))
+	}
+
+	data := url.Values{}
+	data.Set("vp_token", response.VPToken)
+
+	return o.sendResponse(ctx, data, response, resolveOpts, timeStartPresentCredential)
+}
+
+func createDCQLAuthorizedResponse( //nolint:funlen // Unable to decompose without making it harder to follow
+	selection map[string][]*verifiable.Credential,
+	requestObject *requestObject,
+	customClaims CustomClaims,
+	didResolver api.DIDResolver,
+	crypto api.Crypto,
+	documentLoader ld.DocumentLoader,
+	opts *presentOpts,
+) (*authorizedResponse, error) {
+	query := requestObject.DCQLQuery
+
+	selected, err := dcql.Select(query, selection)
+	if err != nil {
+		return nil, err
+	}
+
+	vpToken := map[string][]interface{}{}
+
+	var (
+		idTokenSigningDID string
+		idTokenSigner     api.JWTSigner
+	)
+
+	// The credential queries are processed in the query's order so that the DID used for the id_token is
+	// deterministic.
+	for _, credentialQuery := range query.Credentials {
+		for _, matched := range selected[credentialQuery.ID] {
+			presentation, holderDID, signer, e := createDCQLPresentation(credentialQuery, matched, requestObject,
+				didResolver, crypto, documentLoader)
+			if e != nil {
+				return nil, fmt.Errorf("credential query %s: %w", credentialQuery.ID, e)
+			}
+
+			vpToken[credentialQuery.ID] = append(vpToken[credentialQuery.ID], presentation)
+
+			if idTokenSigningDID == "" && holderDID != "" {
+				idTokenSigningDID, idTokenSigner = holderDID, signer
+			}
+		}
+	}
+
+	vpTokenJSON, err := json.Marshal(vpToken)
+	if err != nil {
+		return nil, fmt.Errorf("marshal vp token: %w", err)
+	}
+
+	var idTokenJWS string
+
+	if strings.Contains(requestObject.ResponseType, "id_token") {
+		if idTokenSigningDID == "" {
+			return nil, errors.New("an id_token was requested, but none of the presented credentials is bound " +
+				"to a DID")
+		}
+
+		var attestationVP string
+
+		if opts.attestationVC != "" {
+			attestationVP, err = createAttestationVP(opts.attestationVC, opts.attestationVPSigner, documentLoader)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		idTokenJWS, err = createIDToken(requestObject, idTokenSigningDID, customClaims, idTokenSigner,
+			attestationVP, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &authorizedResponse{
+		IDTokenJWS: idTokenJWS,
+		VPToken:    string(vpTokenJSON),
+		State:      requestObject.State,
+		SigningDID: idTokenSigningDID,
+		Signer:     idTokenSigner,
+	}, nil
+}
+
+// createDCQLPresentation creates the presentation of a single credential. Along with the presentation, it returns
+// the holder's DID and signer if the presentation was signed using a DID.
+func createDCQLPresentation(
+	credentialQuery *dcql.CredentialQuery,
+	matched *dcql.MatchedCredential,
+	requestObject *requestObject,
+	didResolver api.DIDResolver,
+	crypto api.Crypto,
+	documentLoader ld.DocumentLoader,
+) (interface{}, string, api.JWTSigner, error) {
+	switch format := dcql.CredentialFormat(matched.Credential); format {
+	case dcql.FormatSDJWTVC:
+		return createSDJWTPresentation(credentialQuery, matched, requestObject, didResolver, crypto)
+	case dcql.FormatJWTVC, dcql.FormatLDPVC:
+		return createW3CPresentation(matched.Credential, format, requestObject, didResolver, crypto, documentLoader)
+	default:
+		return nil, "", nil, fmt.Errorf("presenting %s credentials isn't supported", format)
+	}
+}
+
+func createW3CPresentation(
+	credential *verifiable.Credential,
+	format string,
+	requestObject *requestObject,
+	didResolver api.DIDResolver,
+	crypto api.Crypto,
+	documentLoader ld.DocumentLoader,
+) (interface{}, string, api.JWTSigner, error) {
+	holderDID, err := getSubjectID(credential)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("presentation VC does not have a subject ID: %w", err)
+	}
+
+	if holderDID == "" {
+		return nil, "", nil, errors.New("presentation VC does not have a subject ID")
+	}
+
+	assertionVM, err := getAssertionVM(holderDID, didResolver)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	signer, err := getHolderSigner(assertionVM, crypto)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	presentation, err := verifiable.NewPresentation(verifiable.WithCredentials(credential))
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("create presentation: %w", err)
+	}
+
+	presentation.ID = "urn:uuid:" + uuid.NewString()
+
+	if format == dcql.FormatLDPVC {
+		vpToken, e := createLdpVPToken(crypto, documentLoader, didResolver, holderDID, assertionVM, requestObject,
+			presentation)
+		if e != nil {
+			return nil, "", nil, fmt.Errorf("create ldp vp token: %w", e)
+		}
+
+		return json.RawMessage(vpToken), holderDID, signer, nil
+	}
+
+	claims := vpTokenClaims{
+		VP:    presentation,
+		Nonce: requestObject.Nonce,
+		Exp:   time.Now().Unix() + tokenLiveTimeSec,
+		Iss:   holderDID,
+		Aud:   requestObject.ClientID,
+		Nbf:   time.Now().Unix(),
+		Iat:   time.Now().Unix(),
+		Jti:   uuid.NewString(),
+	}
+
+	vpToken, err := signToken(claims, signer)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("sign vp token: %w", err)
+	}
+
+	return vpToken, holderDID, signer, nil
+}
+
+// createSDJWTPresentation creates an SD-JWT presentation that only contains the disclosures needed for the matched
+// claims. If the SD-JWT VC is bound to a key, then a key binding JWT is appended.
+func createSDJWTPresentation(
+	credentialQuery *dcql.CredentialQuery,
+	matched *dcql.MatchedCredential,
+	requestObject *requestObject,
+	didResolver api.DIDResolver,
+	crypto api.Crypto,
+) (interface{}, string, api.JWTSigner, error) {
+	combinedFormat := sdjwtcommon.ParseCombinedFormatForIssuance(matched.Credential.JWTEnvelope.JWT)
+
+	token, _, err := jwt.Parse(combinedFormat.SDJWT)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("parse SD-JWT: %w", err)
+	}
+
+	hash, err := sdjwtcommon.GetCryptoHashFromClaims(token.Payload)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	disclosures, err := selectDisclosures(token.Payload, combinedFormat.Disclosures, hash, matched.ClaimPaths)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	presentation := combinedFormat.SDJWT + sdjwtcommon.CombinedFormatSeparator
+
+	for _, disclosure := range disclosures {
+		presentation += disclosure + sdjwtcommon.CombinedFormatSeparator
+	}
+
+	cnf, err := sdjwtcommon.GetCNF(token.Payload)
+	if err != nil {
+		if credentialQuery.RequireCryptographicHolderBinding == nil || *credentialQuery.RequireCryptographicHolderBinding {
+			return nil, "", nil, fmt.Errorf("the verifier requires holder binding, but the SD-JWT VC isn't bound "+
+				"to a key: %w", err)
+		}
+
+		return presentation, "", nil, nil
+	}
+
+	signer, holderDID, err := getKeyBindingSigner(cnf, didResolver, crypto)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	keyBindingJWT, err := createKeyBindingJWT(presentation, hash, requestObject, signer)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return presentation + keyBindingJWT, holderDID, signer, nil
+}
+
+func createKeyBindingJWT(
+	presentation string,
+	hash crypto.Hash,
+	requestObject *requestObject,
+	signer api.JWTSigner,
+) (string, error) {
+	sdHash, err := sdjwtcommon.GetHash(hash, presentation)
+	if err != nil {
+		return "", fmt.Errorf("hash SD-JWT presentation: %w", err)
+	}
+
+	claims := keyBindingClaims{
+		Nonce:  requestObject.Nonce,
+		Aud:    requestObject.ClientID,
+		Iat:    time.Now().Unix(),
+		SDHash: sdHash,
+	}
+
+	token, err := jwt.NewSigned(claims,
+		jwt.SignParameters{AdditionalHeaders: jose.Headers{jose.HeaderType: keyBindingJWTType}}, signer)
+	if err != nil {
+		return "", fmt.Errorf("sign key binding JWT: %w", err)
+	}
+
+	keyBindingJWT, err := token.Serialize(false)
+	if err != nil {
+		return "", fmt.Errorf("serialize key binding JWT: %w", err)
+	}
+
+	return keyBindingJWT, nil
+}
+
+// getKeyBindingSigner returns a signer for the key in the given cnf claim. If the key is identified by a DID URL,
+// then the DID is also returned.
+func getKeyBindingSigner(
+	cnf map[string]interface{},
+	didResolver api.DIDResolver,
+	crypto api.Crypto,
+) (api.JWTSigner, string, error) {
+	if kid, ok := cnf["kid"].(string); ok {
+		vm, err := getVerificationMethod(kid, didResolver)
+		if err != nil {
+			return nil, "", err
+		}
+
+		signer, err := getHolderSigner(vm, crypto)
+		if err != nil {
+			return nil, "", err
+		}
+
+		return signer, strings.Split(kid, "#")[0], nil
+	}
+
+	jwkObject, ok := cnf["jwk"]
+	if !ok {
+		return nil, "", errors.New("the cnf claim in the SD-JWT VC must contain either a jwk or a kid")
+	}
+
+	jwkBytes, err := json.Marshal(jwkObject)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var key jwk.JWK
+
+	err = key.UnmarshalJSON(jwkBytes)
+	if err != nil {
+		return nil, "", fmt.Errorf("parse the jwk in the SD-JWT VC's cnf claim: %w", err)
+	}
+
+	signer, err := common.NewJWSSigner(&models.VerificationMethod{
+		Type: common.JSONWebKey2020,
+		Key:  models.VerificationKey{JSONWebKey: &key},
+	}, crypto)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return signer, "", nil
+}
+
+func getVerificationMethod(keyID string, didResolver api.DIDResolver) (*diddoc.VerificationMethod, error) {
+	holderDID := strings.Split(keyID, "#")[0]
+
+	docRes, err := didResolver.Resolve(holderDID)
+	if err != nil {
+		return nil, fmt.Errorf("resolve holder DID for key binding: %w", err)
+	}
+
+	for i := range docRes.DIDDocument.VerificationMethod {
+		vm := docRes.DIDDocument.VerificationMethod[i]
+
+		// Verification method IDs may be relative to the DID.
+		if vm.ID == keyID || holderDID+vm.ID == keyID {
+			return &vm, nil
+		}
+	}
+
+	return nil, fmt.Errorf("verification method %s not found in the holder's DID document", keyID)
+}
+
+type sdJWTDisclosure struct {
+	encoded        string
+	name           string
+	value          interface{}
+	isArrayElement bool
+}
+
+// disclosureSelector walks an SD-JWT's claims, recording the disclosures that are needed to reveal them.
+type disclosureSelector struct {
+	disclosures map[string]*sdJWTDisclosure
+	selected    map[string]struct{}
+}
+
+// selectDisclosures returns the disclosures that are needed to reveal the claims at the given paths, along with any
+// claims nested inside them. The disclosures are returned in their original order.
+func selectDisclosures(
+	payload map[string]interface{},
+	disclosures []string,
+	hash crypto.Hash,
+	claimPaths [][]interface{},
+) ([]string, error) {
+	selector := &disclosureSelector{
+		disclosures: map[string]*sdJWTDisclosure{},
+		selected:    map[string]struct{}{},
+	}
+
+	for _, encoded := range disclosures {
+		if encoded == "" {
+			continue
+		}
+
+		disclosure, err := decodeDisclosure(encoded)
+		if err != nil {
+			return nil, err
+		}
+
+		digest, err := sdjwtcommon.GetHash(hash, encoded)
+		if err != nil {
+			return nil, fmt.Errorf("hash disclosure: %w", err)
+		}
+
+		selector.disclosures[digest] = disclosure
+	}
+
+	for _, path := range claimPaths {
+		selector.selectPath(payload, path)
+	}
+
+	var selected []string
+
+	for _, encoded := range disclosures {
+		if _, ok := selector.selected[encoded]; ok {
+			selected = append(selected, encoded)
+		}
+	}
+
+	return selected, nil
+}
+
+func decodeDisclosure(encoded string) (*sdJWTDisclosure, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil {
+		return nil, fmt.Errorf("decode disclosure: %w", err)
+	}
+
+	var elements []interface{}
+
+	err = json.Unmarshal(decoded, &elements)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal disclosure: %w", err)
+	}
+
+	switch len(elements) {
+	case disclosureLength:
+		name, ok := elements[1].(string)
+		if !ok {
+			return nil, errors.New("disclosure claim name must be a string")
+		}
+
+		return &sdJWTDisclosure{encoded: encoded, name: name, value: elements[2]}, nil
+	case disclosureLength - 1:
+		return &sdJWTDisclosure{encoded: encoded, value: elements[1], isArrayElement: true}, nil
+	default:
+		return nil, fmt.Errorf("disclosure must have 2 or 3 elements, got %d", len(elements))
+	}
+}
+
+func (s *disclosureSelector) selectPath(claims interface{}, path []interface{}) {
+	values := []interface{}{claims}
+
+	for _, element := range path {
+		var children []interface{}
+
+		for _, value := range values {
+			children = append(children, s.children(value, element)...)
+		}
+
+		values = children
+	}
+
+	for _, value := range values {
+		s.selectNested(value)
+	}
+}
+
+// children returns the values that a claims path element selects from the given value, selecting any disclosures
+// needed to reveal them.
+func (s *disclosureSelector) children(value, element interface{}) []interface{} {
+	if key, ok := element.(string); ok {
+		object, isObject := value.(map[string]interface{})
+		if !isObject {
+			return nil
+		}
+
+		if child, exists := object[key]; exists && key != sdDigestsKey {
+			return []interface{}{child}
+		}
+
+		for _, disclosure := range s.objectDisclosures(object) {
+			if disclosure.name == key {
+				s.selected[disclosure.encoded] = struct{}{}
+
+				return []interface{}{disclosure.value}
+			}
+		}
+
+		return nil
+	}
+
+	array, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	if element != nil {
+		index, isIndex := element.(float64)
+		if !isIndex || index < 0 || int(index) >= len(array) {
+			return nil
+		}
+
+		array = array[int(index) : int(index)+1]
+	}
+
+	var children []interface{}
+
+	for _, arrayElement := range array {
+		if child, revealed := s.revealArrayElement(arrayElement); revealed {
+			children = append(children, child)
+		}
+	}
+
+	return children
+}
+
+// selectNested selects the disclosures of all the claims nested inside the given value.
+func (s *disclosureSelector) selectNested(value interface{}) {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		for key, child := range typedValue {
+			if key != sdDigestsKey {
+				s.selectNested(child)
+			}
+		}
+
+		for _, disclosure := range s.objectDisclosures(typedValue) {
+			s.selected[disclosure.encoded] = struct{}{}
+
+			s.selectNested(disclosure.value)
+		}
+	case []interface{}:
+		for _, arrayElement := range typedValue {
+			if child, revealed := s.revealArrayElement(arrayElement); revealed {
+				s.selectNested(child)
+			}
+		}
+	}
+}
+
+// objectDisclosures returns the disclosures of the object's selectively disclosable properties.
+func (s *disclosureSelector) objectDisclosures(object map[string]interface{}) []*sdJWTDisclosure {
+	digests, ok := object[sdDigestsKey].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	var disclosures []*sdJWTDisclosure
+
+	for _, digest := range digests {
+		digestString, isString := digest.(string)
+		if !isString {
+			continue
+		}
+
+		if disclosure, exists := s.disclosures[digestString]; exists && !disclosure.isArrayElement {
+			disclosures = append(disclosures, disclosure)
+		}
+	}
+
+	return disclosures
+}
+
+// revealArrayElement returns the value of an array element. If the element is selectively disclosable, then its
+// disclosure is selected. If the holder doesn't have the disclosure, then false is returned.
+func (s *disclosureSelector) revealArrayElement(arrayElement interface{}) (interface{}, bool) {
+	object, ok := arrayElement.(map[string]interface{})
+	if !ok || len(object) != 1 {
+		return arrayElement, true
+	}
+
+	digest, ok := object[arrayElementKey].(string)
+	if !ok {
+		return arrayElement, true
+	}
+
+	disclosure, exists := s.disclosures[digest]
+	if !exists || !disclosure.isArrayElement {
+		return nil, false
+	}
+
+	s.selected[disclosure.encoded] = struct{}{}
+
+	return disclosure.value, true
+}
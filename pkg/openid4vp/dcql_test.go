@@ -0,0 +1,466 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4vp //nolint: testpackage
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/piprate/json-gold/ld"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/kms-go/doc/jose"
+	"github.com/trustbloc/kms-go/doc/util/jwkkid"
+	"github.com/trustbloc/kms-go/spi/kms"
+	"github.com/trustbloc/vc-go/jwt"
+	"github.com/trustbloc/vc-go/presexch"
+	sdjwtcommon "github.com/trustbloc/vc-go/sdjwt/common"
+	"github.com/trustbloc/vc-go/sdjwt/issuer"
+	"github.com/trustbloc/vc-go/verifiable"
+
+	"github.com/trustbloc/wallet-sdk/internal/testutil"
+	"github.com/trustbloc/wallet-sdk/pkg/activitylogger/noop"
+	"github.com/trustbloc/wallet-sdk/pkg/dcql"
+	"github.com/trustbloc/wallet-sdk/pkg/internal/mock"
+	noopmetricslogger "github.com/trustbloc/wallet-sdk/pkg/metricslogger/noop"
+	"github.com/trustbloc/wallet-sdk/pkg/sdjwtvc"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+const (
+	pidVct        = "urn:eu.europa.ec.eudi:pid:1"
+	testClientID  = "https://verifier.example.com"
+	testNonce     = "n-0S6_WzA2Mj"
+	testDCQLQuery = `{
+		"credentials": [
+			{
+				"id": "pid",
+				"format": "dc+sd-jwt",
+				"meta": {"vct_values": ["urn:eu.europa.ec.eudi:pid:1"]},
+				"claims": [
+					{"path": ["given_name"]},
+					{"path": ["address", "country"]}
+				]
+			},
+			{
+				"id": "degree",
+				"format": "ldp_vc",
+				"claims": [{"path": ["credentialSubject", "degree", "type"], "values": ["BachelorDegree"]}]
+			}
+		],
+		"credential_sets": [
+			{"options": [["pid"]]},
+			{"options": [["degree"]], "required": false}
+		]
+	}`
+)
+
+func TestParseRequestObject_DCQL(t *testing.T) {
+	query, err := dcql.Parse([]byte(testDCQLQuery))
+	require.NoError(t, err)
+
+	verifier := &requestObjectVerifier{signatureVerifier: &jwtSignatureVerifierMock{}}
+
+	t.Run("Success", func(t *testing.T) {
+		reqObject, err := parseRequestObject(testClientID,
+			createUnsecuredRequestObject(t, &requestObject{DCQLQuery: query}), verifier)
+		require.NoError(t, err)
+		require.Equal(t, query, reqObject.DCQLQuery)
+	})
+
+	t.Run("Both presentation definition and DCQL query", func(t *testing.T) {
+		reqObject, err := parseRequestObject(testClientID, createUnsecuredRequestObject(t, &requestObject{
+			DCQLQuery:              query,
+			PresentationDefinition: &presexch.PresentationDefinition{ID: "pd"},
+		}), verifier)
+		require.ErrorContains(t, err, "must not contain both presentation_definition and dcql_query")
+		require.Nil(t, reqObject)
+	})
+
+	t.Run("Invalid DCQL query", func(t *testing.T) {
+		reqObject, err := parseRequestObject(testClientID,
+			createUnsecuredRequestObject(t, &requestObject{DCQLQuery: &dcql.Query{}}), verifier)
+		require.ErrorContains(t, err, "invalid dcql_query: DCQL query must contain at least one credential query")
+		require.Nil(t, reqObject)
+	})
+}
+
+func TestOpenID4VP_PresentDCQLCredentials(t *testing.T) {
+	lddl := testutil.DocumentLoader(t)
+
+	query, err := dcql.Parse([]byte(testDCQLQuery))
+	require.NoError(t, err)
+
+	degreeCredential := parseJSONLDCredential(t, lddl)
+	mockDoc := mockResolution(t, mockDID, false)
+
+	newInteraction := func(reqObject *requestObject, httpClient *mock.HTTPClientMock) *Interaction {
+		return &Interaction{
+			requestObject:  reqObject,
+			httpClient:     httpClient,
+			activityLogger: noop.NewActivityLogger(),
+			metricsLogger:  noopmetricslogger.NewMetricsLogger(),
+			didResolver:    &didResolverMock{ResolveValue: mockDoc},
+			crypto:         &cryptoMock{SignVal: []byte(testSignature)},
+			documentLoader: lddl,
+		}
+	}
+
+	t.Run("Success - SD-JWT VC bound to a JWK", func(t *testing.T) {
+		pid := createPIDCredential(t, nil)
+		httpClient := &mock.HTTPClientMock{StatusCode: 200}
+
+		interaction := newInteraction(&requestObject{
+			ClientID:     testClientID,
+			Nonce:        testNonce,
+			State:        "state",
+			ResponseType: "vp_token",
+			DCQLQuery:    query,
+		}, httpClient)
+		require.Equal(t, query, interaction.DCQLQuery())
+
+		err = interaction.PresentDCQLCredentials(map[string][]*verifiable.Credential{"pid": {pid}}, CustomClaims{})
+		require.NoError(t, err)
+
+		data, err := url.ParseQuery(string(httpClient.SentBody))
+		require.NoError(t, err)
+		require.Equal(t, "state", data.Get("state"))
+		require.NotContains(t, data, "presentation_submission")
+		require.NotContains(t, data, "id_token")
+
+		var vpToken map[string][]string
+
+		require.NoError(t, json.Unmarshal([]byte(data.Get("vp_token")), &vpToken))
+		require.Len(t, vpToken["pid"], 1)
+
+		checkPIDPresentation(t, vpToken["pid"][0], pid)
+	})
+
+	t.Run("Success - SD-JWT VC bound to a DID and LDP VC, with id_token", func(t *testing.T) {
+		pid := createPIDCredential(t, map[string]interface{}{"kid": mockDID + mockVMID})
+		httpClient := &mock.HTTPClientMock{StatusCode: 200}
+
+		interaction := newInteraction(&requestObject{
+			ClientID:     testClientID,
+			Nonce:        testNonce,
+			ResponseType: "vp_token id_token",
+			ClientMetadata: clientMetadata{VPFormats: &presexch.Format{
+				LdpVP: &presexch.LdpType{ProofType: []string{"Ed25519Signature2018"}},
+			}},
+			DCQLQuery: query,
+		}, httpClient)
+
+		err = interaction.PresentDCQLCredentials(map[string][]*verifiable.Credential{
+			"pid":    {pid},
+			"degree": {degreeCredential},
+		}, CustomClaims{})
+		require.NoError(t, err)
+
+		data, err := url.ParseQuery(string(httpClient.SentBody))
+		require.NoError(t, err)
+		require.NotEmpty(t, data.Get("id_token"))
+
+		var vpToken map[string][]json.RawMessage
+
+		require.NoError(t, json.Unmarshal([]byte(data.Get("vp_token")), &vpToken))
+		require.Len(t, vpToken["pid"], 1)
+		require.Len(t, vpToken["degree"], 1)
+
+		var sdJWTPresentation string
+
+		require.NoError(t, json.Unmarshal(vpToken["pid"][0], &sdJWTPresentation))
+		checkPIDPresentation(t, sdJWTPresentation, pid)
+
+		presentation, err := verifiable.ParsePresentation(vpToken["degree"][0],
+			verifiable.WithPresDisabledProofCheck(), verifiable.WithPresJSONLDDocumentLoader(lddl))
+		require.NoError(t, err)
+		require.Len(t, presentation.Credentials(), 1)
+		require.Len(t, presentation.Proofs, 1)
+		require.Equal(t, testNonce, presentation.Proofs[0]["challenge"])
+	})
+
+	t.Run("Request doesn't contain a DCQL query", func(t *testing.T) {
+		interaction := newInteraction(&requestObject{}, &mock.HTTPClientMock{StatusCode: 200})
+
+		err = interaction.PresentDCQLCredentials(nil, CustomClaims{})
+
+		var walletError *walleterror.Error
+
+		require.ErrorAs(t, err, &walletError)
+		require.Equal(t, "INVALID_SDK_USAGE", walletError.Category)
+		require.Contains(t, err.Error(), "must be presented with PresentCredential")
+	})
+
+	t.Run("Presentation exchange methods can't be used with a DCQL query", func(t *testing.T) {
+		interaction := newInteraction(&requestObject{DCQLQuery: query}, &mock.HTTPClientMock{StatusCode: 200})
+
+		err = interaction.PresentCredential([]*verifiable.Credential{degreeCredential}, CustomClaims{})
+		require.ErrorContains(t, err, "must be presented with PresentDCQLCredentials")
+
+		claims, err := interaction.PresentedClaims(degreeCredential)
+		require.ErrorContains(t, err, "must be presented with PresentDCQLCredentials")
+		require.Nil(t, claims)
+	})
+
+	t.Run("Selection doesn't satisfy the query", func(t *testing.T) {
+		interaction := newInteraction(&requestObject{DCQLQuery: query}, &mock.HTTPClientMock{StatusCode: 200})
+
+		err = interaction.PresentDCQLCredentials(map[string][]*verifiable.Credential{
+			"degree": {degreeCredential},
+		}, CustomClaims{})
+		require.ErrorContains(t, err, CreateAuthorizedResponseFailedError)
+		require.ErrorContains(t, err, "the selected credentials don't satisfy the DCQL query")
+	})
+
+	t.Run("Holder binding is required, but the SD-JWT VC isn't bound to a key", func(t *testing.T) {
+		interaction := newInteraction(&requestObject{DCQLQuery: query}, &mock.HTTPClientMock{StatusCode: 200})
+
+		err = interaction.PresentDCQLCredentials(map[string][]*verifiable.Credential{
+			"pid": {createUnboundPIDCredential(t)},
+		}, CustomClaims{})
+		require.ErrorContains(t, err, "the verifier requires holder binding, but the SD-JWT VC isn't bound to a key")
+	})
+
+	t.Run("id_token requested, but no credential is bound to a DID", func(t *testing.T) {
+		interaction := newInteraction(&requestObject{ResponseType: "vp_token id_token", DCQLQuery: query},
+			&mock.HTTPClientMock{StatusCode: 200})
+
+		err = interaction.PresentDCQLCredentials(map[string][]*verifiable.Credential{
+			"pid": {createPIDCredential(t, nil)},
+		}, CustomClaims{})
+		require.ErrorContains(t, err, "an id_token was requested, but none of the presented credentials is bound")
+	})
+}
+
+func TestSelectDisclosures(t *testing.T) {
+	givenName, givenNameDigest := createDisclosure(t, "given_name", "Erika")
+	locality, localityDigest := createDisclosure(t, "locality", "Berlin")
+	address, addressDigest := createDisclosure(t, "address",
+		map[string]interface{}{"country": "DE", sdDigestsKey: []interface{}{localityDigest}})
+	firstNationality, firstNationalityDigest := createDisclosure(t, "", "DE")
+	secondNationality, secondNationalityDigest := createDisclosure(t, "", "FR")
+
+	payload := map[string]interface{}{
+		sdDigestsKey: []interface{}{givenNameDigest, addressDigest},
+		"nationalities": []interface{}{
+			map[string]interface{}{arrayElementKey: firstNationalityDigest},
+			map[string]interface{}{arrayElementKey: secondNationalityDigest},
+		},
+	}
+
+	disclosures := []string{givenName, locality, address, firstNationality, secondNationality}
+
+	tests := []struct {
+		name     string
+		paths    [][]interface{}
+		expected []string
+	}{
+		{
+			name:     "Top-level claim",
+			paths:    [][]interface{}{{"given_name"}},
+			expected: []string{givenName},
+		},
+		{
+			name:     "Nested claims are disclosed along with their parent",
+			paths:    [][]interface{}{{"address"}},
+			expected: []string{locality, address},
+		},
+		{
+			name:     "Claim that isn't selectively disclosable",
+			paths:    [][]interface{}{{"address", "country"}},
+			expected: []string{address},
+		},
+		{
+			name:     "Array element",
+			paths:    [][]interface{}{{"nationalities", float64(1)}},
+			expected: []string{secondNationality},
+		},
+		{
+			name:     "All array elements",
+			paths:    [][]interface{}{{"nationalities", nil}},
+			expected: []string{firstNationality, secondNationality},
+		},
+		{
+			name:  "Missing claim",
+			paths: [][]interface{}{{"family_name"}, {"nationalities", float64(2)}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			selected, err := selectDisclosures(payload, disclosures, crypto.SHA256, test.paths)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, selected)
+		})
+	}
+
+	t.Run("Invalid disclosure", func(t *testing.T) {
+		selected, err := selectDisclosures(payload, []string{"!"}, crypto.SHA256, nil)
+		require.ErrorContains(t, err, "decode disclosure")
+		require.Nil(t, selected)
+	})
+}
+
+// checkPIDPresentation checks that only the requested claims were disclosed, and that the key binding JWT is bound
+// to the request.
+func checkPIDPresentation(t *testing.T, presentation string, pid *verifiable.Credential) {
+	t.Helper()
+
+	parts := strings.Split(presentation, sdjwtcommon.CombinedFormatSeparator)
+	require.Greater(t, len(parts), 2)
+	require.Equal(t, strings.Split(pid.JWTEnvelope.JWT, sdjwtcommon.CombinedFormatSeparator)[0], parts[0])
+
+	var disclosedNames []string
+
+	for _, encoded := range parts[1 : len(parts)-1] {
+		disclosure, err := decodeDisclosure(encoded)
+		require.NoError(t, err)
+
+		disclosedNames = append(disclosedNames, disclosure.name)
+	}
+
+	require.ElementsMatch(t, []string{"given_name", "country"}, disclosedNames)
+
+	keyBindingJWT := parts[len(parts)-1]
+
+	token, _, err := jwt.Parse(keyBindingJWT)
+	require.NoError(t, err)
+
+	typ, _ := token.Headers.Type()
+	require.Equal(t, keyBindingJWTType, typ)
+
+	expectedSDHash, err := sdjwtcommon.GetHash(crypto.SHA256, strings.TrimSuffix(presentation, keyBindingJWT))
+	require.NoError(t, err)
+
+	require.Equal(t, testNonce, token.Payload["nonce"])
+	require.Equal(t, testClientID, token.Payload["aud"])
+	require.Equal(t, expectedSDHash, token.Payload["sd_hash"])
+}
+
+// createPIDCredential creates an SD-JWT VC that's bound to the given cnf claim, or to a new JWK if cnf is nil.
+func createPIDCredential(t *testing.T, cnf map[string]interface{}) *verifiable.Credential {
+	t.Helper()
+
+	opts := []issuer.NewOpt{issuer.WithNonSelectivelyDisclosableClaims([]string{"vct", "cnf", "cnf.kid"})}
+
+	claims := pidClaims()
+
+	if cnf != nil {
+		claims["cnf"] = cnf
+	} else {
+		holderPublicKey, _, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		holderJWK, err := jwkkid.BuildJWK(holderPublicKey, kms.ED25519Type)
+		require.NoError(t, err)
+
+		opts = append(opts, issuer.WithHolderPublicKey(holderJWK))
+	}
+
+	return issueSDJWTVC(t, claims, opts...)
+}
+
+func createUnboundPIDCredential(t *testing.T) *verifiable.Credential {
+	t.Helper()
+
+	return issueSDJWTVC(t, pidClaims(), issuer.WithNonSelectivelyDisclosableClaims([]string{"vct"}))
+}
+
+func pidClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"vct":         pidVct,
+		"given_name":  "Erika",
+		"family_name": "Mustermann",
+		"address":     map[string]interface{}{"country": "DE", "locality": "Berlin"},
+	}
+}
+
+func issueSDJWTVC(t *testing.T, claims map[string]interface{}, opts ...issuer.NewOpt) *verifiable.Credential {
+	t.Helper()
+
+	_, issuerPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	opts = append(opts, issuer.WithSDJWTVersion(sdjwtcommon.SDJWTVersionV5), issuer.WithStructuredClaims(true))
+
+	sdJWT, err := issuer.New("https://issuer.example.com", claims, nil, &ed25519Signer{privateKey: issuerPrivateKey},
+		opts...)
+	require.NoError(t, err)
+
+	serialized, err := sdJWT.Serialize(false)
+	require.NoError(t, err)
+
+	credential, err := sdjwtvc.Parse(serialized, sdjwtvc.WithDisabledProofCheck())
+	require.NoError(t, err)
+
+	return credential
+}
+
+func parseJSONLDCredential(t *testing.T, documentLoader ld.DocumentLoader) *verifiable.Credential {
+	t.Helper()
+
+	var rawCredentials []json.RawMessage
+
+	require.NoError(t, json.Unmarshal(credentialsJSONLD, &rawCredentials))
+
+	credential, err := verifiable.ParseCredential(rawCredentials[0], verifiable.WithDisabledProofCheck(),
+		verifiable.WithJSONLDDocumentLoader(documentLoader))
+	require.NoError(t, err)
+
+	return credential
+}
+
+func createDisclosure(t *testing.T, name string, value interface{}) (string, string) {
+	t.Helper()
+
+	elements := []interface{}{"salt", name, value}
+	if name == "" {
+		elements = []interface{}{"salt", value}
+	}
+
+	disclosureBytes, err := json.Marshal(elements)
+	require.NoError(t, err)
+
+	disclosure := base64.RawURLEncoding.EncodeToString(disclosureBytes)
+
+	digest, err := sdjwtcommon.GetHash(crypto.SHA256, disclosure)
+	require.NoError(t, err)
+
+	return disclosure, digest
+}
+
+func createUnsecuredRequestObject(t *testing.T, reqObject *requestObject) string {
+	t.Helper()
+
+	reqObject.ClientIDScheme = redirectURIScheme
+	reqObject.ResponseURI = testClientID
+
+	token, err := jwt.NewUnsecured(reqObject)
+	require.NoError(t, err)
+
+	reqObjectJWT, err := token.Serialize(false)
+	require.NoError(t, err)
+
+	return reqObjectJWT
+}
+
+type ed25519Signer struct {
+	privateKey ed25519.PrivateKey
+}
+
+func (s *ed25519Signer) Sign(data []byte) ([]byte, error) {
+	return ed25519.Sign(s.privateKey, data), nil
+}
+
+func (s *ed25519Signer) Headers() jose.Headers {
+	return jose.Headers{jose.HeaderAlgorithm: "EdDSA", jose.HeaderType: "dc+sd-jwt"}
+}
@@ -12,4 +12,6 @@ type VerifierDisplayData struct {
 	Name    string
 	Purpose string
 	LogoURI string
+	// The subject of the X.509 certificate that the request object was signed with, if any.
+	CertificateSubject string
 }
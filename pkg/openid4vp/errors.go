@@ -24,6 +24,7 @@ const (
 	MSEntraNotFoundError                        = "MS_ENTRA_NOT_FOUND"
 	MSEntraTokenError                           = "MS_ENTRA_TOKEN_ERROR"     //nolint:gosec,lll //false positive, can't shorten
 	MSEntraTransientError                       = "MS_ENTRA_TRANSIENT_ERROR" //nolint:gosec,lll //false positive, can't shorten
+	PresentationDefinitionFetchFailedError      = "PRESENTATION_DEFINITION_FETCH_FAILED"
 )
 
 // Constants' names and reasons are obvious, so they do not require additional comments.
@@ -43,6 +44,7 @@ const (
 	MSEntraNotFoundErrorCode                        = 11
 	MSEntraTokenErrorCode                           = 12
 	MSEntraTransientErrorCode                       = 13
+	PresentationDefinitionFetchFailedCode           = 14
 )
 
 type errorResponse struct {
@@ -0,0 +1,274 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4vp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/trustbloc/vc-go/jwt"
+
+	"github.com/trustbloc/wallet-sdk/pkg/internal/httprequest"
+)
+
+const (
+	directPostJWTResponseMode = "direct_post.jwt"
+
+	// The content encryption algorithm to use if the verifier only specifies the key management algorithm, as
+	// defined by JARM.
+	defaultResponseEncryptionEnc = jose.A128CBC_HS256
+
+	fetchJWKSEventText = "Fetch verifier JWKS via an HTTP GET request to %s"
+)
+
+// Key management algorithms that can be used for encrypted authorization responses.
+//
+//nolint:gochecknoglobals
+var supportedResponseEncryptionAlgs = []jose.KeyAlgorithm{
+	jose.ECDH_ES, jose.ECDH_ES_A128KW, jose.ECDH_ES_A192KW, jose.ECDH_ES_A256KW, jose.RSA_OAEP_256,
+}
+
+// Content encryption algorithms that can be used for encrypted authorization responses.
+//
+//nolint:gochecknoglobals
+var supportedResponseEncryptionEncs = []jose.ContentEncryption{
+	jose.A128GCM, jose.A192GCM, jose.A256GCM, jose.A128CBC_HS256, jose.A192CBC_HS384, jose.A256CBC_HS512,
+}
+
+// Response parameters whose values are JSON, and so are embedded as JSON (rather than as strings) in response JWTs.
+//
+//nolint:gochecknoglobals
+var jsonResponseParameters = []string{"vp_token", "presentation_submission"}
+
+// createResponseJWT encodes the authorization response parameters as a JWT for the direct_post.jwt response mode.
+// The JWT is signed if the verifier asked for authorization_signed_response_alg, and then encrypted if it asked for
+// authorization_encrypted_response_alg. At least one of them is required.
+func (o *Interaction) createResponseJWT(
+	ctx context.Context,
+	data url.Values,
+	response *authorizedResponse,
+) (string, error) {
+	metadata := &o.requestObject.ClientMetadata
+
+	if metadata.AuthorizationSignedResponseAlg == "" && metadata.AuthorizationEncryptedResponseAlg == "" {
+		return "", fmt.Errorf("the %s response mode requires authorization_signed_response_alg or "+
+			"authorization_encrypted_response_alg in client_metadata", directPostJWTResponseMode)
+	}
+
+	claims := responseClaims(data)
+
+	var (
+		responseJWT []byte
+		err         error
+	)
+
+	if metadata.AuthorizationSignedResponseAlg != "" {
+		signedJWT, e := o.signResponse(claims, response)
+		if e != nil {
+			return "", e
+		}
+
+		responseJWT = []byte(signedJWT)
+	} else {
+		responseJWT, err = json.Marshal(claims)
+		if err != nil {
+			return "", fmt.Errorf("encode response claims: %w", err)
+		}
+	}
+
+	if metadata.AuthorizationEncryptedResponseAlg == "" {
+		return string(responseJWT), nil
+	}
+
+	return o.encryptResponse(ctx, responseJWT, metadata.AuthorizationSignedResponseAlg != "")
+}
+
+// signedResponseRequested indicates whether the verifier asked for a signed response, which is only done in the
+// direct_post.jwt response mode.
+func signedResponseRequested(requestObject *requestObject) bool {
+	return requestObject.ResponseMode == directPostJWTResponseMode &&
+		requestObject.ClientMetadata.AuthorizationSignedResponseAlg != ""
+}
+
+// responseClaims converts the form-encoded response parameters into JWT claims.
+func responseClaims(data url.Values) map[string]interface{} {
+	claims := map[string]interface{}{}
+
+	for name := range data {
+		value := data.Get(name)
+
+		if slices.Contains(jsonResponseParameters, name) && json.Valid([]byte(value)) {
+			claims[name] = json.RawMessage(value)
+		} else {
+			claims[name] = value
+		}
+	}
+
+	return claims
+}
+
+// signResponse signs the response claims using the holder's key. As required by JARM, the signed response identifies
+// its issuer and audience, and expires.
+func (o *Interaction) signResponse(claims map[string]interface{}, response *authorizedResponse) (string, error) {
+	if response.Signer == nil {
+		return "", errors.New("the verifier requires a signed response, but none of the presented credentials is " +
+			"bound to a DID")
+	}
+
+	headers, err := response.Signer.CreateJWTHeaders(jwt.SignParameters{})
+	if err != nil {
+		return "", fmt.Errorf("create response JWT headers: %w", err)
+	}
+
+	alg, _ := headers.Algorithm()
+	if requestedAlg := o.requestObject.ClientMetadata.AuthorizationSignedResponseAlg; alg != requestedAlg {
+		return "", fmt.Errorf("the verifier requires responses signed using %s, but the holder's key uses %s",
+			requestedAlg, alg)
+	}
+
+	claims["iss"] = response.SigningDID
+	claims["aud"] = o.requestObject.ClientID
+	claims["iat"] = time.Now().Unix()
+	claims["exp"] = time.Now().Unix() + tokenLiveTimeSec
+
+	signedJWT, err := signToken(claims, response.Signer)
+	if err != nil {
+		return "", fmt.Errorf("sign response: %w", err)
+	}
+
+	return signedJWT, nil
+}
+
+// encryptResponse encrypts the response to one of the verifier's keys, using the algorithms that the verifier asked
+// for.
+func (o *Interaction) encryptResponse(ctx context.Context, payload []byte, signed bool) (string, error) {
+	metadata := &o.requestObject.ClientMetadata
+
+	alg := jose.KeyAlgorithm(metadata.AuthorizationEncryptedResponseAlg)
+	if !slices.Contains(supportedResponseEncryptionAlgs, alg) {
+		return "", fmt.Errorf("unsupported authorization_encrypted_response_alg: %s", alg)
+	}
+
+	enc := defaultResponseEncryptionEnc
+	if metadata.AuthorizationEncryptedResponseEnc != "" {
+		enc = jose.ContentEncryption(metadata.AuthorizationEncryptedResponseEnc)
+	}
+
+	if !slices.Contains(supportedResponseEncryptionEncs, enc) {
+		return "", fmt.Errorf("unsupported authorization_encrypted_response_enc: %s", enc)
+	}
+
+	keys, err := o.verifierKeys(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := selectEncryptionKey(keys, alg)
+	if err != nil {
+		return "", err
+	}
+
+	encrypterOpts := &jose.EncrypterOptions{}
+	if signed {
+		encrypterOpts = encrypterOpts.WithContentType("JWT")
+	}
+
+	encrypter, err := jose.NewEncrypter(enc, jose.Recipient{Algorithm: alg, Key: key.Key, KeyID: key.KeyID},
+		encrypterOpts)
+	if err != nil {
+		return "", fmt.Errorf("create response encrypter: %w", err)
+	}
+
+	jwe, err := encrypter.Encrypt(payload)
+	if err != nil {
+		return "", fmt.Errorf("encrypt response: %w", err)
+	}
+
+	return jwe.CompactSerialize()
+}
+
+// verifierKeys returns the keys from the verifier's client metadata, which are either given by value (jwks) or by
+// reference (jwks_uri). Keys that can't be parsed are skipped, since they can't be used anyway.
+func (o *Interaction) verifierKeys(ctx context.Context) ([]jose.JSONWebKey, error) {
+	metadata := &o.requestObject.ClientMetadata
+
+	rawJWKS := []byte(metadata.JWKS)
+
+	if len(rawJWKS) == 0 {
+		if metadata.JWKSURI == "" {
+			return nil, errors.New("client_metadata must contain jwks or jwks_uri to encrypt the response")
+		}
+
+		var err error
+
+		rawJWKS, err = httprequest.New(o.httpClient, o.metricsLogger).DoContext(ctx, http.MethodGet,
+			metadata.JWKSURI, "", nil, nil, fmt.Sprintf(fetchJWKSEventText, metadata.JWKSURI),
+			presentCredentialEventText, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("fetch verifier JWKS: %w", err)
+		}
+	}
+
+	var jwks struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+
+	err := json.Unmarshal(rawJWKS, &jwks)
+	if err != nil {
+		return nil, fmt.Errorf("decode verifier JWKS: %w", err)
+	}
+
+	var keys []jose.JSONWebKey
+
+	for _, rawKey := range jwks.Keys {
+		var key jose.JSONWebKey
+
+		if key.UnmarshalJSON(rawKey) == nil {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+// selectEncryptionKey returns the first of the verifier's keys that can be used for encryption with the given
+// algorithm.
+func selectEncryptionKey(keys []jose.JSONWebKey, alg jose.KeyAlgorithm) (*jose.JSONWebKey, error) {
+	for i := range keys {
+		key := &keys[i]
+
+		if key.Use != "" && key.Use != "enc" {
+			continue
+		}
+
+		if key.Algorithm != "" && key.Algorithm != string(alg) {
+			continue
+		}
+
+		switch key.Key.(type) {
+		case *ecdsa.PublicKey:
+			if alg != jose.RSA_OAEP_256 {
+				return key, nil
+			}
+		case *rsa.PublicKey:
+			if alg == jose.RSA_OAEP_256 {
+				return key, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("verifier has no key that can be used to encrypt the response with %s", alg)
+}
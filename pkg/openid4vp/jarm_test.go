@@ -0,0 +1,254 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4vp //nolint: testpackage
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/vc-go/verifiable"
+
+	"github.com/trustbloc/wallet-sdk/internal/testutil"
+	"github.com/trustbloc/wallet-sdk/pkg/activitylogger/noop"
+	"github.com/trustbloc/wallet-sdk/pkg/dcql"
+	"github.com/trustbloc/wallet-sdk/pkg/internal/mock"
+	noopmetricslogger "github.com/trustbloc/wallet-sdk/pkg/metricslogger/noop"
+)
+
+func TestOpenID4VP_DirectPostJWT(t *testing.T) {
+	lddl := testutil.DocumentLoader(t)
+
+	query, err := dcql.Parse([]byte(testDCQLQuery))
+	require.NoError(t, err)
+
+	mockDoc := mockResolution(t, mockDID, false)
+
+	verifierKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwks := func(keys ...jose.JSONWebKey) json.RawMessage {
+		jwksBytes, e := json.Marshal(&jose.JSONWebKeySet{Keys: keys})
+		require.NoError(t, e)
+
+		return jwksBytes
+	}
+
+	encryptionJWKS := jwks(
+		jose.JSONWebKey{Key: &verifierKey.PublicKey, KeyID: "sig-key", Use: "sig"},
+		jose.JSONWebKey{Key: &rsaKey.PublicKey, KeyID: "rsa-key", Use: "enc"},
+		jose.JSONWebKey{Key: &verifierKey.PublicKey, KeyID: "enc-key", Use: "enc"},
+	)
+
+	newInteraction := func(metadata clientMetadata, httpClient httpClient) *Interaction {
+		return &Interaction{
+			requestObject: &requestObject{
+				ClientID:       testClientID,
+				Nonce:          testNonce,
+				State:          "state",
+				ResponseType:   "vp_token",
+				ResponseMode:   directPostJWTResponseMode,
+				ResponseURI:    testVerifierURI,
+				ClientMetadata: metadata,
+				DCQLQuery:      query,
+			},
+			httpClient:     httpClient,
+			activityLogger: noop.NewActivityLogger(),
+			metricsLogger:  noopmetricslogger.NewMetricsLogger(),
+			didResolver:    &didResolverMock{ResolveValue: mockDoc},
+			crypto:         &cryptoMock{SignVal: []byte(testSignature)},
+			documentLoader: lddl,
+		}
+	}
+
+	present := func(interaction *Interaction, pid *verifiable.Credential) error {
+		return interaction.PresentDCQLCredentials(map[string][]*verifiable.Credential{"pid": {pid}}, CustomClaims{})
+	}
+
+	decryptResponse := func(t *testing.T, body []byte) *jose.JSONWebEncryption {
+		t.Helper()
+
+		data, e := url.ParseQuery(string(body))
+		require.NoError(t, e)
+		require.Len(t, data, 1)
+
+		jwe, e := jose.ParseEncrypted(data.Get("response"))
+		require.NoError(t, e)
+
+		return jwe
+	}
+
+	t.Run("Encrypted response", func(t *testing.T) {
+		pid := createPIDCredential(t, nil)
+		httpClient := &mock.HTTPClientMock{StatusCode: 200}
+
+		err = present(newInteraction(clientMetadata{
+			JWKS:                              encryptionJWKS,
+			AuthorizationEncryptedResponseAlg: "ECDH-ES",
+			AuthorizationEncryptedResponseEnc: "A256GCM",
+		}, httpClient), pid)
+		require.NoError(t, err)
+
+		jwe := decryptResponse(t, httpClient.SentBody)
+		require.Equal(t, "enc-key", jwe.Header.KeyID)
+		require.Equal(t, "ECDH-ES", jwe.Header.Algorithm)
+		require.Equal(t, "A256GCM", jwe.Header.ExtraHeaders["enc"])
+
+		payload, err := jwe.Decrypt(verifierKey)
+		require.NoError(t, err)
+
+		var response struct {
+			VPToken map[string][]string `json:"vp_token"`
+			State   string              `json:"state"`
+		}
+
+		require.NoError(t, json.Unmarshal(payload, &response))
+		require.Equal(t, "state", response.State)
+		require.Len(t, response.VPToken["pid"], 1)
+
+		checkPIDPresentation(t, response.VPToken["pid"][0], pid)
+	})
+
+	t.Run("Signed and encrypted response, with keys from jwks_uri", func(t *testing.T) {
+		var sentBody []byte
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				_, e := w.Write(jwks(jose.JSONWebKey{Key: &rsaKey.PublicKey, KeyID: "rsa-key"}))
+				require.NoError(t, e)
+
+				return
+			}
+
+			var e error
+
+			sentBody, e = io.ReadAll(r.Body)
+			require.NoError(t, e)
+		}))
+		defer server.Close()
+
+		interaction := newInteraction(clientMetadata{
+			JWKSURI:                           server.URL + "/jwks",
+			AuthorizationSignedResponseAlg:    "EdDSA",
+			AuthorizationEncryptedResponseAlg: "RSA-OAEP-256",
+		}, http.DefaultClient)
+		interaction.requestObject.ResponseURI = server.URL + "/response"
+
+		err = present(interaction, createPIDCredential(t, map[string]interface{}{"kid": mockDID + mockVMID}))
+		require.NoError(t, err)
+
+		jwe := decryptResponse(t, sentBody)
+		require.Equal(t, "rsa-key", jwe.Header.KeyID)
+		require.Equal(t, "A128CBC-HS256", jwe.Header.ExtraHeaders["enc"])
+		require.Equal(t, "JWT", jwe.Header.ExtraHeaders["cty"])
+
+		payload, err := jwe.Decrypt(rsaKey)
+		require.NoError(t, err)
+
+		jws, err := jose.ParseSigned(string(payload))
+		require.NoError(t, err)
+		require.Equal(t, "EdDSA", jws.Signatures[0].Header.Algorithm)
+
+		var claims map[string]interface{}
+
+		require.NoError(t, json.Unmarshal(jws.UnsafePayloadWithoutVerification(), &claims))
+		require.Equal(t, mockDID, claims["iss"])
+		require.Equal(t, testClientID, claims["aud"])
+		require.Equal(t, "state", claims["state"])
+		require.NotEmpty(t, claims["exp"])
+		require.Contains(t, claims["vp_token"], "pid")
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		tests := []struct {
+			name          string
+			metadata      clientMetadata
+			pid           *verifiable.Credential
+			expectedError string
+		}{
+			{
+				name:          "Neither signing nor encryption requested",
+				expectedError: "requires authorization_signed_response_alg or authorization_encrypted_response_alg",
+			},
+			{
+				name: "No keys",
+				metadata: clientMetadata{
+					AuthorizationEncryptedResponseAlg: "ECDH-ES",
+				},
+				expectedError: "client_metadata must contain jwks or jwks_uri to encrypt the response",
+			},
+			{
+				name: "No key for the algorithm",
+				metadata: clientMetadata{
+					JWKS:                              jwks(jose.JSONWebKey{Key: &rsaKey.PublicKey, Use: "enc"}),
+					AuthorizationEncryptedResponseAlg: "ECDH-ES+A128KW",
+				},
+				expectedError: "verifier has no key that can be used to encrypt the response with ECDH-ES+A128KW",
+			},
+			{
+				name: "Unsupported alg",
+				metadata: clientMetadata{
+					JWKS:                              encryptionJWKS,
+					AuthorizationEncryptedResponseAlg: "RSA1_5",
+				},
+				expectedError: "unsupported authorization_encrypted_response_alg: RSA1_5",
+			},
+			{
+				name: "Unsupported enc",
+				metadata: clientMetadata{
+					JWKS:                              encryptionJWKS,
+					AuthorizationEncryptedResponseAlg: "ECDH-ES",
+					AuthorizationEncryptedResponseEnc: "A256KW",
+				},
+				expectedError: "unsupported authorization_encrypted_response_enc: A256KW",
+			},
+			{
+				name: "Signed response, but no credential is bound to a DID",
+				metadata: clientMetadata{
+					AuthorizationSignedResponseAlg: "EdDSA",
+				},
+				expectedError: "the verifier requires a signed response, but none of the presented credentials",
+			},
+			{
+				name: "Signing alg doesn't match the holder's key",
+				metadata: clientMetadata{
+					AuthorizationSignedResponseAlg: "ES256",
+				},
+				pid:           createPIDCredential(t, map[string]interface{}{"kid": mockDID + mockVMID}),
+				expectedError: "the verifier requires responses signed using ES256, but the holder's key uses EdDSA",
+			},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				pid := test.pid
+				if pid == nil {
+					pid = createPIDCredential(t, nil)
+				}
+
+				httpClient := &mock.HTTPClientMock{StatusCode: 200}
+
+				err := present(newInteraction(test.metadata, httpClient), pid)
+				require.ErrorContains(t, err, CreateAuthorizedResponseFailedError)
+				require.ErrorContains(t, err, test.expectedError)
+				require.Nil(t, httpClient.SentBody)
+			})
+		}
+	})
+}
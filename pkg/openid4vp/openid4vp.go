@@ -9,7 +9,9 @@ package openid4vp
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/gob"
 	"encoding/json"
@@ -45,10 +47,13 @@ const (
 
 	activityLogOperation = "oidc-presentation"
 
-	newInteractionEventText         = "Instantiating OpenID4VP interaction object"
-	fetchRequestObjectEventText     = "Fetch request object via an HTTP GET request to %s"
-	presentCredentialEventText      = "Present credential" //nolint:gosec // false positive
-	sendAuthorizedResponseEventText = "Send authorized response via an HTTP POST request to %s"
+	newInteractionEventText     = "Instantiating OpenID4VP interaction object"
+	fetchRequestObjectEventText = "Fetch request object via an HTTP GET request to %s"
+	postWalletMetadataEventText = "Fetch request object via an HTTP POST request to %s"
+
+	fetchPresentationDefinitionEventText = "Fetch presentation definition via an HTTP GET request to %s"
+	presentCredentialEventText           = "Present credential" //nolint:gosec // false positive
+	sendAuthorizedResponseEventText      = "Send authorized response via an HTTP POST request to %s"
 )
 
 type httpClient interface {
@@ -60,6 +65,15 @@ type VerifierTrustInfo struct {
 	DID         string
 	Domain      string
 	DomainValid bool
+	// The subject of the X.509 certificate that the request object was signed with. Only set if the verifier uses the
+	// x509_san_dns or x509_san_uri client_id scheme, in which case DID is empty.
+	CertificateSubject string
+	// The following are only set if the verifier uses the verifier_attestation client_id scheme, in which case DID is
+	// empty. They identify the issuer of the Verifier Attestation JWT, and the verifier's name and the scope it's
+	// permitted to request, as attested by that issuer.
+	AttestationIssuer string
+	AttestedName      string
+	AttestedScope     []string
 }
 
 // Interaction is used to help with OpenID4VP operations.
@@ -78,6 +92,10 @@ type authorizedResponse struct {
 	VPToken                string
 	PresentationSubmission string
 	State                  string
+	// SigningDID and Signer identify the holder key used to sign the response for the direct_post.jwt response mode.
+	// They're empty if none of the presented credentials is bound to a DID.
+	SigningDID string
+	Signer     api.JWTSigner
 }
 
 // NewInteraction creates a new OpenID4VP interaction object.
@@ -90,46 +108,31 @@ func NewInteraction(
 	documentLoader ld.DocumentLoader,
 	opts ...Opt,
 ) (*Interaction, error) {
-	client, activityLogger, metricsLogger := processOpts(opts)
-
-	var (
-		authorizationRequestClientID string
-		rawRequestObject             string
-	)
-
-	if strings.HasPrefix(authorizationRequest, "openid-vc://") ||
-		strings.HasPrefix(authorizationRequest, "openid4vp://") {
-		var (
-			authorizationRequestURL *url.URL
-			err                     error
-		)
-
-		authorizationRequestURL, err = url.Parse(authorizationRequest)
-		if err != nil {
-			return nil, walleterror.NewValidationError(
-				ErrorModule,
-				InvalidAuthorizationRequestErrorCode,
-				InvalidAuthorizationRequestError,
-				err)
-		}
+	return NewInteractionContext(context.Background(), authorizationRequest, signatureVerifier, didResolver, crypto,
+		documentLoader, opts...)
+}
 
-		authorizationRequestClientID = authorizationRequestURL.Query().Get("client_id")
+// NewInteractionContext is the same as NewInteraction, but uses the given context for fetching the request object.
+func NewInteractionContext(
+	ctx context.Context,
+	authorizationRequest string,
+	signatureVerifier jwt.ProofChecker,
+	didResolver api.DIDResolver,
+	crypto api.Crypto,
+	documentLoader ld.DocumentLoader,
+	opts ...Opt,
+) (*Interaction, error) {
+	client, activityLogger, metricsLogger, trustAnchors, verifierAttestationIssuers := processOpts(opts)
 
-		rawRequestObject, err = fetchRequestObject(authorizationRequestURL, client, metricsLogger)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		rawRequestObject = authorizationRequest
+	verifier := &requestObjectVerifier{
+		signatureVerifier:          signatureVerifier,
+		trustAnchors:               trustAnchors,
+		verifierAttestationIssuers: verifierAttestationIssuers,
 	}
 
-	reqObject, err := parseRequestObject(authorizationRequestClientID, rawRequestObject, signatureVerifier)
+	reqObject, err := loadRequestObject(ctx, authorizationRequest, verifier, client, metricsLogger)
 	if err != nil {
-		return nil, walleterror.NewValidationError(
-			ErrorModule,
-			InvalidAuthorizationRequestErrorCode,
-			InvalidAuthorizationRequestError,
-			fmt.Errorf("verify request object: %w", err))
+		return nil, err
 	}
 
 	return &Interaction{
@@ -163,18 +166,41 @@ func (o *Interaction) CustomScope() []string {
 
 // VerifierDisplayData returns display information about verifier.
 func (o *Interaction) VerifierDisplayData() *VerifierDisplayData {
-	return &VerifierDisplayData{
+	displayData := &VerifierDisplayData{
 		DID:     o.requestObject.ClientID,
 		Name:    o.requestObject.ClientMetadata.ClientName,
 		Purpose: o.requestObject.ClientMetadata.ClientPurpose,
 		LogoURI: o.requestObject.ClientMetadata.ClientLogoURI,
 	}
+
+	if certificate := o.requestObject.signingCertificate; certificate != nil {
+		displayData.CertificateSubject = certificate.Subject.String()
+
+		if displayData.Name == "" {
+			displayData.Name = certificateName(certificate)
+		}
+	}
+
+	return displayData
 }
 
 // TrustInfo return verifier trust info.
 func (o *Interaction) TrustInfo() (*VerifierTrustInfo, error) {
+	return o.TrustInfoContext(context.Background())
+}
+
+// TrustInfoContext is the same as TrustInfo, but uses the given context for validating the verifier's linked domains.
+func (o *Interaction) TrustInfoContext(ctx context.Context) (*VerifierTrustInfo, error) {
 	trustInfo := &VerifierTrustInfo{}
 
+	if o.requestObject.signingCertificate != nil {
+		return certificateTrustInfo(o.requestObject)
+	}
+
+	if o.requestObject.verifierAttestation != nil {
+		return attestationTrustInfo(o.requestObject)
+	}
+
 	if o.requestObject.ClientIDScheme == redirectURIScheme {
 		verifierURI, err := url.Parse(o.requestObject.ResponseURI)
 		if err != nil {
@@ -188,7 +214,8 @@ func (o *Interaction) TrustInfo() (*VerifierTrustInfo, error) {
 
 		verifierDID := strings.Split(verifier, "#")[0]
 
-		valid, linkedDomain, err := wellknown.ValidateLinkedDomains(verifierDID, o.didResolver, o.httpClient)
+		valid, linkedDomain, err := wellknown.ValidateLinkedDomainsContext(ctx, verifierDID, o.didResolver,
+			o.httpClient)
 		if err != nil {
 			return nil, err
 		}
@@ -246,6 +273,17 @@ func (o *Interaction) PresentCredential(
 	credentials []*verifiable.Credential,
 	customClaims CustomClaims,
 	opts ...PresentOpt,
+) error {
+	return o.PresentCredentialContext(context.Background(), credentials, customClaims, opts...)
+}
+
+// PresentCredentialContext is the same as PresentCredential, but sends the authorized response using the given
+// context.
+func (o *Interaction) PresentCredentialContext(
+	ctx context.Context,
+	credentials []*verifiable.Credential,
+	customClaims CustomClaims,
+	opts ...PresentOpt,
 ) error {
 	resolveOpts := &presentOpts{}
 
@@ -256,6 +294,7 @@ func (o *Interaction) PresentCredential(
 	}
 
 	return o.presentCredentials(
+		ctx,
 		credentials,
 		customClaims,
 		resolveOpts,
@@ -265,7 +304,16 @@ func (o *Interaction) PresentCredential(
 // PresentCredentialUnsafe presents a single credential to redirect uri from request object.
 // This skips presentation definition constraint validation.
 func (o *Interaction) PresentCredentialUnsafe(credential *verifiable.Credential, customClaims CustomClaims) error {
+	return o.PresentCredentialUnsafeContext(context.Background(), credential, customClaims)
+}
+
+// PresentCredentialUnsafeContext is the same as PresentCredentialUnsafe, but sends the authorized response using the
+// given context.
+func (o *Interaction) PresentCredentialUnsafeContext(ctx context.Context, credential *verifiable.Credential,
+	customClaims CustomClaims,
+) error {
 	return o.presentCredentials(
+		ctx,
 		[]*verifiable.Credential{credential},
 		customClaims,
 		&presentOpts{
@@ -275,11 +323,16 @@ func (o *Interaction) PresentCredentialUnsafe(credential *verifiable.Credential,
 }
 
 // PresentCredential presents credentials to redirect uri from request object.
-func (o *Interaction) presentCredentials( //nolint: funlen
+func (o *Interaction) presentCredentials(
+	ctx context.Context,
 	credentials []*verifiable.Credential,
 	customClaims CustomClaims,
 	opts *presentOpts,
 ) error {
+	if o.requestObject.DCQLQuery != nil {
+		return errDCQLRequest()
+	}
+
 	timeStartPresentCredential := time.Now()
 
 	response, err := createAuthorizedResponse(
@@ -303,6 +356,18 @@ func (o *Interaction) presentCredentials( //nolint: funlen
 	data.Set("presentation_submission", response.PresentationSubmission)
 	data.Set("vp_token", response.VPToken)
 
+	return o.sendResponse(ctx, data, response, opts, timeStartPresentCredential)
+}
+
+// sendResponse adds the parts of the authorized response that don't depend on how the credentials were requested,
+// sends it to the verifier and logs the presentation.
+func (o *Interaction) sendResponse(
+	ctx context.Context,
+	data url.Values,
+	response *authorizedResponse,
+	opts *presentOpts,
+	timeStartPresentCredential time.Time,
+) error {
 	if response.IDTokenJWS != "" {
 		data.Set("id_token", response.IDTokenJWS)
 	}
@@ -320,7 +385,20 @@ func (o *Interaction) presentCredentials( //nolint: funlen
 		data.Add("interaction_details", base64.StdEncoding.EncodeToString(interactionDetailsBytes))
 	}
 
-	err = o.sendAuthorizedResponse(data.Encode())
+	if o.requestObject.ResponseMode == directPostJWTResponseMode {
+		responseJWT, e := o.createResponseJWT(ctx, data, response)
+		if e != nil {
+			return walleterror.NewExecutionError(
+				ErrorModule,
+				CreateAuthorizedResponseFailedCode,
+				CreateAuthorizedResponseFailedError,
+				fmt.Errorf("create %s response failed: %w", directPostJWTResponseMode, e))
+		}
+
+		data = url.Values{"response": {responseJWT}}
+	}
+
+	err := o.sendAuthorizedResponse(ctx, data.Encode())
 	if err != nil {
 		return fmt.Errorf("send authorized response failed: %w", err)
 	}
@@ -346,6 +424,10 @@ func (o *Interaction) presentCredentials( //nolint: funlen
 }
 
 func (o *Interaction) PresentedClaims(credential *verifiable.Credential) (interface{}, error) {
+	if o.requestObject.DCQLQuery != nil {
+		return nil, errDCQLRequest()
+	}
+
 	pd := o.requestObject.PresentationDefinition
 
 	bbsProofCreator := &verifiable.BBSProofCreator{
@@ -376,51 +458,32 @@ func (o *Interaction) PresentedClaims(credential *verifiable.Credential) (interf
 	return copyJSONKeysOnly(vcContent.Subject[0].CustomFields), nil
 }
 
-func (o *Interaction) sendAuthorizedResponse(responseBody string) error {
-	_, err := httprequest.New(o.httpClient, o.metricsLogger).Do(http.MethodPost,
-		o.requestObject.ResponseURI, "application/x-www-form-urlencoded",
+func (o *Interaction) sendAuthorizedResponse(ctx context.Context, responseBody string) error {
+	_, err := httprequest.New(o.httpClient, o.metricsLogger).DoContext(ctx, http.MethodPost,
+		o.requestObject.ResponseURI, "application/x-www-form-urlencoded", nil,
 		bytes.NewBufferString(responseBody),
 		fmt.Sprintf(sendAuthorizedResponseEventText, o.requestObject.ResponseURI),
-		presentCredentialEventText, processAuthorizationErrorResponse)
+		presentCredentialEventText, nil, processAuthorizationErrorResponse)
 
 	return err
 }
 
-func fetchRequestObject(authorizationRequestURL *url.URL, client httpClient,
-	metricsLogger api.MetricsLogger,
-) (string, error) {
-	if !authorizationRequestURL.Query().Has("request_uri") {
-		return "", walleterror.NewValidationError(
-			ErrorModule,
-			InvalidAuthorizationRequestErrorCode,
-			InvalidAuthorizationRequestError,
-			errors.New("request_uri missing from authorization request URI"))
-	}
-
-	requestURI := authorizationRequestURL.Query().Get("request_uri")
-
-	respBytes, err := httprequest.New(client, metricsLogger).Do(http.MethodGet, requestURI, "", nil,
-		fmt.Sprintf(fetchRequestObjectEventText, requestURI), newInteractionEventText, nil)
-	if err != nil {
-		return "", walleterror.NewExecutionError(
-			ErrorModule,
-			RequestObjectFetchFailedCode,
-			RequestObjectFetchFailedError,
-			fmt.Errorf("fetch request object: %w", err))
-	}
-
-	return string(respBytes), nil
+// requestObjectVerifier holds what's needed to verify request objects under each of the supported client_id schemes.
+type requestObjectVerifier struct {
+	signatureVerifier          jwt.ProofChecker
+	trustAnchors               []*x509.Certificate
+	verifierAttestationIssuers []string
 }
 
 //nolint:gocyclo
 func parseRequestObject(
 	authorizationRequestClientID string,
 	rawRequestObject string,
-	signatureVerifier jwt.ProofChecker,
+	verifier *requestObjectVerifier,
 ) (*requestObject, error) {
 	reqObject := &requestObject{}
 
-	_, _, err := jwt.Parse(rawRequestObject,
+	token, _, err := jwt.Parse(rawRequestObject,
 		jwt.DecodeClaimsTo(reqObject),
 		jwt.WithIgnoreClaimsMapDecoding(true),
 	)
@@ -434,10 +497,22 @@ func parseRequestObject(
 			return nil, errors.New("iss claim in request object is required")
 		}
 
-		err = jwt.CheckProof(rawRequestObject, signatureVerifier, &reqObject.Issuer, nil)
+		err = jwt.CheckProof(rawRequestObject, verifier.signatureVerifier, &reqObject.Issuer, nil)
 		if err != nil {
 			return nil, fmt.Errorf("check proof: %w", err)
 		}
+	case x509SANDNSScheme, x509SANURIScheme:
+		reqObject.signingCertificate, err = verifyX509RequestObject(authorizationRequestClientID, rawRequestObject,
+			reqObject, verifier.trustAnchors)
+		if err != nil {
+			return nil, err
+		}
+	case verifierAttestationScheme:
+		reqObject.verifierAttestation, err = verifyAttestationRequestObject(authorizationRequestClientID,
+			rawRequestObject, token.Headers, reqObject, verifier)
+		if err != nil {
+			return nil, err
+		}
 	case redirectURIScheme:
 		if !matchClientIDAndResponseURI(authorizationRequestClientID, reqObject.ResponseURI) {
 			return nil, errors.New("client_id mismatch between authorization request and request object")
@@ -446,6 +521,17 @@ func parseRequestObject(
 		return nil, fmt.Errorf("unsupported client_id_scheme: %s", reqObject.ClientIDScheme)
 	}
 
+	err = finalizeRequestObject(reqObject)
+	if err != nil {
+		return nil, err
+	}
+
+	return reqObject, nil
+}
+
+// finalizeRequestObject fills in the fields that older request objects carry elsewhere, and checks that the request
+// object asks for credentials in only one way.
+func finalizeRequestObject(reqObject *requestObject) error {
 	// temporary solution for backward compatibility
 	if reqObject.PresentationDefinition == nil && reqObject.Claims.VPToken.PresentationDefinition != nil {
 		reqObject.PresentationDefinition = reqObject.Claims.VPToken.PresentationDefinition
@@ -459,11 +545,32 @@ func parseRequestObject(
 		reqObject.ClientMetadata.SubjectSyntaxTypesSupported = reqObject.Registration.SubjectSyntaxTypesSupported
 	}
 
+	if reqObject.PresentationDefinition != nil && reqObject.PresentationDefinitionURI != "" {
+		return errors.New(
+			"request object must not contain both presentation_definition and presentation_definition_uri")
+	}
+
+	if reqObject.DCQLQuery != nil {
+		if reqObject.PresentationDefinition != nil || reqObject.PresentationDefinitionURI != "" {
+			return errors.New("request object must not contain both presentation_definition and dcql_query")
+		}
+
+		err := reqObject.DCQLQuery.Validate()
+		if err != nil {
+			return fmt.Errorf("invalid dcql_query: %w", err)
+		}
+	}
+
 	if reqObject.ResponseURI == "" && reqObject.RedirectURI != "" {
 		reqObject.ResponseURI = reqObject.RedirectURI
 	}
 
-	return reqObject, nil
+	return nil
+}
+
+func errDCQLRequest() error {
+	return walleterror.NewInvalidSDKUsageError(ErrorModule,
+		errors.New("the request contains a DCQL query, so credentials must be presented with PresentDCQLCredentials"))
 }
 
 func matchClientIDAndResponseURI(clientID, responseURI string) bool {
@@ -631,6 +738,8 @@ func createAuthorizedResponseOneCred( //nolint:funlen,gocyclo // Unable to decom
 		VPToken:                vpToken,
 		IDTokenJWS:             idTokenJWS,
 		State:                  requestObject.State,
+		SigningDID:             did,
+		Signer:                 jwtSigner,
 	}, nil
 }
 
@@ -742,17 +851,22 @@ func createAuthorizedResponseMultiCred( //nolint:funlen,gocyclo,gocognit
 		return nil, err
 	}
 
-	var idTokenJWS string
+	idTokenRequested := strings.Contains(requestObject.ResponseType, "id_token")
 
-	//nolint:nestif
-	if strings.Contains(requestObject.ResponseType, "id_token") {
-		var idTokenSigningDID string
+	var signingDID string
 
-		idTokenSigningDID, err = pickRandomElement(mapKeys(signers))
+	// The same holder DID signs the id_token and, for the direct_post.jwt response mode, the response, so one is only
+	// picked if either of them needs to be signed.
+	if idTokenRequested || signedResponseRequested(requestObject) {
+		signingDID, err = pickRandomElement(mapKeys(signers))
 		if err != nil {
 			return nil, err
 		}
+	}
+
+	var idTokenJWS string
 
+	if idTokenRequested {
 		var attestationVP string
 
 		if opts.attestationVC != "" {
@@ -762,8 +876,8 @@ func createAuthorizedResponseMultiCred( //nolint:funlen,gocyclo,gocognit
 			}
 		}
 
-		idTokenJWS, err = createIDToken(requestObject, idTokenSigningDID, customClaims,
-			signers[idTokenSigningDID], attestationVP, presentationSubmission)
+		idTokenJWS, err = createIDToken(requestObject, signingDID, customClaims,
+			signers[signingDID], attestationVP, presentationSubmission)
 		if err != nil {
 			return nil, err
 		}
@@ -779,6 +893,8 @@ func createAuthorizedResponseMultiCred( //nolint:funlen,gocyclo,gocognit
 		VPToken:                string(vpTokenListJSON),
 		PresentationSubmission: string(presentationSubmissionJSON),
 		State:                  requestObject.State,
+		SigningDID:             signingDID,
+		Signer:                 signers[signingDID],
 	}, nil
 }
 
@@ -963,6 +1079,10 @@ func mapKeys(in map[string]api.JWTSigner) []string {
 }
 
 func pickRandomElement(list []string) (string, error) {
+	if len(list) == 0 {
+		return "", errors.New("none of the presented credentials is bound to a DID that can sign the response")
+	}
+
 	idx, err := rand.Int(rand.Reader, big.NewInt(int64(len(list))))
 	if err != nil {
 		return "", err
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package openid4vp //nolint: testpackage
 
 import (
+	"context"
 	"crypto/ed25519"
 	"crypto/rand"
 	_ "embed" //nolint:gci // required for go:embed
@@ -231,7 +232,7 @@ func TestNewInteraction(t *testing.T) {
 			testutil.RequireErrorContains(t, err, "invalid URL escape")
 			require.Nil(t, interaction)
 		})
-		t.Run("URI missing request_uri parameter", func(t *testing.T) {
+		t.Run("URI missing request_uri and client_id parameters", func(t *testing.T) {
 			interaction, err := NewInteraction(
 				"openid-vc://",
 				&jwtSignatureVerifierMock{},
@@ -241,7 +242,7 @@ func TestNewInteraction(t *testing.T) {
 				nil,
 			)
 			testutil.RequireErrorContains(t, err, "INVALID_AUTHORIZATION_REQUEST")
-			testutil.RequireErrorContains(t, err, "request_uri missing from authorization request URI")
+			testutil.RequireErrorContains(t, err, "client_id missing from authorization request URI")
 			require.Nil(t, interaction)
 		})
 	})
@@ -271,6 +272,21 @@ func TestNewInteraction(t *testing.T) {
 			"failed to log event (Event=Fetch request object via an HTTP GET request to https://request-object)")
 		require.Nil(t, interaction)
 	})
+
+	t.Run("Context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		interaction, err := NewInteractionContext(ctx, "openid-vc://?request_uri=https://request-object",
+			&jwtSignatureVerifierMock{},
+			nil,
+			nil,
+			testutil.DocumentLoader(t),
+			WithHTTPClient(&contextHTTPClientMock{}),
+		)
+		require.ErrorContains(t, err, "context canceled")
+		require.Nil(t, interaction)
+	})
 }
 
 func TestOpenID4VP_PresentCredential(t *testing.T) {
@@ -409,6 +425,27 @@ func TestOpenID4VP_PresentCredential(t *testing.T) {
 		require.Equal(t, "test://response", ack.ResponseURI)
 	})
 
+	t.Run("Context is cancelled", func(t *testing.T) {
+		interaction, err := NewInteraction(
+			requestObjectJWT,
+			&jwtSignatureVerifierMock{},
+			&didResolverMock{ResolveValue: mockDoc},
+			&cryptoMock{SignVal: []byte(testSignature)},
+			lddl,
+			WithHTTPClient(&contextHTTPClientMock{}),
+		)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err = interaction.PresentCredentialContext(ctx, credentials, CustomClaims{})
+		require.ErrorContains(t, err, "context canceled")
+
+		err = interaction.PresentCredentialUnsafeContext(ctx, credentials[0], CustomClaims{})
+		require.ErrorContains(t, err, "context canceled")
+	})
+
 	t.Run("Success - Unsafe", func(t *testing.T) {
 		httpClient := &mock.HTTPClientMock{
 			StatusCode: 200,
@@ -1449,6 +1486,20 @@ func TestCopyJSONKeysOnly(t *testing.T) {
 	})
 }
 
+func TestPickRandomElement(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		element, err := pickRandomElement([]string{"did:example:1", "did:example:2"})
+		require.NoError(t, err)
+		require.Contains(t, []string{"did:example:1", "did:example:2"}, element)
+	})
+
+	t.Run("Empty list", func(t *testing.T) {
+		element, err := pickRandomElement(nil)
+		require.EqualError(t, err, "none of the presented credentials is bound to a DID that can sign the response")
+		require.Empty(t, element)
+	})
+}
+
 type jwtSignatureVerifierMock struct {
 	err error
 }
@@ -1457,6 +1508,17 @@ func (s *jwtSignatureVerifierMock) CheckJWTProof(jose.Headers, string, []byte, [
 	return s.err
 }
 
+// contextHTTPClientMock fails requests whose context is done, as an HTTP client does.
+type contextHTTPClientMock struct{}
+
+func (c *contextHTTPClientMock) Do(req *http.Request) (*http.Response, error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, errors.New("unexpected request")
+}
+
 type didResolverMock struct {
 	ResolveValue *did.DocResolution
 	ResolveErr   error
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package openid4vp
 
 import (
+	"crypto/x509"
 	"net/http"
 
 	noopactivitylogger "github.com/trustbloc/wallet-sdk/pkg/activitylogger/noop"
@@ -18,6 +19,9 @@ type opts struct {
 	httpClient     httpClient
 	activityLogger api.ActivityLogger
 	metricsLogger  api.MetricsLogger
+	trustAnchors   []*x509.Certificate
+
+	verifierAttestationIssuers []string
 }
 
 // An Opt is a single option for an OpenID4VP instance.
@@ -49,10 +53,30 @@ func WithMetricsLogger(metricsLogger api.MetricsLogger) Opt {
 	}
 }
 
+// WithTrustAnchors is an option for an OpenID4VP instance that sets the trust anchors that the certificate chains of
+// request objects are verified against. This is needed to accept requests from verifiers that use the x509_san_dns or
+// x509_san_uri client_id schemes. Without any trust anchors, such requests are rejected.
+func WithTrustAnchors(trustAnchors []*x509.Certificate) Opt {
+	return func(opts *opts) {
+		opts.trustAnchors = trustAnchors
+	}
+}
+
+// WithVerifierAttestationIssuers is an option for an OpenID4VP instance that sets the DIDs of the issuers whose
+// Verifier Attestation JWTs are trusted. This is needed to accept requests from verifiers that use the
+// verifier_attestation client_id scheme. Without any attestation issuers, such requests are rejected.
+func WithVerifierAttestationIssuers(issuerDIDs []string) Opt {
+	return func(opts *opts) {
+		opts.verifierAttestationIssuers = issuerDIDs
+	}
+}
+
 func processOpts(options []Opt) (
 	httpClient,
 	api.ActivityLogger,
 	api.MetricsLogger,
+	[]*x509.Certificate,
+	[]string,
 ) {
 	opts := mergeOpts(options)
 
@@ -68,7 +92,7 @@ func processOpts(options []Opt) (
 		opts.metricsLogger = noopmetricslogger.NewMetricsLogger()
 	}
 
-	return opts.httpClient, opts.activityLogger, opts.metricsLogger
+	return opts.httpClient, opts.activityLogger, opts.metricsLogger, opts.trustAnchors, opts.verifierAttestationIssuers
 }
 
 func mergeOpts(options []Opt) *opts {
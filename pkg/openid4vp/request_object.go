@@ -6,30 +6,44 @@ SPDX-License-Identifier: Apache-2.0
 
 package openid4vp
 
-import "github.com/trustbloc/vc-go/presexch"
+import (
+	"crypto/x509"
+	"encoding/json"
+
+	"github.com/trustbloc/vc-go/presexch"
+
+	"github.com/trustbloc/wallet-sdk/pkg/dcql"
+)
 
 type clientIDScheme string
 
 const (
 	didScheme         clientIDScheme = "did"
 	redirectURIScheme clientIDScheme = "redirect_uri"
+	x509SANDNSScheme  clientIDScheme = "x509_san_dns"
+	x509SANURIScheme  clientIDScheme = "x509_san_uri"
+
+	verifierAttestationScheme clientIDScheme = "verifier_attestation"
 )
 
 type requestObject struct {
-	JTI                    string                           `json:"jti"`
-	IAT                    int64                            `json:"iat"`
-	Issuer                 string                           `json:"iss"`
-	ResponseType           string                           `json:"response_type"` //nolint: tagliatelle
-	ResponseMode           string                           `json:"response_mode"` //nolint: tagliatelle
-	ResponseURI            string                           `json:"response_uri"`
-	Scope                  string                           `json:"scope"`
-	Nonce                  string                           `json:"nonce"`
-	ClientID               string                           `json:"client_id"` //nolint: tagliatelle
-	ClientIDScheme         clientIDScheme                   `json:"client_id_scheme"`
-	State                  string                           `json:"state"`
-	Exp                    int64                            `json:"exp"`
-	ClientMetadata         clientMetadata                   `json:"client_metadata"`
-	PresentationDefinition *presexch.PresentationDefinition `json:"presentation_definition"`
+	JTI                       string                           `json:"jti"`
+	IAT                       int64                            `json:"iat"`
+	Issuer                    string                           `json:"iss"`
+	ResponseType              string                           `json:"response_type"` //nolint: tagliatelle
+	ResponseMode              string                           `json:"response_mode"` //nolint: tagliatelle
+	ResponseURI               string                           `json:"response_uri"`
+	Scope                     string                           `json:"scope"`
+	Nonce                     string                           `json:"nonce"`
+	ClientID                  string                           `json:"client_id"` //nolint: tagliatelle
+	ClientIDScheme            clientIDScheme                   `json:"client_id_scheme"`
+	State                     string                           `json:"state"`
+	Exp                       int64                            `json:"exp"`
+	ClientMetadata            clientMetadata                   `json:"client_metadata"`
+	PresentationDefinition    *presexch.PresentationDefinition `json:"presentation_definition"`
+	PresentationDefinitionURI string                           `json:"presentation_definition_uri"`
+	DCQLQuery                 *dcql.Query                      `json:"dcql_query"`
+	WalletNonce               string                           `json:"wallet_nonce"`
 
 	// Deprecated: Deprecated in OID4VP-ID2. Use response_uri instead.
 	RedirectURI string `json:"redirect_uri"`
@@ -37,6 +51,13 @@ type requestObject struct {
 	Registration requestObjectRegistration `json:"registration"`
 	// Deprecated: Deprecated in OID4VP-ID2. Use top-level "presentation_definition" instead.
 	Claims requestObjectClaims `json:"claims"`
+
+	// The leaf certificate of the x5c chain that the request object was signed with. Only set for the x509_san_dns
+	// and x509_san_uri client_id schemes, once the chain and signature have been verified.
+	signingCertificate *x509.Certificate
+	// The verified Verifier Attestation JWT from the request object's jwt header. Only set for the
+	// verifier_attestation client_id scheme.
+	verifierAttestation *verifierAttestation
 }
 
 type clientMetadata struct {
@@ -45,6 +66,12 @@ type clientMetadata struct {
 	ClientLogoURI               string           `json:"logo_uri"`                       //nolint: tagliatelle
 	SubjectSyntaxTypesSupported []string         `json:"subject_syntax_types_supported"` //nolint: tagliatelle
 	VPFormats                   *presexch.Format `json:"vp_formats"`                     //nolint: tagliatelle
+	// JWKS is kept raw so that keys of unsupported types don't prevent the request object from being parsed.
+	JWKS                              json.RawMessage `json:"jwks"`
+	JWKSURI                           string          `json:"jwks_uri"`                             //nolint: tagliatelle
+	AuthorizationSignedResponseAlg    string          `json:"authorization_signed_response_alg"`    //nolint: tagliatelle
+	AuthorizationEncryptedResponseAlg string          `json:"authorization_encrypted_response_alg"` //nolint: tagliatelle
+	AuthorizationEncryptedResponseEnc string          `json:"authorization_encrypted_response_enc"` //nolint: tagliatelle
 }
 
 type requestObjectRegistration struct {
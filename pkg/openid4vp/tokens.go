@@ -45,3 +45,10 @@ type vpTokenClaims struct {
 	Iat   int64                    `json:"iat"`
 	Jti   string                   `json:"jti"`
 }
+
+type keyBindingClaims struct {
+	Nonce  string `json:"nonce"`
+	Aud    string `json:"aud"`
+	Iat    int64  `json:"iat"`
+	SDHash string `json:"sd_hash"` //nolint: tagliatelle
+}
@@ -0,0 +1,144 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4vp
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/trustbloc/kms-go/doc/jose"
+	"github.com/trustbloc/kms-go/doc/jose/jwk"
+	"github.com/trustbloc/vc-go/jwt"
+
+	"github.com/trustbloc/wallet-sdk/pkg/common"
+)
+
+const (
+	verifierAttestationHeader  = "jwt"
+	verifierAttestationJWTType = "verifier-attestation+jwt"
+)
+
+// verifierAttestation holds the claims of a Verifier Attestation JWT, which an attestation issuer uses to vouch for
+// a verifier and to bind the verifier's client_id to the key that it signs its request objects with.
+type verifierAttestation struct {
+	Issuer       string   `json:"iss"`
+	Subject      string   `json:"sub"`
+	Exp          int64    `json:"exp"`
+	ClientName   string   `json:"client_name"`
+	Scope        string   `json:"scope"`
+	RedirectURIs []string `json:"redirect_uris"`
+	CNF          struct {
+		JWK *jwk.JWK `json:"jwk"`
+	} `json:"cnf"`
+}
+
+// verifyAttestationRequestObject verifies a request object from a verifier that uses the verifier_attestation
+// client_id scheme. The Verifier Attestation JWT in the request object's jwt header must be signed by one of the
+// allowed attestation issuers, must be about the request object's client_id, and must not have expired. The request
+// object itself must be signed with the key in the attestation's cnf claim. The verified attestation is returned.
+func verifyAttestationRequestObject(
+	authorizationRequestClientID string,
+	rawRequestObject string,
+	headers jose.Headers,
+	reqObject *requestObject,
+	verifier *requestObjectVerifier,
+) (*verifierAttestation, error) {
+	if authorizationRequestClientID != "" && authorizationRequestClientID != reqObject.ClientID {
+		return nil, errors.New("client_id mismatch between authorization request and request object")
+	}
+
+	rawAttestation, ok := headers[verifierAttestationHeader].(string)
+	if !ok {
+		return nil, errors.New("verifier attestation JWT missing from the request object's jwt header")
+	}
+
+	attestation, err := verifyVerifierAttestation(rawAttestation, verifier)
+	if err != nil {
+		return nil, fmt.Errorf("verify verifier attestation: %w", err)
+	}
+
+	if attestation.Subject != reqObject.ClientID {
+		return nil, errors.New("verifier attestation subject doesn't match client_id")
+	}
+
+	if len(attestation.RedirectURIs) > 0 && !slices.Contains(attestation.RedirectURIs, responseURI(reqObject)) {
+		return nil, errors.New("response_uri isn't one of the redirect_uris in the verifier attestation")
+	}
+
+	// An expected proof issuer is passed in so that the kid header isn't required. It's ignored by the proof checker.
+	var expectedProofIssuer string
+
+	err = jwt.CheckProof(rawRequestObject, common.NewJWKProofChecker(attestation.CNF.JWK), &expectedProofIssuer, nil)
+	if err != nil {
+		return nil, fmt.Errorf("check proof with verifier attestation key: %w", err)
+	}
+
+	return attestation, nil
+}
+
+func verifyVerifierAttestation(rawAttestation string, verifier *requestObjectVerifier) (*verifierAttestation, error) {
+	attestation := &verifierAttestation{}
+
+	token, _, err := jwt.Parse(rawAttestation,
+		jwt.DecodeClaimsTo(attestation),
+		jwt.WithIgnoreClaimsMapDecoding(true),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("parse jwt: %w", err)
+	}
+
+	if typ, _ := token.Headers.Type(); typ != verifierAttestationJWTType {
+		return nil, fmt.Errorf("typ header must be %s", verifierAttestationJWTType)
+	}
+
+	if !slices.Contains(verifier.verifierAttestationIssuers, attestation.Issuer) {
+		return nil, fmt.Errorf("%s isn't a trusted verifier attestation issuer", attestation.Issuer)
+	}
+
+	err = jwt.CheckProof(rawAttestation, verifier.signatureVerifier, &attestation.Issuer, nil)
+	if err != nil {
+		return nil, fmt.Errorf("check proof: %w", err)
+	}
+
+	if attestation.Exp == 0 || time.Now().After(time.Unix(attestation.Exp, 0)) {
+		return nil, errors.New("verifier attestation has expired")
+	}
+
+	if attestation.CNF.JWK == nil {
+		return nil, errors.New("verifier attestation is missing the cnf key")
+	}
+
+	return attestation, nil
+}
+
+// attestationTrustInfo is used when the verifier was vouched for by a Verifier Attestation JWT. The verifier's domain
+// is only considered valid if the attestation pins the response URIs, since otherwise it says nothing about them.
+func attestationTrustInfo(reqObject *requestObject) (*VerifierTrustInfo, error) {
+	attestation := reqObject.verifierAttestation
+
+	parsedResponseURI, err := url.Parse(responseURI(reqObject))
+	if err != nil {
+		return nil, err
+	}
+
+	trustInfo := &VerifierTrustInfo{
+		Domain:            parsedResponseURI.Host,
+		DomainValid:       len(attestation.RedirectURIs) > 0,
+		AttestationIssuer: attestation.Issuer,
+		AttestedName:      attestation.ClientName,
+	}
+
+	if attestation.Scope != "" {
+		trustInfo.AttestedScope = strings.Fields(attestation.Scope)
+	}
+
+	return trustInfo, nil
+}
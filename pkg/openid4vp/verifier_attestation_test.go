@@ -0,0 +1,201 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4vp //nolint: testpackage
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/kms-go/doc/jose"
+	"github.com/trustbloc/kms-go/doc/jose/jwk/jwksupport"
+	"github.com/trustbloc/vc-go/jwt"
+)
+
+const (
+	testAttestationIssuer = "did:example:attestation-issuer"
+	testAttestedClientID  = "verifier.example.com"
+)
+
+func TestNewInteraction_VerifierAttestation(t *testing.T) {
+	issuerKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	verifierKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	verifierJWK, err := jwksupport.JWKFromKey(&verifierKey.PublicKey)
+	require.NoError(t, err)
+
+	newAttestation := func() *verifierAttestation {
+		attestation := &verifierAttestation{
+			Issuer:       testAttestationIssuer,
+			Subject:      testAttestedClientID,
+			Exp:          time.Now().Add(time.Hour).Unix(),
+			ClientName:   "Test Verifier",
+			Scope:        "pid mdl",
+			RedirectURIs: []string{testVerifierURI},
+		}
+		attestation.CNF.JWK = verifierJWK
+
+		return attestation
+	}
+
+	signAttestation := func(attestation *verifierAttestation, typ string) string {
+		return signTestJWT(t, issuerKey, attestation, jose.Headers{
+			jose.HeaderType:  typ,
+			jose.HeaderKeyID: testAttestationIssuer + "#key-1",
+		})
+	}
+
+	newRequest := func(key *ecdsa.PrivateKey, attestation string) string {
+		headers := jose.Headers{}
+		if attestation != "" {
+			headers[verifierAttestationHeader] = attestation
+		}
+
+		return signTestJWT(t, key, &requestObject{
+			ClientID:               testAttestedClientID,
+			ClientIDScheme:         verifierAttestationScheme,
+			ResponseURI:            testVerifierURI,
+			PresentationDefinition: testPD(t),
+		}, headers)
+	}
+
+	attestationIssuers := WithVerifierAttestationIssuers([]string{testAttestationIssuer})
+
+	t.Run("Success", func(t *testing.T) {
+		interaction, err := NewInteraction(
+			newRequest(verifierKey, signAttestation(newAttestation(), verifierAttestationJWTType)),
+			&jwtSignatureVerifierMock{}, nil, nil, nil, attestationIssuers)
+		require.NoError(t, err)
+
+		trustInfo, err := interaction.TrustInfo()
+		require.NoError(t, err)
+		require.Equal(t, &VerifierTrustInfo{
+			Domain:            "verifier.example.com",
+			DomainValid:       true,
+			AttestationIssuer: testAttestationIssuer,
+			AttestedName:      "Test Verifier",
+			AttestedScope:     []string{"pid", "mdl"},
+		}, trustInfo)
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		expiredAttestation := newAttestation()
+		expiredAttestation.Exp = time.Now().Add(-time.Minute).Unix()
+
+		otherSubjectAttestation := newAttestation()
+		otherSubjectAttestation.Subject = "other.example.com"
+
+		otherRedirectURIsAttestation := newAttestation()
+		otherRedirectURIsAttestation.RedirectURIs = []string{"https://other.example.com/response"}
+
+		noCNFAttestation := newAttestation()
+		noCNFAttestation.CNF.JWK = nil
+
+		tests := []struct {
+			name              string
+			request           string
+			signatureVerifier *jwtSignatureVerifierMock
+			opts              []Opt
+			expectedError     string
+		}{
+			{
+				name:          "Missing jwt header",
+				request:       newRequest(verifierKey, ""),
+				opts:          []Opt{attestationIssuers},
+				expectedError: "verifier attestation JWT missing from the request object's jwt header",
+			},
+			{
+				name:          "Wrong typ header",
+				request:       newRequest(verifierKey, signAttestation(newAttestation(), "JWT")),
+				opts:          []Opt{attestationIssuers},
+				expectedError: "typ header must be verifier-attestation+jwt",
+			},
+			{
+				name: "No attestation issuers configured",
+				request: newRequest(verifierKey,
+					signAttestation(newAttestation(), verifierAttestationJWTType)),
+				expectedError: "did:example:attestation-issuer isn't a trusted verifier attestation issuer",
+			},
+			{
+				name: "Invalid attestation signature",
+				request: newRequest(verifierKey,
+					signAttestation(newAttestation(), verifierAttestationJWTType)),
+				signatureVerifier: &jwtSignatureVerifierMock{err: errors.New("sig verification err")},
+				opts:              []Opt{attestationIssuers},
+				expectedError:     "sig verification err",
+			},
+			{
+				name: "Expired attestation",
+				request: newRequest(verifierKey,
+					signAttestation(expiredAttestation, verifierAttestationJWTType)),
+				opts:          []Opt{attestationIssuers},
+				expectedError: "verifier attestation has expired",
+			},
+			{
+				name: "Attestation without cnf key",
+				request: newRequest(verifierKey,
+					signAttestation(noCNFAttestation, verifierAttestationJWTType)),
+				opts:          []Opt{attestationIssuers},
+				expectedError: "verifier attestation is missing the cnf key",
+			},
+			{
+				name: "Attestation for another client",
+				request: newRequest(verifierKey,
+					signAttestation(otherSubjectAttestation, verifierAttestationJWTType)),
+				opts:          []Opt{attestationIssuers},
+				expectedError: "verifier attestation subject doesn't match client_id",
+			},
+			{
+				name: "response_uri not attested",
+				request: newRequest(verifierKey,
+					signAttestation(otherRedirectURIsAttestation, verifierAttestationJWTType)),
+				opts:          []Opt{attestationIssuers},
+				expectedError: "response_uri isn't one of the redirect_uris in the verifier attestation",
+			},
+			{
+				name: "Request object not signed with the cnf key",
+				request: newRequest(issuerKey,
+					signAttestation(newAttestation(), verifierAttestationJWTType)),
+				opts:          []Opt{attestationIssuers},
+				expectedError: "check proof with verifier attestation key",
+			},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				signatureVerifier := test.signatureVerifier
+				if signatureVerifier == nil {
+					signatureVerifier = &jwtSignatureVerifierMock{}
+				}
+
+				interaction, err := NewInteraction(test.request, signatureVerifier, nil, nil, nil, test.opts...)
+				require.ErrorContains(t, err, "INVALID_AUTHORIZATION_REQUEST")
+				require.ErrorContains(t, err, test.expectedError)
+				require.Nil(t, interaction)
+			})
+		}
+	})
+}
+
+func signTestJWT(t *testing.T, key *ecdsa.PrivateKey, claims interface{}, headers jose.Headers) string {
+	t.Helper()
+
+	token, err := jwt.NewJoseSigned(claims, nil, &es256Signer{key: key, headers: headers})
+	require.NoError(t, err)
+
+	signedJWT, err := token.Serialize(false)
+	require.NoError(t, err)
+
+	return signedJWT
+}
@@ -0,0 +1,133 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4vp
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/trustbloc/wallet-sdk/pkg/internal/x5c"
+)
+
+// verifyX509RequestObject verifies a request object from a verifier that uses the x509_san_dns or x509_san_uri
+// client_id scheme. The request object must be signed with the key of the leaf certificate in its x5c header, the
+// certificate chain must lead to one of the trust anchors, and the leaf certificate must have a SAN that matches the
+// client_id. The verified leaf certificate is returned.
+func verifyX509RequestObject(
+	authorizationRequestClientID string,
+	rawRequestObject string,
+	reqObject *requestObject,
+	trustAnchors []*x509.Certificate,
+) (*x509.Certificate, error) {
+	if authorizationRequestClientID != "" && authorizationRequestClientID != reqObject.ClientID {
+		return nil, errors.New("client_id mismatch between authorization request and request object")
+	}
+
+	_, _, chain, err := x5c.ParseAndCheckProof(rawRequestObject, trustAnchors)
+	if err != nil {
+		return nil, fmt.Errorf("check proof: %w", err)
+	}
+
+	certificate := chain[0]
+
+	switch reqObject.ClientIDScheme {
+	case x509SANDNSScheme:
+		err = verifyDNSClientID(reqObject, certificate)
+	case x509SANURIScheme:
+		err = verifyURIClientID(reqObject, certificate)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return certificate, nil
+}
+
+// verifyDNSClientID checks that the certificate has a dNSName SAN equal to the client_id, and that the response URI
+// is on that host.
+func verifyDNSClientID(reqObject *requestObject, certificate *x509.Certificate) error {
+	if !slices.ContainsFunc(certificate.DNSNames, func(name string) bool {
+		return strings.EqualFold(name, reqObject.ClientID)
+	}) {
+		return fmt.Errorf("signing certificate has no DNS SAN that matches client_id %s", reqObject.ClientID)
+	}
+
+	parsedResponseURI, err := url.Parse(responseURI(reqObject))
+	if err != nil {
+		return fmt.Errorf("parse response_uri: %w", err)
+	}
+
+	if !strings.EqualFold(parsedResponseURI.Hostname(), reqObject.ClientID) {
+		return errors.New("response_uri host doesn't match client_id")
+	}
+
+	return nil
+}
+
+// verifyURIClientID checks that the certificate has a uniformResourceIdentifier SAN equal to the client_id, and that
+// the response URI matches the client_id.
+func verifyURIClientID(reqObject *requestObject, certificate *x509.Certificate) error {
+	if !slices.ContainsFunc(certificate.URIs, func(uri *url.URL) bool {
+		return uri.String() == reqObject.ClientID
+	}) {
+		return fmt.Errorf("signing certificate has no URI SAN that matches client_id %s", reqObject.ClientID)
+	}
+
+	if !matchClientIDAndResponseURI(reqObject.ClientID, responseURI(reqObject)) {
+		return errors.New("response_uri doesn't match client_id")
+	}
+
+	return nil
+}
+
+// certificateTrustInfo is used when the request object was signed using an x5c certificate chain (which has already
+// been verified against the trust anchors, and whose SAN matches the client_id). Since the response URI has been
+// checked against the client_id, the verifier's domain is valid.
+func certificateTrustInfo(reqObject *requestObject) (*VerifierTrustInfo, error) {
+	trustInfo := &VerifierTrustInfo{
+		DomainValid:        true,
+		CertificateSubject: reqObject.signingCertificate.Subject.String(),
+	}
+
+	if reqObject.ClientIDScheme == x509SANDNSScheme {
+		trustInfo.Domain = reqObject.ClientID
+
+		return trustInfo, nil
+	}
+
+	clientIDURI, err := url.Parse(reqObject.ClientID)
+	if err != nil {
+		return nil, err
+	}
+
+	trustInfo.Domain = clientIDURI.Host
+
+	return trustInfo, nil
+}
+
+// certificateName returns a name for the verifier from its certificate's subject, preferring the organization.
+func certificateName(certificate *x509.Certificate) string {
+	if len(certificate.Subject.Organization) > 0 {
+		return certificate.Subject.Organization[0]
+	}
+
+	return certificate.Subject.CommonName
+}
+
+// responseURI returns the request object's response URI, falling back to the deprecated redirect_uri.
+func responseURI(reqObject *requestObject) string {
+	if reqObject.ResponseURI != "" {
+		return reqObject.ResponseURI
+	}
+
+	return reqObject.RedirectURI
+}
@@ -0,0 +1,220 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4vp //nolint: testpackage
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/kms-go/doc/jose"
+)
+
+func TestNewInteraction_X509ClientIDSchemes(t *testing.T) {
+	rootKey, root := createTestCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "Test Root CA"}},
+		nil, nil)
+	signingKey, signingCertificate := createTestCertificate(t, &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "Test Verifier", Organization: []string{"Test Government Agency"}},
+		DNSNames: []string{"verifier.example.com"},
+		URIs:     []*url.URL{{Scheme: "https", Host: "verifier.example.com", Path: "/response"}},
+	}, root, rootKey)
+
+	trustAnchors := WithTrustAnchors([]*x509.Certificate{root})
+
+	newRequest := func(scheme clientIDScheme, clientID string) string {
+		return createX5CRequestObject(t, signingKey, &requestObject{
+			ClientID:               clientID,
+			ClientIDScheme:         scheme,
+			ResponseURI:            testVerifierURI,
+			PresentationDefinition: testPD(t),
+		}, signingCertificate)
+	}
+
+	t.Run("x509_san_dns", func(t *testing.T) {
+		interaction, err := NewInteraction(newRequest(x509SANDNSScheme, "verifier.example.com"),
+			&jwtSignatureVerifierMock{}, nil, nil, nil, trustAnchors)
+		require.NoError(t, err)
+
+		trustInfo, err := interaction.TrustInfo()
+		require.NoError(t, err)
+		require.Equal(t, &VerifierTrustInfo{
+			Domain:             "verifier.example.com",
+			DomainValid:        true,
+			CertificateSubject: "CN=Test Verifier,O=Test Government Agency",
+		}, trustInfo)
+
+		displayData := interaction.VerifierDisplayData()
+		require.Equal(t, "Test Government Agency", displayData.Name)
+		require.Equal(t, "CN=Test Verifier,O=Test Government Agency", displayData.CertificateSubject)
+	})
+
+	t.Run("x509_san_uri", func(t *testing.T) {
+		interaction, err := NewInteraction("openid4vp://?client_id="+url.QueryEscape(testVerifierURI)+
+			"&request="+newRequest(x509SANURIScheme, testVerifierURI), &jwtSignatureVerifierMock{}, nil, nil, nil,
+			trustAnchors)
+		require.NoError(t, err)
+
+		trustInfo, err := interaction.TrustInfo()
+		require.NoError(t, err)
+		require.Equal(t, "verifier.example.com", trustInfo.Domain)
+		require.True(t, trustInfo.DomainValid)
+		require.Empty(t, trustInfo.DID)
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		_, otherRoot := createTestCertificate(t, &x509.Certificate{Subject: pkix.Name{CommonName: "Other Root CA"}},
+			nil, nil)
+
+		tests := []struct {
+			name          string
+			request       string
+			opts          []Opt
+			expectedError string
+		}{
+			{
+				name:          "No trust anchors",
+				request:       newRequest(x509SANDNSScheme, "verifier.example.com"),
+				expectedError: "no trust anchors are configured",
+			},
+			{
+				name:          "Untrusted chain",
+				request:       newRequest(x509SANDNSScheme, "verifier.example.com"),
+				opts:          []Opt{WithTrustAnchors([]*x509.Certificate{otherRoot})},
+				expectedError: "certificate chain of CN=Test Verifier,O=Test Government Agency is not trusted",
+			},
+			{
+				name:          "DNS SAN doesn't match client_id",
+				request:       newRequest(x509SANDNSScheme, "other.example.com"),
+				opts:          []Opt{trustAnchors},
+				expectedError: "signing certificate has no DNS SAN that matches client_id other.example.com",
+			},
+			{
+				name:          "URI SAN doesn't match client_id",
+				request:       newRequest(x509SANURIScheme, "https://verifier.example.com/other"),
+				opts:          []Opt{trustAnchors},
+				expectedError: "signing certificate has no URI SAN that matches client_id",
+			},
+			{
+				name: "response_uri on another host",
+				request: createX5CRequestObject(t, signingKey, &requestObject{
+					ClientID:       "verifier.example.com",
+					ClientIDScheme: x509SANDNSScheme,
+					ResponseURI:    "https://attacker.example.com/response",
+				}, signingCertificate),
+				opts:          []Opt{trustAnchors},
+				expectedError: "response_uri host doesn't match client_id",
+			},
+			{
+				name: "client_id mismatch between authorization request and request object",
+				request: "openid4vp://?client_id=other.example.com&request=" +
+					newRequest(x509SANDNSScheme, "verifier.example.com"),
+				opts:          []Opt{trustAnchors},
+				expectedError: "client_id mismatch between authorization request and request object",
+			},
+			{
+				name: "Not signed with the certificate's key",
+				request: createX5CRequestObject(t, rootKey, &requestObject{
+					ClientID:       "verifier.example.com",
+					ClientIDScheme: x509SANDNSScheme,
+					ResponseURI:    testVerifierURI,
+				}, signingCertificate),
+				opts:          []Opt{trustAnchors},
+				expectedError: "invalid signature",
+			},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				interaction, err := NewInteraction(test.request, &jwtSignatureVerifierMock{}, nil, nil, nil,
+					test.opts...)
+				require.ErrorContains(t, err, "INVALID_AUTHORIZATION_REQUEST")
+				require.ErrorContains(t, err, test.expectedError)
+				require.Nil(t, interaction)
+			})
+		}
+	})
+}
+
+func createTestCertificate(t *testing.T, template, parent *x509.Certificate, parentKey *ecdsa.PrivateKey,
+) (*ecdsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign
+
+	if parent == nil {
+		template.IsCA = true
+		parent, parentKey = template, key
+	}
+
+	certificateBytes, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+
+	certificate, err := x509.ParseCertificate(certificateBytes)
+	require.NoError(t, err)
+
+	return key, certificate
+}
+
+func createX5CRequestObject(t *testing.T, key *ecdsa.PrivateKey, reqObject *requestObject,
+	chain ...*x509.Certificate,
+) string {
+	t.Helper()
+
+	encodedChain := make([]string, len(chain))
+
+	for i, certificate := range chain {
+		encodedChain[i] = base64.StdEncoding.EncodeToString(certificate.Raw)
+	}
+
+	return signTestJWT(t, key, reqObject, jose.Headers{jose.HeaderX509CertificateChain: encodedChain})
+}
+
+type es256Signer struct {
+	key     *ecdsa.PrivateKey
+	headers jose.Headers
+}
+
+func (s *es256Signer) Sign(data []byte) ([]byte, error) {
+	digest := sha256.Sum256(data)
+
+	r, sig, err := ecdsa.Sign(rand.Reader, s.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	sig.FillBytes(signature[32:])
+
+	return signature, nil
+}
+
+func (s *es256Signer) Headers() jose.Headers {
+	headers := jose.Headers{jose.HeaderAlgorithm: "ES256"}
+
+	for name, value := range s.headers {
+		headers[name] = value
+	}
+
+	return headers
+}
@@ -0,0 +1,24 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package router
+
+// Constants' names and reasons are obvious, so they do not require additional comments.
+// nolint:golint,nolintlint
+const (
+	ErrorModule                     = "RTR"
+	UnsupportedRequestError         = "UNSUPPORTED_REQUEST"
+	InvalidCredentialOfferError     = "INVALID_CREDENTIAL_OFFER" //nolint:gosec //false positive
+	InvalidPresentationRequestError = "INVALID_PRESENTATION_REQUEST"
+)
+
+// Constants' names and reasons are obvious, so they do not require additional comments.
+// nolint:golint,nolintlint
+const (
+	UnsupportedRequestCode         = 0
+	InvalidCredentialOfferCode     = 1
+	InvalidPresentationRequestCode = 2
+)
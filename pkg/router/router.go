@@ -0,0 +1,355 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package router provides a single entry point for the requests that a wallet receives, typically by scanning a QR
+// code or by following a link. It works out whether a request is a credential offer or a presentation request and
+// creates the interaction object that handles it.
+package router
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/piprate/json-gold/ld"
+	"github.com/trustbloc/vc-go/jwt"
+
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+	"github.com/trustbloc/wallet-sdk/pkg/openid4vp"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+const (
+	credentialOfferScheme = "openid-credential-offer"
+	openID4VPScheme       = "openid4vp"
+	openIDVCScheme        = "openid-vc"
+	haipScheme            = "haip"
+	httpsScheme           = "https"
+
+	jwtPartCount = 3
+)
+
+// RequestType indicates the kind of request that a payload contains.
+type RequestType string
+
+// Request types that can be returned by Parse.
+const (
+	Unknown             RequestType = "unknown"
+	CredentialOffer     RequestType = "credential_offer"
+	PresentationRequest RequestType = "presentation_request"
+)
+
+// Request is the result of parsing a payload.
+type Request struct {
+	// The kind of request the payload contains. If the payload isn't recognised, then this is Unknown.
+	Type RequestType
+	// The request in the form that's accepted by the interaction that handles it. For credential offers, this is an
+	// openid-credential-offer:// URI. For presentation requests, this is an openid4vp:// or openid-vc:// URI, or a
+	// raw request object.
+	URI string
+	// Set if the payload isn't a valid request. If the type of the request could be determined, then Type is still
+	// set, so that the caller can tell the user what kind of request failed.
+	ValidationError error
+}
+
+// Config contains the configuration used to create interactions. Only the configuration for the request types that
+// are expected needs to be set.
+type Config struct {
+	OpenID4CI *openid4ci.ClientConfig
+	OpenID4VP *OpenID4VPConfig
+}
+
+// OpenID4VPConfig contains the parameters that are passed to openid4vp.NewInteraction.
+type OpenID4VPConfig struct {
+	SignatureVerifier jwt.ProofChecker
+	DIDResolver       api.DIDResolver
+	Crypto            api.Crypto
+	DocumentLoader    ld.DocumentLoader
+	Opts              []openid4vp.Opt
+}
+
+// Interaction holds the interaction created for a request. Exactly one of IssuerInitiated and Presentation is set,
+// depending on the request's type.
+type Interaction struct {
+	Request         *Request
+	IssuerInitiated *openid4ci.IssuerInitiatedInteraction
+	Presentation    *openid4vp.Interaction
+}
+
+// Parse determines what kind of request the given payload contains. The following payloads are recognised:
+//   - openid-credential-offer:// URIs, which are credential offers.
+//   - openid4vp://, openid-vc:// and haip:// URIs, which are presentation requests.
+//   - https universal links, whose type is determined by their query parameters.
+//   - Raw request objects (JWTs), which are presentation requests.
+//   - Raw credential offer JSON objects, which are credential offers.
+//
+// Only the structure of the request is checked. Nothing is fetched, so a request that's reported as valid can still
+// fail when the interaction is created.
+func Parse(payload string) *Request {
+	payload = strings.TrimSpace(payload)
+
+	parsedURL, err := url.Parse(payload)
+	if err == nil && parsedURL.Scheme != "" {
+		return parseURI(payload, parsedURL)
+	}
+
+	switch {
+	case isJWT(payload):
+		return &Request{Type: PresentationRequest, URI: payload}
+	case strings.HasPrefix(payload, "{"):
+		return parseCredentialOfferObject(payload)
+	default:
+		return unsupportedRequest(errors.New("payload is neither a URI, a request object nor a credential offer"))
+	}
+}
+
+// NewInteraction parses the given payload and creates the interaction that handles it.
+// If the payload isn't a valid request, then the request's validation error is returned.
+func NewInteraction(payload string, config *Config) (*Interaction, error) {
+	return NewInteractionContext(context.Background(), payload, config)
+}
+
+// NewInteractionContext is the same as NewInteraction, but uses the given context when creating the interaction.
+func NewInteractionContext(ctx context.Context, payload string, config *Config) (*Interaction, error) {
+	if config == nil {
+		return nil, walleterror.NewInvalidSDKUsageError(ErrorModule, errors.New("config must be provided"))
+	}
+
+	request := Parse(payload)
+	if request.ValidationError != nil {
+		return nil, request.ValidationError
+	}
+
+	interaction := &Interaction{Request: request}
+
+	switch request.Type {
+	case CredentialOffer:
+		if config.OpenID4CI == nil {
+			return nil, walleterror.NewInvalidSDKUsageError(ErrorModule,
+				errors.New("OpenID4CI config must be provided to handle credential offers"))
+		}
+
+		issuerInitiatedInteraction, err := openid4ci.NewIssuerInitiatedInteractionContext(ctx, request.URI,
+			config.OpenID4CI)
+		if err != nil {
+			return nil, err
+		}
+
+		interaction.IssuerInitiated = issuerInitiatedInteraction
+	case PresentationRequest:
+		vpConfig := config.OpenID4VP
+		if vpConfig == nil {
+			return nil, walleterror.NewInvalidSDKUsageError(ErrorModule,
+				errors.New("OpenID4VP config must be provided to handle presentation requests"))
+		}
+
+		presentationInteraction, err := openid4vp.NewInteractionContext(ctx, request.URI, vpConfig.SignatureVerifier,
+			vpConfig.DIDResolver, vpConfig.Crypto, vpConfig.DocumentLoader, vpConfig.Opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		interaction.Presentation = presentationInteraction
+	default:
+		return nil, walleterror.NewValidationError(ErrorModule, UnsupportedRequestCode, UnsupportedRequestError,
+			fmt.Errorf("unsupported request type: %s", request.Type))
+	}
+
+	return interaction, nil
+}
+
+func parseURI(payload string, parsedURL *url.URL) *Request {
+	// Schemes are case-insensitive, but the interactions only accept lowercase ones.
+	scheme := strings.ToLower(parsedURL.Scheme)
+	payload = scheme + payload[len(parsedURL.Scheme):]
+
+	switch scheme {
+	case credentialOfferScheme:
+		return credentialOfferRequest(payload, parsedURL.Query())
+	case openID4VPScheme, openIDVCScheme:
+		return presentationRequest(payload, parsedURL.Query())
+	case haipScheme:
+		// HAIP requests use the same parameters as OpenID4VP requests, they only differ in the scheme.
+		return presentationRequest(openID4VPScheme+"://?"+parsedURL.RawQuery, parsedURL.Query())
+	case httpsScheme:
+		return parseUniversalLink(parsedURL)
+	default:
+		return unsupportedRequest(fmt.Errorf("unsupported URI scheme: %s", parsedURL.Scheme))
+	}
+}
+
+// parseUniversalLink handles https links that wrap a request. The host and path only identify the wallet, so the
+// request's type is determined by its query parameters.
+func parseUniversalLink(parsedURL *url.URL) *Request {
+	query := parsedURL.Query()
+
+	switch {
+	case query.Has("credential_offer") || query.Has("credential_offer_uri"):
+		return credentialOfferRequest(credentialOfferScheme+"://?"+parsedURL.RawQuery, query)
+	case query.Has("request") || query.Has("request_uri") || query.Has("client_id"):
+		return presentationRequest(openID4VPScheme+"://?"+parsedURL.RawQuery, query)
+	default:
+		return unsupportedRequest(errors.New("https link doesn't contain a credential offer or a presentation request"))
+	}
+}
+
+func parseCredentialOfferObject(payload string) *Request {
+	request := &Request{
+		Type: CredentialOffer,
+		URI:  credentialOfferScheme + "://?credential_offer=" + url.QueryEscape(payload),
+	}
+
+	var fields map[string]json.RawMessage
+
+	err := json.Unmarshal([]byte(payload), &fields)
+	if err != nil || fields["credential_issuer"] == nil {
+		return unsupportedRequest(errors.New("JSON payload isn't a credential offer"))
+	}
+
+	if err = validateCredentialOffer(payload); err != nil {
+		request.ValidationError = invalidCredentialOffer(err)
+	}
+
+	return request
+}
+
+func credentialOfferRequest(uri string, query url.Values) *Request {
+	request := &Request{Type: CredentialOffer, URI: uri}
+
+	if err := validateCredentialOfferQuery(query); err != nil {
+		request.ValidationError = invalidCredentialOffer(err)
+	}
+
+	return request
+}
+
+func validateCredentialOfferQuery(query url.Values) error {
+	switch {
+	case query.Has("credential_offer") && query.Has("credential_offer_uri"):
+		return errors.New("only one of credential_offer and credential_offer_uri may be provided")
+	case query.Has("credential_offer"):
+		return validateCredentialOffer(query.Get("credential_offer"))
+	case query.Has("credential_offer_uri"):
+		return validateHTTPURL("credential_offer_uri", query.Get("credential_offer_uri"))
+	default:
+		return errors.New("credential offer URI must have a credential_offer or credential_offer_uri query parameter")
+	}
+}
+
+func validateCredentialOffer(rawCredentialOffer string) error {
+	var credentialOffer struct {
+		CredentialIssuer           string   `json:"credential_issuer"`
+		CredentialConfigurationIDs []string `json:"credential_configuration_ids"`
+	}
+
+	err := json.Unmarshal([]byte(rawCredentialOffer), &credentialOffer)
+	if err != nil {
+		return fmt.Errorf("decode credential offer: %w", err)
+	}
+
+	if credentialOffer.CredentialIssuer == "" {
+		return errors.New("credential offer is missing credential_issuer")
+	}
+
+	if len(credentialOffer.CredentialConfigurationIDs) == 0 {
+		return errors.New("credential offer is missing credential_configuration_ids")
+	}
+
+	return nil
+}
+
+func presentationRequest(uri string, query url.Values) *Request {
+	request := &Request{Type: PresentationRequest, URI: uri}
+
+	if err := validatePresentationQuery(query); err != nil {
+		request.ValidationError = invalidPresentationRequest(err)
+	}
+
+	return request
+}
+
+// validatePresentationQuery checks a presentation request, which can pass its request object by value (request), by
+// reference (request_uri), or as plain URL parameters.
+func validatePresentationQuery(query url.Values) error {
+	switch {
+	case query.Has("request") && query.Has("request_uri"):
+		return errors.New("only one of request and request_uri may be provided")
+	case query.Has("request"):
+		if !isJWT(query.Get("request")) {
+			return errors.New("request must be a request object JWT")
+		}
+
+		return nil
+	case query.Has("request_uri"):
+		method := query.Get("request_uri_method")
+		if method != "" && method != "get" && method != "post" {
+			return fmt.Errorf("unsupported request_uri_method: %s", method)
+		}
+
+		return validateHTTPURL("request_uri", query.Get("request_uri"))
+	case !query.Has("client_id"):
+		return errors.New("presentation request URI must have a request, request_uri or client_id query parameter")
+	case query.Has("presentation_definition_uri"):
+		return validateHTTPURL("presentation_definition_uri", query.Get("presentation_definition_uri"))
+	case query.Has("presentation_definition") || query.Has("dcql_query"):
+		return nil
+	default:
+		return errors.New("presentation request URI must have a presentation_definition, " +
+			"presentation_definition_uri or dcql_query query parameter")
+	}
+}
+
+func validateHTTPURL(parameterName, rawURL string) error {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", parameterName, err)
+	}
+
+	if (parsedURL.Scheme != "https" && parsedURL.Scheme != "http") || parsedURL.Host == "" {
+		return fmt.Errorf("%s must be an absolute http(s) URL", parameterName)
+	}
+
+	return nil
+}
+
+// isJWT checks whether the payload looks like a compact JWS with a JSON object as its payload. Signatures aren't
+// checked here, since that's done when the interaction is created.
+func isJWT(payload string) bool {
+	parts := strings.Split(payload, ".")
+	if len(parts) != jwtPartCount {
+		return false
+	}
+
+	claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+
+	var claimsObject map[string]interface{}
+
+	return json.Unmarshal(claims, &claimsObject) == nil
+}
+
+func unsupportedRequest(err error) *Request {
+	return &Request{
+		Type:            Unknown,
+		ValidationError: walleterror.NewValidationError(ErrorModule, UnsupportedRequestCode, UnsupportedRequestError, err),
+	}
+}
+
+func invalidCredentialOffer(err error) error {
+	return walleterror.NewValidationError(ErrorModule, InvalidCredentialOfferCode, InvalidCredentialOfferError, err)
+}
+
+func invalidPresentationRequest(err error) error {
+	return walleterror.NewValidationError(ErrorModule, InvalidPresentationRequestCode,
+		InvalidPresentationRequestError, err)
+}
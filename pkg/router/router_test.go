@@ -0,0 +1,276 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package router_test
+
+import (
+	_ "embed"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/kms-go/doc/jose"
+
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+	"github.com/trustbloc/wallet-sdk/pkg/router"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+const sampleCredentialOffer = `{"credential_issuer":"https://issuer.example.com",` +
+	`"credential_configuration_ids":["PermanentResidentCard"]}`
+
+//go:embed test_data/request_object.jwt
+var requestObjectJWT string
+
+func TestParse(t *testing.T) {
+	escapedCredentialOffer := url.QueryEscape(sampleCredentialOffer)
+
+	tests := []struct {
+		name          string
+		payload       string
+		expectedType  router.RequestType
+		expectedURI   string
+		expectedError string
+	}{
+		{
+			name:         "Credential offer by value",
+			payload:      "openid-credential-offer://?credential_offer=" + escapedCredentialOffer,
+			expectedType: router.CredentialOffer,
+			expectedURI:  "openid-credential-offer://?credential_offer=" + escapedCredentialOffer,
+		},
+		{
+			name:         "Credential offer by reference with surrounding whitespace",
+			payload:      " openid-credential-offer://?credential_offer_uri=https%3A%2F%2Fissuer.example.com%2Foffer\n",
+			expectedType: router.CredentialOffer,
+			expectedURI:  "openid-credential-offer://?credential_offer_uri=https%3A%2F%2Fissuer.example.com%2Foffer",
+		},
+		{
+			name:         "Credential offer JSON object",
+			payload:      sampleCredentialOffer,
+			expectedType: router.CredentialOffer,
+			expectedURI:  "openid-credential-offer://?credential_offer=" + escapedCredentialOffer,
+		},
+		{
+			name:         "Credential offer in a universal link",
+			payload:      "https://wallet.example.com/offer?credential_offer=" + escapedCredentialOffer,
+			expectedType: router.CredentialOffer,
+			expectedURI:  "openid-credential-offer://?credential_offer=" + escapedCredentialOffer,
+		},
+		{
+			name:         "OpenID4VP request",
+			payload:      "openid4vp://authorize?request_uri=https://verifier.example.com/request",
+			expectedType: router.PresentationRequest,
+			expectedURI:  "openid4vp://authorize?request_uri=https://verifier.example.com/request",
+		},
+		{
+			name:         "OpenID4VP request with an uppercase scheme",
+			payload:      "OPENID-VC://?request_uri=https://verifier.example.com/request",
+			expectedType: router.PresentationRequest,
+			expectedURI:  "openid-vc://?request_uri=https://verifier.example.com/request",
+		},
+		{
+			name:         "HAIP request",
+			payload:      "haip://?client_id=verifier&request_uri=https://verifier.example.com/request",
+			expectedType: router.PresentationRequest,
+			expectedURI:  "openid4vp://?client_id=verifier&request_uri=https://verifier.example.com/request",
+		},
+		{
+			name:         "Presentation request in a universal link",
+			payload:      "https://wallet.example.com/present?request_uri=https://verifier.example.com/request",
+			expectedType: router.PresentationRequest,
+			expectedURI:  "openid4vp://?request_uri=https://verifier.example.com/request",
+		},
+		{
+			name:         "OpenID4VP request by value",
+			payload:      "openid4vp://?request=" + requestObjectJWT,
+			expectedType: router.PresentationRequest,
+			expectedURI:  "openid4vp://?request=" + requestObjectJWT,
+		},
+		{
+			name: "OpenID4VP request with URL parameters",
+			payload: "openid4vp://?client_id=https://verifier.example.com/response" +
+				"&presentation_definition_uri=https://verifier.example.com/pd",
+			expectedType: router.PresentationRequest,
+			expectedURI: "openid4vp://?client_id=https://verifier.example.com/response" +
+				"&presentation_definition_uri=https://verifier.example.com/pd",
+		},
+		{
+			name:         "OpenID4VP request with request_uri_method=post",
+			payload:      "openid4vp://?request_uri=https://verifier.example.com/request&request_uri_method=post",
+			expectedType: router.PresentationRequest,
+			expectedURI:  "openid4vp://?request_uri=https://verifier.example.com/request&request_uri_method=post",
+		},
+		{
+			name:         "Raw request object",
+			payload:      requestObjectJWT,
+			expectedType: router.PresentationRequest,
+			expectedURI:  requestObjectJWT,
+		},
+		{
+			name:          "Credential offer missing parameters",
+			payload:       "openid-credential-offer://?",
+			expectedType:  router.CredentialOffer,
+			expectedError: "must have a credential_offer or credential_offer_uri query parameter",
+		},
+		{
+			name: "Credential offer with both parameters",
+			payload: "openid-credential-offer://?credential_offer=" + escapedCredentialOffer +
+				"&credential_offer_uri=https://issuer.example.com/offer",
+			expectedType:  router.CredentialOffer,
+			expectedError: "only one of credential_offer and credential_offer_uri may be provided",
+		},
+		{
+			name:          "Credential offer that isn't JSON",
+			payload:       "openid-credential-offer://?credential_offer=offer",
+			expectedType:  router.CredentialOffer,
+			expectedError: "decode credential offer",
+		},
+		{
+			name:          "Credential offer without credential configuration IDs",
+			payload:       `{"credential_issuer":"https://issuer.example.com"}`,
+			expectedType:  router.CredentialOffer,
+			expectedError: "credential offer is missing credential_configuration_ids",
+		},
+		{
+			name:          "Credential offer URI that isn't a URL",
+			payload:       "openid-credential-offer://?credential_offer_uri=offer",
+			expectedType:  router.CredentialOffer,
+			expectedError: "credential_offer_uri must be an absolute http(s) URL",
+		},
+		{
+			name:          "Presentation request missing the presentation definition",
+			payload:       "openid4vp://?client_id=verifier",
+			expectedType:  router.PresentationRequest,
+			expectedError: "must have a presentation_definition, presentation_definition_uri or dcql_query",
+		},
+		{
+			name:          "Presentation request missing client_id",
+			payload:       "openid4vp://?presentation_definition={}",
+			expectedType:  router.PresentationRequest,
+			expectedError: "must have a request, request_uri or client_id query parameter",
+		},
+		{
+			name:          "Presentation request with both request and request_uri",
+			payload:       "openid4vp://?request=" + requestObjectJWT + "&request_uri=https://verifier.example.com/request",
+			expectedType:  router.PresentationRequest,
+			expectedError: "only one of request and request_uri may be provided",
+		},
+		{
+			name:          "Presentation request with a request that isn't a JWT",
+			payload:       "openid4vp://?request=request",
+			expectedType:  router.PresentationRequest,
+			expectedError: "request must be a request object JWT",
+		},
+		{
+			name:          "Presentation request with an unsupported request_uri_method",
+			payload:       "openid4vp://?request_uri=https://verifier.example.com/request&request_uri_method=put",
+			expectedType:  router.PresentationRequest,
+			expectedError: "unsupported request_uri_method: put",
+		},
+		{
+			name:          "Unsupported scheme",
+			payload:       "mailto:someone@example.com",
+			expectedType:  router.Unknown,
+			expectedError: "unsupported URI scheme: mailto",
+		},
+		{
+			name:          "Universal link without a request",
+			payload:       "https://wallet.example.com/",
+			expectedType:  router.Unknown,
+			expectedError: "https link doesn't contain a credential offer or a presentation request",
+		},
+		{
+			name:          "JSON object that isn't a credential offer",
+			payload:       `{"key":"value"}`,
+			expectedType:  router.Unknown,
+			expectedError: "JSON payload isn't a credential offer",
+		},
+		{
+			name:          "Arbitrary text",
+			payload:       "hello",
+			expectedType:  router.Unknown,
+			expectedError: "payload is neither a URI, a request object nor a credential offer",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := router.Parse(test.payload)
+			require.Equal(t, test.expectedType, request.Type)
+
+			if test.expectedError == "" {
+				require.NoError(t, request.ValidationError)
+				require.Equal(t, test.expectedURI, request.URI)
+
+				return
+			}
+
+			require.ErrorContains(t, request.ValidationError, test.expectedError)
+
+			var walletError *walleterror.Error
+
+			require.ErrorAs(t, request.ValidationError, &walletError)
+			require.Contains(t, walletError.Code, router.ErrorModule)
+		})
+	}
+}
+
+func TestNewInteraction(t *testing.T) {
+	t.Run("Presentation request in a HAIP URI", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+			_, err := writer.Write([]byte(requestObjectJWT))
+			require.NoError(t, err)
+		}))
+		defer server.Close()
+
+		interaction, err := router.NewInteraction("haip://?request_uri="+url.QueryEscape(server.URL),
+			&router.Config{OpenID4VP: &router.OpenID4VPConfig{SignatureVerifier: &signatureVerifierMock{}}})
+		require.NoError(t, err)
+		require.Equal(t, router.PresentationRequest, interaction.Request.Type)
+		require.NotNil(t, interaction.Presentation)
+		require.Nil(t, interaction.IssuerInitiated)
+	})
+	t.Run("Raw request object", func(t *testing.T) {
+		interaction, err := router.NewInteraction(requestObjectJWT,
+			&router.Config{OpenID4VP: &router.OpenID4VPConfig{SignatureVerifier: &signatureVerifierMock{}}})
+		require.NoError(t, err)
+		require.NotNil(t, interaction.Presentation)
+	})
+	t.Run("Credential offer errors come from the OpenID4CI interaction", func(t *testing.T) {
+		interaction, err := router.NewInteraction(sampleCredentialOffer,
+			&router.Config{OpenID4CI: &openid4ci.ClientConfig{}})
+		require.ErrorContains(t, err, "no DID resolver provided")
+		require.Nil(t, interaction)
+	})
+	t.Run("Config not provided", func(t *testing.T) {
+		interaction, err := router.NewInteraction(sampleCredentialOffer, nil)
+		require.ErrorContains(t, err, "config must be provided")
+		require.Nil(t, interaction)
+	})
+	t.Run("OpenID4CI config not provided", func(t *testing.T) {
+		interaction, err := router.NewInteraction(sampleCredentialOffer, &router.Config{})
+		require.ErrorContains(t, err, "OpenID4CI config must be provided to handle credential offers")
+		require.Nil(t, interaction)
+	})
+	t.Run("OpenID4VP config not provided", func(t *testing.T) {
+		interaction, err := router.NewInteraction(requestObjectJWT, &router.Config{})
+		require.ErrorContains(t, err, "OpenID4VP config must be provided to handle presentation requests")
+		require.Nil(t, interaction)
+	})
+	t.Run("Invalid payload", func(t *testing.T) {
+		interaction, err := router.NewInteraction("hello", &router.Config{})
+		require.ErrorContains(t, err, "UNSUPPORTED_REQUEST")
+		require.Nil(t, interaction)
+	})
+}
+
+type signatureVerifierMock struct{}
+
+func (*signatureVerifierMock) CheckJWTProof(jose.Headers, string, []byte, []byte) error {
+	return nil
+}
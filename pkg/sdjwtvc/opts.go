@@ -0,0 +1,47 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sdjwtvc
+
+import (
+	"github.com/trustbloc/vc-go/jwt"
+
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+)
+
+type options struct {
+	proofChecker       jwt.ProofChecker
+	disabledProofCheck bool
+	holderKeyID        string
+	didResolver        api.DIDResolver
+}
+
+// Opt is an option for the Parse function.
+type Opt func(opts *options)
+
+// WithProofChecker sets the proof checker used to verify the issuer's signature.
+// Either this option or WithDisabledProofCheck must be used.
+func WithProofChecker(proofChecker jwt.ProofChecker) Opt {
+	return func(opts *options) {
+		opts.proofChecker = proofChecker
+	}
+}
+
+// WithDisabledProofCheck disables the verification of the issuer's signature.
+func WithDisabledProofCheck() Opt {
+	return func(opts *options) {
+		opts.disabledProofCheck = true
+	}
+}
+
+// WithHolderBinding causes the credential's cnf claim to be checked against the holder's key with the given key ID.
+// The DID resolver is used to resolve the key if the cnf claim contains a JWK.
+func WithHolderBinding(holderKeyID string, didResolver api.DIDResolver) Opt {
+	return func(opts *options) {
+		opts.holderKeyID = holderKeyID
+		opts.didResolver = didResolver
+	}
+}
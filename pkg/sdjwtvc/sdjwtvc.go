@@ -0,0 +1,276 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package sdjwtvc contains functions for parsing IETF SD-JWT VCs (SD-JWT-based Verifiable Credentials), as
+// defined in https://datatracker.ietf.org/doc/draft-ietf-oauth-sd-jwt-vc/.
+package sdjwtvc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	afgotime "github.com/trustbloc/did-go/doc/util/time"
+	"github.com/trustbloc/kms-go/doc/jose"
+	"github.com/trustbloc/kms-go/doc/jose/jwk"
+	"github.com/trustbloc/vc-go/jwt"
+	"github.com/trustbloc/vc-go/sdjwt/common"
+	"github.com/trustbloc/vc-go/verifiable"
+
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+	walletcommon "github.com/trustbloc/wallet-sdk/pkg/common"
+)
+
+const verifiableCredentialType = "VerifiableCredential"
+
+// Registered claims that describe the SD-JWT VC itself rather than its subject.
+//
+//nolint:gochecknoglobals
+var nonSubjectClaims = map[string]struct{}{
+	"iss": {}, "sub": {}, "iat": {}, "nbf": {}, "exp": {}, "vct": {}, "vct#integrity": {}, "cnf": {},
+	"status": {}, "_sd": {}, "_sd_alg": {},
+}
+
+type sdJWTVCClaims struct {
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub"`
+	Vct       string `json:"vct"`
+	IssuedAt  *int64 `json:"iat"`
+	ExpiresAt *int64 `json:"exp"`
+}
+
+// IsSDJWTVC indicates whether the given data is an SD-JWT VC in compact form (optionally as a JSON string).
+// The issuer's signature is not checked.
+func IsSDJWTVC(data []byte) bool {
+	sdJWTVC := unquote(data)
+
+	combinedFormat := common.ParseCombinedFormatForIssuance(sdJWTVC)
+
+	if !jose.IsCompactJWS(combinedFormat.SDJWT) {
+		return false
+	}
+
+	var claims sdJWTVCClaims
+
+	_, _, err := jwt.Parse(combinedFormat.SDJWT, jwt.WithIgnoreClaimsMapDecoding(true), jwt.DecodeClaimsTo(&claims))
+	if err != nil {
+		return false
+	}
+
+	return claims.Vct != ""
+}
+
+// Parse parses the given SD-JWT VC (optionally as a JSON string) into a Credential object. The issuer's signature
+// and the disclosures are verified. If the WithHolderBinding option is used, then the cnf claim is also checked.
+//
+// The returned credential has the types VerifiableCredential and the vct value, and its credential subject
+// contains the disclosed claims. Serializing it returns the original SD-JWT VC.
+func Parse(sdJWTVC string, opts ...Opt) (*verifiable.Credential, error) {
+	processedOpts := &options{}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(processedOpts)
+		}
+	}
+
+	sdJWTVC = unquote([]byte(sdJWTVC))
+
+	combinedFormat := common.ParseCombinedFormatForIssuance(sdJWTVC)
+
+	var claims sdJWTVCClaims
+
+	token, _, err := jwt.Parse(combinedFormat.SDJWT, jwt.DecodeClaimsTo(&claims))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SD-JWT VC: %w", err)
+	}
+
+	if claims.Vct == "" {
+		return nil, errors.New("SD-JWT VC is missing the vct claim")
+	}
+
+	if !processedOpts.disabledProofCheck {
+		if processedOpts.proofChecker == nil {
+			return nil, errors.New("jwt proofChecker is not defined")
+		}
+
+		err = jwt.CheckProof(combinedFormat.SDJWT, processedOpts.proofChecker, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify the issuer's signature on the SD-JWT VC: %w", err)
+		}
+	}
+
+	disclosedClaims, disclosureClaims, err := getDisclosedClaims(combinedFormat.Disclosures, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if processedOpts.holderKeyID != "" {
+		err = checkHolderBinding(token.Payload, processedOpts.holderKeyID, processedOpts.didResolver)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	vc, err := createCredential(&claims, disclosedClaims)
+	if err != nil {
+		return nil, err
+	}
+
+	vc.JWTEnvelope = &verifiable.JWTEnvelope{
+		JWT:              sdJWTVC,
+		JWTHeaders:       token.Headers,
+		SDJWTVersion:     common.SDJWTVersionV5,
+		SDJWTDisclosures: disclosureClaims,
+	}
+
+	return vc, nil
+}
+
+func getDisclosedClaims(disclosures []string,
+	token *jwt.JSONWebToken,
+) (map[string]interface{}, []*common.DisclosureClaim, error) {
+	err := common.VerifyDisclosuresInSDJWT(disclosures, token)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to verify the disclosures in the SD-JWT VC: %w", err)
+	}
+
+	cryptoHash, err := common.GetCryptoHashFromClaims(token.Payload)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to verify the disclosures in the SD-JWT VC: %w", err)
+	}
+
+	disclosureClaims, err := common.GetDisclosureClaims(disclosures, cryptoHash)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse the disclosures in the SD-JWT VC: %w", err)
+	}
+
+	disclosedClaims, err := common.GetDisclosedClaims(disclosureClaims, token.Payload)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse the disclosures in the SD-JWT VC: %w", err)
+	}
+
+	return disclosedClaims, disclosureClaims, nil
+}
+
+// checkHolderBinding checks that the SD-JWT VC is bound (via its cnf claim) to the holder's key.
+func checkHolderBinding(claims map[string]interface{}, holderKeyID string, didResolver api.DIDResolver) error {
+	cnf, err := common.GetCNF(claims)
+	if err != nil {
+		return fmt.Errorf("SD-JWT VC is not bound to a key: %w", err)
+	}
+
+	if kid, ok := cnf["kid"].(string); ok {
+		if kid != holderKeyID {
+			return fmt.Errorf("SD-JWT VC is bound to key %s instead of the holder's key %s", kid, holderKeyID)
+		}
+
+		return nil
+	}
+
+	cnfJWKObject, ok := cnf["jwk"]
+	if !ok {
+		return errors.New("the cnf claim in the SD-JWT VC must contain either a jwk or a kid")
+	}
+
+	cnfJWKBytes, err := json.Marshal(cnfJWKObject)
+	if err != nil {
+		return err
+	}
+
+	var cnfJWK jwk.JWK
+
+	err = cnfJWK.UnmarshalJSON(cnfJWKBytes)
+	if err != nil {
+		return fmt.Errorf("failed to parse the jwk in the SD-JWT VC's cnf claim: %w", err)
+	}
+
+	cnfKeyBytes, err := cnfJWK.PublicKeyBytes()
+	if err != nil {
+		return fmt.Errorf("failed to parse the jwk in the SD-JWT VC's cnf claim: %w", err)
+	}
+
+	holderKeyBytes, err := resolveHolderKey(holderKeyID, didResolver)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(cnfKeyBytes, holderKeyBytes) {
+		return fmt.Errorf("SD-JWT VC is not bound to the holder's key %s", holderKeyID)
+	}
+
+	return nil
+}
+
+func resolveHolderKey(holderKeyID string, didResolver api.DIDResolver) ([]byte, error) {
+	if didResolver == nil {
+		return nil, errors.New("a DID resolver is required to check the SD-JWT VC's key binding")
+	}
+
+	verificationMethod, err := walletcommon.NewVDRKeyResolver(didResolver).ResolveVerificationMethod(holderKeyID,
+		strings.Split(holderKeyID, "#")[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve the holder's key: %w", err)
+	}
+
+	if verificationMethod.JWK != nil {
+		return verificationMethod.JWK.PublicKeyBytes()
+	}
+
+	return verificationMethod.Value, nil
+}
+
+func createCredential(claims *sdJWTVCClaims, disclosedClaims map[string]interface{}) (*verifiable.Credential, error) {
+	subject := verifiable.Subject{
+		ID:           claims.Subject,
+		CustomFields: verifiable.CustomFields{},
+	}
+
+	for name, value := range disclosedClaims {
+		if _, ok := nonSubjectClaims[name]; !ok {
+			subject.CustomFields[name] = value
+		}
+	}
+
+	contents := verifiable.CredentialContents{
+		Context: []string{verifiable.V1ContextURI},
+		Types:   []string{verifiableCredentialType, claims.Vct},
+		Subject: []verifiable.Subject{subject},
+	}
+
+	if claims.Issuer != "" {
+		contents.Issuer = &verifiable.Issuer{ID: claims.Issuer}
+	}
+
+	if claims.IssuedAt != nil {
+		contents.Issued = afgotime.NewTime(time.Unix(*claims.IssuedAt, 0))
+	}
+
+	if claims.ExpiresAt != nil {
+		contents.Expired = afgotime.NewTime(time.Unix(*claims.ExpiresAt, 0))
+	}
+
+	vc, err := verifiable.CreateCredential(contents, verifiable.CustomFields{"vct": claims.Vct})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create credential from SD-JWT VC: %w", err)
+	}
+
+	return vc, nil
+}
+
+// unquote returns the string inside the given data if it's a JSON string, or the data itself otherwise.
+func unquote(data []byte) string {
+	var unquoted string
+
+	if json.Unmarshal(data, &unquoted) == nil {
+		return unquoted
+	}
+
+	return string(data)
+}
@@ -0,0 +1,249 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sdjwtvc_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	diddoc "github.com/trustbloc/did-go/doc/did"
+	"github.com/trustbloc/kms-go/doc/jose"
+	"github.com/trustbloc/kms-go/doc/jose/jwk"
+	"github.com/trustbloc/kms-go/doc/jose/jwk/jwksupport"
+	"github.com/trustbloc/vc-go/proof/defaults"
+	"github.com/trustbloc/vc-go/sdjwt/common"
+	"github.com/trustbloc/vc-go/sdjwt/issuer"
+
+	walletcommon "github.com/trustbloc/wallet-sdk/pkg/common"
+	"github.com/trustbloc/wallet-sdk/pkg/sdjwtvc"
+)
+
+const (
+	mockDID         = "did:example:12345"
+	issuerKeyID     = mockDID + "#issuer-key"
+	holderKeyID     = mockDID + "#holder-key"
+	otherKeyID      = mockDID + "#other-key"
+	sampleVct       = "urn:eu.europa.ec.eudi:pid:1"
+	sampleIssuerURL = "https://issuer.example.com"
+)
+
+func TestIsSDJWTVC(t *testing.T) {
+	keys := newTestKeys(t)
+
+	sdJWTVC := createSDJWTVC(t, keys, map[string]interface{}{"vct": sampleVct, "given_name": "Erika"}, keys.holder)
+
+	require.True(t, sdjwtvc.IsSDJWTVC([]byte(sdJWTVC)))
+
+	quotedSDJWTVC, err := json.Marshal(sdJWTVC)
+	require.NoError(t, err)
+	require.True(t, sdjwtvc.IsSDJWTVC(quotedSDJWTVC))
+
+	withoutVct := createSDJWTVC(t, keys, map[string]interface{}{"given_name": "Erika"}, keys.holder)
+	require.False(t, sdjwtvc.IsSDJWTVC([]byte(withoutVct)))
+
+	require.False(t, sdjwtvc.IsSDJWTVC([]byte(`{"@context":["https://www.w3.org/2018/credentials/v1"]}`)))
+	require.False(t, sdjwtvc.IsSDJWTVC([]byte("")))
+}
+
+func TestParse(t *testing.T) {
+	keys := newTestKeys(t)
+
+	claims := map[string]interface{}{
+		"iat":         time.Now().Unix(),
+		"vct":         sampleVct,
+		"given_name":  "Erika",
+		"family_name": "Mustermann",
+	}
+
+	sdJWTVC := createSDJWTVC(t, keys, claims, keys.holder)
+
+	proofChecker := defaults.NewDefaultProofChecker(walletcommon.NewVDRKeyResolver(keys.didResolver))
+
+	t.Run("Success", func(t *testing.T) {
+		vc, err := sdjwtvc.Parse(sdJWTVC, sdjwtvc.WithProofChecker(proofChecker),
+			sdjwtvc.WithHolderBinding(holderKeyID, keys.didResolver))
+		require.NoError(t, err)
+
+		contents := vc.Contents()
+		require.Equal(t, []string{"VerifiableCredential", sampleVct}, contents.Types)
+		require.Equal(t, sampleIssuerURL, contents.Issuer.ID)
+		require.NotNil(t, contents.Issued)
+		require.Len(t, contents.Subject, 1)
+		require.Equal(t, "Erika", contents.Subject[0].CustomFields["given_name"])
+		require.Equal(t, "Mustermann", contents.Subject[0].CustomFields["family_name"])
+		require.NotContains(t, contents.Subject[0].CustomFields, "vct")
+		require.NotContains(t, contents.Subject[0].CustomFields, "cnf")
+		require.Len(t, vc.SDJWTDisclosures(), 2)
+
+		serializedVC, err := vc.MarshalJSON()
+		require.NoError(t, err)
+
+		var serializedVCString string
+
+		require.NoError(t, json.Unmarshal(serializedVC, &serializedVCString))
+		require.Equal(t, sdJWTVC, serializedVCString)
+
+		reparsedVC, err := sdjwtvc.Parse(string(serializedVC), sdjwtvc.WithDisabledProofCheck())
+		require.NoError(t, err)
+		require.Equal(t, contents.Types, reparsedVC.Contents().Types)
+	})
+	t.Run("Key binding using a key ID", func(t *testing.T) {
+		sdJWTVCWithKID := createSDJWTVC(t, keys, map[string]interface{}{
+			"vct": sampleVct,
+			"cnf": map[string]interface{}{"kid": holderKeyID},
+		}, nil)
+
+		_, err := sdjwtvc.Parse(sdJWTVCWithKID, sdjwtvc.WithDisabledProofCheck(),
+			sdjwtvc.WithHolderBinding(holderKeyID, keys.didResolver))
+		require.NoError(t, err)
+
+		_, err = sdjwtvc.Parse(sdJWTVCWithKID, sdjwtvc.WithDisabledProofCheck(),
+			sdjwtvc.WithHolderBinding(otherKeyID, keys.didResolver))
+		require.ErrorContains(t, err, "instead of the holder's key")
+	})
+	t.Run("Bound to a different key", func(t *testing.T) {
+		_, err := sdjwtvc.Parse(sdJWTVC, sdjwtvc.WithProofChecker(proofChecker),
+			sdjwtvc.WithHolderBinding(otherKeyID, keys.didResolver))
+		require.ErrorContains(t, err, "SD-JWT VC is not bound to the holder's key "+otherKeyID)
+	})
+	t.Run("Not bound to any key", func(t *testing.T) {
+		unboundSDJWTVC := createSDJWTVC(t, keys, claims, nil)
+
+		_, err := sdjwtvc.Parse(unboundSDJWTVC, sdjwtvc.WithProofChecker(proofChecker),
+			sdjwtvc.WithHolderBinding(holderKeyID, keys.didResolver))
+		require.ErrorContains(t, err, "SD-JWT VC is not bound to a key")
+	})
+	t.Run("Invalid signature", func(t *testing.T) {
+		parts := strings.SplitN(sdJWTVC, "~", 2)
+		jwtParts := strings.Split(parts[0], ".")
+
+		otherSDJWTVC := createSDJWTVC(t, keys, map[string]interface{}{"vct": "other"}, keys.holder)
+		otherSignature := strings.Split(strings.SplitN(otherSDJWTVC, "~", 2)[0], ".")[2]
+
+		tamperedSDJWTVC := jwtParts[0] + "." + jwtParts[1] + "." + otherSignature + "~" + parts[1]
+
+		_, err := sdjwtvc.Parse(tamperedSDJWTVC, sdjwtvc.WithProofChecker(proofChecker))
+		require.ErrorContains(t, err, "failed to verify the issuer's signature on the SD-JWT VC")
+	})
+	t.Run("Proof checker not defined", func(t *testing.T) {
+		_, err := sdjwtvc.Parse(sdJWTVC)
+		require.ErrorContains(t, err, "jwt proofChecker is not defined")
+	})
+	t.Run("Unknown disclosure", func(t *testing.T) {
+		_, err := sdjwtvc.Parse(sdJWTVC+"WyJzYWx0IiwgIm5hbWUiLCAidmFsdWUiXQ~", sdjwtvc.WithDisabledProofCheck())
+		require.ErrorContains(t, err, "failed to verify the disclosures in the SD-JWT VC")
+	})
+	t.Run("Missing vct", func(t *testing.T) {
+		withoutVct := createSDJWTVC(t, keys, map[string]interface{}{"given_name": "Erika"}, keys.holder)
+
+		_, err := sdjwtvc.Parse(withoutVct, sdjwtvc.WithDisabledProofCheck())
+		require.EqualError(t, err, "SD-JWT VC is missing the vct claim")
+	})
+	t.Run("Not a JWT", func(t *testing.T) {
+		_, err := sdjwtvc.Parse("not a JWT", sdjwtvc.WithDisabledProofCheck())
+		require.ErrorContains(t, err, "failed to parse SD-JWT VC")
+	})
+}
+
+type testKeys struct {
+	issuer      ed25519.PrivateKey
+	holder      *jwk.JWK
+	didResolver *didResolverMock
+}
+
+func newTestKeys(t *testing.T) *testKeys {
+	t.Helper()
+
+	issuerPublicKey, issuerPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	holderPublicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	otherPublicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	holderJWK, err := jwksupport.JWKFromKey(holderPublicKey)
+	require.NoError(t, err)
+
+	doc := &diddoc.Doc{ID: mockDID}
+
+	for keyID, publicKey := range map[string]ed25519.PublicKey{
+		issuerKeyID: issuerPublicKey,
+		holderKeyID: holderPublicKey,
+		otherKeyID:  otherPublicKey,
+	} {
+		verificationMethod := diddoc.VerificationMethod{
+			ID:         keyID,
+			Controller: mockDID,
+			Type:       "Ed25519VerificationKey2018",
+			Value:      publicKey,
+		}
+
+		doc.VerificationMethod = append(doc.VerificationMethod, verificationMethod)
+		doc.AssertionMethod = append(doc.AssertionMethod, diddoc.Verification{
+			VerificationMethod: verificationMethod,
+			Relationship:       diddoc.AssertionMethod,
+		})
+	}
+
+	return &testKeys{
+		issuer:      issuerPrivateKey,
+		holder:      holderJWK,
+		didResolver: &didResolverMock{docResolution: &diddoc.DocResolution{DIDDocument: doc}},
+	}
+}
+
+func createSDJWTVC(t *testing.T, keys *testKeys, claims map[string]interface{}, holderJWK *jwk.JWK) string {
+	t.Helper()
+
+	opts := []issuer.NewOpt{
+		issuer.WithSDJWTVersion(common.SDJWTVersionV5),
+		issuer.WithNonSelectivelyDisclosableClaims([]string{"iat", "vct", "cnf", "cnf.kid"}),
+	}
+
+	if holderJWK != nil {
+		opts = append(opts, issuer.WithHolderPublicKey(holderJWK))
+	}
+
+	sdJWT, err := issuer.New(sampleIssuerURL, claims, nil, &ed25519Signer{privateKey: keys.issuer}, opts...)
+	require.NoError(t, err)
+
+	sdJWTVC, err := sdJWT.Serialize(false)
+	require.NoError(t, err)
+
+	return sdJWTVC
+}
+
+type ed25519Signer struct {
+	privateKey ed25519.PrivateKey
+}
+
+func (s *ed25519Signer) Sign(data []byte) ([]byte, error) {
+	return ed25519.Sign(s.privateKey, data), nil
+}
+
+func (s *ed25519Signer) Headers() jose.Headers {
+	return jose.Headers{
+		jose.HeaderAlgorithm: "EdDSA",
+		jose.HeaderKeyID:     issuerKeyID,
+		jose.HeaderType:      "dc+sd-jwt",
+	}
+}
+
+type didResolverMock struct {
+	docResolution *diddoc.DocResolution
+}
+
+func (m *didResolverMock) Resolve(string) (*diddoc.DocResolution, error) {
+	return m.docResolution, nil
+}
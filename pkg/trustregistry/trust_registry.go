@@ -9,6 +9,7 @@ package trustregistry
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -63,7 +64,14 @@ func New(config *RegistryConfig) *Registry {
 }
 
 // EvaluateIssuance evaluate is issuance request by calling trust registry.
-func (r *Registry) EvaluateIssuance(request *IssuanceRequest) (*EvaluationResult, error) { //nolint:dupl
+func (r *Registry) EvaluateIssuance(request *IssuanceRequest) (*EvaluationResult, error) {
+	return r.EvaluateIssuanceContext(context.Background(), request)
+}
+
+// EvaluateIssuanceContext is the same as EvaluateIssuance, but calls the trust registry using the given context.
+func (r *Registry) EvaluateIssuanceContext(ctx context.Context, //nolint:dupl
+	request *IssuanceRequest,
+) (*EvaluationResult, error) {
 	req := httprequest.New(r.httpClient, r.metricsLogger)
 
 	requestBytes, err := json.Marshal(request)
@@ -71,10 +79,10 @@ func (r *Registry) EvaluateIssuance(request *IssuanceRequest) (*EvaluationResult
 		return nil, fmt.Errorf("fail to marshal issuanceRequest: %w", err)
 	}
 
-	responseBytes, err := req.Do(http.MethodPost, r.evaluateIssuanceURL, "application/json",
+	responseBytes, err := req.DoContext(ctx, http.MethodPost, r.evaluateIssuanceURL, "application/json", nil,
 		bytes.NewBuffer(requestBytes),
 		fmt.Sprintf(evaluateIssuanceEventVIAGetReqEventText, r.evaluateIssuanceURL),
-		evaluateIssuanceEventText, nil,
+		evaluateIssuanceEventText, nil, nil,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("evaluate issuance endpoint: %w", err)
@@ -91,7 +99,15 @@ func (r *Registry) EvaluateIssuance(request *IssuanceRequest) (*EvaluationResult
 }
 
 // EvaluatePresentation evaluate is presentation request by calling trust registry.
-func (r *Registry) EvaluatePresentation(request *PresentationRequest) (*EvaluationResult, error) { //nolint:dupl
+func (r *Registry) EvaluatePresentation(request *PresentationRequest) (*EvaluationResult, error) {
+	return r.EvaluatePresentationContext(context.Background(), request)
+}
+
+// EvaluatePresentationContext is the same as EvaluatePresentation, but calls the trust registry using the given
+// context.
+func (r *Registry) EvaluatePresentationContext(ctx context.Context, //nolint:dupl
+	request *PresentationRequest,
+) (*EvaluationResult, error) {
 	req := httprequest.New(r.httpClient, r.metricsLogger)
 
 	requestBytes, err := json.Marshal(request)
@@ -99,10 +115,10 @@ func (r *Registry) EvaluatePresentation(request *PresentationRequest) (*Evaluati
 		return nil, fmt.Errorf("fail to marshal presentationRequest: %w", err)
 	}
 
-	responseBytes, err := req.Do(http.MethodPost, r.evaluatePresentationURL, "application/json",
+	responseBytes, err := req.DoContext(ctx, http.MethodPost, r.evaluatePresentationURL, "application/json", nil,
 		bytes.NewBuffer(requestBytes),
 		fmt.Sprintf(evaluatePresentationEventVIAGetReqEventText, r.evaluatePresentationURL),
-		evaluatePresentationEventText, nil,
+		evaluatePresentationEventText, nil, nil,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("evaluate presentation endpoint: %w", err)
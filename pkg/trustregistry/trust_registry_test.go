@@ -6,6 +6,7 @@ SPDX-License-Identifier: Apache-2.0
 package trustregistry_test
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"net/http/httptest"
@@ -54,6 +55,18 @@ func TestRegistry_EvaluateIssuance(t *testing.T) {
 		require.Equal(t, "didForbidden", result.ErrorCode)
 	})
 
+	t.Run("Context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		result, err := registry.EvaluateIssuanceContext(ctx, &trustregistry.IssuanceRequest{
+			IssuerDID: "did:web:correct.com",
+		})
+
+		require.ErrorIs(t, err, context.Canceled)
+		require.Nil(t, result)
+	})
+
 	t.Run("Invalid server URI", func(t *testing.T) {
 		result, err := trustregistry.New(&trustregistry.RegistryConfig{
 			EvaluateIssuanceURL: "http://invalid",
@@ -106,6 +119,18 @@ func TestRegistry_EvaluatePresentation(t *testing.T) {
 		require.Equal(t, "didForbidden", result.ErrorCode)
 	})
 
+	t.Run("Context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		result, err := registry.EvaluatePresentationContext(ctx, &trustregistry.PresentationRequest{
+			VerifierDid: "did:web:correct.com",
+		})
+
+		require.ErrorIs(t, err, context.Canceled)
+		require.Nil(t, result)
+	})
+
 	t.Run("Invalid server URI", func(t *testing.T) {
 		result, err := trustregistry.New(&trustregistry.RegistryConfig{
 			EvaluatePresentationURL: "http://invalid",